@@ -15,15 +15,9 @@ import (
 //   - a fresh refresh token produces a new *access* token that actually
 //     authenticates protected endpoints;
 //   - invalid / garbage refresh tokens are rejected with 401;
+//   - every refresh rotates the refresh token, and replaying a rotated token
+//     revokes the whole session (see auth.RefreshUseCase.Execute);
 //   - logout revokes the refresh token so subsequent refresh attempts fail.
-//
-// The current implementation does NOT rotate the refresh token itself —
-// successive refreshes with the same refresh token are accepted until the
-// user explicitly logs out or the token expires. That's a deliberate choice
-// in the server code (see auth.RefreshUseCase.Execute); if you ever add
-// rotation, the "second refresh with the same token succeeds" assertion
-// below will start failing and should be updated to assert rotation
-// semantics instead (old-token-after-rotation → 401).
 func TestRefreshTokenFlow(t *testing.T) {
 	email := "refresh@example.test"
 	password := "refreshSecret!123"
@@ -60,9 +54,10 @@ func TestRefreshTokenFlow(t *testing.T) {
 	// identical tokens. The meaningful property is that the returned token
 	// authenticates a protected endpoint.
 	var refresh1 struct {
-		AccessToken string `json:"access_token"`
-		ExpiresAt   int64  `json:"expires_at"`
-		TokenType   string `json:"token_type"`
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresAt    int64  `json:"expires_at"`
+		TokenType    string `json:"token_type"`
 	}
 	code = doJSON(t, http.MethodPost, "/auth/refresh", "",
 		map[string]string{"refresh_token": login.RefreshToken}, &refresh1)
@@ -74,22 +69,42 @@ func TestRefreshTokenFlow(t *testing.T) {
 	status, raw := restCall(t, http.MethodGet, "/users/me", refresh1.AccessToken, nil)
 	require.Equalf(t, http.StatusOK, status, "refreshed access token: %s", string(raw))
 
-	// --- Second refresh with the same refresh token is still accepted ---
-	// (Current server does not rotate refresh tokens. If rotation is added
-	// later this sub-assertion should flip to asserting 401 on the second
-	// use of the same refresh token.)
+	// --- The refresh token was rotated -------------------------------------
+	require.NotEmpty(t, refresh1.RefreshToken)
+	assert.NotEqual(t, login.RefreshToken, refresh1.RefreshToken)
+
+	var refresh2 struct {
+		RefreshToken string `json:"refresh_token"`
+	}
 	code = doJSON(t, http.MethodPost, "/auth/refresh", "",
-		map[string]string{"refresh_token": login.RefreshToken}, nil)
-	require.Equal(t, http.StatusOK, code,
-		"without rotation the same refresh token should keep working until logout")
+		map[string]string{"refresh_token": refresh1.RefreshToken}, &refresh2)
+	require.Equal(t, http.StatusOK, code, "the rotated refresh token must work once")
+	require.NotEmpty(t, refresh2.RefreshToken)
+
+	// --- Replaying a rotated token revokes the whole session ---------------
+	status, _ = restCall(t, http.MethodPost, "/auth/refresh", "",
+		map[string]string{"refresh_token": login.RefreshToken})
+	assert.Equal(t, http.StatusUnauthorized, status, "reused refresh token must 401")
+
+	status, _ = restCall(t, http.MethodPost, "/auth/refresh", "",
+		map[string]string{"refresh_token": refresh2.RefreshToken})
+	assert.Equal(t, http.StatusUnauthorized, status,
+		"reuse detection must also revoke the latest token of the session")
 
 	// --- Logout revokes the refresh token -------------------------------
+	var relogin struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	code = doJSON(t, http.MethodPost, "/auth/login", "",
+		map[string]string{"email": email, "password": password}, &relogin)
+	require.Equal(t, http.StatusOK, code)
+
 	code = doJSON(t, http.MethodPost, "/auth/logout", refresh1.AccessToken,
-		map[string]string{"refresh_token": login.RefreshToken}, nil)
+		map[string]string{"refresh_token": relogin.RefreshToken}, nil)
 	require.Equal(t, http.StatusOK, code)
 
 	status, _ = restCall(t, http.MethodPost, "/auth/refresh", "",
-		map[string]string{"refresh_token": login.RefreshToken})
+		map[string]string{"refresh_token": relogin.RefreshToken})
 	assert.Equal(t, http.StatusUnauthorized, status,
 		"refresh after logout must be rejected")
 }
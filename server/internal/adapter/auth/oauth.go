@@ -3,6 +3,7 @@ package auth
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/coreos/go-oidc/v3/oidc"
 	"github.com/jherrma/caldav-server/internal/config"
@@ -12,30 +13,70 @@ import (
 // OAuthProvider defines the interface for interacting with an OAuth/OIDC provider
 type OAuthProvider interface {
 	Name() string
+	DisplayName() string
+	Icon() string
+	Policy() ProviderPolicy
 	AuthCodeURL(state string, opts ...oauth2.AuthCodeOption) string
 	Exchange(ctx context.Context, code string) (*oauth2.Token, error)
 	UserInfo(ctx context.Context, tokenSource oauth2.TokenSource) (*UserInfo, error)
 }
 
+// ProviderPolicy controls which users a provider may sign in
+type ProviderPolicy struct {
+	AllowedDomains []string // Empty allows every email domain
+	AutoRegister   bool     // Create unknown users on first sign-in
+	TrustEmail     bool     // Treat every email as verified, for IdPs that omit email_verified
+}
+
+// AllowsEmail reports whether the email address belongs to an allowed domain
+func (p ProviderPolicy) AllowsEmail(email string) bool {
+	if len(p.AllowedDomains) == 0 {
+		return true
+	}
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	domain := email[at+1:]
+	for _, allowed := range p.AllowedDomains {
+		if strings.EqualFold(domain, strings.TrimPrefix(allowed, "@")) {
+			return true
+		}
+	}
+	return false
+}
+
+// EmailVerified reports whether the email of the user info may be used to
+// find an existing account
+func (p ProviderPolicy) EmailVerified(info *UserInfo) bool {
+	return info.EmailVerified || p.TrustEmail
+}
+
 // UserInfo represents the user information retrieved from the provider
 type UserInfo struct {
 	Subject       string
 	Email         string
 	EmailVerified bool
 	Name          string
+	Username      string // Preferred username, may be empty
 }
 
 type oidcProvider struct {
-	name     string
+	conf     config.OIDCProviderConfig
 	provider *oidc.Provider
 	config   *oauth2.Config
 }
 
 // NewOIDCProvider creates a new OIDC-based OAuth provider
-func NewOIDCProvider(ctx context.Context, name string, conf config.OAuthProviderConfig, redirectURL string) (OAuthProvider, error) {
+func NewOIDCProvider(ctx context.Context, conf config.OIDCProviderConfig, redirectURL string) (OAuthProvider, error) {
 	provider, err := oidc.NewProvider(ctx, conf.Issuer)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create OIDC provider for %s: %w", name, err)
+		return nil, fmt.Errorf("failed to create OIDC provider for %s: %w", conf.Name, err)
+	}
+
+	scopes := conf.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{oidc.ScopeOpenID, "profile", "email"}
 	}
 
 	oauthConfig := &oauth2.Config{
@@ -43,18 +84,37 @@ func NewOIDCProvider(ctx context.Context, name string, conf config.OAuthProvider
 		ClientSecret: conf.ClientSecret,
 		RedirectURL:  redirectURL,
 		Endpoint:     provider.Endpoint(),
-		Scopes:       []string{oidc.ScopeOpenID, "profile", "email"},
+		Scopes:       scopes,
 	}
 
 	return &oidcProvider{
-		name:     name,
+		conf:     conf,
 		provider: provider,
 		config:   oauthConfig,
 	}, nil
 }
 
 func (p *oidcProvider) Name() string {
-	return p.name
+	return p.conf.Name
+}
+
+func (p *oidcProvider) DisplayName() string {
+	if p.conf.DisplayName != "" {
+		return p.conf.DisplayName
+	}
+	return "SSO (" + p.conf.Name + ")"
+}
+
+func (p *oidcProvider) Icon() string {
+	return p.conf.Icon
+}
+
+func (p *oidcProvider) Policy() ProviderPolicy {
+	return ProviderPolicy{
+		AllowedDomains: p.conf.AllowedDomains,
+		AutoRegister:   p.conf.AutoRegisterEnabled(),
+		TrustEmail:     p.conf.TrustEmail,
+	}
 }
 
 func (p *oidcProvider) AuthCodeURL(state string, opts ...oauth2.AuthCodeOption) string {
@@ -71,23 +131,39 @@ func (p *oidcProvider) UserInfo(ctx context.Context, tokenSource oauth2.TokenSou
 		return nil, err
 	}
 
-	var claims struct {
-		Subject       string `json:"sub"`
-		Email         string `json:"email"`
-		EmailVerified bool   `json:"email_verified"`
-		Name          string `json:"name"`
-	}
-
+	var claims map[string]interface{}
 	if err := userInfo.Claims(&claims); err != nil {
 		return nil, err
 	}
 
-	return &UserInfo{
-		Subject:       claims.Subject,
-		Email:         claims.Email,
-		EmailVerified: claims.EmailVerified,
-		Name:          claims.Name,
-	}, nil
+	return MapClaims(claims, p.conf.Claims), nil
+}
+
+// MapClaims builds the user information from the raw provider claims using
+// the configured claim names, falling back to the standard OIDC claims
+func MapClaims(claims map[string]interface{}, mapping config.OIDCClaimConfig) *UserInfo {
+	str := func(name, fallback string) string {
+		if name == "" {
+			name = fallback
+		}
+		v, _ := claims[name].(string)
+		return v
+	}
+
+	info := &UserInfo{
+		Subject:  str("sub", ""),
+		Email:    str(mapping.Email, "email"),
+		Name:     str(mapping.DisplayName, "name"),
+		Username: str(mapping.Username, "preferred_username"),
+	}
+	switch v := claims["email_verified"].(type) {
+	case bool:
+		info.EmailVerified = v
+	case string:
+		// Some providers (e.g. older Cognito) send the flag as a string
+		info.EmailVerified = v == "true"
+	}
+	return info
 }
 
 // OAuthProviderManager defines the interface for managing OAuth providers
@@ -99,38 +175,28 @@ type OAuthProviderManager interface {
 // oauthProviderManager implements OAuthProviderManager
 type oauthProviderManager struct {
 	providers map[string]OAuthProvider
+	order     []string
 }
 
-// NewOAuthProviderManager creates a new OAuth provider manager
-func NewOAuthProviderManager(cfg *config.OAuthConfig) (OAuthProviderManager, error) {
-	providers := make(map[string]OAuthProvider)
-	ctx := context.Background() // TODO: potentially pass context
-
-	// Initialize Google
-	if cfg.Google.ClientID != "" && cfg.Google.ClientSecret != "" {
-		p, err := NewOIDCProvider(ctx, "google", cfg.Google, "/api/v1/auth/oauth/google/callback") // Redirect URL needs to be constructed properly
-		if err == nil {
-			providers["google"] = p
-		}
-	}
-
-	// Initialize Microsoft
-	if cfg.Microsoft.ClientID != "" && cfg.Microsoft.ClientSecret != "" {
-		p, err := NewOIDCProvider(ctx, "microsoft", cfg.Microsoft, "/api/v1/auth/oauth/microsoft/callback")
-		if err == nil {
-			providers["microsoft"] = p
+// NewOAuthProviderManager creates a new OAuth provider manager. Providers
+// whose discovery fails are logged and skipped so one unreachable issuer
+// does not take down the others.
+func NewOAuthProviderManager(cfg *config.OAuthConfig, baseURL string) (OAuthProviderManager, error) {
+	m := &oauthProviderManager{providers: make(map[string]OAuthProvider)}
+	ctx := context.Background()
+
+	for _, conf := range cfg.OIDCProviders() {
+		redirectURL := strings.TrimSuffix(baseURL, "/") + "/api/v1/auth/oauth/" + conf.Name + "/callback"
+		p, err := NewOIDCProvider(ctx, conf, redirectURL)
+		if err != nil {
+			fmt.Printf("Warning: OAuth provider %s disabled: %v\n", conf.Name, err)
+			continue
 		}
+		m.providers[conf.Name] = p
+		m.order = append(m.order, conf.Name)
 	}
 
-	// Initialize Custom
-	if cfg.Custom.ClientID != "" && cfg.Custom.ClientSecret != "" {
-		p, err := NewOIDCProvider(ctx, "custom", cfg.Custom, "/api/v1/auth/oauth/custom/callback")
-		if err == nil {
-			providers["custom"] = p
-		}
-	}
-
-	return &oauthProviderManager{providers: providers}, nil
+	return m, nil
 }
 
 func (m *oauthProviderManager) GetProvider(name string) (OAuthProvider, error) {
@@ -141,10 +207,7 @@ func (m *oauthProviderManager) GetProvider(name string) (OAuthProvider, error) {
 	return p, nil
 }
 
+// ListProviders returns the provider names in configuration order
 func (m *oauthProviderManager) ListProviders() []string {
-	var names []string
-	for name := range m.providers {
-		names = append(names, name)
-	}
-	return names
+	return append([]string(nil), m.order...)
 }
@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/jherrma/caldav-server/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMapClaims(t *testing.T) {
+	claims := map[string]interface{}{
+		"sub":                "abc",
+		"email":              "jane@corp.example",
+		"upn":                "jane.doe@corp.example",
+		"email_verified":     "true",
+		"name":               "Jane Doe",
+		"preferred_username": "jane",
+		"samaccountname":     "jdoe",
+	}
+
+	info := MapClaims(claims, config.OIDCClaimConfig{})
+	assert.Equal(t, "abc", info.Subject)
+	assert.Equal(t, "jane@corp.example", info.Email)
+	assert.Equal(t, "jane", info.Username)
+	assert.Equal(t, "Jane Doe", info.Name)
+	assert.True(t, info.EmailVerified)
+
+	info = MapClaims(claims, config.OIDCClaimConfig{Username: "samaccountname", Email: "upn"})
+	assert.Equal(t, "jdoe", info.Username)
+	assert.Equal(t, "jane.doe@corp.example", info.Email)
+}
+
+func TestProviderPolicy_AllowsEmail(t *testing.T) {
+	assert.True(t, ProviderPolicy{}.AllowsEmail("anyone@anywhere.example"))
+
+	p := ProviderPolicy{AllowedDomains: []string{"corp.example", "@Partner.example"}}
+	assert.True(t, p.AllowsEmail("jane@CORP.example"))
+	assert.True(t, p.AllowsEmail("bob@partner.example"))
+	assert.False(t, p.AllowsEmail("eve@sub.corp.example"))
+	assert.False(t, p.AllowsEmail("eve@evil.example"))
+	assert.False(t, p.AllowsEmail(""))
+}
@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jherrma/caldav-server/internal/domain"
+)
+
+const oidcSigningKeySetting = "oidc_signing_key"
+
+// OIDCSigner signs ID tokens issued by the built-in authorization server
+// with RS256. The key is generated on first start and kept in the system
+// settings so tokens stay verifiable across restarts.
+type OIDCSigner struct {
+	key *rsa.PrivateKey
+	kid string
+}
+
+// NewOIDCSigner loads the signing key from the system settings, generating
+// and storing one if none exists yet
+func NewOIDCSigner(ctx context.Context, repo domain.SystemSettingRepository) (*OIDCSigner, error) {
+	stored, err := repo.Get(ctx, oidcSigningKeySetting)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get oidc signing key from db: %w", err)
+	}
+
+	var key *rsa.PrivateKey
+	if stored != "" {
+		block, _ := pem.Decode([]byte(stored))
+		if block == nil {
+			return nil, errors.New("stored oidc signing key is not valid PEM")
+		}
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse oidc signing key: %w", err)
+		}
+	} else {
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate oidc signing key: %w", err)
+		}
+		encoded := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+		if err := repo.Set(ctx, oidcSigningKeySetting, string(encoded)); err != nil {
+			return nil, fmt.Errorf("failed to save oidc signing key to db: %w", err)
+		}
+	}
+
+	return newOIDCSigner(key), nil
+}
+
+func newOIDCSigner(key *rsa.PrivateKey) *OIDCSigner {
+	sum := sha256.Sum256(x509.MarshalPKCS1PublicKey(&key.PublicKey))
+	return &OIDCSigner{key: key, kid: base64.RawURLEncoding.EncodeToString(sum[:8])}
+}
+
+// SignIDToken signs the claims as a compact JWS
+func (s *OIDCSigner) SignIDToken(claims map[string]interface{}) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims(claims))
+	token.Header["kid"] = s.kid
+	return token.SignedString(s.key)
+}
+
+// JWKS returns the public key as a JSON Web Key Set (RFC 7517)
+func (s *OIDCSigner) JWKS() map[string]interface{} {
+	pub := s.key.PublicKey
+	return map[string]interface{}{
+		"keys": []map[string]interface{}{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": s.kid,
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	}
+}
@@ -228,10 +228,14 @@ func (h *AddressBookHandler) Delete(c fiber.Ctx) error {
 
 // Export godoc
 // @Summary      Export address book
-// @Description  Export address book as vCard
+// @Description  Export address book as vCard, or as CSV in the Google or Outlook column layout
 // @Tags         Import/Export
 // @Produce      text/vcard
-// @Param        id   path      integer  true  "Address Book ID"
+// @Produce      text/csv
+// @Param        id       path      integer  true   "Address Book ID"
+// @Param        version  query     string   false  "Convert all contacts to this vCard version (3.0 or 4.0)"
+// @Param        format   query     string   false  "vcard (default) or csv"
+// @Param        mapping  query     string   false  "CSV column layout: google (default) or outlook"
 // @Success      200  {file}    file
 // @Failure      400  {object}  ErrorResponseBody
 // @Failure      401  {object}  ErrorResponseBody
@@ -248,7 +252,28 @@ func (h *AddressBookHandler) Export(c fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_id"})
 	}
 
-	data, filename, err := h.exportUC.Execute(c.Context(), uint(id), userID)
+	if format := c.Query("format", "vcard"); format == "csv" {
+		mapping, ok := domainaddressbook.CSVMappingByName(c.Query("mapping", domainaddressbook.CSVMappingGoogle))
+		if !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_mapping"})
+		}
+		data, filename, err := h.exportUC.ExecuteCSV(c.Context(), uint(id), userID, mapping)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		c.Set("Content-Type", "text/csv; charset=utf-8")
+		c.Set("Content-Disposition", "attachment; filename=\""+filename+"\"")
+		return c.Send(data)
+	} else if format != "vcard" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_format"})
+	}
+
+	version := c.Query("version")
+	if version != "" && !domainaddressbook.IsSupportedVCardVersion(version) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_version"})
+	}
+
+	data, filename, err := h.exportUC.Execute(c.Context(), uint(id), userID, version)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 	}
@@ -0,0 +1,104 @@
+package http
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/jherrma/caldav-server/internal/domain/user"
+	"github.com/jherrma/caldav-server/internal/usecase/addressbook"
+)
+
+type AddressBookPublicHandler struct {
+	enablePublicUC    *addressbook.EnablePublicUseCase
+	getPublicStatusUC *addressbook.GetPublicStatusUseCase
+	regenerateTokenUC *addressbook.RegenerateTokenUseCase
+	updateOptionsUC   *addressbook.UpdatePublicOptionsUseCase
+}
+
+func NewAddressBookPublicHandler(
+	enablePublicUC *addressbook.EnablePublicUseCase,
+	getPublicStatusUC *addressbook.GetPublicStatusUseCase,
+	regenerateTokenUC *addressbook.RegenerateTokenUseCase,
+	updateOptionsUC *addressbook.UpdatePublicOptionsUseCase,
+) *AddressBookPublicHandler {
+	return &AddressBookPublicHandler{
+		enablePublicUC:    enablePublicUC,
+		getPublicStatusUC: getPublicStatusUC,
+		regenerateTokenUC: regenerateTokenUC,
+		updateOptionsUC:   updateOptionsUC,
+	}
+}
+
+// POST /api/v1/addressbooks/:id/public
+func (h *AddressBookPublicHandler) EnablePublic(c fiber.Ctx) error {
+	u := c.Locals("user").(*user.User)
+	addressBookID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_id"})
+	}
+
+	var req addressbook.EnablePublicInput
+	if err := c.Bind().JSON(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_request"})
+	}
+
+	output, err := h.enablePublicUC.Execute(c.Context(), u.ID, uint(addressBookID), req)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(output)
+}
+
+// GET /api/v1/addressbooks/:id/public
+func (h *AddressBookPublicHandler) GetPublicStatus(c fiber.Ctx) error {
+	u := c.Locals("user").(*user.User)
+	addressBookID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_id"})
+	}
+
+	output, err := h.getPublicStatusUC.Execute(c.Context(), u.ID, uint(addressBookID))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(output)
+}
+
+// POST /api/v1/addressbooks/:id/public/regenerate
+func (h *AddressBookPublicHandler) RegenerateToken(c fiber.Ctx) error {
+	u := c.Locals("user").(*user.User)
+	addressBookID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_id"})
+	}
+
+	output, err := h.regenerateTokenUC.Execute(c.Context(), u.ID, uint(addressBookID))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(output)
+}
+
+// PUT /api/v1/addressbooks/:id/public/options
+func (h *AddressBookPublicHandler) UpdateOptions(c fiber.Ctx) error {
+	u := c.Locals("user").(*user.User)
+	addressBookID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_id"})
+	}
+
+	var req addressbook.PublicOptionsInput
+	if err := c.Bind().JSON(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_request"})
+	}
+
+	output, err := h.updateOptionsUC.Execute(c.Context(), u.ID, uint(addressBookID), req)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(output)
+}
@@ -0,0 +1,80 @@
+package http
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/jherrma/caldav-server/internal/adapter/http/dto"
+	authusecase "github.com/jherrma/caldav-server/internal/usecase/auth"
+	"github.com/jherrma/caldav-server/internal/usecase/directory"
+)
+
+type AdminHandler struct {
+	unlockUC  *authusecase.UnlockAccountUseCase
+	directory *directory.Service
+}
+
+func NewAdminHandler(unlockUC *authusecase.UnlockAccountUseCase, dir *directory.Service) *AdminHandler {
+	return &AdminHandler{unlockUC: unlockUC, directory: dir}
+}
+
+// UnlockUser godoc
+// @Summary      Unlock a user
+// @Description  Clear failed login counters and lockouts for a user's account and DAV credentials
+// @Tags         Admin
+// @Produce      json
+// @Param        id   path      string  true  "User UUID"
+// @Success      200  {object}  dto.UnlockUserResponse
+// @Failure      401  {object}  ErrorResponseBody
+// @Failure      403  {object}  ErrorResponseBody  "Not an administrator"
+// @Failure      404  {object}  ErrorResponseBody  "User not found"
+// @Security     BearerAuth
+// @Router       /admin/users/{id}/unlock [post]
+func (h *AdminHandler) UnlockUser(c fiber.Ctx) error {
+	adminID, err := GetUserIDFromContext(c)
+	if err != nil {
+		return UnauthorizedResponse(c, "Unauthorized")
+	}
+
+	cleared, err := h.unlockUC.Execute(c.Context(), adminID, c.Params("id"), c.IP(), c.Get("User-Agent"))
+	if err != nil {
+		if errors.Is(err, authusecase.ErrUserNotFound) {
+			return ErrorResponse(c, fiber.StatusNotFound, "User not found")
+		}
+		return ErrorResponse(c, fiber.StatusInternalServerError, "Failed to unlock user")
+	}
+
+	return SuccessResponse(c, dto.UnlockUserResponse{Cleared: cleared})
+}
+
+// SetDirectoryVisibility godoc
+// @Summary      Set directory visibility
+// @Description  Hide a user from the directory (global address list) or list them again
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                          true  "User UUID"
+// @Param        request  body      dto.DirectoryVisibilityRequest  true  "Visibility"
+// @Success      200      {object}  dto.DirectoryVisibilityResponse
+// @Failure      400      {object}  ErrorResponseBody
+// @Failure      401      {object}  ErrorResponseBody
+// @Failure      403      {object}  ErrorResponseBody  "Not an administrator"
+// @Failure      404      {object}  ErrorResponseBody  "User not found"
+// @Security     BearerAuth
+// @Router       /admin/users/{id}/directory [patch]
+func (h *AdminHandler) SetDirectoryVisibility(c fiber.Ctx) error {
+	var req dto.DirectoryVisibilityRequest
+	if err := c.Bind().JSON(&req); err != nil || req.Hidden == nil {
+		return BadRequestResponse(c, "hidden is required")
+	}
+
+	u, err := h.directory.SetHidden(c.Context(), c.Params("id"), *req.Hidden)
+	if err != nil {
+		if errors.Is(err, directory.ErrUserNotFound) {
+			return ErrorResponse(c, fiber.StatusNotFound, "User not found")
+		}
+		return ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update directory visibility")
+	}
+
+	return SuccessResponse(c, dto.DirectoryVisibilityResponse{ID: u.UUID, Hidden: u.DirectoryHidden})
+}
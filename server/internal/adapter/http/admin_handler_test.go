@@ -0,0 +1,90 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/jherrma/caldav-server/internal/adapter/http/dto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminUnlockUser(t *testing.T) {
+	app, _, _ := setupTestApp(t)
+
+	register := func(email string) string {
+		body, _ := json.Marshal(map[string]string{"email": email, "password": "Password123!", "display_name": "Test"})
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/register", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		var respData struct {
+			Data dto.RegisterResponse `json:"data"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&respData))
+		return respData.Data.ID
+	}
+	login := func(email, password string) *http.Response {
+		body, _ := json.Marshal(map[string]string{"email": email, "password": password})
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp
+	}
+	unlock := func(accessToken, userID string) *http.Response {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/users/"+userID+"/unlock", nil)
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	register("admin@example.com")
+	victimID := register("victim@example.com")
+
+	admin := loginForTest(t, app, "admin@example.com", "Password123!", "test")
+	assert.True(t, admin.User.IsAdmin, "first registered user should be admin")
+	victim := loginForTest(t, app, "victim@example.com", "Password123!", "test")
+	assert.False(t, victim.User.IsAdmin)
+
+	// Lock the victim: setupTestApp locks after 5 failures
+	for i := 0; i < 5; i++ {
+		resp := login("victim@example.com", "wrong-password")
+		if i < 4 {
+			assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+		}
+	}
+	resp := login("victim@example.com", "Password123!")
+	assert.Equal(t, fiber.StatusTooManyRequests, resp.StatusCode)
+	assert.NotEmpty(t, resp.Header.Get("Retry-After"))
+
+	t.Run("Non-admin is forbidden", func(t *testing.T) {
+		resp := unlock(victim.AccessToken, victimID)
+		assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+	})
+
+	t.Run("Unknown user", func(t *testing.T) {
+		resp := unlock(admin.AccessToken, "00000000-0000-0000-0000-000000000000")
+		assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("Admin unlocks the account", func(t *testing.T) {
+		resp := unlock(admin.AccessToken, victimID)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		var respData struct {
+			Data dto.UnlockUserResponse `json:"data"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&respData))
+		assert.Equal(t, int64(1), respData.Data.Cleared)
+
+		assert.Equal(t, fiber.StatusOK, login("victim@example.com", "Password123!").StatusCode)
+	})
+}
@@ -112,6 +112,7 @@ func (h *AuthHandler) Verify(c fiber.Ctx) error {
 // @Success      200      {object}  dto.LoginResponse
 // @Failure      400      {object}  ErrorResponseBody
 // @Failure      401      {object}  ErrorResponseBody  "Invalid credentials"
+// @Failure      429      {object}  ErrorResponseBody  "Too many failed attempts; see Retry-After"
 // @Router       /auth/login [post]
 func (h *AuthHandler) Login(c fiber.Ctx) error {
 	var req dto.LoginRequest
@@ -124,6 +125,10 @@ func (h *AuthHandler) Login(c fiber.Ctx) error {
 		if err == authusecase.ErrInvalidCredentials || err == authusecase.ErrInactiveAccount {
 			return UnauthorizedResponse(c, err.Error())
 		}
+		var locked *authusecase.LockedError
+		if errors.As(err, &locked) {
+			return TooManyRequestsResponse(c, locked.Error(), locked.RetryAfterSeconds())
+		}
 		return ErrorResponse(c, fiber.StatusInternalServerError, "Internal server error")
 	}
 
@@ -136,18 +141,21 @@ func (h *AuthHandler) Login(c fiber.Ctx) error {
 			ID:          res.User.UUID,
 			Email:       res.User.Email,
 			DisplayName: res.User.DisplayName,
+			IsAdmin:     res.User.IsAdmin,
 		},
 	})
 }
 
 // Refresh godoc
 // @Summary      Refresh access token
-// @Description  Get a new access token using refresh token
+// @Description  Get a new access token using refresh token. The refresh token is rotated: the
+// @Description  response contains a new refresh token and the presented one stops working.
+// @Description  Reusing a rotated refresh token revokes the whole session.
 // @Tags         Authentication
 // @Accept       json
 // @Produce      json
 // @Param        request  body      object{refresh_token=string}  true  "Refresh token"
-// @Success      200      {object}  object{access_token=string,token_type=string,expires_at=int}
+// @Success      200      {object}  object{access_token=string,refresh_token=string,token_type=string,expires_at=int}
 // @Failure      400      {object}  ErrorResponseBody
 // @Failure      401      {object}  ErrorResponseBody  "Invalid refresh token"
 // @Router       /auth/refresh [post]
@@ -159,15 +167,16 @@ func (h *AuthHandler) Refresh(c fiber.Ctx) error {
 		return BadRequestResponse(c, "Invalid request body")
 	}
 
-	res, err := h.refreshUC.Execute(c.Context(), req.RefreshToken)
+	res, err := h.refreshUC.Execute(c.Context(), req.RefreshToken, c.Get("User-Agent"), c.IP())
 	if err != nil {
 		return UnauthorizedResponse(c, "Invalid or expired refresh token")
 	}
 
 	return SuccessResponse(c, fiber.Map{
-		"access_token": res.AccessToken,
-		"token_type":   "Bearer",
-		"expires_at":   res.ExpiresAt.Unix(),
+		"access_token":  res.AccessToken,
+		"refresh_token": res.RefreshToken,
+		"token_type":    "Bearer",
+		"expires_at":    res.ExpiresAt.Unix(),
 	})
 }
 
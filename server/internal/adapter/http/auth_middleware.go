@@ -3,14 +3,19 @@ package http
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v3"
 	authadapter "github.com/jherrma/caldav-server/internal/adapter/auth"
+	"github.com/jherrma/caldav-server/internal/domain/oauthserver"
 	"github.com/jherrma/caldav-server/internal/domain/user"
 )
 
-// Authenticate returns a Fiber middleware that validates JWT tokens
-func Authenticate(jwtManager user.TokenProvider, userRepo user.UserRepository) fiber.Handler {
+// Authenticate returns a Fiber middleware that validates JWT tokens. Access
+// tokens issued to third-party clients by the built-in authorization server
+// are accepted too, limited to the endpoints their scopes cover; pass a nil
+// oauthTokens repository to reject them.
+func Authenticate(jwtManager user.TokenProvider, userRepo user.UserRepository, oauthTokens oauthserver.TokenRepository) fiber.Handler {
 	return func(c fiber.Ctx) error {
 		authHeader := c.Get("Authorization")
 		if authHeader == "" {
@@ -22,6 +27,10 @@ func Authenticate(jwtManager user.TokenProvider, userRepo user.UserRepository) f
 			return UnauthorizedResponse(c, "invalid authentication header format")
 		}
 
+		if strings.HasPrefix(parts[1], oauthserver.AccessTokenPrefix) {
+			return authenticateOAuthToken(c, parts[1], userRepo, oauthTokens)
+		}
+
 		userUUID, email, err := jwtManager.ValidateAccessToken(parts[1])
 		if err != nil {
 			if err == authadapter.ErrExpiredToken {
@@ -49,6 +58,39 @@ func Authenticate(jwtManager user.TokenProvider, userRepo user.UserRepository) f
 	}
 }
 
+// authenticateOAuthToken validates an access token issued by the built-in
+// authorization server and checks its scopes against the request
+func authenticateOAuthToken(c fiber.Ctx, token string, userRepo user.UserRepository, oauthTokens oauthserver.TokenRepository) error {
+	if oauthTokens == nil {
+		return UnauthorizedResponse(c, "invalid or expired token")
+	}
+
+	t, err := oauthTokens.GetByAccessHash(c.Context(), oauthserver.HashToken(token))
+	if err != nil || t == nil || !t.AccessActive(time.Now()) {
+		c.Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		return UnauthorizedResponse(c, "invalid or expired token")
+	}
+
+	scope, ok := requiredOAuthScope(c.Method(), c.Path())
+	if !ok || !oauthserver.Allows(t.ScopeList(), scope) {
+		return insufficientScopeResponse(c, scope)
+	}
+
+	u, err := userRepo.GetByID(c.Context(), t.UserID)
+	if err != nil || u == nil {
+		return UnauthorizedResponse(c, "user not found")
+	}
+
+	c.Locals("user_uuid", u.UUID)
+	c.Locals("user_email", u.Email)
+	c.Locals("user_id", u.ID)
+	c.Locals("user", u)
+	c.Locals("oauth_client_id", t.ClientID)
+	c.Locals("oauth_scopes", t.ScopeList())
+
+	return c.Next()
+}
+
 // GetUserIDFromContext retrieves the user ID from the fiber context
 func GetUserIDFromContext(c fiber.Ctx) (uint, error) {
 	userID, ok := c.Locals("user_id").(uint)
@@ -57,3 +99,15 @@ func GetUserIDFromContext(c fiber.Ctx) (uint, error) {
 	}
 	return userID, nil
 }
+
+// RequireAdmin returns a Fiber middleware that only lets administrators
+// through. It must run after Authenticate.
+func RequireAdmin() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		u, ok := c.Locals("user").(*user.User)
+		if !ok || u == nil || !u.IsAdmin {
+			return ForbiddenResponse(c, "administrator access required")
+		}
+		return c.Next()
+	}
+}
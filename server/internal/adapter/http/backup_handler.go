@@ -2,6 +2,7 @@ package http
 
 import (
 	"github.com/gofiber/fiber/v3"
+	"github.com/jherrma/caldav-server/internal/domain/addressbook"
 	"github.com/jherrma/caldav-server/internal/usecase/importexport"
 )
 
@@ -15,14 +16,20 @@ func NewBackupHandler(backupExportUC *importexport.BackupExportUseCase) *BackupH
 	return &BackupHandler{backupExportUC: backupExportUC}
 }
 
-// Export handles GET /api/v1/users/me/export
+// Export handles GET /api/v1/users/me/export. The optional version query
+// parameter converts all contacts to vCard 3.0 or 4.0.
 func (h *BackupHandler) Export(c fiber.Ctx) error {
 	userID, err := GetUserIDFromContext(c)
 	if err != nil {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
 	}
 
-	data, filename, err := h.backupExportUC.Execute(c.Context(), userID)
+	version := c.Query("version")
+	if version != "" && !addressbook.IsSupportedVCardVersion(version) {
+		return ErrorResponse(c, fiber.StatusBadRequest, "version must be 3.0 or 4.0")
+	}
+
+	data, filename, err := h.backupExportUC.Execute(c.Context(), userID, version)
 	if err != nil {
 		return ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
 	}
@@ -0,0 +1,138 @@
+package http
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/jherrma/caldav-server/internal/adapter/http/dto"
+	"github.com/jherrma/caldav-server/internal/usecase/birthday"
+)
+
+// BirthdayHandler serves the birthday calendar over the REST API
+type BirthdayHandler struct {
+	birthdays *birthday.Service
+}
+
+func NewBirthdayHandler(birthdays *birthday.Service) *BirthdayHandler {
+	return &BirthdayHandler{birthdays: birthdays}
+}
+
+// Get godoc
+// @Summary      Get birthday calendar
+// @Description  Get the settings of the read-only calendar generated from the birthdays and
+// @Description  anniversaries of the user's contacts, with its number of events and sync token.
+// @Tags         Birthdays
+// @Produce      json
+// @Success      200  {object}  birthday.SettingsOutput
+// @Failure      401  {object}  ErrorResponseBody
+// @Failure      500  {object}  ErrorResponseBody
+// @Security     BearerAuth
+// @Router       /birthdays [get]
+func (h *BirthdayHandler) Get(c fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	output, err := h.birthdays.Describe(c.Context(), userID)
+	if err != nil {
+		return ErrorResponse(c, fiber.StatusInternalServerError, "Failed to load birthday calendar")
+	}
+	return c.JSON(output)
+}
+
+// Update godoc
+// @Summary      Update birthday calendar
+// @Description  Show or hide the birthday calendar, rename it, or set the reminder added to every
+// @Description  event: reminder_days_before days before the date at reminder_time (HH:MM).
+// @Tags         Birthdays
+// @Accept       json
+// @Produce      json
+// @Param        settings  body      birthday.UpdateSettingsInput  true  "Settings"
+// @Success      200       {object}  birthday.SettingsOutput
+// @Failure      400       {object}  ErrorResponseBody
+// @Failure      401       {object}  ErrorResponseBody
+// @Failure      500       {object}  ErrorResponseBody
+// @Security     BearerAuth
+// @Router       /birthdays [patch]
+func (h *BirthdayHandler) Update(c fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	var req birthday.UpdateSettingsInput
+	if err := c.Bind().JSON(&req); err != nil {
+		return ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	output, err := h.birthdays.UpdateSettings(c.Context(), userID, req)
+	if err != nil {
+		if errors.Is(err, birthday.ErrInvalidSettings) {
+			return ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+		}
+		return ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update birthday calendar")
+	}
+	return c.JSON(output)
+}
+
+// Events godoc
+// @Summary      List birthdays
+// @Description  List the birthdays and anniversaries of the user's contacts between start and end,
+// @Description  ordered by date. Without a range the next 365 days are listed.
+// @Tags         Birthdays
+// @Produce      json
+// @Param        start  query     string  false  "Start time (RFC3339)"
+// @Param        end    query     string  false  "End time (RFC3339)"
+// @Success      200    {object}  dto.EventListResponse
+// @Failure      400    {object}  ErrorResponseBody
+// @Failure      404    {object}  ErrorResponseBody  "Birthday calendar disabled"
+// @Failure      500    {object}  ErrorResponseBody
+// @Security     BearerAuth
+// @Router       /birthdays/events [get]
+func (h *BirthdayHandler) Events(c fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	now := time.Now().UTC()
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if s := c.Query("start"); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return BadRequestResponse(c, "Invalid start time format")
+		}
+		start = t
+	}
+	end := start.AddDate(1, 0, 0)
+	if s := c.Query("end"); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return BadRequestResponse(c, "Invalid end time format")
+		}
+		end = t
+	}
+
+	instances, err := h.birthdays.Instances(c.Context(), userID, start, end)
+	if err != nil {
+		if errors.Is(err, birthday.ErrDisabled) {
+			return ErrorResponse(c, fiber.StatusNotFound, "Birthday calendar is disabled")
+		}
+		return ErrorResponse(c, fiber.StatusInternalServerError, "Failed to list birthdays")
+	}
+
+	events := make([]dto.EventResponse, len(instances))
+	for i, inst := range instances {
+		rid := inst.RecurrenceID
+		events[i] = dto.EventResponse{
+			ID:           inst.ID,
+			CalendarID:   inst.CalendarID,
+			UID:          inst.UID,
+			Summary:      inst.Summary,
+			Description:  inst.Description,
+			Start:        inst.Start,
+			End:          inst.End,
+			IsAllDay:     inst.IsAllDay,
+			IsRecurring:  true,
+			RecurrenceID: &rid,
+		}
+	}
+
+	return c.JSON(dto.EventListResponse{
+		Events: events,
+		Count:  len(events),
+	})
+}
@@ -0,0 +1,103 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/jherrma/caldav-server/internal/adapter/http/dto"
+	"github.com/jherrma/caldav-server/internal/adapter/repository"
+	"github.com/jherrma/caldav-server/internal/domain/addressbook"
+	"github.com/jherrma/caldav-server/internal/usecase/birthday"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBirthdayHandler(t *testing.T) {
+	app, db, _ := setupTestApp(t)
+	ctx := context.Background()
+
+	body, _ := json.Marshal(map[string]string{"email": "bday@example.com", "password": "Password123!", "display_name": "Bday User"})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/register", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	login := loginForTest(t, app, "bday@example.com", "Password123!", "test")
+
+	u, err := repository.NewUserRepository(db.DB()).GetByUUID(ctx, login.User.ID)
+	require.NoError(t, err)
+	abRepo := repository.NewAddressBookRepository(db.DB())
+	book := &addressbook.AddressBook{UUID: "bday-ab", UserID: u.ID, Path: "bday-contacts", Name: "Contacts"}
+	book.UpdateSyncTokens()
+	require.NoError(t, abRepo.Create(ctx, book))
+	obj := &addressbook.AddressObject{
+		UUID:          "erin-uuid",
+		AddressBookID: book.ID,
+		Path:          "erin.vcf",
+		UID:           "erin",
+		ETag:          "1",
+		VCardData:     "BEGIN:VCARD\r\nVERSION:3.0\r\nUID:erin\r\nFN:Erin\r\nBDAY:1992-08-15\r\nEND:VCARD\r\n",
+		VCardVersion:  addressbook.VCardVersion3,
+	}
+	require.NoError(t, obj.PopulateDenormFieldsFromVCard())
+	require.NoError(t, abRepo.CreateObject(ctx, obj))
+
+	do := func(method, target string, body any) *http.Response {
+		var data []byte
+		if body != nil {
+			data, _ = json.Marshal(body)
+		}
+		req := httptest.NewRequest(method, target, bytes.NewReader(data))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+login.AccessToken)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	t.Run("Settings", func(t *testing.T) {
+		resp := do(http.MethodGet, "/api/v1/birthdays", nil)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		var out birthday.SettingsOutput
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+		assert.True(t, out.Enabled)
+		assert.Equal(t, 1, out.EventCount)
+		assert.Equal(t, "birthdays", out.Path)
+
+		resp = do(http.MethodPatch, "/api/v1/birthdays", map[string]any{"name": "Geburtstage", "reminder_enabled": true, "reminder_time": "08:30"})
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+		assert.Equal(t, "Geburtstage", out.Name)
+		assert.Equal(t, "08:30", out.ReminderTime)
+
+		resp = do(http.MethodPatch, "/api/v1/birthdays", map[string]any{"reminder_days_before": 99})
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("Events", func(t *testing.T) {
+		resp := do(http.MethodGet, "/api/v1/birthdays/events?start=2030-01-01T00:00:00Z&end=2031-01-01T00:00:00Z", nil)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		var out dto.EventListResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+		require.Equal(t, 1, out.Count)
+		assert.Equal(t, "Erin's birthday", out.Events[0].Summary)
+		assert.Equal(t, "2030-08-15", out.Events[0].Start.Format("2006-01-02"))
+		assert.True(t, out.Events[0].IsAllDay)
+
+		resp = do(http.MethodGet, "/api/v1/birthdays/events?start=tomorrow", nil)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		resp := do(http.MethodPatch, "/api/v1/birthdays", map[string]bool{"enabled": false})
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		resp = do(http.MethodGet, "/api/v1/birthdays/events", nil)
+		assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/gofiber/fiber/v3"
 	domaincalendar "github.com/jherrma/caldav-server/internal/domain/calendar"
 	calendaruc "github.com/jherrma/caldav-server/internal/usecase/calendar"
+	"github.com/jherrma/caldav-server/internal/usecase/event"
 )
 
 var _ = domaincalendar.Calendar{}
@@ -16,6 +17,7 @@ type CalendarHandler struct {
 	updateUC *calendaruc.UpdateCalendarUseCase
 	deleteUC *calendaruc.DeleteCalendarUseCase
 	exportUC *calendaruc.ExportCalendarUseCase
+	dataUC   *calendaruc.ExportCalendarDataUseCase
 }
 
 // NewCalendarHandler creates a new calendar handler
@@ -26,6 +28,7 @@ func NewCalendarHandler(
 	updateUC *calendaruc.UpdateCalendarUseCase,
 	deleteUC *calendaruc.DeleteCalendarUseCase,
 	exportUC *calendaruc.ExportCalendarUseCase,
+	dataUC *calendaruc.ExportCalendarDataUseCase,
 ) *CalendarHandler {
 	return &CalendarHandler{
 		createUC: createUC,
@@ -34,6 +37,7 @@ func NewCalendarHandler(
 		updateUC: updateUC,
 		deleteUC: deleteUC,
 		exportUC: exportUC,
+		dataUC:   dataUC,
 	}
 }
 
@@ -113,7 +117,7 @@ func (h *CalendarHandler) Get(c fiber.Ctx) error {
 
 // Update godoc
 // @Summary      Update calendar
-// @Description  Update a calendar's properties
+// @Description  Update a calendar's properties. For a calendar shared with the user only the name, color and hidden flag can be set; they apply to that user alone.
 // @Tags         Calendars
 // @Accept       json
 // @Produce      json
@@ -169,13 +173,24 @@ func (h *CalendarHandler) Delete(c fiber.Ctx) error {
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
+// exportFormats maps the format query of an export to its media type
+var exportFormats = map[string]string{
+	"ics":        "text/calendar",
+	"jcal":       event.MediaTypeJCal,
+	"jscalendar": event.MediaTypeJSCalendar,
+}
+
 // Export godoc
 // @Summary      Export calendar as iCalendar
-// @Description  Download calendar as .ics file
+// @Description  Download calendar as .ics file. With format=jcal or format=jscalendar, or an Accept
+// @Description  header of application/calendar+json or application/jscalendar+json, the calendar is
+// @Description  exported as one jCal vcalendar or as a JSCalendar Group named after the calendar.
 // @Tags         Import/Export
-// @Produce      text/calendar
-// @Param        id  path      string  true  "Calendar UUID"
+// @Produce      text/calendar,application/calendar+json,application/jscalendar+json
+// @Param        id      path      string  true   "Calendar UUID"
+// @Param        format  query     string  false  "Export format (ics, jcal, jscalendar)"
 // @Success      200  {file}    file
+// @Failure      400  {object}  ErrorResponseBody
 // @Failure      401  {object}  ErrorResponseBody
 // @Failure      404  {object}  ErrorResponseBody
 // @Security     BearerAuth
@@ -184,6 +199,19 @@ func (h *CalendarHandler) Export(c fiber.Ctx) error {
 	userID := c.Locals("user_id").(uint)
 	calendarUUID := c.Params("id")
 
+	mediaType := "text/calendar"
+	if format := c.Query("format"); format != "" {
+		var ok bool
+		if mediaType, ok = exportFormats[format]; !ok {
+			return BadRequestResponse(c, "Unsupported export format")
+		}
+	} else if t := c.Accepts("text/calendar", event.MediaTypeJCal, event.MediaTypeJSCalendar); t != "" {
+		mediaType = t
+	}
+	if mediaType != "text/calendar" {
+		return h.exportData(c, userID, calendarUUID, mediaType)
+	}
+
 	content, filename, err := h.exportUC.Execute(c.Context(), userID, calendarUUID)
 	if err != nil {
 		return ErrorResponse(c, fiber.StatusNotFound, "Calendar not found")
@@ -194,3 +222,25 @@ func (h *CalendarHandler) Export(c fiber.Ctx) error {
 
 	return c.SendString(content)
 }
+
+// exportData writes a calendar export as jCal or JSCalendar
+func (h *CalendarHandler) exportData(c fiber.Ctx, userID uint, calendarUUID, mediaType string) error {
+	out, err := h.dataUC.Execute(c.Context(), userID, calendarUUID)
+	if err != nil {
+		return ErrorResponse(c, fiber.StatusNotFound, "Calendar not found")
+	}
+
+	var body interface{}
+	if mediaType == event.MediaTypeJSCalendar {
+		group := event.ToJSCalendarGroup(out.Data)
+		group.UID = out.Calendar.UUID
+		group.Title = out.Calendar.Name
+		group.Description = out.Calendar.Description
+		body = group
+	} else {
+		body = event.ToJCal(out.Data.Component)
+	}
+
+	c.Set("Content-Disposition", `attachment; filename="`+out.Filename+`.json"`)
+	return c.JSON(body, mediaType)
+}
@@ -12,6 +12,7 @@ import (
 	authadapter "github.com/jherrma/caldav-server/internal/adapter/auth"
 	"github.com/jherrma/caldav-server/internal/adapter/repository"
 	"github.com/jherrma/caldav-server/internal/domain/calendar"
+	"github.com/jherrma/caldav-server/internal/domain/sharing"
 	"github.com/jherrma/caldav-server/internal/domain/user"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -208,4 +209,141 @@ func TestCalendarHandler_Export(t *testing.T) {
 		// Content-Disposition should be attachment
 		assert.Contains(t, resp.Header.Get("Content-Disposition"), "attachment")
 	})
+
+	// Imported objects hold a bare VEVENT block
+	err = calRepo.CreateCalendarObject(context.Background(), &calendar.CalendarObject{
+		UUID:          "export-event-uuid",
+		CalendarID:    cal.ID,
+		Path:          "export-event.ics",
+		UID:           "export-event",
+		ETag:          `"1"`,
+		ComponentType: "VEVENT",
+		ICalData: "BEGIN:VEVENT\r\nUID:export-event\r\nDTSTAMP:20240101T000000Z\r\n" +
+			"DTSTART:20240102T100000Z\r\nDTEND:20240102T110000Z\r\nSUMMARY:Exported\r\n" +
+			"ATTENDEE;PARTSTAT=ACCEPTED:mailto:bob@example.com\r\nEND:VEVENT\r\n",
+	})
+	require.NoError(t, err)
+
+	t.Run("jCal via format", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/calendars/"+cal.UUID+"/export?format=jcal", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Equal(t, "application/calendar+json", resp.Header.Get("Content-Type"))
+		assert.Contains(t, resp.Header.Get("Content-Disposition"), "Export Calendar.json")
+
+		var doc []interface{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&doc))
+		assert.Equal(t, "vcalendar", doc[0])
+		assert.Contains(t, doc[1], []interface{}{"x-wr-calname", map[string]interface{}{}, "text", "Export Calendar"})
+		require.Len(t, doc[2], 1)
+		assert.Equal(t, "vevent", doc[2].([]interface{})[0].([]interface{})[0])
+	})
+
+	t.Run("JSCalendar via Accept", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/calendars/"+cal.UUID+"/export", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Accept", "application/jscalendar+json")
+
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		var group map[string]interface{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&group))
+		assert.Equal(t, "Group", group["@type"])
+		assert.Equal(t, cal.UUID, group["uid"])
+		assert.Equal(t, "Export Calendar", group["title"])
+		entries := group["entries"].([]interface{})
+		require.Len(t, entries, 1)
+		entry := entries[0].(map[string]interface{})
+		assert.Equal(t, "Exported", entry["title"])
+		assert.Len(t, entry["participants"], 1)
+	})
+
+	t.Run("Unknown format", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/calendars/"+cal.UUID+"/export?format=xml", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+func TestCalendarHandler_SharedProperties(t *testing.T) {
+	app, db, cfg := setupTestApp(t)
+	ctx := context.Background()
+	userRepo := repository.NewUserRepository(db.DB())
+	calRepo := repository.NewCalendarRepository(db.DB())
+	jwtManager := authadapter.NewJWTManager(&cfg.JWT)
+
+	owner := &user.User{Email: "owner@example.com", Username: "owner", PasswordHash: "hash", IsActive: true, EmailVerified: true, UUID: "owner-uuid"}
+	sharee := &user.User{Email: "sharee@example.com", Username: "sharee", PasswordHash: "hash", IsActive: true, EmailVerified: true, UUID: "sharee-uuid"}
+	require.NoError(t, userRepo.Create(ctx, owner))
+	require.NoError(t, userRepo.Create(ctx, sharee))
+	cal := &calendar.Calendar{UUID: "shared-cal", UserID: owner.ID, Name: "Team", Path: "default", Color: "#112233"}
+	require.NoError(t, calRepo.Create(ctx, cal))
+	require.NoError(t, repository.NewCalendarShareRepository(db.DB()).Create(ctx, &sharing.CalendarShare{
+		UUID: "share-uuid", CalendarID: cal.ID, SharedWithID: &sharee.ID, Permission: sharing.PermissionRead, Status: sharing.ShareStatusAccepted,
+	}))
+
+	token, _, err := jwtManager.GenerateAccessToken(sharee.UUID, sharee.Email)
+	require.NoError(t, err)
+	patch := func(payload map[string]any) *http.Response {
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(http.MethodPatch, "/api/v1/calendars/"+cal.UUID, bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp
+	}
+	list := func() map[string]any {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/calendars", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		var out struct {
+			Calendars []map[string]any `json:"calendars"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+		for _, c := range out.Calendars {
+			if c["uuid"] == cal.UUID {
+				return c
+			}
+		}
+		return nil
+	}
+
+	shared := list()
+	require.NotNil(t, shared)
+	assert.Equal(t, "owner-default", shared["path"])
+	assert.Equal(t, "Team", shared["name"])
+
+	resp := patch(map[string]any{"name": "Owner's team", "color": "#00ff00", "hidden": true})
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	shared = list()
+	assert.Equal(t, "Owner's team", shared["name"])
+	assert.Equal(t, "#00ff00", shared["color"])
+	assert.Equal(t, true, shared["hidden"])
+
+	// The owner's calendar is untouched
+	stored, err := calRepo.GetByUUID(ctx, cal.UUID)
+	require.NoError(t, err)
+	assert.Equal(t, "Team", stored.Name)
+	assert.Equal(t, "#112233", stored.Color)
+
+	resp = patch(map[string]any{"description": "Mine now"})
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+
+	// Empty values restore the owner's properties
+	resp = patch(map[string]any{"name": "", "color": "", "hidden": false})
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	shared = list()
+	assert.Equal(t, "Team", shared["name"])
+	assert.Equal(t, "#112233", shared["color"])
+	assert.Nil(t, shared["hidden"])
 }
@@ -0,0 +1,78 @@
+package http
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/jherrma/caldav-server/internal/domain/user"
+	"github.com/jherrma/caldav-server/internal/usecase/sharing"
+)
+
+type CalendarProxyHandler struct {
+	proxiesUC *sharing.CalendarProxiesUseCase
+}
+
+func NewCalendarProxyHandler(proxiesUC *sharing.CalendarProxiesUseCase) *CalendarProxyHandler {
+	return &CalendarProxyHandler{proxiesUC: proxiesUC}
+}
+
+// GrantCalendarProxyRequest makes a user a calendar proxy
+type GrantCalendarProxyRequest struct {
+	UserIdentifier string `json:"user_identifier"` // Email or username
+	Level          string `json:"level"`           // "read" or "write"
+}
+
+// GET /api/v1/calendar-proxies
+func (h *CalendarProxyHandler) List(c fiber.Ctx) error {
+	u := c.Locals("user").(*user.User)
+
+	output, err := h.proxiesUC.List(c.Context(), u.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{"proxies": output})
+}
+
+// GET /api/v1/calendar-proxies/delegations
+func (h *CalendarProxyHandler) ListDelegations(c fiber.Ctx) error {
+	u := c.Locals("user").(*user.User)
+
+	output, err := h.proxiesUC.ListDelegations(c.Context(), u.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{"delegations": output})
+}
+
+// POST /api/v1/calendar-proxies
+func (h *CalendarProxyHandler) Grant(c fiber.Ctx) error {
+	u := c.Locals("user").(*user.User)
+
+	var req GrantCalendarProxyRequest
+	if err := c.Bind().JSON(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_request"})
+	}
+
+	output, err := h.proxiesUC.Grant(c.Context(), u.ID, req.UserIdentifier, req.Level)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(output)
+}
+
+// DELETE /api/v1/calendar-proxies/:id
+func (h *CalendarProxyHandler) Revoke(c fiber.Ctx) error {
+	u := c.Locals("user").(*user.User)
+
+	if err := h.proxiesUC.Revoke(c.Context(), u.ID, c.Params("id")); err != nil {
+		if errors.Is(err, sharing.ErrProxyNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
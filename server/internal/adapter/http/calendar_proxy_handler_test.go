@@ -0,0 +1,78 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/jherrma/caldav-server/internal/usecase/sharing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalendarProxyHandler(t *testing.T) {
+	app, _, _ := setupTestApp(t)
+
+	tokens := make(map[string]string)
+	for _, name := range []string{"exec", "assistant"} {
+		body, _ := json.Marshal(map[string]string{"email": name + "@example.com", "password": "Password123!", "display_name": name})
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/register", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		tokens[name] = loginForTest(t, app, name+"@example.com", "Password123!", "test").AccessToken
+	}
+
+	do := func(as, method, target string, body any) *http.Response {
+		var data []byte
+		if body != nil {
+			data, _ = json.Marshal(body)
+		}
+		req := httptest.NewRequest(method, target, bytes.NewReader(data))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+tokens[as])
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp
+	}
+	list := func(as, target, key string) []sharing.ProxyOutput {
+		resp := do(as, http.MethodGet, target, nil)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		var out map[string][]sharing.ProxyOutput
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+		return out[key]
+	}
+
+	resp := do("exec", http.MethodPost, "/api/v1/calendar-proxies", map[string]string{"user_identifier": "exec@example.com", "level": "write"})
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	resp = do("exec", http.MethodPost, "/api/v1/calendar-proxies", map[string]string{"user_identifier": "assistant@example.com", "level": "admin"})
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+
+	resp = do("exec", http.MethodPost, "/api/v1/calendar-proxies", map[string]string{"user_identifier": "assistant@example.com", "level": "read"})
+	require.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	var proxy sharing.ProxyOutput
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&proxy))
+	assert.Equal(t, "assistant@example.com", proxy.User.Email)
+
+	// Granting again changes the level of the existing proxy
+	resp = do("exec", http.MethodPost, "/api/v1/calendar-proxies", map[string]string{"user_identifier": "assistant@example.com", "level": "write"})
+	require.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	proxies := list("exec", "/api/v1/calendar-proxies", "proxies")
+	require.Len(t, proxies, 1)
+	assert.Equal(t, proxy.ID, proxies[0].ID)
+	assert.Equal(t, "write", proxies[0].Level)
+
+	delegations := list("assistant", "/api/v1/calendar-proxies/delegations", "delegations")
+	require.Len(t, delegations, 1)
+	assert.Equal(t, "exec@example.com", delegations[0].User.Email)
+
+	resp = do("assistant", http.MethodDelete, "/api/v1/calendar-proxies/"+proxy.ID, nil)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+	resp = do("exec", http.MethodDelete, "/api/v1/calendar-proxies/"+proxy.ID, nil)
+	assert.Equal(t, fiber.StatusNoContent, resp.StatusCode)
+	assert.Empty(t, list("assistant", "/api/v1/calendar-proxies/delegations", "delegations"))
+}
@@ -12,17 +12,20 @@ type CalendarPublicHandler struct {
 	enablePublicUC    *calendarusecase.EnablePublicUseCase
 	getPublicStatusUC *calendarusecase.GetPublicStatusUseCase
 	regenerateTokenUC *calendarusecase.RegenerateTokenUseCase
+	updateOptionsUC   *calendarusecase.UpdatePublicFeedOptionsUseCase
 }
 
 func NewCalendarPublicHandler(
 	enablePublicUC *calendarusecase.EnablePublicUseCase,
 	getPublicStatusUC *calendarusecase.GetPublicStatusUseCase,
 	regenerateTokenUC *calendarusecase.RegenerateTokenUseCase,
+	updateOptionsUC *calendarusecase.UpdatePublicFeedOptionsUseCase,
 ) *CalendarPublicHandler {
 	return &CalendarPublicHandler{
 		enablePublicUC:    enablePublicUC,
 		getPublicStatusUC: getPublicStatusUC,
 		regenerateTokenUC: regenerateTokenUC,
+		updateOptionsUC:   updateOptionsUC,
 	}
 }
 
@@ -78,3 +81,24 @@ func (h *CalendarPublicHandler) RegenerateToken(c fiber.Ctx) error {
 
 	return c.JSON(output)
 }
+
+// PUT /api/v1/calendars/:id/public/options
+func (h *CalendarPublicHandler) UpdateOptions(c fiber.Ctx) error {
+	u := c.Locals("user").(*user.User)
+	calendarID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_calendar_id"})
+	}
+
+	var req calendarusecase.PublicFeedOptionsInput
+	if err := c.Bind().JSON(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_request"})
+	}
+
+	output, err := h.updateOptionsUC.Execute(c.Context(), u.ID, uint(calendarID), req)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(output)
+}
@@ -0,0 +1,140 @@
+package http
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/jherrma/caldav-server/internal/domain/contact"
+	contactusecase "github.com/jherrma/caldav-server/internal/usecase/contact"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContactHandler_CardRepresentations(t *testing.T) {
+	app, db, _, ab, token := setupContactHandlerTest(t)
+	defer db.Close()
+
+	base := "/api/v1/addressbooks/" + strconv.Itoa(int(ab.ID)) + "/contacts"
+	do := func(method, url, contentType, accept, body string) *http.Response {
+		req, _ := http.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	jcard := `["vcard",[
+		["version",{},"text","4.0"],
+		["fn",{},"text","Jane Public"],
+		["n",{},"text",["Public","Jane","","",""]],
+		["impp",{"pref":"1"},"uri","xmpp:jane@example.com"],
+		["related",{"type":"friend"},"uri","urn:uuid:friend-1"],
+		["anniversary",{},"date-and-or-time","2010-06-01"],
+		["geo",{},"uri","geo:39.78,-89.65"],
+		["x-custom",{"group":"item1"},"unknown","kept"]
+	]]`
+
+	var contactID string
+
+	t.Run("Create from jCard", func(t *testing.T) {
+		resp := do("POST", base, contactusecase.MediaTypeJCard, "", jcard)
+		require.Equal(t, fiber.StatusCreated, resp.StatusCode)
+		assert.Equal(t, contactusecase.MediaTypeJCard, resp.Header.Get("Content-Type"))
+
+		location := resp.Header.Get("Content-Location")
+		require.True(t, strings.HasPrefix(location, base+"/"))
+		contactID = strings.TrimPrefix(location, base+"/")
+
+		body, _ := io.ReadAll(resp.Body)
+		card, err := contactusecase.FromJCard(body)
+		require.NoError(t, err)
+		assert.Equal(t, "xmpp:jane@example.com", card.Value("IMPP"))
+		assert.Equal(t, "geo:39.78,-89.65", card.Value("GEO"))
+		assert.Equal(t, "item1", card.Get("X-CUSTOM").Group)
+		assert.NotEmpty(t, card.Value("UID"))
+	})
+
+	t.Run("DTO stays the default", func(t *testing.T) {
+		resp := do("GET", base+"/"+contactID, "", "", "")
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		var res contact.Contact
+		json.NewDecoder(resp.Body).Decode(&res)
+		assert.Equal(t, "Jane", res.GivenName)
+	})
+
+	t.Run("Get as JSContact", func(t *testing.T) {
+		resp := do("GET", base+"/"+contactID, "", contactusecase.MediaTypeJSContact, "")
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Equal(t, contactusecase.MediaTypeJSContact, resp.Header.Get("Content-Type"))
+
+		var res contactusecase.JSContactCard
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&res))
+		assert.Equal(t, "Card", res.Type)
+		assert.Equal(t, "Jane Public", res.Name.Full)
+		assert.Equal(t, "xmpp:jane@example.com", res.OnlineServices["s1"].URI)
+		assert.Equal(t, map[string]bool{"friend": true}, res.RelatedTo["urn:uuid:friend-1"].Relation)
+		assert.Len(t, res.Anniversaries, 1)
+	})
+
+	t.Run("Replace from JSContact", func(t *testing.T) {
+		resp := do("GET", base+"/"+contactID, "", contactusecase.MediaTypeJSContact, "")
+		var card contactusecase.JSContactCard
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&card))
+		uid := card.UID
+		card.UID = "ignored"
+		card.Name.Full = "Jane Q. Public"
+		body, _ := json.Marshal(card)
+
+		resp = do("PATCH", base+"/"+contactID, contactusecase.MediaTypeJSContact, "application/json", string(body))
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		var res contact.Contact
+		json.NewDecoder(resp.Body).Decode(&res)
+		assert.Equal(t, "Jane Q. Public", res.FormattedName)
+		assert.Equal(t, uid, res.UID)
+
+		resp = do("GET", base+"/"+contactID, "", contactusecase.MediaTypeJCard, "")
+		raw, _ := io.ReadAll(resp.Body)
+		stored, err := contactusecase.FromJCard(raw)
+		require.NoError(t, err)
+		assert.Equal(t, "geo:39.78,-89.65", stored.Value("GEO"))
+		assert.Equal(t, "kept", stored.Value("X-CUSTOM"))
+	})
+
+	t.Run("List as jCard", func(t *testing.T) {
+		resp := do("GET", base, "", contactusecase.MediaTypeJCard, "")
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Equal(t, "1", resp.Header.Get("X-Total-Count"))
+
+		var cards []json.RawMessage
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&cards))
+		require.Len(t, cards, 1)
+		card, err := contactusecase.FromJCard(cards[0])
+		require.NoError(t, err)
+		assert.Equal(t, "Jane Q. Public", card.Value("FN"))
+	})
+
+	t.Run("Invalid bodies", func(t *testing.T) {
+		resp := do("POST", base, contactusecase.MediaTypeJCard, "", `{"not":"jcard"}`)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+
+		resp = do("PATCH", base+"/"+contactID, contactusecase.MediaTypeJSContact, "", `{"@type":"Group"}`)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("Replace unknown contact", func(t *testing.T) {
+		resp := do("PATCH", base+"/missing", contactusecase.MediaTypeJCard, "", jcard)
+		assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+	})
+}
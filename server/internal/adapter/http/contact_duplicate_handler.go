@@ -0,0 +1,119 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/jherrma/caldav-server/internal/adapter/http/dto"
+	"github.com/jherrma/caldav-server/internal/domain/addressbook"
+	contactuc "github.com/jherrma/caldav-server/internal/usecase/contact"
+)
+
+// ContactDuplicateHandler handles duplicate detection and merging of
+// contacts across the address books of the authenticated user
+type ContactDuplicateHandler struct {
+	findUC          *contactuc.FindDuplicatesUseCase
+	mergeUC         *contactuc.MergeContactsUseCase
+	addressBookRepo addressbook.Repository
+}
+
+func NewContactDuplicateHandler(
+	findUC *contactuc.FindDuplicatesUseCase,
+	mergeUC *contactuc.MergeContactsUseCase,
+	addressBookRepo addressbook.Repository,
+) *ContactDuplicateHandler {
+	return &ContactDuplicateHandler{
+		findUC:          findUC,
+		mergeUC:         mergeUC,
+		addressBookRepo: addressBookRepo,
+	}
+}
+
+// mergeError maps merge use case errors to responses
+func mergeError(c fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, contactuc.ErrMergeContactNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, contactuc.ErrMergeTooFewContacts), errors.Is(err, contactuc.ErrMergeGroup), errors.Is(err, contactuc.ErrMergePhotoSource):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+}
+
+// Find godoc
+// @Summary      Find duplicate contacts
+// @Description  Group contacts that probably describe the same person into clusters, scored on normalized email, phone, name and organization. Searches all address books of the user unless addressbook_id is given.
+// @Tags         Contacts
+// @Produce      json
+// @Param        addressbook_id  query     integer  false  "Address Book ID filter"
+// @Param        min_score       query     number   false  "Minimum pair score between 0 and 1 (default 0.5)"
+// @Success      200             {object}  contactuc.FindDuplicatesOutput
+// @Failure      400             {object}  ErrorResponseBody
+// @Failure      404             {object}  ErrorResponseBody
+// @Failure      500             {object}  ErrorResponseBody
+// @Security     BearerAuth
+// @Router       /contacts/duplicates [get]
+func (h *ContactDuplicateHandler) Find(c fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+	input := contactuc.FindDuplicatesInput{UserID: userID}
+
+	if val := c.Query("addressbook_id"); val != "" {
+		id, err := strconv.ParseUint(val, 10, 32)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid addressbook ID"})
+		}
+		ab, err := h.addressBookRepo.GetByID(c.Context(), uint(id))
+		if err != nil || ab == nil || ab.UserID != userID {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "not_found"})
+		}
+		abID := uint(id)
+		input.AddressBookID = &abID
+	}
+	if val := c.Query("min_score"); val != "" {
+		score, err := strconv.ParseFloat(val, 64)
+		if err != nil || score <= 0 || score > 1 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "min_score must be a number between 0 and 1"})
+		}
+		input.MinScore = score
+	}
+
+	output, err := h.findUC.Execute(c.Context(), input)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(output)
+}
+
+// Merge godoc
+// @Summary      Merge duplicate contacts
+// @Description  Fold contacts into a target contact and delete them. Multi-value properties are unioned, notes concatenated and one photo kept. The contacts may live in different address books of the user.
+// @Tags         Contacts
+// @Accept       json
+// @Produce      json
+// @Param        merge  body      dto.MergeContactsRequest  true  "Contacts to merge"
+// @Success      200    {object}  contact.Contact
+// @Failure      400    {object}  ErrorResponseBody
+// @Failure      404    {object}  ErrorResponseBody
+// @Failure      500    {object}  ErrorResponseBody
+// @Security     BearerAuth
+// @Router       /contacts/duplicates/merge [post]
+func (h *ContactDuplicateHandler) Merge(c fiber.Ctx) error {
+	var req dto.MergeContactsRequest
+	if err := json.Unmarshal(c.Body(), &req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+	}
+
+	merged, err := h.mergeUC.Execute(c.Context(), contactuc.MergeContactsInput{
+		UserID:     c.Locals("user_id").(uint),
+		TargetID:   req.TargetID,
+		ContactIDs: req.ContactIDs,
+		PhotoFrom:  req.PhotoFrom,
+	})
+	if err != nil {
+		return mergeError(c, err)
+	}
+	return c.JSON(merged)
+}
@@ -0,0 +1,157 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/jherrma/caldav-server/internal/adapter/repository"
+	"github.com/jherrma/caldav-server/internal/domain/addressbook"
+	"github.com/jherrma/caldav-server/internal/domain/contact"
+	contactuc "github.com/jherrma/caldav-server/internal/usecase/contact"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContactDuplicateHandler(t *testing.T) {
+	app, db, u, ab1, token := setupContactHandlerTest(t)
+	defer db.Close()
+	abRepo := repository.NewAddressBookRepository(db.DB())
+
+	ab2 := &addressbook.AddressBook{
+		UUID:      uuid.New().String(),
+		UserID:    u.ID,
+		Name:      "Phone",
+		Path:      "phone",
+		SyncToken: "1",
+		CTag:      "1",
+	}
+	require.NoError(t, abRepo.Create(context.Background(), ab2))
+
+	do := func(method, path string, body interface{}) *http.Response {
+		b, _ := json.Marshal(body)
+		req, _ := http.NewRequest(method, "/api/v1"+path, bytes.NewReader(b))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp
+	}
+	createContact := func(ab *addressbook.AddressBook, c contact.Contact) contact.Contact {
+		resp := do("POST", "/addressbooks/"+strconv.Itoa(int(ab.ID))+"/contacts", c)
+		require.Equal(t, fiber.StatusCreated, resp.StatusCode)
+		var out contact.Contact
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+		return out
+	}
+
+	outlook := createContact(ab1, contact.Contact{
+		GivenName: "Jane", FamilyName: "Public", Organization: "ACME Inc.",
+		Emails: []contact.Email{{Type: "work", Value: "jane@acme.example"}},
+		Notes:  "Met at the conference",
+	})
+	phone := createContact(ab2, contact.Contact{
+		GivenName: "Jane", FamilyName: "Public",
+		Emails: []contact.Email{{Type: "work", Value: "Jane@ACME.example"}, {Type: "home", Value: "jane@home.example"}},
+		Phones: []contact.Phone{{Type: "cell", Value: "+49 170 1234567"}},
+		Notes:  "Prefers phone calls",
+	})
+	csv := createContact(ab1, contact.Contact{
+		FormattedName: "J. Public",
+		Phones:        []contact.Phone{{Type: "cell", Value: "0170/1234567"}},
+	})
+	other := createContact(ab1, contact.Contact{GivenName: "John", FamilyName: "Doe"})
+
+	groupResp := do("POST", "/addressbooks/"+strconv.Itoa(int(ab1.ID))+"/groups", map[string]interface{}{
+		"name": "Customers", "member_ids": []string{csv.ID},
+	})
+	require.Equal(t, fiber.StatusCreated, groupResp.StatusCode)
+	var group contact.Group
+	require.NoError(t, json.NewDecoder(groupResp.Body).Decode(&group))
+
+	t.Run("Find Across Address Books", func(t *testing.T) {
+		resp := do("GET", "/contacts/duplicates", nil)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		var out contactuc.FindDuplicatesOutput
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+
+		require.Equal(t, 1, out.Count)
+		cluster := out.Clusters[0]
+		assert.Equal(t, 1.0, cluster.Score)
+		var ids []string
+		for _, c := range cluster.Contacts {
+			ids = append(ids, c.ID)
+		}
+		assert.ElementsMatch(t, []string{outlook.ID, phone.ID, csv.ID}, ids)
+		assert.NotContains(t, ids, other.ID)
+		assert.NotContains(t, ids, group.ID)
+		assert.Len(t, cluster.Matches, 2)
+	})
+
+	t.Run("Find Within One Address Book", func(t *testing.T) {
+		resp := do("GET", "/contacts/duplicates?addressbook_id="+strconv.Itoa(int(ab1.ID)), nil)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		var out contactuc.FindDuplicatesOutput
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+		assert.Equal(t, 0, out.Count)
+	})
+
+	t.Run("Find Invalid Parameters", func(t *testing.T) {
+		assert.Equal(t, fiber.StatusBadRequest, do("GET", "/contacts/duplicates?min_score=2", nil).StatusCode)
+		assert.Equal(t, fiber.StatusNotFound, do("GET", "/contacts/duplicates?addressbook_id=9999", nil).StatusCode)
+	})
+
+	t.Run("Merge Validation", func(t *testing.T) {
+		resp := do("POST", "/contacts/duplicates/merge", map[string]interface{}{"target_id": outlook.ID})
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+		resp = do("POST", "/contacts/duplicates/merge", map[string]interface{}{
+			"target_id": outlook.ID, "contact_ids": []string{uuid.New().String()},
+		})
+		assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+		resp = do("POST", "/contacts/duplicates/merge", map[string]interface{}{
+			"target_id": outlook.ID, "contact_ids": []string{group.ID},
+		})
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("Merge", func(t *testing.T) {
+		resp := do("POST", "/contacts/duplicates/merge", map[string]interface{}{
+			"target_id":   outlook.ID,
+			"contact_ids": []string{phone.ID, csv.ID},
+		})
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		var merged contact.Contact
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&merged))
+
+		assert.Equal(t, outlook.ID, merged.ID)
+		assert.Equal(t, outlook.UID, merged.UID)
+		assert.Equal(t, "Jane", merged.GivenName)
+		assert.Equal(t, "Public", merged.FamilyName)
+		assert.Equal(t, "ACME Inc.", merged.Organization)
+		assert.Len(t, merged.Emails, 2)
+		assert.Len(t, merged.Phones, 1)
+		assert.Equal(t, "Met at the conference\n\nPrefers phone calls", merged.Notes)
+
+		// The others are gone with a sync entry in their address books
+		for _, c := range []contact.Contact{phone, csv} {
+			obj, err := abRepo.GetObjectByUUID(context.Background(), c.ID)
+			require.NoError(t, err)
+			assert.Nil(t, obj)
+		}
+		var changes []addressbook.SyncChangeLog
+		require.NoError(t, db.DB().Where("address_book_id = ? AND change_type = ?", ab2.ID, "deleted").Find(&changes).Error)
+		require.Len(t, changes, 1)
+		assert.Equal(t, phone.UID, changes[0].ResourceUID)
+
+		// Group membership moved to the surviving contact
+		groupObj, err := abRepo.GetObjectByUUID(context.Background(), group.ID)
+		require.NoError(t, err)
+		assert.Contains(t, groupObj.VCardData, "urn:uuid:"+outlook.UID)
+		assert.NotContains(t, groupObj.VCardData, csv.UID)
+	})
+}
@@ -0,0 +1,254 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/jherrma/caldav-server/internal/adapter/http/dto"
+	"github.com/jherrma/caldav-server/internal/domain/addressbook"
+	contactuc "github.com/jherrma/caldav-server/internal/usecase/contact"
+)
+
+// ContactGroupHandler handles contact group endpoints. Groups are group
+// vCards in an address book, so they also show up (with kind "group") in the
+// contact endpoints.
+type ContactGroupHandler struct {
+	createUC        *contactuc.CreateGroupUseCase
+	listUC          *contactuc.ListGroupsUseCase
+	getUC           *contactuc.GetGroupUseCase
+	addMemberUC     *contactuc.AddGroupMemberUseCase
+	removeMemberUC  *contactuc.RemoveGroupMemberUseCase
+	convertUC       *contactuc.ConvertGroupUseCase
+	addressBookRepo addressbook.Repository
+}
+
+func NewContactGroupHandler(
+	createUC *contactuc.CreateGroupUseCase,
+	listUC *contactuc.ListGroupsUseCase,
+	getUC *contactuc.GetGroupUseCase,
+	addMemberUC *contactuc.AddGroupMemberUseCase,
+	removeMemberUC *contactuc.RemoveGroupMemberUseCase,
+	convertUC *contactuc.ConvertGroupUseCase,
+	addressBookRepo addressbook.Repository,
+) *ContactGroupHandler {
+	return &ContactGroupHandler{
+		createUC:        createUC,
+		listUC:          listUC,
+		getUC:           getUC,
+		addMemberUC:     addMemberUC,
+		removeMemberUC:  removeMemberUC,
+		convertUC:       convertUC,
+		addressBookRepo: addressBookRepo,
+	}
+}
+
+// ownsAddressBook reports whether the authenticated user owns the given
+// addressbook id; callers answer 404 so existence isn't leaked
+func (h *ContactGroupHandler) ownsAddressBook(c fiber.Ctx, abID uint) bool {
+	userID := c.Locals("user_id").(uint)
+	ab, err := h.addressBookRepo.GetByID(c.Context(), abID)
+	return err == nil && ab != nil && ab.UserID == userID
+}
+
+// groupError maps group use case errors to responses
+func groupError(c fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, contactuc.ErrGroupNotFound), errors.Is(err, contactuc.ErrMemberNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, contactuc.ErrInvalidGroupStyle), errors.Is(err, contactuc.ErrGroupNameRequired):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+}
+
+// List godoc
+// @Summary      List contact groups
+// @Description  Get the contact groups of an address book with their members
+// @Tags         Contacts
+// @Produce      json
+// @Param        addressbook_id  path      integer  true  "Address Book ID"
+// @Success      200             {object}  dto.GroupListResponse
+// @Failure      400             {object}  ErrorResponseBody
+// @Failure      404             {object}  ErrorResponseBody
+// @Failure      500             {object}  ErrorResponseBody
+// @Security     BearerAuth
+// @Router       /addressbooks/{addressbook_id}/groups [get]
+func (h *ContactGroupHandler) List(c fiber.Ctx) error {
+	abID, err := strconv.ParseUint(c.Params("addressbook_id"), 10, 32)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid addressbook ID"})
+	}
+	if !h.ownsAddressBook(c, uint(abID)) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "not_found"})
+	}
+
+	groups, err := h.listUC.Execute(c.Context(), uint(abID))
+	if err != nil {
+		return groupError(c, err)
+	}
+	return c.JSON(dto.GroupListResponse{Groups: groups})
+}
+
+// Create godoc
+// @Summary      Create contact group
+// @Description  Create a contact group stored as a group vCard (vCard 4 KIND:group or Apple X-ADDRESSBOOKSERVER-KIND)
+// @Tags         Contacts
+// @Accept       json
+// @Produce      json
+// @Param        addressbook_id  path      integer                     true  "Address Book ID"
+// @Param        group           body      contactuc.CreateGroupInput  true  "Group details"
+// @Success      201             {object}  contact.Group
+// @Failure      400             {object}  ErrorResponseBody
+// @Failure      404             {object}  ErrorResponseBody
+// @Failure      500             {object}  ErrorResponseBody
+// @Security     BearerAuth
+// @Router       /addressbooks/{addressbook_id}/groups [post]
+func (h *ContactGroupHandler) Create(c fiber.Ctx) error {
+	abID, err := strconv.ParseUint(c.Params("addressbook_id"), 10, 32)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid addressbook ID"})
+	}
+	if !h.ownsAddressBook(c, uint(abID)) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "not_found"})
+	}
+
+	var input contactuc.CreateGroupInput
+	if err := json.Unmarshal(c.Body(), &input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+	}
+
+	userID := c.Locals("user_id").(uint)
+	group, err := h.createUC.Execute(c.Context(), userID, uint(abID), input)
+	if err != nil {
+		return groupError(c, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(group)
+}
+
+// Get godoc
+// @Summary      Get contact group
+// @Description  Get a contact group with its members
+// @Tags         Contacts
+// @Produce      json
+// @Param        addressbook_id  path      integer  true  "Address Book ID"
+// @Param        group_id        path      string   true  "Group UUID"
+// @Success      200             {object}  contact.Group
+// @Failure      400             {object}  ErrorResponseBody
+// @Failure      404             {object}  ErrorResponseBody
+// @Failure      500             {object}  ErrorResponseBody
+// @Security     BearerAuth
+// @Router       /addressbooks/{addressbook_id}/groups/{group_id} [get]
+func (h *ContactGroupHandler) Get(c fiber.Ctx) error {
+	abID, err := strconv.ParseUint(c.Params("addressbook_id"), 10, 32)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid addressbook ID"})
+	}
+	if !h.ownsAddressBook(c, uint(abID)) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "not_found"})
+	}
+
+	group, err := h.getUC.Execute(c.Context(), uint(abID), c.Params("group_id"))
+	if err != nil {
+		return groupError(c, err)
+	}
+	return c.JSON(group)
+}
+
+// AddMember godoc
+// @Summary      Add group member
+// @Description  Add a contact of the same address book to a group
+// @Tags         Contacts
+// @Produce      json
+// @Param        addressbook_id  path      integer  true  "Address Book ID"
+// @Param        group_id        path      string   true  "Group UUID"
+// @Param        contact_id      path      string   true  "Contact UUID"
+// @Success      200             {object}  contact.Group
+// @Failure      400             {object}  ErrorResponseBody
+// @Failure      404             {object}  ErrorResponseBody
+// @Failure      500             {object}  ErrorResponseBody
+// @Security     BearerAuth
+// @Router       /addressbooks/{addressbook_id}/groups/{group_id}/members/{contact_id} [put]
+func (h *ContactGroupHandler) AddMember(c fiber.Ctx) error {
+	abID, err := strconv.ParseUint(c.Params("addressbook_id"), 10, 32)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid addressbook ID"})
+	}
+	if !h.ownsAddressBook(c, uint(abID)) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "not_found"})
+	}
+
+	group, err := h.addMemberUC.Execute(c.Context(), uint(abID), c.Params("group_id"), c.Params("contact_id"))
+	if err != nil {
+		return groupError(c, err)
+	}
+	return c.JSON(group)
+}
+
+// RemoveMember godoc
+// @Summary      Remove group member
+// @Description  Remove a member from a group. The member is identified by contact UUID, or by vCard UID for members that no longer exist.
+// @Tags         Contacts
+// @Produce      json
+// @Param        addressbook_id  path      integer  true  "Address Book ID"
+// @Param        group_id        path      string   true  "Group UUID"
+// @Param        contact_id      path      string   true  "Contact UUID or member UID"
+// @Success      200             {object}  contact.Group
+// @Failure      400             {object}  ErrorResponseBody
+// @Failure      404             {object}  ErrorResponseBody
+// @Failure      500             {object}  ErrorResponseBody
+// @Security     BearerAuth
+// @Router       /addressbooks/{addressbook_id}/groups/{group_id}/members/{contact_id} [delete]
+func (h *ContactGroupHandler) RemoveMember(c fiber.Ctx) error {
+	abID, err := strconv.ParseUint(c.Params("addressbook_id"), 10, 32)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid addressbook ID"})
+	}
+	if !h.ownsAddressBook(c, uint(abID)) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "not_found"})
+	}
+
+	group, err := h.removeMemberUC.Execute(c.Context(), uint(abID), c.Params("group_id"), c.Params("contact_id"))
+	if err != nil {
+		return groupError(c, err)
+	}
+	return c.JSON(group)
+}
+
+// Convert godoc
+// @Summary      Convert contact group style
+// @Description  Rewrite a group as vCard 4 (KIND:group / MEMBER) or Apple (X-ADDRESSBOOKSERVER-KIND / X-ADDRESSBOOKSERVER-MEMBER)
+// @Tags         Contacts
+// @Accept       json
+// @Produce      json
+// @Param        addressbook_id  path      integer                  true  "Address Book ID"
+// @Param        group_id        path      string                   true  "Group UUID"
+// @Param        request         body      dto.ConvertGroupRequest  true  "Target style"
+// @Success      200             {object}  contact.Group
+// @Failure      400             {object}  ErrorResponseBody
+// @Failure      404             {object}  ErrorResponseBody
+// @Failure      500             {object}  ErrorResponseBody
+// @Security     BearerAuth
+// @Router       /addressbooks/{addressbook_id}/groups/{group_id}/convert [post]
+func (h *ContactGroupHandler) Convert(c fiber.Ctx) error {
+	abID, err := strconv.ParseUint(c.Params("addressbook_id"), 10, 32)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid addressbook ID"})
+	}
+	if !h.ownsAddressBook(c, uint(abID)) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "not_found"})
+	}
+
+	var input dto.ConvertGroupRequest
+	if err := json.Unmarshal(c.Body(), &input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
+	}
+
+	group, err := h.convertUC.Execute(c.Context(), uint(abID), c.Params("group_id"), input.Style)
+	if err != nil {
+		return groupError(c, err)
+	}
+	return c.JSON(group)
+}
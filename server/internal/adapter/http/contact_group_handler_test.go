@@ -0,0 +1,155 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/jherrma/caldav-server/internal/adapter/repository"
+	"github.com/jherrma/caldav-server/internal/domain/addressbook"
+	"github.com/jherrma/caldav-server/internal/domain/contact"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContactGroupHandler(t *testing.T) {
+	app, db, u, ab, token := setupContactHandlerTest(t)
+	defer db.Close()
+	abRepo := repository.NewAddressBookRepository(db.DB())
+	abIDStr := strconv.Itoa(int(ab.ID))
+
+	do := func(method, path string, body interface{}) *http.Response {
+		var reader *bytes.Reader
+		if body != nil {
+			b, _ := json.Marshal(body)
+			reader = bytes.NewReader(b)
+		} else {
+			reader = bytes.NewReader(nil)
+		}
+		req, _ := http.NewRequest(method, "/api/v1/addressbooks/"+abIDStr+path, reader)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp
+	}
+	createContact := func(name string) contact.Contact {
+		resp := do("POST", "/contacts", contact.Contact{FormattedName: name, GivenName: name})
+		require.Equal(t, fiber.StatusCreated, resp.StatusCode)
+		var c contact.Contact
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&c))
+		return c
+	}
+	decodeGroup := func(resp *http.Response) contact.Group {
+		var g contact.Group
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&g))
+		return g
+	}
+
+	alice := createContact("Alice")
+	bob := createContact("Bob")
+	carol := createContact("Carol")
+
+	var groupID string
+
+	t.Run("Create Group", func(t *testing.T) {
+		resp := do("POST", "/groups", map[string]interface{}{
+			"name":       "Team",
+			"member_ids": []string{alice.ID, bob.ID},
+		})
+		require.Equal(t, fiber.StatusCreated, resp.StatusCode)
+		g := decodeGroup(resp)
+		groupID = g.ID
+		assert.Equal(t, "Team", g.Name)
+		assert.Equal(t, addressbook.GroupStyleApple, g.Style)
+		require.Len(t, g.Members, 2)
+		assert.Equal(t, alice.ID, g.Members[0].ID)
+		assert.Equal(t, "Alice", g.Members[0].FormattedName)
+
+		obj, err := abRepo.GetObjectByUUID(context.Background(), groupID)
+		require.NoError(t, err)
+		assert.Equal(t, addressbook.KindGroup, obj.Kind)
+		assert.Contains(t, obj.VCardData, "X-ADDRESSBOOKSERVER-MEMBER:urn:uuid:"+alice.UID)
+	})
+
+	t.Run("Invalid Style", func(t *testing.T) {
+		resp := do("POST", "/groups", map[string]interface{}{"name": "X", "style": "outlook"})
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("Add And Remove Members", func(t *testing.T) {
+		resp := do("PUT", "/groups/"+groupID+"/members/"+carol.ID, nil)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Len(t, decodeGroup(resp).Members, 3)
+
+		resp = do("DELETE", "/groups/"+groupID+"/members/"+bob.ID, nil)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Len(t, decodeGroup(resp).Members, 2)
+
+		resp = do("DELETE", "/groups/"+groupID+"/members/"+bob.ID, nil)
+		assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("Convert To vCard 4", func(t *testing.T) {
+		resp := do("POST", "/groups/"+groupID+"/convert", map[string]string{"style": "vcard4"})
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		g := decodeGroup(resp)
+		assert.Equal(t, addressbook.GroupStyleVCard4, g.Style)
+		assert.Len(t, g.Members, 2)
+
+		obj, err := abRepo.GetObjectByUUID(context.Background(), groupID)
+		require.NoError(t, err)
+		assert.Contains(t, obj.VCardData, "KIND:group")
+		assert.Contains(t, obj.VCardData, "MEMBER:urn:uuid:"+carol.UID)
+		assert.NotContains(t, obj.VCardData, "X-ADDRESSBOOKSERVER")
+		assert.Equal(t, "4.0", obj.VCardVersion)
+	})
+
+	t.Run("Delete Contact Removes Membership", func(t *testing.T) {
+		resp := do("DELETE", "/contacts/"+alice.ID, nil)
+		require.Equal(t, fiber.StatusNoContent, resp.StatusCode)
+
+		g := decodeGroup(do("GET", "/groups/"+groupID, nil))
+		require.Len(t, g.Members, 1)
+		assert.Equal(t, carol.ID, g.Members[0].ID)
+	})
+
+	t.Run("Move Contact Removes Membership", func(t *testing.T) {
+		other := &addressbook.AddressBook{UUID: uuid.New().String(), UserID: u.ID, Name: "Other", Path: "other"}
+		require.NoError(t, abRepo.Create(context.Background(), other))
+
+		resp := do("POST", "/contacts/"+carol.ID+"/move", map[string]string{
+			"target_addressbook_id": strconv.Itoa(int(other.ID)),
+		})
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		g := decodeGroup(do("GET", "/groups/"+groupID, nil))
+		assert.Empty(t, g.Members)
+	})
+
+	t.Run("List Groups", func(t *testing.T) {
+		resp := do("GET", "/groups", nil)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		var res struct {
+			Groups []contact.Group `json:"groups"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&res))
+		require.Len(t, res.Groups, 1)
+		assert.Equal(t, groupID, res.Groups[0].ID)
+	})
+
+	t.Run("Group Shows In Contacts With Kind", func(t *testing.T) {
+		resp := do("GET", "/contacts/"+groupID, nil)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		var c contact.Contact
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&c))
+		assert.Equal(t, addressbook.KindGroup, c.Kind)
+		assert.True(t, strings.EqualFold(c.GroupStyle, addressbook.GroupStyleVCard4))
+	})
+}
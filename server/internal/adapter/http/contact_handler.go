@@ -3,11 +3,14 @@ package http
 import (
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"mime"
 	"net/http"
 	"strconv"
 	"strings"
 
+	"github.com/emersion/go-vcard"
 	"github.com/gofiber/fiber/v3"
 	"github.com/jherrma/caldav-server/internal/adapter/http/dto"
 	"github.com/jherrma/caldav-server/internal/domain/addressbook"
@@ -24,6 +27,10 @@ type ContactHandler struct {
 	searchUC        *contactuc.SearchUseCase
 	moveUC          *contactuc.MoveUseCase
 	photoUC         *contactuc.PhotoUseCase
+	getCardUC       *contactuc.GetCardUseCase
+	listCardsUC     *contactuc.ListCardsUseCase
+	createCardUC    *contactuc.CreateCardUseCase
+	replaceCardUC   *contactuc.ReplaceCardUseCase
 	addressBookRepo addressbook.Repository
 }
 
@@ -36,6 +43,10 @@ func NewContactHandler(
 	searchUC *contactuc.SearchUseCase,
 	moveUC *contactuc.MoveUseCase,
 	photoUC *contactuc.PhotoUseCase,
+	getCardUC *contactuc.GetCardUseCase,
+	listCardsUC *contactuc.ListCardsUseCase,
+	createCardUC *contactuc.CreateCardUseCase,
+	replaceCardUC *contactuc.ReplaceCardUseCase,
 	addressBookRepo addressbook.Repository,
 ) *ContactHandler {
 	return &ContactHandler{
@@ -47,6 +58,10 @@ func NewContactHandler(
 		searchUC:        searchUC,
 		moveUC:          moveUC,
 		photoUC:         photoUC,
+		getCardUC:       getCardUC,
+		listCardsUC:     listCardsUC,
+		createCardUC:    createCardUC,
+		replaceCardUC:   replaceCardUC,
 		addressBookRepo: addressBookRepo,
 	}
 }
@@ -72,11 +87,87 @@ func (h *ContactHandler) ownsContact(c fiber.Ctx, contactUUID string) bool {
 	return err == nil && ab != nil && ab.UserID == userID
 }
 
+// contactMediaType picks the contact representation a client asked for in
+// its Accept header: the contact DTO, jCard (RFC 7095) or JSContact (RFC
+// 9553). Clients that accept none of them still get the DTO.
+func contactMediaType(c fiber.Ctx) string {
+	if t := c.Accepts(fiber.MIMEApplicationJSON, contactuc.MediaTypeJCard, contactuc.MediaTypeJSContact); t != "" {
+		return t
+	}
+	return fiber.MIMEApplicationJSON
+}
+
+// decodeCardBody parses a jCard or JSContact request body into a vCard. ok is
+// false when the body is in neither format and holds the contact DTO.
+func decodeCardBody(c fiber.Ctx) (card vcard.Card, mediaType string, ok bool, err error) {
+	mediaType, _, _ = mime.ParseMediaType(c.Get(fiber.HeaderContentType))
+	switch mediaType {
+	case contactuc.MediaTypeJCard:
+		card, err = contactuc.FromJCard(c.Body())
+	case contactuc.MediaTypeJSContact:
+		card, err = contactuc.FromJSContact(c.Body())
+	default:
+		return nil, "", false, nil
+	}
+	return card, mediaType, true, err
+}
+
+// cardRepresentation renders a vCard as jCard or JSContact
+func cardRepresentation(mediaType string, card vcard.Card) interface{} {
+	if mediaType == contactuc.MediaTypeJSContact {
+		return contactuc.ToJSContact(card)
+	}
+	return contactuc.ToJCard(card)
+}
+
+// sendCard writes a vCard as jCard or JSContact
+func sendCard(c fiber.Ctx, status int, mediaType string, card vcard.Card) error {
+	return c.Status(status).JSON(cardRepresentation(mediaType, card), mediaType)
+}
+
+// sendContactDTO writes a stored contact as the contact DTO, with the photo
+// left to the photo endpoint
+func (h *ContactHandler) sendContactDTO(c fiber.Ctx, status int, abID uint, contactID string) error {
+	res, err := h.getUC.Execute(c.Context(), abID, contactID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	if res == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Contact not found"})
+	}
+
+	// Populate PhotoURL for separate loading
+
+	if res.Photo != "" {
+		res.PhotoURL = fmt.Sprintf("/api/v1/addressbooks/%d/contacts/%s/photo", abID, contactID)
+		res.Photo = "" // Clear base64 data to avoid bloating JSON response
+	}
+
+	return c.Status(status).JSON(res)
+}
+
+// sendWrittenCard answers a jCard or JSContact write. Without an Accept
+// header the response mirrors the format of the request body.
+func (h *ContactHandler) sendWrittenCard(c fiber.Ctx, status int, bodyType string, abID uint, contactID string, card vcard.Card) error {
+	c.Set(fiber.HeaderContentLocation, fmt.Sprintf("/api/v1/addressbooks/%d/contacts/%s", abID, contactID))
+
+	mediaType := bodyType
+	if c.Get(fiber.HeaderAccept) != "" {
+		mediaType = contactMediaType(c)
+	}
+	if mediaType == fiber.MIMEApplicationJSON {
+		return h.sendContactDTO(c, status, abID, contactID)
+	}
+	return sendCard(c, status, mediaType, card)
+}
+
 // List godoc
 // @Summary      List contacts
-// @Description  Get contacts from address book
+// @Description  Get contacts from address book. With an Accept header of application/vcard+json or
+// @Description  application/jscontact+json the full vCards are returned as an array of jCards or
+// @Description  JSContact cards, with the total count in the X-Total-Count header.
 // @Tags         Contacts
-// @Produce      json
+// @Produce      json,application/vcard+json,application/jscontact+json
 // @Param        addressbook_id  path      integer  true  "Address Book ID"
 // @Param        limit           query     integer  false "Limit (default 50)"
 // @Param        offset          query     integer  false "Offset (default 0)"
@@ -111,13 +202,28 @@ func (h *ContactHandler) List(c fiber.Ctx) error {
 	sort := c.Query("sort", "name")
 	order := c.Query("order", "asc")
 
-	output, err := h.listUC.Execute(c.Context(), contactuc.ListInput{
+	input := contactuc.ListInput{
 		AddressBookID: uint(abID),
 		Limit:         limit,
 		Offset:        offset,
 		Sort:          sort,
 		Order:         order,
-	})
+	}
+
+	if mediaType := contactMediaType(c); mediaType != fiber.MIMEApplicationJSON {
+		output, err := h.listCardsUC.Execute(c.Context(), input)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		cards := make([]interface{}, 0, len(output.Cards))
+		for _, card := range output.Cards {
+			cards = append(cards, cardRepresentation(mediaType, card))
+		}
+		c.Set("X-Total-Count", strconv.Itoa(output.Total))
+		return c.JSON(cards, mediaType)
+	}
+
+	output, err := h.listUC.Execute(c.Context(), input)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
@@ -127,9 +233,11 @@ func (h *ContactHandler) List(c fiber.Ctx) error {
 
 // Get godoc
 // @Summary      Get contact
-// @Description  Get contact by ID
+// @Description  Get contact by ID. The Accept header selects the contact DTO (application/json), jCard
+// @Description  (application/vcard+json) or JSContact (application/jscontact+json); the latter two carry
+// @Description  every property of the stored vCard.
 // @Tags         Contacts
-// @Produce      json
+// @Produce      json,application/vcard+json,application/jscontact+json
 // @Param        addressbook_id  path      integer  true  "Address Book ID"
 // @Param        contact_id      path      string   true  "Contact UUID"
 // @Success      200             {object}  contact.Contact
@@ -148,30 +256,28 @@ func (h *ContactHandler) Get(c fiber.Ctx) error {
 	}
 	contactID := c.Params("contact_id")
 
-	res, err := h.getUC.Execute(c.Context(), uint(abID), contactID)
+	mediaType := contactMediaType(c)
+	if mediaType == fiber.MIMEApplicationJSON {
+		return h.sendContactDTO(c, fiber.StatusOK, uint(abID), contactID)
+	}
+
+	card, err := h.getCardUC.Execute(c.Context(), uint(abID), contactID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 	}
-	if res == nil {
+	if card == nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Contact not found"})
 	}
-
-	// Populate PhotoURL for separate loading
-
-	if res.Photo != "" {
-		res.PhotoURL = fmt.Sprintf("/api/v1/addressbooks/%d/contacts/%s/photo", abID, contactID)
-		res.Photo = "" // Clear base64 data to avoid bloating JSON response
-	}
-
-	return c.JSON(res)
+	return sendCard(c, fiber.StatusOK, mediaType, card)
 }
 
 // Create godoc
 // @Summary      Create contact
-// @Description  Create a new contact
+// @Description  Create a new contact from the contact DTO, a jCard (application/vcard+json) or a JSContact
+// @Description  card (application/jscontact+json). The contact ID is returned in the Content-Location header.
 // @Tags         Contacts
-// @Accept       json
-// @Produce      json
+// @Accept       json,application/vcard+json,application/jscontact+json
+// @Produce      json,application/vcard+json,application/jscontact+json
 // @Param        addressbook_id  path      integer          true  "Address Book ID"
 // @Param        contact         body      contact.Contact  true  "Contact details"
 // @Success      201             {object}  contact.Contact
@@ -188,12 +294,25 @@ func (h *ContactHandler) Create(c fiber.Ctx) error {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "not_found"})
 	}
 
+	userID := c.Locals("user_id").(uint)
+
+	card, bodyType, isCard, err := decodeCardBody(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if isCard {
+		obj, stored, err := h.createCardUC.Execute(c.Context(), userID, uint(abID), card)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		return h.sendWrittenCard(c, fiber.StatusCreated, bodyType, uint(abID), obj.UUID, stored)
+	}
+
 	var input contact.Contact
 	if err := json.Unmarshal(c.Body(), &input); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
 	}
 
-	userID := c.Locals("user_id").(uint)
 	res, err := h.createUC.Execute(c.Context(), userID, uint(abID), &input)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
@@ -204,10 +323,11 @@ func (h *ContactHandler) Create(c fiber.Ctx) error {
 
 // Update godoc
 // @Summary      Update contact
-// @Description  Update contact details
+// @Description  Update contact details. A jCard (application/vcard+json) or JSContact card
+// @Description  (application/jscontact+json) body replaces the whole vCard, keeping its UID.
 // @Tags         Contacts
-// @Accept       json
-// @Produce      json
+// @Accept       json,application/vcard+json,application/jscontact+json
+// @Produce      json,application/vcard+json,application/jscontact+json
 // @Param        addressbook_id  path      integer                true  "Address Book ID"
 // @Param        contact_id      path      string                 true  "Contact UUID"
 // @Param        contact         body      contactuc.UpdateInput  true  "Contact updates"
@@ -227,6 +347,21 @@ func (h *ContactHandler) Update(c fiber.Ctx) error {
 	}
 	contactID := c.Params("contact_id")
 
+	card, bodyType, isCard, err := decodeCardBody(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if isCard {
+		obj, stored, err := h.replaceCardUC.Execute(c.Context(), uint(abID), contactID, card)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		if obj == nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Contact not found"})
+		}
+		return h.sendWrittenCard(c, fiber.StatusOK, bodyType, uint(abID), obj.UUID, stored)
+	}
+
 	var input contactuc.UpdateInput
 	if err := json.Unmarshal(c.Body(), &input); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid input"})
@@ -274,7 +409,12 @@ func (h *ContactHandler) Delete(c fiber.Ctx) error {
 
 // Search godoc
 // @Summary      Search contacts
-// @Description  Search for contacts by query
+// @Description  Search for contacts by query. Every word must match a name, email address, phone number
+// @Description  or organization, ignoring accents and case; prefix a word with name:, email:, org: or tel:
+// @Description  to search only that property. Phone numbers match in any format ("030 1234" finds
+// @Description  "+49 30 1234" with default region DE). Without addressbook_id the results also include
+// @Description  matching entries of the directory (addressbook_id "directory") when it is enabled; they
+// @Description  fill the slots left after the user's own contacts.
 // @Tags         Contacts
 // @Produce      json
 // @Param        q               query     string   true   "Search query"
@@ -369,15 +509,15 @@ func (h *ContactHandler) Move(c fiber.Ctx) error {
 
 // UploadPhoto godoc
 // @Summary      Upload contact photo
-// @Description  Upload a photo for a contact (JPEG, PNG, GIF)
+// @Description  Upload a photo for a contact (JPEG, PNG, GIF). The photo is re-encoded as JPEG (PNG when transparent), downscaled to the configured maximum and stored with thumbnails.
 // @Tags         Contacts
 // @Accept       image/jpeg,image/png,image/gif
 // @Param        addressbook_id  path  integer  true  "Address Book ID"
 // @Param        contact_id      path  string   true  "Contact UUID"
 // @Param        file            body  []byte   true  "Photo data"
 // @Success      204
-// @Failure      400            {object}  ErrorResponseBody
-// @Failure      413            {object}  ErrorResponseBody  "Photo too large"
+// @Failure      400            {object}  ErrorResponseBody  "Not a decodable image, or above the size limits"
+// @Failure      404            {object}  ErrorResponseBody
 // @Failure      415            {object}  ErrorResponseBody  "Unsupported file type"
 // @Failure      500            {object}  ErrorResponseBody
 // @Security     BearerAuth
@@ -394,10 +534,8 @@ func (h *ContactHandler) UploadPhoto(c fiber.Ctx) error {
 
 	data := c.Body()
 
-	// Max size check usually in middleware or config, but check length here explicitly
-	if len(data) > 1024*1024 { // 1MB
-		return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{"error": "Photo too large (max 1MB)"})
-	}
+	// Size and resolution limits are enforced by the photo processor, which
+	// downscales what it accepts
 
 	// Validate file type
 	contentType := http.DetectContentType(data)
@@ -421,7 +559,7 @@ func (h *ContactHandler) UploadPhoto(c fiber.Ctx) error {
 	}
 
 	if err := h.photoUC.Upload(c.Context(), uint(abID), contactID, data); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return photoError(c, err)
 	}
 
 	return c.SendStatus(fiber.StatusNoContent)
@@ -449,7 +587,7 @@ func (h *ContactHandler) DeletePhoto(c fiber.Ctx) error {
 	contactID := c.Params("contact_id")
 
 	if err := h.photoUC.Delete(c.Context(), uint(abID), contactID); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		return photoError(c, err)
 	}
 
 	return c.SendStatus(fiber.StatusNoContent)
@@ -457,12 +595,14 @@ func (h *ContactHandler) DeletePhoto(c fiber.Ctx) error {
 
 // ServePhoto godoc
 // @Summary      Get contact photo
-// @Description  Get contact's photo content
+// @Description  Get contact's photo content, or with size the smallest stored thumbnail whose longest edge is at least that many pixels. Responses carry an ETag and honour If-None-Match.
 // @Tags         Contacts
 // @Produce      image/jpeg,image/png,image/gif
-// @Param        addressbook_id  path  integer  true  "Address Book ID"
-// @Param        contact_id      path  string   true  "Contact UUID"
+// @Param        addressbook_id  path   integer  true   "Address Book ID"
+// @Param        contact_id      path   string   true   "Contact UUID"
+// @Param        size            query  integer  false  "Requested longest edge in pixels"
 // @Success      200             {file}    file
+// @Success      304             "Not modified"
 // @Failure      400             {object}  ErrorResponseBody
 // @Failure      404             {object}  ErrorResponseBody
 // @Failure      500             {object}  ErrorResponseBody
@@ -478,25 +618,47 @@ func (h *ContactHandler) ServePhoto(c fiber.Ctx) error {
 	}
 	contactID := c.Params("contact_id")
 
-	res, err := h.getUC.Execute(c.Context(), uint(abID), contactID)
-	if err != nil || res == nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Photo not found"})
+	size := 0
+	if val := c.Query("size"); val != "" {
+		if size, err = strconv.Atoi(val); err != nil || size <= 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "size must be a positive number of pixels"})
+		}
+	}
+
+	photo, err := h.photoUC.Get(c.Context(), uint(abID), contactID, size)
+	if err != nil {
+		return photoError(c, err)
 	}
 
-	if res.Photo == "" {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Photo not found"})
+	etag := `"` + photo.ETag + `"`
+	c.Set("ETag", etag)
+	c.Set("Cache-Control", "private, no-cache")
+	if match := c.Get("If-None-Match"); match != "" && (match == etag || match == "*" || strings.Contains(match, etag)) {
+		return c.SendStatus(fiber.StatusNotModified)
 	}
 
-	data, err := base64.StdEncoding.DecodeString(res.Photo)
+	data, err := base64.StdEncoding.DecodeString(photo.PhotoData)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to decode photo"})
 	}
 
 	contentType := "image/jpeg" // fallback
-	if res.PhotoType != "" {
-		contentType = "image/" + strings.ToLower(res.PhotoType)
+	if photo.PhotoType != "" {
+		contentType = "image/" + strings.ToLower(photo.PhotoType)
 	}
 	c.Set("Content-Type", contentType)
 	_, err = c.Write(data)
 	return err
 }
+
+// photoError maps photo use case errors to responses
+func photoError(c fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, contactuc.ErrContactNotFound), errors.Is(err, contactuc.ErrPhotoNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, addressbook.ErrInvalidPhoto):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+}
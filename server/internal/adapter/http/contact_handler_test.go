@@ -5,6 +5,11 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"io"
 	"net/http"
 	"os"
 	"strconv"
@@ -20,6 +25,7 @@ import (
 	"github.com/jherrma/caldav-server/internal/domain/contact"
 	"github.com/jherrma/caldav-server/internal/domain/user"
 	"github.com/jherrma/caldav-server/internal/infrastructure/database"
+	"github.com/jherrma/caldav-server/internal/infrastructure/imaging"
 	addressbookusecase "github.com/jherrma/caldav-server/internal/usecase/addressbook"
 	contactusecase "github.com/jherrma/caldav-server/internal/usecase/contact"
 	"github.com/stretchr/testify/assert"
@@ -39,6 +45,9 @@ func setupContactHandlerTest(t *testing.T) (*fiber.App, database.Database, *user
 			Secret:       "test-secret",
 			AccessExpiry: time.Hour,
 		},
+		Photos: config.PhotoConfig{
+			ThumbnailSizes: []int{48, 128, 256},
+		},
 	}
 
 	db, err := database.New(cfg)
@@ -51,7 +60,8 @@ func setupContactHandlerTest(t *testing.T) (*fiber.App, database.Database, *user
 
 	// Repos
 	userRepo := repository.NewUserRepository(db.DB())
-	abRepo := repository.NewAddressBookRepository(db.DB())
+	photoProcessor := imaging.NewPhotoProcessor(cfg.Photos)
+	abRepo := repository.NewAddressBookRepository(db.DB(), repository.WithPhotoProcessor(photoProcessor))
 	jwtManager := authadapter.NewJWTManager(&cfg.JWT)
 
 	// User
@@ -85,9 +95,9 @@ func setupContactHandlerTest(t *testing.T) (*fiber.App, database.Database, *user
 	contactGetUC := contactusecase.NewGetUseCase(abRepo)
 	contactUpdateUC := contactusecase.NewUpdateUseCase(abRepo)
 	contactDeleteUC := contactusecase.NewDeleteUseCase(abRepo)
-	contactSearchUC := contactusecase.NewSearchUseCase(abRepo)
+	contactSearchUC := contactusecase.NewSearchUseCase(abRepo, nil)
 	contactMoveUC := contactusecase.NewMoveUseCase(abRepo)
-	contactPhotoUC := contactusecase.NewPhotoUseCase(abRepo)
+	contactPhotoUC := contactusecase.NewPhotoUseCase(abRepo, photoProcessor)
 
 	handler := NewContactHandler(
 		contactCreateUC,
@@ -98,12 +108,16 @@ func setupContactHandlerTest(t *testing.T) (*fiber.App, database.Database, *user
 		contactSearchUC,
 		contactMoveUC,
 		contactPhotoUC,
+		contactusecase.NewGetCardUseCase(abRepo),
+		contactusecase.NewListCardsUseCase(abRepo),
+		contactusecase.NewCreateCardUseCase(abRepo, abCreateContactUC),
+		contactusecase.NewReplaceCardUseCase(abRepo),
 		abRepo,
 	)
 
 	// Routes
 	v1 := app.Group("/api/v1")
-	abGroup := v1.Group("/addressbooks", Authenticate(jwtManager, userRepo))
+	abGroup := v1.Group("/addressbooks", Authenticate(jwtManager, userRepo, nil))
 
 	abGroup.Get("/:addressbook_id/contacts", handler.List)
 	abGroup.Post("/:addressbook_id/contacts", handler.Create)
@@ -116,7 +130,31 @@ func setupContactHandlerTest(t *testing.T) (*fiber.App, database.Database, *user
 	abGroup.Delete("/:addressbook_id/contacts/:contact_id/photo", handler.DeletePhoto)
 	abGroup.Get("/:addressbook_id/contacts/:contact_id/photo", handler.ServePhoto)
 
-	v1.Get("/contacts/search", Authenticate(jwtManager, userRepo), handler.Search)
+	v1.Get("/contacts/search", Authenticate(jwtManager, userRepo, nil), handler.Search)
+
+	groupHandler := NewContactGroupHandler(
+		contactusecase.NewCreateGroupUseCase(abRepo, abCreateContactUC),
+		contactusecase.NewListGroupsUseCase(abRepo),
+		contactusecase.NewGetGroupUseCase(abRepo),
+		contactusecase.NewAddGroupMemberUseCase(abRepo),
+		contactusecase.NewRemoveGroupMemberUseCase(abRepo),
+		contactusecase.NewConvertGroupUseCase(abRepo),
+		abRepo,
+	)
+	abGroup.Get("/:addressbook_id/groups", groupHandler.List)
+	abGroup.Post("/:addressbook_id/groups", groupHandler.Create)
+	abGroup.Get("/:addressbook_id/groups/:group_id", groupHandler.Get)
+	abGroup.Post("/:addressbook_id/groups/:group_id/convert", groupHandler.Convert)
+	abGroup.Put("/:addressbook_id/groups/:group_id/members/:contact_id", groupHandler.AddMember)
+	abGroup.Delete("/:addressbook_id/groups/:group_id/members/:contact_id", groupHandler.RemoveMember)
+
+	duplicateHandler := NewContactDuplicateHandler(
+		contactusecase.NewFindDuplicatesUseCase(abRepo),
+		contactusecase.NewMergeContactsUseCase(abRepo),
+		abRepo,
+	)
+	v1.Get("/contacts/duplicates", Authenticate(jwtManager, userRepo, nil), duplicateHandler.Find)
+	v1.Post("/contacts/duplicates/merge", Authenticate(jwtManager, userRepo, nil), duplicateHandler.Merge)
 
 	return app, db, u, ab, token
 }
@@ -287,7 +325,64 @@ func TestContactHandler_Photo(t *testing.T) {
 		resp, err := app.Test(req)
 		require.NoError(t, err)
 		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
-		assert.Equal(t, "image/gif", resp.Header.Get("Content-Type"))
+		// The GIF has a transparent pixel, so it is normalized to PNG
+		assert.Equal(t, "image/png", resp.Header.Get("Content-Type"))
+		etag := resp.Header.Get("ETag")
+		require.NotEmpty(t, etag)
+
+		req.Header.Set("If-None-Match", etag)
+		resp, err = app.Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusNotModified, resp.StatusCode)
+	})
+
+	t.Run("Upload Large Photo", func(t *testing.T) {
+		img := image.NewRGBA(image.Rect(0, 0, 2000, 1000))
+		for i := range img.Pix {
+			img.Pix[i] = uint8(i)
+		}
+		var buf bytes.Buffer
+		require.NoError(t, jpeg.Encode(&buf, img, nil))
+
+		req, _ := http.NewRequest("PUT", "/api/v1/addressbooks/"+abIDStr+"/contacts/"+contactID+"/photo", bytes.NewReader(buf.Bytes()))
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusNoContent, resp.StatusCode)
+
+		serve := func(query string) image.Image {
+			req, _ := http.NewRequest("GET", "/api/v1/addressbooks/"+abIDStr+"/contacts/"+contactID+"/photo"+query, nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			require.Equal(t, fiber.StatusOK, resp.StatusCode)
+			assert.Equal(t, "image/jpeg", resp.Header.Get("Content-Type"))
+			decoded, _, err := image.Decode(resp.Body)
+			require.NoError(t, err)
+			return decoded
+		}
+		assert.Equal(t, image.Rect(0, 0, 512, 256), serve("").Bounds())
+		assert.Equal(t, image.Rect(0, 0, 128, 64), serve("?size=100").Bounds())
+		assert.Equal(t, image.Rect(0, 0, 512, 256), serve("?size=300").Bounds())
+
+		// The vCard carries the downscaled photo
+		reqGet, _ := http.NewRequest("GET", "/api/v1/addressbooks/"+abIDStr+"/contacts/"+contactID, nil)
+		reqGet.Header.Set("Authorization", "Bearer "+token)
+		reqGet.Header.Set("Accept", "application/vcard+json")
+		respGet, err := app.Test(reqGet)
+		require.NoError(t, err)
+		body, _ := io.ReadAll(respGet.Body)
+		assert.Less(t, len(body), buf.Len())
+	})
+
+	t.Run("Upload Invalid Photo", func(t *testing.T) {
+		// Passes content sniffing as PNG but does not decode
+		data := append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{1}, 64)...)
+		req, _ := http.NewRequest("PUT", "/api/v1/addressbooks/"+abIDStr+"/contacts/"+contactID+"/photo", bytes.NewReader(data))
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
 	})
 
 	t.Run("Delete Photo", func(t *testing.T) {
@@ -0,0 +1,86 @@
+package http
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/emersion/go-vcard"
+	"github.com/gofiber/fiber/v3"
+	"github.com/jherrma/caldav-server/internal/usecase/directory"
+)
+
+// DirectoryHandler serves the global address list over the REST API
+type DirectoryHandler struct {
+	directory *directory.Service
+}
+
+func NewDirectoryHandler(dir *directory.Service) *DirectoryHandler {
+	return &DirectoryHandler{directory: dir}
+}
+
+// List godoc
+// @Summary      List directory
+// @Description  List the directory of all server users (global address list), optionally filtered by a
+// @Description  query on name, username, email address or organization. Returns 404 when the directory
+// @Description  is disabled.
+// @Tags         Directory
+// @Produce      json
+// @Param        q       query     string   false  "Search query"
+// @Param        limit   query     integer  false  "Limit (default 50)"
+// @Param        offset  query     integer  false  "Offset"
+// @Success      200     {object}  directory.ListOutput
+// @Failure      404     {object}  ErrorResponseBody
+// @Failure      500     {object}  ErrorResponseBody
+// @Security     BearerAuth
+// @Router       /directory [get]
+func (h *DirectoryHandler) List(c fiber.Ctx) error {
+	if !h.directory.Enabled() {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "not_found"})
+	}
+
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	offset, _ := strconv.Atoi(c.Query("offset"))
+
+	output, err := h.directory.List(c.Context(), c.Query("q"), limit, offset)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(output)
+}
+
+// Get godoc
+// @Summary      Get directory entry
+// @Description  Get one entry of the directory by its UID. The Accept header selects the contact DTO,
+// @Description  jCard or JSContact, as for contacts.
+// @Tags         Directory
+// @Produce      json,application/vcard+json,application/jscontact+json
+// @Param        id   path      string  true  "Entry UID"
+// @Success      200  {object}  contact.Contact
+// @Failure      404  {object}  ErrorResponseBody
+// @Failure      500  {object}  ErrorResponseBody
+// @Security     BearerAuth
+// @Router       /directory/{id} [get]
+func (h *DirectoryHandler) Get(c fiber.Ctx) error {
+	if !h.directory.Enabled() {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "not_found"})
+	}
+
+	entry, err := h.directory.Get(c.Context(), c.Params("id"))
+	if err != nil {
+		if errors.Is(err, directory.ErrNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "not_found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if mediaType := contactMediaType(c); mediaType != fiber.MIMEApplicationJSON {
+		card, err := vcard.NewDecoder(strings.NewReader(entry.VCardData)).Decode()
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		return sendCard(c, fiber.StatusOK, mediaType, card)
+	}
+
+	return c.JSON(directory.ToContact(entry.AddressObject()))
+}
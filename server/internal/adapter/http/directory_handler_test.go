@@ -0,0 +1,110 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/jherrma/caldav-server/internal/domain/contact"
+	"github.com/jherrma/caldav-server/internal/usecase/directory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirectoryHandler(t *testing.T) {
+	app, _, _ := setupTestApp(t)
+
+	register := func(email, displayName string) {
+		body, _ := json.Marshal(map[string]string{"email": email, "password": "Password123!", "display_name": displayName})
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/register", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	}
+	do := func(method, target, token string, body any) *http.Response {
+		var data []byte
+		if body != nil {
+			data, _ = json.Marshal(body)
+		}
+		req := httptest.NewRequest(method, target, bytes.NewReader(data))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp
+	}
+	list := func(token, query string) directory.ListOutput {
+		resp := do(http.MethodGet, "/api/v1/directory"+query, token, nil)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		var out directory.ListOutput
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+		return out
+	}
+
+	register("admin@example.com", "Ada Admin")
+	register("bea@example.com", "Bea Builder")
+	admin := loginForTest(t, app, "admin@example.com", "Password123!", "test")
+	bea := loginForTest(t, app, "bea@example.com", "Password123!", "test")
+
+	t.Run("List", func(t *testing.T) {
+		out := list(bea.AccessToken, "")
+		assert.Equal(t, 2, out.Total)
+		require.Len(t, out.Contacts, 2)
+		assert.Equal(t, "Ada Admin", out.Contacts[0].FormattedName)
+		assert.Equal(t, "directory", out.Contacts[0].AddressBookID)
+		assert.Equal(t, "Example Corp", out.Contacts[0].Organization)
+		assert.NotEmpty(t, out.SyncToken)
+
+		out = list(bea.AccessToken, "?q=builder")
+		require.Len(t, out.Contacts, 1)
+		assert.Equal(t, "bea@example.com", out.Contacts[0].Emails[0].Value)
+
+		out = list(bea.AccessToken, "?limit=1&offset=1")
+		assert.Equal(t, 2, out.Total)
+		require.Len(t, out.Contacts, 1)
+		assert.Equal(t, "Bea Builder", out.Contacts[0].FormattedName)
+	})
+
+	t.Run("Profile Fields", func(t *testing.T) {
+		resp := do(http.MethodPatch, "/api/v1/users/me", bea.AccessToken, map[string]string{"job_title": "Carpenter", "phone": "+49 30 1234"})
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		resp = do(http.MethodGet, "/api/v1/directory/"+bea.User.ID, admin.AccessToken, nil)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		var c contact.Contact
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&c))
+		assert.Equal(t, "Carpenter", c.Title)
+		require.Len(t, c.Phones, 1)
+		assert.Equal(t, "+49 30 1234", c.Phones[0].Value)
+
+		resp = do(http.MethodGet, "/api/v1/directory/unknown", admin.AccessToken, nil)
+		assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("Admin Visibility", func(t *testing.T) {
+		before := list(admin.AccessToken, "")
+
+		resp := do(http.MethodPatch, "/api/v1/admin/users/"+bea.User.ID+"/directory", bea.AccessToken, map[string]bool{"hidden": true})
+		assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+
+		resp = do(http.MethodPatch, "/api/v1/admin/users/"+bea.User.ID+"/directory", admin.AccessToken, map[string]bool{"hidden": true})
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		after := list(admin.AccessToken, "")
+		require.Len(t, after.Contacts, 1)
+		assert.Equal(t, "Ada Admin", after.Contacts[0].FormattedName)
+		assert.NotEqual(t, before.SyncToken, after.SyncToken)
+
+		resp = do(http.MethodGet, "/api/v1/directory/"+bea.User.ID, admin.AccessToken, nil)
+		assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+
+		resp = do(http.MethodPatch, "/api/v1/admin/users/"+bea.User.ID+"/directory", admin.AccessToken, map[string]any{})
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+		resp = do(http.MethodPatch, "/api/v1/admin/users/unknown/directory", admin.AccessToken, map[string]bool{"hidden": false})
+		assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+	})
+}
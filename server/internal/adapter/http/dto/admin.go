@@ -0,0 +1,17 @@
+package dto
+
+// UnlockUserResponse reports how many lockout records were cleared
+type UnlockUserResponse struct {
+	Cleared int64 `json:"cleared"`
+}
+
+// DirectoryVisibilityRequest hides a user from the directory or lists them again
+type DirectoryVisibilityRequest struct {
+	Hidden *bool `json:"hidden"`
+}
+
+// DirectoryVisibilityResponse reports a user's directory visibility
+type DirectoryVisibilityResponse struct {
+	ID     string `json:"id"`
+	Hidden bool   `json:"hidden"`
+}
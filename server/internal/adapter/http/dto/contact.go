@@ -1,6 +1,27 @@
 package dto
 
+import "github.com/jherrma/caldav-server/internal/domain/contact"
+
 // MoveContactRequest represents the request body for moving a contact
 type MoveContactRequest struct {
 	TargetAddressBookID string `json:"target_addressbook_id"`
 }
+
+// ConvertGroupRequest represents the request body for converting a contact
+// group between the vCard 4 and Apple styles
+type ConvertGroupRequest struct {
+	Style string `json:"style"` // "vcard4" or "apple"
+}
+
+// GroupListResponse wraps the groups of an address book
+type GroupListResponse struct {
+	Groups []*contact.Group `json:"groups"`
+}
+
+// MergeContactsRequest represents the request body for merging duplicate
+// contacts into a target contact
+type MergeContactsRequest struct {
+	TargetID   string   `json:"target_id"`            // Contact that survives the merge
+	ContactIDs []string `json:"contact_ids"`          // Contacts folded into the target and deleted
+	PhotoFrom  string   `json:"photo_from,omitempty"` // Contact whose photo is kept
+}
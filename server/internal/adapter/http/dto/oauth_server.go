@@ -0,0 +1,88 @@
+package dto
+
+// CreateOAuthClientRequest registers a third-party application
+type CreateOAuthClientRequest struct {
+	Name         string   `json:"name"`
+	RedirectURIs []string `json:"redirect_uris"`
+	Scopes       []string `json:"scopes"`       // Defaults to all supported scopes
+	Confidential bool     `json:"confidential"` // Issue a client secret
+}
+
+// OAuthClientResponse describes a registered application
+type OAuthClientResponse struct {
+	ClientID     string   `json:"client_id"`
+	ClientSecret string   `json:"client_secret,omitempty"` // Only returned on creation
+	Name         string   `json:"name"`
+	RedirectURIs []string `json:"redirect_uris"`
+	Scopes       []string `json:"scopes"`
+	Confidential bool     `json:"confidential"`
+	CreatedAt    string   `json:"created_at"`
+}
+
+// ListOAuthClientsResponse lists the user's applications
+type ListOAuthClientsResponse struct {
+	Clients []OAuthClientResponse `json:"clients"`
+}
+
+// OAuthScopeResponse is a scope with its human-readable description
+type OAuthScopeResponse struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// OAuthConsentResponse is what the consent screen shows
+type OAuthConsentResponse struct {
+	ClientID     string               `json:"client_id"`
+	ClientName   string               `json:"client_name"`
+	RedirectURI  string               `json:"redirect_uri"`
+	Scopes       []OAuthScopeResponse `json:"scopes"`
+	ConsentGiven bool                 `json:"consent_given"`
+}
+
+// OAuthDecisionRequest carries the original authorization request
+// parameters and the user's decision
+type OAuthDecisionRequest struct {
+	ResponseType        string `json:"response_type"`
+	ClientID            string `json:"client_id"`
+	RedirectURI         string `json:"redirect_uri"`
+	Scope               string `json:"scope"`
+	State               string `json:"state"`
+	CodeChallenge       string `json:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method"`
+	Nonce               string `json:"nonce"`
+	Approved            bool   `json:"approved"`
+}
+
+// OAuthDecisionResponse tells the frontend where to send the user
+type OAuthDecisionResponse struct {
+	RedirectTo string `json:"redirect_to"`
+}
+
+// OAuthTokenResponse is the token endpoint response (RFC 6749 §5.1)
+type OAuthTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope"`
+	IDToken      string `json:"id_token,omitempty"`
+}
+
+// OAuthErrorResponse is the error response of the token, introspection
+// and revocation endpoints (RFC 6749 §5.2)
+type OAuthErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// OAuthIntrospectionResponse is the introspection response (RFC 7662 §2.2)
+type OAuthIntrospectionResponse struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Username  string `json:"username,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+}
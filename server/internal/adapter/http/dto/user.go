@@ -7,8 +7,12 @@ type UserProfileResponse struct {
 	ID            string           `json:"id"`
 	Email         string           `json:"email"`
 	DisplayName   string           `json:"display_name"`
+	Phone         string           `json:"phone,omitempty"`
+	JobTitle      string           `json:"job_title,omitempty"`
+	Department    string           `json:"department,omitempty"`
 	IsActive      bool             `json:"is_active"`
 	EmailVerified bool             `json:"email_verified"`
+	IsAdmin       bool             `json:"is_admin"`
 	CreatedAt     time.Time        `json:"created_at"`
 	UpdatedAt     time.Time        `json:"updated_at"`
 	AuthMethods   []string         `json:"auth_methods"`
@@ -25,6 +29,9 @@ type UserProfileStats struct {
 // UpdateProfileRequest represents the request body for updating profile
 type UpdateProfileRequest struct {
 	DisplayName *string `json:"display_name,omitempty"`
+	Phone       *string `json:"phone,omitempty"`
+	JobTitle    *string `json:"job_title,omitempty"`
+	Department  *string `json:"department,omitempty"`
 }
 
 // DeleteAccountRequest represents the request body for account deletion
@@ -76,3 +83,30 @@ type ChangePasswordRequest struct {
 	CurrentPassword string `json:"current_password"`
 	NewPassword     string `json:"new_password"`
 }
+
+// SessionResponse represents an active login session
+type SessionResponse struct {
+	ID         string `json:"id"`
+	DeviceName string `json:"device_name"`
+	UserAgent  string `json:"user_agent"`
+	IP         string `json:"ip"`
+	CreatedAt  string `json:"created_at"`
+	LastUsedAt string `json:"last_used_at"`
+	ExpiresAt  string `json:"expires_at"`
+}
+
+// ListSessionsResponse represents the list of active sessions
+type ListSessionsResponse struct {
+	Sessions []SessionResponse `json:"sessions"`
+}
+
+// RevokeSessionsRequest represents the optional body for revoking all sessions
+type RevokeSessionsRequest struct {
+	// RefreshToken identifies the caller's own session, which is kept
+	RefreshToken string `json:"refresh_token"`
+}
+
+// RevokeSessionsResponse reports how many sessions were revoked
+type RevokeSessionsResponse struct {
+	Revoked int64 `json:"revoked"`
+}
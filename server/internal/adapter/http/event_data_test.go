@@ -0,0 +1,141 @@
+package http
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/emersion/go-ical"
+	"github.com/gofiber/fiber/v3"
+	"github.com/jherrma/caldav-server/internal/adapter/http/dto"
+	eventusecase "github.com/jherrma/caldav-server/internal/usecase/event"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventHandler_DataRepresentations(t *testing.T) {
+	app, db, _, cal, token := setupEventHandlerTest(t)
+	defer db.Close()
+
+	base := "/api/v1/calendars/" + strconv.Itoa(int(cal.ID)) + "/events"
+	do := func(method, url, contentType, accept, body string) *http.Response {
+		req, _ := http.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	jsEvent := `{
+		"@type": "Event",
+		"uid": "planning-1",
+		"title": "Planning",
+		"start": "2024-03-04T09:00:00",
+		"timeZone": "Europe/Berlin",
+		"duration": "PT2H",
+		"keywords": {"work": true},
+		"participants": {
+			"p1": {"@type": "Participant", "name": "Alice", "roles": {"owner": true}, "sendTo": {"imip": "mailto:alice@example.com"}},
+			"p2": {"@type": "Participant", "roles": {"attendee": true}, "participationStatus": "tentative", "sendTo": {"imip": "mailto:bob@example.com"}}
+		},
+		"alerts": {"a1": {"@type": "Alert", "trigger": {"@type": "OffsetTrigger", "offset": "-PT10M"}}},
+		"links": {"k1": {"@type": "Link", "href": "https://example.com/slides.pdf", "contentType": "application/pdf"}}
+	}`
+
+	var eventID string
+
+	t.Run("Create from JSCalendar", func(t *testing.T) {
+		resp := do("POST", base, eventusecase.MediaTypeJSCalendar, "", jsEvent)
+		require.Equal(t, fiber.StatusCreated, resp.StatusCode)
+		assert.Equal(t, eventusecase.MediaTypeJSCalendar, resp.Header.Get("Content-Type"))
+
+		location := resp.Header.Get("Content-Location")
+		require.True(t, strings.HasPrefix(location, base+"/"))
+		eventID = strings.TrimPrefix(location, base+"/")
+
+		var obj eventusecase.JSCalendarObject
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&obj))
+		assert.Equal(t, "planning-1", obj.UID)
+		assert.Equal(t, "PT2H", obj.Duration)
+		assert.Len(t, obj.Participants, 2)
+		assert.Len(t, obj.Alerts, 1)
+		assert.Equal(t, map[string]bool{"work": true}, obj.Keywords)
+	})
+
+	t.Run("DTO stays the default", func(t *testing.T) {
+		resp := do("GET", base+"/"+eventID, "", "", "")
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		var res dto.EventResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&res))
+		assert.Equal(t, "Planning", res.Summary)
+		assert.Equal(t, "2024-03-04T08:00:00Z", res.Start.UTC().Format("2006-01-02T15:04:05Z"))
+		assert.Equal(t, 2.0, res.End.Sub(res.Start).Hours())
+	})
+
+	t.Run("Get as jCal", func(t *testing.T) {
+		resp := do("GET", base+"/"+eventID, "", eventusecase.MediaTypeJCal, "")
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Equal(t, eventusecase.MediaTypeJCal, resp.Header.Get("Content-Type"))
+
+		body, _ := io.ReadAll(resp.Body)
+		data, err := eventusecase.FromJCal(body)
+		require.NoError(t, err)
+		events := data.Events()
+		require.Len(t, events, 1)
+		assert.Len(t, events[0].Props[ical.PropAttendee], 1)
+		assert.Equal(t, "mailto:alice@example.com", events[0].Props.Get(ical.PropOrganizer).Value)
+		assert.Equal(t, "application/pdf", events[0].Props.Get(ical.PropAttach).Params.Get(ical.ParamFormatType))
+		require.Len(t, events[0].Children, 1)
+		assert.Equal(t, ical.CompAlarm, events[0].Children[0].Name)
+	})
+
+	t.Run("Replace from jCal", func(t *testing.T) {
+		jcal := `["vcalendar", [["version", {}, "text", "2.0"], ["prodid", {}, "text", "-//Test//EN"]], [
+			["vevent", [
+				["uid", {}, "text", "something-else"],
+				["dtstamp", {}, "date-time", "2024-01-01T00:00:00Z"],
+				["dtstart", {}, "date", "2024-03-05"],
+				["summary", {}, "text", "Planning day"],
+				["categories", {}, "text", "work", "offsite"]
+			], []]
+		]]`
+		resp := do("PUT", base+"/"+eventID, eventusecase.MediaTypeJCal, "application/json", jcal)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		var res dto.EventResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&res))
+		assert.Equal(t, "Planning day", res.Summary)
+		assert.Equal(t, "planning-1", res.UID)
+		assert.True(t, res.IsAllDay)
+	})
+
+	t.Run("List as JSCalendar", func(t *testing.T) {
+		resp := do("GET", base+"?start=2024-03-01T00:00:00Z&end=2024-04-01T00:00:00Z", "", eventusecase.MediaTypeJSCalendar, "")
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		var obj eventusecase.JSCalendarObject
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&obj))
+		assert.Equal(t, "Event", obj.Type)
+		assert.Equal(t, "Planning day", obj.Title)
+		assert.True(t, obj.ShowWithoutTime)
+		assert.Equal(t, map[string]bool{"work": true, "offsite": true}, obj.Keywords)
+	})
+
+	t.Run("Invalid bodies", func(t *testing.T) {
+		resp := do("POST", base, eventusecase.MediaTypeJSCalendar, "", `{"@type": "Event"}`)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+
+		resp = do("POST", base, eventusecase.MediaTypeJCal, "", `["vcalendar", [], []]`)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+}
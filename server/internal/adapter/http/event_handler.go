@@ -1,9 +1,13 @@
 package http
 
 import (
+	"errors"
+	"fmt"
+	"mime"
 	"strconv"
 	"time"
 
+	"github.com/emersion/go-ical"
 	"github.com/gofiber/fiber/v3"
 	"github.com/jherrma/caldav-server/internal/adapter/http/dto"
 	"github.com/jherrma/caldav-server/internal/domain/calendar"
@@ -11,13 +15,17 @@ import (
 )
 
 type EventHandler struct {
-	listUC       *event.ListEventsUseCase
-	getUC        *event.GetEventUseCase
-	createUC     *event.CreateEventUseCase
-	updateUC     *event.UpdateEventUseCase
-	deleteUC     *event.DeleteEventUseCase
-	moveUC       *event.MoveEventUseCase
-	calendarRepo calendar.CalendarRepository
+	listUC        *event.ListEventsUseCase
+	getUC         *event.GetEventUseCase
+	createUC      *event.CreateEventUseCase
+	updateUC      *event.UpdateEventUseCase
+	deleteUC      *event.DeleteEventUseCase
+	moveUC        *event.MoveEventUseCase
+	getDataUC     *event.GetEventDataUseCase
+	listDataUC    *event.ListEventDataUseCase
+	createDataUC  *event.CreateEventDataUseCase
+	replaceDataUC *event.ReplaceEventDataUseCase
+	calendarRepo  calendar.CalendarRepository
 }
 
 func NewEventHandler(
@@ -27,16 +35,24 @@ func NewEventHandler(
 	updateUC *event.UpdateEventUseCase,
 	deleteUC *event.DeleteEventUseCase,
 	moveUC *event.MoveEventUseCase,
+	getDataUC *event.GetEventDataUseCase,
+	listDataUC *event.ListEventDataUseCase,
+	createDataUC *event.CreateEventDataUseCase,
+	replaceDataUC *event.ReplaceEventDataUseCase,
 	calendarRepo calendar.CalendarRepository,
 ) *EventHandler {
 	return &EventHandler{
-		listUC:       listUC,
-		getUC:        getUC,
-		createUC:     createUC,
-		updateUC:     updateUC,
-		deleteUC:     deleteUC,
-		moveUC:       moveUC,
-		calendarRepo: calendarRepo,
+		listUC:        listUC,
+		getUC:         getUC,
+		createUC:      createUC,
+		updateUC:      updateUC,
+		deleteUC:      deleteUC,
+		moveUC:        moveUC,
+		getDataUC:     getDataUC,
+		listDataUC:    listDataUC,
+		createDataUC:  createDataUC,
+		replaceDataUC: replaceDataUC,
+		calendarRepo:  calendarRepo,
 	}
 }
 
@@ -62,11 +78,110 @@ func (h *EventHandler) ownsEvent(c fiber.Ctx, eventUUID string) bool {
 	return err == nil && cal != nil && cal.UserID == userID
 }
 
+// readOnlyCalendar reports whether the calendar with the given numeric id
+// mirrors a subscribed feed, whose events cannot be changed.
+func (h *EventHandler) readOnlyCalendar(c fiber.Ctx, calendarID uint) bool {
+	cal, err := h.calendarRepo.GetByID(c.Context(), calendarID)
+	return err == nil && cal != nil && cal.ReadOnly
+}
+
+// readOnlyEvent reports whether the event identified by eventUUID belongs to
+// a read-only calendar.
+func (h *EventHandler) readOnlyEvent(c fiber.Ctx, eventUUID string) bool {
+	obj, err := h.calendarRepo.GetCalendarObjectByUUID(c.Context(), eventUUID)
+	return err == nil && obj != nil && h.readOnlyCalendar(c, obj.CalendarID)
+}
+
+// eventMediaType picks the event representation a client asked for in its
+// Accept header: the event DTO, jCal (RFC 7265) or JSCalendar (RFC 8984).
+// Clients that accept none of them still get the DTO.
+func eventMediaType(c fiber.Ctx) string {
+	if t := c.Accepts(fiber.MIMEApplicationJSON, event.MediaTypeJCal, event.MediaTypeJSCalendar); t != "" {
+		return t
+	}
+	return fiber.MIMEApplicationJSON
+}
+
+// decodeEventBody parses a jCal or JSCalendar request body into an
+// iCalendar. ok is false when the body is in neither format and holds the
+// event DTO.
+func decodeEventBody(c fiber.Ctx) (cal *ical.Calendar, mediaType string, ok bool, err error) {
+	mediaType, _, _ = mime.ParseMediaType(c.Get(fiber.HeaderContentType))
+	switch mediaType {
+	case event.MediaTypeJCal:
+		cal, err = event.FromJCal(c.Body())
+	case event.MediaTypeJSCalendar:
+		cal, err = event.FromJSCalendar(c.Body())
+	default:
+		return nil, "", false, nil
+	}
+	return cal, mediaType, true, err
+}
+
+// calendarRepresentation renders an iCalendar as jCal or JSCalendar
+func calendarRepresentation(mediaType string, cal *ical.Calendar) interface{} {
+	if mediaType == event.MediaTypeJSCalendar {
+		return event.ToJSCalendar(cal)
+	}
+	return event.ToJCal(cal.Component)
+}
+
+// eventResponse is the event DTO of a stored object. Tasks may lack a start
+// or end, which are then left zero.
+func eventResponse(obj *calendar.CalendarObject) dto.EventResponse {
+	res := dto.EventResponse{
+		ID:         obj.UUID,
+		CalendarID: obj.CalendarID,
+		UID:        obj.UID,
+		Summary:    obj.Summary,
+		IsAllDay:   obj.IsAllDay,
+	}
+	if obj.StartTime != nil {
+		res.Start = *obj.StartTime
+	}
+	if obj.EndTime != nil {
+		res.End = *obj.EndTime
+	}
+	return res
+}
+
+// sendWrittenEvent answers a jCal or JSCalendar write. Without an Accept
+// header the response mirrors the format of the request body.
+func (h *EventHandler) sendWrittenEvent(c fiber.Ctx, status int, bodyType string, obj *calendar.CalendarObject) error {
+	c.Set(fiber.HeaderContentLocation, fmt.Sprintf("/api/v1/calendars/%d/events/%s", obj.CalendarID, obj.UUID))
+
+	mediaType := bodyType
+	if c.Get(fiber.HeaderAccept) != "" {
+		mediaType = eventMediaType(c)
+	}
+	if mediaType == fiber.MIMEApplicationJSON {
+		return c.Status(status).JSON(eventResponse(obj))
+	}
+	cal, err := event.ParseICalData(obj.ICalData)
+	if err != nil {
+		return ErrorResponse(c, fiber.StatusInternalServerError, "Failed to read event")
+	}
+	return c.Status(status).JSON(calendarRepresentation(mediaType, cal), mediaType)
+}
+
+// eventDataError maps a failed jCal or JSCalendar write to a response
+func eventDataError(c fiber.Ctx, err error) error {
+	if errors.Is(err, event.ErrInvalidEventData) || errors.Is(err, event.ErrInvalidJCal) || errors.Is(err, event.ErrInvalidJSCalendar) {
+		return BadRequestResponse(c, err.Error())
+	}
+	if errors.Is(err, event.ErrEventUIDConflict) {
+		return ErrorResponse(c, fiber.StatusConflict, err.Error())
+	}
+	return ErrorResponse(c, fiber.StatusInternalServerError, "Failed to save event")
+}
+
 // List godoc
 // @Summary      List events
-// @Description  Get events from calendar
+// @Description  Get events from calendar. With an Accept header of application/calendar+json or
+// @Description  application/jscalendar+json the full events of the range are returned as one jCal
+// @Description  vcalendar or a JSCalendar Group, with recurring events unexpanded.
 // @Tags         Events
-// @Produce      json
+// @Produce      json,application/calendar+json,application/jscalendar+json
 // @Param        calendar_id  path      integer  true   "Calendar ID"
 // @Param        start        query     string   false  "Start time (RFC3339)"
 // @Param        end          query     string   false  "End time (RFC3339)"
@@ -95,6 +210,18 @@ func (h *EventHandler) List(c fiber.Ctx) error {
 	}
 	expand := expandStr == "true"
 
+	if mediaType := eventMediaType(c); mediaType != fiber.MIMEApplicationJSON {
+		cal, err := h.listDataUC.Execute(c.Context(), event.ListEventsInput{
+			CalendarID: uint(calendarID),
+			Start:      start,
+			End:        end,
+		})
+		if err != nil {
+			return ErrorResponse(c, fiber.StatusInternalServerError, "Failed to list events")
+		}
+		return c.JSON(calendarRepresentation(mediaType, cal), mediaType)
+	}
+
 	instances, err := h.listUC.Execute(c.Context(), event.ListEventsInput{
 		CalendarID: uint(calendarID),
 		Start:      start,
@@ -130,9 +257,10 @@ func (h *EventHandler) List(c fiber.Ctx) error {
 
 // Get godoc
 // @Summary      Get event
-// @Description  Get event by ID
+// @Description  Get event by ID. The full event, with attendees, alarms and exceptions, is
+// @Description  available as jCal or JSCalendar through the Accept header.
 // @Tags         Events
-// @Produce      json
+// @Produce      json,application/calendar+json,application/jscalendar+json
 // @Param        calendar_id  path      integer  true  "Calendar ID"
 // @Param        event_id     path      string   true  "Event UUID"
 // @Success      200          {object}  dto.EventResponse
@@ -145,6 +273,15 @@ func (h *EventHandler) Get(c fiber.Ctx) error {
 	if !h.ownsEvent(c, eventID) {
 		return ErrorResponse(c, fiber.StatusNotFound, "Event not found")
 	}
+
+	if mediaType := eventMediaType(c); mediaType != fiber.MIMEApplicationJSON {
+		_, cal, err := h.getDataUC.Execute(c.Context(), eventID)
+		if err != nil {
+			return ErrorResponse(c, fiber.StatusNotFound, "Event not found")
+		}
+		return c.JSON(calendarRepresentation(mediaType, cal), mediaType)
+	}
+
 	obj, err := h.getUC.Execute(c.Context(), eventID)
 	if err != nil {
 		return ErrorResponse(c, fiber.StatusNotFound, "Event not found")
@@ -163,14 +300,16 @@ func (h *EventHandler) Get(c fiber.Ctx) error {
 
 // Create godoc
 // @Summary      Create event
-// @Description  Create a new event
+// @Description  Create a new event. A jCal or JSCalendar body, marked by its Content-Type, is
+// @Description  stored as is; the response mirrors its format unless an Accept header is sent.
 // @Tags         Events
-// @Accept       json
-// @Produce      json
+// @Accept       json,application/calendar+json,application/jscalendar+json
+// @Produce      json,application/calendar+json,application/jscalendar+json
 // @Param        calendar_id  path      integer                 true  "Calendar ID"
 // @Param        event        body      dto.CreateEventRequest  true  "Event details"
 // @Success      201          {object}  dto.EventResponse
 // @Failure      400          {object}  ErrorResponseBody
+// @Failure      409          {object}  ErrorResponseBody  "UID already used in the calendar"
 // @Failure      500          {object}  ErrorResponseBody
 // @Security     BearerAuth
 // @Router       /calendars/{calendar_id}/events [post]
@@ -179,6 +318,22 @@ func (h *EventHandler) Create(c fiber.Ctx) error {
 	if !h.ownsCalendar(c, uint(calendarID)) {
 		return ErrorResponse(c, fiber.StatusNotFound, "Calendar not found")
 	}
+	if h.readOnlyCalendar(c, uint(calendarID)) {
+		return ErrorResponse(c, fiber.StatusForbidden, "Subscribed calendars are read-only")
+	}
+
+	cal, bodyType, isData, err := decodeEventBody(c)
+	if isData {
+		if err != nil {
+			return BadRequestResponse(c, err.Error())
+		}
+		obj, err := h.createDataUC.Execute(c.Context(), uint(calendarID), cal)
+		if err != nil {
+			return eventDataError(c, err)
+		}
+		return h.sendWrittenEvent(c, fiber.StatusCreated, bodyType, obj)
+	}
+
 	var req dto.CreateEventRequest
 	if err := c.Bind().Body(&req); err != nil {
 		return ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
@@ -216,10 +371,11 @@ func (h *EventHandler) Create(c fiber.Ctx) error {
 
 // Update godoc
 // @Summary      Update event
-// @Description  Update event details
+// @Description  Update event details. A jCal or JSCalendar body replaces the whole event, keeping
+// @Description  its UID; recurrence_id and scope do not apply to it.
 // @Tags         Events
-// @Accept       json
-// @Produce      json
+// @Accept       json,application/calendar+json,application/jscalendar+json
+// @Produce      json,application/calendar+json,application/jscalendar+json
 // @Param        calendar_id    path      integer                 true   "Calendar ID"
 // @Param        event_id       path      string                  true   "Event UUID"
 // @Param        recurrence_id  query     string                  false  "Recurrence ID (for recurring events)"
@@ -235,6 +391,22 @@ func (h *EventHandler) Update(c fiber.Ctx) error {
 	if !h.ownsEvent(c, eventID) {
 		return ErrorResponse(c, fiber.StatusNotFound, "Event not found")
 	}
+	if h.readOnlyEvent(c, eventID) {
+		return ErrorResponse(c, fiber.StatusForbidden, "Subscribed calendars are read-only")
+	}
+
+	cal, bodyType, isData, err := decodeEventBody(c)
+	if isData {
+		if err != nil {
+			return BadRequestResponse(c, err.Error())
+		}
+		obj, err := h.replaceDataUC.Execute(c.Context(), eventID, cal)
+		if err != nil {
+			return eventDataError(c, err)
+		}
+		return h.sendWrittenEvent(c, fiber.StatusOK, bodyType, obj)
+	}
+
 	var req dto.UpdateEventRequest
 	if err := c.Bind().Body(&req); err != nil {
 		return ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
@@ -291,6 +463,9 @@ func (h *EventHandler) Delete(c fiber.Ctx) error {
 	if !h.ownsEvent(c, eventID) {
 		return ErrorResponse(c, fiber.StatusNotFound, "Event not found")
 	}
+	if h.readOnlyEvent(c, eventID) {
+		return ErrorResponse(c, fiber.StatusForbidden, "Subscribed calendars are read-only")
+	}
 	scope := c.Query("scope", "all")
 	recurrenceID := c.Query("recurrence_id")
 
@@ -320,6 +495,9 @@ func (h *EventHandler) Move(c fiber.Ctx) error {
 	if !h.ownsEvent(c, eventID) {
 		return ErrorResponse(c, fiber.StatusNotFound, "Event not found")
 	}
+	if h.readOnlyEvent(c, eventID) {
+		return ErrorResponse(c, fiber.StatusForbidden, "Subscribed calendars are read-only")
+	}
 	var req dto.MoveEventRequest
 	if err := c.Bind().Body(&req); err != nil {
 		return ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
@@ -329,6 +507,9 @@ func (h *EventHandler) Move(c fiber.Ctx) error {
 	if !h.ownsCalendar(c, uint(targetCalendarID)) {
 		return ErrorResponse(c, fiber.StatusNotFound, "Calendar not found")
 	}
+	if h.readOnlyCalendar(c, uint(targetCalendarID)) {
+		return ErrorResponse(c, fiber.StatusForbidden, "Subscribed calendars are read-only")
+	}
 	obj, err := h.moveUC.Execute(c.Context(), event.MoveEventInput{
 		EventUUID:        eventID,
 		TargetCalendarID: uint(targetCalendarID),
@@ -77,11 +77,16 @@ func setupEventHandlerTest(t *testing.T) (*fiber.App, database.Database, *user.U
 	eventUpdateUC := eventusecase.NewUpdateEventUseCase(calendarRepo)
 	eventDeleteUC := eventusecase.NewDeleteEventUseCase(calendarRepo)
 	eventMoveUC := eventusecase.NewMoveEventUseCase(calendarRepo)
+	eventGetDataUC := eventusecase.NewGetEventDataUseCase(calendarRepo)
+	eventListDataUC := eventusecase.NewListEventDataUseCase(calendarRepo)
+	eventCreateDataUC := eventusecase.NewCreateEventDataUseCase(calendarRepo)
+	eventReplaceDataUC := eventusecase.NewReplaceEventDataUseCase(calendarRepo)
 
-	handler := NewEventHandler(eventListUC, eventGetUC, eventCreateUC, eventUpdateUC, eventDeleteUC, eventMoveUC, calendarRepo)
+	handler := NewEventHandler(eventListUC, eventGetUC, eventCreateUC, eventUpdateUC, eventDeleteUC, eventMoveUC,
+		eventGetDataUC, eventListDataUC, eventCreateDataUC, eventReplaceDataUC, calendarRepo)
 
 	v1 := app.Group("/api/v1")
-	calendars := v1.Group("/calendars", Authenticate(jwtManager, userRepo))
+	calendars := v1.Group("/calendars", Authenticate(jwtManager, userRepo, nil))
 	events := calendars.Group("/:calendar_id/events")
 	events.Post("/", handler.Create)
 	events.Get("/", handler.List)
@@ -382,4 +387,48 @@ func TestEventHandler(t *testing.T) {
 		assert.Equal(t, 2, dayCounts["Splittable Series"], "Should have 2 instances of original summary")
 		assert.Equal(t, 3, dayCounts["Shared Future"], "Should have 3 instances of new summary")
 	})
+
+	t.Run("UID Conflict", func(t *testing.T) {
+		body := `["vcalendar",[["version",{},"text","2.0"],["prodid",{},"text","-//Test//EN"]],[["vevent",[` +
+			`["uid",{},"text","dup-uid"],["dtstamp",{},"date-time","2024-01-01T00:00:00Z"],` +
+			`["dtstart",{},"date-time","2024-01-01T10:00:00Z"],["dtend",{},"date-time","2024-01-01T11:00:00Z"],` +
+			`["summary",{},"text","Dup"]],[]]]]`
+		create := func() int {
+			req, _ := http.NewRequest("POST", "/api/v1/calendars/"+strconv.Itoa(int(cal.ID))+"/events", bytes.NewReader([]byte(body)))
+			req.Header.Set("Authorization", "Bearer "+token)
+			req.Header.Set("Content-Type", "application/calendar+json")
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			return resp.StatusCode
+		}
+		require.Equal(t, fiber.StatusCreated, create())
+		assert.Equal(t, fiber.StatusConflict, create())
+	})
+
+	t.Run("Read-Only Calendar", func(t *testing.T) {
+		send := func(method, target string, body any) int {
+			data, _ := json.Marshal(body)
+			req, _ := http.NewRequest(method, "/api/v1/calendars/"+strconv.Itoa(int(cal.ID))+"/events"+target, bytes.NewReader(data))
+			req.Header.Set("Authorization", "Bearer "+token)
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			if method == http.MethodPost && target == "" && resp.StatusCode == fiber.StatusCreated {
+				var res dto.EventResponse
+				json.NewDecoder(resp.Body).Decode(&res)
+				eventID = res.ID
+			}
+			return resp.StatusCode
+		}
+		event := dto.CreateEventRequest{Summary: "Feed Event", Start: time.Now(), End: time.Now().Add(time.Hour)}
+		require.Equal(t, fiber.StatusCreated, send(http.MethodPost, "", event))
+
+		// Calendars of feed subscriptions only change through the fetcher
+		require.NoError(t, db.DB().Model(cal).Update("read_only", true).Error)
+		defer db.DB().Model(cal).Update("read_only", false)
+
+		assert.Equal(t, fiber.StatusForbidden, send(http.MethodPost, "", event))
+		assert.Equal(t, fiber.StatusForbidden, send(http.MethodPut, "/"+eventID, map[string]string{"summary": "Changed"}))
+		assert.Equal(t, fiber.StatusForbidden, send(http.MethodDelete, "/"+eventID, nil))
+	})
 }
@@ -0,0 +1,233 @@
+package http
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/jherrma/caldav-server/internal/usecase/usergroup"
+)
+
+// GroupHandler manages user groups over the REST API
+type GroupHandler struct {
+	groups *usergroup.Service
+}
+
+func NewGroupHandler(groups *usergroup.Service) *GroupHandler {
+	return &GroupHandler{groups: groups}
+}
+
+// AddGroupMemberRequest identifies the user to add to a group
+type AddGroupMemberRequest struct {
+	UserIdentifier string `json:"user_identifier"` // Email or Username
+}
+
+// List godoc
+// @Summary      List groups
+// @Description  List the groups the user can share with: groups managed by administrators and the
+// @Description  groups the user owns or belongs to. Administrators see every group.
+// @Tags         Groups
+// @Produce      json
+// @Success      200  {array}   usergroup.GroupOutput
+// @Failure      401  {object}  ErrorResponseBody
+// @Failure      500  {object}  ErrorResponseBody
+// @Security     BearerAuth
+// @Router       /groups [get]
+func (h *GroupHandler) List(c fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	output, err := h.groups.List(c.Context(), userID)
+	if err != nil {
+		return ErrorResponse(c, fiber.StatusInternalServerError, "Failed to list groups")
+	}
+	return c.JSON(output)
+}
+
+// Create godoc
+// @Summary      Create group
+// @Description  Create a group owned by the user. Administrators may set admin_managed to create a
+// @Description  group that every user can share with and only administrators manage.
+// @Tags         Groups
+// @Accept       json
+// @Produce      json
+// @Param        group  body      usergroup.CreateInput  true  "Group"
+// @Success      201    {object}  usergroup.GroupOutput
+// @Failure      400    {object}  ErrorResponseBody
+// @Failure      403    {object}  ErrorResponseBody
+// @Failure      500    {object}  ErrorResponseBody
+// @Security     BearerAuth
+// @Router       /groups [post]
+func (h *GroupHandler) Create(c fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	var req usergroup.CreateInput
+	if err := c.Bind().JSON(&req); err != nil {
+		return BadRequestResponse(c, "Invalid request body")
+	}
+
+	output, err := h.groups.Create(c.Context(), userID, req)
+	if err != nil {
+		return h.handleError(c, err, "Failed to create group")
+	}
+	return c.Status(fiber.StatusCreated).JSON(output)
+}
+
+// Get godoc
+// @Summary      Get group
+// @Description  Get a group with its members
+// @Tags         Groups
+// @Produce      json
+// @Param        id   path      string  true  "Group UUID"
+// @Success      200  {object}  usergroup.GroupOutput
+// @Failure      404  {object}  ErrorResponseBody
+// @Failure      500  {object}  ErrorResponseBody
+// @Security     BearerAuth
+// @Router       /groups/{id} [get]
+func (h *GroupHandler) Get(c fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	output, err := h.groups.Get(c.Context(), userID, c.Params("id"))
+	if err != nil {
+		return h.handleError(c, err, "Failed to load group")
+	}
+	return c.JSON(output)
+}
+
+// Update godoc
+// @Summary      Update group
+// @Description  Rename a group or change its description
+// @Tags         Groups
+// @Accept       json
+// @Produce      json
+// @Param        id     path      string                 true  "Group UUID"
+// @Param        group  body      usergroup.UpdateInput  true  "Changes"
+// @Success      200    {object}  usergroup.GroupOutput
+// @Failure      400    {object}  ErrorResponseBody
+// @Failure      403    {object}  ErrorResponseBody
+// @Failure      404    {object}  ErrorResponseBody
+// @Failure      500    {object}  ErrorResponseBody
+// @Security     BearerAuth
+// @Router       /groups/{id} [patch]
+func (h *GroupHandler) Update(c fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	var req usergroup.UpdateInput
+	if err := c.Bind().JSON(&req); err != nil {
+		return BadRequestResponse(c, "Invalid request body")
+	}
+
+	output, err := h.groups.Update(c.Context(), userID, c.Params("id"), req)
+	if err != nil {
+		return h.handleError(c, err, "Failed to update group")
+	}
+	return c.JSON(output)
+}
+
+// Delete godoc
+// @Summary      Delete group
+// @Description  Delete a group. Calendars and address books shared with the group are no longer
+// @Description  available to its members.
+// @Tags         Groups
+// @Param        id   path  string  true  "Group UUID"
+// @Success      204
+// @Failure      403  {object}  ErrorResponseBody
+// @Failure      404  {object}  ErrorResponseBody
+// @Failure      500  {object}  ErrorResponseBody
+// @Security     BearerAuth
+// @Router       /groups/{id} [delete]
+func (h *GroupHandler) Delete(c fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	if err := h.groups.Delete(c.Context(), userID, c.Params("id")); err != nil {
+		return h.handleError(c, err, "Failed to delete group")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// AddMember godoc
+// @Summary      Add group member
+// @Description  Add a user to a group by email address or username. Members of groups managed by
+// @Description  administrators get the group's shares immediately; users added to a user's group
+// @Description  are invited and get them once they accept.
+// @Tags         Groups
+// @Accept       json
+// @Produce      json
+// @Param        id      path      string                 true  "Group UUID"
+// @Param        member  body      AddGroupMemberRequest  true  "Member"
+// @Success      200     {object}  usergroup.GroupOutput
+// @Failure      400     {object}  ErrorResponseBody
+// @Failure      403     {object}  ErrorResponseBody
+// @Failure      404     {object}  ErrorResponseBody
+// @Failure      500     {object}  ErrorResponseBody
+// @Security     BearerAuth
+// @Router       /groups/{id}/members [post]
+func (h *GroupHandler) AddMember(c fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	var req AddGroupMemberRequest
+	if err := c.Bind().JSON(&req); err != nil || req.UserIdentifier == "" {
+		return BadRequestResponse(c, "user_identifier is required")
+	}
+
+	output, err := h.groups.AddMember(c.Context(), userID, c.Params("id"), req.UserIdentifier)
+	if err != nil {
+		return h.handleError(c, err, "Failed to add group member")
+	}
+	return c.JSON(output)
+}
+
+// AcceptInvitation godoc
+// @Summary      Accept group invitation
+// @Description  Join a group the current user was added to by its owner. Invitations are declined by
+// @Description  removing oneself from the group.
+// @Tags         Groups
+// @Produce      json
+// @Param        id   path      string  true  "Group UUID"
+// @Success      200  {object}  usergroup.GroupOutput
+// @Failure      404  {object}  ErrorResponseBody
+// @Failure      500  {object}  ErrorResponseBody
+// @Security     BearerAuth
+// @Router       /groups/{id}/accept [post]
+func (h *GroupHandler) AcceptInvitation(c fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	output, err := h.groups.AcceptInvitation(c.Context(), userID, c.Params("id"))
+	if err != nil {
+		return h.handleError(c, err, "Failed to accept group invitation")
+	}
+	return c.JSON(output)
+}
+
+// RemoveMember godoc
+// @Summary      Remove group member
+// @Description  Remove a member from a group. Members may remove themselves to leave a group or
+// @Description  decline an invitation.
+// @Tags         Groups
+// @Param        id       path  string  true  "Group UUID"
+// @Param        user_id  path  string  true  "User UUID"
+// @Success      204
+// @Failure      403  {object}  ErrorResponseBody
+// @Failure      404  {object}  ErrorResponseBody
+// @Failure      500  {object}  ErrorResponseBody
+// @Security     BearerAuth
+// @Router       /groups/{id}/members/{user_id} [delete]
+func (h *GroupHandler) RemoveMember(c fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	if err := h.groups.RemoveMember(c.Context(), userID, c.Params("id"), c.Params("user_id")); err != nil {
+		return h.handleError(c, err, "Failed to remove group member")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (h *GroupHandler) handleError(c fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, usergroup.ErrNotFound):
+		return ErrorResponse(c, fiber.StatusNotFound, "Group not found")
+	case errors.Is(err, usergroup.ErrForbidden):
+		return ForbiddenResponse(c, "You cannot manage this group")
+	case errors.Is(err, usergroup.ErrInvalidInput):
+		return BadRequestResponse(c, err.Error())
+	default:
+		return ErrorResponse(c, fiber.StatusInternalServerError, message)
+	}
+}
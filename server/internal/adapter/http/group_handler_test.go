@@ -0,0 +1,197 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/jherrma/caldav-server/internal/adapter/repository"
+	"github.com/jherrma/caldav-server/internal/domain/calendar"
+	"github.com/jherrma/caldav-server/internal/usecase/usergroup"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupHandler(t *testing.T) {
+	app, db, _ := setupTestApp(t)
+	ctx := context.Background()
+
+	tokens := make(map[string]string)
+	// The first registered user is an administrator
+	for _, name := range []string{"admin", "lead", "staff", "outsider"} {
+		body, _ := json.Marshal(map[string]string{"email": name + "@example.com", "password": "Password123!", "display_name": name})
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/register", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		tokens[name] = loginForTest(t, app, name+"@example.com", "Password123!", "test").AccessToken
+	}
+
+	userRepo := repository.NewUserRepository(db.DB())
+	lead, err := userRepo.GetByEmail(ctx, "lead@example.com")
+	require.NoError(t, err)
+	staff, err := userRepo.GetByEmail(ctx, "staff@example.com")
+	require.NoError(t, err)
+	cal := &calendar.Calendar{UUID: "team-cal", UserID: lead.ID, Name: "Team", Path: "team"}
+	require.NoError(t, repository.NewCalendarRepository(db.DB()).Create(ctx, cal))
+
+	do := func(as, method, target string, body any) *http.Response {
+		var data []byte
+		if body != nil {
+			data, _ = json.Marshal(body)
+		}
+		req := httptest.NewRequest(method, target, bytes.NewReader(data))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+tokens[as])
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp
+	}
+	sharedCalendars := func(as string) []string {
+		resp := do(as, http.MethodGet, "/api/v1/calendars", nil)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		var out struct {
+			Calendars []struct {
+				UUID   string `json:"uuid"`
+				Shared bool   `json:"shared"`
+			} `json:"calendars"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+		var ids []string
+		for _, c := range out.Calendars {
+			if c.Shared {
+				ids = append(ids, c.UUID)
+			}
+		}
+		return ids
+	}
+
+	resp := do("lead", http.MethodPost, "/api/v1/groups", map[string]any{"name": "Team", "admin_managed": true})
+	assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+	resp = do("lead", http.MethodPost, "/api/v1/groups", map[string]any{"name": " "})
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+
+	resp = do("lead", http.MethodPost, "/api/v1/groups", map[string]any{"name": "Team", "description": "Support staff"})
+	require.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	var group usergroup.GroupOutput
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&group))
+	assert.False(t, group.AdminManaged)
+	assert.True(t, group.CanManage)
+
+	resp = do("lead", http.MethodPost, "/api/v1/groups/"+group.ID+"/members", map[string]string{"user_identifier": "staff@example.com"})
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&group))
+	require.Len(t, group.Members, 1)
+	assert.Equal(t, staff.UUID, group.Members[0].ID)
+	assert.True(t, group.Members[0].Pending)
+
+	t.Run("Visibility", func(t *testing.T) {
+		resp := do("outsider", http.MethodGet, "/api/v1/groups/"+group.ID, nil)
+		assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+
+		// Users added to a user's group are invited until they accept
+		resp = do("staff", http.MethodGet, "/api/v1/groups/"+group.ID, nil)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		var out usergroup.GroupOutput
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+		assert.False(t, out.CanManage)
+		assert.True(t, out.Invited)
+
+		resp = do("outsider", http.MethodPost, "/api/v1/groups/"+group.ID+"/accept", nil)
+		assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+		resp = do("staff", http.MethodPost, "/api/v1/groups/"+group.ID+"/accept", nil)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+		assert.False(t, out.Invited)
+		assert.False(t, out.Members[0].Pending)
+
+		resp = do("staff", http.MethodPatch, "/api/v1/groups/"+group.ID, map[string]string{"name": "Mine"})
+		assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+	})
+
+	t.Run("Group Share Follows Membership", func(t *testing.T) {
+		resp := do("lead", http.MethodPost, fmt.Sprintf("/api/v1/calendars/%d/shares", cal.ID),
+			map[string]string{"group_id": group.ID, "permission": "read"})
+		require.Equal(t, fiber.StatusCreated, resp.StatusCode)
+		var share map[string]any
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&share))
+		assert.Nil(t, share["shared_with"])
+		assert.Equal(t, "Team", share["shared_with_group"].(map[string]any)["name"])
+
+		resp = do("lead", http.MethodPost, fmt.Sprintf("/api/v1/calendars/%d/shares", cal.ID),
+			map[string]string{"group_id": group.ID, "permission": "read-write"})
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+
+		assert.Equal(t, []string{"team-cal"}, sharedCalendars("staff"))
+		assert.Empty(t, sharedCalendars("outsider"))
+
+		// A direct share raises the permission of the member
+		resp = do("lead", http.MethodPost, fmt.Sprintf("/api/v1/calendars/%d/shares", cal.ID),
+			map[string]string{"user_identifier": "staff@example.com", "permission": "read-write"})
+		require.Equal(t, fiber.StatusCreated, resp.StatusCode)
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&share))
+		resp = do("staff", http.MethodPost, "/api/v1/invitations/"+share["id"].(string)+"/accept", nil)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Equal(t, []string{"team-cal"}, sharedCalendars("staff"))
+		perm, err := repository.NewCalendarRepository(db.DB()).GetUserPermission(ctx, cal.ID, staff.ID)
+		require.NoError(t, err)
+		assert.Equal(t, calendar.PermissionReadWrite, perm)
+
+		// Members leave by removing themselves
+		resp = do("staff", http.MethodDelete, "/api/v1/groups/"+group.ID+"/members/"+staff.UUID, nil)
+		require.Equal(t, fiber.StatusNoContent, resp.StatusCode)
+
+		// Nothing is pushed onto invited users before they accept
+		resp = do("lead", http.MethodPost, "/api/v1/groups/"+group.ID+"/members", map[string]string{"user_identifier": "outsider@example.com"})
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Empty(t, sharedCalendars("outsider"))
+		resp = do("outsider", http.MethodPost, "/api/v1/groups/"+group.ID+"/accept", nil)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Equal(t, []string{"team-cal"}, sharedCalendars("outsider"))
+	})
+
+	t.Run("Delete Removes Group Shares", func(t *testing.T) {
+		resp := do("outsider", http.MethodDelete, "/api/v1/groups/"+group.ID, nil)
+		assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+
+		resp = do("lead", http.MethodDelete, "/api/v1/groups/"+group.ID, nil)
+		require.Equal(t, fiber.StatusNoContent, resp.StatusCode)
+		assert.Empty(t, sharedCalendars("outsider"))
+
+		resp = do("lead", http.MethodGet, "/api/v1/groups", nil)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		var groups []usergroup.GroupOutput
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&groups))
+		assert.Empty(t, groups)
+	})
+
+	t.Run("Admin Managed", func(t *testing.T) {
+		resp := do("admin", http.MethodPost, "/api/v1/groups", map[string]any{"name": "Everyone", "admin_managed": true})
+		require.Equal(t, fiber.StatusCreated, resp.StatusCode)
+		var everyone usergroup.GroupOutput
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&everyone))
+		assert.True(t, everyone.AdminManaged)
+		assert.Nil(t, everyone.Owner)
+
+		// Every user may share with it, only administrators manage it
+		resp = do("lead", http.MethodPost, fmt.Sprintf("/api/v1/calendars/%d/shares", cal.ID),
+			map[string]string{"group_id": everyone.ID, "permission": "read"})
+		assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+		resp = do("lead", http.MethodPost, "/api/v1/groups/"+everyone.ID+"/members", map[string]string{"user_identifier": "staff@example.com"})
+		assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+
+		// Members of administrators' groups join without accepting
+		resp = do("admin", http.MethodPost, "/api/v1/groups/"+everyone.ID+"/members", map[string]string{"user_identifier": "staff@example.com"})
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&everyone))
+		require.Len(t, everyone.Members, 1)
+		assert.False(t, everyone.Members[0].Pending)
+		assert.Equal(t, []string{"team-cal"}, sharedCalendars("staff"))
+	})
+}
@@ -1,10 +1,13 @@
 package http
 
 import (
+	"encoding/json"
 	"io"
 	"strconv"
+	"strings"
 
 	"github.com/gofiber/fiber/v3"
+	"github.com/jherrma/caldav-server/internal/domain/addressbook"
 	"github.com/jherrma/caldav-server/internal/usecase/importexport"
 )
 
@@ -51,7 +54,10 @@ func (h *ImportHandler) ImportCalendar(c fiber.Ctx) error {
 	return c.JSON(result)
 }
 
-// ImportContact handles POST /api/v1/addressbooks/:id/import
+// ImportContact handles POST /api/v1/addressbooks/:id/import. Besides vCard
+// it takes CSV (?format=csv, a text/csv body or a .csv upload) laid out as
+// ?mapping=google|outlook, or ?mapping=custom with a "columns" object from
+// CSV header to field. ?dry_run=true only parses and returns the contacts.
 func (h *ImportHandler) ImportContact(c fiber.Ctx) error {
 	userID, err := GetUserIDFromContext(c)
 	if err != nil {
@@ -66,6 +72,15 @@ func (h *ImportHandler) ImportContact(c fiber.Ctx) error {
 	// Get import options
 	opts := importexport.ImportOptions{
 		DuplicateHandling: c.Query("duplicate_handling", "skip"),
+		DryRun:            c.Query("dry_run") == "true",
+		Format:            contactImportFormat(c),
+	}
+	if opts.Format == importexport.FormatCSV {
+		mapping, err := csvImportMapping(c)
+		if err != nil {
+			return ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+		}
+		opts.CSVMapping = mapping
 	}
 
 	// Try to get data from file upload first
@@ -113,11 +128,58 @@ func (h *ImportHandler) getImportData(c fiber.Ctx) ([]byte, error) {
 		return []byte(req.Data), nil
 	}
 
-	// Check for raw body (text/calendar or text/vcard content type)
+	// Check for raw body (text/calendar, text/vcard or text/csv content type)
 	contentType := c.Get("Content-Type")
-	if contentType == "text/calendar" || contentType == "text/vcard" {
+	if contentType == "text/calendar" || contentType == "text/vcard" || contentType == "text/csv" {
 		return c.Body(), nil
 	}
 
 	return nil, fiber.NewError(fiber.StatusBadRequest, "No import data provided. Upload a file or send data in request body.")
 }
+
+// contactImportFormat picks the format of contact import data from the
+// format query parameter, the content type or the uploaded file's name
+func contactImportFormat(c fiber.Ctx) string {
+	if format := c.Query("format"); format != "" {
+		return strings.ToLower(format)
+	}
+	if c.Get("Content-Type") == "text/csv" {
+		return importexport.FormatCSV
+	}
+	if file, err := c.FormFile("file"); err == nil && strings.HasSuffix(strings.ToLower(file.Filename), ".csv") {
+		return importexport.FormatCSV
+	}
+	return importexport.FormatVCard
+}
+
+// csvImportMapping resolves the mapping query parameter. Custom column
+// mappings come as JSON in the "columns" form field or request body field.
+func csvImportMapping(c fiber.Ctx) (*addressbook.CSVMapping, error) {
+	name := c.Query("mapping", addressbook.CSVMappingGoogle)
+	if mapping, ok := addressbook.CSVMappingByName(name); ok {
+		return &mapping, nil
+	}
+	if name != addressbook.CSVMappingCustom {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Unknown CSV mapping. Use google, outlook or custom.")
+	}
+
+	var columns map[string]string
+	if raw := c.FormValue("columns"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &columns); err != nil {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "columns must be a JSON object from CSV header to field")
+		}
+	} else {
+		var req struct {
+			Columns map[string]string `json:"columns"`
+		}
+		if err := c.Bind().JSON(&req); err == nil {
+			columns = req.Columns
+		}
+	}
+
+	mapping, err := addressbook.NewCSVMapping(columns)
+	if err != nil {
+		return nil, err
+	}
+	return &mapping, nil
+}
@@ -0,0 +1,145 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	authadapter "github.com/jherrma/caldav-server/internal/adapter/auth"
+	"github.com/jherrma/caldav-server/internal/adapter/repository"
+	"github.com/jherrma/caldav-server/internal/domain/addressbook"
+	"github.com/jherrma/caldav-server/internal/domain/user"
+	"github.com/jherrma/caldav-server/internal/usecase/importexport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportHandler_ImportContactCSV(t *testing.T) {
+	app, db, cfg := setupTestApp(t)
+	userRepo := repository.NewUserRepository(db.DB())
+	abRepo := repository.NewAddressBookRepository(db.DB())
+	jwtManager := authadapter.NewJWTManager(&cfg.JWT)
+
+	u := &user.User{
+		Email:         "csv@example.com",
+		Username:      "csvuser",
+		PasswordHash:  "hash",
+		IsActive:      true,
+		EmailVerified: true,
+		UUID:          "csv-user-uuid",
+	}
+	require.NoError(t, userRepo.Create(context.Background(), u))
+	token, _, err := jwtManager.GenerateAccessToken(u.UUID, u.Email)
+	require.NoError(t, err)
+
+	ab := &addressbook.AddressBook{UserID: u.ID, Name: "CSV", SyncToken: "1", CTag: "1"}
+	require.NoError(t, abRepo.Create(context.Background(), ab))
+	base := "/api/v1/addressbooks/" + typeToString(ab.ID)
+
+	googleCSV := "First Name,Last Name,E-mail 1 - Label,E-mail 1 - Value,Phone 1 - Label,Phone 1 - Value\n" +
+		"Jane,Doe,* Work,jane@example.com,Mobile,+1 555 0100\n" +
+		",,,,,\n" +
+		",,,,Mobile,+1 555 0199\n"
+
+	send := func(t *testing.T, req *http.Request) importexport.ImportResult {
+		t.Helper()
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		var result importexport.ImportResult
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+		return result
+	}
+	count := func() int64 {
+		var n int64
+		db.DB().Model(&addressbook.AddressObject{}).Where("address_book_id = ?", ab.ID).Count(&n)
+		return n
+	}
+
+	t.Run("Dry Run", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, base+"/import?dry_run=true", strings.NewReader(googleCSV))
+		req.Header.Set("Content-Type", "text/csv")
+		result := send(t, req)
+
+		assert.True(t, result.DryRun)
+		assert.Equal(t, 2, result.Total)
+		assert.Equal(t, 1, result.Imported)
+		assert.Equal(t, 1, result.Failed)
+		require.Len(t, result.Errors, 1)
+		assert.Equal(t, 1, result.Errors[0].Index)
+		require.Len(t, result.Contacts, 1)
+		assert.Equal(t, "Jane Doe", result.Contacts[0].FormattedName)
+		require.Len(t, result.Contacts[0].Emails, 1)
+		assert.Equal(t, "jane@example.com", result.Contacts[0].Emails[0].Value)
+		assert.Zero(t, count())
+	})
+
+	t.Run("Import Upload", func(t *testing.T) {
+		var body bytes.Buffer
+		w := multipart.NewWriter(&body)
+		part, _ := w.CreateFormFile("file", "contacts.csv")
+		_, _ = part.Write([]byte(googleCSV))
+		require.NoError(t, w.Close())
+
+		req := httptest.NewRequest(http.MethodPost, base+"/import", &body)
+		req.Header.Set("Content-Type", w.FormDataContentType())
+		result := send(t, req)
+
+		assert.False(t, result.DryRun)
+		assert.Equal(t, 1, result.Imported)
+		assert.Empty(t, result.Contacts)
+		assert.Equal(t, int64(1), count())
+	})
+
+	t.Run("Custom Mapping", func(t *testing.T) {
+		payload, _ := json.Marshal(map[string]any{
+			"data":    "Name;Mail\nMax;max@example.com\n",
+			"columns": map[string]string{"Name": "full_name", "Mail": "email:work"},
+		})
+		req := httptest.NewRequest(http.MethodPost, base+"/import?format=csv&mapping=custom&dry_run=true", bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		result := send(t, req)
+		require.Len(t, result.Contacts, 1)
+		assert.Equal(t, "Max", result.Contacts[0].FormattedName)
+		require.Len(t, result.Contacts[0].Emails, 1)
+		assert.Equal(t, "max@example.com", result.Contacts[0].Emails[0].Value)
+
+		payload, _ = json.Marshal(map[string]any{"data": "A\n1\n", "columns": map[string]string{"A": "shoe_size"}})
+		req = httptest.NewRequest(http.MethodPost, base+"/import?format=csv&mapping=custom", bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("Export CSV", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, base+"/export?format=csv&mapping=outlook", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Contains(t, resp.Header.Get("Content-Type"), "text/csv")
+		assert.Contains(t, resp.Header.Get("Content-Disposition"), "CSV.csv")
+
+		records, err := csv.NewReader(resp.Body).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, records, 2)
+		assert.Contains(t, records[1], "jane@example.com")
+		assert.Contains(t, records[1], "+1 555 0100")
+
+		req = httptest.NewRequest(http.MethodGet, base+"/export?format=csv&mapping=yahoo", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err = app.Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+}
@@ -0,0 +1,53 @@
+package http
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/jherrma/caldav-server/internal/domain/user"
+	"github.com/jherrma/caldav-server/internal/usecase/sharing"
+)
+
+type InvitationHandler struct {
+	invitationsUC *sharing.InvitationsUseCase
+}
+
+func NewInvitationHandler(invitationsUC *sharing.InvitationsUseCase) *InvitationHandler {
+	return &InvitationHandler{invitationsUC: invitationsUC}
+}
+
+// GET /api/v1/invitations
+func (h *InvitationHandler) List(c fiber.Ctx) error {
+	u := c.Locals("user").(*user.User)
+
+	output, err := h.invitationsUC.ListPending(c.Context(), u.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{"invitations": output})
+}
+
+// POST /api/v1/invitations/:id/accept
+func (h *InvitationHandler) Accept(c fiber.Ctx) error {
+	return h.respond(c, true)
+}
+
+// POST /api/v1/invitations/:id/decline
+func (h *InvitationHandler) Decline(c fiber.Ctx) error {
+	return h.respond(c, false)
+}
+
+func (h *InvitationHandler) respond(c fiber.Ctx, accept bool) error {
+	u := c.Locals("user").(*user.User)
+
+	output, err := h.invitationsUC.Respond(c.Context(), u.ID, c.Params("id"), accept)
+	if err != nil {
+		if errors.Is(err, sharing.ErrInvitationNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(output)
+}
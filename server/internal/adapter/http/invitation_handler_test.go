@@ -0,0 +1,135 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/jherrma/caldav-server/internal/adapter/repository"
+	"github.com/jherrma/caldav-server/internal/domain/calendar"
+	"github.com/jherrma/caldav-server/internal/usecase/sharing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInvitationHandler(t *testing.T) {
+	app, db, _ := setupTestApp(t)
+	ctx := context.Background()
+
+	tokens := make(map[string]string)
+	for _, name := range []string{"host", "guest"} {
+		body, _ := json.Marshal(map[string]string{"email": name + "@example.com", "password": "Password123!", "display_name": name})
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/register", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		tokens[name] = loginForTest(t, app, name+"@example.com", "Password123!", "test").AccessToken
+	}
+
+	host, err := repository.NewUserRepository(db.DB()).GetByEmail(ctx, "host@example.com")
+	require.NoError(t, err)
+	cal := &calendar.Calendar{UUID: "plans-cal", UserID: host.ID, Name: "Plans", Path: "plans"}
+	require.NoError(t, repository.NewCalendarRepository(db.DB()).Create(ctx, cal))
+
+	do := func(as, method, target string, body any) *http.Response {
+		var data []byte
+		if body != nil {
+			data, _ = json.Marshal(body)
+		}
+		req := httptest.NewRequest(method, target, bytes.NewReader(data))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+tokens[as])
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp
+	}
+	invitations := func() []sharing.InvitationOutput {
+		resp := do("guest", http.MethodGet, "/api/v1/invitations", nil)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		var out struct {
+			Invitations []sharing.InvitationOutput `json:"invitations"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+		return out.Invitations
+	}
+	hasSharedCalendar := func() bool {
+		resp := do("guest", http.MethodGet, "/api/v1/calendars", nil)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		var out struct {
+			Calendars []struct {
+				UUID string `json:"uuid"`
+			} `json:"calendars"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+		for _, c := range out.Calendars {
+			if c.UUID == cal.UUID {
+				return true
+			}
+		}
+		return false
+	}
+	invite := func() string {
+		resp := do("host", http.MethodPost, fmt.Sprintf("/api/v1/calendars/%d/shares", cal.ID),
+			map[string]string{"user_identifier": "guest@example.com", "permission": "read", "summary": "Holiday plans"})
+		require.Equal(t, fiber.StatusCreated, resp.StatusCode)
+		var share sharing.CreateCalendarShareOutput
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&share))
+		assert.Equal(t, "pending", share.Status)
+		return share.ID
+	}
+
+	shareID := invite()
+
+	t.Run("Pending Invitation", func(t *testing.T) {
+		list := invitations()
+		require.Len(t, list, 1)
+		assert.Equal(t, shareID, list[0].ID)
+		assert.Equal(t, sharing.CollectionCalendar, list[0].Type)
+		assert.Equal(t, "Plans", list[0].Name)
+		assert.Equal(t, "host@example.com", list[0].Owner.Email)
+		assert.False(t, hasSharedCalendar())
+
+		resp := do("host", http.MethodPost, "/api/v1/invitations/"+shareID+"/accept", nil)
+		assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("Decline And Invite Again", func(t *testing.T) {
+		resp := do("guest", http.MethodPost, "/api/v1/invitations/"+shareID+"/decline", nil)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Empty(t, invitations())
+		assert.False(t, hasSharedCalendar())
+
+		assert.Equal(t, shareID, invite())
+		assert.Len(t, invitations(), 1)
+	})
+
+	t.Run("Accept", func(t *testing.T) {
+		resp := do("guest", http.MethodPost, "/api/v1/invitations/"+shareID+"/accept", nil)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		var out sharing.InvitationOutput
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+		assert.Equal(t, "accepted", out.Status)
+		assert.Empty(t, invitations())
+		assert.True(t, hasSharedCalendar())
+	})
+
+	t.Run("Revoke", func(t *testing.T) {
+		resp := do("host", http.MethodDelete, fmt.Sprintf("/api/v1/calendars/%d/shares/%s", cal.ID, shareID), nil)
+		require.Equal(t, fiber.StatusNoContent, resp.StatusCode)
+		assert.False(t, hasSharedCalendar())
+
+		resp = do("guest", http.MethodPost, "/api/v1/invitations/"+shareID+"/accept", nil)
+		assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("Unknown Invitation", func(t *testing.T) {
+		resp := do("guest", http.MethodPost, "/api/v1/invitations/missing/decline", nil)
+		assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+	})
+}
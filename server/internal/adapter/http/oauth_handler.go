@@ -3,6 +3,7 @@ package http
 import (
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"time"
 
@@ -101,6 +102,10 @@ func (h *OAuthHandler) Callback(c fiber.Ctx) error {
 	ip := c.IP()
 
 	result, err := h.callbackUC.Execute(c.Context(), provider, code, userAgent, ip, currentUser)
+	if errors.Is(err, authUseCase.ErrOAuthDomainNotAllowed) || errors.Is(err, authUseCase.ErrOAuthRegistrationDisabled) ||
+		errors.Is(err, authUseCase.ErrOAuthEmailNotVerified) {
+		return c.Status(http.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	}
 	if err != nil {
 		// Handle specific errors for 409, etc.
 		// For now generic 500 or 400.
@@ -0,0 +1,47 @@
+package http
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/jherrma/caldav-server/internal/domain/oauthserver"
+)
+
+// requiredOAuthScope returns the scope an access token issued by the
+// built-in authorization server needs for a REST request. Endpoints that
+// return false are not available to OAuth clients at all: account
+// management, credentials, sharing and public links stay first-party only.
+func requiredOAuthScope(method, path string) (string, bool) {
+	path = strings.TrimSuffix(strings.TrimPrefix(path, "/api/v1"), "/")
+	read := method == fiber.MethodGet || method == fiber.MethodHead
+
+	switch {
+	case path == "/oauth/userinfo":
+		return oauthserver.ScopeOpenID, true
+	case path == "/users/me" && read:
+		return oauthserver.ScopeProfile, true
+	case strings.Contains(path+"/", "/shares/") || strings.Contains(path+"/", "/public/"):
+		return "", false
+	case path == "/calendars" || strings.HasPrefix(path, "/calendars/"):
+		if read {
+			return oauthserver.ScopeCalendarsRead, true
+		}
+		return oauthserver.ScopeCalendarsWrite, true
+	case path == "/addressbooks" || strings.HasPrefix(path, "/addressbooks/") || strings.HasPrefix(path, "/contacts/"):
+		if read {
+			return oauthserver.ScopeContactsRead, true
+		}
+		return oauthserver.ScopeContactsWrite, true
+	}
+	return "", false
+}
+
+// insufficientScopeResponse rejects a token that lacks the required scope
+// (RFC 6750 §3.1)
+func insufficientScopeResponse(c fiber.Ctx, scope string) error {
+	if scope == "" {
+		return ForbiddenResponse(c, "this endpoint is not available to OAuth clients")
+	}
+	c.Set("WWW-Authenticate", `Bearer error="insufficient_scope", scope="`+scope+`"`)
+	return ForbiddenResponse(c, "token lacks the required scope: "+scope)
+}
@@ -0,0 +1,457 @@
+package http
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/jherrma/caldav-server/internal/adapter/http/dto"
+	"github.com/jherrma/caldav-server/internal/config"
+	"github.com/jherrma/caldav-server/internal/domain/oauthserver"
+	"github.com/jherrma/caldav-server/internal/domain/user"
+	oauthserverusecase "github.com/jherrma/caldav-server/internal/usecase/oauthserver"
+)
+
+// OAuthServerHandler serves the built-in OAuth 2.0 / OpenID Connect
+// authorization server: client management, the consent flow and the
+// protocol endpoints used by third-party applications.
+type OAuthServerHandler struct {
+	registerUC   *oauthserverusecase.RegisterClientUseCase
+	listUC       *oauthserverusecase.ListClientsUseCase
+	deleteUC     *oauthserverusecase.DeleteClientUseCase
+	authorizeUC  *oauthserverusecase.AuthorizeUseCase
+	tokenUC      *oauthserverusecase.TokenUseCase
+	introspectUC *oauthserverusecase.IntrospectUseCase
+	revokeUC     *oauthserverusecase.RevokeUseCase
+	signer       oauthserver.IDTokenSigner
+	cfg          *config.Config
+}
+
+func NewOAuthServerHandler(
+	registerUC *oauthserverusecase.RegisterClientUseCase,
+	listUC *oauthserverusecase.ListClientsUseCase,
+	deleteUC *oauthserverusecase.DeleteClientUseCase,
+	authorizeUC *oauthserverusecase.AuthorizeUseCase,
+	tokenUC *oauthserverusecase.TokenUseCase,
+	introspectUC *oauthserverusecase.IntrospectUseCase,
+	revokeUC *oauthserverusecase.RevokeUseCase,
+	signer oauthserver.IDTokenSigner,
+	cfg *config.Config,
+) *OAuthServerHandler {
+	return &OAuthServerHandler{
+		registerUC:   registerUC,
+		listUC:       listUC,
+		deleteUC:     deleteUC,
+		authorizeUC:  authorizeUC,
+		tokenUC:      tokenUC,
+		introspectUC: introspectUC,
+		revokeUC:     revokeUC,
+		signer:       signer,
+		cfg:          cfg,
+	}
+}
+
+func toOAuthClientResponse(client *oauthserver.Client) dto.OAuthClientResponse {
+	return dto.OAuthClientResponse{
+		ClientID:     client.ClientID,
+		Name:         client.Name,
+		RedirectURIs: client.RedirectURIList(),
+		Scopes:       client.ScopeList(),
+		Confidential: client.IsConfidential(),
+		CreatedAt:    client.CreatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+// CreateClient godoc
+// @Summary      Register an OAuth client
+// @Description  Register a third-party application that can request access to the user's data. The client secret is only returned once.
+// @Tags         OAuth Server
+// @Accept       json
+// @Produce      json
+// @Param        request  body      dto.CreateOAuthClientRequest  true  "Client details"
+// @Success      200      {object}  dto.OAuthClientResponse
+// @Failure      400      {object}  ErrorResponseBody
+// @Failure      401      {object}  ErrorResponseBody
+// @Security     BearerAuth
+// @Router       /oauth/clients [post]
+func (h *OAuthServerHandler) CreateClient(c fiber.Ctx) error {
+	userID, err := GetUserIDFromContext(c)
+	if err != nil {
+		return UnauthorizedResponse(c, "Unauthorized")
+	}
+
+	var req dto.CreateOAuthClientRequest
+	if err := c.Bind().JSON(&req); err != nil {
+		return BadRequestResponse(c, "Invalid request body")
+	}
+
+	client, secret, err := h.registerUC.Execute(c.Context(), userID, oauthserverusecase.RegisterClientInput{
+		Name:         req.Name,
+		RedirectURIs: req.RedirectURIs,
+		Scopes:       req.Scopes,
+		Confidential: req.Confidential,
+	})
+	if err != nil {
+		if errors.Is(err, oauthserverusecase.ErrInvalidClient) {
+			return BadRequestResponse(c, err.Error())
+		}
+		return ErrorResponse(c, fiber.StatusInternalServerError, "Failed to register client")
+	}
+
+	res := toOAuthClientResponse(client)
+	res.ClientSecret = secret
+	return SuccessResponse(c, res)
+}
+
+// ListClients godoc
+// @Summary      List OAuth clients
+// @Description  List the third-party applications registered by the current user
+// @Tags         OAuth Server
+// @Produce      json
+// @Success      200  {object}  dto.ListOAuthClientsResponse
+// @Failure      401  {object}  ErrorResponseBody
+// @Security     BearerAuth
+// @Router       /oauth/clients [get]
+func (h *OAuthServerHandler) ListClients(c fiber.Ctx) error {
+	userID, err := GetUserIDFromContext(c)
+	if err != nil {
+		return UnauthorizedResponse(c, "Unauthorized")
+	}
+
+	clients, err := h.listUC.Execute(c.Context(), userID)
+	if err != nil {
+		return ErrorResponse(c, fiber.StatusInternalServerError, "Failed to list clients")
+	}
+
+	res := dto.ListOAuthClientsResponse{Clients: make([]dto.OAuthClientResponse, len(clients))}
+	for i := range clients {
+		res.Clients[i] = toOAuthClientResponse(&clients[i])
+	}
+	return SuccessResponse(c, res)
+}
+
+// DeleteClient godoc
+// @Summary      Delete an OAuth client
+// @Description  Delete an application; all tokens issued to it are revoked
+// @Tags         OAuth Server
+// @Param        client_id  path  string  true  "Client ID"
+// @Success      204
+// @Failure      401  {object}  ErrorResponseBody
+// @Failure      404  {object}  ErrorResponseBody
+// @Security     BearerAuth
+// @Router       /oauth/clients/{client_id} [delete]
+func (h *OAuthServerHandler) DeleteClient(c fiber.Ctx) error {
+	userID, err := GetUserIDFromContext(c)
+	if err != nil {
+		return UnauthorizedResponse(c, "Unauthorized")
+	}
+
+	if err := h.deleteUC.Execute(c.Context(), userID, c.Params("client_id")); err != nil {
+		if errors.Is(err, oauthserverusecase.ErrClientNotFound) {
+			return ErrorResponse(c, fiber.StatusNotFound, "Client not found")
+		}
+		return ErrorResponse(c, fiber.StatusInternalServerError, "Failed to delete client")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// AuthorizeRedirect sends the browser from the standard authorization
+// endpoint to the frontend consent page, which signs the user in if needed
+// and then calls the consent API below.
+func (h *OAuthServerHandler) AuthorizeRedirect(c fiber.Ctx) error {
+	return c.Redirect().To("/oauth/consent?" + string(c.Request().URI().QueryString()))
+}
+
+func authorizationRequestFromQuery(c fiber.Ctx) oauthserverusecase.AuthorizationRequest {
+	return oauthserverusecase.AuthorizationRequest{
+		ResponseType:        c.Query("response_type"),
+		ClientID:            c.Query("client_id"),
+		RedirectURI:         c.Query("redirect_uri"),
+		Scope:               c.Query("scope"),
+		State:               c.Query("state"),
+		CodeChallenge:       c.Query("code_challenge"),
+		CodeChallengeMethod: c.Query("code_challenge_method"),
+		Nonce:               c.Query("nonce"),
+	}
+}
+
+// authorizeErrorResponse reports an invalid authorization request. Errors
+// with a verified redirect URI are handed to the frontend to redirect to;
+// the others are shown to the user.
+func authorizeErrorResponse(c fiber.Ctx, err error) error {
+	var oauthErr *oauthserverusecase.Error
+	if errors.As(err, &oauthErr) {
+		if redirect := oauthErr.RedirectTo(); redirect != "" {
+			return c.Status(fiber.StatusBadRequest).JSON(Response{
+				Status: "error",
+				Error:  oauthErr.Code,
+				Data:   dto.OAuthDecisionResponse{RedirectTo: redirect},
+			})
+		}
+		return BadRequestResponse(c, oauthErr.Error())
+	}
+	return ErrorResponse(c, fiber.StatusInternalServerError, "Failed to process authorization request")
+}
+
+// GetConsent godoc
+// @Summary      Get consent prompt
+// @Description  Validate an authorization request and return what the consent screen shows
+// @Tags         OAuth Server
+// @Produce      json
+// @Param        client_id              query     string  true   "Client ID"
+// @Param        redirect_uri           query     string  false  "Redirect URI"
+// @Param        response_type          query     string  true   "Must be code"
+// @Param        scope                  query     string  true   "Space-separated scopes"
+// @Param        state                  query     string  false  "Opaque client state"
+// @Param        code_challenge         query     string  false  "PKCE challenge"
+// @Param        code_challenge_method  query     string  false  "Must be S256"
+// @Success      200  {object}  dto.OAuthConsentResponse
+// @Failure      400  {object}  ErrorResponseBody
+// @Failure      401  {object}  ErrorResponseBody
+// @Security     BearerAuth
+// @Router       /oauth/authorize [get]
+func (h *OAuthServerHandler) GetConsent(c fiber.Ctx) error {
+	userID, err := GetUserIDFromContext(c)
+	if err != nil {
+		return UnauthorizedResponse(c, "Unauthorized")
+	}
+
+	prompt, err := h.authorizeUC.Prepare(c.Context(), userID, authorizationRequestFromQuery(c))
+	if err != nil {
+		return authorizeErrorResponse(c, err)
+	}
+
+	res := dto.OAuthConsentResponse{
+		ClientID:     prompt.Client.ClientID,
+		ClientName:   prompt.Client.Name,
+		RedirectURI:  prompt.RedirectURI,
+		Scopes:       make([]dto.OAuthScopeResponse, len(prompt.Scopes)),
+		ConsentGiven: prompt.ConsentGiven,
+	}
+	for i, s := range prompt.Scopes {
+		res.Scopes[i] = dto.OAuthScopeResponse{Name: s, Description: oauthserver.Describe(s)}
+	}
+	return SuccessResponse(c, res)
+}
+
+// Decide godoc
+// @Summary      Approve or deny an authorization request
+// @Description  Record the user's decision and return the client redirect URL carrying the authorization code or the error
+// @Tags         OAuth Server
+// @Accept       json
+// @Produce      json
+// @Param        request  body      dto.OAuthDecisionRequest  true  "Authorization request and decision"
+// @Success      200      {object}  dto.OAuthDecisionResponse
+// @Failure      400      {object}  ErrorResponseBody
+// @Failure      401      {object}  ErrorResponseBody
+// @Security     BearerAuth
+// @Router       /oauth/authorize [post]
+func (h *OAuthServerHandler) Decide(c fiber.Ctx) error {
+	userID, err := GetUserIDFromContext(c)
+	if err != nil {
+		return UnauthorizedResponse(c, "Unauthorized")
+	}
+
+	var req dto.OAuthDecisionRequest
+	if err := c.Bind().JSON(&req); err != nil {
+		return BadRequestResponse(c, "Invalid request body")
+	}
+
+	redirect, err := h.authorizeUC.Decide(c.Context(), userID, oauthserverusecase.AuthorizationRequest{
+		ResponseType:        req.ResponseType,
+		ClientID:            req.ClientID,
+		RedirectURI:         req.RedirectURI,
+		Scope:               req.Scope,
+		State:               req.State,
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+		Nonce:               req.Nonce,
+	}, req.Approved)
+	if err != nil {
+		return authorizeErrorResponse(c, err)
+	}
+	return SuccessResponse(c, dto.OAuthDecisionResponse{RedirectTo: redirect})
+}
+
+// clientCredentials reads client_secret_basic credentials, falling back to
+// client_secret_post form parameters (RFC 6749 §2.3.1)
+func clientCredentials(c fiber.Ctx) (string, string) {
+	if header := c.Get("Authorization"); len(header) > 6 && strings.EqualFold(header[:6], "basic ") {
+		if payload, err := base64.StdEncoding.DecodeString(header[6:]); err == nil {
+			if id, secret, ok := strings.Cut(string(payload), ":"); ok {
+				id, _ = url.QueryUnescape(id)
+				secret, _ = url.QueryUnescape(secret)
+				return id, secret
+			}
+		}
+	}
+	return c.FormValue("client_id"), c.FormValue("client_secret")
+}
+
+// oauthErrorResponse writes a protocol error (RFC 6749 §5.2)
+func oauthErrorResponse(c fiber.Ctx, err error) error {
+	var oauthErr *oauthserverusecase.Error
+	if !errors.As(err, &oauthErr) {
+		oauthErr = &oauthserverusecase.Error{Code: oauthserverusecase.CodeServerError}
+	}
+
+	status := fiber.StatusBadRequest
+	switch oauthErr.Code {
+	case oauthserverusecase.CodeInvalidClient:
+		status = fiber.StatusUnauthorized
+		c.Set("WWW-Authenticate", `Basic realm="oauth"`)
+	case oauthserverusecase.CodeServerError:
+		status = fiber.StatusInternalServerError
+	}
+	return c.Status(status).JSON(dto.OAuthErrorResponse{
+		Error:            oauthErr.Code,
+		ErrorDescription: oauthErr.Description,
+	})
+}
+
+// Token godoc
+// @Summary      Token endpoint
+// @Description  Exchange an authorization code or refresh token for tokens (authorization_code and refresh_token grants)
+// @Tags         OAuth Server
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        grant_type     formData  string  true   "authorization_code or refresh_token"
+// @Param        code           formData  string  false  "Authorization code"
+// @Param        redirect_uri   formData  string  false  "Redirect URI of the authorization request"
+// @Param        code_verifier  formData  string  false  "PKCE verifier"
+// @Param        refresh_token  formData  string  false  "Refresh token"
+// @Param        scope          formData  string  false  "Narrower scope for the refreshed token"
+// @Success      200  {object}  dto.OAuthTokenResponse
+// @Failure      400  {object}  dto.OAuthErrorResponse
+// @Failure      401  {object}  dto.OAuthErrorResponse
+// @Router       /oauth/token [post]
+func (h *OAuthServerHandler) Token(c fiber.Ctx) error {
+	c.Set("Cache-Control", "no-store")
+	c.Set("Pragma", "no-cache")
+
+	clientID, clientSecret := clientCredentials(c)
+	res, err := h.tokenUC.Execute(c.Context(), oauthserverusecase.TokenRequest{
+		GrantType:    c.FormValue("grant_type"),
+		Code:         c.FormValue("code"),
+		RedirectURI:  c.FormValue("redirect_uri"),
+		CodeVerifier: c.FormValue("code_verifier"),
+		RefreshToken: c.FormValue("refresh_token"),
+		Scope:        c.FormValue("scope"),
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+	})
+	if err != nil {
+		return oauthErrorResponse(c, err)
+	}
+
+	return c.JSON(dto.OAuthTokenResponse{
+		AccessToken:  res.AccessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    res.ExpiresIn,
+		RefreshToken: res.RefreshToken,
+		Scope:        res.Scope,
+		IDToken:      res.IDToken,
+	})
+}
+
+// Introspect godoc
+// @Summary      Token introspection
+// @Description  Report whether a token issued to the calling client is active (RFC 7662)
+// @Tags         OAuth Server
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        token  formData  string  true  "Access or refresh token"
+// @Success      200  {object}  dto.OAuthIntrospectionResponse
+// @Failure      401  {object}  dto.OAuthErrorResponse
+// @Router       /oauth/introspect [post]
+func (h *OAuthServerHandler) Introspect(c fiber.Ctx) error {
+	clientID, clientSecret := clientCredentials(c)
+	res, err := h.introspectUC.Execute(c.Context(), clientID, clientSecret, c.FormValue("token"))
+	if err != nil {
+		return oauthErrorResponse(c, err)
+	}
+
+	return c.JSON(dto.OAuthIntrospectionResponse{
+		Active:    res.Active,
+		Scope:     res.Scope,
+		ClientID:  res.ClientID,
+		Username:  res.Username,
+		Subject:   res.Subject,
+		TokenType: res.TokenType,
+		ExpiresAt: res.ExpiresAt,
+		IssuedAt:  res.IssuedAt,
+	})
+}
+
+// Revoke godoc
+// @Summary      Token revocation
+// @Description  Revoke an access or refresh token issued to the calling client (RFC 7009)
+// @Tags         OAuth Server
+// @Accept       x-www-form-urlencoded
+// @Param        token  formData  string  true  "Access or refresh token"
+// @Success      200
+// @Failure      401  {object}  dto.OAuthErrorResponse
+// @Router       /oauth/revoke [post]
+func (h *OAuthServerHandler) Revoke(c fiber.Ctx) error {
+	clientID, clientSecret := clientCredentials(c)
+	if err := h.revokeUC.Execute(c.Context(), clientID, clientSecret, c.FormValue("token")); err != nil {
+		return oauthErrorResponse(c, err)
+	}
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// UserInfo godoc
+// @Summary      OpenID Connect userinfo
+// @Description  Return the claims about the user released by the token's scopes
+// @Tags         OAuth Server
+// @Produce      json
+// @Success      200  {object}  map[string]interface{}
+// @Failure      401  {object}  ErrorResponseBody
+// @Failure      403  {object}  ErrorResponseBody  "Token lacks the openid scope"
+// @Security     BearerAuth
+// @Router       /oauth/userinfo [get]
+func (h *OAuthServerHandler) UserInfo(c fiber.Ctx) error {
+	u, ok := c.Locals("user").(*user.User)
+	if !ok || u == nil {
+		return UnauthorizedResponse(c, "Unauthorized")
+	}
+
+	scopes, ok := c.Locals("oauth_scopes").([]string)
+	if !ok {
+		// First-party session: the user is asking about themselves
+		scopes = []string{oauthserver.ScopeProfile, oauthserver.ScopeEmail}
+	}
+	return c.JSON(oauthserverusecase.IdentityClaims(u, scopes))
+}
+
+// Discovery serves the OpenID Provider metadata (OpenID Connect Discovery
+// 1.0, RFC 8414)
+func (h *OAuthServerHandler) Discovery(c fiber.Ctx) error {
+	issuer := h.cfg.BaseURL
+	return c.JSON(fiber.Map{
+		"issuer":                                         issuer,
+		"authorization_endpoint":                         issuer + "/oauth/authorize",
+		"token_endpoint":                                 issuer + "/oauth/token",
+		"userinfo_endpoint":                              issuer + "/oauth/userinfo",
+		"jwks_uri":                                       issuer + "/oauth/jwks",
+		"introspection_endpoint":                         issuer + "/oauth/introspect",
+		"revocation_endpoint":                            issuer + "/oauth/revoke",
+		"scopes_supported":                               oauthserver.SupportedScopes(),
+		"response_types_supported":                       []string{"code"},
+		"grant_types_supported":                          []string{"authorization_code", "refresh_token"},
+		"subject_types_supported":                        []string{"public"},
+		"id_token_signing_alg_values_supported":          []string{"RS256"},
+		"code_challenge_methods_supported":               []string{"S256"},
+		"token_endpoint_auth_methods_supported":          []string{"client_secret_basic", "client_secret_post", "none"},
+		"claims_supported":                               []string{"sub", "name", "preferred_username", "email", "email_verified"},
+		"authorization_response_iss_parameter_supported": true,
+	})
+}
+
+// JWKS serves the public keys ID tokens are signed with
+func (h *OAuthServerHandler) JWKS(c fiber.Ctx) error {
+	return c.JSON(h.signer.JWKS())
+}
@@ -0,0 +1,289 @@
+package http
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/jherrma/caldav-server/internal/adapter/http/dto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOAuthServerFlow(t *testing.T) {
+	app, _, _ := setupTestApp(t)
+
+	body, _ := json.Marshal(map[string]string{"email": "owner@example.com", "password": "Password123!", "display_name": "Owner"})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/register", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	session := loginForTest(t, app, "owner@example.com", "Password123!", "test")
+
+	doJSON := func(method, path, token string, payload interface{}) *http.Response {
+		var reader *bytes.Reader
+		if payload != nil {
+			b, _ := json.Marshal(payload)
+			reader = bytes.NewReader(b)
+		} else {
+			reader = bytes.NewReader(nil)
+		}
+		req := httptest.NewRequest(method, path, reader)
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp
+	}
+	postForm := func(path string, form url.Values) (*http.Response, map[string]interface{}) {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		var out map[string]interface{}
+		_ = json.NewDecoder(resp.Body).Decode(&out)
+		return resp, out
+	}
+
+	// Register a public client limited to calendar read access
+	resp = doJSON(http.MethodPost, "/api/v1/oauth/clients", session.AccessToken, map[string]interface{}{
+		"name":          "Agenda Widget",
+		"redirect_uris": []string{"http://127.0.0.1:9000/callback"},
+		"scopes":        []string{"openid", "profile", "calendars:read"},
+	})
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var created struct {
+		Data dto.OAuthClientResponse `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+	client := created.Data
+	assert.False(t, client.Confidential)
+	assert.Empty(t, client.ClientSecret)
+
+	verifier := "a-sufficiently-long-code-verifier-for-the-pkce-test-1234567890"
+	sum := sha256.Sum256([]byte(verifier))
+	authz := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {client.ClientID},
+		"redirect_uri":          {"http://127.0.0.1:9000/callback"},
+		"scope":                 {"openid profile calendars:read"},
+		"state":                 {"xyz"},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(sum[:])},
+		"code_challenge_method": {"S256"},
+	}
+
+	t.Run("Browser entry point redirects to the consent page", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/oauth/authorize?"+authz.Encode(), nil)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusSeeOther, resp.StatusCode)
+		assert.True(t, strings.HasPrefix(resp.Header.Get("Location"), "/oauth/consent?"))
+	})
+
+	t.Run("Scope outside the client registration is rejected", func(t *testing.T) {
+		bad := url.Values{}
+		for k, v := range authz {
+			bad[k] = v
+		}
+		bad.Set("scope", "contacts:write")
+		resp := doJSON(http.MethodGet, "/api/v1/oauth/authorize?"+bad.Encode(), session.AccessToken, nil)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+
+	resp = doJSON(http.MethodGet, "/api/v1/oauth/authorize?"+authz.Encode(), session.AccessToken, nil)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var prompt struct {
+		Data dto.OAuthConsentResponse `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&prompt))
+	assert.Equal(t, "Agenda Widget", prompt.Data.ClientName)
+	assert.Len(t, prompt.Data.Scopes, 3)
+	assert.False(t, prompt.Data.ConsentGiven)
+
+	decision := map[string]interface{}{"approved": true}
+	for k := range authz {
+		decision[k] = authz.Get(k)
+	}
+	resp = doJSON(http.MethodPost, "/api/v1/oauth/authorize", session.AccessToken, decision)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var decided struct {
+		Data dto.OAuthDecisionResponse `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&decided))
+	redirect, err := url.Parse(decided.Data.RedirectTo)
+	require.NoError(t, err)
+	assert.Equal(t, "xyz", redirect.Query().Get("state"))
+	code := redirect.Query().Get("code")
+	require.NotEmpty(t, code)
+
+	exchange := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {"http://127.0.0.1:9000/callback"},
+		"client_id":     {client.ClientID},
+		"code_verifier": {"wrong-verifier"},
+	}
+	resp, out := postForm("/oauth/token", exchange)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode, "wrong PKCE verifier")
+	assert.Equal(t, "invalid_grant", out["error"])
+
+	exchange.Set("code_verifier", verifier)
+	resp, out = postForm("/oauth/token", exchange)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, "no-store", resp.Header.Get("Cache-Control"))
+	accessToken := out["access_token"].(string)
+	refreshToken := out["refresh_token"].(string)
+	assert.NotEmpty(t, out["id_token"])
+	assert.Equal(t, "openid profile calendars:read", out["scope"])
+
+	t.Run("Scopes are enforced on the REST API", func(t *testing.T) {
+		resp := doJSON(http.MethodGet, "/api/v1/calendars", accessToken, nil)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		resp = doJSON(http.MethodPost, "/api/v1/calendars", accessToken, map[string]string{"name": "New"})
+		assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+		assert.Contains(t, resp.Header.Get("WWW-Authenticate"), "insufficient_scope")
+
+		resp = doJSON(http.MethodGet, "/api/v1/addressbooks", accessToken, nil)
+		assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+
+		resp = doJSON(http.MethodGet, "/api/v1/users/me/sessions", accessToken, nil)
+		assert.Equal(t, fiber.StatusForbidden, resp.StatusCode, "account management is first-party only")
+
+		resp = doJSON(http.MethodGet, "/api/v1/oauth/clients", accessToken, nil)
+		assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+	})
+
+	t.Run("Userinfo releases profile claims", func(t *testing.T) {
+		resp := doJSON(http.MethodGet, "/oauth/userinfo", accessToken, nil)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		var claims map[string]interface{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&claims))
+		assert.Equal(t, "Owner", claims["name"])
+		assert.NotContains(t, claims, "email", "email scope was not granted")
+	})
+
+	t.Run("Authorization code cannot be reused", func(t *testing.T) {
+		resp, out := postForm("/oauth/token", exchange)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, "invalid_grant", out["error"])
+
+		// Reuse revokes the tokens issued from the code
+		resp = doJSON(http.MethodGet, "/api/v1/calendars", accessToken, nil)
+		assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("Refresh rotates and detects replay", func(t *testing.T) {
+		// The pair above was revoked by the code reuse; start a new grant
+		resp := doJSON(http.MethodPost, "/api/v1/oauth/authorize", session.AccessToken, decision)
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&decided))
+		redirect, _ := url.Parse(decided.Data.RedirectTo)
+		exchange.Set("code", redirect.Query().Get("code"))
+		_, out := postForm("/oauth/token", exchange)
+		refreshToken = out["refresh_token"].(string)
+
+		refresh := url.Values{
+			"grant_type":    {"refresh_token"},
+			"refresh_token": {refreshToken},
+			"client_id":     {client.ClientID},
+			"scope":         {"calendars:read"},
+		}
+		resp, out = postForm("/oauth/token", refresh)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Equal(t, "calendars:read", out["scope"])
+		rotated := out["access_token"].(string)
+
+		resp, out = postForm("/oauth/token", refresh)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, "invalid_grant", out["error"])
+
+		resp = doJSON(http.MethodGet, "/api/v1/calendars", rotated, nil)
+		assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode, "replay revokes the whole grant")
+	})
+
+	t.Run("Introspection and revocation", func(t *testing.T) {
+		resp := doJSON(http.MethodPost, "/api/v1/oauth/authorize", session.AccessToken, decision)
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&decided))
+		redirect, _ := url.Parse(decided.Data.RedirectTo)
+		exchange.Set("code", redirect.Query().Get("code"))
+		_, out := postForm("/oauth/token", exchange)
+		token := out["access_token"].(string)
+
+		creds := url.Values{"client_id": {client.ClientID}, "token": {token}}
+		resp, out = postForm("/oauth/introspect", creds)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Equal(t, true, out["active"])
+		assert.Equal(t, "owner@example.com", out["username"])
+
+		resp, _ = postForm("/oauth/revoke", creds)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		_, out = postForm("/oauth/introspect", creds)
+		assert.Equal(t, false, out["active"])
+	})
+
+	t.Run("Deleting the client", func(t *testing.T) {
+		resp := doJSON(http.MethodDelete, "/api/v1/oauth/clients/"+client.ClientID, session.AccessToken, nil)
+		assert.Equal(t, fiber.StatusNoContent, resp.StatusCode)
+
+		resp, out := postForm("/oauth/token", url.Values{"grant_type": {"refresh_token"}, "client_id": {client.ClientID}, "refresh_token": {refreshToken}})
+		assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+		assert.Equal(t, "invalid_client", out["error"])
+	})
+}
+
+func TestOAuthServerConfidentialClient(t *testing.T) {
+	app, _, _ := setupTestApp(t)
+
+	body, _ := json.Marshal(map[string]string{"email": "dev@example.com", "password": "Password123!", "display_name": "Dev"})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/register", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	_, err := app.Test(req)
+	require.NoError(t, err)
+	session := loginForTest(t, app, "dev@example.com", "Password123!", "test")
+
+	body, _ = json.Marshal(map[string]interface{}{
+		"name":          "Server App",
+		"redirect_uris": []string{"https://app.example.com/cb"},
+		"confidential":  true,
+	})
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/oauth/clients", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+session.AccessToken)
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	var created struct {
+		Data dto.OAuthClientResponse `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+	require.NotEmpty(t, created.Data.ClientSecret)
+
+	introspect := func(secret string) int {
+		req := httptest.NewRequest(http.MethodPost, "/oauth/introspect", strings.NewReader("token=cco_at_unknown"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth(created.Data.ClientID, secret)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp.StatusCode
+	}
+	assert.Equal(t, fiber.StatusUnauthorized, introspect("wrong"))
+	assert.Equal(t, fiber.StatusOK, introspect(created.Data.ClientSecret))
+
+	req = httptest.NewRequest(http.MethodGet, "/.well-known/openid-configuration", nil)
+	resp, err = app.Test(req)
+	require.NoError(t, err)
+	var meta map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&meta))
+	assert.Equal(t, "http://localhost:8080", meta["issuer"])
+	assert.Equal(t, "http://localhost:8080/oauth/token", meta["token_endpoint"])
+}
@@ -0,0 +1,100 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/emersion/go-vcard"
+	"github.com/gofiber/fiber/v3"
+	domainaddressbook "github.com/jherrma/caldav-server/internal/domain/addressbook"
+	"github.com/jherrma/caldav-server/internal/usecase/addressbook"
+	contactuc "github.com/jherrma/caldav-server/internal/usecase/contact"
+)
+
+type PublicAddressBookHandler struct {
+	publicFeedUC *addressbook.PublicFeedUseCase
+}
+
+func NewPublicAddressBookHandler(publicFeedUC *addressbook.PublicFeedUseCase) *PublicAddressBookHandler {
+	return &PublicAddressBookHandler{
+		publicFeedUC: publicFeedUC,
+	}
+}
+
+// GetVCardFeed godoc
+// @Summary      Get public address book feed
+// @Description  Get the contacts of an address book via public token, as a vCard stream (token or token.vcf)
+// @Description  or as a JSON array of jCards (token.json, or an Accept header of application/vcard+json).
+// @Description  Fields hidden by the publication settings are left out, as are private contacts.
+// @Tags         Public
+// @Produce      text/vcard
+// @Produce      application/vcard+json
+// @Param        token    path   string  true   "Public Token, optionally with .vcf or .json"
+// @Param        version  query  string  false  "Convert all contacts to this vCard version (3.0 or 4.0)"
+// @Success      200      {file} file
+// @Failure      400      {string} string "Invalid version"
+// @Failure      404      {string} string "Address book not found"
+// @Failure      500      {string} string "Internal error"
+// @Router       /public/addressbook/{token} [get]
+func (h *PublicAddressBookHandler) GetVCardFeed(c fiber.Ctx) error {
+	token := c.Params("token")
+	format := "vcf"
+	for _, ext := range []string{"vcf", "json"} {
+		if strings.HasSuffix(token, "."+ext) {
+			token, format = strings.TrimSuffix(token, "."+ext), ext
+		}
+	}
+	if format == "vcf" && c.Accepts("text/vcard", contactuc.MediaTypeJCard) == contactuc.MediaTypeJCard {
+		format = "json"
+	}
+	version := c.Query("version")
+	if version != "" && !domainaddressbook.IsSupportedVCardVersion(version) {
+		return c.Status(fiber.StatusBadRequest).SendString("Invalid version")
+	}
+
+	feed, err := h.publicFeedUC.Execute(c.Context(), token)
+	if err != nil {
+		if errors.Is(err, addressbook.ErrPublicFeedNotFound) {
+			return c.Status(fiber.StatusNotFound).SendString("Address book not found")
+		}
+		return c.Status(fiber.StatusInternalServerError).SendString("Internal error")
+	}
+
+	// Check ETag for caching
+	currentETag := strings.TrimSuffix(feed.ETag, `"`) + "-" + format + version + `"`
+	c.Set("Vary", "Accept")
+	if c.Get("If-None-Match") == currentETag {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	// Set headers
+	c.Set("Cache-Control", "public, max-age=300")
+	c.Set("ETag", currentETag)
+
+	if version != "" {
+		for _, card := range feed.Cards {
+			domainaddressbook.ConvertVCard(card, version)
+		}
+	}
+
+	if format == "json" {
+		jcards := make([]interface{}, 0, len(feed.Cards))
+		for _, card := range feed.Cards {
+			jcards = append(jcards, contactuc.ToJCard(card))
+		}
+		c.Set("Access-Control-Allow-Origin", "*")
+		return c.JSON(jcards, contactuc.MediaTypeJCard)
+	}
+
+	var b strings.Builder
+	enc := vcard.NewEncoder(&b)
+	for _, card := range feed.Cards {
+		if err := enc.Encode(card); err != nil {
+			return c.Status(fiber.StatusInternalServerError).SendString("Internal error")
+		}
+	}
+	c.Set("Content-Type", "text/vcard; charset=utf-8")
+	c.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.vcf"`, feed.AddressBook.Name))
+	return c.SendString(b.String())
+}
@@ -0,0 +1,154 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/jherrma/caldav-server/internal/adapter/repository"
+	"github.com/jherrma/caldav-server/internal/domain/addressbook"
+	addressbookusecase "github.com/jherrma/caldav-server/internal/usecase/addressbook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublicAddressBookFeed(t *testing.T) {
+	app, db, _ := setupTestApp(t)
+	ctx := context.Background()
+
+	body, _ := json.Marshal(map[string]string{"email": "sales@example.com", "password": "Password123!", "display_name": "Sales"})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/register", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	token := loginForTest(t, app, "sales@example.com", "Password123!", "test").AccessToken
+
+	userRepo := repository.NewUserRepository(db.DB())
+	owner, err := userRepo.GetByEmail(ctx, "sales@example.com")
+	require.NoError(t, err)
+	abRepo := repository.NewAddressBookRepository(db.DB())
+	ab := &addressbook.AddressBook{UUID: "public-ab", UserID: owner.ID, Name: "Team", Path: "team", SyncToken: "1", CTag: "1"}
+	require.NoError(t, abRepo.Create(ctx, ab))
+	addContact := func(uid, extra string) {
+		require.NoError(t, abRepo.CreateObject(ctx, &addressbook.AddressObject{
+			UUID: uid, AddressBookID: ab.ID, Path: uid + ".vcf", UID: uid, ETag: `"` + uid + `"`, VCardVersion: "3.0",
+			VCardData: fmt.Sprintf("BEGIN:VCARD\r\nVERSION:3.0\r\nUID:%s\r\nFN:Contact %s\r\nN:%s;Contact;;;\r\n"+
+				"EMAIL:%s@example.com\r\nTEL:+49 30 1234567\r\nADR:;;Main St 1;Berlin;;10115;Germany\r\n%sEND:VCARD\r\n", uid, uid, uid, uid, extra),
+		}))
+	}
+	addContact("alice", "")
+	addContact("bob", "PHOTO;ENCODING=b;TYPE=JPEG:SGVsbG8=\r\n")
+	addContact("carol", "CLASS:PRIVATE\r\n")
+
+	api := func(method, target string, body any) *http.Response {
+		data, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, target, bytes.NewReader(data))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp
+	}
+	get := func(target, accept string) (*http.Response, string) {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		data, _ := io.ReadAll(resp.Body)
+		return resp, string(data)
+	}
+
+	publicURL := fmt.Sprintf("/api/v1/addressbooks/%d/public", ab.ID)
+	resp = api(http.MethodPost, publicURL, map[string]bool{"enabled": true})
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var status addressbookusecase.EnablePublicOutput
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&status))
+	require.NotNil(t, status.Token)
+	require.NotNil(t, status.PublicURL)
+	assert.Contains(t, *status.PublicURL, "/public/addressbook/"+*status.Token+".vcf")
+	feedURL := "/public/addressbook/" + *status.Token
+
+	t.Run("VCard", func(t *testing.T) {
+		resp, body := get(feedURL+".vcf", "")
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Contains(t, resp.Header.Get("Content-Type"), "text/vcard")
+		assert.Contains(t, body, "FN:Contact alice")
+		assert.Contains(t, body, "FN:Contact bob")
+		assert.Contains(t, body, "TEL:+49 30 1234567")
+		assert.Contains(t, body, "SGVsbG8=")
+		assert.NotContains(t, body, "carol")
+
+		req := httptest.NewRequest(http.MethodGet, feedURL+".vcf", nil)
+		req.Header.Set("If-None-Match", resp.Header.Get("ETag"))
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusNotModified, resp.StatusCode)
+
+		_, body = get(feedURL+"?version=4.0", "")
+		assert.Contains(t, body, "VERSION:4.0")
+		resp, _ = get(feedURL+"?version=2.1", "")
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("JCard", func(t *testing.T) {
+		for _, target := range []string{feedURL + ".json", feedURL} {
+			resp, body := get(target, "application/vcard+json")
+			require.Equal(t, fiber.StatusOK, resp.StatusCode)
+			assert.Contains(t, resp.Header.Get("Content-Type"), "application/vcard+json")
+			var cards []json.RawMessage
+			require.NoError(t, json.Unmarshal([]byte(body), &cards))
+			assert.Len(t, cards, 2)
+			assert.Contains(t, body, `"Contact alice"`)
+		}
+	})
+
+	t.Run("Field Filtering", func(t *testing.T) {
+		resp, _ := get(feedURL, "")
+		etag := resp.Header.Get("ETag")
+
+		resp = api(http.MethodPut, publicURL+"/options", map[string]bool{"hide_phones": true, "hide_addresses": true, "hide_photos": true})
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&status))
+		assert.True(t, status.Options.HidePhones)
+		assert.True(t, status.Options.HideAddresses)
+
+		resp, body := get(feedURL, "")
+		assert.NotEqual(t, etag, resp.Header.Get("ETag"))
+		assert.Contains(t, body, "EMAIL:alice@example.com")
+		assert.NotContains(t, body, "TEL")
+		assert.NotContains(t, body, "ADR")
+		assert.NotContains(t, body, "PHOTO")
+	})
+
+	t.Run("Regenerate And Disable", func(t *testing.T) {
+		resp := api(http.MethodPost, publicURL+"/regenerate", nil)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		var regenerated addressbookusecase.RegenerateTokenOutput
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&regenerated))
+		require.NotNil(t, regenerated.Token)
+		assert.NotEqual(t, *status.Token, *regenerated.Token)
+
+		resp, _ = get(feedURL, "")
+		assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+		newURL := "/public/addressbook/" + *regenerated.Token
+		resp, _ = get(newURL, "")
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		resp = api(http.MethodPost, publicURL, map[string]bool{"enabled": false})
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		resp, _ = get(newURL, "")
+		assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+
+		resp = api(http.MethodPost, publicURL+"/regenerate", nil)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+}
@@ -1,131 +1,379 @@
 package http
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"html/template"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/emersion/go-ical"
 	"github.com/gofiber/fiber/v3"
-	"github.com/jherrma/caldav-server/internal/domain/calendar"
+	authusecase "github.com/jherrma/caldav-server/internal/usecase/auth"
+	calendarusecase "github.com/jherrma/caldav-server/internal/usecase/calendar"
 )
 
 type PublicCalendarHandler struct {
-	calendarRepo calendar.CalendarRepository
+	publicFeedUC *calendarusecase.PublicFeedUseCase
 }
 
-func NewPublicCalendarHandler(calendarRepo calendar.CalendarRepository) *PublicCalendarHandler {
+func NewPublicCalendarHandler(publicFeedUC *calendarusecase.PublicFeedUseCase) *PublicCalendarHandler {
 	return &PublicCalendarHandler{
-		calendarRepo: calendarRepo,
+		publicFeedUC: publicFeedUC,
 	}
 }
 
+// PublicCalendarJSON is the JSON rendering of a public calendar feed
+type PublicCalendarJSON struct {
+	Name        string                        `json:"name"`
+	Description string                        `json:"description"`
+	Color       string                        `json:"color"`
+	Timezone    string                        `json:"timezone"`
+	From        time.Time                     `json:"from"`
+	To          time.Time                     `json:"to"`
+	Events      []calendarusecase.PublicEvent `json:"events"`
+}
+
 // GetICalFeed godoc
 // @Summary      Get public calendar feed
-// @Description  Get calendar events in iCalendar format via public token
+// @Description  Get calendar events via public token, as iCalendar (token or token.ics), JSON (token.json) or
+// @Description  HTML (token.html). The JSON and HTML renderings list the event occurrences in the publication
+// @Description  window. Password protected feeds ask for the password with HTTP Basic auth (any username).
 // @Tags         Public
 // @Produce      text/calendar
-// @Param        token  path   string  true  "Public Token or Token.ics"
+// @Produce      json
+// @Produce      html
+// @Param        token  path   string  true  "Public Token, optionally with .ics, .json or .html"
 // @Success      200    {file} file
+// @Failure      401    {string} string "Password required"
 // @Failure      404    {string} string "Calendar not found"
+// @Failure      429    {string} string "Too many wrong passwords; see Retry-After"
 // @Failure      500    {string} string "Internal error"
 // @Router       /public/calendar/{token} [get]
 func (h *PublicCalendarHandler) GetICalFeed(c fiber.Ctx) error {
 	token := c.Params("token")
-	// Remove .ics extension if present
-	token = strings.TrimSuffix(token, ".ics")
+	format := "ics"
+	for _, ext := range []string{"ics", "json", "html"} {
+		if strings.HasSuffix(token, "."+ext) {
+			token, format = strings.TrimSuffix(token, "."+ext), ext
+		}
+	}
 
-	// Find calendar by public token
-	cal, err := h.calendarRepo.FindByPublicToken(c.Context(), token)
-	if err != nil || cal == nil || !cal.PublicEnabled {
-		return c.Status(fiber.StatusNotFound).SendString("Calendar not found")
+	feed, err := h.publicFeedUC.Execute(c.Context(), token, feedPassword(c), c.Get("User-Agent"), c.IP())
+	if err != nil {
+		return publicFeedError(c, err)
 	}
 
-	// Check ETag for caching
-	clientETag := c.Get("If-None-Match")
-	currentETag := fmt.Sprintf(`"%s"`, cal.CTag)
-	if clientETag == currentETag {
+	if feedNotModified(c, feed, format) {
 		return c.SendStatus(fiber.StatusNotModified)
 	}
 
-	// Get all events
-	events, err := h.calendarRepo.GetCalendarObjects(c.Context(), cal.ID)
-	if err != nil {
+	switch format {
+	case "json":
+		from, to := feed.ListingWindow(time.Now())
+		events := feed.Events(from, to)
+		if events == nil {
+			events = []calendarusecase.PublicEvent{}
+		}
+		allowEmbedding(c)
+		c.Set("Access-Control-Allow-Origin", "*")
+		return c.JSON(PublicCalendarJSON{
+			Name:        feed.Calendar.Name,
+			Description: feed.Calendar.Description,
+			Color:       feed.Calendar.Color,
+			Timezone:    feed.Calendar.Timezone,
+			From:        from,
+			To:          to,
+			Events:      events,
+		})
+	case "html":
+		allowEmbedding(c)
+		return renderPublicCalendarHTML(c, feed)
+	}
+
+	var b strings.Builder
+	if err := ical.NewEncoder(&b).Encode(feed.Data); err != nil {
 		return c.Status(fiber.StatusInternalServerError).SendString("Internal error")
 	}
+	c.Set("Content-Type", "text/calendar; charset=utf-8")
+	c.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.ics"`, feed.Calendar.Name))
+	return c.SendString(b.String())
+}
+
+// GetView godoc
+// @Summary      Get embeddable public calendar
+// @Description  Render a public calendar as an HTML page for embedding in other sites. view is agenda (the
+// @Description  default), month or week; date (YYYY-MM-DD) picks the shown month or week, or the first day of
+// @Description  the agenda. tz is an IANA timezone (default: the calendar's), theme light, dark or auto
+// @Description  (following the visitor's color scheme) and lang one of en, de, fr, es, it and nl. Recurring
+// @Description  events are expanded; occurrences outside the publication window are not shown.
+// @Tags         Public
+// @Produce      html
+// @Param        token  path   string  true   "Public Token"
+// @Param        view   query  string  false  "agenda, month or week"
+// @Param        date   query  string  false  "Shown day (YYYY-MM-DD), default today"
+// @Param        tz     query  string  false  "IANA timezone"
+// @Param        theme  query  string  false  "light, dark or auto"
+// @Param        lang   query  string  false  "Language"
+// @Success      200    {string} string "HTML page"
+// @Failure      400    {string} string "Invalid parameter"
+// @Failure      401    {string} string "Password required"
+// @Failure      404    {string} string "Calendar not found"
+// @Failure      429    {string} string "Too many wrong passwords; see Retry-After"
+// @Failure      500    {string} string "Internal error"
+// @Router       /public/calendar/{token}/view [get]
+func (h *PublicCalendarHandler) GetView(c fiber.Ctx) error {
+	feed, err := h.publicFeedUC.Execute(c.Context(), c.Params("token"), feedPassword(c), c.Get("User-Agent"), c.IP())
+	if err != nil {
+		return publicFeedError(c, err)
+	}
+
+	view := &publicCalendarView{
+		view:   c.Query("view", publicViewAgenda),
+		loc:    publicCalendarTimezone(feed),
+		theme:  c.Query("theme", "light"),
+		locale: publicCalendarLanguage(c.Query("lang")),
+		query:  url.Values{},
+	}
+	if view.view != publicViewAgenda && view.view != publicViewMonth && view.view != publicViewWeek {
+		return c.Status(fiber.StatusBadRequest).SendString("view must be agenda, month or week")
+	}
+	if !publicCalendarThemes[view.theme] {
+		return c.Status(fiber.StatusBadRequest).SendString("theme must be light, dark or auto")
+	}
+	if tz := c.Query("tz"); tz != "" {
+		if view.loc, err = time.LoadLocation(tz); err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString("Unknown timezone")
+		}
+	}
+	for _, key := range []string{"tz", "theme", "lang"} {
+		if value := c.Query(key); value != "" {
+			view.query.Set(key, value)
+		}
+	}
+	now := time.Now()
+	today := now.In(view.loc)
+	view.today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, view.loc)
+	view.date = view.today
+	if date := c.Query("date"); date != "" {
+		if view.date, err = time.ParseInLocation("2006-01-02", date, view.loc); err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString("date must be YYYY-MM-DD")
+		}
+		if view.date.Before(view.today.AddDate(-publicViewMaxYears, 0, 0)) || view.date.After(view.today.AddDate(publicViewMaxYears, 0, 0)) {
+			return c.Status(fiber.StatusBadRequest).SendString(fmt.Sprintf("date must be within %d years of today", publicViewMaxYears))
+		}
+	}
 
-	// Generate iCal feed
-	ical := h.generateICalFeed(cal, events)
+	// The page changes with the feed and, through the highlighted day, daily
+	variant := fmt.Sprintf("view|%s|%s|%s|%s|%s|%s", view.view, view.date.Format("2006-01-02"),
+		view.today.Format("2006-01-02"), view.loc, view.theme, view.locale.code)
+	sum := sha256.Sum256([]byte(variant))
+	if feedNotModified(c, feed, hex.EncodeToString(sum[:8])) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
 
-	// Set headers
-	c.Set("Content-Type", "text/calendar; charset=utf-8")
-	c.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.ics"`, cal.Name))
-	c.Set("Cache-Control", "public, max-age=300")
-	c.Set("ETag", currentETag)
+	page, err := view.render(feed, now)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString("Internal error")
+	}
+	allowEmbedding(c)
+	c.Set("Content-Type", "text/html; charset=utf-8")
+	c.Set("Content-Language", view.locale.code)
+	return c.SendString(page)
+}
 
-	return c.SendString(ical)
+// feedNotModified answers conditional requests for a rendering of a feed.
+// The ETag of a rendering is the feed's, which follows the calendar's CTag,
+// with the variant appended.
+func feedNotModified(c fiber.Ctx, feed *calendarusecase.PublicFeed, variant string) bool {
+	currentETag := strings.TrimSuffix(feed.ETag, `"`) + "-" + variant + `"`
+	if c.Get("If-None-Match") == currentETag {
+		return true
+	}
+	if feed.Calendar.PublicFeed.Protected() {
+		c.Set("Cache-Control", "private, max-age=300")
+	} else {
+		c.Set("Cache-Control", "public, max-age=300")
+	}
+	c.Set("ETag", currentETag)
+	return false
 }
 
-func (h *PublicCalendarHandler) generateICalFeed(cal *calendar.Calendar, events []*calendar.CalendarObject) string {
-	var b strings.Builder
+// publicFeedError answers a request for a feed that could not be opened
+func publicFeedError(c fiber.Ctx, err error) error {
+	var locked *authusecase.LockedError
+	switch {
+	case errors.Is(err, calendarusecase.ErrPublicFeedNotFound):
+		return c.Status(fiber.StatusNotFound).SendString("Calendar not found")
+	case errors.Is(err, calendarusecase.ErrPublicFeedUnauthorized):
+		c.Set("WWW-Authenticate", `Basic realm="Calendar"`)
+		return c.Status(fiber.StatusUnauthorized).SendString("Password required")
+	case errors.As(err, &locked):
+		c.Set("Retry-After", strconv.Itoa(locked.RetryAfterSeconds()))
+		return c.Status(fiber.StatusTooManyRequests).SendString("Too many wrong passwords, try again later")
+	}
+	return c.Status(fiber.StatusInternalServerError).SendString("Internal error")
+}
 
-	// VCALENDAR header
-	b.WriteString("BEGIN:VCALENDAR\r\n")
-	b.WriteString("VERSION:2.0\r\n")
-	b.WriteString("PRODID:-//CalDAV Server//EN\r\n")
-	b.WriteString("CALSCALE:GREGORIAN\r\n")
-	b.WriteString("METHOD:PUBLISH\r\n")
-	b.WriteString(fmt.Sprintf("X-WR-CALNAME:%s\r\n", escapeICalText(cal.Name)))
-	if cal.Timezone != "" {
-		b.WriteString(fmt.Sprintf("X-WR-TIMEZONE:%s\r\n", cal.Timezone))
-	}
-
-	// Add events - extract VEVENT/VTODO from stored iCalendar data
-	for _, event := range events {
-		vevent := extractVComponent(event.ICalData)
-		if vevent != "" {
-			b.WriteString(vevent)
-			b.WriteString("\r\n")
+// feedPassword returns the password of HTTP Basic credentials; public feeds
+// ignore the username
+func feedPassword(c fiber.Ctx) string {
+	if header := c.Get("Authorization"); len(header) > 6 && strings.EqualFold(header[:6], "basic ") {
+		if payload, err := base64.StdEncoding.DecodeString(header[6:]); err == nil {
+			if _, password, ok := strings.Cut(string(payload), ":"); ok {
+				return password
+			}
 		}
 	}
+	return ""
+}
+
+// allowEmbedding lets other sites show a public rendering in a frame or
+// fetch it, which the global security headers forbid
+func allowEmbedding(c fiber.Ctx) {
+	c.Response().Header.Del("X-Frame-Options")
+	c.Set("Content-Security-Policy", "frame-ancestors *")
+	c.Set("Cross-Origin-Resource-Policy", "cross-origin")
+}
+
+var publicCalendarTemplate = template.Must(template.New("calendar").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Name}}</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 1rem; color: #222; }
+h1 { border-left: 6px solid {{.Color}}; padding-left: .5rem; font-size: 1.4rem; }
+h2 { font-size: 1rem; margin: 1.2rem 0 .4rem; color: #555; }
+ul { list-style: none; padding: 0; margin: 0; }
+li { padding: .3rem 0; border-bottom: 1px solid #eee; }
+.time { display: inline-block; min-width: 7.5rem; color: #555; }
+.busy { color: #888; font-style: italic; }
+.details { margin: .2rem 0 0 7.5rem; color: #555; font-size: .9rem; white-space: pre-line; }
+</style>
+</head>
+<body>
+<h1>{{.Name}}</h1>
+{{if .Description}}<p>{{.Description}}</p>{{end}}
+{{range .Days}}<h2>{{.Date}}</h2>
+<ul>
+{{range .Events}}<li><span class="time">{{.Time}}</span> <span{{if .Busy}} class="busy"{{end}}>{{.Summary}}</span>
+{{if .Location}}<div class="details">{{.Location}}</div>{{end}}{{if .Description}}<div class="details">{{.Description}}</div>{{end}}</li>
+{{end}}</ul>
+{{else}}<p>No upcoming events.</p>
+{{end}}</body>
+</html>
+`))
 
-	b.WriteString("END:VCALENDAR\r\n")
-	return b.String()
+type publicCalendarDay struct {
+	Date    string
+	InRange bool // In the shown month, for days of the month view
+	Today   bool
+	Events  []publicCalendarEvent
 }
 
-func escapeICalText(s string) string {
-	s = strings.ReplaceAll(s, "\\", "\\\\")
-	s = strings.ReplaceAll(s, ";", "\\;")
-	s = strings.ReplaceAll(s, ",", "\\,")
-	s = strings.ReplaceAll(s, "\n", "\\n")
-	return s
+type publicCalendarEvent struct {
+	calendarusecase.PublicEvent
+	Time string
+}
+
+// renderPublicCalendarHTML renders the event occurrences of a feed as an
+// agenda grouped by day, in the timezone of the calendar
+func renderPublicCalendarHTML(c fiber.Ctx, feed *calendarusecase.PublicFeed) error {
+	color := feed.Calendar.Color
+	if color == "" {
+		color = "#3788d8"
+	}
+
+	from, to := feed.ListingWindow(time.Now())
+	page, err := executeTemplate(publicCalendarTemplate, map[string]any{
+		"Name":        feed.Calendar.Name,
+		"Description": feed.Calendar.Description,
+		"Color":       template.CSS(color),
+		"Days":        agendaDays(feed.Events(from, to), publicCalendarTimezone(feed), publicCalendarLocales["en"]),
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString("Internal error")
+	}
+	c.Set("Content-Type", "text/html; charset=utf-8")
+	return c.SendString(page)
 }
 
-func extractVComponent(icalData string) string {
-	// Extract VEVENT, VTODO, or VTIMEZONE components from iCalendar data
-	var result strings.Builder
-	lines := strings.Split(icalData, "\n")
-	inComponent := false
-	depth := 0
+func executeTemplate(t *template.Template, data any) (string, error) {
+	var b strings.Builder
+	if err := t.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
 
-	for _, line := range lines {
-		line = strings.TrimRight(line, "\r")
-		if strings.HasPrefix(line, "BEGIN:VEVENT") ||
-			strings.HasPrefix(line, "BEGIN:VTODO") ||
-			strings.HasPrefix(line, "BEGIN:VTIMEZONE") {
-			inComponent = true
-			depth++
+// publicCalendarTimezone returns the timezone of a feed's calendar, UTC if
+// it has none
+func publicCalendarTimezone(feed *calendarusecase.PublicFeed) *time.Location {
+	if feed.Calendar.Timezone != "" {
+		if l, err := time.LoadLocation(feed.Calendar.Timezone); err == nil {
+			return l
 		}
-		if inComponent {
-			result.WriteString(line)
-			result.WriteString("\r\n")
+	}
+	return time.UTC
+}
+
+// agendaDays groups event occurrences by the day they start on in loc.
+// All-day events are floating and keep their date.
+func agendaDays(events []calendarusecase.PublicEvent, loc *time.Location, l *publicCalendarLocale) []publicCalendarDay {
+	var days []publicCalendarDay
+	for _, e := range events {
+		start, end := e.Start.In(loc), e.End.In(loc)
+		event := publicCalendarEvent{PublicEvent: e, Time: start.Format("15:04") + " – " + end.Format("15:04")}
+		if e.AllDay {
+			start = e.Start.UTC()
+			event.Time = l.allDay
 		}
-		if strings.HasPrefix(line, "END:VEVENT") ||
-			strings.HasPrefix(line, "END:VTODO") ||
-			strings.HasPrefix(line, "END:VTIMEZONE") {
-			depth--
-			if depth == 0 {
-				inComponent = false
-			}
+		date := l.format(l.longDate, start)
+		if len(days) == 0 || days[len(days)-1].Date != date {
+			days = append(days, publicCalendarDay{Date: date})
 		}
+		days[len(days)-1].Events = append(days[len(days)-1].Events, event)
+	}
+	return days
+}
+
+// occurrenceDays returns midnight in loc of every day an occurrence covers
+func occurrenceDays(e calendarusecase.PublicEvent, loc *time.Location) []time.Time {
+	start, end := e.Start.In(loc), e.End.In(loc).Add(-time.Nanosecond)
+	if e.AllDay {
+		start, end = e.Start.UTC(), e.End.UTC().Add(-time.Nanosecond)
+	}
+	first := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, loc)
+	last := time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, loc)
+	days := []time.Time{first}
+	for day := first.AddDate(0, 0, 1); !day.After(last); day = day.AddDate(0, 0, 1) {
+		days = append(days, day)
+	}
+	return days
+}
+
+// gridEvent labels an occurrence in the cell of one of its days: with its
+// start time on the first day, its end time on the last and as all-day on
+// the days between
+func gridEvent(e calendarusecase.PublicEvent, day time.Time, loc *time.Location, l *publicCalendarLocale) publicCalendarEvent {
+	event := publicCalendarEvent{PublicEvent: e, Time: l.allDay}
+	if e.AllDay {
+		return event
+	}
+	start, end := e.Start.In(loc), e.End.In(loc)
+	next := day.AddDate(0, 0, 1)
+	switch {
+	case !start.Before(day):
+		event.Time = start.Format("15:04")
+	case end.Before(next):
+		event.Time = "– " + end.Format("15:04")
 	}
-	return strings.TrimRight(result.String(), "\r\n")
+	return event
 }
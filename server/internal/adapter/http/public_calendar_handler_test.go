@@ -0,0 +1,347 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/jherrma/caldav-server/internal/adapter/repository"
+	"github.com/jherrma/caldav-server/internal/domain/calendar"
+	calendarusecase "github.com/jherrma/caldav-server/internal/usecase/calendar"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublicCalendarFeed(t *testing.T) {
+	app, db, _ := setupTestApp(t)
+	ctx := context.Background()
+
+	body, _ := json.Marshal(map[string]string{"email": "owner@example.com", "password": "Password123!", "display_name": "Owner"})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/register", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	token := loginForTest(t, app, "owner@example.com", "Password123!", "test").AccessToken
+
+	userRepo := repository.NewUserRepository(db.DB())
+	owner, err := userRepo.GetByEmail(ctx, "owner@example.com")
+	require.NoError(t, err)
+	calendarRepo := repository.NewCalendarRepository(db.DB())
+	cal := &calendar.Calendar{UUID: "public-cal", UserID: owner.ID, Name: "Events", Path: "events", Color: "#28a745", Timezone: "UTC"}
+	require.NoError(t, calendarRepo.Create(ctx, cal))
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	addEvent := func(uid string, start time.Time, extra string) {
+		end := start.Add(time.Hour)
+		require.NoError(t, calendarRepo.CreateCalendarObject(ctx, &calendar.CalendarObject{
+			UUID: uid, CalendarID: cal.ID, Path: uid + ".ics", UID: uid, ETag: `"` + uid + `"`, ComponentType: "VEVENT",
+			StartTime: &start, EndTime: &end,
+			ICalData: fmt.Sprintf("BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//Test//EN\r\nBEGIN:VEVENT\r\nUID:%s\r\nDTSTAMP:20240101T000000Z\r\n"+
+				"DTSTART:%s\r\nDTEND:%s\r\nSUMMARY:Event %s\r\nDESCRIPTION:Details of %s\r\nATTENDEE:mailto:guest@example.com\r\n%sEND:VEVENT\r\nEND:VCALENDAR\r\n",
+				uid, start.Format("20060102T150405Z"), end.Format("20060102T150405Z"), uid, uid, extra),
+		}))
+	}
+	addEvent("upcoming", today.AddDate(0, 0, 2).Add(10*time.Hour), "")
+	addEvent("old", today.AddDate(0, 0, -40).Add(10*time.Hour), "")
+	addEvent("private", today.AddDate(0, 0, 3).Add(10*time.Hour), "CLASS:PRIVATE\r\n")
+	addEvent("confidential", today.AddDate(0, 0, 4).Add(10*time.Hour), "CLASS:CONFIDENTIAL\r\n")
+
+	api := func(method, target string, body any) *http.Response {
+		data, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, target, bytes.NewReader(data))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp
+	}
+	resp = api(http.MethodPost, fmt.Sprintf("/api/v1/calendars/%d/public", cal.ID), map[string]bool{"enabled": true})
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var status calendarusecase.EnablePublicOutput
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&status))
+	require.NotNil(t, status.Token)
+	feedURL := "/public/calendar/" + *status.Token
+
+	get := func(target, password string) (*http.Response, string) {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if password != "" {
+			req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte("feed:"+password)))
+		}
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		data, _ := io.ReadAll(resp.Body)
+		return resp, string(data)
+	}
+	setOptions := func(options map[string]any) {
+		resp := api(http.MethodPut, fmt.Sprintf("/api/v1/calendars/%d/public/options", cal.ID), options)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	}
+
+	t.Run("Privacy Classes", func(t *testing.T) {
+		resp, body := get(feedURL+".ics", "")
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Contains(t, body, "X-WR-CALNAME:Events")
+		assert.Contains(t, body, "SUMMARY:Event upcoming")
+		assert.Contains(t, body, "SUMMARY:Event old")
+		assert.NotContains(t, body, "Event private")
+		assert.NotContains(t, body, "Event confidential")
+		assert.Contains(t, body, "SUMMARY:Busy")
+		assert.Contains(t, body, "ATTENDEE:mailto:guest@example.com")
+
+		req := httptest.NewRequest(http.MethodGet, feedURL+".ics", nil)
+		req.Header.Set("If-None-Match", resp.Header.Get("ETag"))
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusNotModified, resp.StatusCode)
+	})
+
+	t.Run("Window And Stripping", func(t *testing.T) {
+		setOptions(map[string]any{"past_days": 7, "future_days": 30, "hide_attendees": true, "hide_descriptions": true})
+		_, body := get(feedURL, "")
+		assert.Contains(t, body, "SUMMARY:Event upcoming")
+		assert.NotContains(t, body, "Event old")
+		assert.NotContains(t, body, "ATTENDEE")
+		assert.NotContains(t, body, "DESCRIPTION")
+	})
+
+	t.Run("Free Busy Only", func(t *testing.T) {
+		setOptions(map[string]any{"free_busy_only": true})
+		_, body := get(feedURL+".ics", "")
+		assert.NotContains(t, body, "Event upcoming")
+		assert.Contains(t, body, "SUMMARY:Busy")
+	})
+
+	t.Run("JSON And HTML", func(t *testing.T) {
+		setOptions(map[string]any{"future_days": 30})
+		resp, body := get(feedURL+".json", "")
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Equal(t, "*", resp.Header.Get("Access-Control-Allow-Origin"))
+		var out PublicCalendarJSON
+		require.NoError(t, json.Unmarshal([]byte(body), &out))
+		require.Len(t, out.Events, 2)
+		assert.Equal(t, "Event upcoming", out.Events[0].Summary)
+		assert.False(t, out.Events[0].Busy)
+		assert.True(t, out.Events[1].Busy)
+
+		resp, body = get(feedURL+".html", "")
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Contains(t, resp.Header.Get("Content-Type"), "text/html")
+		assert.Contains(t, body, "Event upcoming")
+		assert.Contains(t, body, "Details of upcoming")
+		assert.NotContains(t, body, "Event old")
+	})
+
+	t.Run("Password And Expiry", func(t *testing.T) {
+		setOptions(map[string]any{"password": "s3cret"})
+		resp, _ := get(feedURL, "")
+		assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+		assert.Contains(t, resp.Header.Get("WWW-Authenticate"), "Basic")
+		resp, _ = get(feedURL, "wrong")
+		assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+		resp, _ = get(feedURL, "s3cret")
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Contains(t, resp.Header.Get("Cache-Control"), "private")
+
+		// Other options keep the password unless it is sent
+		setOptions(map[string]any{"expires_at": time.Now().Add(-time.Minute)})
+		resp, _ = get(feedURL, "s3cret")
+		assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+
+		resp = api(http.MethodPut, fmt.Sprintf("/api/v1/calendars/%d/public/options", cal.ID), map[string]any{"password": ""})
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&status))
+		assert.False(t, status.Options.PasswordProtected)
+		resp, _ = get(feedURL, "")
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("Password Guessing", func(t *testing.T) {
+		setOptions(map[string]any{"password": "s3cret"})
+
+		// Challenges without a password are free; wrong passwords lock the
+		// feed once the free attempts are used up
+		for i := 0; i < 5; i++ {
+			resp, _ := get(feedURL, "")
+			assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+		}
+		for i := 0; i < 4; i++ {
+			resp, _ := get(feedURL, fmt.Sprintf("guess-%d", i))
+			assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+		}
+		resp, _ := get(feedURL, "s3cret")
+		assert.Equal(t, fiber.StatusTooManyRequests, resp.StatusCode)
+		assert.NotEmpty(t, resp.Header.Get("Retry-After"))
+
+		resp = api(http.MethodPut, fmt.Sprintf("/api/v1/calendars/%d/public/options", cal.ID), map[string]any{"password": ""})
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("Invalid Options", func(t *testing.T) {
+		resp := api(http.MethodPut, fmt.Sprintf("/api/v1/calendars/%d/public/options", cal.ID), map[string]any{"past_days": -1})
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+func TestPublicCalendarView(t *testing.T) {
+	app, db, _ := setupTestApp(t)
+	ctx := context.Background()
+
+	body, _ := json.Marshal(map[string]string{"email": "embed@example.com", "password": "Password123!", "display_name": "Embed"})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/register", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	token := loginForTest(t, app, "embed@example.com", "Password123!", "test").AccessToken
+
+	owner, err := repository.NewUserRepository(db.DB()).GetByEmail(ctx, "embed@example.com")
+	require.NoError(t, err)
+	calendarRepo := repository.NewCalendarRepository(db.DB())
+	cal := &calendar.Calendar{UUID: "embed-cal", UserID: owner.ID, Name: "Meetups", Path: "meetups", Color: "#28a745", Timezone: "UTC"}
+	require.NoError(t, calendarRepo.Create(ctx, cal))
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	meetup := today.Add(10 * time.Hour)
+	addObject := func(uid string, start time.Time, event string) {
+		require.NoError(t, calendarRepo.CreateCalendarObject(ctx, &calendar.CalendarObject{
+			UUID: uid, CalendarID: cal.ID, Path: uid + ".ics", UID: uid, ETag: `"` + uid + `"`, ComponentType: "VEVENT", StartTime: &start,
+			IsAllDay: strings.Contains(event, "VALUE=DATE"),
+			ICalData: "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//Test//EN\r\nBEGIN:VEVENT\r\nUID:" + uid + "\r\nDTSTAMP:20240101T000000Z\r\n" +
+				event + "END:VEVENT\r\nEND:VCALENDAR\r\n",
+		}))
+	}
+	addObject("meetup", meetup, "DTSTART:"+meetup.Format("20060102T150405Z")+"\r\nDTEND:"+meetup.Add(2*time.Hour).Format("20060102T150405Z")+
+		"\r\nRRULE:FREQ=WEEKLY;COUNT=10\r\nSUMMARY:Weekly meetup\r\n")
+	holiday := today.AddDate(0, 0, 1)
+	addObject("holiday", holiday, "DTSTART;VALUE=DATE:"+holiday.Format("20060102")+"\r\nDTEND;VALUE=DATE:"+holiday.AddDate(0, 0, 1).Format("20060102")+
+		"\r\nSUMMARY:Holiday\r\n")
+
+	api := func(method, target string, body any) *http.Response {
+		data, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, target, bytes.NewReader(data))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp
+	}
+	resp = api(http.MethodPost, fmt.Sprintf("/api/v1/calendars/%d/public", cal.ID), map[string]bool{"enabled": true})
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var status calendarusecase.EnablePublicOutput
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&status))
+	require.NotNil(t, status.EmbedURL)
+	assert.True(t, strings.HasSuffix(*status.EmbedURL, "/public/calendar/"+*status.Token+"/view"))
+	viewURL := "/public/calendar/" + *status.Token + "/view"
+
+	get := func(query, etag string) (*http.Response, string) {
+		req := httptest.NewRequest(http.MethodGet, viewURL+query, nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		data, _ := io.ReadAll(resp.Body)
+		return resp, string(data)
+	}
+	// occurrences counts the meetups shown in the cells of a month or week
+	occurrences := func(body string) int { return strings.Count(body, ">Weekly meetup</div>") }
+
+	t.Run("Agenda", func(t *testing.T) {
+		resp, body := get("", "")
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Contains(t, resp.Header.Get("Content-Type"), "text/html")
+		assert.Empty(t, resp.Header.Get("X-Frame-Options"))
+		assert.Contains(t, resp.Header.Get("Content-Security-Policy"), "frame-ancestors *")
+		assert.Equal(t, "public, max-age=300", resp.Header.Get("Cache-Control"))
+		assert.Equal(t, 10, strings.Count(body, ">Weekly meetup</span>"))
+		assert.Contains(t, body, "All day")
+		assert.Contains(t, body, `<body class="light">`)
+	})
+
+	t.Run("Month And Week", func(t *testing.T) {
+		resp, body := get("?view=week&date="+today.Format("2006-01-02"), "")
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Equal(t, 1, occurrences(body))
+		assert.Contains(t, body, ">Holiday</div>")
+		assert.Contains(t, body, "?date="+today.AddDate(0, 0, -int(today.Weekday()+6)%7+7).Format("2006-01-02")+"&amp;view=week")
+
+		// The month grid runs from the Monday before the first to the
+		// Sunday after the last day of the month
+		month := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, 1, 0)
+		first := month.AddDate(0, 0, -(int(month.Weekday())+6)%7)
+		monthEnd := month.AddDate(0, 1, -1)
+		last := monthEnd.AddDate(0, 0, 7-(int(monthEnd.Weekday())+6)%7)
+		want := 0
+		for i := 0; i < 10; i++ {
+			if day := today.AddDate(0, 0, 7*i); !day.Before(first) && day.Before(last) {
+				want++
+			}
+		}
+		resp, body = get("?view=month&date="+month.Format("2006-01-02"), "")
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Equal(t, want, occurrences(body))
+		assert.Equal(t, int(last.Sub(first).Hours()/24), strings.Count(body, "<td class="))
+	})
+
+	t.Run("Theme Language And Timezone", func(t *testing.T) {
+		berlin, err := time.LoadLocation("Europe/Berlin")
+		require.NoError(t, err)
+		resp, body := get("?view=week&theme=dark&lang=de-DE&tz=Europe/Berlin&date="+today.Format("2006-01-02"), "")
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Equal(t, "de", resp.Header.Get("Content-Language"))
+		assert.Contains(t, body, `<html lang="de">`)
+		assert.Contains(t, body, `<body class="dark">`)
+		assert.Contains(t, body, "Ganztägig")
+		assert.Contains(t, body, ">"+meetup.In(berlin).Format("15:04")+"</span>Weekly meetup")
+		assert.Contains(t, body, "tz=Europe%2FBerlin")
+
+		for _, query := range []string{"?view=year", "?theme=neon", "?tz=Mars/Olympus", "?date=tomorrow"} {
+			resp, _ := get(query, "")
+			assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode, query)
+		}
+
+		// Dates far from today would expand recurrences over centuries
+		for _, date := range []string{"0001-01-01", "9999-12-31", today.AddDate(6, 0, 0).Format("2006-01-02"), today.AddDate(-6, 0, 0).Format("2006-01-02")} {
+			resp, _ := get("?view=month&date="+date, "")
+			assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode, date)
+		}
+		resp, _ = get("?view=month&date="+today.AddDate(4, 0, 0).Format("2006-01-02"), "")
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("Cache Follows CTag", func(t *testing.T) {
+		resp, _ := get("?view=month", "")
+		etag := resp.Header.Get("ETag")
+		require.NotEmpty(t, etag)
+		resp, _ = get("?view=month", etag)
+		assert.Equal(t, fiber.StatusNotModified, resp.StatusCode)
+		resp, _ = get("?view=week", etag)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		addObject("launch", today.AddDate(0, 0, 2), "DTSTART:"+today.AddDate(0, 0, 2).Format("20060102T150405Z")+"\r\nSUMMARY:Launch\r\n")
+		resp, body := get("?view=month", etag)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.NotEqual(t, etag, resp.Header.Get("ETag"))
+		assert.Contains(t, body, "Launch")
+	})
+
+	t.Run("Publication Window", func(t *testing.T) {
+		resp := api(http.MethodPut, fmt.Sprintf("/api/v1/calendars/%d/public/options", cal.ID), map[string]any{"future_days": 10})
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		_, body := get("?view=week&date="+today.AddDate(0, 0, 7).Format("2006-01-02"), "")
+		assert.Equal(t, 1, occurrences(body))
+		_, body = get("?view=week&date="+today.AddDate(0, 0, 35).Format("2006-01-02"), "")
+		assert.Zero(t, occurrences(body))
+	})
+}
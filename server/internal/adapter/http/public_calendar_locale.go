@@ -0,0 +1,159 @@
+package http
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// publicCalendarLocale holds the names and phrases of one language of the
+// public calendar views. Date patterns use the placeholders {weekday},
+// {day}, {month} and {year}.
+type publicCalendarLocale struct {
+	code      string
+	months    [12]string
+	weekdays  [7]string // Sunday first, as time.Weekday
+	shortDays [7]string
+	firstDay  time.Weekday
+	longDate  string // A day heading of the agenda
+	dayMonth  string // A day heading of the week view
+	monthYear string // The heading of the month view
+	allDay    string
+	noEvents  string
+	today     string
+	previous  string
+	next      string
+	agenda    string
+	month     string
+	week      string
+}
+
+var publicCalendarLocales = map[string]*publicCalendarLocale{
+	"en": {
+		code:      "en",
+		months:    [12]string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"},
+		weekdays:  [7]string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"},
+		shortDays: [7]string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"},
+		firstDay:  time.Monday,
+		longDate:  "{weekday}, {day} {month} {year}",
+		dayMonth:  "{day} {month}",
+		monthYear: "{month} {year}",
+		allDay:    "All day",
+		noEvents:  "No upcoming events.",
+		today:     "Today",
+		previous:  "Previous",
+		next:      "Next",
+		agenda:    "Agenda",
+		month:     "Month",
+		week:      "Week",
+	},
+	"de": {
+		code:      "de",
+		months:    [12]string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"},
+		weekdays:  [7]string{"Sonntag", "Montag", "Dienstag", "Mittwoch", "Donnerstag", "Freitag", "Samstag"},
+		shortDays: [7]string{"So", "Mo", "Di", "Mi", "Do", "Fr", "Sa"},
+		firstDay:  time.Monday,
+		longDate:  "{weekday}, {day}. {month} {year}",
+		dayMonth:  "{day}. {month}",
+		monthYear: "{month} {year}",
+		allDay:    "Ganztägig",
+		noEvents:  "Keine anstehenden Termine.",
+		today:     "Heute",
+		previous:  "Zurück",
+		next:      "Weiter",
+		agenda:    "Terminliste",
+		month:     "Monat",
+		week:      "Woche",
+	},
+	"fr": {
+		code:      "fr",
+		months:    [12]string{"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"},
+		weekdays:  [7]string{"dimanche", "lundi", "mardi", "mercredi", "jeudi", "vendredi", "samedi"},
+		shortDays: [7]string{"dim.", "lun.", "mar.", "mer.", "jeu.", "ven.", "sam."},
+		firstDay:  time.Monday,
+		longDate:  "{weekday} {day} {month} {year}",
+		dayMonth:  "{day} {month}",
+		monthYear: "{month} {year}",
+		allDay:    "Toute la journée",
+		noEvents:  "Aucun événement à venir.",
+		today:     "Aujourd'hui",
+		previous:  "Précédent",
+		next:      "Suivant",
+		agenda:    "Agenda",
+		month:     "Mois",
+		week:      "Semaine",
+	},
+	"es": {
+		code:      "es",
+		months:    [12]string{"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+		weekdays:  [7]string{"domingo", "lunes", "martes", "miércoles", "jueves", "viernes", "sábado"},
+		shortDays: [7]string{"dom", "lun", "mar", "mié", "jue", "vie", "sáb"},
+		firstDay:  time.Monday,
+		longDate:  "{weekday}, {day} de {month} de {year}",
+		dayMonth:  "{day} de {month}",
+		monthYear: "{month} de {year}",
+		allDay:    "Todo el día",
+		noEvents:  "No hay próximos eventos.",
+		today:     "Hoy",
+		previous:  "Anterior",
+		next:      "Siguiente",
+		agenda:    "Agenda",
+		month:     "Mes",
+		week:      "Semana",
+	},
+	"it": {
+		code:      "it",
+		months:    [12]string{"gennaio", "febbraio", "marzo", "aprile", "maggio", "giugno", "luglio", "agosto", "settembre", "ottobre", "novembre", "dicembre"},
+		weekdays:  [7]string{"domenica", "lunedì", "martedì", "mercoledì", "giovedì", "venerdì", "sabato"},
+		shortDays: [7]string{"dom", "lun", "mar", "mer", "gio", "ven", "sab"},
+		firstDay:  time.Monday,
+		longDate:  "{weekday} {day} {month} {year}",
+		dayMonth:  "{day} {month}",
+		monthYear: "{month} {year}",
+		allDay:    "Tutto il giorno",
+		noEvents:  "Nessun evento in programma.",
+		today:     "Oggi",
+		previous:  "Precedente",
+		next:      "Successivo",
+		agenda:    "Agenda",
+		month:     "Mese",
+		week:      "Settimana",
+	},
+	"nl": {
+		code:      "nl",
+		months:    [12]string{"januari", "februari", "maart", "april", "mei", "juni", "juli", "augustus", "september", "oktober", "november", "december"},
+		weekdays:  [7]string{"zondag", "maandag", "dinsdag", "woensdag", "donderdag", "vrijdag", "zaterdag"},
+		shortDays: [7]string{"zo", "ma", "di", "wo", "do", "vr", "za"},
+		firstDay:  time.Monday,
+		longDate:  "{weekday} {day} {month} {year}",
+		dayMonth:  "{day} {month}",
+		monthYear: "{month} {year}",
+		allDay:    "Hele dag",
+		noEvents:  "Geen komende afspraken.",
+		today:     "Vandaag",
+		previous:  "Vorige",
+		next:      "Volgende",
+		agenda:    "Agenda",
+		month:     "Maand",
+		week:      "Week",
+	},
+}
+
+// publicCalendarLanguage returns the locale of a language tag such as "de"
+// or "de-CH", English for languages without one
+func publicCalendarLanguage(tag string) *publicCalendarLocale {
+	base, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+	if l, ok := publicCalendarLocales[base]; ok {
+		return l
+	}
+	return publicCalendarLocales["en"]
+}
+
+func (l *publicCalendarLocale) format(pattern string, t time.Time) string {
+	return strings.NewReplacer(
+		"{weekday}", l.weekdays[t.Weekday()],
+		"{day}", strconv.Itoa(t.Day()),
+		"{month}", l.months[t.Month()-1],
+		"{year}", strconv.Itoa(t.Year()),
+	).Replace(pattern)
+}
@@ -0,0 +1,225 @@
+package http
+
+import (
+	"fmt"
+	"html/template"
+	"net/url"
+	"time"
+
+	calendarusecase "github.com/jherrma/caldav-server/internal/usecase/calendar"
+)
+
+// Views of the embeddable public calendar page
+const (
+	publicViewAgenda = "agenda"
+	publicViewMonth  = "month"
+	publicViewWeek   = "week"
+
+	// publicViewMaxYears is how far from today the views can be moved,
+	// which bounds the recurrences expanded for a page of a feed without a
+	// publication window
+	publicViewMaxYears = 5
+)
+
+// Themes of the embeddable public calendar page; auto follows the color
+// scheme of the visitor's system
+var publicCalendarThemes = map[string]bool{"light": true, "dark": true, "auto": true}
+
+// publicCalendarView is a request for the embeddable page of a public
+// calendar. Theme, language and timezone are kept in the navigation links
+// only when the request set them.
+type publicCalendarView struct {
+	view   string
+	date   time.Time // Midnight of the shown day, in loc
+	today  time.Time
+	loc    *time.Location
+	theme  string
+	locale *publicCalendarLocale
+	query  url.Values // The tz, theme and lang parameters of the request
+}
+
+var publicCalendarViewTemplate = template.Must(template.New("view").Parse(`<!DOCTYPE html>
+<html lang="{{.Lang}}">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Name}}</title>
+<style>
+body { --fg: #222; --muted: #666; --bg: #fff; --line: #e3e3e3; --outside: #f6f6f6; --today: #fff8dc; }
+body.dark { --fg: #e6e6e6; --muted: #a0a0a0; --bg: #1c1c1e; --line: #3a3a3c; --outside: #262628; --today: #3a3424; }
+@media (prefers-color-scheme: dark) {
+body.auto { --fg: #e6e6e6; --muted: #a0a0a0; --bg: #1c1c1e; --line: #3a3a3c; --outside: #262628; --today: #3a3424; }
+}
+body { font-family: system-ui, sans-serif; margin: 1rem; color: var(--fg); background: var(--bg); }
+a { color: inherit; }
+h1 { border-left: 6px solid {{.Color}}; padding-left: .5rem; font-size: 1.4rem; }
+h2 { font-size: 1rem; margin: 1.2rem 0 .4rem; color: var(--muted); }
+nav { display: flex; flex-wrap: wrap; gap: .5rem; align-items: center; margin-bottom: .8rem; }
+nav .title { font-weight: 600; margin-right: auto; }
+nav a { padding: .2rem .5rem; border: 1px solid var(--line); border-radius: 4px; text-decoration: none; }
+nav a.current { background: {{.Color}}; border-color: {{.Color}}; color: #fff; }
+ul { list-style: none; padding: 0; margin: 0; }
+li { padding: .3rem 0; border-bottom: 1px solid var(--line); }
+.time { display: inline-block; min-width: 7.5rem; color: var(--muted); }
+.busy { color: var(--muted); font-style: italic; }
+.details { margin: .2rem 0 0 7.5rem; color: var(--muted); font-size: .9rem; white-space: pre-line; }
+table { width: 100%; border-collapse: collapse; table-layout: fixed; }
+th { font-weight: 600; color: var(--muted); padding: .3rem; text-align: left; }
+td { border: 1px solid var(--line); vertical-align: top; padding: .2rem; height: 5.5rem; overflow: hidden; }
+td.outside { background: var(--outside); color: var(--muted); }
+td.today { background: var(--today); }
+td .day { font-size: .85rem; color: var(--muted); }
+td .event { font-size: .8rem; margin: .15rem 0; padding-left: .3rem; border-left: 3px solid {{.Color}}; overflow: hidden; text-overflow: ellipsis; white-space: nowrap; }
+td .event .time { min-width: 0; margin-right: .2rem; }
+.week td { height: 12rem; }
+.week td .event { white-space: normal; }
+</style>
+</head>
+<body class="{{.Theme}}">
+<h1>{{.Name}}</h1>
+{{if .Description}}<p>{{.Description}}</p>{{end}}
+<nav>
+<span class="title">{{.Title}}</span>
+{{if .Previous}}<a href="{{.Previous}}">&lsaquo; {{.Text.Previous}}</a> <a href="{{.Today}}">{{.Text.Today}}</a> <a href="{{.Next}}">{{.Text.Next}} &rsaquo;</a>{{end}}
+{{range .Views}}<a href="{{.Link}}"{{if .Current}} class="current"{{end}}>{{.Label}}</a>
+{{end}}</nav>
+{{if eq .View "agenda"}}{{range .Days}}<h2>{{.Date}}</h2>
+<ul>
+{{range .Events}}<li><span class="time">{{.Time}}</span> <span{{if .Busy}} class="busy"{{end}}>{{.Summary}}</span>
+{{if .Location}}<div class="details">{{.Location}}</div>{{end}}{{if .Description}}<div class="details">{{.Description}}</div>{{end}}</li>
+{{end}}</ul>
+{{else}}<p>{{.Text.NoEvents}}</p>
+{{end}}{{else}}<table class="{{.View}}">
+<tr>{{range .Weekdays}}<th>{{.}}</th>{{end}}</tr>
+{{range .Weeks}}<tr>{{range .}}<td class="{{if not .InRange}}outside{{end}}{{if .Today}} today{{end}}">
+<div class="day">{{.Date}}</div>
+{{range .Events}}<div class="event{{if .Busy}} busy{{end}}" title="{{.Summary}}{{if .Location}} – {{.Location}}{{end}}"><span class="time">{{.Time}}</span>{{.Summary}}</div>
+{{end}}</td>{{end}}</tr>
+{{end}}</table>
+{{end}}</body>
+</html>
+`))
+
+type publicCalendarViewLink struct {
+	Label   string
+	Link    string
+	Current bool
+}
+
+// link returns the query of this page for another view or date
+func (v *publicCalendarView) link(view string, date time.Time) string {
+	q := url.Values{}
+	for key, values := range v.query {
+		q[key] = values
+	}
+	q.Set("view", view)
+	if !date.IsZero() {
+		q.Set("date", date.Format("2006-01-02"))
+	}
+	return "?" + q.Encode()
+}
+
+// weekStart returns the first day of the week containing day
+func (v *publicCalendarView) weekStart(day time.Time) time.Time {
+	return day.AddDate(0, 0, -((int(day.Weekday()) - int(v.locale.firstDay) + 7) % 7))
+}
+
+// render builds the page of the view from the occurrences of a feed
+func (v *publicCalendarView) render(feed *calendarusecase.PublicFeed, now time.Time) (string, error) {
+	l := v.locale
+	color := feed.Calendar.Color
+	if color == "" {
+		color = "#3788d8"
+	}
+	data := map[string]any{
+		"Lang":        l.code,
+		"Name":        feed.Calendar.Name,
+		"Description": feed.Calendar.Description,
+		"Color":       template.CSS(color),
+		"Theme":       v.theme,
+		"View":        v.view,
+		"Text": map[string]string{
+			"Previous": l.previous, "Next": l.next, "Today": l.today, "NoEvents": l.noEvents,
+		},
+	}
+	var views []publicCalendarViewLink
+	for _, view := range []struct{ name, label string }{{publicViewAgenda, l.agenda}, {publicViewMonth, l.month}, {publicViewWeek, l.week}} {
+		views = append(views, publicCalendarViewLink{Label: view.label, Link: v.link(view.name, v.date), Current: view.name == v.view})
+	}
+	data["Views"] = views
+
+	if v.view == publicViewAgenda {
+		from, to := feed.ListingWindow(now)
+		if v.date.After(from) {
+			from = v.date
+		}
+		data["Title"] = l.format(l.longDate, from)
+		var events []calendarusecase.PublicEvent
+		if from.Before(to) {
+			events = feed.Events(from, to)
+		}
+		data["Days"] = agendaDays(events, v.loc, l)
+		return executeTemplate(publicCalendarViewTemplate, data)
+	}
+
+	// Month and week views lay the days out in a grid of weeks
+	var first, last, prev, next time.Time
+	if v.view == publicViewMonth {
+		monthStart := time.Date(v.date.Year(), v.date.Month(), 1, 0, 0, 0, 0, v.loc)
+		first = v.weekStart(monthStart)
+		last = v.weekStart(monthStart.AddDate(0, 1, -1)).AddDate(0, 0, 7)
+		prev, next = monthStart.AddDate(0, -1, 0), monthStart.AddDate(0, 1, 0)
+		data["Title"] = l.format(l.monthYear, monthStart)
+	} else {
+		first = v.weekStart(v.date)
+		last = first.AddDate(0, 0, 7)
+		prev, next = first.AddDate(0, 0, -7), last
+		data["Title"] = l.format(l.dayMonth, first) + " – " + l.format(l.dayMonth, last.AddDate(0, 0, -1)) + " " + fmt.Sprint(last.AddDate(0, 0, -1).Year())
+	}
+	data["Previous"] = v.link(v.view, prev)
+	data["Next"] = v.link(v.view, next)
+	data["Today"] = v.link(v.view, v.today)
+
+	var weekdays []string
+	for i := 0; i < 7; i++ {
+		day := (int(l.firstDay) + i) % 7
+		if v.view == publicViewWeek {
+			weekdays = append(weekdays, l.shortDays[day]+" "+l.format(l.dayMonth, first.AddDate(0, 0, i)))
+		} else {
+			weekdays = append(weekdays, l.shortDays[day])
+		}
+	}
+	data["Weekdays"] = weekdays
+
+	var cells []*publicCalendarDay
+	index := make(map[string]*publicCalendarDay)
+	for day := first; day.Before(last); day = day.AddDate(0, 0, 1) {
+		cell := &publicCalendarDay{
+			Date:    fmt.Sprint(day.Day()),
+			InRange: v.view == publicViewWeek || day.Month() == v.date.Month(),
+			Today:   day.Equal(v.today),
+		}
+		cells = append(cells, cell)
+		index[day.Format("2006-01-02")] = cell
+	}
+
+	// All-day dates are floating and may start up to a day before the
+	// first cell in UTC; the index drops what falls outside the grid
+	from, to := feed.ClampToWindow(first.AddDate(0, 0, -1), last.AddDate(0, 0, 1), now)
+	if from.Before(to) {
+		for _, e := range feed.Events(from, to) {
+			for _, day := range occurrenceDays(e, v.loc) {
+				if cell := index[day.Format("2006-01-02")]; cell != nil {
+					cell.Events = append(cell.Events, gridEvent(e, day, v.loc, l))
+				}
+			}
+		}
+	}
+
+	var weeks [][]*publicCalendarDay
+	for i := 0; i < len(cells); i += 7 {
+		weeks = append(weeks, cells[i:i+7])
+	}
+	data["Weeks"] = weeks
+	return executeTemplate(publicCalendarViewTemplate, data)
+}
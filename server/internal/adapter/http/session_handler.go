@@ -0,0 +1,128 @@
+package http
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/jherrma/caldav-server/internal/adapter/http/dto"
+	authusecase "github.com/jherrma/caldav-server/internal/usecase/auth"
+)
+
+type SessionHandler struct {
+	listUC      *authusecase.ListSessionsUseCase
+	revokeUC    *authusecase.RevokeSessionUseCase
+	revokeAllUC *authusecase.RevokeAllSessionsUseCase
+}
+
+func NewSessionHandler(
+	listUC *authusecase.ListSessionsUseCase,
+	revokeUC *authusecase.RevokeSessionUseCase,
+	revokeAllUC *authusecase.RevokeAllSessionsUseCase,
+) *SessionHandler {
+	return &SessionHandler{
+		listUC:      listUC,
+		revokeUC:    revokeUC,
+		revokeAllUC: revokeAllUC,
+	}
+}
+
+// List godoc
+// @Summary      List active sessions
+// @Description  List the devices the current user is logged in on
+// @Tags         Users
+// @Produce      json
+// @Success      200  {object}  dto.ListSessionsResponse
+// @Failure      401  {object}  ErrorResponseBody
+// @Failure      500  {object}  ErrorResponseBody
+// @Security     BearerAuth
+// @Router       /users/me/sessions [get]
+func (h *SessionHandler) List(c fiber.Ctx) error {
+	userID, err := GetUserIDFromContext(c)
+	if err != nil {
+		return UnauthorizedResponse(c, "Unauthorized")
+	}
+
+	sessions, err := h.listUC.Execute(c.Context(), userID)
+	if err != nil {
+		return ErrorResponse(c, fiber.StatusInternalServerError, "Failed to list sessions")
+	}
+
+	res := dto.ListSessionsResponse{
+		Sessions: make([]dto.SessionResponse, len(sessions)),
+	}
+	for i, s := range sessions {
+		res.Sessions[i] = dto.SessionResponse{
+			ID:         s.ID,
+			DeviceName: s.DeviceName,
+			UserAgent:  s.UserAgent,
+			IP:         s.IP,
+			CreatedAt:  s.CreatedAt.UTC().Format(time.RFC3339),
+			LastUsedAt: s.LastUsedAt.UTC().Format(time.RFC3339),
+			ExpiresAt:  s.ExpiresAt.UTC().Format(time.RFC3339),
+		}
+	}
+
+	return SuccessResponse(c, res)
+}
+
+// Revoke godoc
+// @Summary      Revoke a session
+// @Description  Log out a single device by revoking its refresh tokens
+// @Tags         Users
+// @Param        id   path  string  true  "Session ID"
+// @Success      204
+// @Failure      401  {object}  ErrorResponseBody
+// @Failure      404  {object}  ErrorResponseBody
+// @Failure      500  {object}  ErrorResponseBody
+// @Security     BearerAuth
+// @Router       /users/me/sessions/{id} [delete]
+func (h *SessionHandler) Revoke(c fiber.Ctx) error {
+	userID, err := GetUserIDFromContext(c)
+	if err != nil {
+		return UnauthorizedResponse(c, "Unauthorized")
+	}
+
+	if err := h.revokeUC.Execute(c.Context(), userID, c.Params("id"), c.IP(), c.Get("User-Agent")); err != nil {
+		if errors.Is(err, authusecase.ErrSessionNotFound) {
+			return ErrorResponse(c, fiber.StatusNotFound, err.Error())
+		}
+		return ErrorResponse(c, fiber.StatusInternalServerError, "Failed to revoke session")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// RevokeAll godoc
+// @Summary      Revoke all sessions
+// @Description  Log out all devices. If the body contains the caller's refresh token, that session is kept.
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Param        request  body      dto.RevokeSessionsRequest  false  "Session to keep"
+// @Success      200      {object}  dto.RevokeSessionsResponse
+// @Failure      400      {object}  ErrorResponseBody
+// @Failure      401      {object}  ErrorResponseBody
+// @Failure      500      {object}  ErrorResponseBody
+// @Security     BearerAuth
+// @Router       /users/me/sessions [delete]
+func (h *SessionHandler) RevokeAll(c fiber.Ctx) error {
+	userID, err := GetUserIDFromContext(c)
+	if err != nil {
+		return UnauthorizedResponse(c, "Unauthorized")
+	}
+
+	var req dto.RevokeSessionsRequest
+	if len(c.Body()) > 0 {
+		if err := c.Bind().JSON(&req); err != nil {
+			return BadRequestResponse(c, "Invalid request body")
+		}
+	}
+
+	n, err := h.revokeAllUC.Execute(c.Context(), userID, req.RefreshToken, c.IP(), c.Get("User-Agent"))
+	if err != nil {
+		return ErrorResponse(c, fiber.StatusInternalServerError, "Failed to revoke sessions")
+	}
+
+	return SuccessResponse(c, dto.RevokeSessionsResponse{Revoked: n})
+}
@@ -0,0 +1,201 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/jherrma/caldav-server/internal/adapter/http/dto"
+	"github.com/jherrma/caldav-server/internal/adapter/repository"
+	"github.com/jherrma/caldav-server/internal/domain/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func loginForTest(t *testing.T, app *fiber.App, email, password, userAgent string) dto.LoginResponse {
+	t.Helper()
+	body, _ := json.Marshal(map[string]string{"email": email, "password": password})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	var respData struct {
+		Data dto.LoginResponse `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&respData))
+	return respData.Data
+}
+
+func refreshForTest(t *testing.T, app *fiber.App, refreshToken string) (int, map[string]interface{}) {
+	t.Helper()
+	body, _ := json.Marshal(map[string]string{"refresh_token": refreshToken})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/refresh", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+
+	var respData struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&respData)
+	return resp.StatusCode, respData.Data
+}
+
+func listSessionsForTest(t *testing.T, app *fiber.App, accessToken string) []dto.SessionResponse {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/users/me/sessions", nil)
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	var respData struct {
+		Data dto.ListSessionsResponse `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&respData))
+	return respData.Data.Sessions
+}
+
+func TestSessionHandler(t *testing.T) {
+	app, db, _ := setupTestApp(t)
+	userRepo := repository.NewUserRepository(db.DB())
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("Pass123!"), bcrypt.DefaultCost)
+	require.NoError(t, err)
+	require.NoError(t, userRepo.Create(context.Background(), &user.User{
+		Email:         "sessions@example.com",
+		Username:      "sessions",
+		PasswordHash:  string(hashedPassword),
+		IsActive:      true,
+		EmailVerified: true,
+		UUID:          "sessions-uuid",
+	}))
+
+	laptop := loginForTest(t, app, "sessions@example.com", "Pass123!",
+		"Mozilla/5.0 (X11; Linux x86_64; rv:128.0) Gecko/20100101 Firefox/128.0")
+	phone := loginForTest(t, app, "sessions@example.com", "Pass123!",
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) Version/17.5 Mobile/15E148 Safari/604.1")
+
+	t.Run("List", func(t *testing.T) {
+		sessions := listSessionsForTest(t, app, laptop.AccessToken)
+		require.Len(t, sessions, 2)
+
+		names := []string{sessions[0].DeviceName, sessions[1].DeviceName}
+		assert.ElementsMatch(t, []string{"Firefox on Linux", "Safari on iOS"}, names)
+		for _, s := range sessions {
+			assert.NotEmpty(t, s.ID)
+			assert.NotEmpty(t, s.CreatedAt)
+			assert.NotEmpty(t, s.LastUsedAt)
+		}
+	})
+
+	t.Run("Refresh rotates within the same session", func(t *testing.T) {
+		before := listSessionsForTest(t, app, laptop.AccessToken)
+
+		status, data := refreshForTest(t, app, laptop.RefreshToken)
+		require.Equal(t, fiber.StatusOK, status)
+		rotated, _ := data["refresh_token"].(string)
+		require.NotEmpty(t, rotated)
+		assert.NotEqual(t, laptop.RefreshToken, rotated)
+
+		after := listSessionsForTest(t, app, laptop.AccessToken)
+		require.Len(t, after, 2)
+		beforeIDs := []string{before[0].ID, before[1].ID}
+		afterIDs := []string{after[0].ID, after[1].ID}
+		assert.ElementsMatch(t, beforeIDs, afterIDs)
+
+		laptop.RefreshToken = rotated
+	})
+
+	t.Run("Reusing a rotated token revokes the session", func(t *testing.T) {
+		status, data := refreshForTest(t, app, laptop.RefreshToken)
+		require.Equal(t, fiber.StatusOK, status)
+		latest := data["refresh_token"].(string)
+
+		// Replay the token we just rotated away from.
+		status, _ = refreshForTest(t, app, laptop.RefreshToken)
+		assert.Equal(t, fiber.StatusUnauthorized, status)
+
+		// The legitimate successor is now dead too.
+		status, _ = refreshForTest(t, app, latest)
+		assert.Equal(t, fiber.StatusUnauthorized, status)
+
+		sessions := listSessionsForTest(t, app, phone.AccessToken)
+		require.Len(t, sessions, 1)
+		assert.Equal(t, "Safari on iOS", sessions[0].DeviceName)
+	})
+
+	t.Run("Revoke single session", func(t *testing.T) {
+		sessions := listSessionsForTest(t, app, phone.AccessToken)
+		require.Len(t, sessions, 1)
+
+		req := httptest.NewRequest(http.MethodDelete, "/api/v1/users/me/sessions/"+sessions[0].ID, nil)
+		req.Header.Set("Authorization", "Bearer "+phone.AccessToken)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusNoContent, resp.StatusCode)
+
+		status, _ := refreshForTest(t, app, phone.RefreshToken)
+		assert.Equal(t, fiber.StatusUnauthorized, status)
+
+		// Second revoke of the same session is a 404.
+		req = httptest.NewRequest(http.MethodDelete, "/api/v1/users/me/sessions/"+sessions[0].ID, nil)
+		req.Header.Set("Authorization", "Bearer "+phone.AccessToken)
+		resp, err = app.Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("Revoke all except current", func(t *testing.T) {
+		current := loginForTest(t, app, "sessions@example.com", "Pass123!", "curl/8.5.0")
+		other := loginForTest(t, app, "sessions@example.com", "Pass123!", "curl/8.5.0")
+
+		body, _ := json.Marshal(map[string]string{"refresh_token": current.RefreshToken})
+		req := httptest.NewRequest(http.MethodDelete, "/api/v1/users/me/sessions", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+current.AccessToken)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		status, _ := refreshForTest(t, app, other.RefreshToken)
+		assert.Equal(t, fiber.StatusUnauthorized, status)
+		status, _ = refreshForTest(t, app, current.RefreshToken)
+		assert.Equal(t, fiber.StatusOK, status)
+	})
+
+	t.Run("Cannot revoke another user's session", func(t *testing.T) {
+		other := &user.User{
+			Email:         "other-sessions@example.com",
+			Username:      "othersessions",
+			PasswordHash:  string(hashedPassword),
+			IsActive:      true,
+			EmailVerified: true,
+			UUID:          "other-sessions-uuid",
+		}
+		require.NoError(t, userRepo.Create(context.Background(), other))
+		victim := loginForTest(t, app, "other-sessions@example.com", "Pass123!", "curl/8.5.0")
+		victimSessions := listSessionsForTest(t, app, victim.AccessToken)
+		require.Len(t, victimSessions, 1)
+
+		req := httptest.NewRequest(http.MethodDelete, "/api/v1/users/me/sessions/"+victimSessions[0].ID, nil)
+		req.Header.Set("Authorization", "Bearer "+laptop.AccessToken)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+
+		status, _ := refreshForTest(t, app, victim.RefreshToken)
+		assert.Equal(t, fiber.StatusOK, status)
+	})
+}
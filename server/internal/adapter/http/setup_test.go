@@ -17,11 +17,19 @@ import (
 	"github.com/jherrma/caldav-server/internal/config"
 	"github.com/jherrma/caldav-server/internal/infrastructure/database"
 	"github.com/jherrma/caldav-server/internal/infrastructure/email"
+	"github.com/jherrma/caldav-server/internal/infrastructure/webcal"
 	addressbookusecase "github.com/jherrma/caldav-server/internal/usecase/addressbook"
 	"github.com/jherrma/caldav-server/internal/usecase/apppassword"
 	authusecase "github.com/jherrma/caldav-server/internal/usecase/auth"
+	"github.com/jherrma/caldav-server/internal/usecase/birthday"
 	calendarusecase "github.com/jherrma/caldav-server/internal/usecase/calendar"
+	"github.com/jherrma/caldav-server/internal/usecase/directory"
+	"github.com/jherrma/caldav-server/internal/usecase/importexport"
+	oauthserverusecase "github.com/jherrma/caldav-server/internal/usecase/oauthserver"
+	sharingusecase "github.com/jherrma/caldav-server/internal/usecase/sharing"
+	"github.com/jherrma/caldav-server/internal/usecase/subscription"
 	userusecase "github.com/jherrma/caldav-server/internal/usecase/user"
+	"github.com/jherrma/caldav-server/internal/usecase/usergroup"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/oauth2"
 )
@@ -41,7 +49,37 @@ func setupTestApp(t *testing.T) (*fiber.App, database.Database, *config.Config)
 			RefreshExpiry: 24 * time.Hour,
 			ResetExpiry:   15 * time.Minute,
 		},
-		SMTP: config.SMTPConfig{}, // Empty config to skip sending emails
+		SMTP:    config.SMTPConfig{}, // Empty config to skip sending emails
+		BaseURL: "http://localhost:8080",
+		OAuthServer: config.OAuthServerConfig{
+			Enabled:            true,
+			CodeExpiry:         10 * time.Minute,
+			AccessTokenExpiry:  time.Hour,
+			RefreshTokenExpiry: 720 * time.Hour,
+		},
+		Lockout: config.LockoutConfig{
+			Enabled:      true,
+			FreeAttempts: 3,
+			BaseDelay:    time.Minute,
+			MaxAttempts:  5,
+			Duration:     15 * time.Minute,
+			ResetAfter:   time.Hour,
+		},
+		Directory: config.DirectoryConfig{
+			Enabled:      true,
+			Name:         "Directory",
+			Organization: "Example Corp",
+		},
+		Contacts: config.ContactsConfig{
+			DefaultPhoneRegion: "DE",
+		},
+		Subscriptions: config.SubscriptionsConfig{
+			DefaultRefresh:       6 * time.Hour,
+			MinRefresh:           15 * time.Minute,
+			Timeout:              5 * time.Second,
+			MaxFeedBytes:         1 << 20,
+			AllowPrivateNetworks: true, // Feeds are served by httptest on loopback
+		},
 	}
 
 	db, err := database.New(cfg)
@@ -57,8 +95,13 @@ func setupTestApp(t *testing.T) (*fiber.App, database.Database, *config.Config)
 	tokenRepo := repository.NewRefreshTokenRepository(db.DB())
 	resetRepo := repository.NewGORMPasswordResetRepository(db.DB())
 	calendarRepo := repository.NewCalendarRepository(db.DB())
-	addressBookRepo := repository.NewAddressBookRepository(db.DB())
+	addressBookRepo := repository.NewAddressBookRepository(db.DB(), repository.WithPhoneRegion(cfg.Contacts.DefaultPhoneRegion))
 	appPwdRepo := repository.NewAppPasswordRepository(db.DB())
+	throttleRepo := repository.NewLoginThrottleRepository(db.DB())
+	oauthClientRepo := repository.NewOAuthClientRepository(db.DB())
+	oauthCodeRepo := repository.NewOAuthAuthorizationCodeRepository(db.DB())
+	oauthTokenRepo := repository.NewOAuthTokenRepository(db.DB())
+	oauthConsentRepo := repository.NewOAuthConsentRepository(db.DB())
 
 	// Services
 	emailService := email.NewEmailService(cfg.SMTP)
@@ -74,15 +117,21 @@ func setupTestApp(t *testing.T) (*fiber.App, database.Database, *config.Config)
 	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
 	securityLogger := logging.NewSecurityLogger(logger)
 
+	lockoutService := authusecase.NewLockoutService(throttleRepo, userRepo, emailService, cfg, securityLogger)
+
 	// Auth Use Cases
 	registerUC := authusecase.NewRegisterUseCase(userRepo, calendarRepo, addressBookRepo, emailService, cfg)
 	verifyUC := authusecase.NewVerifyUseCase(userRepo)
-	loginUC := authusecase.NewLoginUseCase(userRepo, tokenRepo, jwtManager, cfg, securityLogger)
-	refreshUC := authusecase.NewRefreshUseCase(tokenRepo, jwtManager)
+	loginUC := authusecase.NewLoginUseCase(userRepo, tokenRepo, jwtManager, lockoutService, cfg, securityLogger)
+	refreshUC := authusecase.NewRefreshUseCase(tokenRepo, jwtManager, cfg, securityLogger)
 	logoutUC := authusecase.NewLogoutUseCase(tokenRepo, jwtManager)
 	forgotUC := authusecase.NewForgotPasswordUseCase(userRepo, resetRepo, emailService, cfg.JWT.ResetExpiry)
 	resetUC := authusecase.NewResetPasswordUseCase(userRepo, resetRepo, tokenRepo)
 	changePasswordUC := authusecase.NewChangePasswordUseCase(userRepo, tokenRepo, jwtManager, securityLogger)
+	listSessionsUC := authusecase.NewListSessionsUseCase(tokenRepo)
+	revokeSessionUC := authusecase.NewRevokeSessionUseCase(tokenRepo, securityLogger)
+	revokeAllSessionsUC := authusecase.NewRevokeAllSessionsUseCase(tokenRepo, jwtManager, securityLogger)
+	unlockAccountUC := authusecase.NewUnlockAccountUseCase(userRepo, throttleRepo, securityLogger)
 
 	// OAuth Use Cases
 	oauthInitiateUC := authusecase.NewInitiateOAuthUseCase(mockProviderManager)
@@ -104,10 +153,31 @@ func setupTestApp(t *testing.T) (*fiber.App, database.Database, *config.Config)
 	calendarCreateUC := calendarusecase.NewCreateCalendarUseCase(calendarRepo)
 	calShareRepo := repository.NewCalendarShareRepository(db.DB())
 	calendarListUC := calendarusecase.NewListCalendarsUseCase(calendarRepo, calShareRepo)
+	groupRepo := repository.NewGroupRepository(db.DB())
+	notificationRepo := repository.NewShareNotificationRepository(db.DB())
+	shareNotifier := sharingusecase.NewNotifier(notificationRepo, userRepo, emailService, cfg.BaseURL)
+	calShareHandler := NewCalendarShareHandler(
+		sharingusecase.NewCreateCalendarShareUseCase(calShareRepo, calendarRepo, userRepo, groupRepo, shareNotifier),
+		sharingusecase.NewListCalendarSharesUseCase(calShareRepo, calendarRepo),
+		sharingusecase.NewUpdateCalendarShareUseCase(calShareRepo, calendarRepo),
+		sharingusecase.NewRevokeCalendarShareUseCase(calShareRepo, calendarRepo, shareNotifier),
+	)
+	invitationHandler := NewInvitationHandler(sharingusecase.NewInvitationsUseCase(
+		calShareRepo, repository.NewAddressBookShareRepository(db.DB()), calendarRepo, addressBookRepo, userRepo, shareNotifier,
+	))
+	calendarProxyHandler := NewCalendarProxyHandler(sharingusecase.NewCalendarProxiesUseCase(repository.NewCalendarProxyRepository(db.DB()), userRepo))
 	calendarGetUC := calendarusecase.NewGetCalendarUseCase(calendarRepo)
-	calendarUpdateUC := calendarusecase.NewUpdateCalendarUseCase(calendarRepo)
+	calendarUpdateUC := calendarusecase.NewUpdateCalendarUseCase(calendarRepo, calShareRepo)
 	calendarDeleteUC := calendarusecase.NewDeleteCalendarUseCase(calendarRepo)
 	calendarExportUC := calendarusecase.NewExportCalendarUseCase(calendarRepo)
+	calendarExportDataUC := calendarusecase.NewExportCalendarDataUseCase(calendarRepo)
+	calendarPublicHandler := NewCalendarPublicHandler(
+		calendarusecase.NewEnablePublicUseCase(calendarRepo, cfg.BaseURL),
+		calendarusecase.NewGetPublicStatusUseCase(calendarRepo, cfg.BaseURL),
+		calendarusecase.NewRegenerateTokenUseCase(calendarRepo, cfg.BaseURL),
+		calendarusecase.NewUpdatePublicFeedOptionsUseCase(calendarRepo, cfg.BaseURL),
+	)
+	publicCalendarHandler := NewPublicCalendarHandler(calendarusecase.NewPublicFeedUseCase(calendarRepo, lockoutService))
 
 	// Address Book Use Cases
 	abCreateUC := addressbookusecase.NewCreateUseCase(addressBookRepo)
@@ -116,6 +186,7 @@ func setupTestApp(t *testing.T) (*fiber.App, database.Database, *config.Config)
 	abUpdateUC := addressbookusecase.NewUpdateUseCase(addressBookRepo)
 	abDeleteUC := addressbookusecase.NewDeleteUseCase(addressBookRepo)
 	abExportUC := addressbookusecase.NewExportUseCase(addressBookRepo)
+	contactImportUC := importexport.NewContactImportUseCase(addressBookRepo)
 
 	// Handlers
 	authHandler := NewAuthHandler(
@@ -139,6 +210,14 @@ func setupTestApp(t *testing.T) (*fiber.App, database.Database, *config.Config)
 		appPwdRepo,
 	)
 
+	sessionHandler := NewSessionHandler(listSessionsUC, revokeSessionUC, revokeAllSessionsUC)
+	directoryService := directory.NewService(repository.NewDirectoryRepository(db.DB()), userRepo, cfg)
+	adminHandler := NewAdminHandler(unlockAccountUC, directoryService)
+	directoryHandler := NewDirectoryHandler(directoryService)
+	birthdayHandler := NewBirthdayHandler(birthday.NewService(repository.NewBirthdayRepository(db.DB()), addressBookRepo))
+	subscriptionHandler := NewSubscriptionHandler(subscription.NewService(repository.NewSubscriptionRepository(db.DB()), calendarRepo, webcal.NewFetcher(cfg.Subscriptions), cfg.Subscriptions))
+	groupHandler := NewGroupHandler(usergroup.NewService(groupRepo, userRepo))
+
 	calendarHandler := NewCalendarHandler(
 		calendarCreateUC,
 		calendarListUC,
@@ -146,6 +225,7 @@ func setupTestApp(t *testing.T) (*fiber.App, database.Database, *config.Config)
 		calendarUpdateUC,
 		calendarDeleteUC,
 		calendarExportUC,
+		calendarExportDataUC,
 	)
 
 	abHandler := NewAddressBookHandler(
@@ -156,6 +236,14 @@ func setupTestApp(t *testing.T) (*fiber.App, database.Database, *config.Config)
 		abDeleteUC,
 		abExportUC,
 	)
+	abPublicHandler := NewAddressBookPublicHandler(
+		addressbookusecase.NewEnablePublicUseCase(addressBookRepo, cfg.BaseURL),
+		addressbookusecase.NewGetPublicStatusUseCase(addressBookRepo, cfg.BaseURL),
+		addressbookusecase.NewRegenerateTokenUseCase(addressBookRepo, cfg.BaseURL),
+		addressbookusecase.NewUpdatePublicOptionsUseCase(addressBookRepo, cfg.BaseURL),
+	)
+	publicAddressBookHandler := NewPublicAddressBookHandler(addressbookusecase.NewPublicFeedUseCase(addressBookRepo))
+	importHandler := NewImportHandler(nil, contactImportUC)
 
 	appPwdHandler := NewAppPasswordHandler(
 		createAppPwdUC,
@@ -171,6 +259,21 @@ func setupTestApp(t *testing.T) (*fiber.App, database.Database, *config.Config)
 		oauthListUC,
 	)
 
+	oidcSigner, err := authadapter.NewOIDCSigner(context.Background(), repository.NewSystemSettingRepository(db.DB()))
+	require.NoError(t, err)
+
+	oauthServerHandler := NewOAuthServerHandler(
+		oauthserverusecase.NewRegisterClientUseCase(oauthClientRepo),
+		oauthserverusecase.NewListClientsUseCase(oauthClientRepo),
+		oauthserverusecase.NewDeleteClientUseCase(oauthClientRepo, oauthTokenRepo, oauthConsentRepo),
+		oauthserverusecase.NewAuthorizeUseCase(oauthClientRepo, oauthCodeRepo, oauthConsentRepo, cfg),
+		oauthserverusecase.NewTokenUseCase(oauthClientRepo, oauthCodeRepo, oauthTokenRepo, userRepo, oidcSigner, cfg),
+		oauthserverusecase.NewIntrospectUseCase(oauthClientRepo, oauthTokenRepo, userRepo),
+		oauthserverusecase.NewRevokeUseCase(oauthClientRepo, oauthTokenRepo),
+		oidcSigner,
+		cfg,
+	)
+
 	healthHandler := NewHealthHandler(db)
 
 	// Routes
@@ -187,32 +290,96 @@ func setupTestApp(t *testing.T) (*fiber.App, database.Database, *config.Config)
 	authGroup.Post("/reset-password", authHandler.ResetPassword)
 
 	// User Routes
-	userGroup := api.Group("/users", Authenticate(jwtManager, userRepo))
+	userGroup := api.Group("/users", Authenticate(jwtManager, userRepo, oauthTokenRepo))
 	userGroup.Get("/me", userHandler.GetProfile)
 	userGroup.Patch("/me", userHandler.UpdateProfile)
 	userGroup.Delete("/me", userHandler.DeleteAccount)
 	userGroup.Put("/me/password", userHandler.ChangePassword)
+	userGroup.Get("/me/sessions", sessionHandler.List)
+	userGroup.Delete("/me/sessions", sessionHandler.RevokeAll)
+	userGroup.Delete("/me/sessions/:id", sessionHandler.Revoke)
+
+	// Admin Routes
+	adminGroup := api.Group("/admin", Authenticate(jwtManager, userRepo, oauthTokenRepo), RequireAdmin())
+	adminGroup.Post("/users/:id/unlock", adminHandler.UnlockUser)
+	adminGroup.Patch("/users/:id/directory", adminHandler.SetDirectoryVisibility)
+
+	// Directory Routes
+	directoryGroup := api.Group("/directory", Authenticate(jwtManager, userRepo, oauthTokenRepo))
+	directoryGroup.Get("/", directoryHandler.List)
+	directoryGroup.Get("/:id", directoryHandler.Get)
+
+	// Birthday Calendar Routes
+	birthdayGroup := api.Group("/birthdays", Authenticate(jwtManager, userRepo, oauthTokenRepo))
+	birthdayGroup.Get("/", birthdayHandler.Get)
+	birthdayGroup.Patch("/", birthdayHandler.Update)
+	birthdayGroup.Get("/events", birthdayHandler.Events)
+
+	// Feed Subscription Routes
+	subscriptionGroup := api.Group("/subscriptions", Authenticate(jwtManager, userRepo, oauthTokenRepo))
+	subscriptionGroup.Post("/", subscriptionHandler.Create)
+	subscriptionGroup.Get("/", subscriptionHandler.List)
+	subscriptionGroup.Get("/:id", subscriptionHandler.Get)
+	subscriptionGroup.Patch("/:id", subscriptionHandler.Update)
+	subscriptionGroup.Delete("/:id", subscriptionHandler.Delete)
+	subscriptionGroup.Post("/:id/refresh", subscriptionHandler.Refresh)
+
+	// User Group Routes
+	userGroupGroup := api.Group("/groups", Authenticate(jwtManager, userRepo, oauthTokenRepo))
+	userGroupGroup.Get("/", groupHandler.List)
+	userGroupGroup.Post("/", groupHandler.Create)
+	userGroupGroup.Get("/:id", groupHandler.Get)
+	userGroupGroup.Patch("/:id", groupHandler.Update)
+	userGroupGroup.Delete("/:id", groupHandler.Delete)
+	userGroupGroup.Post("/:id/members", groupHandler.AddMember)
+	userGroupGroup.Post("/:id/accept", groupHandler.AcceptInvitation)
+	userGroupGroup.Delete("/:id/members/:user_id", groupHandler.RemoveMember)
 
 	// Calendar Routes
-	calendarGroup := api.Group("/calendars", Authenticate(jwtManager, userRepo))
+	calendarGroup := api.Group("/calendars", Authenticate(jwtManager, userRepo, oauthTokenRepo))
 	calendarGroup.Post("/", calendarHandler.Create)
 	calendarGroup.Get("/", calendarHandler.List)
 	calendarGroup.Get("/:id", calendarHandler.Get)
 	calendarGroup.Patch("/:id", calendarHandler.Update)
 	calendarGroup.Delete("/:id", calendarHandler.Delete)
 	calendarGroup.Get("/:id/export", calendarHandler.Export)
+	calendarGroup.Post("/:id/shares", calShareHandler.Create)
+	calendarGroup.Get("/:id/shares", calShareHandler.List)
+	calendarGroup.Delete("/:id/shares/:share_id", calShareHandler.Revoke)
+	calendarGroup.Post("/:id/public", calendarPublicHandler.EnablePublic)
+	calendarGroup.Get("/:id/public", calendarPublicHandler.GetPublicStatus)
+	calendarGroup.Post("/:id/public/regenerate", calendarPublicHandler.RegenerateToken)
+	calendarGroup.Put("/:id/public/options", calendarPublicHandler.UpdateOptions)
+
+	// Invitation Routes
+	invitationGroup := api.Group("/invitations", Authenticate(jwtManager, userRepo, oauthTokenRepo))
+	invitationGroup.Get("/", invitationHandler.List)
+	invitationGroup.Post("/:id/accept", invitationHandler.Accept)
+	invitationGroup.Post("/:id/decline", invitationHandler.Decline)
+
+	// Calendar Proxy Routes
+	calendarProxyGroup := api.Group("/calendar-proxies", Authenticate(jwtManager, userRepo, oauthTokenRepo))
+	calendarProxyGroup.Get("/", calendarProxyHandler.List)
+	calendarProxyGroup.Post("/", calendarProxyHandler.Grant)
+	calendarProxyGroup.Get("/delegations", calendarProxyHandler.ListDelegations)
+	calendarProxyGroup.Delete("/:id", calendarProxyHandler.Revoke)
 
 	// Address Book Routes
-	abGroup := api.Group("/addressbooks", Authenticate(jwtManager, userRepo))
+	abGroup := api.Group("/addressbooks", Authenticate(jwtManager, userRepo, oauthTokenRepo))
 	abGroup.Post("/", abHandler.Create)
 	abGroup.Get("/", abHandler.List)
 	abGroup.Get("/:id", abHandler.Get)
 	abGroup.Patch("/:id", abHandler.Update)
 	abGroup.Delete("/:id", abHandler.Delete)
 	abGroup.Get("/:id/export", abHandler.Export)
+	abGroup.Post("/:id/public", abPublicHandler.EnablePublic)
+	abGroup.Get("/:id/public", abPublicHandler.GetPublicStatus)
+	abGroup.Post("/:id/public/regenerate", abPublicHandler.RegenerateToken)
+	abGroup.Put("/:id/public/options", abPublicHandler.UpdateOptions)
+	abGroup.Post("/:id/import", importHandler.ImportContact)
 
 	// App Password Routes
-	appPwdGroup := api.Group("/app-passwords", Authenticate(jwtManager, userRepo))
+	appPwdGroup := api.Group("/app-passwords", Authenticate(jwtManager, userRepo, oauthTokenRepo))
 	appPwdGroup.Get("/", appPwdHandler.List)
 	appPwdGroup.Delete("/:id", appPwdHandler.Revoke)
 
@@ -220,13 +387,31 @@ func setupTestApp(t *testing.T) (*fiber.App, database.Database, *config.Config)
 	oauthGroup := api.Group("/auth/oauth")
 	oauthGroup.Get("/:provider/initiate", oauthHandler.Initiate)
 	oauthGroup.Get("/:provider/callback", oauthHandler.Callback)
-	oauthGroup.Post("/:provider/link", Authenticate(jwtManager, userRepo), oauthHandler.Link)
-	oauthGroup.Delete("/:provider", Authenticate(jwtManager, userRepo), oauthHandler.Unlink)
-	oauthGroup.Get("/providers", Authenticate(jwtManager, userRepo), oauthHandler.List)
+	oauthGroup.Post("/:provider/link", Authenticate(jwtManager, userRepo, oauthTokenRepo), oauthHandler.Link)
+	oauthGroup.Delete("/:provider", Authenticate(jwtManager, userRepo, oauthTokenRepo), oauthHandler.Unlink)
+	oauthGroup.Get("/providers", Authenticate(jwtManager, userRepo, oauthTokenRepo), oauthHandler.List)
+
+	// OAuth Server Routes
+	oauthServerGroup := api.Group("/oauth", Authenticate(jwtManager, userRepo, oauthTokenRepo))
+	oauthServerGroup.Post("/clients", oauthServerHandler.CreateClient)
+	oauthServerGroup.Get("/clients", oauthServerHandler.ListClients)
+	oauthServerGroup.Delete("/clients/:client_id", oauthServerHandler.DeleteClient)
+	oauthServerGroup.Get("/authorize", oauthServerHandler.GetConsent)
+	oauthServerGroup.Post("/authorize", oauthServerHandler.Decide)
+	app.Get("/.well-known/openid-configuration", oauthServerHandler.Discovery)
+	app.Get("/oauth/authorize", oauthServerHandler.AuthorizeRedirect)
+	app.Post("/oauth/token", oauthServerHandler.Token)
+	app.Post("/oauth/introspect", oauthServerHandler.Introspect)
+	app.Post("/oauth/revoke", oauthServerHandler.Revoke)
+	app.Get("/oauth/userinfo", Authenticate(jwtManager, userRepo, oauthTokenRepo), oauthServerHandler.UserInfo)
+	app.Get("/oauth/jwks", oauthServerHandler.JWKS)
 
 	// Health Routes
 	app.Get("/health", healthHandler.Liveness)
 	app.Get("/health/ready", healthHandler.Readiness)
+	app.Get("/public/calendar/:token", publicCalendarHandler.GetICalFeed)
+	app.Get("/public/calendar/:token/view", publicCalendarHandler.GetView)
+	app.Get("/public/addressbook/:token", publicAddressBookHandler.GetVCardFeed)
 
 	// Cleanup
 	t.Cleanup(func() {
@@ -244,7 +429,12 @@ type mockOAuthProvider struct {
 	name string
 }
 
-func (p *mockOAuthProvider) Name() string { return p.name }
+func (p *mockOAuthProvider) Name() string        { return p.name }
+func (p *mockOAuthProvider) DisplayName() string { return "Mock " + p.name }
+func (p *mockOAuthProvider) Icon() string        { return "" }
+func (p *mockOAuthProvider) Policy() authadapter.ProviderPolicy {
+	return authadapter.ProviderPolicy{AutoRegister: true}
+}
 func (p *mockOAuthProvider) AuthCodeURL(state string, opts ...oauth2.AuthCodeOption) string {
 	return "https://example.com/auth?state=" + state
 }
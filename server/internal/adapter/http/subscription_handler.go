@@ -0,0 +1,193 @@
+package http
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/jherrma/caldav-server/internal/usecase/subscription"
+)
+
+// SubscriptionHandler manages subscriptions to external iCalendar feeds
+type SubscriptionHandler struct {
+	subscriptions *subscription.Service
+}
+
+func NewSubscriptionHandler(subscriptions *subscription.Service) *SubscriptionHandler {
+	return &SubscriptionHandler{subscriptions: subscriptions}
+}
+
+// Create godoc
+// @Summary      Subscribe to a feed
+// @Description  Subscribe to an external iCalendar feed (http, https, webcal or webcals URL). The feed
+// @Description  is fetched right away and mirrored into a new read-only calendar; a failing first
+// @Description  fetch is reported in last_error. Without a name the feed's X-WR-CALNAME is used.
+// @Description  Returns 403 once the user has as many subscriptions as the server allows.
+// @Tags         Subscriptions
+// @Accept       json
+// @Produce      json
+// @Param        subscription  body      subscription.CreateInput  true  "Subscription"
+// @Success      201           {object}  subscription.Output
+// @Failure      400           {object}  ErrorResponseBody
+// @Failure      401           {object}  ErrorResponseBody
+// @Failure      403           {object}  ErrorResponseBody
+// @Failure      500           {object}  ErrorResponseBody
+// @Security     BearerAuth
+// @Router       /subscriptions [post]
+func (h *SubscriptionHandler) Create(c fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	var req subscription.CreateInput
+	if err := c.Bind().JSON(&req); err != nil {
+		return ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	output, err := h.subscriptions.Create(c.Context(), userID, req)
+	if err != nil {
+		return h.error(c, err, "Failed to create subscription")
+	}
+	return c.Status(fiber.StatusCreated).JSON(output)
+}
+
+// List godoc
+// @Summary      List subscriptions
+// @Description  List the user's feed subscriptions with the state of their last fetch.
+// @Tags         Subscriptions
+// @Produce      json
+// @Success      200  {array}   subscription.Output
+// @Failure      401  {object}  ErrorResponseBody
+// @Failure      500  {object}  ErrorResponseBody
+// @Security     BearerAuth
+// @Router       /subscriptions [get]
+func (h *SubscriptionHandler) List(c fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	output, err := h.subscriptions.List(c.Context(), userID)
+	if err != nil {
+		return ErrorResponse(c, fiber.StatusInternalServerError, "Failed to list subscriptions")
+	}
+	return c.JSON(output)
+}
+
+// Get godoc
+// @Summary      Get subscription
+// @Description  Get a feed subscription with the state of its last fetch.
+// @Tags         Subscriptions
+// @Produce      json
+// @Param        id   path      int  true  "Subscription ID"
+// @Success      200  {object}  subscription.Output
+// @Failure      401  {object}  ErrorResponseBody
+// @Failure      404  {object}  ErrorResponseBody
+// @Security     BearerAuth
+// @Router       /subscriptions/{id} [get]
+func (h *SubscriptionHandler) Get(c fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return ErrorResponse(c, fiber.StatusNotFound, "Subscription not found")
+	}
+
+	output, err := h.subscriptions.Get(c.Context(), userID, uint(id))
+	if err != nil {
+		return h.error(c, err, "Failed to load subscription")
+	}
+	return c.JSON(output)
+}
+
+// Update godoc
+// @Summary      Update subscription
+// @Description  Change the URL, refresh interval, name or color of a feed subscription. A new URL is
+// @Description  fetched right away.
+// @Tags         Subscriptions
+// @Accept       json
+// @Produce      json
+// @Param        id            path      int                       true  "Subscription ID"
+// @Param        subscription  body      subscription.UpdateInput  true  "Changes"
+// @Success      200           {object}  subscription.Output
+// @Failure      400           {object}  ErrorResponseBody
+// @Failure      401           {object}  ErrorResponseBody
+// @Failure      404           {object}  ErrorResponseBody
+// @Failure      500           {object}  ErrorResponseBody
+// @Security     BearerAuth
+// @Router       /subscriptions/{id} [patch]
+func (h *SubscriptionHandler) Update(c fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return ErrorResponse(c, fiber.StatusNotFound, "Subscription not found")
+	}
+
+	var req subscription.UpdateInput
+	if err := c.Bind().JSON(&req); err != nil {
+		return ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	output, err := h.subscriptions.Update(c.Context(), userID, uint(id), req)
+	if err != nil {
+		return h.error(c, err, "Failed to update subscription")
+	}
+	return c.JSON(output)
+}
+
+// Delete godoc
+// @Summary      Unsubscribe
+// @Description  End a feed subscription and delete its calendar.
+// @Tags         Subscriptions
+// @Param        id   path  int  true  "Subscription ID"
+// @Success      204
+// @Failure      401  {object}  ErrorResponseBody
+// @Failure      404  {object}  ErrorResponseBody
+// @Failure      500  {object}  ErrorResponseBody
+// @Security     BearerAuth
+// @Router       /subscriptions/{id} [delete]
+func (h *SubscriptionHandler) Delete(c fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return ErrorResponse(c, fiber.StatusNotFound, "Subscription not found")
+	}
+
+	if err := h.subscriptions.Delete(c.Context(), userID, uint(id)); err != nil {
+		return h.error(c, err, "Failed to delete subscription")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// Refresh godoc
+// @Summary      Refresh subscription
+// @Description  Fetch a subscribed feed now instead of waiting for its refresh interval. Fetch errors
+// @Description  are reported in last_error.
+// @Tags         Subscriptions
+// @Produce      json
+// @Param        id   path      int  true  "Subscription ID"
+// @Success      200  {object}  subscription.Output
+// @Failure      401  {object}  ErrorResponseBody
+// @Failure      404  {object}  ErrorResponseBody
+// @Failure      500  {object}  ErrorResponseBody
+// @Security     BearerAuth
+// @Router       /subscriptions/{id}/refresh [post]
+func (h *SubscriptionHandler) Refresh(c fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return ErrorResponse(c, fiber.StatusNotFound, "Subscription not found")
+	}
+
+	output, err := h.subscriptions.Refresh(c.Context(), userID, uint(id))
+	if err != nil {
+		return h.error(c, err, "Failed to refresh subscription")
+	}
+	return c.JSON(output)
+}
+
+func (h *SubscriptionHandler) error(c fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, subscription.ErrNotFound):
+		return ErrorResponse(c, fiber.StatusNotFound, "Subscription not found")
+	case errors.Is(err, subscription.ErrInvalidSubscription):
+		return ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	case errors.Is(err, subscription.ErrLimitReached):
+		return ErrorResponse(c, fiber.StatusForbidden, err.Error())
+	}
+	return ErrorResponse(c, fiber.StatusInternalServerError, message)
+}
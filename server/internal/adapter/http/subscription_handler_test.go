@@ -0,0 +1,207 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/jherrma/caldav-server/internal/adapter/repository"
+	"github.com/jherrma/caldav-server/internal/infrastructure/webcal"
+	"github.com/jherrma/caldav-server/internal/usecase/subscription"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscriptionHandler(t *testing.T) {
+	app, db, cfg := setupTestApp(t)
+	ctx := context.Background()
+
+	// The feed server answers conditional requests with 304 while the feed
+	// is unchanged
+	var mu sync.Mutex
+	feed, version, status, notModified := "", 0, http.StatusOK, 0
+	setFeed := func(events string) {
+		mu.Lock()
+		defer mu.Unlock()
+		version++
+		feed = "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//Feed//EN\r\nX-WR-CALNAME:Holidays\r\n" + events + "END:VCALENDAR\r\n"
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+		etag := `"` + strconv.Itoa(version) + `"`
+		if r.Header.Get("If-None-Match") == etag {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Content-Type", "text/calendar")
+		w.Write([]byte(feed))
+	}))
+	defer srv.Close()
+
+	const newYear = "BEGIN:VEVENT\r\nUID:new-year\r\nDTSTAMP:20240101T000000Z\r\nDTSTART;VALUE=DATE:20250101\r\nSUMMARY:New Year\r\nEND:VEVENT\r\n"
+	const labourDay = "BEGIN:VEVENT\r\nUID:labour-day\r\nDTSTAMP:20240101T000000Z\r\nDTSTART;VALUE=DATE:20250501\r\nSUMMARY:Labour Day\r\nEND:VEVENT\r\n"
+	setFeed(newYear + labourDay)
+
+	body, _ := json.Marshal(map[string]string{"email": "subs@example.com", "password": "Password123!", "display_name": "Subs User"})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/register", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	login := loginForTest(t, app, "subs@example.com", "Password123!", "test")
+
+	do := func(method, target string, body any) *http.Response {
+		var data []byte
+		if body != nil {
+			data, _ = json.Marshal(body)
+		}
+		req := httptest.NewRequest(method, target, bytes.NewReader(data))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+login.AccessToken)
+		resp, err := app.Test(req, fiber.TestConfig{Timeout: 10 * time.Second})
+		require.NoError(t, err)
+		return resp
+	}
+	decode := func(resp *http.Response) subscription.Output {
+		var out subscription.Output
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+		return out
+	}
+	calendarRepo := repository.NewCalendarRepository(db.DB())
+	summaries := func(calendarID uint) map[string]string {
+		objects, err := calendarRepo.GetCalendarObjects(ctx, calendarID)
+		require.NoError(t, err)
+		result := make(map[string]string)
+		for _, obj := range objects {
+			result[obj.UID] = obj.Summary
+		}
+		return result
+	}
+
+	var sub subscription.Output
+	t.Run("Create", func(t *testing.T) {
+		resp := do(http.MethodPost, "/api/v1/subscriptions", map[string]any{"url": "webcal" + srv.URL[len("http"):] + "/holidays.ics"})
+		require.Equal(t, fiber.StatusCreated, resp.StatusCode)
+		sub = decode(resp)
+		assert.Equal(t, srv.URL+"/holidays.ics", sub.URL)
+		assert.Equal(t, "Holidays", sub.Name)
+		assert.Equal(t, 360, sub.RefreshMinutes)
+		assert.Equal(t, int64(2), sub.EventCount)
+		assert.Empty(t, sub.LastError)
+		require.NotNil(t, sub.LastSuccessAt)
+		assert.Equal(t, map[string]string{"new-year": "New Year", "labour-day": "Labour Day"}, summaries(sub.CalendarID))
+
+		cal, err := calendarRepo.GetByID(ctx, sub.CalendarID)
+		require.NoError(t, err)
+		assert.True(t, cal.ReadOnly)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		resp := do(http.MethodPost, "/api/v1/subscriptions", map[string]any{"url": "ftp://example.com/cal.ics"})
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+		resp = do(http.MethodPost, "/api/v1/subscriptions", map[string]any{"url": srv.URL, "refresh_minutes": 1})
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("Not Modified", func(t *testing.T) {
+		cal, err := calendarRepo.GetByID(ctx, sub.CalendarID)
+		require.NoError(t, err)
+
+		resp := do(http.MethodPost, "/api/v1/subscriptions/"+strconv.Itoa(int(sub.ID))+"/refresh", nil)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Empty(t, decode(resp).LastError)
+		assert.Equal(t, 1, notModified)
+
+		after, err := calendarRepo.GetByID(ctx, sub.CalendarID)
+		require.NoError(t, err)
+		assert.Equal(t, cal.SyncToken, after.SyncToken)
+	})
+
+	t.Run("Changed Feed", func(t *testing.T) {
+		setFeed(labourDay[:len(labourDay)-len("SUMMARY:Labour Day\r\nEND:VEVENT\r\n")] + "SUMMARY:May Day\r\nEND:VEVENT\r\n" +
+			"BEGIN:VEVENT\r\nUID:christmas\r\nDTSTAMP:20240101T000000Z\r\nDTSTART;VALUE=DATE:20251225\r\nSUMMARY:Christmas\r\nEND:VEVENT\r\n")
+
+		resp := do(http.MethodPost, "/api/v1/subscriptions/"+strconv.Itoa(int(sub.ID))+"/refresh", nil)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Equal(t, int64(2), decode(resp).EventCount)
+		assert.Equal(t, map[string]string{"labour-day": "May Day", "christmas": "Christmas"}, summaries(sub.CalendarID))
+	})
+
+	t.Run("Fetch Errors", func(t *testing.T) {
+		mu.Lock()
+		status = http.StatusInternalServerError
+		mu.Unlock()
+
+		resp := do(http.MethodPost, "/api/v1/subscriptions/"+strconv.Itoa(int(sub.ID))+"/refresh", nil)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		out := decode(resp)
+		assert.Contains(t, out.LastError, "HTTP 500")
+		assert.Equal(t, 1, out.FailureCount)
+		assert.Len(t, summaries(sub.CalendarID), 2)
+
+		mu.Lock()
+		status = http.StatusOK
+		mu.Unlock()
+	})
+
+	t.Run("Background Refresh", func(t *testing.T) {
+		subRepo := repository.NewSubscriptionRepository(db.DB())
+		service := subscription.NewService(subRepo, calendarRepo, webcal.NewFetcher(cfg.Subscriptions), cfg.Subscriptions)
+		n, err := service.RefreshDue(ctx)
+		require.NoError(t, err)
+		assert.Zero(t, n)
+
+		stored, err := subRepo.GetByID(ctx, sub.ID)
+		require.NoError(t, err)
+		stored.NextFetchAt = time.Now().Add(-time.Minute)
+		require.NoError(t, subRepo.Update(ctx, stored))
+
+		n, err = service.RefreshDue(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+
+		resp := do(http.MethodGet, "/api/v1/subscriptions/"+strconv.Itoa(int(sub.ID)), nil)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		out := decode(resp)
+		assert.Zero(t, out.FailureCount)
+		assert.True(t, out.NextFetchAt.After(time.Now().Add(5*time.Hour)))
+	})
+
+	t.Run("List and Update", func(t *testing.T) {
+		resp := do(http.MethodGet, "/api/v1/subscriptions", nil)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		var list []subscription.Output
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&list))
+		require.Len(t, list, 1)
+
+		resp = do(http.MethodPatch, "/api/v1/subscriptions/"+strconv.Itoa(int(sub.ID)), map[string]any{"name": "Public Holidays", "refresh_minutes": 60})
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		out := decode(resp)
+		assert.Equal(t, "Public Holidays", out.Name)
+		assert.Equal(t, 60, out.RefreshMinutes)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		resp := do(http.MethodDelete, "/api/v1/subscriptions/"+strconv.Itoa(int(sub.ID)), nil)
+		require.Equal(t, fiber.StatusNoContent, resp.StatusCode)
+
+		resp = do(http.MethodGet, "/api/v1/subscriptions/"+strconv.Itoa(int(sub.ID)), nil)
+		assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+		cal, _ := calendarRepo.GetByID(ctx, sub.CalendarID)
+		assert.Nil(t, cal)
+	})
+}
@@ -46,12 +46,20 @@ func (h *SystemHandler) AuthMethods(c fiber.Ctx) error {
 
 	if h.oauthManager != nil {
 		for _, name := range h.oauthManager.ListProviders() {
-			methods = append(methods, fiber.Map{
+			provider, err := h.oauthManager.GetProvider(name)
+			if err != nil {
+				continue
+			}
+			method := fiber.Map{
 				"id":   name,
 				"type": "oidc",
-				"name": providerDisplayName(name),
+				"name": provider.DisplayName(),
 				"url":  h.cfg.BaseURL + "/api/v1/auth/oauth/" + name,
-			})
+			}
+			if icon := provider.Icon(); icon != "" {
+				method["icon"] = icon
+			}
+			methods = append(methods, method)
 		}
 	}
 
@@ -59,14 +67,3 @@ func (h *SystemHandler) AuthMethods(c fiber.Ctx) error {
 		"methods": methods,
 	})
 }
-
-func providerDisplayName(name string) string {
-	switch name {
-	case "google":
-		return "Google"
-	case "microsoft":
-		return "Microsoft"
-	default:
-		return "SSO (" + name + ")"
-	}
-}
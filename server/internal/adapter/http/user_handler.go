@@ -75,8 +75,12 @@ func (h *UserHandler) GetProfile(c fiber.Ctx) error {
 		ID:            u.UUID,
 		Email:         u.Email,
 		DisplayName:   u.DisplayName,
+		Phone:         u.Phone,
+		JobTitle:      u.JobTitle,
+		Department:    u.Department,
 		IsActive:      u.IsActive,
 		EmailVerified: u.EmailVerified,
+		IsAdmin:       u.IsAdmin,
 		CreatedAt:     u.CreatedAt,
 		UpdatedAt:     u.UpdatedAt,
 		AuthMethods:   []string{"local"},
@@ -92,7 +96,7 @@ func (h *UserHandler) GetProfile(c fiber.Ctx) error {
 
 // UpdateProfile godoc
 // @Summary      Update user profile
-// @Description  Update current user's display name
+// @Description  Update current user's display name and the profile fields published in the directory
 // @Tags         Users
 // @Accept       json
 // @Produce      json
@@ -118,6 +122,9 @@ func (h *UserHandler) UpdateProfile(c fiber.Ctx) error {
 
 	usecaseReq := userusecase.UpdateProfileRequest{
 		DisplayName: req.DisplayName,
+		Phone:       req.Phone,
+		JobTitle:    req.JobTitle,
+		Department:  req.Department,
 	}
 
 	u, err := h.updateProfileUC.Execute(c.Context(), userUUID, usecaseReq)
@@ -125,7 +132,7 @@ func (h *UserHandler) UpdateProfile(c fiber.Ctx) error {
 		if errors.Is(err, userusecase.ErrEmailAlreadyExists) {
 			return ConflictResponse(c, err.Error())
 		}
-		if errors.Is(err, userusecase.ErrDisplayNameTooLong) {
+		if errors.Is(err, userusecase.ErrDisplayNameTooLong) || errors.Is(err, userusecase.ErrProfileFieldTooLong) {
 			return BadRequestResponse(c, err.Error())
 		}
 		if err.Error() == "user not found" {
@@ -142,8 +149,12 @@ func (h *UserHandler) UpdateProfile(c fiber.Ctx) error {
 		ID:            u.UUID,
 		Email:         u.Email,
 		DisplayName:   u.DisplayName,
+		Phone:         u.Phone,
+		JobTitle:      u.JobTitle,
+		Department:    u.Department,
 		IsActive:      u.IsActive,
 		EmailVerified: u.EmailVerified,
+		IsAdmin:       u.IsAdmin,
 		CreatedAt:     u.CreatedAt,
 		UpdatedAt:     u.UpdatedAt,
 		AuthMethods:   []string{"local"},
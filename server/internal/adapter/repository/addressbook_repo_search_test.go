@@ -9,6 +9,7 @@ import (
 	"github.com/jherrma/caldav-server/internal/adapter/repository"
 	"github.com/jherrma/caldav-server/internal/domain/addressbook"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
@@ -121,3 +122,132 @@ func TestSearchObjects(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Len(t, results, 0)
 }
+
+func setupSearchRepo(t *testing.T, region string) (*gorm.DB, addressbook.Repository, *addressbook.AddressBook) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&addressbook.AddressBook{}, &addressbook.AddressObject{}, &addressbook.ContactPhoto{}, &addressbook.SyncChangeLog{}))
+
+	repo := repository.NewAddressBookRepository(db, repository.WithPhoneRegion(region))
+	ab := &addressbook.AddressBook{Name: "Book", UserID: 1, UUID: uuid.New().String(), Path: "book", SyncToken: "1", CTag: "1"}
+	require.NoError(t, repo.Create(context.Background(), ab))
+	return db, repo, ab
+}
+
+func createSearchContact(t *testing.T, repo addressbook.Repository, ab *addressbook.AddressBook, uid, props string) *addressbook.AddressObject {
+	t.Helper()
+	obj := &addressbook.AddressObject{
+		UUID:          uuid.New().String(),
+		AddressBookID: ab.ID,
+		UID:           uid,
+		Path:          uid + ".vcf",
+		ETag:          "1",
+		VCardData:     "BEGIN:VCARD\r\nVERSION:3.0\r\nUID:" + uid + "\r\n" + props + "END:VCARD\r\n",
+		VCardVersion:  addressbook.VCardVersion3,
+	}
+	require.NoError(t, obj.PopulateDenormFieldsFromVCard())
+	require.NoError(t, repo.CreateObject(context.Background(), obj))
+	return obj
+}
+
+func TestSearchObjects_Normalized(t *testing.T) {
+	ctx := context.Background()
+	_, repo, ab := setupSearchRepo(t, "DE")
+
+	jose := createSearchContact(t, repo, ab, "jose", "FN:José Müller\r\nEMAIL:jose@work.example\r\nEMAIL:pepe@home.example\r\nTEL:030/1234567\r\nORG:Acme\r\n")
+	createSearchContact(t, repo, ab, "mia", "FN:Mia Acme\r\nEMAIL:mia@acme.example\r\nTEL:+44 20 7946 0000\r\n")
+
+	search := func(query string) []string {
+		t.Helper()
+		results, err := repo.SearchObjects(ctx, 1, query, nil, 10)
+		require.NoError(t, err)
+		uids := []string{}
+		for _, r := range results {
+			uids = append(uids, r.UID)
+		}
+		return uids
+	}
+
+	assert.Equal(t, []string{"jose"}, search("muller"))
+	assert.Equal(t, []string{"jose"}, search("JOSÉ"))
+	assert.Equal(t, []string{"jose"}, search("pepe@home"), "secondary email")
+	assert.Equal(t, []string{"jose"}, search("+49 30 1234"))
+	assert.Equal(t, []string{"jose"}, search("tel:030 1234567"))
+	assert.Equal(t, []string{"jose", "mia"}, search("acme"))
+	assert.Equal(t, []string{"jose"}, search("org:acme"))
+	assert.Equal(t, []string{"mia"}, search("email:acme"))
+	assert.Equal(t, []string{"mia"}, search("name:acme"))
+	assert.Empty(t, search("jose email:acme"))
+	assert.Empty(t, search("100%"))
+
+	// CardDAV text-matches use the same columns
+	query := func(filter addressbook.ObjectQueryFilter) []string {
+		t.Helper()
+		results, err := repo.QueryObjects(ctx, ab.ID, &addressbook.ObjectQuery{Filters: []addressbook.ObjectQueryFilter{filter}})
+		require.NoError(t, err)
+		uids := []string{}
+		for _, r := range results {
+			uids = append(uids, r.UID)
+		}
+		return uids
+	}
+	assert.Equal(t, []string{"jose"}, query(addressbook.ObjectQueryFilter{PropertyName: "TEL", MatchType: "equals", SearchText: "+49 30 1234567"}))
+	assert.Equal(t, []string{"jose"}, query(addressbook.ObjectQueryFilter{PropertyName: "TEL", MatchType: "contains", SearchText: "1234"}))
+	assert.Empty(t, query(addressbook.ObjectQueryFilter{PropertyName: "TEL", MatchType: "contains", SearchText: "none"}))
+	assert.Equal(t, []string{"jose"}, query(addressbook.ObjectQueryFilter{PropertyName: "EMAIL", MatchType: "equals", SearchText: "PEPE@home.example"}))
+	assert.Empty(t, query(addressbook.ObjectQueryFilter{PropertyName: "EMAIL", MatchType: "equals", SearchText: "pepe@home"}))
+	assert.Equal(t, []string{"jose"}, query(addressbook.ObjectQueryFilter{PropertyName: "EMAIL", MatchType: "starts-with", SearchText: "pepe"}))
+	assert.Equal(t, []string{"jose"}, query(addressbook.ObjectQueryFilter{PropertyName: "FN", MatchType: "starts-with", SearchText: "jose"}))
+	assert.Equal(t, []string{"jose"}, query(addressbook.ObjectQueryFilter{PropertyName: "EMAIL", MatchType: "ends-with", SearchText: "ACME.example", NegateCondition: true}))
+	assert.Equal(t, []string{"jose"}, query(addressbook.ObjectQueryFilter{PropertyName: "UID", MatchType: "equals", SearchText: jose.UID}))
+}
+
+func TestReindexSearch(t *testing.T) {
+	ctx := context.Background()
+	db, repo, ab := setupSearchRepo(t, "DE")
+	obj := createSearchContact(t, repo, ab, "anna", "FN:Anna\r\nTEL:0301234567\r\n")
+
+	// Nothing to do while the index is current
+	n, err := repo.ReindexSearch(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	// Rows written before the search columns existed
+	require.NoError(t, db.Model(obj).UpdateColumns(map[string]any{"search_phones": "", "search_key": ""}).Error)
+	n, err = repo.ReindexSearch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	results, err := repo.SearchObjects(ctx, 1, "+49301234567", nil, 10)
+	require.NoError(t, err)
+	assert.Len(t, results, 1)
+
+	// Another default region renormalizes national numbers
+	other := repository.NewAddressBookRepository(db, repository.WithPhoneRegion("AT"))
+	n, err = other.ReindexSearch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	results, err = other.SearchObjects(ctx, 1, "+43 301234567", nil, 10)
+	require.NoError(t, err)
+	assert.Len(t, results, 1)
+}
+
+func TestReindexSearch_BackfillsKind(t *testing.T) {
+	ctx := context.Background()
+	db, repo, ab := setupSearchRepo(t, "DE")
+	group := createSearchContact(t, repo, ab, "team", "FN:Team\r\nX-ADDRESSBOOKSERVER-KIND:group\r\nX-ADDRESSBOOKSERVER-MEMBER:urn:uuid:anna\r\n")
+
+	// A group stored before Kind existed, by the previous index version
+	require.NoError(t, db.Model(group).UpdateColumns(map[string]any{"kind": "", "search_key": "v1:DE"}).Error)
+	groups, err := repo.ListGroups(ctx, ab.ID)
+	require.NoError(t, err)
+	assert.Empty(t, groups)
+
+	n, err := repo.ReindexSearch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	groups, err = repo.ListGroups(ctx, ab.ID)
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	assert.Equal(t, "team", groups[0].UID)
+}
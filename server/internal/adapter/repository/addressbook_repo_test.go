@@ -1,15 +1,22 @@
 package repository_test
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
 	"errors"
+	"image"
+	"image/jpeg"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jherrma/caldav-server/internal/adapter/repository"
+	"github.com/jherrma/caldav-server/internal/config"
 	"github.com/jherrma/caldav-server/internal/domain/addressbook"
+	"github.com/jherrma/caldav-server/internal/infrastructure/imaging"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
@@ -80,3 +87,127 @@ func TestPhotoSeparation(t *testing.T) {
 	assert.Error(t, err)
 	assert.True(t, errors.Is(err, gorm.ErrRecordNotFound))
 }
+
+func TestPhotoProcessing(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&addressbook.AddressBook{}, &addressbook.AddressObject{}, &addressbook.ContactPhoto{}, &addressbook.ContactPhotoThumbnail{}, &addressbook.SyncChangeLog{}))
+
+	processor := imaging.NewPhotoProcessor(config.PhotoConfig{MaxDimension: 100, ThumbnailSizes: []int{32, 64}})
+	repo := repository.NewAddressBookRepository(db, repository.WithPhotoProcessor(processor))
+	ctx := context.Background()
+
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 400, 200)), nil))
+	encoded := base64.StdEncoding.EncodeToString(buf.Bytes())
+
+	uid := uuid.New().String()
+	card := "BEGIN:VCARD\r\nVERSION:3.0\r\nUID:" + uid + "\r\nFN:Photo Tester\r\nPHOTO;ENCODING=b;TYPE=JPEG:" + encoded + "\r\nEND:VCARD\r\n"
+	obj := &addressbook.AddressObject{UUID: uuid.New().String(), AddressBookID: 1, UID: uid, VCardData: card}
+	require.NoError(t, repo.CreateObject(ctx, obj))
+
+	// 1. The stored photo is downscaled, with thumbnails alongside
+	photo, err := repo.GetPhoto(ctx, obj.ID, 0)
+	require.NoError(t, err)
+	require.NotNil(t, photo)
+	assert.Equal(t, 100, photo.Width)
+	assert.Equal(t, 50, photo.Height)
+	assert.NotEqual(t, encoded, photo.PhotoData)
+	assert.NotEmpty(t, photo.ETag)
+
+	var count int64
+	db.Model(&addressbook.ContactPhotoThumbnail{}).Where("address_object_id = ?", obj.ID).Count(&count)
+	assert.Equal(t, int64(2), count)
+
+	// 2. A size picks the smallest thumbnail covering it
+	thumb, err := repo.GetPhoto(ctx, obj.ID, 40)
+	require.NoError(t, err)
+	assert.Equal(t, 64, thumb.Width)
+	assert.Equal(t, photo.ETag+"-64", thumb.ETag)
+
+	larger, err := repo.GetPhoto(ctx, obj.ID, 80)
+	require.NoError(t, err)
+	assert.Equal(t, photo.ETag, larger.ETag)
+
+	// 3. Saving the photo the client was served does not reprocess it
+	served, err := repo.GetObjectByUUID(ctx, obj.UUID)
+	require.NoError(t, err)
+	require.NoError(t, repo.UpdateObject(ctx, served))
+	again, err := repo.GetPhoto(ctx, obj.ID, 0)
+	require.NoError(t, err)
+	assert.Equal(t, photo.PhotoData, again.PhotoData)
+
+	// 4. A photo the processor can't decode is kept as sent, without
+	// thumbnails, and one that isn't base64 is dropped
+	served.VCardData = "BEGIN:VCARD\r\nVERSION:3.0\r\nUID:" + uid + "\r\nFN:Photo Tester\r\nPHOTO;ENCODING=b;TYPE=WEBP:SGVsbG8=\r\nEND:VCARD\r\n"
+	require.NoError(t, repo.UpdateObject(ctx, served))
+	kept, err := repo.GetPhoto(ctx, obj.ID, 40)
+	require.NoError(t, err)
+	assert.Equal(t, "SGVsbG8=", kept.PhotoData)
+	assert.Equal(t, "WEBP", kept.PhotoType)
+	db.Model(&addressbook.ContactPhotoThumbnail{}).Where("address_object_id = ?", obj.ID).Count(&count)
+	assert.Zero(t, count)
+
+	served.VCardData = "BEGIN:VCARD\r\nVERSION:3.0\r\nUID:" + uid + "\r\nFN:Photo Tester\r\nPHOTO;ENCODING=b;TYPE=JPEG:not base64!\r\nEND:VCARD\r\n"
+	require.NoError(t, repo.UpdateObject(ctx, served))
+	dropped, err := repo.GetPhoto(ctx, obj.ID, 0)
+	require.NoError(t, err)
+	assert.Nil(t, dropped)
+
+	// 5. Removing the photo drops the thumbnails too
+	served.VCardData = "BEGIN:VCARD\r\nVERSION:3.0\r\nUID:" + uid + "\r\nFN:Photo Tester\r\nEND:VCARD\r\n"
+	require.NoError(t, repo.UpdateObject(ctx, served))
+	db.Model(&addressbook.ContactPhotoThumbnail{}).Where("address_object_id = ?", obj.ID).Count(&count)
+	assert.Zero(t, count)
+}
+
+func TestSaveObjectChanges(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&addressbook.AddressBook{}, &addressbook.AddressObject{}, &addressbook.ContactPhoto{}, &addressbook.SyncChangeLog{}))
+
+	repo := repository.NewAddressBookRepository(db)
+	ctx := context.Background()
+	create := func(name string) *addressbook.AddressObject {
+		obj := &addressbook.AddressObject{
+			UUID:          uuid.New().String(),
+			AddressBookID: 1,
+			UID:           name,
+			Path:          name + ".vcf",
+			VCardData:     "BEGIN:VCARD\r\nVERSION:3.0\r\nUID:" + name + "\r\nFN:" + name + "\r\nEND:VCARD\r\n",
+		}
+		require.NoError(t, repo.CreateObject(ctx, obj))
+		return obj
+	}
+	a, b, c := create("a"), create("b"), create("c")
+	rename := func(obj *addressbook.AddressObject, fn string) {
+		obj.VCardData = "BEGIN:VCARD\r\nVERSION:3.0\r\nUID:" + obj.UID + "\r\nFN:" + fn + "\r\nEND:VCARD\r\n"
+	}
+
+	// 1. A failing write rolls back the whole change
+	rename(a, "Renamed")
+	b.UUID = c.UUID
+	require.Error(t, repo.SaveObjectChanges(ctx, []*addressbook.AddressObject{a, b}, []string{c.UUID}))
+	stored, err := repo.GetObjectByID(ctx, a.ID)
+	require.NoError(t, err)
+	assert.Contains(t, stored.VCardData, "FN:a")
+	still, err := repo.GetObjectByUUID(ctx, c.UUID)
+	require.NoError(t, err)
+	assert.NotNil(t, still)
+
+	// 2. Otherwise every object is updated and deleted with sync entries
+	rename(a, "Renamed")
+	require.NoError(t, repo.SaveObjectChanges(ctx, []*addressbook.AddressObject{a}, []string{c.UUID}))
+	stored, err = repo.GetObjectByID(ctx, a.ID)
+	require.NoError(t, err)
+	assert.Contains(t, stored.VCardData, "FN:Renamed")
+	gone, err := repo.GetObjectByUUID(ctx, c.UUID)
+	require.NoError(t, err)
+	assert.Nil(t, gone)
+
+	var changes []addressbook.SyncChangeLog
+	require.NoError(t, db.Where("change_type <> ?", "created").Order("id").Find(&changes).Error)
+	require.Len(t, changes, 2)
+	assert.Equal(t, "modified", changes[0].ChangeType)
+	assert.Equal(t, "deleted", changes[1].ChangeType)
+}
@@ -3,8 +3,12 @@ package repository
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"log/slog"
 	"strings"
 
 	"github.com/emersion/go-vcard"
@@ -13,11 +17,39 @@ import (
 )
 
 type AddressBookRepository struct {
-	db *gorm.DB
+	db          *gorm.DB
+	photos      addressbook.PhotoProcessor
+	phoneRegion string
 }
 
-func NewAddressBookRepository(db *gorm.DB) addressbook.Repository {
-	return &AddressBookRepository{db: db}
+// AddressBookRepositoryOption configures optional behaviour of the address
+// book repository
+type AddressBookRepositoryOption func(*AddressBookRepository)
+
+// WithPhotoProcessor normalizes every photo written through CreateObject and
+// UpdateObject and stores its thumbnails. Without it photos are stored as
+// they arrive.
+func WithPhotoProcessor(p addressbook.PhotoProcessor) AddressBookRepositoryOption {
+	return func(r *AddressBookRepository) {
+		r.photos = p
+	}
+}
+
+// WithPhoneRegion sets the region (ISO 3166-1 alpha-2, e.g. "DE") whose
+// calling code national phone numbers get when they are normalized to E.164
+// for search. Without it only numbers with an international prefix are.
+func WithPhoneRegion(region string) AddressBookRepositoryOption {
+	return func(r *AddressBookRepository) {
+		r.phoneRegion = strings.ToUpper(region)
+	}
+}
+
+func NewAddressBookRepository(db *gorm.DB, opts ...AddressBookRepositoryOption) addressbook.Repository {
+	r := &AddressBookRepository{db: db}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
 }
 
 func (r *AddressBookRepository) Create(ctx context.Context, ab *addressbook.AddressBook) error {
@@ -54,6 +86,19 @@ func (r *AddressBookRepository) ListByUserID(ctx context.Context, userID uint) (
 	return abs, nil
 }
 
+// FindByPublicToken retrieves an address book by its public token
+func (r *AddressBookRepository) FindByPublicToken(ctx context.Context, token string) (*addressbook.AddressBook, error) {
+	var ab addressbook.AddressBook
+	err := r.db.WithContext(ctx).Where("public_token = ? AND public_enabled = ?", token, true).First(&ab).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &ab, nil
+}
+
 func (r *AddressBookRepository) Update(ctx context.Context, ab *addressbook.AddressBook) error {
 	return r.db.WithContext(ctx).Save(ab).Error
 }
@@ -84,6 +129,30 @@ func (r *AddressBookRepository) GetObjectByPath(ctx context.Context, addressBook
 	return &obj, nil
 }
 
+// GetObjectByUID finds an object by its vCard UID within an address book
+func (r *AddressBookRepository) GetObjectByUID(ctx context.Context, addressBookID uint, uid string) (*addressbook.AddressObject, error) {
+	var obj addressbook.AddressObject
+	if err := r.db.WithContext(ctx).Where("address_book_id = ? AND uid = ?", addressBookID, uid).First(&obj).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &obj, nil
+}
+
+// ListGroups returns the group vCards of an address book ordered by name
+func (r *AddressBookRepository) ListGroups(ctx context.Context, addressBookID uint) ([]addressbook.AddressObject, error) {
+	var objs []addressbook.AddressObject
+	if err := r.db.WithContext(ctx).
+		Where("address_book_id = ? AND kind = ?", addressBookID, addressbook.KindGroup).
+		Order("formatted_name ASC").
+		Find(&objs).Error; err != nil {
+		return nil, err
+	}
+	return objs, nil
+}
+
 // QueryObjects performs database-level filtering based on CardDAV query parameters.
 // It maps vCard property names to database columns.
 func (r *AddressBookRepository) QueryObjects(ctx context.Context, addressBookID uint, query *addressbook.ObjectQuery) ([]addressbook.AddressObject, error) {
@@ -108,7 +177,10 @@ func (r *AddressBookRepository) QueryObjects(ctx context.Context, addressBookID
 	return objs, nil
 }
 
-// applyFilter applies a single filter to the query.
+// applyFilter applies a single filter to the query. Names, email addresses,
+// phone numbers and organizations are matched against the search columns,
+// so every value of the property counts, accents and case are ignored and
+// phone numbers compare in E.164.
 func (r *AddressBookRepository) applyFilter(db *gorm.DB, filter addressbook.ObjectQueryFilter) *gorm.DB {
 	// Map vCard property names to database columns
 	column := r.propertyToColumn(filter.PropertyName)
@@ -124,27 +196,44 @@ func (r *AddressBookRepository) applyFilter(db *gorm.DB, filter addressbook.Obje
 		return db.Where(column + " IS NULL OR " + column + " = ''")
 	}
 
+	// Multi-valued search columns wrap every value in separators; a whole
+	// value match includes them
+	expr, open, close := "LOWER("+column+")", "", ""
 	searchText := strings.ToLower(filter.SearchText)
+	if column != "uid" {
+		expr, open, close = column, "%"+addressbook.SearchValueSeparator, addressbook.SearchValueSeparator+"%"
+		searchText = addressbook.FoldText(filter.SearchText)
+	}
+	if column == "search_phones" {
+		if filter.MatchType == "equals" {
+			searchText = addressbook.NormalizePhone(filter.SearchText, r.phoneRegion)
+		} else {
+			searchText = addressbook.PhoneSearchText(filter.SearchText, r.phoneRegion)
+		}
+		if searchText == "" && filter.SearchText != "" {
+			// Text without digits matches no phone number
+			if filter.NegateCondition {
+				return db
+			}
+			return db.Where("1 = 0")
+		}
+	}
+	searchText = escapeLike(searchText)
 
-	var condition string
-	var value interface{}
-
+	var value string
 	switch filter.MatchType {
 	case "equals":
-		condition = "LOWER(" + column + ") = ?"
-		value = searchText
+		value = open + searchText + close
 	case "starts-with":
-		condition = "LOWER(" + column + ") LIKE ?"
-		value = searchText + "%"
+		value = open + searchText + "%"
 	case "ends-with":
-		condition = "LOWER(" + column + ") LIKE ?"
-		value = "%" + searchText
+		value = "%" + searchText + close
 	case "contains":
 		fallthrough
 	default:
-		condition = "LOWER(" + column + ") LIKE ?"
 		value = "%" + searchText + "%"
 	}
+	condition := expr + ` LIKE ? ESCAPE '\'`
 
 	if filter.NegateCondition {
 		return db.Where("NOT ("+condition+")", value)
@@ -157,20 +246,14 @@ func (r *AddressBookRepository) propertyToColumn(property string) string {
 	// Normalize property name to uppercase
 	prop := strings.ToUpper(property)
 	switch prop {
-	case "FN":
-		return "formatted_name"
-	case "N":
-		return "family_name" // For N, we primarily match on family_name
+	case "FN", "N", "GIVEN-NAME", "FAMILY-NAME", "NICKNAME":
+		return "search_names"
 	case "EMAIL":
-		return "email"
+		return "search_emails"
 	case "TEL":
-		return "phone"
+		return "search_phones"
 	case "ORG":
-		return "organization"
-	case "GIVEN-NAME":
-		return "given_name"
-	case "FAMILY-NAME":
-		return "family_name"
+		return "search_organization"
 	case "UID":
 		return "uid"
 	default:
@@ -178,6 +261,14 @@ func (r *AddressBookRepository) propertyToColumn(property string) string {
 	}
 }
 
+// likeEscaper escapes the LIKE wildcards in user input; patterns use a
+// backslash as ESCAPE character
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
 // Address Object methods
 // Helpers for Photo Management
 func (r *AddressBookRepository) extractPhoto(vcardData string) (string, string, string, error) {
@@ -195,9 +286,22 @@ func (r *AddressBookRepository) extractPhoto(vcardData string) (string, string,
 	// Extract photo data
 	photoData := photoField.Value
 
+	// Photos referenced by URL stay in the card; only inline data is
+	// stored separately
+	if _, _, ok := addressbook.ParsePhotoDataURI(photoData); !ok && (strings.EqualFold(photoField.Params.Get(vcard.ParamValue), "uri") ||
+		strings.HasPrefix(photoData, "http://") || strings.HasPrefix(photoData, "https://")) {
+		return vcardData, "", "", nil
+	}
+
 	// Extract type
 	photoType := "JPEG" // Default
-	if photoField.Params != nil {
+	if data, t, ok := addressbook.ParsePhotoDataURI(photoData); ok {
+		// vCard 4.0 data URI; stored the same way as vCard 3.0 inline photos
+		photoData = data
+		if t != "" {
+			photoType = t
+		}
+	} else if photoField.Params != nil {
 		types := photoField.Params.Types()
 		if len(types) > 0 {
 			photoType = strings.ToUpper(types[0])
@@ -234,15 +338,18 @@ func (r *AddressBookRepository) injectPhoto(vcardData string, photoData string,
 		return "", err
 	}
 
-	// Add photo back
-	params := make(vcard.Params)
-	params.Set("ENCODING", "b")
+	// Add photo back in the form the card's version expects
 	if photoType == "" {
 		photoType = "JPEG"
 	}
-	params.Set("TYPE", photoType)
-
-	card.Add(vcard.FieldPhoto, &vcard.Field{Value: photoData, Params: params})
+	if card.Value(vcard.FieldVersion) == addressbook.VCardVersion4 {
+		card.Add(vcard.FieldPhoto, &vcard.Field{Value: addressbook.PhotoDataURI(photoData, photoType)})
+	} else {
+		params := make(vcard.Params)
+		params.Set("ENCODING", "b")
+		params.Set("TYPE", photoType)
+		card.Add(vcard.FieldPhoto, &vcard.Field{Value: photoData, Params: params})
+	}
 
 	var buf bytes.Buffer
 	enc := vcard.NewEncoder(&buf)
@@ -260,23 +367,31 @@ func (r *AddressBookRepository) CreateObject(ctx context.Context, object *addres
 		return fmt.Errorf("failed to process vcard: %w", err)
 	}
 
+	var photo *addressbook.ContactPhoto
+	var thumbnails []addressbook.ContactPhotoThumbnail
+	if photoData != "" {
+		photo, thumbnails = r.preparePhoto(object.UID, photoData, photoType)
+	}
+
 	// Use stripped data for main object
 	object.VCardData = strippedVCard
+	if err := object.PopulateSearchFieldsFromVCard(r.phoneRegion); err != nil {
+		return fmt.Errorf("failed to process vcard: %w", err)
+	}
 
 	return r.db.Transaction(func(tx *gorm.DB) error {
 		if err := tx.WithContext(ctx).Create(object).Error; err != nil {
 			return err
 		}
 
-		if photoData != "" {
-			photo := &addressbook.ContactPhoto{
-				AddressObjectID: object.ID,
-				PhotoData:       photoData,
-				PhotoType:       photoType,
-			}
+		if photo != nil {
+			photo.AddressObjectID = object.ID
 			if err := tx.WithContext(ctx).Create(photo).Error; err != nil {
 				return err
 			}
+			if err := r.replaceThumbnails(tx.WithContext(ctx), object.ID, thumbnails); err != nil {
+				return err
+			}
 		}
 		return r.recordAddressBookChange(tx, object.AddressBookID, object.Path, object.UID, "created")
 	})
@@ -365,76 +480,243 @@ func (r *AddressBookRepository) GetObjectByUUID(ctx context.Context, uuid string
 }
 
 func (r *AddressBookRepository) UpdateObject(ctx context.Context, object *addressbook.AddressObject) error {
-	// Extract photo from vCardData
-	strippedVCard, photoData, photoType, err := r.extractPhoto(object.VCardData)
+	update, err := r.prepareUpdate(ctx, object)
 	if err != nil {
-		return fmt.Errorf("failed to process vcard: %w", err)
+		return err
 	}
-
-	// Use stripped data for main object
-	object.VCardData = strippedVCard
-
 	return r.db.Transaction(func(tx *gorm.DB) error {
-		if err := tx.WithContext(ctx).Save(object).Error; err != nil {
+		return r.applyUpdate(tx.WithContext(ctx), update)
+	})
+}
+
+// SaveObjectChanges updates the modified objects and deletes the objects
+// with the given UUIDs in one transaction, so a multi-object change such as
+// a merge is stored completely or not at all
+func (r *AddressBookRepository) SaveObjectChanges(ctx context.Context, modified []*addressbook.AddressObject, deletedUUIDs []string) error {
+	updates := make([]*objectUpdate, 0, len(modified))
+	for _, object := range modified {
+		update, err := r.prepareUpdate(ctx, object)
+		if err != nil {
 			return err
 		}
-
-		// Handle Photo
-		if photoData != "" {
-			// Upsert photo
-			var photo addressbook.ContactPhoto
-			// Check if exists
-			err := tx.WithContext(ctx).Where("address_object_id = ?", object.ID).First(&photo).Error
-			if err != nil {
-				if !errors.Is(err, gorm.ErrRecordNotFound) {
-					return err
-				}
-				// Create new
-				photo = addressbook.ContactPhoto{
-					AddressObjectID: object.ID,
-					PhotoData:       photoData,
-					PhotoType:       photoType,
-				}
-				if err := tx.WithContext(ctx).Create(&photo).Error; err != nil {
-					return err
-				}
-			} else {
-				// Update existing
-				photo.PhotoData = photoData
-				photo.PhotoType = photoType
-				if err := tx.WithContext(ctx).Save(&photo).Error; err != nil {
-					return err
-				}
+		updates = append(updates, update)
+	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		tx = tx.WithContext(ctx)
+		for _, update := range updates {
+			if err := r.applyUpdate(tx, update); err != nil {
+				return err
 			}
-		} else {
-			// If vCard has no photo, ensure no photo record exists.
-			if err := tx.WithContext(ctx).Where("address_object_id = ?", object.ID).Delete(&addressbook.ContactPhoto{}).Error; err != nil {
+		}
+		for _, uuid := range deletedUUIDs {
+			if err := r.deleteObject(tx, uuid); err != nil {
 				return err
 			}
 		}
-		return r.recordAddressBookChange(tx, object.AddressBookID, object.Path, object.UID, "modified")
+		return nil
 	})
 }
 
-func (r *AddressBookRepository) DeleteObjectByUUID(ctx context.Context, uuid string) error {
-	return r.db.Transaction(func(tx *gorm.DB) error {
-		// Look up the object first so we still have its AddressBookID /
-		// Path / UID after the soft-delete — we need them for the change
-		// log entry below.
-		var obj addressbook.AddressObject
-		if err := tx.WithContext(ctx).Where("uuid = ?", uuid).First(&obj).Error; err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				return nil // nothing to delete, nothing to log
-			}
+// objectUpdate is an object write whose photo was processed before the
+// transaction that stores it
+type objectUpdate struct {
+	object     *addressbook.AddressObject
+	photoData  string
+	photo      *addressbook.ContactPhoto
+	thumbnails []addressbook.ContactPhotoThumbnail
+	changed    bool // The photo differs from the stored one
+	hadPhoto   bool
+}
+
+func (r *AddressBookRepository) prepareUpdate(ctx context.Context, object *addressbook.AddressObject) (*objectUpdate, error) {
+	// Extract photo from vCardData
+	strippedVCard, photoData, photoType, err := r.extractPhoto(object.VCardData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to process vcard: %w", err)
+	}
+
+	// Clients send back the photo they were served; only a different one
+	// goes through the processor again
+	var existing addressbook.ContactPhoto
+	if err := r.db.WithContext(ctx).Where("address_object_id = ?", object.ID).First(&existing).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	update := &objectUpdate{object: object, photoData: photoData, hadPhoto: existing.AddressObjectID != 0}
+	update.changed = photoData != "" && photoData != existing.PhotoData
+	if update.changed {
+		if update.photo, update.thumbnails = r.preparePhoto(object.UID, photoData, photoType); update.photo == nil {
+			update.changed, update.photoData = false, ""
+		}
+	}
+
+	// Use stripped data for main object
+	object.VCardData = strippedVCard
+	if err := object.PopulateSearchFieldsFromVCard(r.phoneRegion); err != nil {
+		return nil, fmt.Errorf("failed to process vcard: %w", err)
+	}
+	return update, nil
+}
+
+func (r *AddressBookRepository) applyUpdate(tx *gorm.DB, u *objectUpdate) error {
+	object := u.object
+	if err := tx.Save(object).Error; err != nil {
+		return err
+	}
+
+	// Handle Photo
+	switch {
+	case u.changed && !u.hadPhoto:
+		u.photo.AddressObjectID = object.ID
+		if err := tx.Create(u.photo).Error; err != nil {
 			return err
 		}
-		if err := tx.WithContext(ctx).Delete(&obj).Error; err != nil {
+	case u.changed:
+		u.photo.AddressObjectID = object.ID
+		if err := tx.Save(u.photo).Error; err != nil {
+			return err
+		}
+	case u.photoData == "":
+		// If vCard has no photo, ensure no photo record exists.
+		if err := tx.Where("address_object_id = ?", object.ID).Delete(&addressbook.ContactPhoto{}).Error; err != nil {
+			return err
+		}
+	}
+	if u.changed || u.photoData == "" {
+		if err := r.replaceThumbnails(tx, object.ID, u.thumbnails); err != nil {
+			return err
+		}
+	}
+	return r.recordAddressBookChange(tx, object.AddressBookID, object.Path, object.UID, "modified")
+}
+
+// preparePhoto runs inline photo data through the photo processor, if one is
+// configured, and returns the records to store. A photo the processor can't
+// handle (WebP, HEIC, oversized images) is kept as sent, without thumbnails,
+// and one that isn't valid base64 is dropped, so the card is stored either way.
+func (r *AddressBookRepository) preparePhoto(uid, photoData, photoType string) (*addressbook.ContactPhoto, []addressbook.ContactPhotoThumbnail) {
+	original := &addressbook.ContactPhoto{PhotoData: photoData, PhotoType: photoType, ETag: photoETag(photoData)}
+	if r.photos == nil {
+		return original, nil
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(photoData), ""))
+	if err != nil {
+		slog.Warn("dropping contact photo that is not valid base64", "uid", uid)
+		return nil, nil
+	}
+	processed, err := r.photos.Process(raw)
+	if err != nil {
+		slog.Warn("storing contact photo unprocessed", "uid", uid, "error", err)
+		return original, nil
+	}
+
+	photo := &addressbook.ContactPhoto{
+		PhotoData: base64.StdEncoding.EncodeToString(processed.Data),
+		PhotoType: processed.Type,
+		Width:     processed.Width,
+		Height:    processed.Height,
+	}
+	photo.ETag = photoETag(photo.PhotoData)
+
+	thumbnails := make([]addressbook.ContactPhotoThumbnail, 0, len(processed.Thumbnails))
+	for _, t := range processed.Thumbnails {
+		thumbnails = append(thumbnails, addressbook.ContactPhotoThumbnail{
+			Size:      t.Size,
+			PhotoData: base64.StdEncoding.EncodeToString(t.Data),
+			PhotoType: t.Type,
+			Width:     t.Width,
+			Height:    t.Height,
+		})
+	}
+	return photo, thumbnails
+}
+
+// replaceThumbnails swaps the stored thumbnails of an object. Thumbnails
+// only exist when a photo processor is configured.
+func (r *AddressBookRepository) replaceThumbnails(tx *gorm.DB, objectID uint, thumbnails []addressbook.ContactPhotoThumbnail) error {
+	if r.photos == nil {
+		return nil
+	}
+	if err := tx.Where("address_object_id = ?", objectID).Delete(&addressbook.ContactPhotoThumbnail{}).Error; err != nil {
+		return err
+	}
+	for i := range thumbnails {
+		thumbnails[i].AddressObjectID = objectID
+		if err := tx.Create(&thumbnails[i]).Error; err != nil {
 			return err
 		}
-		return r.recordAddressBookChange(tx, obj.AddressBookID, obj.Path, obj.UID, "deleted")
+	}
+	return nil
+}
+
+// photoETag derives a stable entity tag from stored photo data
+func photoETag(photoData string) string {
+	sum := sha256.Sum256([]byte(photoData))
+	return hex.EncodeToString(sum[:16])
+}
+
+// GetPhoto returns the stored photo of an address object. For size > 0 it
+// returns the smallest thumbnail at least that large, or the full photo if
+// there is none. Thumbnails carry the photo's ETag suffixed with their size.
+func (r *AddressBookRepository) GetPhoto(ctx context.Context, objectID uint, size int) (*addressbook.ContactPhoto, error) {
+	var photo addressbook.ContactPhoto
+	if err := r.db.WithContext(ctx).Where("address_object_id = ?", objectID).First(&photo).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if photo.ETag == "" {
+		photo.ETag = photoETag(photo.PhotoData) // Stored before ETags were kept
+	}
+	if size <= 0 || r.photos == nil {
+		return &photo, nil
+	}
+
+	var thumb addressbook.ContactPhotoThumbnail
+	err := r.db.WithContext(ctx).
+		Where("address_object_id = ? AND size >= ?", objectID, size).
+		Order("size ASC").
+		First(&thumb).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &photo, nil
+		}
+		return nil, err
+	}
+	return &addressbook.ContactPhoto{
+		AddressObjectID: objectID,
+		PhotoData:       thumb.PhotoData,
+		PhotoType:       thumb.PhotoType,
+		Width:           thumb.Width,
+		Height:          thumb.Height,
+		ETag:            fmt.Sprintf("%s-%d", photo.ETag, thumb.Size),
+	}, nil
+}
+
+func (r *AddressBookRepository) DeleteObjectByUUID(ctx context.Context, uuid string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return r.deleteObject(tx.WithContext(ctx), uuid)
 	})
 }
 
+func (r *AddressBookRepository) deleteObject(tx *gorm.DB, uuid string) error {
+	// Look up the object first so we still have its AddressBookID /
+	// Path / UID after the soft-delete — we need them for the change
+	// log entry below.
+	var obj addressbook.AddressObject
+	if err := tx.Where("uuid = ?", uuid).First(&obj).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil // nothing to delete, nothing to log
+		}
+		return err
+	}
+	if err := tx.Delete(&obj).Error; err != nil {
+		return err
+	}
+	return r.recordAddressBookChange(tx, obj.AddressBookID, obj.Path, obj.UID, "deleted")
+}
+
 // recordAddressBookChange advances the address book's sync token and writes
 // a matching entry to the sync change log, so subsequent sync-collection
 // REPORTs can compute the delta. Without this, the DAV sync-token returned
@@ -460,12 +742,20 @@ func (r *AddressBookRepository) recordAddressBookChange(tx *gorm.DB, addressBook
 	}).Error
 }
 
+// searchTermColumns lists the search columns a term of each field is
+// matched against
+var searchTermColumns = map[addressbook.SearchField][]string{
+	addressbook.SearchFieldAny:          {"search_names", "search_emails", "search_organization"},
+	addressbook.SearchFieldName:         {"search_names"},
+	addressbook.SearchFieldEmail:        {"search_emails"},
+	addressbook.SearchFieldOrganization: {"search_organization"},
+	addressbook.SearchFieldPhone:        nil,
+}
+
 func (r *AddressBookRepository) SearchObjects(ctx context.Context, userID uint, query string, addressBookID *uint, limit int) ([]addressbook.AddressObject, error) {
 	var objs []addressbook.AddressObject
-	q := "%" + query + "%"
 
 	// Join with AddressBooks to filter by UserID
-	// And filter by query on denormalized fields
 	db := r.db.WithContext(ctx).
 		Joins("JOIN address_books ON address_books.id = address_objects.address_book_id").
 		Where("address_books.user_id = ?", userID)
@@ -474,7 +764,25 @@ func (r *AddressBookRepository) SearchObjects(ctx context.Context, userID uint,
 		db = db.Where("address_objects.address_book_id = ?", *addressBookID)
 	}
 
-	err := db.Where("address_objects.formatted_name LIKE ? OR address_objects.email LIKE ? OR address_objects.phone LIKE ? OR address_objects.organization LIKE ? OR address_objects.given_name LIKE ? OR address_objects.family_name LIKE ?", q, q, q, q, q, q).
+	// Every term must match one of the search columns of its field
+	for _, term := range addressbook.ParseSearchQuery(query, r.phoneRegion).Terms {
+		var conditions []string
+		var values []any
+		for _, column := range searchTermColumns[term.Field] {
+			conditions = append(conditions, "address_objects."+column+` LIKE ? ESCAPE '\'`)
+			values = append(values, "%"+escapeLike(term.Text)+"%")
+		}
+		if term.Phone != "" {
+			conditions = append(conditions, `address_objects.search_phones LIKE ? ESCAPE '\'`)
+			values = append(values, "%"+escapeLike(term.Phone)+"%")
+		}
+		if len(conditions) == 0 {
+			return objs, nil
+		}
+		db = db.Where("("+strings.Join(conditions, " OR ")+")", values...)
+	}
+
+	err := db.Order("address_objects.formatted_name").
 		Limit(limit).
 		Find(&objs).Error
 
@@ -484,6 +792,48 @@ func (r *AddressBookRepository) SearchObjects(ctx context.Context, userID uint,
 	return objs, nil
 }
 
+// ReindexSearch fills the search columns and Kind of objects whose SearchKey
+// differs from the current one: rows written before the search columns
+// existed, by an older release, or while another phone region was configured
+func (r *AddressBookRepository) ReindexSearch(ctx context.Context) (int, error) {
+	key := addressbook.SearchIndexKey(r.phoneRegion)
+	count := 0
+	for {
+		var objs []addressbook.AddressObject
+		if err := r.db.WithContext(ctx).Unscoped().
+			Where("search_key IS NULL OR search_key != ?", key).
+			Order("id").Limit(200).Find(&objs).Error; err != nil {
+			return count, err
+		}
+		if len(objs) == 0 {
+			return count, nil
+		}
+		for i := range objs {
+			obj := &objs[i]
+			// An unparsable card stays unsearchable, but is not tried again
+			card, err := vcard.NewDecoder(strings.NewReader(obj.VCardData)).Decode()
+			if err != nil {
+				card = vcard.Card{}
+			} else {
+				obj.Kind = addressbook.CardKind(card)
+			}
+			obj.IndexForSearch(card, r.phoneRegion)
+			// UpdateColumns leaves UpdatedAt alone: the contact did not change
+			if err := r.db.WithContext(ctx).Unscoped().Model(obj).UpdateColumns(map[string]any{
+				"search_names":        obj.SearchNames,
+				"search_emails":       obj.SearchEmails,
+				"search_phones":       obj.SearchPhones,
+				"search_organization": obj.SearchOrganization,
+				"search_key":          obj.SearchKey,
+				"kind":                obj.Kind,
+			}).Error; err != nil {
+				return count, err
+			}
+			count++
+		}
+	}
+}
+
 // GetChangesSinceToken returns all changes since the given sync token.
 // If token is empty, returns all current objects as "created".
 func (r *AddressBookRepository) GetChangesSinceToken(ctx context.Context, addressBookID uint, token string) ([]*addressbook.SyncChangeLog, error) {
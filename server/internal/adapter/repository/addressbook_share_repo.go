@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 
+	"github.com/jherrma/caldav-server/internal/domain/addressbook"
 	"github.com/jherrma/caldav-server/internal/domain/sharing"
 	"gorm.io/gorm"
 )
@@ -23,7 +24,7 @@ func (r *gormAddressBookShareRepo) Create(ctx context.Context, share *sharing.Ad
 
 func (r *gormAddressBookShareRepo) GetByUUID(ctx context.Context, uuid string) (*sharing.AddressBookShare, error) {
 	var share sharing.AddressBookShare
-	if err := r.db.WithContext(ctx).Where("uuid = ?", uuid).Preload("SharedWith").First(&share).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("uuid = ?", uuid).Preload("SharedWith").Preload("SharedWithGroup").First(&share).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil
 		}
@@ -34,7 +35,7 @@ func (r *gormAddressBookShareRepo) GetByUUID(ctx context.Context, uuid string) (
 
 func (r *gormAddressBookShareRepo) ListByAddressBookID(ctx context.Context, addressBookID uint) ([]sharing.AddressBookShare, error) {
 	var shares []sharing.AddressBookShare
-	if err := r.db.WithContext(ctx).Where("address_book_id = ?", addressBookID).Preload("SharedWith").Find(&shares).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("address_book_id = ?", addressBookID).Preload("SharedWith").Preload("SharedWithGroup").Find(&shares).Error; err != nil {
 		return nil, err
 	}
 	return shares, nil
@@ -42,7 +43,68 @@ func (r *gormAddressBookShareRepo) ListByAddressBookID(ctx context.Context, addr
 
 func (r *gormAddressBookShareRepo) FindAddressBooksSharedWithUser(ctx context.Context, userID uint) ([]sharing.AddressBookShare, error) {
 	var shares []sharing.AddressBookShare
-	if err := r.db.WithContext(ctx).Where("shared_with_id = ?", userID).Preload("AddressBook").Preload("AddressBook.User").Find(&shares).Error; err != nil {
+	owned := r.db.Model(&addressbook.AddressBook{}).Select("id").Where("user_id = ?", userID)
+	if err := r.db.WithContext(ctx).
+		Where(activeSharesOf(r.db, userID)).
+		Where("address_book_id NOT IN (?)", owned).
+		Order("id").Preload("AddressBook").Preload("AddressBook.User").Find(&shares).Error; err != nil {
+		return nil, err
+	}
+	shares = sharing.EffectiveAddressBookShares(shares)
+	if err := r.mount(ctx, userID, shares); err != nil {
+		return nil, err
+	}
+	return shares, nil
+}
+
+// mount sets the Mount of each share, mounting address books seen for the
+// first time at a path not used by the user's own address books
+func (r *gormAddressBookShareRepo) mount(ctx context.Context, userID uint, shares []sharing.AddressBookShare) error {
+	var mounts []sharing.SharedAddressBook
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Find(&mounts).Error; err != nil {
+		return err
+	}
+	byAddressBook := make(map[uint]*sharing.SharedAddressBook, len(mounts))
+	taken := map[string]bool{addressbook.DirectoryPath: true}
+	for i := range mounts {
+		byAddressBook[mounts[i].AddressBookID] = &mounts[i]
+		taken[mounts[i].Path] = true
+	}
+
+	var ownPaths []string
+	if err := r.db.WithContext(ctx).Model(&addressbook.AddressBook{}).Where("user_id = ?", userID).Pluck("path", &ownPaths).Error; err != nil {
+		return err
+	}
+	for _, p := range ownPaths {
+		taken[p] = true
+	}
+
+	for i := range shares {
+		m := byAddressBook[shares[i].AddressBookID]
+		if m == nil {
+			m = &sharing.SharedAddressBook{
+				UserID:        userID,
+				AddressBookID: shares[i].AddressBookID,
+				Path:          sharing.MountPath(shares[i].AddressBook.User.Username, shares[i].AddressBook.Path, taken),
+			}
+			if err := r.db.WithContext(ctx).Create(m).Error; err != nil {
+				// A concurrent request may have mounted it already
+				if err := r.db.WithContext(ctx).Where("user_id = ? AND address_book_id = ?", userID, shares[i].AddressBookID).First(m).Error; err != nil {
+					return err
+				}
+			}
+			taken[m.Path] = true
+		}
+		shares[i].Mount = m
+	}
+	return nil
+}
+
+func (r *gormAddressBookShareRepo) ListPendingForUser(ctx context.Context, userID uint) ([]sharing.AddressBookShare, error) {
+	var shares []sharing.AddressBookShare
+	if err := r.db.WithContext(ctx).
+		Where("shared_with_id = ? AND status = ?", userID, sharing.ShareStatusPending).
+		Order("id").Preload("AddressBook").Preload("AddressBook.User").Find(&shares).Error; err != nil {
 		return nil, err
 	}
 	return shares, nil
@@ -53,7 +115,8 @@ func (r *gormAddressBookShareRepo) Update(ctx context.Context, share *sharing.Ad
 }
 
 func (r *gormAddressBookShareRepo) Revoke(ctx context.Context, id uint) error {
-	return r.db.WithContext(ctx).Delete(&sharing.AddressBookShare{}, id).Error
+	// Shares are removed for good so the recipient can be invited again
+	return r.db.WithContext(ctx).Unscoped().Delete(&sharing.AddressBookShare{}, id).Error
 }
 
 func (r *gormAddressBookShareRepo) GetByAddressBookAndUser(ctx context.Context, addressBookID, userID uint) (*sharing.AddressBookShare, error) {
@@ -66,3 +129,14 @@ func (r *gormAddressBookShareRepo) GetByAddressBookAndUser(ctx context.Context,
 	}
 	return &share, nil
 }
+
+func (r *gormAddressBookShareRepo) GetByAddressBookAndGroup(ctx context.Context, addressBookID, groupID uint) (*sharing.AddressBookShare, error) {
+	var share sharing.AddressBookShare
+	if err := r.db.WithContext(ctx).Where("address_book_id = ? AND shared_with_group_id = ?", addressBookID, groupID).First(&share).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &share, nil
+}
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jherrma/caldav-server/internal/domain/calendar"
+	"gorm.io/gorm"
+)
+
+// BirthdayRepository implements calendar.BirthdayRepository
+type BirthdayRepository struct {
+	db *gorm.DB
+}
+
+func NewBirthdayRepository(db *gorm.DB) calendar.BirthdayRepository {
+	return &BirthdayRepository{db: db}
+}
+
+func (r *BirthdayRepository) GetSettings(ctx context.Context, userID uint) (*calendar.BirthdaySettings, error) {
+	var settings calendar.BirthdaySettings
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&settings).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &settings, nil
+}
+
+func (r *BirthdayRepository) SaveSettings(ctx context.Context, settings *calendar.BirthdaySettings) error {
+	return r.db.WithContext(ctx).Save(settings).Error
+}
+
+func (r *BirthdayRepository) ListEvents(ctx context.Context, userID uint) ([]calendar.BirthdayEvent, error) {
+	var events []calendar.BirthdayEvent
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("summary ASC, id ASC").
+		Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (r *BirthdayRepository) SaveChanges(ctx context.Context, userID uint, created, modified, removed []calendar.BirthdayEvent, token string) error {
+	// All log rows of one batch carry the same timestamp so that a client
+	// holding this token does not see the rest of the batch again
+	now := time.Now()
+	logChange := func(tx *gorm.DB, e *calendar.BirthdayEvent, changeType string) error {
+		return tx.Create(&calendar.BirthdayChangeLog{
+			UserID:       userID,
+			ResourcePath: e.Path,
+			ResourceUID:  e.UID,
+			ChangeType:   changeType,
+			SyncToken:    token,
+			CreatedAt:    now,
+		}).Error
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i := range created {
+			if err := tx.Create(&created[i]).Error; err != nil {
+				return err
+			}
+			if err := logChange(tx, &created[i], "created"); err != nil {
+				return err
+			}
+		}
+		for i := range modified {
+			if err := tx.Save(&modified[i]).Error; err != nil {
+				return err
+			}
+			if err := logChange(tx, &modified[i], "modified"); err != nil {
+				return err
+			}
+		}
+		for i := range removed {
+			if err := tx.Delete(&calendar.BirthdayEvent{}, removed[i].ID).Error; err != nil {
+				return err
+			}
+			if err := logChange(tx, &removed[i], "deleted"); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *BirthdayRepository) SyncToken(ctx context.Context, userID uint) (string, error) {
+	var last calendar.BirthdayChangeLog
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("id DESC").
+		First(&last).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", nil
+		}
+		return "", err
+	}
+	return last.SyncToken, nil
+}
+
+func (r *BirthdayRepository) GetChangesSinceToken(ctx context.Context, userID uint, token string) ([]*calendar.SyncChangeLog, error) {
+	var lastChange calendar.BirthdayChangeLog
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ? AND sync_token = ?", userID, token).
+		First(&lastChange).Error; err != nil {
+		return nil, err
+	}
+
+	var logs []calendar.BirthdayChangeLog
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ? AND created_at > ?", userID, lastChange.CreatedAt).
+		Order("created_at ASC, id ASC").
+		Find(&logs).Error; err != nil {
+		return nil, err
+	}
+
+	changes := make([]*calendar.SyncChangeLog, 0, len(logs))
+	for i := range logs {
+		changes = append(changes, logs[i].SyncChangeLog())
+	}
+	return changes, nil
+}
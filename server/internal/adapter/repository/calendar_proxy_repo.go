@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jherrma/caldav-server/internal/domain/sharing"
+	"gorm.io/gorm"
+)
+
+type gormCalendarProxyRepo struct {
+	db *gorm.DB
+}
+
+// NewCalendarProxyRepository creates a new GORM-based calendar proxy repository
+func NewCalendarProxyRepository(db *gorm.DB) sharing.CalendarProxyRepository {
+	return &gormCalendarProxyRepo{db: db}
+}
+
+func (r *gormCalendarProxyRepo) ListByUser(ctx context.Context, userID uint) ([]sharing.CalendarProxy, error) {
+	var proxies []sharing.CalendarProxy
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Preload("Proxy").Order("id").Find(&proxies).Error; err != nil {
+		return nil, err
+	}
+	return proxies, nil
+}
+
+func (r *gormCalendarProxyRepo) ListForProxy(ctx context.Context, proxyID uint) ([]sharing.CalendarProxy, error) {
+	var proxies []sharing.CalendarProxy
+	if err := r.db.WithContext(ctx).Where("proxy_id = ?", proxyID).Preload("User").Order("id").Find(&proxies).Error; err != nil {
+		return nil, err
+	}
+	return proxies, nil
+}
+
+func (r *gormCalendarProxyRepo) Get(ctx context.Context, userID, proxyID uint) (*sharing.CalendarProxy, error) {
+	var proxy sharing.CalendarProxy
+	if err := r.db.WithContext(ctx).Where("user_id = ? AND proxy_id = ?", userID, proxyID).
+		Preload("User").Preload("Proxy").First(&proxy).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &proxy, nil
+}
+
+func (r *gormCalendarProxyRepo) Set(ctx context.Context, proxy *sharing.CalendarProxy) error {
+	return r.db.WithContext(ctx).Omit("User", "Proxy").Save(proxy).Error
+}
+
+func (r *gormCalendarProxyRepo) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&sharing.CalendarProxy{}, id).Error
+}
@@ -179,6 +179,20 @@ func (r *CalendarRepository) GetCalendarObjectByUUID(ctx context.Context, uuid s
 	return &obj, nil
 }
 
+// GetCalendarObjectByUID retrieves the object of a calendar with an
+// iCalendar UID, or nil if there is none
+func (r *CalendarRepository) GetCalendarObjectByUID(ctx context.Context, calendarID uint, uid string) (*calendar.CalendarObject, error) {
+	var obj calendar.CalendarObject
+	err := r.db.WithContext(ctx).Where("calendar_id = ? AND uid = ?", calendarID, uid).First(&obj).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &obj, nil
+}
+
 // ListEvents retrieves calendar objects within a time range
 func (r *CalendarRepository) ListEvents(ctx context.Context, calendarID uint, start, end time.Time) ([]*calendar.CalendarObject, error) {
 	var objects []*calendar.CalendarObject
@@ -226,17 +240,24 @@ func (r *CalendarRepository) GetUserPermission(ctx context.Context, calendarID,
 		return calendar.PermissionOwner, nil
 	}
 
-	// Check share
-	var share sharing.CalendarShare
-	err := r.db.WithContext(ctx).Where("calendar_id = ? AND shared_with_id = ?", calendarID, userID).First(&share).Error
+	// Check accepted direct shares and group shares; the highest permission wins
+	var shares []sharing.CalendarShare
+	err := r.db.WithContext(ctx).
+		Where("calendar_id = ?", calendarID).
+		Where(activeSharesOf(r.db, userID)).
+		Find(&shares).Error
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return calendar.PermissionNone, nil
-		}
 		return calendar.PermissionNone, err
 	}
+	if len(shares) == 0 {
+		return calendar.PermissionNone, nil
+	}
 
-	if share.Permission == "read-write" {
+	perm := shares[0].Permission
+	for _, s := range shares[1:] {
+		perm = sharing.HigherPermission(perm, s.Permission)
+	}
+	if perm == sharing.PermissionReadWrite {
 		return calendar.PermissionReadWrite, nil
 	}
 	return calendar.PermissionRead, nil
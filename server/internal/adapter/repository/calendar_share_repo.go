@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 
+	"github.com/jherrma/caldav-server/internal/domain/calendar"
 	"github.com/jherrma/caldav-server/internal/domain/sharing"
 	"gorm.io/gorm"
 )
@@ -23,7 +24,7 @@ func (r *gormCalendarShareRepo) Create(ctx context.Context, share *sharing.Calen
 
 func (r *gormCalendarShareRepo) GetByUUID(ctx context.Context, uuid string) (*sharing.CalendarShare, error) {
 	var share sharing.CalendarShare
-	if err := r.db.WithContext(ctx).Where("uuid = ?", uuid).Preload("SharedWith").First(&share).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("uuid = ?", uuid).Preload("SharedWith").Preload("SharedWithGroup").First(&share).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil
 		}
@@ -34,7 +35,7 @@ func (r *gormCalendarShareRepo) GetByUUID(ctx context.Context, uuid string) (*sh
 
 func (r *gormCalendarShareRepo) ListByCalendarID(ctx context.Context, calendarID uint) ([]sharing.CalendarShare, error) {
 	var shares []sharing.CalendarShare
-	if err := r.db.WithContext(ctx).Where("calendar_id = ?", calendarID).Preload("SharedWith").Find(&shares).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("calendar_id = ?", calendarID).Preload("SharedWith").Preload("SharedWithGroup").Find(&shares).Error; err != nil {
 		return nil, err
 	}
 	return shares, nil
@@ -42,7 +43,72 @@ func (r *gormCalendarShareRepo) ListByCalendarID(ctx context.Context, calendarID
 
 func (r *gormCalendarShareRepo) FindCalendarsSharedWithUser(ctx context.Context, userID uint) ([]sharing.CalendarShare, error) {
 	var shares []sharing.CalendarShare
-	if err := r.db.WithContext(ctx).Where("shared_with_id = ?", userID).Preload("Calendar").Preload("Calendar.Owner").Find(&shares).Error; err != nil {
+	owned := r.db.Model(&calendar.Calendar{}).Select("id").Where("user_id = ?", userID)
+	if err := r.db.WithContext(ctx).
+		Where(activeSharesOf(r.db, userID)).
+		Where("calendar_id NOT IN (?)", owned).
+		Order("id").Preload("Calendar").Preload("Calendar.Owner").Find(&shares).Error; err != nil {
+		return nil, err
+	}
+	shares = sharing.EffectiveCalendarShares(shares)
+	if err := r.mount(ctx, userID, shares); err != nil {
+		return nil, err
+	}
+	return shares, nil
+}
+
+// mount sets the Mount of each share, mounting calendars seen for the
+// first time at a path not used by the user's own calendars
+func (r *gormCalendarShareRepo) mount(ctx context.Context, userID uint, shares []sharing.CalendarShare) error {
+	var mounts []sharing.SharedCalendar
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Find(&mounts).Error; err != nil {
+		return err
+	}
+	byCalendar := make(map[uint]*sharing.SharedCalendar, len(mounts))
+	taken := map[string]bool{calendar.BirthdayCalendarPath: true}
+	for i := range mounts {
+		byCalendar[mounts[i].CalendarID] = &mounts[i]
+		taken[mounts[i].Path] = true
+	}
+
+	var ownPaths []string
+	if err := r.db.WithContext(ctx).Model(&calendar.Calendar{}).Where("user_id = ?", userID).Pluck("path", &ownPaths).Error; err != nil {
+		return err
+	}
+	for _, p := range ownPaths {
+		taken[p] = true
+	}
+
+	for i := range shares {
+		m := byCalendar[shares[i].CalendarID]
+		if m == nil {
+			m = &sharing.SharedCalendar{
+				UserID:     userID,
+				CalendarID: shares[i].CalendarID,
+				Path:       sharing.MountPath(shares[i].Calendar.Owner.Username, shares[i].Calendar.Path, taken),
+			}
+			if err := r.db.WithContext(ctx).Create(m).Error; err != nil {
+				// A concurrent request may have mounted it already
+				if err := r.db.WithContext(ctx).Where("user_id = ? AND calendar_id = ?", userID, shares[i].CalendarID).First(m).Error; err != nil {
+					return err
+				}
+			}
+			taken[m.Path] = true
+		}
+		shares[i].Mount = m
+	}
+	return nil
+}
+
+func (r *gormCalendarShareRepo) UpdateSharedCalendar(ctx context.Context, sc *sharing.SharedCalendar) error {
+	return r.db.WithContext(ctx).Save(sc).Error
+}
+
+func (r *gormCalendarShareRepo) ListPendingForUser(ctx context.Context, userID uint) ([]sharing.CalendarShare, error) {
+	var shares []sharing.CalendarShare
+	if err := r.db.WithContext(ctx).
+		Where("shared_with_id = ? AND status = ?", userID, sharing.ShareStatusPending).
+		Order("id").Preload("Calendar").Preload("Calendar.Owner").Find(&shares).Error; err != nil {
 		return nil, err
 	}
 	return shares, nil
@@ -53,7 +119,8 @@ func (r *gormCalendarShareRepo) Update(ctx context.Context, share *sharing.Calen
 }
 
 func (r *gormCalendarShareRepo) Revoke(ctx context.Context, id uint) error {
-	return r.db.WithContext(ctx).Delete(&sharing.CalendarShare{}, id).Error
+	// Shares are removed for good so the recipient can be invited again
+	return r.db.WithContext(ctx).Unscoped().Delete(&sharing.CalendarShare{}, id).Error
 }
 
 func (r *gormCalendarShareRepo) GetByCalendarAndUser(ctx context.Context, calendarID, userID uint) (*sharing.CalendarShare, error) {
@@ -66,3 +133,14 @@ func (r *gormCalendarShareRepo) GetByCalendarAndUser(ctx context.Context, calend
 	}
 	return &share, nil
 }
+
+func (r *gormCalendarShareRepo) GetByCalendarAndGroup(ctx context.Context, calendarID, groupID uint) (*sharing.CalendarShare, error) {
+	var share sharing.CalendarShare
+	if err := r.db.WithContext(ctx).Where("calendar_id = ? AND shared_with_group_id = ?", calendarID, groupID).First(&share).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &share, nil
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jherrma/caldav-server/internal/domain/addressbook"
+	"gorm.io/gorm"
+)
+
+// DirectoryRepository implements addressbook.DirectoryRepository. Changes
+// share the address book change log under addressbook.DirectoryAddressBookID.
+type DirectoryRepository struct {
+	db *gorm.DB
+}
+
+func NewDirectoryRepository(db *gorm.DB) addressbook.DirectoryRepository {
+	return &DirectoryRepository{db: db}
+}
+
+func (r *DirectoryRepository) ListEntries(ctx context.Context) ([]addressbook.DirectoryEntry, error) {
+	var entries []addressbook.DirectoryEntry
+	if err := r.db.WithContext(ctx).Order("formatted_name ASC, id ASC").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (r *DirectoryRepository) SaveChanges(ctx context.Context, created, modified, removed []addressbook.DirectoryEntry, token string) error {
+	// All log rows of one batch carry the same timestamp so that a client
+	// holding this token does not see the rest of the batch again
+	now := time.Now()
+	logChange := func(tx *gorm.DB, e *addressbook.DirectoryEntry, changeType string) error {
+		return tx.Create(&addressbook.SyncChangeLog{
+			AddressBookID: addressbook.DirectoryAddressBookID,
+			ResourcePath:  e.Path(),
+			ResourceUID:   e.UID,
+			ChangeType:    changeType,
+			SyncToken:     token,
+			CreatedAt:     now,
+		}).Error
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i := range created {
+			if err := tx.Create(&created[i]).Error; err != nil {
+				return err
+			}
+			if err := logChange(tx, &created[i], "created"); err != nil {
+				return err
+			}
+		}
+		for i := range modified {
+			if err := tx.Save(&modified[i]).Error; err != nil {
+				return err
+			}
+			if err := logChange(tx, &modified[i], "modified"); err != nil {
+				return err
+			}
+		}
+		for i := range removed {
+			if err := tx.Delete(&addressbook.DirectoryEntry{}, removed[i].ID).Error; err != nil {
+				return err
+			}
+			if err := logChange(tx, &removed[i], "deleted"); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *DirectoryRepository) SyncToken(ctx context.Context) (string, error) {
+	var last addressbook.SyncChangeLog
+	if err := r.db.WithContext(ctx).
+		Where("address_book_id = ?", addressbook.DirectoryAddressBookID).
+		Order("id DESC").
+		First(&last).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", nil
+		}
+		return "", err
+	}
+	return last.SyncToken, nil
+}
+
+func (r *DirectoryRepository) GetChangesSinceToken(ctx context.Context, token string) ([]*addressbook.SyncChangeLog, error) {
+	var lastChange addressbook.SyncChangeLog
+	if err := r.db.WithContext(ctx).
+		Where("address_book_id = ? AND sync_token = ?", addressbook.DirectoryAddressBookID, token).
+		First(&lastChange).Error; err != nil {
+		return nil, err
+	}
+
+	var changes []*addressbook.SyncChangeLog
+	if err := r.db.WithContext(ctx).
+		Where("address_book_id = ? AND created_at > ?", addressbook.DirectoryAddressBookID, lastChange.CreatedAt).
+		Order("created_at ASC, id ASC").
+		Find(&changes).Error; err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
@@ -0,0 +1,6 @@
+package repository
+
+// Domain models tag sensitive columns with serializer:encrypted, which GORM
+// resolves by name when parsing a schema. Importing the package registers
+// the serializers; encryption itself stays transparent to the repositories.
+import _ "github.com/jherrma/caldav-server/internal/infrastructure/encryption"
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jherrma/caldav-server/internal/domain/sharing"
+	"github.com/jherrma/caldav-server/internal/domain/user"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type gormGroupRepo struct {
+	db *gorm.DB
+}
+
+// NewGroupRepository creates a new GORM-based user group repository
+func NewGroupRepository(db *gorm.DB) user.GroupRepository {
+	return &gormGroupRepo{db: db}
+}
+
+// groupIDsOfUser selects the IDs of the groups a user belongs to, for use as
+// a subquery when resolving group shares. Invitations do not count.
+func groupIDsOfUser(db *gorm.DB, userID uint) *gorm.DB {
+	return db.Model(&user.GroupMember{}).Select("group_id").Where("user_id = ? AND pending = ?", userID, false)
+}
+
+// activeSharesOf is a grouped condition matching the shares that grant a
+// user access: accepted direct shares and the shares with the user's groups
+func activeSharesOf(db *gorm.DB, userID uint) *gorm.DB {
+	return db.Where("(shared_with_id = ? AND status = ?) OR shared_with_group_id IN (?)",
+		userID, sharing.ShareStatusAccepted, groupIDsOfUser(db, userID))
+}
+
+func (r *gormGroupRepo) preload(db *gorm.DB) *gorm.DB {
+	return db.Preload("Owner").Preload("Members", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).Preload("Members.User")
+}
+
+func (r *gormGroupRepo) Create(ctx context.Context, group *user.Group) error {
+	return r.db.WithContext(ctx).Create(group).Error
+}
+
+func (r *gormGroupRepo) GetByUUID(ctx context.Context, uuid string) (*user.Group, error) {
+	var group user.Group
+	if err := r.preload(r.db.WithContext(ctx)).Where("uuid = ?", uuid).First(&group).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &group, nil
+}
+
+func (r *gormGroupRepo) ListAll(ctx context.Context) ([]user.Group, error) {
+	var groups []user.Group
+	if err := r.preload(r.db.WithContext(ctx)).Order("name").Find(&groups).Error; err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+func (r *gormGroupRepo) ListForUser(ctx context.Context, userID uint) ([]user.Group, error) {
+	var groups []user.Group
+	if err := r.preload(r.db.WithContext(ctx)).
+		Where("owner_id IS NULL OR owner_id = ? OR id IN (?)", userID,
+			r.db.Model(&user.GroupMember{}).Select("group_id").Where("user_id = ?", userID)).
+		Order("name").Find(&groups).Error; err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+func (r *gormGroupRepo) Update(ctx context.Context, group *user.Group) error {
+	return r.db.WithContext(ctx).Omit(clause.Associations).Save(group).Error
+}
+
+func (r *gormGroupRepo) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("shared_with_group_id = ?", id).Delete(&sharing.CalendarShare{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("shared_with_group_id = ?", id).Delete(&sharing.AddressBookShare{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("group_id = ?", id).Delete(&user.GroupMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&user.Group{}, id).Error
+	})
+}
+
+func (r *gormGroupRepo) AddMember(ctx context.Context, groupID, userID uint, pending bool) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&user.GroupMember{GroupID: groupID, UserID: userID, Pending: pending}).Error
+}
+
+func (r *gormGroupRepo) AcceptMember(ctx context.Context, groupID, userID uint) error {
+	return r.db.WithContext(ctx).Model(&user.GroupMember{}).
+		Where("group_id = ? AND user_id = ?", groupID, userID).Update("pending", false).Error
+}
+
+func (r *gormGroupRepo) RemoveMember(ctx context.Context, groupID, userID uint) error {
+	return r.db.WithContext(ctx).Where("group_id = ? AND user_id = ?", groupID, userID).Delete(&user.GroupMember{}).Error
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jherrma/caldav-server/internal/domain/user"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type gormLoginThrottleRepo struct {
+	db *gorm.DB
+}
+
+// NewLoginThrottleRepository creates a new GORM-based login throttle repository
+func NewLoginThrottleRepository(db *gorm.DB) user.LoginThrottleRepository {
+	return &gormLoginThrottleRepo{db: db}
+}
+
+func (r *gormLoginThrottleRepo) Get(ctx context.Context, subjectType string, subjectID uint) (*user.LoginThrottle, error) {
+	var t user.LoginThrottle
+	if err := r.db.WithContext(ctx).
+		Where("subject_type = ? AND subject_id = ?", subjectType, subjectID).
+		First(&t).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *gormLoginThrottleRepo) IncrementFailures(ctx context.Context, subjectType string, subjectID, userID uint, at time.Time) (*user.LoginThrottle, error) {
+	t := &user.LoginThrottle{
+		SubjectType:   subjectType,
+		SubjectID:     subjectID,
+		UserID:        userID,
+		FailureCount:  1,
+		LastFailureAt: &at,
+	}
+	// Upsert with an in-database increment so concurrent failures are not lost
+	if err := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "subject_type"}, {Name: "subject_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"failure_count":   gorm.Expr("login_throttles.failure_count + 1"),
+			"last_failure_at": at,
+			"updated_at":      at,
+		}),
+	}).Create(t).Error; err != nil {
+		return nil, err
+	}
+	return r.Get(ctx, subjectType, subjectID)
+}
+
+func (r *gormLoginThrottleRepo) SetLockedUntil(ctx context.Context, id uint, until *time.Time) error {
+	return r.db.WithContext(ctx).Model(&user.LoginThrottle{}).
+		Where("id = ?", id).
+		Update("locked_until", until).Error
+}
+
+func (r *gormLoginThrottleRepo) Delete(ctx context.Context, subjectType string, subjectID uint) error {
+	return r.db.WithContext(ctx).
+		Where("subject_type = ? AND subject_id = ?", subjectType, subjectID).
+		Delete(&user.LoginThrottle{}).Error
+}
+
+func (r *gormLoginThrottleRepo) DeleteByUserID(ctx context.Context, userID uint) (int64, error) {
+	res := r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&user.LoginThrottle{})
+	return res.RowsAffected, res.Error
+}
@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jherrma/caldav-server/internal/domain/oauthserver"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type gormOAuthClientRepo struct {
+	db *gorm.DB
+}
+
+// NewOAuthClientRepository creates a new GORM-based OAuth client repository
+func NewOAuthClientRepository(db *gorm.DB) oauthserver.ClientRepository {
+	return &gormOAuthClientRepo{db: db}
+}
+
+func (r *gormOAuthClientRepo) Create(ctx context.Context, c *oauthserver.Client) error {
+	return r.db.WithContext(ctx).Create(c).Error
+}
+
+func (r *gormOAuthClientRepo) GetByClientID(ctx context.Context, clientID string) (*oauthserver.Client, error) {
+	var c oauthserver.Client
+	if err := r.db.WithContext(ctx).Where("client_id = ?", clientID).First(&c).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *gormOAuthClientRepo) ListByUserID(ctx context.Context, userID uint) ([]oauthserver.Client, error) {
+	var clients []oauthserver.Client
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC").Find(&clients).Error; err != nil {
+		return nil, err
+	}
+	return clients, nil
+}
+
+func (r *gormOAuthClientRepo) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&oauthserver.Client{}, id).Error
+}
+
+type gormOAuthCodeRepo struct {
+	db *gorm.DB
+}
+
+// NewOAuthAuthorizationCodeRepository creates a new GORM-based authorization code repository
+func NewOAuthAuthorizationCodeRepository(db *gorm.DB) oauthserver.AuthorizationCodeRepository {
+	return &gormOAuthCodeRepo{db: db}
+}
+
+func (r *gormOAuthCodeRepo) Create(ctx context.Context, code *oauthserver.AuthorizationCode) error {
+	// Codes live for minutes; drop stale ones whenever a new one is issued
+	if err := r.db.WithContext(ctx).
+		Where("expires_at < ?", time.Now().Add(-time.Hour)).
+		Delete(&oauthserver.AuthorizationCode{}).Error; err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Create(code).Error
+}
+
+func (r *gormOAuthCodeRepo) GetByHash(ctx context.Context, hash string) (*oauthserver.AuthorizationCode, error) {
+	var code oauthserver.AuthorizationCode
+	if err := r.db.WithContext(ctx).Where("code_hash = ?", hash).First(&code).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &code, nil
+}
+
+func (r *gormOAuthCodeRepo) MarkUsed(ctx context.Context, id uint) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&oauthserver.AuthorizationCode{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+type gormOAuthTokenRepo struct {
+	db *gorm.DB
+}
+
+// NewOAuthTokenRepository creates a new GORM-based OAuth token repository
+func NewOAuthTokenRepository(db *gorm.DB) oauthserver.TokenRepository {
+	return &gormOAuthTokenRepo{db: db}
+}
+
+func (r *gormOAuthTokenRepo) Create(ctx context.Context, t *oauthserver.Token) error {
+	return r.db.WithContext(ctx).Create(t).Error
+}
+
+func (r *gormOAuthTokenRepo) GetByAccessHash(ctx context.Context, hash string) (*oauthserver.Token, error) {
+	return r.getWhere(ctx, "access_token_hash = ?", hash)
+}
+
+func (r *gormOAuthTokenRepo) GetByRefreshHash(ctx context.Context, hash string) (*oauthserver.Token, error) {
+	return r.getWhere(ctx, "refresh_token_hash = ?", hash)
+}
+
+func (r *gormOAuthTokenRepo) getWhere(ctx context.Context, query string, arg string) (*oauthserver.Token, error) {
+	var t oauthserver.Token
+	if err := r.db.WithContext(ctx).Where(query, arg).First(&t).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *gormOAuthTokenRepo) Revoke(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Model(&oauthserver.Token{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *gormOAuthTokenRepo) RevokeByAuthorizationCode(ctx context.Context, codeID uint) error {
+	return r.db.WithContext(ctx).Model(&oauthserver.Token{}).
+		Where("authorization_code_id = ? AND revoked_at IS NULL", codeID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *gormOAuthTokenRepo) RevokeByClient(ctx context.Context, clientID string) error {
+	return r.db.WithContext(ctx).Model(&oauthserver.Token{}).
+		Where("client_id = ? AND revoked_at IS NULL", clientID).
+		Update("revoked_at", time.Now()).Error
+}
+
+type gormOAuthConsentRepo struct {
+	db *gorm.DB
+}
+
+// NewOAuthConsentRepository creates a new GORM-based OAuth consent repository
+func NewOAuthConsentRepository(db *gorm.DB) oauthserver.ConsentRepository {
+	return &gormOAuthConsentRepo{db: db}
+}
+
+func (r *gormOAuthConsentRepo) Get(ctx context.Context, userID uint, clientID string) (*oauthserver.Consent, error) {
+	var c oauthserver.Consent
+	if err := r.db.WithContext(ctx).Where("user_id = ? AND client_id = ?", userID, clientID).First(&c).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *gormOAuthConsentRepo) Save(ctx context.Context, c *oauthserver.Consent) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "client_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"scope", "updated_at"}),
+	}).Create(c).Error
+}
+
+func (r *gormOAuthConsentRepo) DeleteByClient(ctx context.Context, clientID string) error {
+	return r.db.WithContext(ctx).Where("client_id = ?", clientID).Delete(&oauthserver.Consent{}).Error
+}
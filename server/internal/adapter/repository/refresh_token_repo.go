@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/jherrma/caldav-server/internal/domain/user"
@@ -37,6 +38,15 @@ func (r *gormRefreshTokenRepo) GetByHash(ctx context.Context, hash string) (*use
 		}
 		return nil, err
 	}
+
+	// Tokens issued before session tracking have no family; each one is
+	// its own session.
+	if t.FamilyID == "" {
+		t.FamilyID = strconv.FormatUint(uint64(t.ID), 10)
+		if err := r.db.WithContext(ctx).Model(&t).Update("family_id", t.FamilyID).Error; err != nil {
+			return nil, err
+		}
+	}
 	return &t, nil
 }
 
@@ -47,3 +57,56 @@ func (r *gormRefreshTokenRepo) DeleteByHash(ctx context.Context, hash string) er
 func (r *gormRefreshTokenRepo) DeleteByUserID(ctx context.Context, userID uint) error {
 	return r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&user.RefreshToken{}).Error
 }
+
+func (r *gormRefreshTokenRepo) DeleteByFamily(ctx context.Context, userID uint, familyID string) error {
+	return r.db.WithContext(ctx).
+		Where("user_id = ? AND family_id = ?", userID, familyID).
+		Delete(&user.RefreshToken{}).Error
+}
+
+func (r *gormRefreshTokenRepo) ListActiveByUserID(ctx context.Context, userID uint) ([]user.RefreshToken, error) {
+	// Same backfill as GetByHash, done in bulk so legacy sessions can be
+	// addressed by ID.
+	if err := r.db.WithContext(ctx).Model(&user.RefreshToken{}).
+		Where("user_id = ? AND (family_id = '' OR family_id IS NULL)", userID).
+		Update("family_id", gorm.Expr("CAST(id AS VARCHAR(36))")).Error; err != nil {
+		return nil, err
+	}
+
+	var tokens []user.RefreshToken
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("created_at DESC").
+		Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+func (r *gormRefreshTokenRepo) MarkRotated(ctx context.Context, id uint) (bool, error) {
+	now := time.Now()
+	res := r.db.WithContext(ctx).Model(&user.RefreshToken{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Updates(map[string]interface{}{"revoked_at": now, "last_used_at": now})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+func (r *gormRefreshTokenRepo) RevokeFamily(ctx context.Context, userID uint, familyID string) (int64, error) {
+	res := r.db.WithContext(ctx).Model(&user.RefreshToken{}).
+		Where("user_id = ? AND family_id = ? AND revoked_at IS NULL", userID, familyID).
+		Update("revoked_at", time.Now())
+	return res.RowsAffected, res.Error
+}
+
+func (r *gormRefreshTokenRepo) RevokeAllExcept(ctx context.Context, userID uint, familyID string) (int64, error) {
+	query := r.db.WithContext(ctx).Model(&user.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID)
+	if familyID != "" {
+		query = query.Where("family_id IS NULL OR family_id <> ?", familyID)
+	}
+	res := query.Update("revoked_at", time.Now())
+	return res.RowsAffected, res.Error
+}
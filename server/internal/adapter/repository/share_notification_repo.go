@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jherrma/caldav-server/internal/domain/sharing"
+	"gorm.io/gorm"
+)
+
+type gormShareNotificationRepo struct {
+	db *gorm.DB
+}
+
+// NewShareNotificationRepository creates a new GORM-based sharing
+// notification repository
+func NewShareNotificationRepository(db *gorm.DB) sharing.NotificationRepository {
+	return &gormShareNotificationRepo{db: db}
+}
+
+func (r *gormShareNotificationRepo) Create(ctx context.Context, n *sharing.Notification) error {
+	return r.db.WithContext(ctx).Create(n).Error
+}
+
+func (r *gormShareNotificationRepo) ListByUser(ctx context.Context, userID uint) ([]sharing.Notification, error) {
+	var notifications []sharing.Notification
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Preload("FromUser").Order("id").Find(&notifications).Error; err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
+
+func (r *gormShareNotificationRepo) GetByUUID(ctx context.Context, userID uint, uuid string) (*sharing.Notification, error) {
+	var n sharing.Notification
+	if err := r.db.WithContext(ctx).Where("user_id = ? AND uuid = ?", userID, uuid).Preload("FromUser").First(&n).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &n, nil
+}
+
+func (r *gormShareNotificationRepo) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&sharing.Notification{}, id).Error
+}
+
+func (r *gormShareNotificationRepo) DeleteByShare(ctx context.Context, userID uint, shareUUID string) error {
+	return r.db.WithContext(ctx).Where("user_id = ? AND share_uuid = ?", userID, shareUUID).Delete(&sharing.Notification{}).Error
+}
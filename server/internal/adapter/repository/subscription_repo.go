@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jherrma/caldav-server/internal/domain/calendar"
+	"gorm.io/gorm"
+)
+
+type gormSubscriptionRepo struct {
+	db *gorm.DB
+}
+
+// NewSubscriptionRepository creates a new GORM-based feed subscription repository
+func NewSubscriptionRepository(db *gorm.DB) calendar.SubscriptionRepository {
+	return &gormSubscriptionRepo{db: db}
+}
+
+func (r *gormSubscriptionRepo) Create(ctx context.Context, s *calendar.Subscription) error {
+	return r.db.WithContext(ctx).Omit("Calendar").Create(s).Error
+}
+
+func (r *gormSubscriptionRepo) GetByID(ctx context.Context, id uint) (*calendar.Subscription, error) {
+	return r.first(ctx, "id = ?", id)
+}
+
+func (r *gormSubscriptionRepo) GetByCalendarID(ctx context.Context, calendarID uint) (*calendar.Subscription, error) {
+	return r.first(ctx, "calendar_id = ?", calendarID)
+}
+
+func (r *gormSubscriptionRepo) first(ctx context.Context, query string, args ...interface{}) (*calendar.Subscription, error) {
+	var s calendar.Subscription
+	if err := r.db.WithContext(ctx).Preload("Calendar").Where(query, args...).First(&s).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *gormSubscriptionRepo) ListByUserID(ctx context.Context, userID uint) ([]*calendar.Subscription, error) {
+	var subs []*calendar.Subscription
+	if err := r.db.WithContext(ctx).Preload("Calendar").Where("user_id = ?", userID).Order("id").Find(&subs).Error; err != nil {
+		return nil, err
+	}
+	return subs, nil
+}
+
+func (r *gormSubscriptionRepo) ListDue(ctx context.Context, now time.Time, limit int) ([]*calendar.Subscription, error) {
+	var subs []*calendar.Subscription
+	if err := r.db.WithContext(ctx).Preload("Calendar").Where("next_fetch_at <= ?", now).
+		Order("next_fetch_at").Limit(limit).Find(&subs).Error; err != nil {
+		return nil, err
+	}
+	return subs, nil
+}
+
+func (r *gormSubscriptionRepo) Update(ctx context.Context, s *calendar.Subscription) error {
+	return r.db.WithContext(ctx).Omit("Calendar").Save(s).Error
+}
+
+func (r *gormSubscriptionRepo) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&calendar.Subscription{}, id).Error
+}
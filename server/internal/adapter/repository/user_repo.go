@@ -6,6 +6,8 @@ import (
 
 	"strings"
 
+	"github.com/jherrma/caldav-server/internal/domain/calendar"
+	"github.com/jherrma/caldav-server/internal/domain/sharing"
 	"github.com/jherrma/caldav-server/internal/domain/user"
 	"gorm.io/gorm"
 )
@@ -85,6 +87,24 @@ func (r *gormUserRepo) Delete(ctx context.Context, userID uint) error {
 		if err := tx.Where("user_id = ?", userID).Delete(&user.EmailVerification{}).Error; err != nil {
 			return err
 		}
+		if err := tx.Where("user_id = ?", userID).Delete(&user.GroupMember{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&sharing.Notification{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&sharing.SharedCalendar{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&sharing.SharedAddressBook{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ? OR proxy_id = ?", userID, userID).Delete(&sharing.CalendarProxy{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&calendar.Subscription{}).Error; err != nil {
+			return err
+		}
 
 		// Soft delete user
 		return tx.Delete(&user.User{}, userID).Error
@@ -118,6 +138,14 @@ func (r *gormUserRepo) Count(ctx context.Context) (int64, error) {
 	return count, nil
 }
 
+func (r *gormUserRepo) ListActive(ctx context.Context) ([]user.User, error) {
+	var users []user.User
+	if err := r.db.WithContext(ctx).Where("is_active = ?", true).Order("id ASC").Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
 func (r *gormUserRepo) GetByOAuth(ctx context.Context, provider, providerID string) (*user.User, error) {
 	var conn user.OAuthConnection
 	if err := r.db.WithContext(ctx).Preload("User").
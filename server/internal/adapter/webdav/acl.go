@@ -0,0 +1,266 @@
+package webdav
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/jherrma/caldav-server/internal/domain/calendar"
+	"github.com/jherrma/caldav-server/internal/domain/sharing"
+	"github.com/jherrma/caldav-server/internal/domain/user"
+)
+
+// WebDAV access control (RFC 3744). go-webdav reports every calendar and
+// address book as readable and writable and knows nothing about owners, so
+// the ACL properties are derived here from the calendar permission and the
+// share tables. Only the owner may read the acl of a collection.
+
+var (
+	davCurrentUserPrivilegeSet = xml.Name{Space: "DAV:", Local: "current-user-privilege-set"}
+	davOwner                   = xml.Name{Space: "DAV:", Local: "owner"}
+	davACL                     = xml.Name{Space: "DAV:", Local: "acl"}
+	davSupportedPrivilegeSet   = xml.Name{Space: "DAV:", Local: "supported-privilege-set"}
+	davPrincipalCollectionSet  = xml.Name{Space: "DAV:", Local: "principal-collection-set"}
+)
+
+// supportedPrivilegeSet lists the privileges granted on calendars and
+// address books
+const supportedPrivilegeSet = `<supported-privilege xmlns="DAV:">` +
+	`<privilege xmlns="DAV:"><all xmlns="DAV:"></all></privilege><abstract xmlns="DAV:"></abstract>` +
+	`<description xmlns="DAV:" xml:lang="en">Any operation</description>` +
+	`<supported-privilege xmlns="DAV:"><privilege xmlns="DAV:"><read xmlns="DAV:"></read></privilege>` +
+	`<description xmlns="DAV:" xml:lang="en">Read objects and properties</description></supported-privilege>` +
+	`<supported-privilege xmlns="DAV:"><privilege xmlns="DAV:"><write xmlns="DAV:"></write></privilege>` +
+	`<description xmlns="DAV:" xml:lang="en">Create, change and delete objects</description></supported-privilege>` +
+	`<supported-privilege xmlns="DAV:"><privilege xmlns="DAV:"><read-acl xmlns="DAV:"></read-acl></privilege>` +
+	`<description xmlns="DAV:" xml:lang="en">Read the access control list</description></supported-privilege>` +
+	`<supported-privilege xmlns="DAV:"><privilege xmlns="DAV:"><read-current-user-privilege-set xmlns="DAV:"></read-current-user-privilege-set></privilege>` +
+	`<description xmlns="DAV:" xml:lang="en">Read the current user's privileges</description></supported-privilege>` +
+	`</supported-privilege>`
+
+// davCollection is a calendar or address book as seen by the current user
+type davCollection struct {
+	owner         *user.User // nil for the directory
+	permission    calendar.CalendarPermission
+	calendarID    uint
+	addressBookID uint
+}
+
+// aclGrant is an access control entry for a sharee
+type aclGrant struct {
+	principal  string
+	permission string // sharing.PermissionRead or sharing.PermissionReadWrite
+}
+
+// grantedPrivileges returns the privileges a permission grants
+func grantedPrivileges(perm calendar.CalendarPermission) []string {
+	switch perm {
+	case calendar.PermissionOwner:
+		return []string{"read", "write", "read-acl", "read-current-user-privilege-set"}
+	case calendar.PermissionReadWrite:
+		return []string{"read", "write", "read-current-user-privilege-set"}
+	case calendar.PermissionRead:
+		return []string{"read", "read-current-user-privilege-set"}
+	}
+	return nil
+}
+
+// privilegeElements writes one DAV:privilege element per privilege
+func privilegeElements(privileges ...string) string {
+	var b strings.Builder
+	for _, p := range privileges {
+		fmt.Fprintf(&b, `<privilege xmlns="DAV:"><%s xmlns="DAV:"></%s></privilege>`, p, p)
+	}
+	return b.String()
+}
+
+// hrefElement writes a DAV:href element
+func hrefElement(href string) string {
+	return `<href xmlns="DAV:">` + escapeXML(href) + `</href>`
+}
+
+// escapeXML escapes s for use as character data
+func escapeXML(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// principalURL is the principal of a user, which go-webdav reports as the
+// current-user-principal
+func principalURL(u *user.User) string {
+	return fmt.Sprintf("/dav/%s/", u.Username)
+}
+
+// requestsACLProperties reports whether a PROPFIND body asks for one of
+// the access control properties, explicitly or through allprop
+func requestsACLProperties(body []byte) bool {
+	if len(bytes.TrimSpace(body)) == 0 || bytes.Contains(body, []byte("allprop")) {
+		return true
+	}
+	for _, name := range []xml.Name{davCurrentUserPrivilegeSet, davOwner, davACL, davSupportedPrivilegeSet, davPrincipalCollectionSet} {
+		if bytes.Contains(body, []byte(name.Local)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *Handler) carddavBackend() *CardDAVBackend {
+	return h.carddavHandler.Backend.(*CardDAVBackend)
+}
+
+// resolveCollection returns the calendar or address book at href, or nil
+// for other resources
+func (h *Handler) resolveCollection(ctx context.Context, u *user.User, href string) *davCollection {
+	parts := strings.Split(strings.Trim(href, "/"), "/")
+	if len(parts) != 4 || parts[0] != "dav" || parts[1] != u.Username {
+		return nil
+	}
+
+	switch parts[2] {
+	case "calendars":
+		backend := h.caldavBackend()
+		if backend.isBirthdays(u, href) {
+			return &davCollection{owner: u, permission: calendar.PermissionRead}
+		}
+		cal, _, perm, err := backend.ResolvePath(ctx, href)
+		if err != nil {
+			return nil
+		}
+		owner := u
+		if perm != calendar.PermissionOwner {
+			owner = &cal.Owner
+		}
+		if cal.ReadOnly {
+			perm = calendar.PermissionRead
+		}
+		return &davCollection{owner: owner, permission: perm, calendarID: cal.ID}
+	case "addressbooks":
+		backend := h.carddavBackend()
+		if backend.isDirectory(u, href) {
+			return &davCollection{permission: calendar.PermissionRead}
+		}
+		ab, access, err := backend.resolveAddressBook(ctx, u, href)
+		if err != nil {
+			return nil
+		}
+		col := &davCollection{owner: u, permission: calendar.PermissionOwner, addressBookID: ab.ID}
+		if access != accessOwner {
+			col.owner = &ab.User
+			col.permission = calendar.PermissionRead
+			if access == accessReadWrite {
+				col.permission = calendar.PermissionReadWrite
+			}
+		}
+		return col
+	}
+	return nil
+}
+
+// aclProperty lists the owner and the accepted shares of a collection as
+// access control entries
+func (h *Handler) aclProperty(ctx context.Context, col *davCollection) (string, bool) {
+	var grants []aclGrant
+	add := func(status, permission string, with *user.User, group *user.Group) {
+		if status != sharing.ShareStatusAccepted {
+			return
+		}
+		switch {
+		case with != nil:
+			grants = append(grants, aclGrant{principalURL(with), permission})
+		case group != nil:
+			grants = append(grants, aclGrant{groupPrincipalHref(group), permission})
+		}
+	}
+	switch {
+	case col.calendarID != 0:
+		shares, err := h.caldavBackend().shareRepo.ListByCalendarID(ctx, col.calendarID)
+		if err != nil {
+			return "", false
+		}
+		for _, s := range shares {
+			add(s.Status, s.Permission, s.SharedWith, s.SharedWithGroup)
+		}
+	case col.addressBookID != 0:
+		if h.carddavBackend().shareRepo != nil {
+			shares, err := h.carddavBackend().shareRepo.ListByAddressBookID(ctx, col.addressBookID)
+			if err != nil {
+				return "", false
+			}
+			for _, s := range shares {
+				add(s.Status, s.Permission, s.SharedWith, s.SharedWithGroup)
+			}
+		}
+	default:
+		return "", false
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<ace xmlns="DAV:"><principal xmlns="DAV:">%s</principal><grant xmlns="DAV:">%s</grant><protected xmlns="DAV:"></protected></ace>`,
+		hrefElement(principalURL(col.owner)), privilegeElements("all"))
+	for _, g := range grants {
+		privileges := []string{"read"}
+		if g.permission == sharing.PermissionReadWrite {
+			privileges = append(privileges, "write")
+		}
+		fmt.Fprintf(&b, `<ace xmlns="DAV:"><principal xmlns="DAV:">%s</principal><grant xmlns="DAV:">%s</grant></ace>`,
+			hrefElement(g.principal), privilegeElements(privileges...))
+	}
+	return b.String(), true
+}
+
+// setACLProperties answers the access control properties in a PROPFIND
+// response written by go-webdav. current-user-privilege-set replaces the
+// read and write go-webdav always reports; the others are reported as not
+// found by go-webdav.
+func (h *Handler) setACLProperties(c fiber.Ctx, ctx context.Context, u *user.User) error {
+	if c.Response().StatusCode() != http.StatusMultiStatus {
+		return nil
+	}
+	collections := make(map[string]*davCollection)
+	collection := func(href string) *davCollection {
+		col, ok := collections[href]
+		if !ok {
+			col = h.resolveCollection(ctx, u, href)
+			collections[href] = col
+		}
+		return col
+	}
+
+	body, err := setProperties(c.Response().Body(), func(href string, name xml.Name) (string, bool) {
+		if name == davPrincipalCollectionSet {
+			return principalCollectionSet(), true
+		}
+		col := collection(href)
+		if col == nil {
+			return "", false
+		}
+		switch name {
+		case davCurrentUserPrivilegeSet:
+			return privilegeElements(grantedPrivileges(col.permission)...), true
+		case davSupportedPrivilegeSet:
+			return supportedPrivilegeSet, true
+		case davOwner:
+			if col.owner == nil {
+				return "", false
+			}
+			return hrefElement(principalURL(col.owner)), true
+		case davACL:
+			if col.permission != calendar.PermissionOwner {
+				return "", false
+			}
+			return h.aclProperty(ctx, col)
+		}
+		return "", false
+	})
+	if err != nil {
+		return err
+	}
+	c.Response().SetBody(body)
+	return nil
+}
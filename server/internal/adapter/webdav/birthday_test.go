@@ -0,0 +1,140 @@
+package webdav
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/jherrma/caldav-server/internal/adapter/repository"
+	"github.com/jherrma/caldav-server/internal/domain/addressbook"
+	"github.com/jherrma/caldav-server/internal/domain/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestCalDAVBirthdayCalendar(t *testing.T) {
+	app, db, _ := setupTestApp(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	passwordHash, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.DefaultCost)
+	alice := &user.User{
+		UUID:         "alice-uuid",
+		Email:        "alice@example.com",
+		Username:     "alice",
+		PasswordHash: string(passwordHash),
+		IsActive:     true,
+	}
+	require.NoError(t, repository.NewUserRepository(db.DB()).Create(ctx, alice))
+
+	abRepo := repository.NewAddressBookRepository(db.DB())
+	book := &addressbook.AddressBook{UUID: "ab-uuid", UserID: alice.ID, Path: "contacts", Name: "Contacts"}
+	book.UpdateSyncTokens()
+	require.NoError(t, abRepo.Create(ctx, book))
+
+	authHeader := "Basic " + base64.StdEncoding.EncodeToString([]byte("alice@example.com:password"))
+	calPath := "/dav/alice/calendars/birthdays/"
+
+	send := func(method, target, body string, headers map[string]string) *http.Response {
+		req, _ := http.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Authorization", authHeader)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp
+	}
+	putContact := func(uid, props string) {
+		card := "BEGIN:VCARD\r\nVERSION:3.0\r\nUID:" + uid + "\r\n" + props + "END:VCARD\r\n"
+		resp := send("PUT", "/dav/alice/addressbooks/contacts/"+uid+".vcf", card, map[string]string{"Content-Type": "text/vcard"})
+		require.Contains(t, []int{http.StatusCreated, http.StatusNoContent}, resp.StatusCode)
+	}
+	syncCollection := func(t *testing.T, token string) SyncMultiStatus {
+		t.Helper()
+		body := fmt.Sprintf(`<?xml version="1.0" encoding="utf-8" ?>
+<D:sync-collection xmlns:D="DAV:">
+  <D:sync-token>%s</D:sync-token>
+  <D:sync-level>1</D:sync-level>
+  <D:prop>
+    <D:getetag/>
+  </D:prop>
+</D:sync-collection>`, token)
+		resp := send("REPORT", calPath, body, map[string]string{"Content-Type": "application/xml"})
+		require.Equal(t, http.StatusMultiStatus, resp.StatusCode)
+		var ms SyncMultiStatus
+		require.NoError(t, xml.NewDecoder(resp.Body).Decode(&ms))
+		return ms
+	}
+
+	putContact("carol", "FN:Carol Jones\r\nBDAY:--07-14\r\n")
+
+	t.Run("Listed In Home Set", func(t *testing.T) {
+		body := `<?xml version="1.0" encoding="utf-8" ?>
+<D:propfind xmlns:D="DAV:"><D:prop><D:displayname/></D:prop></D:propfind>`
+		resp := send("PROPFIND", "/dav/alice/calendars/", body, map[string]string{"Depth": "1", "Content-Type": "application/xml"})
+		assert.Equal(t, http.StatusMultiStatus, resp.StatusCode)
+		data, _ := io.ReadAll(resp.Body)
+		assert.Contains(t, string(data), calPath)
+		assert.Contains(t, string(data), "Birthdays")
+	})
+
+	t.Run("Time Range Query", func(t *testing.T) {
+		body := `<?xml version="1.0" encoding="utf-8" ?>
+<C:calendar-query xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+  <D:prop><D:getetag/><C:calendar-data/></D:prop>
+  <C:filter>
+    <C:comp-filter name="VCALENDAR">
+      <C:comp-filter name="VEVENT">
+        <C:time-range start="20260701T000000Z" end="20260801T000000Z"/>
+      </C:comp-filter>
+    </C:comp-filter>
+  </C:filter>
+</C:calendar-query>`
+		resp := send("REPORT", calPath, body, map[string]string{"Depth": "1", "Content-Type": "application/xml"})
+		require.Equal(t, http.StatusMultiStatus, resp.StatusCode)
+		data, _ := io.ReadAll(resp.Body)
+		assert.Contains(t, string(data), "Carol Jones&#39;s birthday")
+	})
+
+	t.Run("Read Only", func(t *testing.T) {
+		event := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:test\r\nBEGIN:VEVENT\r\nUID:x\r\nDTSTAMP:20260101T000000Z\r\nDTSTART:20260101T100000Z\r\nSUMMARY:X\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+		resp := send("PUT", calPath+"x.ics", event, map[string]string{"Content-Type": "text/calendar"})
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+		resp = send("DELETE", calPath+"carol-uuid-birthday.ics", "", nil)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+		resp = send("MKCOL", calPath, "", nil)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+
+	t.Run("Sync Follows Contacts", func(t *testing.T) {
+		initial := syncCollection(t, "")
+		require.Len(t, initial.Responses, 1)
+		href := initial.Responses[0].Href
+		require.NotEmpty(t, initial.SyncToken)
+
+		resp := send("GET", href, "", nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		data, _ := io.ReadAll(resp.Body)
+		assert.Contains(t, string(data), "DTSTART;VALUE=DATE:19720714")
+		assert.Contains(t, string(data), "RRULE:FREQ=YEARLY")
+
+		unchanged := syncCollection(t, initial.SyncToken)
+		assert.Empty(t, unchanged.Responses)
+
+		// A contact written over CardDAV shows up on the next sync
+		putContact("dave", "FN:Dave\r\nBDAY:1985-03-02\r\n")
+		added := syncCollection(t, initial.SyncToken)
+		require.Len(t, added.Responses, 1)
+		assert.Contains(t, added.Responses[0].Href, "birthday.ics")
+		assert.NotEqual(t, href, added.Responses[0].Href)
+	})
+}
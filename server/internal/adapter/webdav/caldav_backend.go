@@ -2,6 +2,7 @@ package webdav
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"path"
@@ -15,6 +16,7 @@ import (
 	"github.com/jherrma/caldav-server/internal/domain/calendar"
 	"github.com/jherrma/caldav-server/internal/domain/sharing"
 	"github.com/jherrma/caldav-server/internal/domain/user"
+	"github.com/jherrma/caldav-server/internal/usecase/birthday"
 )
 
 // CalDAVBackend implements caldav.Backend
@@ -22,17 +24,20 @@ type CalDAVBackend struct {
 	calendarRepo calendar.CalendarRepository
 	userRepo     user.UserRepository
 	shareRepo    sharing.CalendarShareRepository
+	birthdays    *birthday.Service // Optional birthday calendar
 }
 
 func NewCalDAVBackend(
 	calendarRepo calendar.CalendarRepository,
 	userRepo user.UserRepository,
 	shareRepo sharing.CalendarShareRepository,
+	birthdays *birthday.Service,
 ) *CalDAVBackend {
 	return &CalDAVBackend{
 		calendarRepo: calendarRepo,
 		userRepo:     userRepo,
 		shareRepo:    shareRepo,
+		birthdays:    birthdays,
 	}
 }
 
@@ -79,11 +84,20 @@ func (b *CalDAVBackend) ListCalendars(ctx context.Context) ([]caldav.Calendar, e
 		res = append(res, *b.mapCalendar(u.Username, c, calendar.PermissionOwner))
 	}
 	for _, s := range shared {
-		perm := calendar.PermissionRead
-		if s.Permission == "read-write" {
-			perm = calendar.PermissionReadWrite
+		if s.Mount.Hidden {
+			continue
+		}
+		view := s.Mount.Apply(s.Calendar)
+		res = append(res, *b.mapCalendar(u.Username, &view, sharePermission(&s)))
+	}
+	if b.birthdays != nil {
+		settings, err := b.birthdays.Settings(ctx, u.ID)
+		if err != nil {
+			return nil, err
+		}
+		if settings.Enabled() {
+			res = append(res, *b.birthdayCalendar(u.Username, settings))
 		}
-		res = append(res, *b.mapCalendar(u.Username, &s.Calendar, perm))
 	}
 
 	return res, nil
@@ -101,41 +115,29 @@ func (b *CalDAVBackend) GetCalendar(ctx context.Context, p string) (*caldav.Cale
 		return nil, webdav.NewHTTPError(http.StatusNotFound, nil)
 	}
 
-	calPath := parts[3]
-	c, err := b.calendarRepo.GetByPath(ctx, u.ID, calPath)
-
-	// If not found in owned, check shared
-	var perm calendar.CalendarPermission
-	if err != nil || c == nil {
-		// Try to find if it's a shared calendar.
-		// Since GetByPath checks user_id, it won't find shared calendars.
-		// We iterate shared calendars to find a match by path.
-		// Note: Potential name collisions are not yet handled; first match wins.
-		shared, err := b.shareRepo.FindCalendarsSharedWithUser(ctx, u.ID)
+	if b.isBirthdays(u, p) {
+		settings, err := b.birthdays.Settings(ctx, u.ID)
 		if err != nil {
-			return nil, webdav.NewHTTPError(http.StatusNotFound, nil)
-		}
-		found := false
-		for _, s := range shared {
-			if s.Calendar.Path == calPath {
-				c = &s.Calendar
-				if s.Permission == "read-write" {
-					perm = calendar.PermissionReadWrite
-				} else {
-					perm = calendar.PermissionRead
-				}
-				found = true
-				break
-			}
+			return nil, err
 		}
-		if !found {
+		if !settings.Enabled() {
 			return nil, webdav.NewHTTPError(http.StatusNotFound, nil)
 		}
-	} else {
-		perm = calendar.PermissionOwner
+		return b.birthdayCalendar(u.Username, settings), nil
+	}
+
+	calPath := parts[3]
+	if c, err := b.calendarRepo.GetByPath(ctx, u.ID, calPath); err == nil && c != nil {
+		return b.mapCalendar(u.Username, c, calendar.PermissionOwner), nil
 	}
 
-	return b.mapCalendar(u.Username, c, perm), nil
+	// Shared calendars appear under the path they are mounted at
+	share, err := b.findShared(ctx, u, calPath)
+	if err != nil || share == nil {
+		return nil, webdav.NewHTTPError(http.StatusNotFound, nil)
+	}
+	view := share.Mount.Apply(share.Calendar)
+	return b.mapCalendar(u.Username, &view, sharePermission(share)), nil
 }
 
 func (b *CalDAVBackend) CreateCalendar(ctx context.Context, cal *caldav.Calendar) error {
@@ -150,7 +152,17 @@ func (b *CalDAVBackend) CreateCalendar(ctx context.Context, cal *caldav.Calendar
 		return webdav.NewHTTPError(http.StatusForbidden, nil)
 	}
 
+	if b.isBirthdays(u, cal.Path) {
+		return webdav.NewHTTPError(http.StatusForbidden, nil)
+	}
+
 	calPath := parts[3]
+	if share, err := b.findShared(ctx, u, calPath); err != nil {
+		return err
+	} else if share != nil {
+		return webdav.NewHTTPError(http.StatusConflict, fmt.Errorf("a shared calendar is mounted at %s", calPath))
+	}
+
 	c := &calendar.Calendar{
 		UUID:                uuid.New().String(),
 		UserID:              u.ID,
@@ -167,6 +179,14 @@ func (b *CalDAVBackend) CreateCalendar(ctx context.Context, cal *caldav.Calendar
 }
 
 func (b *CalDAVBackend) GetCalendarObject(ctx context.Context, p string, req *caldav.CalendarCompRequest) (*caldav.CalendarObject, error) {
+	if u, ok := UserFromContext(ctx); ok && b.isBirthdays(u, p) {
+		event, err := b.birthdays.Get(ctx, u.ID, path.Base(p))
+		if err != nil {
+			return nil, birthdayError(err)
+		}
+		return b.mapCalendarObject(p, event.CalendarObject())
+	}
+
 	c, _, perm, err := b.ResolvePath(ctx, p)
 	if err != nil {
 		return nil, err
@@ -191,6 +211,10 @@ func (b *CalDAVBackend) GetCalendarObject(ctx context.Context, p string, req *ca
 }
 
 func (b *CalDAVBackend) ListCalendarObjects(ctx context.Context, p string, req *caldav.CalendarCompRequest) ([]caldav.CalendarObject, error) {
+	if u, ok := UserFromContext(ctx); ok && b.isBirthdays(u, p) {
+		return b.listBirthdayObjects(ctx, u, p)
+	}
+
 	// Trim trailing slash for ResolvePath compatibility if needed, or handle it
 	c, _, perm, err := b.ResolvePath(ctx, p)
 	if err != nil {
@@ -237,6 +261,10 @@ func (b *CalDAVBackend) QueryCalendarObjects(ctx context.Context, p string, quer
 }
 
 func (b *CalDAVBackend) PutCalendarObject(ctx context.Context, p string, icalCal *ical.Calendar, opts *caldav.PutCalendarObjectOptions) (*caldav.CalendarObject, error) {
+	if u, ok := UserFromContext(ctx); ok && b.isBirthdays(u, p) {
+		return nil, webdav.NewHTTPError(http.StatusForbidden, errors.New("the birthday calendar is read-only"))
+	}
+
 	c, _, perm, err := b.ResolvePath(ctx, p)
 	if err != nil {
 		return nil, err
@@ -246,6 +274,9 @@ func (b *CalDAVBackend) PutCalendarObject(ctx context.Context, p string, icalCal
 	if perm != calendar.PermissionOwner && perm != calendar.PermissionReadWrite {
 		return nil, webdav.NewHTTPError(http.StatusForbidden, nil)
 	}
+	if c.ReadOnly {
+		return nil, webdav.NewHTTPError(http.StatusForbidden, errors.New("subscribed calendars are read-only"))
+	}
 
 	parts := strings.Split(strings.Trim(p, "/"), "/")
 	objPath := parts[4]
@@ -270,6 +301,11 @@ func (b *CalDAVBackend) PutCalendarObject(ctx context.Context, p string, icalCal
 			}
 		}
 	}
+	if proxy, ok := ProxyFromContext(ctx); ok {
+		if u, ok := UserFromContext(ctx); ok {
+			scheduleOnBehalf(icalCal, u, proxy)
+		}
+	}
 
 	existing, _ := b.calendarRepo.GetCalendarObjectByPath(ctx, c.ID, objPath)
 
@@ -356,6 +392,10 @@ func (b *CalDAVBackend) PutCalendarObject(ctx context.Context, p string, icalCal
 }
 
 func (b *CalDAVBackend) DeleteCalendarObject(ctx context.Context, p string) error {
+	if u, ok := UserFromContext(ctx); ok && b.isBirthdays(u, p) {
+		return webdav.NewHTTPError(http.StatusForbidden, errors.New("the birthday calendar is read-only"))
+	}
+
 	c, _, perm, err := b.ResolvePath(ctx, p)
 	if err != nil {
 		return err
@@ -377,6 +417,9 @@ func (b *CalDAVBackend) DeleteCalendarObject(ctx context.Context, p string) erro
 		}
 		return b.calendarRepo.Delete(ctx, c.ID)
 	}
+	if c.ReadOnly {
+		return webdav.NewHTTPError(http.StatusForbidden, errors.New("subscribed calendars are read-only"))
+	}
 
 	objPath := parts[4]
 
@@ -393,6 +436,13 @@ func (b *CalDAVBackend) DeleteCalendarObject(ctx context.Context, p string) erro
 }
 
 func (b *CalDAVBackend) GetCalendarObjectByPath(ctx context.Context, calendarID uint, path string) (*calendar.CalendarObject, error) {
+	if u, ok := UserFromContext(ctx); ok && calendarID == calendar.BirthdayCalendarID && b.birthdays != nil {
+		event, err := b.birthdays.Get(ctx, u.ID, path)
+		if err != nil {
+			return nil, err
+		}
+		return event.CalendarObject(), nil
+	}
 	return b.calendarRepo.GetCalendarObjectByPath(ctx, calendarID, path)
 }
 
@@ -417,29 +467,44 @@ func (b *CalDAVBackend) ResolvePath(ctx context.Context, p string) (*calendar.Ca
 	}
 
 	// 2. Try shared calendar
-	// We need to fetch ID to check permission, but GetByPath failed for user.
-	// So we need to look up shared calendars by path.
-	// This is inefficient loop, but acceptable for MVP.
-	shared, err := b.shareRepo.FindCalendarsSharedWithUser(ctx, u.ID)
-	if err != nil {
+	share, err := b.findShared(ctx, u, calPath)
+	if err != nil || share == nil {
 		return nil, nil, calendar.PermissionNone, webdav.NewHTTPError(http.StatusNotFound, nil)
 	}
+	return &share.Calendar, u, sharePermission(share), nil
+}
 
-	for _, s := range shared {
-		if s.Calendar.Path == calPath {
-			perm := calendar.PermissionRead
-			if s.Permission == "read-write" {
-				perm = calendar.PermissionReadWrite
-			}
-			// Preload of Calendar is expected in FindCalendarsSharedWithUser
-			return &s.Calendar, u, perm, nil
+// findShared returns the share of the calendar mounted at calPath in the
+// user's calendar home, or nil
+func (b *CalDAVBackend) findShared(ctx context.Context, u *user.User, calPath string) (*sharing.CalendarShare, error) {
+	shared, err := b.shareRepo.FindCalendarsSharedWithUser(ctx, u.ID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range shared {
+		if shared[i].Mount.Path == calPath {
+			return &shared[i], nil
 		}
 	}
+	return nil, nil
+}
 
-	return nil, nil, calendar.PermissionNone, webdav.NewHTTPError(http.StatusNotFound, nil)
+func sharePermission(s *sharing.CalendarShare) calendar.CalendarPermission {
+	if s.Permission == sharing.PermissionReadWrite {
+		return calendar.PermissionReadWrite
+	}
+	return calendar.PermissionRead
 }
 
 func (b *CalDAVBackend) GetSyncChanges(ctx context.Context, calendarPath, token string) ([]*calendar.SyncChangeLog, string, error) {
+	if u, ok := UserFromContext(ctx); ok && b.isBirthdays(u, calendarPath) {
+		changes, newToken, err := b.birthdays.Changes(ctx, u.ID, token)
+		if err != nil {
+			return nil, "", birthdayError(err)
+		}
+		return changes, newToken, nil
+	}
+
 	c, _, perm, err := b.ResolvePath(ctx, calendarPath)
 	if err != nil {
 		return nil, "", err
@@ -457,6 +522,51 @@ func (b *CalDAVBackend) GetSyncChanges(ctx context.Context, calendarPath, token
 	return changes, c.SyncToken, nil
 }
 
+// isBirthdays reports whether p lies within the user's birthday calendar
+func (b *CalDAVBackend) isBirthdays(u *user.User, p string) bool {
+	if b.birthdays == nil {
+		return false
+	}
+	parts := strings.Split(strings.Trim(p, "/"), "/")
+	return len(parts) >= 4 && parts[0] == "dav" && parts[1] == u.Username &&
+		parts[2] == "calendars" && parts[3] == calendar.BirthdayCalendarPath
+}
+
+// birthdayCalendar describes the birthday calendar
+func (b *CalDAVBackend) birthdayCalendar(username string, settings *calendar.BirthdaySettings) *caldav.Calendar {
+	return &caldav.Calendar{
+		Path:                  fmt.Sprintf("/dav/%s/calendars/%s/", username, calendar.BirthdayCalendarPath),
+		Name:                  settings.Name,
+		Description:           "Birthdays and anniversaries of your contacts",
+		SupportedComponentSet: []string{"VEVENT"},
+	}
+}
+
+// listBirthdayObjects returns every birthday event below the calendar path p
+func (b *CalDAVBackend) listBirthdayObjects(ctx context.Context, u *user.User, p string) ([]caldav.CalendarObject, error) {
+	events, _, err := b.birthdays.Events(ctx, u.ID)
+	if err != nil {
+		return nil, birthdayError(err)
+	}
+
+	res := make([]caldav.CalendarObject, 0, len(events))
+	for i := range events {
+		co, err := b.mapCalendarObject(path.Join(p, events[i].Path), events[i].CalendarObject())
+		if err == nil {
+			res = append(res, *co)
+		}
+	}
+	return res, nil
+}
+
+// birthdayError maps birthday calendar lookup failures to WebDAV errors
+func birthdayError(err error) error {
+	if errors.Is(err, birthday.ErrNotFound) || errors.Is(err, birthday.ErrDisabled) {
+		return webdav.NewHTTPError(http.StatusNotFound, err)
+	}
+	return err
+}
+
 func (b *CalDAVBackend) mapCalendar(username string, c *calendar.Calendar, permission calendar.CalendarPermission) *caldav.Calendar {
 	// Set Description
 	desc := c.Description
@@ -156,3 +156,61 @@ END:VCALENDAR`
 		assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
 	})
 }
+
+func TestBasicAuthLockout(t *testing.T) {
+	app, db, _ := setupTestApp(t)
+	defer db.Close()
+
+	userRepo := repository.NewUserRepository(db.DB())
+	credRepo := repository.NewCalDAVCredentialRepository(db.DB())
+	passwordHash, _ := bcrypt.GenerateFromPassword([]byte("primary-pass"), bcrypt.MinCost)
+
+	u := &user.User{
+		UUID:         "lockout-uuid",
+		Email:        "lockout@example.com",
+		Username:     "lockoutuser",
+		PasswordHash: string(passwordHash),
+		IsActive:     true,
+	}
+	require.NoError(t, userRepo.Create(context.Background(), u))
+
+	credHash, _ := bcrypt.GenerateFromPassword([]byte("cred-pass"), bcrypt.MinCost)
+	cred := &user.CalDAVCredential{
+		UUID:         "lockout-cred-uuid",
+		UserID:       u.ID,
+		Name:         "Phone",
+		Username:     "lockout-cred",
+		PasswordHash: string(credHash),
+		Permission:   "read-write",
+		CreatedAt:    time.Now(),
+	}
+	require.NoError(t, credRepo.Create(context.Background(), cred))
+
+	propfind := func(username, password string) *http.Response {
+		req, _ := http.NewRequest("PROPFIND", "/dav/lockoutuser/", nil)
+		req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(username+":"+password)))
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	t.Run("Account is blocked after free attempts", func(t *testing.T) {
+		for i := 0; i < 4; i++ {
+			assert.Equal(t, fiber.StatusUnauthorized, propfind("lockout@example.com", "wrong").StatusCode)
+		}
+
+		// The correct password is rejected while the backoff delay runs
+		resp := propfind("lockout@example.com", "primary-pass")
+		assert.Equal(t, fiber.StatusTooManyRequests, resp.StatusCode)
+		assert.NotEmpty(t, resp.Header.Get("Retry-After"))
+	})
+
+	t.Run("Credential is throttled independently", func(t *testing.T) {
+		assert.Equal(t, fiber.StatusMultiStatus, propfind("lockout-cred", "cred-pass").StatusCode)
+
+		for i := 0; i < 4; i++ {
+			assert.Equal(t, fiber.StatusUnauthorized, propfind("lockout-cred", "wrong").StatusCode)
+		}
+		assert.Equal(t, fiber.StatusTooManyRequests, propfind("lockout-cred", "cred-pass").StatusCode)
+	})
+}
@@ -4,16 +4,26 @@ import (
 	"bytes"
 	"context"
 	"encoding/base64"
+	"io"
+	"log/slog"
 	"net/http"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/gofiber/fiber/v3"
 	authadapter "github.com/jherrma/caldav-server/internal/adapter/auth"
 	"github.com/jherrma/caldav-server/internal/adapter/repository"
 	"github.com/jherrma/caldav-server/internal/config"
+	"github.com/jherrma/caldav-server/internal/domain/calendar"
 	"github.com/jherrma/caldav-server/internal/domain/user"
 	"github.com/jherrma/caldav-server/internal/infrastructure/database"
+	"github.com/jherrma/caldav-server/internal/infrastructure/email"
+	"github.com/jherrma/caldav-server/internal/infrastructure/logging"
+	authusecase "github.com/jherrma/caldav-server/internal/usecase/auth"
+	"github.com/jherrma/caldav-server/internal/usecase/birthday"
+	"github.com/jherrma/caldav-server/internal/usecase/directory"
+	sharinguc "github.com/jherrma/caldav-server/internal/usecase/sharing"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/crypto/bcrypt"
@@ -31,6 +41,21 @@ func setupTestApp(t *testing.T) (*fiber.App, database.Database, *config.Config)
 		JWT: config.JWTConfig{
 			Secret: "test-secret",
 		},
+		Lockout: config.LockoutConfig{
+			Enabled:      true,
+			FreeAttempts: 3,
+			BaseDelay:    time.Minute,
+			MaxAttempts:  5,
+			Duration:     15 * time.Minute,
+			ResetAfter:   time.Hour,
+		},
+		Directory: config.DirectoryConfig{
+			Enabled: true,
+			Name:    "Directory",
+		},
+		Contacts: config.ContactsConfig{
+			DefaultPhoneRegion: "DE",
+		},
 	}
 
 	db, err := database.New(cfg)
@@ -51,13 +76,29 @@ func setupTestApp(t *testing.T) (*fiber.App, database.Database, *config.Config)
 	caldavCredRepo := repository.NewCalDAVCredentialRepository(db.DB())
 	carddavCredRepo := repository.NewCardDAVCredentialRepository(db.DB())
 	jwtManager := authadapter.NewJWTManager(&cfg.JWT)
+	securityLogger := logging.NewSecurityLogger(slog.New(slog.NewJSONHandler(io.Discard, nil)))
+	emailService := email.NewEmailService(cfg.SMTP)
+	lockoutService := authusecase.NewLockoutService(
+		repository.NewLoginThrottleRepository(db.DB()), userRepo, emailService, cfg, securityLogger,
+	)
 
 	shareRepo := repository.NewCalendarShareRepository(db.DB())
 	abShareRepo := repository.NewAddressBookShareRepository(db.DB())
-	caldavBackend := NewCalDAVBackend(calendarRepo, userRepo, shareRepo)
-	addressBookRepo := repository.NewAddressBookRepository(db.DB())
-	carddavBackend := NewCardDAVBackend(addressBookRepo, userRepo, abShareRepo)
-	davHandler := NewHandler(caldavBackend, carddavBackend, userRepo, appPwdRepo, caldavCredRepo, carddavCredRepo, jwtManager)
+	addressBookRepo := repository.NewAddressBookRepository(db.DB(), repository.WithPhoneRegion(cfg.Contacts.DefaultPhoneRegion))
+	caldavBackend := NewCalDAVBackend(calendarRepo, userRepo, shareRepo, birthday.NewService(repository.NewBirthdayRepository(db.DB()), addressBookRepo))
+	carddavBackend := NewCardDAVBackend(addressBookRepo, userRepo, abShareRepo, directory.NewService(repository.NewDirectoryRepository(db.DB()), userRepo, cfg))
+	groupRepo := repository.NewGroupRepository(db.DB())
+	notificationRepo := repository.NewShareNotificationRepository(db.DB())
+	notifier := sharinguc.NewNotifier(notificationRepo, userRepo, emailService, cfg.BaseURL)
+	invitations := sharinguc.NewInvitationsUseCase(shareRepo, abShareRepo, calendarRepo, addressBookRepo, userRepo, notifier)
+	calendarInvites := sharinguc.NewApplyCalendarInvitesUseCase(shareRepo, userRepo,
+		sharinguc.NewCreateCalendarShareUseCase(shareRepo, calendarRepo, userRepo, groupRepo, notifier),
+		sharinguc.NewUpdateCalendarShareUseCase(shareRepo, calendarRepo),
+		sharinguc.NewRevokeCalendarShareUseCase(shareRepo, calendarRepo, notifier),
+	)
+	proxyRepo := repository.NewCalendarProxyRepository(db.DB())
+	calendarProxies := sharinguc.NewCalendarProxiesUseCase(proxyRepo, userRepo)
+	davHandler := NewHandler(caldavBackend, carddavBackend, userRepo, appPwdRepo, caldavCredRepo, carddavCredRepo, jwtManager, lockoutService, nil, notificationRepo, invitations, calendarInvites, groupRepo, proxyRepo, calendarProxies)
 
 	app.Get("/.well-known/caldav", WellKnownCalDAVRedirect)
 	app.Get("/.well-known/carddav", WellKnownCardDAVRedirect)
@@ -148,6 +189,27 @@ END:VCALENDAR`
 		assert.Contains(t, resp.Header.Get("Content-Type"), "text/calendar")
 	})
 
+	t.Run("Read-Only Calendar", func(t *testing.T) {
+		work := db.DB().Model(&calendar.Calendar{}).Where("path = ?", "work")
+		require.NoError(t, work.Update("read_only", true).Error)
+		defer db.DB().Model(&calendar.Calendar{}).Where("path = ?", "work").Update("read_only", false)
+
+		req, _ := http.NewRequest("PUT", "/dav/testuser/calendars/work/event-2.ics", bytes.NewReader([]byte(
+			"BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//CalCard//EN\r\nBEGIN:VEVENT\r\nUID:event-2@example.com\r\n"+
+				"DTSTAMP:20240122T090000Z\r\nDTSTART:20240123T090000Z\r\nSUMMARY:Mine\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n")))
+		req.Header.Set("Authorization", authHeader)
+		req.Header.Set("Content-Type", "text/calendar")
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+
+		req, _ = http.NewRequest("DELETE", "/dav/testuser/calendars/work/event-1.ics", nil)
+		req.Header.Set("Authorization", authHeader)
+		resp, err = app.Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+	})
+
 	t.Run("DELETE Event", func(t *testing.T) {
 		req, _ := http.NewRequest("DELETE", "/dav/testuser/calendars/work/event-1.ics", nil)
 		req.Header.Set("Authorization", authHeader)
@@ -15,6 +15,7 @@ import (
 	"github.com/jherrma/caldav-server/internal/adapter/repository"
 	"github.com/jherrma/caldav-server/internal/config"
 	"github.com/jherrma/caldav-server/internal/domain/calendar"
+	"github.com/jherrma/caldav-server/internal/domain/sharing"
 	"github.com/jherrma/caldav-server/internal/domain/user"
 	"github.com/jherrma/caldav-server/internal/infrastructure/database"
 	sharingUC "github.com/jherrma/caldav-server/internal/usecase/sharing"
@@ -51,17 +52,17 @@ func setupSharingTest(t *testing.T) (*fiber.App, database.Database, *user.User,
 	shareRepo := repository.NewCalendarShareRepository(db.DB())
 	jwtManager := authadapter.NewJWTManager(&config.JWTConfig{Secret: "test-secret"})
 
-	createShareUC := sharingUC.NewCreateCalendarShareUseCase(shareRepo, calendarRepo, userRepo)
+	createShareUC := sharingUC.NewCreateCalendarShareUseCase(shareRepo, calendarRepo, userRepo, repository.NewGroupRepository(db.DB()), nil)
 	listShareUC := sharingUC.NewListCalendarSharesUseCase(shareRepo, calendarRepo)
 	updateShareUC := sharingUC.NewUpdateCalendarShareUseCase(shareRepo, calendarRepo)
-	revokeShareUC := sharingUC.NewRevokeCalendarShareUseCase(shareRepo, calendarRepo)
+	revokeShareUC := sharingUC.NewRevokeCalendarShareUseCase(shareRepo, calendarRepo, nil)
 
 	shareHandler := adapterhttp.NewCalendarShareHandler(createShareUC, listShareUC, updateShareUC, revokeShareUC)
 
 	// Suppress unused variable error if strictly checked
 	_ = shareHandler
 
-	api := app.Group("/api/v1", adapterhttp.Authenticate(jwtManager, userRepo))
+	api := app.Group("/api/v1", adapterhttp.Authenticate(jwtManager, userRepo, nil))
 	api.Post("/calendars/:id/shares", shareHandler.Create)
 	api.Get("/calendars/:id/shares", shareHandler.List)
 	api.Patch("/calendars/:id/shares/:share_id", shareHandler.Update)
@@ -69,7 +70,7 @@ func setupSharingTest(t *testing.T) (*fiber.App, database.Database, *user.User,
 
 	// Setup CalDAV with Sharing
 	// Setup CalDAV with Sharing
-	caldavBackend := NewCalDAVBackend(calendarRepo, userRepo, shareRepo)
+	caldavBackend := NewCalDAVBackend(calendarRepo, userRepo, shareRepo, nil)
 	_ = caldavBackend // Suppress unused
 	// We need to re-register /dav handler to use the new backend with sharing support
 	// But Fiber app is already set up in setupTestApp...
@@ -123,7 +124,7 @@ func TestCalendarSharingIntegration(t *testing.T) {
 	require.NoError(t, userRepo.Create(context.Background(), recipient))
 
 	// Setup Handler
-	createShareUC := sharingUC.NewCreateCalendarShareUseCase(shareRepo, calendarRepo, userRepo)
+	createShareUC := sharingUC.NewCreateCalendarShareUseCase(shareRepo, calendarRepo, userRepo, repository.NewGroupRepository(db.DB()), nil)
 	shareHandler := adapterhttp.NewCalendarShareHandler(createShareUC, nil, nil, nil)
 
 	// We need to inject the handler into the app, but the app is already built in setupTestApp.
@@ -157,17 +158,23 @@ func TestCalendarSharingIntegration(t *testing.T) {
 	shares, err := shareRepo.ListByCalendarID(context.Background(), cal.ID)
 	require.NoError(t, err)
 	require.Len(t, shares, 1)
-	assert.Equal(t, recipient.ID, shares[0].SharedWithID)
+	require.NotNil(t, shares[0].SharedWithID)
+	assert.Equal(t, recipient.ID, *shares[0].SharedWithID)
 	assert.Equal(t, "read-write", shares[0].Permission)
+	assert.Equal(t, sharing.ShareStatusPending, shares[0].Status)
+
+	// The recipient accepts the invitation
+	shares[0].Status = sharing.ShareStatusAccepted
+	require.NoError(t, shareRepo.Update(context.Background(), &shares[0]))
 
 	// 4. Verify CalDAV Access for Recipient
 	// We need the CalDAV backend initialized with shareRepo.
 	// The setupTestApp likely initialized it without shareRepo (old version).
 	// We need to replace the backend or create a new handler.
 
-	caldavBackend := NewCalDAVBackend(calendarRepo, userRepo, shareRepo)
+	caldavBackend := NewCalDAVBackend(calendarRepo, userRepo, shareRepo, nil)
 	// Create a specific handler for this test
-	handler := NewHandler(caldavBackend, nil, userRepo, nil, nil, nil, nil)
+	handler := NewHandler(caldavBackend, nil, userRepo, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
 	_ = handler // Suppress unused
 
 	// We can test the backend methods directly instead of full HTTP stack to be easier
@@ -181,7 +188,7 @@ func TestCalendarSharingIntegration(t *testing.T) {
 	require.NoError(t, err)
 	found := false
 	for _, c := range cals {
-		if strings.Contains(c.Path, "/dav/recipient/calendars/shared-cal/") {
+		if strings.Contains(c.Path, "/dav/recipient/calendars/owner-shared-cal/") {
 			found = true
 			break
 		}
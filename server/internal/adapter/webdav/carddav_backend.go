@@ -1,7 +1,9 @@
 package webdav
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"path"
@@ -14,6 +16,8 @@ import (
 	"github.com/jherrma/caldav-server/internal/domain/addressbook"
 	"github.com/jherrma/caldav-server/internal/domain/sharing"
 	"github.com/jherrma/caldav-server/internal/domain/user"
+	"github.com/jherrma/caldav-server/internal/usecase/contact"
+	"github.com/jherrma/caldav-server/internal/usecase/directory"
 )
 
 // CardDAVBackend implements carddav.Backend
@@ -21,13 +25,15 @@ type CardDAVBackend struct {
 	addressBookRepo addressbook.Repository
 	userRepo        user.UserRepository
 	shareRepo       sharing.AddressBookShareRepository
+	directory       *directory.Service // Optional global address list
 }
 
-func NewCardDAVBackend(addressBookRepo addressbook.Repository, userRepo user.UserRepository, shareRepo sharing.AddressBookShareRepository) *CardDAVBackend {
+func NewCardDAVBackend(addressBookRepo addressbook.Repository, userRepo user.UserRepository, shareRepo sharing.AddressBookShareRepository, dir *directory.Service) *CardDAVBackend {
 	return &CardDAVBackend{
 		addressBookRepo: addressBookRepo,
 		userRepo:        userRepo,
 		shareRepo:       shareRepo,
+		directory:       dir,
 	}
 }
 
@@ -63,20 +69,20 @@ func (b *CardDAVBackend) ListAddressBooks(ctx context.Context) ([]carddav.Addres
 	}
 
 	// 2. Get shared address books
-	var shared []sharing.AddressBookShare
-	if b.shareRepo != nil {
-		shared, err = b.shareRepo.FindAddressBooksSharedWithUser(ctx, u.ID)
-		if err != nil {
-			return nil, err
-		}
+	shared, err := b.sharedAddressBooks(ctx, u)
+	if err != nil {
+		return nil, err
 	}
 
 	res := make([]carddav.AddressBook, 0, len(books)+len(shared))
 	for _, ab := range books {
-		res = append(res, *b.mapAddressBook(u.Username, &ab))
+		res = append(res, *b.mapAddressBook(u.Username, ab.Path, &ab))
 	}
 	for _, s := range shared {
-		res = append(res, *b.mapAddressBook(u.Username, &s.AddressBook))
+		res = append(res, *b.mapAddressBook(u.Username, s.path, &s.share.AddressBook))
+	}
+	if b.directory.Enabled() {
+		res = append(res, *b.directoryAddressBook(u.Username))
 	}
 
 	return res, nil
@@ -89,12 +95,18 @@ func (b *CardDAVBackend) GetAddressBook(ctx context.Context, p string) (*carddav
 		return nil, webdav.NewHTTPError(http.StatusUnauthorized, nil)
 	}
 
-	ab, err := b.resolveAddressBook(ctx, u, p)
+	if b.isDirectory(u, p) {
+		return b.directoryAddressBook(u.Username), nil
+	}
+
+	ab, _, err := b.resolveAddressBook(ctx, u, p)
 	if err != nil {
 		return nil, err
 	}
 
-	return b.mapAddressBook(u.Username, ab), nil
+	// Shared address books appear at their mount path, not the owner's
+	abPath := strings.Split(strings.Trim(p, "/"), "/")[3]
+	return b.mapAddressBook(u.Username, abPath, ab), nil
 }
 
 // CreateAddressBook creates a new address book.
@@ -111,6 +123,12 @@ func (b *CardDAVBackend) CreateAddressBook(ctx context.Context, ab *carddav.Addr
 	}
 
 	abPath := parts[3]
+	if b.isDirectory(u, ab.Path) {
+		return webdav.NewHTTPError(http.StatusForbidden, nil)
+	}
+	if _, access, err := b.resolveAddressBook(ctx, u, ab.Path); err == nil && access != accessOwner {
+		return webdav.NewHTTPError(http.StatusConflict, fmt.Errorf("a shared address book appears at %s", abPath))
+	}
 	newAB := &addressbook.AddressBook{
 		UUID:        uuid.New().String(),
 		UserID:      u.ID,
@@ -130,10 +148,18 @@ func (b *CardDAVBackend) DeleteAddressBook(ctx context.Context, p string) error
 		return webdav.NewHTTPError(http.StatusUnauthorized, nil)
 	}
 
-	ab, err := b.resolveAddressBook(ctx, u, p)
+	if b.isDirectory(u, p) {
+		return webdav.NewHTTPError(http.StatusForbidden, nil)
+	}
+
+	ab, access, err := b.resolveAddressBook(ctx, u, p)
 	if err != nil {
 		return err
 	}
+	// Sharees can never delete the owner's address book
+	if access != accessOwner {
+		return webdav.NewHTTPError(http.StatusForbidden, errors.New("only the owner can delete an address book"))
+	}
 
 	return b.addressBookRepo.Delete(ctx, ab.ID)
 }
@@ -145,12 +171,26 @@ func (b *CardDAVBackend) GetAddressObject(ctx context.Context, p string, req *ca
 		return nil, webdav.NewHTTPError(http.StatusUnauthorized, nil)
 	}
 
-	obj, err := b.resolveAddressObject(ctx, u, p)
+	if b.isDirectory(u, p) {
+		entry, err := b.directory.Get(ctx, strings.TrimSuffix(path.Base(p), ".vcf"))
+		if err != nil {
+			return nil, directoryError(err)
+		}
+		return b.mapAddressObject(ctx, p, entry.AddressObject())
+	}
+
+	obj, _, err := b.resolveAddressObject(ctx, u, p)
 	if err != nil {
 		return nil, err
 	}
 
-	return b.mapAddressObject(p, obj)
+	// Object lists leave out the separately stored photo; reload the
+	// single object so clients get the complete card
+	if full, err := b.addressBookRepo.GetObjectByUUID(ctx, obj.UUID); err == nil && full != nil {
+		obj = full
+	}
+
+	return b.mapAddressObject(ctx, p, obj)
 }
 
 // ListAddressObjects returns all address objects in an address book.
@@ -160,7 +200,11 @@ func (b *CardDAVBackend) ListAddressObjects(ctx context.Context, p string, req *
 		return nil, webdav.NewHTTPError(http.StatusUnauthorized, nil)
 	}
 
-	ab, err := b.resolveAddressBook(ctx, u, p)
+	if b.isDirectory(u, p) {
+		return b.listDirectoryObjects(ctx, p)
+	}
+
+	ab, _, err := b.resolveAddressBook(ctx, u, p)
 	if err != nil {
 		return nil, err
 	}
@@ -175,7 +219,7 @@ func (b *CardDAVBackend) ListAddressObjects(ctx context.Context, p string, req *
 
 	res := make([]carddav.AddressObject, 0, len(objects))
 	for _, obj := range objects {
-		ao, err := b.mapAddressObject(path.Join(p, obj.Path), &obj)
+		ao, err := b.mapAddressObject(ctx, path.Join(p, obj.Path), &obj)
 		if err == nil {
 			res = append(res, *ao)
 		}
@@ -192,7 +236,17 @@ func (b *CardDAVBackend) QueryAddressObjects(ctx context.Context, p string, quer
 		return nil, webdav.NewHTTPError(http.StatusUnauthorized, nil)
 	}
 
-	ab, err := b.resolveAddressBook(ctx, u, p)
+	// The directory is small and not stored as address objects, so its
+	// cards are filtered in memory
+	if b.isDirectory(u, p) {
+		objects, err := b.listDirectoryObjects(ctx, p)
+		if err != nil {
+			return nil, err
+		}
+		return carddav.Filter(query, objects)
+	}
+
+	ab, _, err := b.resolveAddressBook(ctx, u, p)
 	if err != nil {
 		return nil, err
 	}
@@ -207,7 +261,7 @@ func (b *CardDAVBackend) QueryAddressObjects(ctx context.Context, p string, quer
 
 	res := make([]carddav.AddressObject, 0, len(objects))
 	for _, obj := range objects {
-		ao, err := b.mapAddressObject(path.Join(p, obj.Path), &obj)
+		ao, err := b.mapAddressObject(ctx, path.Join(p, obj.Path), &obj)
 		if err != nil {
 			continue
 		}
@@ -268,24 +322,19 @@ func (b *CardDAVBackend) PutAddressObject(ctx context.Context, p string, card vc
 		return nil, webdav.NewHTTPError(http.StatusForbidden, nil)
 	}
 
-	abPath := parts[3]
 	objPath := parts[4]
 
+	if b.isDirectory(u, p) {
+		return nil, webdav.NewHTTPError(http.StatusForbidden, errors.New("the directory is read-only"))
+	}
+
 	// Find the address book
-	books, err := b.addressBookRepo.ListByUserID(ctx, u.ID)
+	ab, access, err := b.resolveAddressBook(ctx, u, p)
 	if err != nil {
 		return nil, err
 	}
-
-	var ab *addressbook.AddressBook
-	for _, book := range books {
-		if book.Path == abPath {
-			ab = &book
-			break
-		}
-	}
-	if ab == nil {
-		return nil, webdav.NewHTTPError(http.StatusNotFound, nil)
+	if access < accessReadWrite {
+		return nil, webdav.NewHTTPError(http.StatusForbidden, errors.New("the address book is shared read-only"))
 	}
 
 	// Extract UID and metadata
@@ -361,7 +410,7 @@ func (b *CardDAVBackend) PutAddressObject(ctx context.Context, p string, card vc
 	// would generate a second, different token and desync the change log
 	// from the address book row.
 
-	return b.mapAddressObject(p, obj)
+	return b.mapAddressObject(ctx, p, obj)
 }
 
 // DeleteAddressObject deletes an address object.
@@ -371,10 +420,17 @@ func (b *CardDAVBackend) DeleteAddressObject(ctx context.Context, p string) erro
 		return webdav.NewHTTPError(http.StatusUnauthorized, nil)
 	}
 
-	obj, err := b.resolveAddressObject(ctx, u, p)
+	if b.isDirectory(u, p) {
+		return webdav.NewHTTPError(http.StatusForbidden, errors.New("the directory is read-only"))
+	}
+
+	obj, access, err := b.resolveAddressObject(ctx, u, p)
 	if err != nil {
 		return err
 	}
+	if access < accessReadWrite {
+		return webdav.NewHTTPError(http.StatusForbidden, errors.New("the address book is shared read-only"))
+	}
 
 	// DeleteObjectByUUID records the change-log entry and advances the
 	// AddressBook sync token in one transaction — no manual post-delete
@@ -383,46 +439,97 @@ func (b *CardDAVBackend) DeleteAddressObject(ctx context.Context, p string) erro
 		return err
 	}
 
-	return nil
+	// Drop the contact from the groups that listed it, as the REST delete does
+	return contact.RemoveFromGroups(ctx, b.addressBookRepo, obj.AddressBookID, obj.UID)
+}
+
+// addressBookAccess is what the current user may do in an address book
+type addressBookAccess int
+
+const (
+	accessRead addressBookAccess = iota + 1
+	accessReadWrite
+	accessOwner
+)
+
+// sharedAddressBook is an address book shared with the current user and the
+// path it appears at in their address book home
+type sharedAddressBook struct {
+	path  string
+	share sharing.AddressBookShare
+}
+
+// sharedAddressBooks returns the address books shared with u at the paths
+// they are mounted at. Mounts are kept, so an address book stays where u's
+// clients found it when other shares come and go.
+func (b *CardDAVBackend) sharedAddressBooks(ctx context.Context, u *user.User) ([]sharedAddressBook, error) {
+	if b.shareRepo == nil {
+		return nil, nil
+	}
+	shares, err := b.shareRepo.FindAddressBooksSharedWithUser(ctx, u.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]sharedAddressBook, 0, len(shares))
+	for _, s := range shares {
+		res = append(res, sharedAddressBook{path: s.Mount.Path, share: s})
+	}
+	return res, nil
 }
 
-// resolveAddressBook parses a path and returns the corresponding address book.
-func (b *CardDAVBackend) resolveAddressBook(ctx context.Context, u *user.User, p string) (*addressbook.AddressBook, error) {
+// resolveAddressBook parses a path and returns the corresponding address
+// book with the user's access to it.
+func (b *CardDAVBackend) resolveAddressBook(ctx context.Context, u *user.User, p string) (*addressbook.AddressBook, addressBookAccess, error) {
 	// Path: /dav/username/addressbooks/abname/
 	parts := strings.Split(strings.Trim(p, "/"), "/")
 	if len(parts) < 4 || parts[0] != "dav" || parts[1] != u.Username || parts[2] != "addressbooks" {
-		return nil, webdav.NewHTTPError(http.StatusNotFound, nil)
+		return nil, 0, webdav.NewHTTPError(http.StatusNotFound, nil)
 	}
 
 	abPath := parts[3]
 	books, err := b.addressBookRepo.ListByUserID(ctx, u.ID)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	for _, ab := range books {
 		if ab.Path == abPath {
-			return &ab, nil
+			return &ab, accessOwner, nil
 		}
 	}
 
-	return nil, webdav.NewHTTPError(http.StatusNotFound, nil)
+	shared, err := b.sharedAddressBooks(ctx, u)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, s := range shared {
+		if s.path == abPath {
+			access := accessRead
+			if s.share.Permission == sharing.PermissionReadWrite {
+				access = accessReadWrite
+			}
+			return &s.share.AddressBook, access, nil
+		}
+	}
+
+	return nil, 0, webdav.NewHTTPError(http.StatusNotFound, nil)
 }
 
-// resolveAddressObject parses a path and returns the corresponding address object.
-func (b *CardDAVBackend) resolveAddressObject(ctx context.Context, u *user.User, p string) (*addressbook.AddressObject, error) {
+// resolveAddressObject parses a path and returns the corresponding address
+// object with the user's access to its address book.
+func (b *CardDAVBackend) resolveAddressObject(ctx context.Context, u *user.User, p string) (*addressbook.AddressObject, addressBookAccess, error) {
 	// Path: /dav/username/addressbooks/abname/contact.vcf
 	parts := strings.Split(strings.Trim(p, "/"), "/")
 	if len(parts) != 5 || parts[0] != "dav" || parts[1] != u.Username || parts[2] != "addressbooks" {
-		return nil, webdav.NewHTTPError(http.StatusNotFound, nil)
+		return nil, 0, webdav.NewHTTPError(http.StatusNotFound, nil)
 	}
 
-	abPath := parts[3]
 	objPath := parts[4]
 
-	ab, err := b.resolveAddressBook(ctx, u, p)
+	ab, access, err := b.resolveAddressBook(ctx, u, p)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	// limit=-1 cancels the LIMIT clause; GORM's Limit(0) would emit LIMIT 0
@@ -430,53 +537,101 @@ func (b *CardDAVBackend) resolveAddressObject(ctx context.Context, u *user.User,
 	// empty address book.
 	objects, _, err := b.addressBookRepo.ListObjects(ctx, ab.ID, -1, 0, "", "")
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	for _, obj := range objects {
 		if obj.Path == objPath {
-			return &obj, nil
-		}
-	}
-
-	// Also check by UUID in case path is different
-	if strings.HasSuffix(abPath, ".vcf") {
-		objUUID := strings.TrimSuffix(abPath, ".vcf")
-		obj, err := b.addressBookRepo.GetObjectByUUID(ctx, objUUID)
-		if err == nil && obj != nil {
-			return obj, nil
+			return &obj, access, nil
 		}
 	}
 
-	return nil, webdav.NewHTTPError(http.StatusNotFound, nil)
+	return nil, 0, webdav.NewHTTPError(http.StatusNotFound, nil)
 }
 
 // mapAddressBook converts domain AddressBook to carddav.AddressBook.
-func (b *CardDAVBackend) mapAddressBook(username string, ab *addressbook.AddressBook) *carddav.AddressBook {
+func (b *CardDAVBackend) mapAddressBook(username, abPath string, ab *addressbook.AddressBook) *carddav.AddressBook {
 	return &carddav.AddressBook{
-		Path:            fmt.Sprintf("/dav/%s/addressbooks/%s/", username, ab.Path),
+		Path:            fmt.Sprintf("/dav/%s/addressbooks/%s/", username, abPath),
 		Name:            ab.Name,
 		Description:     ab.Description,
 		MaxResourceSize: 102400, // 100KB
 		SupportedAddressData: []carddav.AddressDataType{
-			{ContentType: "text/vcard", Version: "3.0"},
-			{ContentType: "text/vcard", Version: "4.0"},
+			{ContentType: vcard.MIMEType, Version: addressbook.VCardVersion3},
+			{ContentType: vcard.MIMEType, Version: addressbook.VCardVersion4},
 		},
 	}
 }
 
-// mapAddressObject converts domain AddressObject to carddav.AddressObject.
-func (b *CardDAVBackend) mapAddressObject(p string, obj *addressbook.AddressObject) (*carddav.AddressObject, error) {
+// isDirectory reports whether p lies within the user's view of the
+// directory address book
+func (b *CardDAVBackend) isDirectory(u *user.User, p string) bool {
+	if !b.directory.Enabled() {
+		return false
+	}
+	parts := strings.Split(strings.Trim(p, "/"), "/")
+	return len(parts) >= 4 && parts[0] == "dav" && parts[1] == u.Username &&
+		parts[2] == "addressbooks" && parts[3] == addressbook.DirectoryPath
+}
+
+// directoryAddressBook describes the directory address book
+func (b *CardDAVBackend) directoryAddressBook(username string) *carddav.AddressBook {
+	return b.mapAddressBook(username, addressbook.DirectoryPath, &addressbook.AddressBook{
+		Path:        addressbook.DirectoryPath,
+		Name:        b.directory.Name(),
+		Description: "Read-only directory of all users",
+	})
+}
+
+// listDirectoryObjects returns every directory card below the directory path p
+func (b *CardDAVBackend) listDirectoryObjects(ctx context.Context, p string) ([]carddav.AddressObject, error) {
+	entries, _, err := b.directory.Entries(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]carddav.AddressObject, 0, len(entries))
+	for i := range entries {
+		ao, err := b.mapAddressObject(ctx, path.Join(p, entries[i].Path()), entries[i].AddressObject())
+		if err == nil {
+			res = append(res, *ao)
+		}
+	}
+	return res, nil
+}
+
+// directoryError maps directory lookup failures to WebDAV errors
+func directoryError(err error) error {
+	if errors.Is(err, directory.ErrNotFound) {
+		return webdav.NewHTTPError(http.StatusNotFound, err)
+	}
+	return err
+}
+
+// mapAddressObject converts domain AddressObject to carddav.AddressObject,
+// converting the card to the vCard version the client asked for.
+func (b *CardDAVBackend) mapAddressObject(ctx context.Context, p string, obj *addressbook.AddressObject) (*carddav.AddressObject, error) {
 	card, err := vcard.NewDecoder(strings.NewReader(obj.VCardData)).Decode()
 	if err != nil {
 		return nil, err
 	}
 
+	if version := VCardVersionFromContext(ctx); version != "" {
+		addressbook.ConvertVCard(card, version)
+	}
+
+	// The stored length goes stale once the photo is injected or the card
+	// is converted, so measure what is actually sent
+	var buf bytes.Buffer
+	if err := vcard.NewEncoder(&buf).Encode(card); err != nil {
+		return nil, err
+	}
+
 	return &carddav.AddressObject{
 		Path:          p,
 		Card:          card,
 		ETag:          obj.ETag,
-		ContentLength: int64(obj.ContentLength),
+		ContentLength: int64(buf.Len()),
 		ModTime:       obj.UpdatedAt,
 	}, nil
 }
@@ -488,7 +643,11 @@ func (b *CardDAVBackend) GetSyncChanges(ctx context.Context, addressBookPath, to
 		return nil, "", fmt.Errorf("unauthorized")
 	}
 
-	ab, err := b.resolveAddressBook(ctx, u, addressBookPath)
+	if b.isDirectory(u, addressBookPath) {
+		return b.directory.Changes(ctx, token)
+	}
+
+	ab, _, err := b.resolveAddressBook(ctx, u, addressBookPath)
 	if err != nil {
 		return nil, "", err
 	}
@@ -503,5 +662,12 @@ func (b *CardDAVBackend) GetSyncChanges(ctx context.Context, addressBookPath, to
 
 // GetAddressObjectByPath returns an address object by its path within an address book.
 func (b *CardDAVBackend) GetAddressObjectByPath(ctx context.Context, addressBookID uint, objPath string) (*addressbook.AddressObject, error) {
+	if addressBookID == addressbook.DirectoryAddressBookID && b.directory.Enabled() {
+		entry, err := b.directory.Get(ctx, strings.TrimSuffix(objPath, ".vcf"))
+		if err != nil {
+			return nil, err
+		}
+		return entry.AddressObject(), nil
+	}
 	return b.addressBookRepo.GetObjectByPath(ctx, addressBookID, objPath)
 }
@@ -0,0 +1,101 @@
+package webdav
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/jherrma/caldav-server/internal/adapter/repository"
+	"github.com/jherrma/caldav-server/internal/domain/addressbook"
+	"github.com/jherrma/caldav-server/internal/domain/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestCardDAVAddressBookQuery(t *testing.T) {
+	app, db, _ := setupTestApp(t)
+	defer db.Close()
+
+	passwordHash, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.DefaultCost)
+	u := &user.User{
+		UUID:         "query-user",
+		Email:        "query@example.com",
+		Username:     "queryuser",
+		PasswordHash: string(passwordHash),
+		IsActive:     true,
+	}
+	require.NoError(t, repository.NewUserRepository(db.DB()).Create(context.Background(), u))
+	require.NoError(t, repository.NewAddressBookRepository(db.DB()).Create(context.Background(), &addressbook.AddressBook{
+		UUID: "query-ab", UserID: u.ID, Name: "Contacts", Path: "contacts",
+	}))
+
+	authHeader := "Basic " + base64.StdEncoding.EncodeToString([]byte("query@example.com:password"))
+	do := func(method, path, body string, headers map[string]string) (*http.Response, string) {
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", authHeader)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		data, _ := io.ReadAll(resp.Body)
+		return resp, string(data)
+	}
+	for uid, props := range map[string]string{
+		"jose": "FN:José Müller\r\nEMAIL:jose@work.example\r\nEMAIL:pepe@home.example\r\nTEL:030/1234567\r\n",
+		"mia":  "FN:Mia Smith\r\nEMAIL:mia@example.com\r\nTEL:+44 20 7946 0000\r\n",
+	} {
+		resp, _ := do("PUT", "/dav/queryuser/addressbooks/contacts/"+uid+".vcf",
+			"BEGIN:VCARD\r\nVERSION:3.0\r\nUID:"+uid+"\r\n"+props+"END:VCARD\r\n", map[string]string{"Content-Type": "text/vcard"})
+		require.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	}
+
+	query := func(t *testing.T, propFilter string) string {
+		t.Helper()
+		body := `<?xml version="1.0" encoding="utf-8" ?>
+<C:addressbook-query xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:carddav">
+  <D:prop><D:getetag/></D:prop>
+  <C:filter>` + propFilter + `</C:filter>
+</C:addressbook-query>`
+		resp, data := do("REPORT", "/dav/queryuser/addressbooks/contacts/", body,
+			map[string]string{"Depth": "1", "Content-Type": "application/xml"})
+		require.Equal(t, http.StatusMultiStatus, resp.StatusCode)
+		return data
+	}
+
+	t.Run("Phone In Another Format", func(t *testing.T) {
+		body := query(t, `<C:prop-filter name="TEL"><C:text-match match-type="equals">+49 30 1234567</C:text-match></C:prop-filter>`)
+		assert.Contains(t, body, "jose.vcf")
+		assert.NotContains(t, body, "mia.vcf")
+	})
+
+	t.Run("Any Email Without Accents", func(t *testing.T) {
+		body := query(t, `<C:prop-filter name="EMAIL"><C:text-match match-type="starts-with">pepe@</C:text-match></C:prop-filter>`)
+		assert.Contains(t, body, "jose.vcf")
+
+		body = query(t, `<C:prop-filter name="FN"><C:text-match>MULLER</C:text-match></C:prop-filter>`)
+		assert.Contains(t, body, "jose.vcf")
+		assert.NotContains(t, body, "mia.vcf")
+	})
+
+	t.Run("Delete Leaves Groups", func(t *testing.T) {
+		resp, _ := do("PUT", "/dav/queryuser/addressbooks/contacts/friends.vcf",
+			"BEGIN:VCARD\r\nVERSION:3.0\r\nUID:friends\r\nFN:Friends\r\nX-ADDRESSBOOKSERVER-KIND:group\r\n"+
+				"X-ADDRESSBOOKSERVER-MEMBER:urn:uuid:jose\r\nX-ADDRESSBOOKSERVER-MEMBER:urn:uuid:mia\r\nEND:VCARD\r\n",
+			map[string]string{"Content-Type": "text/vcard"})
+		require.Equal(t, fiber.StatusCreated, resp.StatusCode)
+
+		resp, _ = do("DELETE", "/dav/queryuser/addressbooks/contacts/jose.vcf", "", nil)
+		require.Equal(t, fiber.StatusNoContent, resp.StatusCode)
+
+		resp, data := do("GET", "/dav/queryuser/addressbooks/contacts/friends.vcf", "", nil)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.NotContains(t, data, "urn:uuid:jose")
+		assert.Contains(t, data, "urn:uuid:mia")
+	})
+}
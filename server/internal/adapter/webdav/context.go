@@ -8,7 +8,11 @@ import (
 
 type contextKey string
 
-const userContextKey contextKey = "user"
+const (
+	userContextKey         contextKey = "user"
+	vcardVersionContextKey contextKey = "vcard_version"
+	proxyContextKey        contextKey = "proxy"
+)
 
 // WithUser adds a user to the context
 func WithUser(ctx context.Context, u *user.User) context.Context {
@@ -20,3 +24,26 @@ func UserFromContext(ctx context.Context) (*user.User, bool) {
 	u, ok := ctx.Value(userContextKey).(*user.User)
 	return u, ok
 }
+
+// WithVCardVersion records the vCard version a CardDAV client asked for
+func WithVCardVersion(ctx context.Context, version string) context.Context {
+	return context.WithValue(ctx, vcardVersionContextKey, version)
+}
+
+// VCardVersionFromContext returns the requested vCard version, or "" when
+// the client has no preference
+func VCardVersionFromContext(ctx context.Context) string {
+	v, _ := ctx.Value(vcardVersionContextKey).(string)
+	return v
+}
+
+// WithProxy records the calendar proxy acting for the user in the context
+func WithProxy(ctx context.Context, proxy *user.User) context.Context {
+	return context.WithValue(ctx, proxyContextKey, proxy)
+}
+
+// ProxyFromContext returns the calendar proxy acting for the user, if any
+func ProxyFromContext(ctx context.Context) (*user.User, bool) {
+	p, ok := ctx.Value(proxyContextKey).(*user.User)
+	return p, ok
+}
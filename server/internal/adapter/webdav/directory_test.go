@@ -0,0 +1,144 @@
+package webdav
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/jherrma/caldav-server/internal/adapter/repository"
+	"github.com/jherrma/caldav-server/internal/domain/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestCardDAVDirectory(t *testing.T) {
+	app, db, _ := setupTestApp(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	userRepo := repository.NewUserRepository(db.DB())
+	passwordHash, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.DefaultCost)
+
+	alice := &user.User{
+		UUID:          "alice-uuid",
+		Email:         "alice@example.com",
+		Username:      "alice",
+		DisplayName:   "Alice Example",
+		JobTitle:      "Engineer",
+		PasswordHash:  string(passwordHash),
+		IsActive:      true,
+		EmailVerified: true,
+	}
+	bob := &user.User{
+		UUID:         "bob-uuid",
+		Email:        "bob@example.com",
+		Username:     "bob",
+		PasswordHash: string(passwordHash),
+		IsActive:     true,
+	}
+	require.NoError(t, userRepo.Create(ctx, alice))
+	require.NoError(t, userRepo.Create(ctx, bob))
+
+	authHeader := "Basic " + base64.StdEncoding.EncodeToString([]byte("alice@example.com:password"))
+	dirPath := "/dav/alice/addressbooks/directory/"
+
+	send := func(method, target, body string, headers map[string]string) *http.Response {
+		req, _ := http.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Authorization", authHeader)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp
+	}
+	syncCollection := func(t *testing.T, token string) SyncMultiStatus {
+		t.Helper()
+		body := fmt.Sprintf(`<?xml version="1.0" encoding="utf-8" ?>
+<D:sync-collection xmlns:D="DAV:">
+  <D:sync-token>%s</D:sync-token>
+  <D:sync-level>1</D:sync-level>
+  <D:prop>
+    <D:getetag/>
+  </D:prop>
+</D:sync-collection>`, token)
+		resp := send("REPORT", dirPath, body, map[string]string{"Content-Type": "application/xml"})
+		require.Equal(t, http.StatusMultiStatus, resp.StatusCode)
+		var ms SyncMultiStatus
+		require.NoError(t, xml.NewDecoder(resp.Body).Decode(&ms))
+		return ms
+	}
+
+	t.Run("Listed In Home Set", func(t *testing.T) {
+		body := `<?xml version="1.0" encoding="utf-8" ?>
+<D:propfind xmlns:D="DAV:"><D:prop><D:displayname/></D:prop></D:propfind>`
+		resp := send("PROPFIND", "/dav/alice/addressbooks/", body, map[string]string{"Depth": "1", "Content-Type": "application/xml"})
+		assert.Equal(t, http.StatusMultiStatus, resp.StatusCode)
+		data, _ := io.ReadAll(resp.Body)
+		assert.Contains(t, string(data), dirPath)
+		assert.Contains(t, string(data), "Directory")
+	})
+
+	t.Run("Get Card", func(t *testing.T) {
+		resp := send("GET", dirPath+"alice-uuid.vcf", "", nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		data, _ := io.ReadAll(resp.Body)
+		assert.Contains(t, string(data), "FN:Alice Example")
+		assert.Contains(t, string(data), "TITLE:Engineer")
+		assert.NotEmpty(t, resp.Header.Get("ETag"))
+
+		// Unverified users are left out by default
+		resp = send("GET", dirPath+"bob-uuid.vcf", "", nil)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("Read Only", func(t *testing.T) {
+		card := "BEGIN:VCARD\r\nVERSION:3.0\r\nUID:x\r\nFN:X\r\nEND:VCARD\r\n"
+		resp := send("PUT", dirPath+"x.vcf", card, map[string]string{"Content-Type": "text/vcard"})
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+		resp = send("DELETE", dirPath+"alice-uuid.vcf", "", nil)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+		resp = send("DELETE", dirPath, "", nil)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+
+	t.Run("Sync Follows Users", func(t *testing.T) {
+		initial := syncCollection(t, "")
+		require.Len(t, initial.Responses, 1)
+		assert.Contains(t, initial.Responses[0].Href, "alice-uuid.vcf")
+		require.NotEmpty(t, initial.SyncToken)
+
+		unchanged := syncCollection(t, initial.SyncToken)
+		assert.Empty(t, unchanged.Responses)
+		assert.Equal(t, initial.SyncToken, unchanged.SyncToken)
+
+		// A newly visible user is reported as a change
+		bob.EmailVerified = true
+		require.NoError(t, userRepo.Update(ctx, bob))
+		added := syncCollection(t, initial.SyncToken)
+		require.Len(t, added.Responses, 1)
+		assert.Contains(t, added.Responses[0].Href, "bob-uuid.vcf")
+		assert.NotEqual(t, initial.SyncToken, added.SyncToken)
+
+		// Hiding a user removes the card
+		bob.DirectoryHidden = true
+		require.NoError(t, userRepo.Update(ctx, bob))
+		removed := syncCollection(t, added.SyncToken)
+		require.Len(t, removed.Responses, 1)
+		assert.Contains(t, removed.Responses[0].Href, "bob-uuid.vcf")
+		assert.Equal(t, "HTTP/1.1 404 Not Found", removed.Responses[0].Status)
+	})
+
+	t.Run("Path Reserved", func(t *testing.T) {
+		resp := send("MKCOL", dirPath, "", nil)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+}
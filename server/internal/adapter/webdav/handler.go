@@ -4,14 +4,21 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/xml"
+	"errors"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/emersion/go-webdav/caldav"
 	"github.com/emersion/go-webdav/carddav"
 	"github.com/gofiber/fiber/v3"
 	"github.com/gofiber/fiber/v3/middleware/adaptor"
+	"github.com/jherrma/caldav-server/internal/domain/oauthserver"
+	"github.com/jherrma/caldav-server/internal/domain/sharing"
 	"github.com/jherrma/caldav-server/internal/domain/user"
+	authusecase "github.com/jherrma/caldav-server/internal/usecase/auth"
+	sharinguc "github.com/jherrma/caldav-server/internal/usecase/sharing"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -23,6 +30,14 @@ type Handler struct {
 	caldavCredRepo  user.CalDAVCredentialRepository
 	carddavCredRepo user.CardDAVCredentialRepository
 	jwtManager      user.TokenProvider
+	lockout         *authusecase.LockoutService
+	oauthTokens     oauthserver.TokenRepository
+	notifications   sharing.NotificationRepository
+	invitations     *sharinguc.InvitationsUseCase
+	calendarInvites *sharinguc.ApplyCalendarInvitesUseCase
+	groups          user.GroupRepository
+	proxies         sharing.CalendarProxyRepository
+	calendarProxies *sharinguc.CalendarProxiesUseCase
 }
 
 func NewHandler(
@@ -33,6 +48,14 @@ func NewHandler(
 	caldavCredRepo user.CalDAVCredentialRepository,
 	carddavCredRepo user.CardDAVCredentialRepository,
 	jwtManager user.TokenProvider,
+	lockout *authusecase.LockoutService,
+	oauthTokens oauthserver.TokenRepository,
+	notifications sharing.NotificationRepository,
+	invitations *sharinguc.InvitationsUseCase,
+	calendarInvites *sharinguc.ApplyCalendarInvitesUseCase,
+	groups user.GroupRepository,
+	proxies sharing.CalendarProxyRepository,
+	calendarProxies *sharinguc.CalendarProxiesUseCase,
 ) *Handler {
 	return &Handler{
 		caldavHandler: &caldav.Handler{
@@ -48,9 +71,27 @@ func NewHandler(
 		caldavCredRepo:  caldavCredRepo,
 		carddavCredRepo: carddavCredRepo,
 		jwtManager:      jwtManager,
+		lockout:         lockout,
+		oauthTokens:     oauthTokens,
+		notifications:   notifications,
+		invitations:     invitations,
+		calendarInvites: calendarInvites,
+		groups:          groups,
+		proxies:         proxies,
+		calendarProxies: calendarProxies,
 	}
 }
 
+// lockedResponse rejects a Basic auth attempt for a locked account or
+// credential. Retry-After lets well-behaved clients back off.
+func lockedResponse(c fiber.Ctx, err error) error {
+	var locked *authusecase.LockedError
+	if errors.As(err, &locked) {
+		c.Set("Retry-After", strconv.Itoa(locked.RetryAfterSeconds()))
+	}
+	return c.SendStatus(fiber.StatusTooManyRequests)
+}
+
 func (h *Handler) Authenticate() fiber.Handler {
 	return func(c fiber.Ctx) error {
 		authHeader := c.Get("Authorization")
@@ -68,6 +109,21 @@ func (h *Handler) Authenticate() fiber.Handler {
 
 		switch strings.ToLower(parts[0]) {
 		case "bearer":
+			if strings.HasPrefix(parts[1], oauthserver.AccessTokenPrefix) {
+				if h.oauthTokens == nil {
+					break
+				}
+				t, err := h.oauthTokens.GetByAccessHash(c.Context(), oauthserver.HashToken(parts[1]))
+				if err != nil || t == nil || !t.AccessActive(time.Now()) {
+					break
+				}
+				if !oauthScopeAllows(t.ScopeList(), c.Method(), c.Path()) {
+					c.Set("WWW-Authenticate", `Bearer error="insufficient_scope"`)
+					return c.SendStatus(fiber.StatusForbidden)
+				}
+				u, _ = h.userRepo.GetByID(c.Context(), t.UserID)
+				break
+			}
 			userUUID, _, err := h.jwtManager.ValidateAccessToken(parts[1])
 			if err == nil {
 				u, _ = h.userRepo.GetByUUID(c.Context(), userUUID)
@@ -85,6 +141,10 @@ func (h *Handler) Authenticate() fiber.Handler {
 			emailOrUsername, password := pair[0], pair[1]
 			u, _ = h.userRepo.GetByEmail(c.Context(), emailOrUsername)
 			if u != nil {
+				subject := authusecase.AccountSubject(u)
+				if err := h.lockout.Check(c.Context(), subject); err != nil {
+					return lockedResponse(c, err)
+				}
 				ap, _ := h.appPwdRepo.FindValidForUser(c.Context(), u.ID, password)
 				if ap == nil {
 					if err := bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)); err != nil {
@@ -92,7 +152,10 @@ func (h *Handler) Authenticate() fiber.Handler {
 					}
 				}
 				if u != nil {
+					h.lockout.RecordSuccess(c.Context(), subject)
 					c.Locals("can_write", true) // Direct user/app password always has write access
+				} else {
+					h.lockout.RecordFailure(c.Context(), subject, c.IP(), c.Get("User-Agent"))
 				}
 			}
 
@@ -101,13 +164,20 @@ func (h *Handler) Authenticate() fiber.Handler {
 				// Try CalDAV credential
 				cred, _ := h.caldavCredRepo.GetByUsername(c.Context(), emailOrUsername)
 				if cred != nil && cred.IsValid() {
+					subject := authusecase.CalDAVCredentialSubject(cred)
+					if err := h.lockout.Check(c.Context(), subject); err != nil {
+						return lockedResponse(c, err)
+					}
 					if err := bcrypt.CompareHashAndPassword([]byte(cred.PasswordHash), []byte(password)); err == nil {
+						h.lockout.RecordSuccess(c.Context(), subject)
 						u, _ = h.userRepo.GetByID(c.Context(), cred.UserID)
 						if u != nil {
 							c.Locals("can_write", cred.CanWrite())
 							c.Locals("caldav_credential_id", cred.ID)
 							go h.caldavCredRepo.UpdateLastUsed(context.Background(), cred.ID, c.IP())
 						}
+					} else {
+						h.lockout.RecordFailure(c.Context(), subject, c.IP(), c.Get("User-Agent"))
 					}
 				}
 
@@ -115,13 +185,20 @@ func (h *Handler) Authenticate() fiber.Handler {
 				if u == nil {
 					cardCred, _ := h.carddavCredRepo.GetByUsername(c.Context(), emailOrUsername)
 					if cardCred != nil && cardCred.IsValid() {
+						subject := authusecase.CardDAVCredentialSubject(cardCred)
+						if err := h.lockout.Check(c.Context(), subject); err != nil {
+							return lockedResponse(c, err)
+						}
 						if err := bcrypt.CompareHashAndPassword([]byte(cardCred.PasswordHash), []byte(password)); err == nil {
+							h.lockout.RecordSuccess(c.Context(), subject)
 							u, _ = h.userRepo.GetByID(c.Context(), cardCred.UserID)
 							if u != nil {
 								c.Locals("can_write", cardCred.CanWrite())
 								c.Locals("carddav_credential_id", cardCred.ID)
 								go h.carddavCredRepo.UpdateLastUsed(context.Background(), cardCred.ID, c.IP())
 							}
+						} else {
+							h.lockout.RecordFailure(c.Context(), subject, c.IP(), c.Get("User-Agent"))
 						}
 					}
 				}
@@ -135,8 +212,7 @@ func (h *Handler) Authenticate() fiber.Handler {
 
 		// Check for write permission on non-safe methods if restricted
 		if canWrite, ok := c.Locals("can_write").(bool); ok && !canWrite {
-			method := c.Method()
-			if method != "GET" && method != "HEAD" && method != "PROPFIND" && method != "REPORT" && method != "OPTIONS" {
+			if !isReadMethod(c.Method()) {
 				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
 					"error":   "forbidden",
 					"message": "This credential has read-only access",
@@ -155,6 +231,17 @@ func (h *Handler) Handler() fiber.Handler {
 		stdCtx := WithUser(c.Context(), u)
 		reqPath := c.Path()
 
+		// Calendar proxies act as the user they are a proxy for in that
+		// user's principal and calendar home
+		principal, handled, err := h.actingPrincipal(c, stdCtx, u)
+		if handled || err != nil {
+			return err
+		}
+		if principal != nil {
+			stdCtx = WithUser(WithProxy(stdCtx, u), principal)
+			u = principal
+		}
+
 		// MKCALENDAR (RFC 4791 §5.3.1) — some clients send this
 		// instead of MKCOL for calendar creation. emersion/go-webdav
 		// only dispatches MKCOL, so normalise here: the caldav backend
@@ -168,6 +255,19 @@ func (h *Handler) Handler() fiber.Handler {
 			c.Request().Header.SetContentLength(0)
 		}
 
+		// Calendar sharing (notification collection, CS:share and
+		// CS:invite-reply) is not part of go-webdav
+		if h.notifications != nil {
+			if handled, err := h.handleSharing(c, stdCtx, u); handled {
+				return err
+			}
+		}
+
+		// Principal collections and principal REPORTs (RFC 3744)
+		if handled, err := h.handlePrincipals(c, stdCtx, u); handled {
+			return err
+		}
+
 		// Handle WebDAV-Sync REPORT for CalDAV
 		if c.Method() == "REPORT" && strings.Contains(reqPath, "/calendars/") {
 			var syncQuery SyncCollectionQuery
@@ -187,6 +287,16 @@ func (h *Handler) Handler() fiber.Handler {
 		// Route to appropriate handler based on path
 		var httpHandler http.Handler
 		if strings.Contains(reqPath, "/addressbooks/") {
+			// go-webdav drops the requested address-data version, so
+			// negotiate it here and let the backend convert
+			version, ok := requestedVCardVersion(c)
+			if !ok {
+				if c.Method() == "REPORT" {
+					return sendUnsupportedAddressData(c)
+				}
+				return c.SendStatus(fiber.StatusNotAcceptable)
+			}
+			stdCtx = WithVCardVersion(stdCtx, version)
 			httpHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 				h.carddavHandler.ServeHTTP(w, r.WithContext(stdCtx))
 			})
@@ -196,7 +306,33 @@ func (h *Handler) Handler() fiber.Handler {
 			})
 		}
 
-		return adaptor.HTTPHandler(httpHandler)(c)
+		if err := adaptor.HTTPHandler(httpHandler)(c); err != nil {
+			return err
+		}
+		if dav := string(c.Response().Header.Peek("DAV")); dav != "" {
+			c.Set("DAV", dav+", access-control")
+		}
+		if c.Method() == "PROPFIND" && requestsACLProperties(c.Body()) {
+			if err := h.setACLProperties(c, stdCtx, u); err != nil {
+				return err
+			}
+		}
+		if c.Method() == "PROPFIND" && requestsProxyProperties(c.Body()) {
+			if err := h.setProxyProperties(c, stdCtx, u); err != nil {
+				return err
+			}
+		}
+		if h.notifications != nil && !strings.Contains(reqPath, "/addressbooks/") {
+			// Advertise calendar sharing and answer the sharing
+			// properties go-webdav reports as not found
+			if dav := string(c.Response().Header.Peek("DAV")); dav != "" {
+				c.Set("DAV", dav+", calendarserver-sharing")
+			}
+			if c.Method() == "PROPFIND" && requestsSharingProperties(c.Body()) {
+				return h.addSharingProperties(c, stdCtx, u)
+			}
+		}
+		return nil
 	}
 }
 
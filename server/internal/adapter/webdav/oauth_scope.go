@@ -0,0 +1,50 @@
+package webdav
+
+import (
+	"strings"
+
+	"github.com/jherrma/caldav-server/internal/domain/oauthserver"
+)
+
+// isReadMethod reports whether a DAV method leaves the collection unchanged
+func isReadMethod(method string) bool {
+	switch method {
+	case "GET", "HEAD", "PROPFIND", "REPORT", "OPTIONS":
+		return true
+	}
+	return false
+}
+
+// oauthScopeAllows reports whether an access token issued by the built-in
+// authorization server with the granted scopes may perform the request.
+// Calendar collections need a calendars scope and address books a contacts
+// scope; the principal and home sets, which clients walk during discovery,
+// are readable with either. Sharing (POSTs of CalendarServer share and
+// invite-reply requests, the notification collection) and proxy group
+// membership stay first-party only, as /shares/ does in the REST API.
+func oauthScopeAllows(granted []string, method, path string) bool {
+	read := isReadMethod(method)
+	p := path + "/"
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	switch {
+	case method == "POST":
+		return false
+	case len(parts) >= 3 && parts[0] == "dav" && parts[2] == notificationCollection:
+		return false
+	case !read && strings.Contains(p, "/calendar-proxy-"):
+		return false
+	case strings.Contains(p, "/calendars/"):
+		if read {
+			return oauthserver.Allows(granted, oauthserver.ScopeCalendarsRead)
+		}
+		return oauthserver.Allows(granted, oauthserver.ScopeCalendarsWrite)
+	case strings.Contains(p, "/addressbooks/"):
+		if read {
+			return oauthserver.Allows(granted, oauthserver.ScopeContactsRead)
+		}
+		return oauthserver.Allows(granted, oauthserver.ScopeContactsWrite)
+	}
+
+	return read && (oauthserver.Allows(granted, oauthserver.ScopeCalendarsRead) ||
+		oauthserver.Allows(granted, oauthserver.ScopeContactsRead))
+}
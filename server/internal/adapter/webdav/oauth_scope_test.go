@@ -0,0 +1,44 @@
+package webdav
+
+import (
+	"testing"
+
+	"github.com/jherrma/caldav-server/internal/domain/oauthserver"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOAuthScopeAllows(t *testing.T) {
+	calRead := oauthserver.ParseScope("calendars:read")
+	calWrite := oauthserver.ParseScope("calendars:write")
+	contactsWrite := oauthserver.ParseScope("contacts:write")
+
+	tests := []struct {
+		name    string
+		granted []string
+		method  string
+		path    string
+		want    bool
+	}{
+		{"calendar read", calRead, "PROPFIND", "/dav/alice/calendars/work/", true},
+		{"calendar write without write scope", calRead, "PUT", "/dav/alice/calendars/work/e.ics", false},
+		{"calendar home without trailing slash", calRead, "PROPFIND", "/dav/alice/calendars", true},
+		{"address book with calendar scope", calRead, "REPORT", "/dav/alice/addressbooks/default/", false},
+		{"address book write", contactsWrite, "DELETE", "/dav/alice/addressbooks/default/c.vcf", true},
+		{"calendar with contacts scope", contactsWrite, "GET", "/dav/alice/calendars/work/e.ics", false},
+		{"principal discovery", contactsWrite, "PROPFIND", "/dav/alice/", true},
+		{"principal write", contactsWrite, "PROPPATCH", "/dav/alice/", false},
+		{"share request", calWrite, "POST", "/dav/alice/calendars/work/", false},
+		{"invite reply", calWrite, "POST", "/dav/alice/calendars/", false},
+		{"notification collection", calWrite, "PROPFIND", "/dav/alice/notifications/", false},
+		{"notification", calWrite, "DELETE", "/dav/alice/notifications/n.xml", false},
+		{"calendar named notifications", calWrite, "PUT", "/dav/alice/calendars/notifications/e.ics", true},
+		{"proxy group membership", calWrite, "PROPPATCH", "/dav/principals/alice/calendar-proxy-write/", false},
+		{"proxy group read", calRead, "PROPFIND", "/dav/principals/alice/calendar-proxy-read/", true},
+		{"no DAV scope", oauthserver.ParseScope("openid profile"), "PROPFIND", "/dav/", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, oauthScopeAllows(tt.granted, tt.method, tt.path))
+		})
+	}
+}
@@ -0,0 +1,488 @@
+package webdav
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/jherrma/caldav-server/internal/domain/addressbook"
+	"github.com/jherrma/caldav-server/internal/domain/sharing"
+	"github.com/jherrma/caldav-server/internal/domain/user"
+)
+
+// Principal collections and the principal REPORTs of RFC 3744. Every user
+// appears at /dav/principals/users/{username}/ and every group the current
+// user may use at /dav/principals/groups/{uuid}/; a user's principal-URL
+// stays /dav/{username}/, where clients discover the home sets. Users the
+// administrators hid from the directory are left out, except for themselves.
+
+const (
+	principalsPath      = "/dav/principals/"
+	userPrincipalsPath  = principalsPath + "users/"
+	groupPrincipalsPath = principalsPath + "groups/"
+	caldavNamespace     = "urn:ietf:params:xml:ns:caldav"
+)
+
+var (
+	davPrincipalPropertySearch    = xml.Name{Space: "DAV:", Local: "principal-property-search"}
+	davPrincipalSearchPropertySet = xml.Name{Space: "DAV:", Local: "principal-search-property-set"}
+	davPrincipalMatch             = xml.Name{Space: "DAV:", Local: "principal-match"}
+	davPrincipalURL               = xml.Name{Space: "DAV:", Local: "principal-URL"}
+	davGroupMemberSet             = xml.Name{Space: "DAV:", Local: "group-member-set"}
+	davGroupMembership            = xml.Name{Space: "DAV:", Local: "group-membership"}
+	calUserAddressSet             = xml.Name{Space: caldavNamespace, Local: "calendar-user-address-set"}
+	calUserType                   = xml.Name{Space: caldavNamespace, Local: "calendar-user-type"}
+	calHomeSet                    = xml.Name{Space: caldavNamespace, Local: "calendar-home-set"}
+	cardHomeSet                   = xml.Name{Space: carddavNamespace, Local: "addressbook-home-set"}
+	csEmailAddressSet             = xml.Name{Space: csNamespace, Local: "email-address-set"}
+)
+
+// principalSearchProperties are the properties principal-property-search
+// matches, with their description
+var principalSearchProperties = []struct {
+	name        xml.Name
+	description string
+}{
+	{davDisplayName, "Display name"},
+	{calUserAddressSet, "Calendar user address"},
+	{csEmailAddressSet, "Email address"},
+}
+
+// principalPropertySearch is the DAV:principal-property-search REPORT body
+type principalPropertySearch struct {
+	XMLName        xml.Name `xml:"DAV: principal-property-search"`
+	Test           string   `xml:"test,attr"`
+	PropertySearch []struct {
+		Prop  Prop   `xml:"DAV: prop"`
+		Match string `xml:"DAV: match"`
+	} `xml:"DAV: property-search"`
+	Prop *Prop `xml:"DAV: prop"`
+}
+
+// principalMatch is the DAV:principal-match REPORT body
+type principalMatch struct {
+	XMLName           xml.Name  `xml:"DAV: principal-match"`
+	Self              *struct{} `xml:"DAV: self"`
+	PrincipalProperty *Prop     `xml:"DAV: principal-property"`
+	Prop              *Prop     `xml:"DAV: prop"`
+}
+
+// davPrincipal is a user or group principal resource
+type davPrincipal struct {
+	href   string
+	props  map[xml.Name]string
+	search map[xml.Name][]string // values principal-property-search matches
+	users  []uint                // the user or the group members
+}
+
+func userPrincipalHref(u *user.User) string {
+	return userPrincipalsPath + u.Username + "/"
+}
+
+func groupPrincipalHref(g *user.Group) string {
+	return groupPrincipalsPath + g.UUID + "/"
+}
+
+// principalCollectionSet is the DAV:principal-collection-set of every resource
+func principalCollectionSet() string {
+	return hrefElement(userPrincipalsPath) + hrefElement(groupPrincipalsPath)
+}
+
+// userDisplayName falls back to the username for users without a display name
+func userDisplayName(u *user.User) string {
+	if u.DisplayName != "" {
+		return u.DisplayName
+	}
+	return u.Username
+}
+
+// handlePrincipals answers requests for the principal collections and the
+// principal REPORTs, which go-webdav does not implement
+func (h *Handler) handlePrincipals(c fiber.Ctx, ctx context.Context, u *user.User) (bool, error) {
+	if strings.HasPrefix(c.Path()+"/", principalsPath) {
+		switch c.Method() {
+		case "PROPFIND":
+			return true, h.propfindPrincipals(c, ctx, u)
+		case "OPTIONS":
+			c.Set("DAV", "1, 3, access-control")
+			c.Set("Allow", "OPTIONS, PROPFIND, PROPPATCH, REPORT")
+			return true, c.SendStatus(fiber.StatusOK)
+		case "PROPPATCH":
+			if level := proxyGroupLevel(u, strings.TrimSuffix(c.Path(), "/")+"/"); level != "" && h.calendarProxies != nil {
+				return true, h.proppatchProxyGroup(c, ctx, u, level)
+			}
+			return true, c.SendStatus(fiber.StatusForbidden)
+		case "REPORT":
+		default:
+			return true, c.SendStatus(fiber.StatusMethodNotAllowed)
+		}
+	}
+	if c.Method() != "REPORT" {
+		return false, nil
+	}
+
+	switch rootElement(c.Body()) {
+	case davPrincipalPropertySearch:
+		return true, h.principalPropertySearch(c, ctx, u)
+	case davPrincipalSearchPropertySet:
+		return true, principalSearchPropertySet(c)
+	case davPrincipalMatch:
+		return true, h.principalMatch(c, ctx, u)
+	case davExpandProperty:
+		return true, h.handleExpandProperty(c, ctx, u)
+	}
+	if strings.HasPrefix(c.Path()+"/", principalsPath) {
+		return true, c.SendStatus(fiber.StatusForbidden)
+	}
+	return false, nil
+}
+
+// visibleUsers returns the active users the current user may look up
+func (h *Handler) visibleUsers(ctx context.Context, u *user.User) ([]user.User, error) {
+	users, err := h.userRepo.ListActive(ctx)
+	if err != nil {
+		return nil, err
+	}
+	visible := users[:0]
+	for _, other := range users {
+		if !other.DirectoryHidden || other.ID == u.ID {
+			visible = append(visible, other)
+		}
+	}
+	return visible, nil
+}
+
+// visibleGroups returns the groups the current user may use
+func (h *Handler) visibleGroups(ctx context.Context, u *user.User) ([]user.Group, error) {
+	if h.groups == nil {
+		return nil, nil
+	}
+	groups, err := h.groups.ListForUser(ctx, u.ID)
+	if err != nil {
+		return nil, err
+	}
+	// Groups the user was only invited to stay out until they join
+	usable := groups[:0]
+	for _, g := range groups {
+		if g.CanUse(u) {
+			usable = append(usable, g)
+		}
+	}
+	return usable, nil
+}
+
+// userPrincipal describes a user to the current user viewer
+func userPrincipal(other, viewer *user.User, groups []user.Group) davPrincipal {
+	var memberships strings.Builder
+	for i := range groups {
+		if groups[i].HasMember(other.ID) {
+			memberships.WriteString(hrefElement(groupPrincipalHref(&groups[i])))
+		}
+	}
+	p := davPrincipal{
+		href: userPrincipalHref(other),
+		props: map[xml.Name]string{
+			davResourceType:           `<principal xmlns="DAV:"></principal>`,
+			davDisplayName:            escapeXML(userDisplayName(other)),
+			davPrincipalURL:           hrefElement(principalURL(other)),
+			calUserAddressSet:         hrefElement("mailto:"+other.Email) + hrefElement(principalURL(other)),
+			calUserType:               "INDIVIDUAL",
+			csEmailAddressSet:         `<email-address xmlns="` + csNamespace + `">` + escapeXML(other.Email) + `</email-address>`,
+			davGroupMembership:        memberships.String(),
+			davPrincipalCollectionSet: principalCollectionSet(),
+		},
+		search: map[xml.Name][]string{
+			davDisplayName:    {userDisplayName(other)},
+			calUserAddressSet: {"mailto:" + other.Email},
+			csEmailAddressSet: {other.Email},
+		},
+		users: []uint{other.ID},
+	}
+	if other.ID == viewer.ID {
+		p.props[calHomeSet] = hrefElement(principalURL(other) + "calendars/")
+		p.props[cardHomeSet] = hrefElement(principalURL(other) + "addressbooks/")
+	}
+	return p
+}
+
+// groupPrincipal describes a group; its members are listed as far as the
+// current user may see them
+func groupPrincipal(g *user.Group, visible map[uint]*user.User) davPrincipal {
+	var members strings.Builder
+	ids := make([]uint, 0, len(g.Members))
+	for _, m := range g.Members {
+		if m.Pending {
+			continue
+		}
+		ids = append(ids, m.UserID)
+		if other, ok := visible[m.UserID]; ok {
+			members.WriteString(hrefElement(principalURL(other)))
+		}
+	}
+	return davPrincipal{
+		href: groupPrincipalHref(g),
+		props: map[xml.Name]string{
+			davResourceType:           `<principal xmlns="DAV:"></principal>`,
+			davDisplayName:            escapeXML(g.Name),
+			davPrincipalURL:           hrefElement(groupPrincipalHref(g)),
+			calUserType:               "GROUP",
+			davGroupMemberSet:         members.String(),
+			davPrincipalCollectionSet: principalCollectionSet(),
+		},
+		search: map[xml.Name][]string{davDisplayName: {g.Name}},
+		users:  ids,
+	}
+}
+
+// principals returns the principals below the principal collection at
+// scope: users, groups or both
+func (h *Handler) principals(ctx context.Context, u *user.User, scope string) ([]davPrincipal, error) {
+	users, err := h.visibleUsers(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := h.visibleGroups(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []davPrincipal
+	if scope != groupPrincipalsPath {
+		for i := range users {
+			p := userPrincipal(&users[i], u, groups)
+			if users[i].ID == u.ID {
+				if err := h.addProxyProperties(ctx, u, &p); err != nil {
+					return nil, err
+				}
+			}
+			res = append(res, p)
+		}
+	}
+	if scope != userPrincipalsPath {
+		visible := make(map[uint]*user.User, len(users))
+		for i := range users {
+			visible[users[i].ID] = &users[i]
+		}
+		for i := range groups {
+			res = append(res, groupPrincipal(&groups[i], visible))
+		}
+	}
+	return res, nil
+}
+
+// principalScope returns the principal collection a request path lies in,
+// or "" outside of them
+func principalScope(p string) string {
+	p = strings.TrimSuffix(p, "/") + "/"
+	for _, scope := range []string{userPrincipalsPath, groupPrincipalsPath} {
+		if strings.HasPrefix(p, scope) {
+			return scope
+		}
+	}
+	return ""
+}
+
+// propfindPrincipals answers PROPFIND on the principal collections and
+// principal resources
+func (h *Handler) propfindPrincipals(c fiber.Ctx, ctx context.Context, u *user.User) error {
+	requested := requestedProps(c.Body())
+	p := strings.TrimSuffix(c.Path(), "/") + "/"
+	collection := func(href, name string) SyncResponse {
+		return propResponse(href, map[xml.Name]string{
+			davResourceType:           `<collection xmlns="DAV:"></collection>`,
+			davDisplayName:            name,
+			davPrincipalCollectionSet: principalCollectionSet(),
+		}, requested)
+	}
+	depth := c.Get("Depth") != "0"
+
+	ms := &davMultiStatus{}
+	switch p {
+	case principalsPath:
+		ms.Responses = append(ms.Responses, collection(principalsPath, "Principals"))
+		if depth {
+			ms.Responses = append(ms.Responses, collection(userPrincipalsPath, "Users"), collection(groupPrincipalsPath, "Groups"))
+		}
+	case userPrincipalsPath, groupPrincipalsPath:
+		name := "Users"
+		if p == groupPrincipalsPath {
+			name = "Groups"
+		}
+		ms.Responses = append(ms.Responses, collection(p, name))
+		if depth {
+			principals, err := h.principals(ctx, u, p)
+			if err != nil {
+				return err
+			}
+			for _, pr := range principals {
+				ms.Responses = append(ms.Responses, propResponse(pr.href, pr.props, requested))
+			}
+		}
+	default:
+		principals, err := h.principals(ctx, u, principalScope(p))
+		if err != nil {
+			return err
+		}
+		for _, pr := range principals {
+			if pr.href == p {
+				ms.Responses = append(ms.Responses, propResponse(pr.href, pr.props, requested))
+			}
+		}
+		// The current user's proxy groups lie below their principal
+		if h.proxies != nil && (p == userPrincipalHref(u) && depth || proxyGroupLevel(u, p) != "") {
+			for _, level := range []string{sharing.ProxyRead, sharing.ProxyWrite} {
+				if p != userPrincipalHref(u) && proxyGroupLevel(u, p) != level {
+					continue
+				}
+				pr, err := h.proxyGroupPrincipal(ctx, u, level)
+				if err != nil {
+					return err
+				}
+				ms.Responses = append(ms.Responses, propResponse(pr.href, pr.props, requested))
+			}
+		}
+		if len(ms.Responses) == 0 {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+	}
+	return sendMultiStatus(c, ms)
+}
+
+// matches reports whether a principal matches a principal-property-search:
+// every property search (test="anyof": any) must find its text in one of
+// the named properties, ignoring case and accents
+func (q *principalPropertySearch) matches(p davPrincipal) bool {
+	anyOf := q.Test == "anyof"
+	for _, ps := range q.PropertySearch {
+		match := addressbook.FoldText(strings.TrimSpace(ps.Match))
+		found := false
+		for _, prop := range ps.Prop.Raw {
+			for _, v := range p.search[prop.XMLName] {
+				if strings.Contains(addressbook.FoldText(v), match) {
+					found = true
+				}
+			}
+		}
+		if found && anyOf {
+			return true
+		}
+		if !found && !anyOf {
+			return false
+		}
+	}
+	return !anyOf && len(q.PropertySearch) > 0
+}
+
+// principalPropertySearch answers the DAV:principal-property-search REPORT,
+// which clients use to look up attendees and sharees. Outside of the
+// principal collections it searches all of them.
+func (h *Handler) principalPropertySearch(c fiber.Ctx, ctx context.Context, u *user.User) error {
+	var q principalPropertySearch
+	if err := xml.Unmarshal(c.Body(), &q); err != nil {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+	var requested []xml.Name
+	if q.Prop != nil {
+		for _, p := range q.Prop.Raw {
+			requested = append(requested, p.XMLName)
+		}
+	}
+
+	principals, err := h.principals(ctx, u, principalScope(c.Path()))
+	if err != nil {
+		return err
+	}
+	ms := &davMultiStatus{Responses: []SyncResponse{}}
+	for _, p := range principals {
+		if q.matches(p) {
+			ms.Responses = append(ms.Responses, propResponse(p.href, p.props, requested))
+		}
+	}
+	return sendMultiStatus(c, ms)
+}
+
+// principalSearchPropertySet answers the DAV:principal-search-property-set
+// REPORT with the properties principal-property-search matches
+func principalSearchPropertySet(c fiber.Ctx) error {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<principal-search-property-set xmlns="DAV:">`)
+	for _, p := range principalSearchProperties {
+		fmt.Fprintf(&b, `<principal-search-property><prop><%s xmlns="%s"/></prop><description xml:lang="en">%s</description></principal-search-property>`,
+			p.name.Local, p.name.Space, p.description)
+	}
+	b.WriteString(`</principal-search-property-set>`)
+	c.Set("Content-Type", "application/xml; charset=utf-8")
+	return c.Status(http.StatusOK).SendString(b.String())
+}
+
+// principalMatch answers the DAV:principal-match REPORT. <self/> matches the
+// current user's principal and the groups they belong to; a principal-property
+// of DAV:owner matches the collections the current user owns in a home set.
+func (h *Handler) principalMatch(c fiber.Ctx, ctx context.Context, u *user.User) error {
+	var q principalMatch
+	if err := xml.Unmarshal(c.Body(), &q); err != nil {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+	var requested []xml.Name
+	if q.Prop != nil {
+		for _, p := range q.Prop.Raw {
+			requested = append(requested, p.XMLName)
+		}
+	}
+
+	ms := &davMultiStatus{Responses: []SyncResponse{}}
+	p := strings.TrimSuffix(c.Path(), "/") + "/"
+	switch {
+	case q.Self != nil && strings.HasPrefix(p, principalsPath):
+		principals, err := h.principals(ctx, u, principalScope(p))
+		if err != nil {
+			return err
+		}
+		for _, pr := range principals {
+			if slices.Contains(pr.users, u.ID) {
+				ms.Responses = append(ms.Responses, propResponse(pr.href, pr.props, requested))
+			}
+		}
+	case q.PrincipalProperty != nil && len(q.PrincipalProperty.Raw) == 1 && q.PrincipalProperty.Raw[0].XMLName == davOwner:
+		hrefs, err := h.ownedCollections(ctx, u, p)
+		if err != nil {
+			return err
+		}
+		for _, href := range hrefs {
+			ms.Responses = append(ms.Responses, propResponse(href, map[xml.Name]string{
+				davOwner: hrefElement(principalURL(u)),
+			}, requested))
+		}
+	}
+	return sendMultiStatus(c, ms)
+}
+
+// ownedCollections lists the calendars or address books the user owns when
+// p is one of their home sets
+func (h *Handler) ownedCollections(ctx context.Context, u *user.User, p string) ([]string, error) {
+	var hrefs []string
+	switch p {
+	case principalURL(u) + "calendars/":
+		cals, err := h.caldavBackend().calendarRepo.ListByUserID(ctx, u.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, cal := range cals {
+			hrefs = append(hrefs, p+cal.Path+"/")
+		}
+	case principalURL(u) + "addressbooks/":
+		books, err := h.carddavBackend().addressBookRepo.ListByUserID(ctx, u.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, ab := range books {
+			hrefs = append(hrefs, p+ab.Path+"/")
+		}
+	}
+	return hrefs, nil
+}
@@ -0,0 +1,174 @@
+package webdav
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/jherrma/caldav-server/internal/adapter/repository"
+	"github.com/jherrma/caldav-server/internal/domain/calendar"
+	"github.com/jherrma/caldav-server/internal/domain/sharing"
+	"github.com/jherrma/caldav-server/internal/domain/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestPrincipalsAndACL(t *testing.T) {
+	app, db, _ := setupTestApp(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	userRepo := repository.NewUserRepository(db.DB())
+	groupRepo := repository.NewGroupRepository(db.DB())
+	passwordHash, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	users := make(map[string]*user.User)
+	for _, u := range []struct {
+		name, displayName string
+		hidden            bool
+	}{
+		{"alice", "Alice Müller", false},
+		{"bob", "Bob Builder", false},
+		{"carol", "Carol", false},
+		{"dave", "Dave Hidden", true},
+	} {
+		account := &user.User{
+			UUID: u.name + "-uuid", Email: u.name + "@example.com", Username: u.name, DisplayName: u.displayName,
+			PasswordHash: string(passwordHash), IsActive: true, DirectoryHidden: u.hidden,
+		}
+		require.NoError(t, userRepo.Create(ctx, account))
+		users[u.name] = account
+	}
+
+	team := &user.Group{UUID: "team-uuid", Name: "Team"}
+	require.NoError(t, groupRepo.Create(ctx, team))
+	require.NoError(t, groupRepo.AddMember(ctx, team.ID, users["alice"].ID, false))
+	require.NoError(t, groupRepo.AddMember(ctx, team.ID, users["bob"].ID, false))
+
+	work := &calendar.Calendar{UUID: "work-uuid", UserID: users["alice"].ID, Name: "Work", Path: "work"}
+	require.NoError(t, repository.NewCalendarRepository(db.DB()).Create(ctx, work))
+	shareRepo := repository.NewCalendarShareRepository(db.DB())
+	require.NoError(t, shareRepo.Create(ctx, &sharing.CalendarShare{
+		UUID: "work-bob", CalendarID: work.ID, SharedWithID: &users["bob"].ID,
+		Permission: sharing.PermissionRead, Status: sharing.ShareStatusAccepted,
+	}))
+	require.NoError(t, shareRepo.Create(ctx, &sharing.CalendarShare{
+		UUID: "work-carol", CalendarID: work.ID, SharedWithID: &users["carol"].ID,
+		Permission: sharing.PermissionRead, Status: sharing.ShareStatusAccepted,
+	}))
+	require.NoError(t, shareRepo.Create(ctx, &sharing.CalendarShare{
+		UUID: "work-team", CalendarID: work.ID, SharedWithGroupID: &team.ID,
+		Permission: sharing.PermissionReadWrite, Status: sharing.ShareStatusAccepted,
+	}))
+
+	dav := func(as, method, target, depth, body string) (int, string) {
+		req, _ := http.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(as+"@example.com:password")))
+		req.Header.Set("Content-Type", "application/xml")
+		req.Header.Set("Depth", depth)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		data, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(data)
+	}
+	hrefs := func(body string) []string {
+		var res []string
+		for _, m := range regexp.MustCompile(`<response[^>]*><href[^>]*>([^<]*)</href>`).FindAllStringSubmatch(body, -1) {
+			res = append(res, m[1])
+		}
+		return res
+	}
+	search := func(prop, match string) []string {
+		status, body := dav("bob", "REPORT", "/dav/principals/", "0", `<?xml version="1.0" encoding="utf-8"?>
+<D:principal-property-search xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+  <D:property-search><D:prop>`+prop+`</D:prop><D:match>`+match+`</D:match></D:property-search>
+  <D:prop><D:displayname/><C:calendar-user-address-set/></D:prop>
+</D:principal-property-search>`)
+		require.Equal(t, http.StatusMultiStatus, status)
+		return hrefs(body)
+	}
+
+	t.Run("Principal Property Search", func(t *testing.T) {
+		assert.Equal(t, []string{"/dav/principals/users/alice/"}, search("<D:displayname/>", "muller"))
+		assert.Equal(t, []string{"/dav/principals/users/bob/"}, search("<C:calendar-user-address-set/>", "BOB@example"))
+		assert.Equal(t, []string{"/dav/principals/groups/team-uuid/"}, search("<D:displayname/>", "tea"))
+		assert.Empty(t, search("<D:displayname/>", "dave"))
+
+		status, body := dav("bob", "REPORT", "/dav/principals/users/", "0", `<?xml version="1.0" encoding="utf-8"?>
+<D:principal-property-search xmlns:D="DAV:" test="anyof">
+  <D:property-search><D:prop><D:displayname/></D:prop><D:match>alice</D:match></D:property-search>
+  <D:property-search><D:prop><D:displayname/></D:prop><D:match>builder</D:match></D:property-search>
+  <D:prop><D:displayname/></D:prop>
+</D:principal-property-search>`)
+		require.Equal(t, http.StatusMultiStatus, status)
+		assert.Equal(t, []string{"/dav/principals/users/alice/", "/dav/principals/users/bob/"}, hrefs(body))
+		assert.Contains(t, body, "Alice Müller")
+
+		status, body = dav("bob", "REPORT", "/dav/principals/", "0",
+			`<?xml version="1.0" encoding="utf-8"?><D:principal-search-property-set xmlns:D="DAV:"/>`)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Contains(t, body, "<displayname")
+	})
+
+	t.Run("Principal Match", func(t *testing.T) {
+		status, body := dav("bob", "REPORT", "/dav/principals/", "0",
+			`<?xml version="1.0" encoding="utf-8"?><D:principal-match xmlns:D="DAV:"><D:self/><D:prop><D:displayname/></D:prop></D:principal-match>`)
+		require.Equal(t, http.StatusMultiStatus, status)
+		assert.Equal(t, []string{"/dav/principals/users/bob/", "/dav/principals/groups/team-uuid/"}, hrefs(body))
+
+		status, body = dav("alice", "REPORT", "/dav/alice/calendars/", "0",
+			`<?xml version="1.0" encoding="utf-8"?><D:principal-match xmlns:D="DAV:"><D:principal-property><D:owner/></D:principal-property></D:principal-match>`)
+		require.Equal(t, http.StatusMultiStatus, status)
+		assert.Contains(t, hrefs(body), "/dav/alice/calendars/work/")
+	})
+
+	t.Run("Principal Collections", func(t *testing.T) {
+		status, body := dav("bob", "PROPFIND", "/dav/principals/users/", "1", "")
+		require.Equal(t, http.StatusMultiStatus, status)
+		assert.Equal(t, []string{"/dav/principals/users/", "/dav/principals/users/alice/", "/dav/principals/users/bob/", "/dav/principals/users/carol/"}, hrefs(body))
+
+		status, body = dav("bob", "PROPFIND", "/dav/principals/users/alice/", "0", "")
+		require.Equal(t, http.StatusMultiStatus, status)
+		assert.Regexp(t, `<principal-URL[^>]*><href[^>]*>/dav/alice/</href>`, body)
+		assert.Contains(t, body, "mailto:alice@example.com")
+		assert.NotContains(t, body, "calendar-home-set")
+
+		status, body = dav("bob", "PROPFIND", "/dav/principals/groups/team-uuid/", "0", "")
+		require.Equal(t, http.StatusMultiStatus, status)
+		assert.Regexp(t, `<group-member-set[^>]*><href[^>]*>/dav/alice/</href><href[^>]*>/dav/bob/</href>`, body)
+
+		status, _ = dav("bob", "PROPFIND", "/dav/principals/users/dave/", "0", "")
+		assert.Equal(t, http.StatusNotFound, status)
+		status, _ = dav("bob", "DELETE", "/dav/principals/users/bob/", "0", "")
+		assert.Equal(t, http.StatusMethodNotAllowed, status)
+	})
+
+	aclProps := `<?xml version="1.0" encoding="utf-8"?><D:propfind xmlns:D="DAV:"><D:prop>
+<D:owner/><D:acl/><D:current-user-privilege-set/><D:principal-collection-set/>
+</D:prop></D:propfind>`
+
+	t.Run("Owner ACL", func(t *testing.T) {
+		status, body := dav("alice", "PROPFIND", "/dav/alice/calendars/work/", "0", aclProps)
+		require.Equal(t, http.StatusMultiStatus, status)
+		assert.Regexp(t, `<owner[^>]*><href[^>]*>/dav/alice/</href></owner>`, body)
+		assert.Regexp(t, `<principal[^>]*><href[^>]*>/dav/bob/</href></principal><grant[^>]*><privilege[^>]*><read[^>]*></read></privilege></grant>`, body)
+		assert.Regexp(t, `<principal[^>]*><href[^>]*>/dav/principals/groups/team-uuid/</href></principal><grant[^>]*><privilege[^>]*><read[^>]*></read></privilege><privilege[^>]*><write`, body)
+		assert.Contains(t, body, "read-acl")
+		assert.Contains(t, body, "/dav/principals/users/")
+	})
+
+	t.Run("Sharee ACL", func(t *testing.T) {
+		status, body := dav("carol", "PROPFIND", "/dav/carol/calendars/alice-work/", "0", aclProps)
+		require.Equal(t, http.StatusMultiStatus, status)
+		assert.Regexp(t, `<owner[^>]*><href[^>]*>/dav/alice/</href></owner>`, body)
+		assert.Regexp(t, `<propstat[^>]*><prop[^>]*><acl[^>]*></acl></prop><status[^>]*>HTTP/1.1 404`, body)
+		privileges := regexp.MustCompile(`<current-user-privilege-set[^>]*>(.*?)</current-user-privilege-set>`).FindStringSubmatch(body)
+		require.NotNil(t, privileges)
+		assert.NotContains(t, privileges[1], "write")
+		assert.NotContains(t, privileges[1], "read-acl")
+	})
+}
@@ -0,0 +1,355 @@
+package webdav
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/emersion/go-ical"
+	"github.com/gofiber/fiber/v3"
+	"github.com/jherrma/caldav-server/internal/domain/sharing"
+	"github.com/jherrma/caldav-server/internal/domain/user"
+)
+
+// Calendar delegation with the CalendarServer proxy model
+// (https://github.com/apple/ccs-calendarserver/blob/master/doc/Extensions/caldav-proxy.txt).
+// A user's proxies are the members of two group principals below their
+// principal, calendar-proxy-read and calendar-proxy-write; the proxies find
+// the users they act for in calendar-proxy-read-for and
+// calendar-proxy-write-for. Requests of a proxy to the principal or the
+// calendar home of such a user are served as that user, so whatever a write
+// proxy does, they do on the user's behalf.
+
+var (
+	csCalendarProxyReadFor  = xml.Name{Space: csNamespace, Local: "calendar-proxy-read-for"}
+	csCalendarProxyWriteFor = xml.Name{Space: csNamespace, Local: "calendar-proxy-write-for"}
+	davExpandProperty       = xml.Name{Space: "DAV:", Local: "expand-property"}
+)
+
+// proxyGroups maps the proxy group path segments to proxy levels
+var proxyGroups = map[string]string{
+	"calendar-proxy-read":  sharing.ProxyRead,
+	"calendar-proxy-write": sharing.ProxyWrite,
+}
+
+func proxyGroupHref(u *user.User, level string) string {
+	return userPrincipalHref(u) + "calendar-proxy-" + level + "/"
+}
+
+// proxyGroupLevel returns the level of the proxy group of u at p, or ""
+func proxyGroupLevel(u *user.User, p string) string {
+	for segment, level := range proxyGroups {
+		if p == userPrincipalHref(u)+segment+"/" {
+			return level
+		}
+	}
+	return ""
+}
+
+// actingPrincipal returns the user whose principal or calendar home the
+// request targets when u is one of their proxies. Read proxies may only
+// read; handled reports that the request was answered.
+func (h *Handler) actingPrincipal(c fiber.Ctx, ctx context.Context, u *user.User) (principal *user.User, handled bool, err error) {
+	if h.proxies == nil {
+		return nil, false, nil
+	}
+	parts := strings.Split(strings.Trim(c.Path(), "/"), "/")
+	if len(parts) < 2 || parts[0] != "dav" || parts[1] == u.Username || parts[1] == "principals" {
+		return nil, false, nil
+	}
+	if len(parts) > 2 && parts[2] != "calendars" {
+		return nil, false, nil
+	}
+
+	other, err := h.userRepo.GetByUsername(ctx, parts[1])
+	if err != nil || other == nil || !other.IsActive {
+		return nil, false, nil
+	}
+	grant, err := h.proxies.Get(ctx, other.ID, u.ID)
+	if err != nil || grant == nil {
+		return nil, false, err
+	}
+	if !grant.CanWrite() && !isReadMethod(c.Method()) {
+		return nil, true, c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error":   "forbidden",
+			"message": "Read proxies cannot change calendars",
+		})
+	}
+	return other, false, nil
+}
+
+// scheduleOnBehalf makes the principal the organizer of events a proxy
+// organizes in the principal's calendars, with the proxy as SENT-BY
+// (RFC 5545 §3.2.18)
+func scheduleOnBehalf(cal *ical.Calendar, principal, proxy *user.User) {
+	proxyAddress := "mailto:" + proxy.Email
+	principalAddress := "mailto:" + principal.Email
+	for _, comp := range cal.Children {
+		if comp.Name != ical.CompEvent && comp.Name != ical.CompToDo {
+			continue
+		}
+		organizer := comp.Props.Get(ical.PropOrganizer)
+		if organizer == nil {
+			continue
+		}
+		switch {
+		case strings.EqualFold(organizer.Value, proxyAddress):
+			organizer.Value = principalAddress
+			organizer.Params.Set(ical.ParamCommonName, userDisplayName(principal))
+		case strings.EqualFold(organizer.Value, principalAddress):
+		default:
+			continue
+		}
+		organizer.Params.Set(ical.ParamSentBy, proxyAddress)
+	}
+}
+
+// delegations returns the grants that let u act for other users
+func (h *Handler) delegations(ctx context.Context, u *user.User) ([]sharing.CalendarProxy, error) {
+	if h.proxies == nil {
+		return nil, nil
+	}
+	return h.proxies.ListForProxy(ctx, u.ID)
+}
+
+// proxyForProperties returns calendar-proxy-read-for and
+// calendar-proxy-write-for of u, and the proxy groups u is a member of
+func proxyForProperties(delegations []sharing.CalendarProxy) (readFor, writeFor, memberships string) {
+	var r, w, m strings.Builder
+	for i := range delegations {
+		d := &delegations[i]
+		if d.CanWrite() {
+			w.WriteString(hrefElement(principalURL(&d.User)))
+		} else {
+			r.WriteString(hrefElement(principalURL(&d.User)))
+		}
+		m.WriteString(hrefElement(proxyGroupHref(&d.User, d.Level)))
+	}
+	return r.String(), w.String(), m.String()
+}
+
+// addProxyProperties adds the users u is a proxy for to the principal of u
+func (h *Handler) addProxyProperties(ctx context.Context, u *user.User, p *davPrincipal) error {
+	delegations, err := h.delegations(ctx, u)
+	if err != nil {
+		return err
+	}
+	readFor, writeFor, memberships := proxyForProperties(delegations)
+	p.props[csCalendarProxyReadFor] = readFor
+	p.props[csCalendarProxyWriteFor] = writeFor
+	p.props[davGroupMembership] += memberships
+	return nil
+}
+
+// proxyGroupPrincipal describes one of the proxy groups of u
+func (h *Handler) proxyGroupPrincipal(ctx context.Context, u *user.User, level string) (davPrincipal, error) {
+	proxies, err := h.proxies.ListByUser(ctx, u.ID)
+	if err != nil {
+		return davPrincipal{}, err
+	}
+	var members strings.Builder
+	var ids []uint
+	for i := range proxies {
+		if proxies[i].Level == level {
+			members.WriteString(hrefElement(principalURL(&proxies[i].Proxy)))
+			ids = append(ids, proxies[i].ProxyID)
+		}
+	}
+	href := proxyGroupHref(u, level)
+	return davPrincipal{
+		href: href,
+		props: map[xml.Name]string{
+			davResourceType:           `<principal xmlns="DAV:"></principal><calendar-proxy-` + level + ` xmlns="` + csNamespace + `"></calendar-proxy-` + level + `>`,
+			davDisplayName:            escapeXML(fmt.Sprintf("%s (calendar proxies, %s)", userDisplayName(u), level)),
+			davPrincipalURL:           hrefElement(href),
+			davGroupMemberSet:         members.String(),
+			davPrincipalCollectionSet: principalCollectionSet(),
+		},
+		users: ids,
+	}, nil
+}
+
+// proppatchProxyGroup replaces the members of one of the current user's
+// proxy groups, which is how clients edit delegates
+func (h *Handler) proppatchProxyGroup(c fiber.Ctx, ctx context.Context, u *user.User, level string) error {
+	var update struct {
+		Set []struct {
+			Members *struct {
+				Hrefs []string `xml:"DAV: href"`
+			} `xml:"DAV: prop>group-member-set"`
+		} `xml:"DAV: set"`
+	}
+	if err := xml.Unmarshal(c.Body(), &update); err != nil || len(update.Set) != 1 || update.Set[0].Members == nil {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+
+	identifiers := make([]string, 0, len(update.Set[0].Members.Hrefs))
+	for _, href := range update.Set[0].Members.Hrefs {
+		identifiers = append(identifiers, shareeIdentifier(href))
+	}
+	resp := SyncResponse{Href: proxyGroupHref(u, level)}
+	status := "HTTP/1.1 200 OK"
+	if err := h.calendarProxies.SetLevel(ctx, u.ID, level, identifiers); err != nil {
+		status = "HTTP/1.1 409 Conflict"
+	}
+	resp.PropStat = []PropStat{{Prop: Prop{Raw: []RawXMLValue{{XMLName: davGroupMemberSet}}}, Status: status}}
+	return sendMultiStatus(c, &davMultiStatus{Responses: []SyncResponse{resp}})
+}
+
+// requestsProxyProperties reports whether a PROPFIND body asks for the
+// proxy-for properties
+func requestsProxyProperties(body []byte) bool {
+	return strings.Contains(string(body), "calendar-proxy-")
+}
+
+// setProxyProperties answers calendar-proxy-read-for and
+// calendar-proxy-write-for on the current user's principal, which go-webdav
+// reports as not found
+func (h *Handler) setProxyProperties(c fiber.Ctx, ctx context.Context, u *user.User) error {
+	if c.Response().StatusCode() != fiber.StatusMultiStatus {
+		return nil
+	}
+	delegations, err := h.delegations(ctx, u)
+	if err != nil {
+		return err
+	}
+	readFor, writeFor, _ := proxyForProperties(delegations)
+
+	body, err := setProperties(c.Response().Body(), func(href string, name xml.Name) (string, bool) {
+		if href != principalURL(u) {
+			return "", false
+		}
+		switch name {
+		case csCalendarProxyReadFor:
+			return readFor, true
+		case csCalendarProxyWriteFor:
+			return writeFor, true
+		}
+		return "", false
+	})
+	if err != nil {
+		return err
+	}
+	c.Response().SetBody(body)
+	return nil
+}
+
+// expandProperty is the DAV:expand-property REPORT body (RFC 3253 §3.8)
+type expandProperty struct {
+	XMLName    xml.Name                 `xml:"DAV: expand-property"`
+	Properties []expandPropertyProperty `xml:"DAV: property"`
+}
+
+type expandPropertyProperty struct {
+	Name       string                   `xml:"name,attr"`
+	Namespace  string                   `xml:"namespace,attr"`
+	Properties []expandPropertyProperty `xml:"DAV: property"`
+}
+
+func (p expandPropertyProperty) xmlName() xml.Name {
+	ns := p.Namespace
+	if ns == "" {
+		ns = "DAV:"
+	}
+	return xml.Name{Space: ns, Local: p.Name}
+}
+
+// principalByHref finds the principal at href as seen by u: a user
+// principal at its principal-URL or in the principal collection, a group or
+// one of u's proxy groups
+func (h *Handler) principalByHref(ctx context.Context, u *user.User, href string) (*davPrincipal, error) {
+	if level := proxyGroupLevel(u, href); level != "" && h.proxies != nil {
+		p, err := h.proxyGroupPrincipal(ctx, u, level)
+		return &p, err
+	}
+	principals, err := h.principals(ctx, u, "")
+	if err != nil {
+		return nil, err
+	}
+	for i := range principals {
+		p := &principals[i]
+		if p.href == href || (strings.HasPrefix(p.href, userPrincipalsPath) && p.props[davPrincipalURL] == hrefElement(href)) {
+			return p, nil
+		}
+	}
+	return nil, nil
+}
+
+// handleExpandProperty answers the expand-property REPORT clients use on
+// principals to list delegates and delegators with their names in one
+// request. Properties holding hrefs of principals are expanded into
+// responses with the nested properties.
+func (h *Handler) handleExpandProperty(c fiber.Ctx, ctx context.Context, u *user.User) error {
+	var q expandProperty
+	if err := xml.Unmarshal(c.Body(), &q); err != nil {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+	href := strings.TrimSuffix(c.Path(), "/") + "/"
+	p, err := h.principalByHref(ctx, u, href)
+	if err != nil {
+		return err
+	}
+	if p == nil {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+
+	resp, err := h.expandResponse(ctx, u, p, q.Properties, 0)
+	if err != nil {
+		return err
+	}
+	return sendMultiStatus(c, &davMultiStatus{Responses: []SyncResponse{resp}})
+}
+
+// expandResponse answers the requested properties of a principal, expanding
+// the hrefs of properties with nested properties
+func (h *Handler) expandResponse(ctx context.Context, u *user.User, p *davPrincipal, properties []expandPropertyProperty, depth int) (SyncResponse, error) {
+	props := make(map[xml.Name]string, len(properties))
+	requested := make([]xml.Name, 0, len(properties))
+	for _, prop := range properties {
+		name := prop.xmlName()
+		requested = append(requested, name)
+		value, ok := p.props[name]
+		if !ok {
+			continue
+		}
+		if len(prop.Properties) == 0 || depth > 0 {
+			props[name] = value
+			continue
+		}
+
+		var expanded strings.Builder
+		for _, target := range hrefValues(value) {
+			tp, err := h.principalByHref(ctx, u, target)
+			if err != nil {
+				return SyncResponse{}, err
+			}
+			if tp == nil {
+				continue
+			}
+			resp, err := h.expandResponse(ctx, u, tp, prop.Properties, depth+1)
+			if err != nil {
+				return SyncResponse{}, err
+			}
+			resp.Href = target
+			data, err := xml.Marshal(resp)
+			if err != nil {
+				return SyncResponse{}, err
+			}
+			expanded.Write(data)
+		}
+		props[name] = expanded.String()
+	}
+	return propResponse(p.href, props, requested), nil
+}
+
+// hrefValues returns the DAV:href values of a property
+func hrefValues(value string) []string {
+	var v struct {
+		Hrefs []string `xml:"DAV: href"`
+	}
+	if err := xml.Unmarshal([]byte("<v>"+value+"</v>"), &v); err != nil {
+		return nil
+	}
+	return v.Hrefs
+}
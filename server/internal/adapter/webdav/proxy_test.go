@@ -0,0 +1,152 @@
+package webdav
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/jherrma/caldav-server/internal/adapter/repository"
+	"github.com/jherrma/caldav-server/internal/domain/calendar"
+	"github.com/jherrma/caldav-server/internal/domain/sharing"
+	"github.com/jherrma/caldav-server/internal/domain/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestCalendarProxies(t *testing.T) {
+	app, db, _ := setupTestApp(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	userRepo := repository.NewUserRepository(db.DB())
+	proxyRepo := repository.NewCalendarProxyRepository(db.DB())
+	passwordHash, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	users := make(map[string]*user.User)
+	for _, name := range []string{"exec", "assistant", "reader", "other"} {
+		account := &user.User{
+			UUID: name + "-uuid", Email: name + "@example.com", Username: name, DisplayName: strings.ToUpper(name[:1]) + name[1:],
+			PasswordHash: string(passwordHash), IsActive: true,
+		}
+		require.NoError(t, userRepo.Create(ctx, account))
+		users[name] = account
+	}
+	require.NoError(t, repository.NewCalendarRepository(db.DB()).Create(ctx, &calendar.Calendar{
+		UUID: "exec-work", UserID: users["exec"].ID, Name: "Work", Path: "work",
+	}))
+	require.NoError(t, proxyRepo.Set(ctx, &sharing.CalendarProxy{
+		UUID: "assistant-proxy", UserID: users["exec"].ID, ProxyID: users["assistant"].ID, Level: sharing.ProxyWrite,
+	}))
+	require.NoError(t, proxyRepo.Set(ctx, &sharing.CalendarProxy{
+		UUID: "reader-proxy", UserID: users["exec"].ID, ProxyID: users["reader"].ID, Level: sharing.ProxyRead,
+	}))
+
+	dav := func(as, method, target, depth, body string) (int, string) {
+		req, _ := http.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(as+"@example.com:password")))
+		req.Header.Set("Content-Type", "application/xml")
+		if method == "PUT" {
+			req.Header.Set("Content-Type", "text/calendar")
+		}
+		if depth != "" {
+			req.Header.Set("Depth", depth)
+		}
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		data, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(data)
+	}
+	event := `BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//Test//EN
+BEGIN:VEVENT
+UID:board-meeting
+DTSTAMP:20240122T090000Z
+DTSTART:20240122T090000Z
+DTEND:20240122T100000Z
+SUMMARY:Board meeting
+ORGANIZER:mailto:assistant@example.com
+ATTENDEE:mailto:other@example.com
+END:VEVENT
+END:VCALENDAR
+`
+
+	t.Run("Write Proxy Schedules On Behalf", func(t *testing.T) {
+		status, _ := dav("assistant", "PUT", "/dav/exec/calendars/work/board.ics", "", event)
+		require.Equal(t, http.StatusCreated, status)
+
+		status, body := dav("exec", "GET", "/dav/exec/calendars/work/board.ics", "", "")
+		require.Equal(t, http.StatusOK, status)
+		assert.Contains(t, body, "SENT-BY=\"mailto:assistant@example.com\"")
+		assert.Contains(t, body, ":mailto:exec@example.com")
+		assert.NotContains(t, body, "ORGANIZER:mailto:assistant@example.com")
+
+		status, body = dav("assistant", "PROPFIND", "/dav/exec/calendars/", "1", "")
+		require.Equal(t, http.StatusMultiStatus, status)
+		assert.Contains(t, body, "/dav/exec/calendars/work/")
+	})
+
+	t.Run("Read Proxy", func(t *testing.T) {
+		status, _ := dav("reader", "GET", "/dav/exec/calendars/work/board.ics", "", "")
+		assert.Equal(t, http.StatusOK, status)
+		status, _ = dav("reader", "PUT", "/dav/exec/calendars/work/other.ics", "", strings.ReplaceAll(event, "board-meeting", "other"))
+		assert.Equal(t, http.StatusForbidden, status)
+		status, _ = dav("reader", "DELETE", "/dav/exec/calendars/work/board.ics", "", "")
+		assert.Equal(t, http.StatusForbidden, status)
+	})
+
+	t.Run("Not A Proxy", func(t *testing.T) {
+		status, _ := dav("other", "GET", "/dav/exec/calendars/work/board.ics", "", "")
+		assert.NotEqual(t, http.StatusOK, status)
+	})
+
+	proxyProps := `<?xml version="1.0" encoding="utf-8"?><D:propfind xmlns:D="DAV:" xmlns:CS="http://calendarserver.org/ns/"><D:prop>
+<CS:calendar-proxy-read-for/><CS:calendar-proxy-write-for/><D:group-membership/>
+</D:prop></D:propfind>`
+
+	t.Run("Proxy For Properties", func(t *testing.T) {
+		status, body := dav("assistant", "PROPFIND", "/dav/assistant/", "0", proxyProps)
+		require.Equal(t, http.StatusMultiStatus, status)
+		assert.Regexp(t, `<calendar-proxy-write-for[^>]*><href[^>]*>/dav/exec/</href></calendar-proxy-write-for>`, body)
+
+		status, body = dav("reader", "PROPFIND", "/dav/principals/users/reader/", "0", proxyProps)
+		require.Equal(t, http.StatusMultiStatus, status)
+		assert.Regexp(t, `<calendar-proxy-read-for[^>]*><href[^>]*>/dav/exec/</href></calendar-proxy-read-for>`, body)
+		assert.Contains(t, body, "/dav/principals/users/exec/calendar-proxy-read/")
+	})
+
+	t.Run("Proxy Groups", func(t *testing.T) {
+		status, body := dav("exec", "PROPFIND", "/dav/principals/users/exec/", "1", "")
+		require.Equal(t, http.StatusMultiStatus, status)
+		assert.Regexp(t, `<response[^>]*><href[^>]*>/dav/principals/users/exec/calendar-proxy-write/</href>`, body)
+		assert.Regexp(t, `<group-member-set[^>]*><href[^>]*>/dav/assistant/</href></group-member-set>`, body)
+
+		status, _ = dav("assistant", "PROPFIND", "/dav/principals/users/exec/calendar-proxy-write/", "0", "")
+		assert.Equal(t, http.StatusNotFound, status)
+
+		status, body = dav("exec", "REPORT", "/dav/principals/users/exec/calendar-proxy-write/", "0", `<?xml version="1.0" encoding="utf-8"?>
+<D:expand-property xmlns:D="DAV:"><D:property name="group-member-set"><D:property name="displayname"/></D:property></D:expand-property>`)
+		require.Equal(t, http.StatusMultiStatus, status)
+		assert.Regexp(t, `<group-member-set[^>]*><response[^>]*><href[^>]*>/dav/assistant/</href>.*Assistant`, body)
+
+		status, _ = dav("exec", "PROPPATCH", "/dav/principals/users/exec/calendar-proxy-write/", "", `<?xml version="1.0" encoding="utf-8"?>
+<D:propertyupdate xmlns:D="DAV:"><D:set><D:prop><D:group-member-set>
+<D:href>/dav/principals/users/other/</D:href><D:href>/dav/assistant/</D:href>
+</D:group-member-set></D:prop></D:set></D:propertyupdate>`)
+		require.Equal(t, http.StatusMultiStatus, status)
+		grant, err := proxyRepo.Get(ctx, users["exec"].ID, users["other"].ID)
+		require.NoError(t, err)
+		require.NotNil(t, grant)
+		assert.Equal(t, sharing.ProxyWrite, grant.Level)
+
+		status, _ = dav("exec", "PROPPATCH", "/dav/principals/users/exec/calendar-proxy-read/", "", `<?xml version="1.0" encoding="utf-8"?>
+<D:propertyupdate xmlns:D="DAV:"><D:set><D:prop><D:group-member-set/></D:prop></D:set></D:propertyupdate>`)
+		require.Equal(t, http.StatusMultiStatus, status)
+		grant, err = proxyRepo.Get(ctx, users["exec"].ID, users["reader"].ID)
+		require.NoError(t, err)
+		assert.Nil(t, grant)
+	})
+}
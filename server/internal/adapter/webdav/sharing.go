@@ -0,0 +1,632 @@
+package webdav
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/jherrma/caldav-server/internal/domain/calendar"
+	"github.com/jherrma/caldav-server/internal/domain/sharing"
+	"github.com/jherrma/caldav-server/internal/domain/user"
+	sharinguc "github.com/jherrma/caldav-server/internal/usecase/sharing"
+)
+
+// Calendar sharing as implemented by Apple's calendar server
+// (https://github.com/apple/ccs-calendarserver/blob/master/doc/Extensions/caldav-sharing.txt):
+// owners POST a CS:share request to a calendar, sharees find invitations in
+// their notification collection and POST a CS:invite-reply.
+
+const csNamespace = "http://calendarserver.org/ns/"
+
+var (
+	csNotificationURL      = xml.Name{Space: csNamespace, Local: "notification-URL"}
+	csInvite               = xml.Name{Space: csNamespace, Local: "invite"}
+	csAllowedSharingModes  = xml.Name{Space: csNamespace, Local: "allowed-sharing-modes"}
+	csNotificationType     = xml.Name{Space: csNamespace, Local: "notificationtype"}
+	davDisplayName         = xml.Name{Space: "DAV:", Local: "displayname"}
+	davResourceType        = xml.Name{Space: "DAV:", Local: "resourcetype"}
+	davGetETag             = xml.Name{Space: "DAV:", Local: "getetag"}
+	davGetContentType      = xml.Name{Space: "DAV:", Local: "getcontenttype"}
+	csInviteReplyElement   = xml.Name{Space: csNamespace, Local: "invite-reply"}
+	csShareElement         = xml.Name{Space: csNamespace, Local: "share"}
+	notificationCollection = "notifications"
+)
+
+// csEmpty is an empty element whose name is chosen at runtime
+type csEmpty struct {
+	XMLName xml.Name
+}
+
+func csElement(local string) csEmpty {
+	return csEmpty{XMLName: xml.Name{Space: csNamespace, Local: local}}
+}
+
+type davHref struct {
+	Href string `xml:"DAV: href"`
+}
+
+type csAccess struct {
+	XMLName xml.Name `xml:"http://calendarserver.org/ns/ access"`
+	Level   csEmpty
+}
+
+type csOrganizer struct {
+	XMLName    xml.Name `xml:"http://calendarserver.org/ns/ organizer"`
+	Href       string   `xml:"DAV: href"`
+	CommonName string   `xml:"http://calendarserver.org/ns/ common-name,omitempty"`
+}
+
+type csUser struct {
+	XMLName    xml.Name `xml:"http://calendarserver.org/ns/ user"`
+	Href       string   `xml:"DAV: href"`
+	CommonName string   `xml:"http://calendarserver.org/ns/ common-name,omitempty"`
+	Status     csEmpty
+	Access     csAccess
+}
+
+type csNotification struct {
+	XMLName xml.Name `xml:"http://calendarserver.org/ns/ notification"`
+	DTStamp string   `xml:"http://calendarserver.org/ns/ dtstamp"`
+	Invite  *csInviteNotification
+	Reply   *csInviteReply
+}
+
+type csInviteNotification struct {
+	XMLName    xml.Name `xml:"http://calendarserver.org/ns/ invite-notification"`
+	SharedType string   `xml:"shared-type,attr"`
+	UID        string   `xml:"http://calendarserver.org/ns/ uid"`
+	Href       string   `xml:"DAV: href"`
+	Status     csEmpty
+	Access     csAccess
+	HostURL    davHref `xml:"http://calendarserver.org/ns/ hosturl"`
+	Organizer  csOrganizer
+	Summary    string `xml:"http://calendarserver.org/ns/ summary,omitempty"`
+}
+
+type csInviteReply struct {
+	XMLName    xml.Name `xml:"http://calendarserver.org/ns/ invite-reply"`
+	SharedType string   `xml:"shared-type,attr"`
+	Href       string   `xml:"DAV: href"`
+	CommonName string   `xml:"http://calendarserver.org/ns/ common-name,omitempty"`
+	Status     csEmpty
+	HostURL    davHref `xml:"http://calendarserver.org/ns/ hosturl"`
+	InReplyTo  string  `xml:"http://calendarserver.org/ns/ in-reply-to"`
+}
+
+// csInviteReplyRequest is the body a sharee POSTs to answer an invitation
+type csInviteReplyRequest struct {
+	XMLName   xml.Name  `xml:"http://calendarserver.org/ns/ invite-reply"`
+	Accepted  *struct{} `xml:"http://calendarserver.org/ns/ invite-accepted"`
+	InReplyTo string    `xml:"http://calendarserver.org/ns/ in-reply-to"`
+}
+
+// csShareRequest is the body an owner POSTs to a calendar to change its sharees
+type csShareRequest struct {
+	XMLName xml.Name `xml:"http://calendarserver.org/ns/ share"`
+	Set     []struct {
+		Href      string    `xml:"DAV: href"`
+		Summary   string    `xml:"http://calendarserver.org/ns/ summary"`
+		ReadWrite *struct{} `xml:"http://calendarserver.org/ns/ read-write"`
+	} `xml:"http://calendarserver.org/ns/ set"`
+	Remove []struct {
+		Href string `xml:"DAV: href"`
+	} `xml:"http://calendarserver.org/ns/ remove"`
+}
+
+type propfindRequest struct {
+	XMLName xml.Name `xml:"DAV: propfind"`
+	Prop    *Prop    `xml:"DAV: prop"`
+}
+
+// davMultiStatus reads and writes PROPFIND multistatus bodies
+type davMultiStatus struct {
+	XMLName   xml.Name       `xml:"DAV: multistatus"`
+	Responses []SyncResponse `xml:"response"`
+}
+
+// shareStatusElement maps a share or notification status to its element
+func shareStatusElement(status string) csEmpty {
+	switch status {
+	case sharing.ShareStatusAccepted:
+		return csElement("invite-accepted")
+	case sharing.ShareStatusDeclined:
+		return csElement("invite-declined")
+	case sharing.NotificationStatusRevoked:
+		return csElement("invite-deleted")
+	default:
+		return csElement("invite-noresponse")
+	}
+}
+
+func accessElement(permission string) csAccess {
+	if permission == sharing.PermissionReadWrite {
+		return csAccess{Level: csElement("read-write")}
+	}
+	return csAccess{Level: csElement("read")}
+}
+
+func mailto(u *user.User) string {
+	return "mailto:" + u.Email
+}
+
+// shareeIdentifier turns a CS:share href (mailto: URI or principal path)
+// into an email address or username
+func shareeIdentifier(href string) string {
+	href = strings.TrimSpace(href)
+	if strings.HasPrefix(strings.ToLower(href), "mailto:") {
+		return href[len("mailto:"):]
+	}
+	parts := strings.Split(strings.Trim(href, "/"), "/")
+	if len(parts) >= 4 && parts[0] == "dav" && parts[1] == "principals" && parts[2] == "users" {
+		return parts[3]
+	}
+	if len(parts) >= 2 && parts[0] == "dav" {
+		return parts[1]
+	}
+	return href
+}
+
+// handleSharing serves the notification collection and the sharing POST
+// requests. handled is false for requests that go to go-webdav.
+func (h *Handler) handleSharing(c fiber.Ctx, ctx context.Context, u *user.User) (bool, error) {
+	parts := strings.Split(strings.Trim(c.Path(), "/"), "/")
+	if len(parts) >= 3 && parts[0] == "dav" && parts[2] == notificationCollection {
+		if parts[1] != u.Username {
+			return true, c.SendStatus(fiber.StatusForbidden)
+		}
+		return true, h.handleNotifications(c, ctx, u, parts[3:])
+	}
+
+	if c.Method() != fiber.MethodPost {
+		return false, nil
+	}
+	switch rootElement(c.Body()) {
+	case csInviteReplyElement:
+		return true, h.handleInviteReply(c, ctx, u)
+	case csShareElement:
+		return true, h.handleShareRequest(c, ctx, u)
+	}
+	return false, nil
+}
+
+func rootElement(body []byte) xml.Name {
+	d := xml.NewDecoder(bytes.NewReader(body))
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return xml.Name{}
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			return start.Name
+		}
+	}
+}
+
+func (h *Handler) handleNotifications(c fiber.Ctx, ctx context.Context, u *user.User, rest []string) error {
+	if len(rest) > 1 {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+
+	if len(rest) == 0 {
+		switch c.Method() {
+		case "PROPFIND":
+			return h.propfindNotifications(c, ctx, u)
+		case fiber.MethodPost:
+			if rootElement(c.Body()) == csInviteReplyElement {
+				return h.handleInviteReply(c, ctx, u)
+			}
+			return c.SendStatus(fiber.StatusBadRequest)
+		case fiber.MethodOptions:
+			return c.SendStatus(fiber.StatusOK)
+		default:
+			return c.SendStatus(fiber.StatusMethodNotAllowed)
+		}
+	}
+
+	n, err := h.notifications.GetByUUID(ctx, u.ID, strings.TrimSuffix(rest[0], ".xml"))
+	if err != nil {
+		return err
+	}
+	if n == nil {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+
+	switch c.Method() {
+	case fiber.MethodGet, fiber.MethodHead:
+		data, err := notificationXML(n, u)
+		if err != nil {
+			return err
+		}
+		c.Set("Content-Type", "application/xml; charset=utf-8")
+		c.Set("ETag", fmt.Sprintf("%q", n.ETag()))
+		return c.Send(data)
+	case "PROPFIND":
+		ms := &davMultiStatus{Responses: []SyncResponse{
+			propResponse(notificationHref(u, n), notificationProps(n), requestedProps(c.Body())),
+		}}
+		return sendMultiStatus(c, ms)
+	case fiber.MethodDelete:
+		if err := h.notifications.Delete(ctx, n.ID); err != nil {
+			return err
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	case fiber.MethodPost:
+		if rootElement(c.Body()) == csInviteReplyElement {
+			return h.handleInviteReply(c, ctx, u)
+		}
+		return c.SendStatus(fiber.StatusBadRequest)
+	default:
+		return c.SendStatus(fiber.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) propfindNotifications(c fiber.Ctx, ctx context.Context, u *user.User) error {
+	requested := requestedProps(c.Body())
+	collection := map[xml.Name]string{
+		davDisplayName:  "Notifications",
+		davResourceType: `<collection xmlns="DAV:"></collection><notification xmlns="` + csNamespace + `"></notification>`,
+	}
+	ms := &davMultiStatus{Responses: []SyncResponse{
+		propResponse(fmt.Sprintf("/dav/%s/%s/", u.Username, notificationCollection), collection, requested),
+	}}
+
+	if c.Get("Depth") != "0" {
+		notifications, err := h.notifications.ListByUser(ctx, u.ID)
+		if err != nil {
+			return err
+		}
+		for i := range notifications {
+			n := &notifications[i]
+			ms.Responses = append(ms.Responses, propResponse(notificationHref(u, n), notificationProps(n), requested))
+		}
+	}
+	return sendMultiStatus(c, ms)
+}
+
+func notificationHref(u *user.User, n *sharing.Notification) string {
+	return fmt.Sprintf("/dav/%s/%s/%s.xml", u.Username, notificationCollection, n.UUID)
+}
+
+func notificationProps(n *sharing.Notification) map[xml.Name]string {
+	return map[xml.Name]string{
+		davResourceType:    "",
+		davGetETag:         fmt.Sprintf("%q", n.ETag()),
+		davGetContentType:  "application/xml",
+		csNotificationType: fmt.Sprintf(`<%s xmlns="%s"></%s>`, n.Type, csNamespace, n.Type),
+	}
+}
+
+// notificationXML renders a notification resource for its recipient
+func notificationXML(n *sharing.Notification, recipient *user.User) ([]byte, error) {
+	doc := csNotification{DTStamp: n.CreatedAt.UTC().Format("20060102T150405Z")}
+	switch n.Type {
+	case sharing.NotificationInviteReply:
+		doc.Reply = &csInviteReply{
+			SharedType: "calendar",
+			Href:       mailto(&n.FromUser),
+			CommonName: n.FromUser.DisplayName,
+			Status:     shareStatusElement(n.Status),
+			HostURL:    davHref{Href: n.CalendarPath},
+			InReplyTo:  n.ShareUUID,
+		}
+	default:
+		doc.Invite = &csInviteNotification{
+			SharedType: "calendar",
+			UID:        n.ShareUUID,
+			Href:       mailto(recipient),
+			Status:     shareStatusElement(n.Status),
+			Access:     accessElement(n.Permission),
+			HostURL:    davHref{Href: n.CalendarPath},
+			Organizer:  csOrganizer{Href: mailto(&n.FromUser), CommonName: n.FromUser.DisplayName},
+			Summary:    n.Summary,
+		}
+	}
+	data, err := xml.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+func (h *Handler) handleInviteReply(c fiber.Ctx, ctx context.Context, u *user.User) error {
+	var req csInviteReplyRequest
+	if err := xml.Unmarshal(c.Body(), &req); err != nil || req.InReplyTo == "" {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+
+	accept := req.Accepted != nil
+	invitation, err := h.invitations.Respond(ctx, u.ID, req.InReplyTo, accept)
+	if err != nil {
+		if errors.Is(err, sharinguc.ErrInvitationNotFound) {
+			return c.SendStatus(fiber.StatusForbidden)
+		}
+		return err
+	}
+	if !accept || invitation.Type != sharinguc.CollectionCalendar {
+		return c.SendStatus(fiber.StatusOK)
+	}
+
+	// Tell the client where the accepted calendar appears
+	href, err := h.caldavBackend().sharedCalendarHref(ctx, u, req.InReplyTo)
+	if err != nil {
+		return err
+	}
+	type sharedAs struct {
+		XMLName xml.Name `xml:"http://calendarserver.org/ns/ shared-as"`
+		Href    string   `xml:"DAV: href"`
+	}
+	data, err := xml.Marshal(sharedAs{Href: href})
+	if err != nil {
+		return err
+	}
+	c.Set("Content-Type", "application/xml; charset=utf-8")
+	return c.Send(append([]byte(xml.Header), data...))
+}
+
+func (h *Handler) handleShareRequest(c fiber.Ctx, ctx context.Context, u *user.User) error {
+	var req csShareRequest
+	if err := xml.Unmarshal(c.Body(), &req); err != nil {
+		return c.SendStatus(fiber.StatusBadRequest)
+	}
+
+	cal, _, perm, err := h.caldavBackend().ResolvePath(ctx, c.Path())
+	if err != nil {
+		return c.SendStatus(fiber.StatusNotFound)
+	}
+	if perm != calendar.PermissionOwner {
+		return c.SendStatus(fiber.StatusForbidden)
+	}
+
+	var changes []sharinguc.CalendarInviteChange
+	for _, s := range req.Set {
+		permission := sharing.PermissionRead
+		if s.ReadWrite != nil {
+			permission = sharing.PermissionReadWrite
+		}
+		changes = append(changes, sharinguc.CalendarInviteChange{
+			UserIdentifier: shareeIdentifier(s.Href),
+			Permission:     permission,
+			Summary:        strings.TrimSpace(s.Summary),
+		})
+	}
+	for _, r := range req.Remove {
+		changes = append(changes, sharinguc.CalendarInviteChange{UserIdentifier: shareeIdentifier(r.Href), Remove: true})
+	}
+
+	if err := h.calendarInvites.Execute(ctx, u.ID, cal, changes); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	return c.SendStatus(fiber.StatusOK)
+}
+
+func (h *Handler) caldavBackend() *CalDAVBackend {
+	return h.caldavHandler.Backend.(*CalDAVBackend)
+}
+
+// requestsSharingProperties reports whether a PROPFIND body asks for one of
+// the sharing properties go-webdav does not know
+func requestsSharingProperties(body []byte) bool {
+	return bytes.Contains(body, []byte(csNamespace)) &&
+		(bytes.Contains(body, []byte(csNotificationURL.Local)) ||
+			bytes.Contains(body, []byte(csInvite.Local)) ||
+			bytes.Contains(body, []byte(csAllowedSharingModes.Local)))
+}
+
+// addSharingProperties fills in the sharing properties of the principal,
+// the calendar home and the calendars in a PROPFIND response written by
+// go-webdav, which reports them as not found
+func (h *Handler) addSharingProperties(c fiber.Ctx, ctx context.Context, u *user.User) error {
+	if c.Response().StatusCode() != http.StatusMultiStatus {
+		return nil
+	}
+	backend := h.caldavBackend()
+	principal := fmt.Sprintf("/dav/%s/", u.Username)
+	home := principal + "calendars/"
+
+	body, err := setProperties(c.Response().Body(), func(href string, name xml.Name) (string, bool) {
+		switch name {
+		case csNotificationURL:
+			if href != principal && href != home {
+				return "", false
+			}
+			return fmt.Sprintf(`<href xmlns="DAV:">%s%s/</href>`, principal, notificationCollection), true
+		case csAllowedSharingModes, csInvite:
+			if !strings.HasPrefix(href, home) || href == home {
+				return "", false
+			}
+			cal, _, perm, err := backend.ResolvePath(ctx, href)
+			if err != nil {
+				return "", false
+			}
+			if name == csAllowedSharingModes {
+				if perm != calendar.PermissionOwner {
+					return "", false
+				}
+				return `<can-be-shared xmlns="` + csNamespace + `"></can-be-shared>`, true
+			}
+			return backend.inviteProperty(ctx, u, cal, perm)
+		}
+		return "", false
+	})
+	if err != nil {
+		return err
+	}
+	c.Response().SetBody(body)
+	return nil
+}
+
+// setProperties overrides the values of properties in a multistatus body
+// when value knows them; properties reported as not found move into the
+// found properties
+func setProperties(body []byte, value func(href string, name xml.Name) (string, bool)) ([]byte, error) {
+	var ms davMultiStatus
+	if err := xml.Unmarshal(body, &ms); err != nil {
+		return nil, err
+	}
+
+	changed := false
+	for i := range ms.Responses {
+		resp := &ms.Responses[i]
+		var found []RawXMLValue
+		propstats := resp.PropStat[:0]
+		for _, ps := range resp.PropStat {
+			if strings.Contains(ps.Status, "200") {
+				for j, p := range ps.Prop.Raw {
+					if inner, ok := value(resp.Href, p.XMLName); ok {
+						ps.Prop.Raw[j].Inner = []byte(inner)
+						changed = true
+					}
+				}
+			}
+			if strings.Contains(ps.Status, "404") {
+				missing := ps.Prop.Raw[:0]
+				for _, p := range ps.Prop.Raw {
+					if inner, ok := value(resp.Href, p.XMLName); ok {
+						found = append(found, RawXMLValue{XMLName: p.XMLName, Inner: []byte(inner)})
+						continue
+					}
+					missing = append(missing, p)
+				}
+				ps.Prop.Raw = missing
+				if len(missing) == 0 {
+					continue
+				}
+			}
+			propstats = append(propstats, ps)
+		}
+		resp.PropStat = propstats
+		if len(found) == 0 {
+			continue
+		}
+
+		changed = true
+		added := false
+		for j := range resp.PropStat {
+			if strings.Contains(resp.PropStat[j].Status, "200") {
+				resp.PropStat[j].Prop.Raw = append(resp.PropStat[j].Prop.Raw, found...)
+				added = true
+				break
+			}
+		}
+		if !added {
+			resp.PropStat = append(resp.PropStat, PropStat{Prop: Prop{Raw: found}, Status: "HTTP/1.1 200 OK"})
+		}
+	}
+	if !changed {
+		return body, nil
+	}
+
+	data, err := xml.Marshal(&ms)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+// requestedProps returns the property names of a PROPFIND body; nil asks
+// for all properties
+func requestedProps(body []byte) []xml.Name {
+	var req propfindRequest
+	if len(bytes.TrimSpace(body)) == 0 || xml.Unmarshal(body, &req) != nil || req.Prop == nil {
+		return nil
+	}
+	names := make([]xml.Name, len(req.Prop.Raw))
+	for i, p := range req.Prop.Raw {
+		names[i] = p.XMLName
+	}
+	return names
+}
+
+// propResponse answers the requested properties of a resource from props
+func propResponse(href string, props map[xml.Name]string, requested []xml.Name) SyncResponse {
+	resp := SyncResponse{Href: href}
+	var found, missing []RawXMLValue
+	if requested == nil {
+		for name := range props {
+			requested = append(requested, name)
+		}
+	}
+	for _, name := range requested {
+		if inner, ok := props[name]; ok {
+			found = append(found, RawXMLValue{XMLName: name, Inner: []byte(inner)})
+		} else {
+			missing = append(missing, RawXMLValue{XMLName: name})
+		}
+	}
+	if len(found) > 0 {
+		resp.PropStat = append(resp.PropStat, PropStat{Prop: Prop{Raw: found}, Status: "HTTP/1.1 200 OK"})
+	}
+	if len(missing) > 0 {
+		resp.PropStat = append(resp.PropStat, PropStat{Prop: Prop{Raw: missing}, Status: "HTTP/1.1 404 Not Found"})
+	}
+	return resp
+}
+
+func sendMultiStatus(c fiber.Ctx, ms *davMultiStatus) error {
+	data, err := xml.Marshal(ms)
+	if err != nil {
+		return err
+	}
+	c.Set("Content-Type", "application/xml; charset=utf-8")
+	c.Status(http.StatusMultiStatus)
+	return c.Send(append([]byte(xml.Header), data...))
+}
+
+// inviteProperty renders the CS:invite property of a calendar: its
+// organizer and the users it is shared with directly
+func (b *CalDAVBackend) inviteProperty(ctx context.Context, u *user.User, cal *calendar.Calendar, perm calendar.CalendarPermission) (string, bool) {
+	shares, err := b.shareRepo.ListByCalendarID(ctx, cal.ID)
+	if err != nil {
+		return "", false
+	}
+
+	owner := u
+	if perm != calendar.PermissionOwner {
+		owner = &cal.Owner
+	}
+
+	var buf bytes.Buffer
+	enc := xml.NewEncoder(&buf)
+	if err := enc.Encode(csOrganizer{Href: mailto(owner), CommonName: owner.DisplayName}); err != nil {
+		return "", false
+	}
+	for _, s := range shares {
+		if s.SharedWith == nil {
+			continue
+		}
+		err := enc.Encode(csUser{
+			Href:       mailto(s.SharedWith),
+			CommonName: s.SharedWith.DisplayName,
+			Status:     shareStatusElement(s.Status),
+			Access:     accessElement(s.Permission),
+		})
+		if err != nil {
+			return "", false
+		}
+	}
+	return buf.String(), true
+}
+
+// sharedCalendarHref returns where a calendar shared with u is mounted in
+// their calendar home
+func (b *CalDAVBackend) sharedCalendarHref(ctx context.Context, u *user.User, shareUUID string) (string, error) {
+	share, err := b.shareRepo.GetByUUID(ctx, shareUUID)
+	if err != nil || share == nil {
+		return "", fmt.Errorf("share not found")
+	}
+	shared, err := b.shareRepo.FindCalendarsSharedWithUser(ctx, u.ID)
+	if err != nil {
+		return "", err
+	}
+	for _, s := range shared {
+		if s.CalendarID == share.CalendarID {
+			return fmt.Sprintf("/dav/%s/calendars/%s/", u.Username, s.Mount.Path), nil
+		}
+	}
+	return "", fmt.Errorf("calendar not shared with %s", u.Username)
+}
@@ -0,0 +1,388 @@
+package webdav
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/jherrma/caldav-server/internal/adapter/repository"
+	"github.com/jherrma/caldav-server/internal/domain/addressbook"
+	"github.com/jherrma/caldav-server/internal/domain/calendar"
+	"github.com/jherrma/caldav-server/internal/domain/sharing"
+	"github.com/jherrma/caldav-server/internal/domain/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestCalDAVSharing(t *testing.T) {
+	app, db, _ := setupTestApp(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	userRepo := repository.NewUserRepository(db.DB())
+	passwordHash, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	for _, name := range []string{"owner", "friend"} {
+		require.NoError(t, userRepo.Create(ctx, &user.User{
+			UUID:         name + "-uuid",
+			Email:        name + "@example.com",
+			Username:     name,
+			DisplayName:  strings.ToUpper(name[:1]) + name[1:],
+			PasswordHash: string(passwordHash),
+			IsActive:     true,
+		}))
+	}
+	owner, err := userRepo.GetByEmail(ctx, "owner@example.com")
+	require.NoError(t, err)
+	cal := &calendar.Calendar{UUID: "team-uuid", UserID: owner.ID, Name: "Team", Path: "team"}
+	require.NoError(t, repository.NewCalendarRepository(db.DB()).Create(ctx, cal))
+
+	dav := func(as, method, target, depth, body string) (int, string) {
+		req, _ := http.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(as+"@example.com:password")))
+		req.Header.Set("Content-Type", "application/xml")
+		if depth != "" {
+			req.Header.Set("Depth", depth)
+		}
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		data, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(data)
+	}
+	propfind := func(props string) string {
+		return `<?xml version="1.0" encoding="utf-8"?>
+<D:propfind xmlns:D="DAV:" xmlns:CS="http://calendarserver.org/ns/"><D:prop>` + props + `</D:prop></D:propfind>`
+	}
+
+	t.Run("Advertises Sharing", func(t *testing.T) {
+		req, _ := http.NewRequest("OPTIONS", "/dav/", nil)
+		req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte("owner@example.com:password")))
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		assert.Contains(t, resp.Header.Get("DAV"), "calendarserver-sharing")
+
+		status, body := dav("owner", "PROPFIND", "/dav/owner/", "0", propfind(`<CS:notification-URL/><D:displayname/>`))
+		require.Equal(t, http.StatusMultiStatus, status)
+		assert.Contains(t, body, "/dav/owner/notifications/")
+		assert.Regexp(t, `notification-URL[^>]*><href[^>]*>/dav/owner/notifications/</href></notification-URL></prop><status>HTTP/1.1 200 OK`, body)
+
+		status, body = dav("owner", "PROPFIND", "/dav/owner/calendars/team/", "0", propfind(`<CS:allowed-sharing-modes/>`))
+		require.Equal(t, http.StatusMultiStatus, status)
+		assert.Contains(t, body, "can-be-shared")
+	})
+
+	var uid string
+	t.Run("Owner Invites", func(t *testing.T) {
+		status, _ := dav("friend", "POST", "/dav/owner/calendars/team/", "", `<?xml version="1.0" encoding="utf-8"?>
+<CS:share xmlns:D="DAV:" xmlns:CS="http://calendarserver.org/ns/">
+  <CS:set><D:href>mailto:owner@example.com</D:href><CS:read/></CS:set>
+</CS:share>`)
+		assert.Equal(t, http.StatusNotFound, status)
+
+		status, _ = dav("owner", "POST", "/dav/owner/calendars/team/", "", `<?xml version="1.0" encoding="utf-8"?>
+<CS:share xmlns:D="DAV:" xmlns:CS="http://calendarserver.org/ns/">
+  <CS:set><D:href>mailto:friend@example.com</D:href><CS:summary>Team events</CS:summary><CS:read-write/></CS:set>
+</CS:share>`)
+		require.Equal(t, http.StatusOK, status)
+
+		_, body := dav("owner", "PROPFIND", "/dav/owner/calendars/team/", "0", propfind(`<CS:invite/>`))
+		assert.Contains(t, body, "mailto:friend@example.com")
+		assert.Contains(t, body, "invite-noresponse")
+
+		// Pending invitations do not show up in the calendar home
+		_, body = dav("friend", "PROPFIND", "/dav/friend/calendars/", "1", propfind(`<D:displayname/>`))
+		assert.NotContains(t, body, "/dav/friend/calendars/owner-team/")
+
+		status, body = dav("friend", "PROPFIND", "/dav/friend/notifications/", "1", propfind(`<D:resourcetype/><CS:notificationtype/>`))
+		require.Equal(t, http.StatusMultiStatus, status)
+		href := regexp.MustCompile(`/dav/friend/notifications/[^<]+\.xml`).FindString(body)
+		require.NotEmpty(t, href)
+		assert.Contains(t, body, "invite-notification")
+
+		status, body = dav("friend", "GET", href, "", "")
+		require.Equal(t, http.StatusOK, status)
+		assert.Contains(t, body, "<href xmlns=\"DAV:\">/dav/owner/calendars/team/</href>")
+		assert.Contains(t, body, "read-write")
+		assert.Contains(t, body, "Team events")
+		uid = regexp.MustCompile(`<uid[^>]*>([^<]+)</uid>`).FindStringSubmatch(body)[1]
+
+		status, _ = dav("owner", "PROPFIND", "/dav/friend/notifications/", "1", propfind(`<D:resourcetype/>`))
+		assert.Equal(t, fiber.StatusForbidden, status)
+	})
+
+	t.Run("Sharee Accepts", func(t *testing.T) {
+		status, body := dav("friend", "POST", "/dav/friend/calendars/", "", `<?xml version="1.0" encoding="utf-8"?>
+<CS:invite-reply xmlns:D="DAV:" xmlns:CS="http://calendarserver.org/ns/">
+  <D:href>mailto:friend@example.com</D:href>
+  <CS:invite-accepted/>
+  <CS:hosturl><D:href>/dav/owner/calendars/team/</D:href></CS:hosturl>
+  <CS:in-reply-to>`+uid+`</CS:in-reply-to>
+</CS:invite-reply>`)
+		require.Equal(t, http.StatusOK, status)
+		assert.Contains(t, body, "/dav/friend/calendars/owner-team/")
+
+		_, body = dav("friend", "PROPFIND", "/dav/friend/calendars/", "1", propfind(`<D:displayname/>`))
+		assert.Contains(t, body, "/dav/friend/calendars/owner-team/")
+
+		_, body = dav("friend", "PROPFIND", "/dav/friend/notifications/", "1", propfind(`<D:resourcetype/>`))
+		assert.NotContains(t, body, ".xml")
+
+		_, body = dav("owner", "PROPFIND", "/dav/owner/notifications/", "1", propfind(`<CS:notificationtype/>`))
+		assert.Contains(t, body, "invite-reply")
+		_, body = dav("owner", "PROPFIND", "/dav/owner/calendars/team/", "0", propfind(`<CS:invite/>`))
+		assert.Contains(t, body, "invite-accepted")
+	})
+
+	t.Run("Owner Removes Sharee", func(t *testing.T) {
+		status, _ := dav("owner", "POST", "/dav/owner/calendars/team/", "", `<?xml version="1.0" encoding="utf-8"?>
+<CS:share xmlns:D="DAV:" xmlns:CS="http://calendarserver.org/ns/">
+  <CS:remove><D:href>/dav/friend/</D:href></CS:remove>
+</CS:share>`)
+		require.Equal(t, http.StatusOK, status)
+
+		_, body := dav("friend", "PROPFIND", "/dav/friend/calendars/", "1", propfind(`<D:displayname/>`))
+		assert.NotContains(t, body, "/dav/friend/calendars/owner-team/")
+
+		_, body = dav("friend", "PROPFIND", "/dav/friend/notifications/", "1", propfind(`<D:getetag/>`))
+		href := regexp.MustCompile(`/dav/friend/notifications/[^<]+\.xml`).FindString(body)
+		require.NotEmpty(t, href)
+		_, body = dav("friend", "GET", href, "", "")
+		assert.Contains(t, body, "invite-deleted")
+
+		status, _ = dav("friend", "DELETE", href, "", "")
+		assert.Equal(t, http.StatusNoContent, status)
+		status, _ = dav("friend", "GET", href, "", "")
+		assert.Equal(t, http.StatusNotFound, status)
+	})
+}
+
+func TestSharedCalendarMounts(t *testing.T) {
+	app, db, _ := setupTestApp(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	userRepo := repository.NewUserRepository(db.DB())
+	calendarRepo := repository.NewCalendarRepository(db.DB())
+	shareRepo := repository.NewCalendarShareRepository(db.DB())
+	passwordHash, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	users := make(map[string]*user.User)
+	for _, name := range []string{"alice", "bob", "carol"} {
+		u := &user.User{UUID: name + "-uuid", Email: name + "@example.com", Username: name, PasswordHash: string(passwordHash), IsActive: true}
+		require.NoError(t, userRepo.Create(ctx, u))
+		users[name] = u
+	}
+
+	// Alice and Bob both share a calendar called "default" with Carol, who
+	// owns a calendar at the path Alice's would be mounted at
+	for _, c := range []struct{ owner, name, path string }{
+		{"alice", "Alice", "default"},
+		{"bob", "Bob", "default"},
+		{"carol", "Mine", "alice-default"},
+	} {
+		cal := &calendar.Calendar{UUID: c.owner + "-" + c.path, UserID: users[c.owner].ID, Name: c.name, Path: c.path}
+		require.NoError(t, calendarRepo.Create(ctx, cal))
+		if c.owner != "carol" {
+			require.NoError(t, shareRepo.Create(ctx, &sharing.CalendarShare{
+				UUID: c.owner + "-share", CalendarID: cal.ID, SharedWithID: &users["carol"].ID,
+				Permission: sharing.PermissionRead, Status: sharing.ShareStatusAccepted,
+			}))
+		}
+	}
+
+	dav := func(method, target, body string) (int, string) {
+		req, _ := http.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte("carol@example.com:password")))
+		req.Header.Set("Content-Type", "application/xml")
+		req.Header.Set("Depth", "1")
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		data, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(data)
+	}
+	propfind := `<?xml version="1.0" encoding="utf-8"?><D:propfind xmlns:D="DAV:"><D:prop><D:displayname/></D:prop></D:propfind>`
+	displayName := func(body, p string) string {
+		m := regexp.MustCompile(`<href>` + regexp.QuoteMeta(p) + `</href><propstat[^>]*><prop[^>]*><displayname[^>]*>([^<]*)<`).FindStringSubmatch(body)
+		if m == nil {
+			return ""
+		}
+		return m[1]
+	}
+
+	_, body := dav("PROPFIND", "/dav/carol/calendars/", propfind)
+	assert.Equal(t, "Mine", displayName(body, "/dav/carol/calendars/alice-default/"))
+	assert.Equal(t, "Alice", displayName(body, "/dav/carol/calendars/alice-default-2/"))
+	assert.Equal(t, "Bob", displayName(body, "/dav/carol/calendars/bob-default/"))
+
+	// Carol's own properties replace the owner's for her alone
+	shares, err := shareRepo.FindCalendarsSharedWithUser(ctx, users["carol"].ID)
+	require.NoError(t, err)
+	require.Len(t, shares, 2)
+	for _, s := range shares {
+		s.Mount.DisplayName = "Carol - " + s.Calendar.Name
+		s.Mount.Hidden = s.Calendar.Name == "Bob"
+		require.NoError(t, shareRepo.UpdateSharedCalendar(ctx, s.Mount))
+	}
+	_, body = dav("PROPFIND", "/dav/carol/calendars/", propfind)
+	assert.Equal(t, "Carol - Alice", displayName(body, "/dav/carol/calendars/alice-default-2/"))
+	assert.NotContains(t, body, "/dav/carol/calendars/bob-default/")
+
+	// Hidden calendars stay reachable at their path
+	status, body := dav("PROPFIND", "/dav/carol/calendars/bob-default/", propfind)
+	assert.Equal(t, http.StatusMultiStatus, status)
+	assert.Equal(t, "Carol - Bob", displayName(body, "/dav/carol/calendars/bob-default/"))
+
+	bob, err := calendarRepo.GetByPath(ctx, users["bob"].ID, "default")
+	require.NoError(t, err)
+	assert.Equal(t, "Bob", bob.Name)
+
+	status, _ = dav("MKCOL", "/dav/carol/calendars/bob-default/", "")
+	assert.Equal(t, http.StatusConflict, status)
+}
+
+func TestSharedAddressBookPermissions(t *testing.T) {
+	app, db, _ := setupTestApp(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	userRepo := repository.NewUserRepository(db.DB())
+	addressBookRepo := repository.NewAddressBookRepository(db.DB())
+	shareRepo := repository.NewAddressBookShareRepository(db.DB())
+	passwordHash, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	users := make(map[string]*user.User)
+	for _, name := range []string{"alice", "carol"} {
+		u := &user.User{UUID: name + "-uuid", Email: name + "@example.com", Username: name, PasswordHash: string(passwordHash), IsActive: true}
+		require.NoError(t, userRepo.Create(ctx, u))
+		users[name] = u
+	}
+
+	// Alice shares "contacts" read-only and "team" read-write with Carol,
+	// who owns a "contacts" address book of her own
+	var aliceBook *addressbook.AddressBook
+	for _, b := range []struct{ owner, path, permission string }{
+		{"alice", "contacts", sharing.PermissionRead},
+		{"alice", "team", sharing.PermissionReadWrite},
+		{"carol", "contacts", ""},
+	} {
+		ab := &addressbook.AddressBook{UUID: b.owner + "-" + b.path, UserID: users[b.owner].ID, Name: b.owner + " " + b.path, Path: b.path}
+		require.NoError(t, addressBookRepo.Create(ctx, ab))
+		if ab.UUID == "alice-contacts" {
+			aliceBook = ab
+		}
+		if b.permission != "" {
+			require.NoError(t, shareRepo.Create(ctx, &sharing.AddressBookShare{
+				UUID: ab.UUID + "-share", AddressBookID: ab.ID, SharedWithID: &users["carol"].ID,
+				Permission: b.permission, Status: sharing.ShareStatusAccepted,
+			}))
+		}
+	}
+
+	dav := func(method, target, contentType, body string) (int, string) {
+		req, _ := http.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte("carol@example.com:password")))
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("Depth", "1")
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		data, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(data)
+	}
+	card := func(uid string) string {
+		return "BEGIN:VCARD\r\nVERSION:3.0\r\nUID:" + uid + "\r\nFN:" + uid + "\r\nEND:VCARD\r\n"
+	}
+
+	status, _ := dav("PUT", "/dav/carol/addressbooks/alice-team/dave.vcf", "text/vcard", card("dave"))
+	require.Equal(t, http.StatusCreated, status)
+	status, _ = dav("DELETE", "/dav/carol/addressbooks/alice-team/dave.vcf", "", "")
+	assert.Equal(t, http.StatusNoContent, status)
+
+	status, _ = dav("PUT", "/dav/carol/addressbooks/alice-contacts/erin.vcf", "text/vcard", card("erin"))
+	assert.Equal(t, http.StatusForbidden, status)
+
+	// The owner's cards cannot be deleted through a read-only share
+	require.NoError(t, addressBookRepo.CreateObject(ctx, &addressbook.AddressObject{
+		UUID: "frank-uuid", AddressBookID: aliceBook.ID, Path: "frank.vcf", UID: "frank", VCardData: card("frank"),
+	}))
+	status, _ = dav("DELETE", "/dav/carol/addressbooks/alice-contacts/frank.vcf", "", "")
+	assert.Equal(t, http.StatusForbidden, status)
+
+	// Sharees can never delete the owner's address book
+	for _, p := range []string{"alice-contacts", "alice-team"} {
+		status, _ = dav("DELETE", "/dav/carol/addressbooks/"+p+"/", "", "")
+		assert.Equal(t, http.StatusForbidden, status, p)
+	}
+	books, err := addressBookRepo.ListByUserID(ctx, users["alice"].ID)
+	require.NoError(t, err)
+	assert.Len(t, books, 2)
+
+	status, _ = dav("MKCOL", "/dav/carol/addressbooks/alice-team/", "", "")
+	assert.Equal(t, http.StatusConflict, status)
+
+	// Clients learn which address books are read-only
+	_, body := dav("PROPFIND", "/dav/carol/addressbooks/", "application/xml",
+		`<?xml version="1.0" encoding="utf-8"?><D:propfind xmlns:D="DAV:"><D:prop><D:current-user-privilege-set/></D:prop></D:propfind>`)
+	privileges := func(p string) string {
+		m := regexp.MustCompile(`<href>` + regexp.QuoteMeta(p) + `</href>.*?<current-user-privilege-set[^>]*>(.*?)</current-user-privilege-set>`).FindStringSubmatch(body)
+		require.NotNil(t, m, p)
+		return m[1]
+	}
+	assert.NotContains(t, privileges("/dav/carol/addressbooks/alice-contacts/"), "write")
+	assert.Contains(t, privileges("/dav/carol/addressbooks/alice-contacts/"), "read")
+	assert.Contains(t, privileges("/dav/carol/addressbooks/alice-team/"), "write")
+	assert.Contains(t, privileges("/dav/carol/addressbooks/contacts/"), "write")
+}
+
+func TestSharedAddressBookMounts(t *testing.T) {
+	app, db, _ := setupTestApp(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	userRepo := repository.NewUserRepository(db.DB())
+	addressBookRepo := repository.NewAddressBookRepository(db.DB())
+	shareRepo := repository.NewAddressBookShareRepository(db.DB())
+	passwordHash, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	users := make(map[string]*user.User)
+	for _, name := range []string{"alice", "carol"} {
+		u := &user.User{UUID: name + "-uuid", Email: name + "@example.com", Username: name, PasswordHash: string(passwordHash), IsActive: true}
+		require.NoError(t, userRepo.Create(ctx, u))
+		users[name] = u
+	}
+
+	// Carol owns an address book at the path Alice's would be mounted at
+	own := &addressbook.AddressBook{UUID: "carol-own", UserID: users["carol"].ID, Name: "Mine", Path: "alice-contacts"}
+	require.NoError(t, addressBookRepo.Create(ctx, own))
+	shared := &addressbook.AddressBook{UUID: "alice-contacts", UserID: users["alice"].ID, Name: "Alice", Path: "contacts"}
+	require.NoError(t, addressBookRepo.Create(ctx, shared))
+	require.NoError(t, shareRepo.Create(ctx, &sharing.AddressBookShare{
+		UUID: "alice-share", AddressBookID: shared.ID, SharedWithID: &users["carol"].ID,
+		Permission: sharing.PermissionRead, Status: sharing.ShareStatusAccepted,
+	}))
+
+	propfind := func(target string) (int, string) {
+		req, _ := http.NewRequest("PROPFIND", target, strings.NewReader(
+			`<?xml version="1.0" encoding="utf-8"?><D:propfind xmlns:D="DAV:"><D:prop><D:displayname/></D:prop></D:propfind>`))
+		req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte("carol@example.com:password")))
+		req.Header.Set("Content-Type", "application/xml")
+		req.Header.Set("Depth", "1")
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		data, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(data)
+	}
+
+	_, body := propfind("/dav/carol/addressbooks/")
+	assert.Contains(t, body, "/dav/carol/addressbooks/alice-contacts-2/")
+
+	// The shared address book keeps its path once the one that pushed it
+	// aside is gone
+	require.NoError(t, addressBookRepo.Delete(ctx, own.ID))
+	_, body = propfind("/dav/carol/addressbooks/")
+	assert.Contains(t, body, "/dav/carol/addressbooks/alice-contacts-2/")
+	assert.NotContains(t, body, "/dav/carol/addressbooks/alice-contacts/")
+	status, _ := propfind("/dav/carol/addressbooks/alice-contacts-2/")
+	assert.Equal(t, http.StatusMultiStatus, status)
+}
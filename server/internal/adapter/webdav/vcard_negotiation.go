@@ -0,0 +1,120 @@
+package webdav
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/emersion/go-vcard"
+	"github.com/gofiber/fiber/v3"
+	"github.com/jherrma/caldav-server/internal/domain/addressbook"
+)
+
+const carddavNamespace = "urn:ietf:params:xml:ns:carddav"
+
+// requestedVCardVersion determines which vCard version a CardDAV request
+// asks for. GET and HEAD use the Accept header, REPORTs the content-type and
+// version attributes of CARDDAV:address-data (RFC 6352 §10.4). An empty
+// version means the client has no preference and gets the stored version.
+// ok is false when the client only accepts versions the server cannot
+// produce.
+func requestedVCardVersion(c fiber.Ctx) (version string, ok bool) {
+	switch c.Method() {
+	case http.MethodGet, http.MethodHead:
+		return negotiateAccept(c.Get("Accept"))
+	case "REPORT":
+		return addressDataVersion(c.Body())
+	}
+	return "", true
+}
+
+// negotiateAccept picks a vCard version from an Accept header. Wildcards
+// and text/vcard without a version parameter accept anything.
+func negotiateAccept(accept string) (string, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return "", true
+	}
+
+	anyVersion := false
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil || params["q"] == "0" {
+			continue
+		}
+		switch mediaType {
+		case "*/*", "text/*":
+			anyVersion = true
+		case vcard.MIMEType, "text/x-vcard":
+			v := params["version"]
+			if v == "" {
+				anyVersion = true
+			} else if addressbook.IsSupportedVCardVersion(v) {
+				return v, true
+			}
+		}
+	}
+	return "", anyVersion
+}
+
+// addressDataVersion reads the requested version from the first
+// CARDDAV:address-data element of a REPORT body
+func addressDataVersion(body []byte) (string, bool) {
+	dec := xml.NewDecoder(bytes.NewReader(body))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return "", true
+		}
+		if err != nil {
+			// Malformed bodies are rejected by the REPORT handler itself
+			return "", true
+		}
+		start, isStart := tok.(xml.StartElement)
+		if !isStart || start.Name.Space != carddavNamespace || start.Name.Local != "address-data" {
+			continue
+		}
+
+		var contentType, version string
+		for _, attr := range start.Attr {
+			switch attr.Name.Local {
+			case "content-type":
+				contentType = attr.Value
+			case "version":
+				version = attr.Value
+			}
+		}
+		if contentType == "" && version == "" {
+			// RFC 6352 defaults to text/vcard 3.0, but clients that omit
+			// both attributes expect the stored data as-is
+			return "", true
+		}
+		if version == "" {
+			version = addressbook.VCardVersion3
+		}
+		if (contentType != "" && !strings.EqualFold(contentType, vcard.MIMEType)) || !addressbook.IsSupportedVCardVersion(version) {
+			return "", false
+		}
+		return version, true
+	}
+}
+
+// sendUnsupportedAddressData answers a REPORT asking for a vCard version the
+// server cannot convert to (CARDDAV:supported-address-data-conversion
+// precondition, RFC 6352 §8.6 and §8.7)
+func sendUnsupportedAddressData(c fiber.Ctx) error {
+	c.Set("Content-Type", "application/xml; charset=utf-8")
+	c.Status(http.StatusForbidden)
+
+	type ErrorResponse struct {
+		XMLName    xml.Name `xml:"DAV: error"`
+		Conversion struct{} `xml:"urn:ietf:params:xml:ns:carddav supported-address-data-conversion"`
+	}
+
+	if _, err := c.Write([]byte(xml.Header)); err != nil {
+		return err
+	}
+	return xml.NewEncoder(c).Encode(ErrorResponse{})
+}
@@ -0,0 +1,147 @@
+package webdav
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/emersion/go-vcard"
+	"github.com/gofiber/fiber/v3"
+	"github.com/jherrma/caldav-server/internal/adapter/repository"
+	"github.com/jherrma/caldav-server/internal/domain/addressbook"
+	"github.com/jherrma/caldav-server/internal/domain/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func decodeBody(t *testing.T, body string) vcard.Card {
+	t.Helper()
+	card, err := vcard.NewDecoder(strings.NewReader(body)).Decode()
+	require.NoError(t, err)
+	return card
+}
+
+func TestNegotiateAccept(t *testing.T) {
+	tests := []struct {
+		accept  string
+		version string
+		ok      bool
+	}{
+		{"", "", true},
+		{"*/*", "", true},
+		{"text/vcard", "", true},
+		{"text/vcard; version=4.0", "4.0", true},
+		{"text/vcard;version=2.1, text/vcard;version=3.0", "3.0", true},
+		{"text/vcard;version=2.1", "", false},
+		{"application/json", "", false},
+	}
+	for _, tt := range tests {
+		version, ok := negotiateAccept(tt.accept)
+		assert.Equal(t, tt.version, version, tt.accept)
+		assert.Equal(t, tt.ok, ok, tt.accept)
+	}
+}
+
+func TestCardDAVVersionNegotiation(t *testing.T) {
+	app, db, _ := setupTestApp(t)
+	defer db.Close()
+
+	userRepo := repository.NewUserRepository(db.DB())
+	abRepo := repository.NewAddressBookRepository(db.DB())
+	passwordHash, _ := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.DefaultCost)
+	u := &user.User{
+		UUID:         "vcard-user",
+		Email:        "vcard@example.com",
+		Username:     "vcarduser",
+		PasswordHash: string(passwordHash),
+		IsActive:     true,
+	}
+	require.NoError(t, userRepo.Create(context.Background(), u))
+	require.NoError(t, abRepo.Create(context.Background(), &addressbook.AddressBook{
+		UUID: "ab-uuid", UserID: u.ID, Name: "Contacts", Path: "contacts",
+	}))
+
+	authHeader := "Basic " + base64.StdEncoding.EncodeToString([]byte("vcard@example.com:password"))
+	do := func(method, path, body string, headers map[string]string) (*http.Response, string) {
+		req, _ := http.NewRequest(method, path, bytes.NewReader([]byte(body)))
+		req.Header.Set("Authorization", authHeader)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		data, _ := io.ReadAll(resp.Body)
+		return resp, string(data)
+	}
+
+	cardPath := "/dav/vcarduser/addressbooks/contacts/jane.vcf"
+	resp, _ := do("PUT", cardPath, "BEGIN:VCARD\r\nVERSION:3.0\r\nUID:jane\r\nFN:Jane\r\n"+
+		"EMAIL;TYPE=pref:jane@example.com\r\nPHOTO;ENCODING=b;TYPE=JPEG:/9j/4AAQ\r\nEND:VCARD\r\n",
+		map[string]string{"Content-Type": "text/vcard"})
+	require.Equal(t, fiber.StatusCreated, resp.StatusCode)
+
+	t.Run("GET Stored Version", func(t *testing.T) {
+		resp, body := do("GET", cardPath, "", nil)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Contains(t, body, "VERSION:3.0")
+		assert.Contains(t, body, "ENCODING=b")
+	})
+
+	t.Run("GET Accept vCard 4", func(t *testing.T) {
+		resp, body := do("GET", cardPath, "", map[string]string{"Accept": "text/vcard; version=4.0"})
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		card := decodeBody(t, body)
+		assert.Equal(t, "4.0", card.Value(vcard.FieldVersion))
+		assert.Equal(t, "data:image/jpeg;base64,/9j/4AAQ", card.Value(vcard.FieldPhoto))
+		assert.Equal(t, "1", card.Get(vcard.FieldEmail).Params.Get(vcard.ParamPreferred))
+	})
+
+	t.Run("GET Unsupported Version", func(t *testing.T) {
+		resp, _ := do("GET", cardPath, "", map[string]string{"Accept": "text/vcard; version=2.1"})
+		assert.Equal(t, fiber.StatusNotAcceptable, resp.StatusCode)
+	})
+
+	t.Run("REPORT address-data version", func(t *testing.T) {
+		report := `<?xml version="1.0" encoding="utf-8" ?>
+<C:addressbook-multiget xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:carddav">
+  <D:prop><D:getetag/><C:address-data content-type="text/vcard" version="4.0"/></D:prop>
+  <D:href>` + cardPath + `</D:href>
+</C:addressbook-multiget>`
+		resp, body := do("REPORT", "/dav/vcarduser/addressbooks/contacts/", report, map[string]string{"Content-Type": "application/xml"})
+		require.Equal(t, fiber.StatusMultiStatus, resp.StatusCode)
+		assert.Contains(t, body, "VERSION:4.0")
+	})
+
+	t.Run("REPORT Unsupported Version", func(t *testing.T) {
+		report := `<?xml version="1.0" encoding="utf-8" ?>
+<C:addressbook-multiget xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:carddav">
+  <D:prop><C:address-data content-type="text/vcard" version="2.1"/></D:prop>
+  <D:href>` + cardPath + `</D:href>
+</C:addressbook-multiget>`
+		resp, body := do("REPORT", "/dav/vcarduser/addressbooks/contacts/", report, map[string]string{"Content-Type": "application/xml"})
+		assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+		assert.Contains(t, body, "supported-address-data-conversion")
+	})
+
+	t.Run("PUT vCard 4 Keeps Photo", func(t *testing.T) {
+		v4Path := "/dav/vcarduser/addressbooks/contacts/john.vcf"
+		resp, _ := do("PUT", v4Path, "BEGIN:VCARD\r\nVERSION:4.0\r\nUID:john\r\nFN:John\r\n"+
+			"PHOTO:data:image/png;base64,iVBORw0KGgo=\r\nEND:VCARD\r\n",
+			map[string]string{"Content-Type": "text/vcard"})
+		require.Equal(t, fiber.StatusCreated, resp.StatusCode)
+
+		_, body := do("GET", v4Path, "", nil)
+		card := decodeBody(t, body)
+		assert.Equal(t, "4.0", card.Value(vcard.FieldVersion))
+		assert.Equal(t, "data:image/png;base64,iVBORw0KGgo=", card.Value(vcard.FieldPhoto))
+
+		_, body = do("GET", v4Path, "", map[string]string{"Accept": "text/vcard;version=3.0"})
+		assert.Contains(t, body, "VERSION:3.0")
+		assert.Contains(t, body, "PHOTO;ENCODING=b;TYPE=PNG:iVBORw0KGgo=")
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"time"
 
@@ -16,18 +17,25 @@ import (
 
 // Config represents the application configuration
 type Config struct {
-	Server    ServerConfig    `yaml:"server"`
-	Database  DatabaseConfig  `yaml:"database"`
-	DataDir   string          `yaml:"data_dir" env:"CALDAV_DATA_DIR"`
-	LogLevel  string          `yaml:"log_level" env:"CALDAV_LOG_LEVEL"`
-	BaseURL   string          `yaml:"base_url" env:"CALDAV_BASE_URL"`
-	SMTP      SMTPConfig      `yaml:"smtp"`
-	JWT       JWTConfig       `yaml:"jwt"`
-	RateLimit RateLimitConfig `yaml:"rate_limit"`
-	OAuth     OAuthConfig     `yaml:"oauth"`
-	TLS       TLSConfig       `yaml:"tls"`
-	CORS      CORSConfig      `yaml:"cors"`
-	Security  SecurityConfig  `yaml:"security"`
+	Server        ServerConfig        `yaml:"server"`
+	Database      DatabaseConfig      `yaml:"database"`
+	DataDir       string              `yaml:"data_dir" env:"CALDAV_DATA_DIR"`
+	LogLevel      string              `yaml:"log_level" env:"CALDAV_LOG_LEVEL"`
+	BaseURL       string              `yaml:"base_url" env:"CALDAV_BASE_URL"`
+	SMTP          SMTPConfig          `yaml:"smtp"`
+	JWT           JWTConfig           `yaml:"jwt"`
+	RateLimit     RateLimitConfig     `yaml:"rate_limit"`
+	Lockout       LockoutConfig       `yaml:"lockout"`
+	OAuth         OAuthConfig         `yaml:"oauth"`
+	OAuthServer   OAuthServerConfig   `yaml:"oauth_server"`
+	TLS           TLSConfig           `yaml:"tls"`
+	CORS          CORSConfig          `yaml:"cors"`
+	Security      SecurityConfig      `yaml:"security"`
+	Encryption    EncryptionConfig    `yaml:"encryption"`
+	Photos        PhotoConfig         `yaml:"photos"`
+	Directory     DirectoryConfig     `yaml:"directory"`
+	Contacts      ContactsConfig      `yaml:"contacts"`
+	Subscriptions SubscriptionsConfig `yaml:"subscriptions"`
 }
 
 // ServerConfig contains server-specific settings
@@ -72,11 +80,104 @@ type RateLimitConfig struct {
 	Window   time.Duration `yaml:"window" env:"CALDAV_RATE_LIMIT_WINDOW"`
 }
 
-// OAuthConfig contains OAuth2/OIDC settings
+// LockoutConfig contains settings for throttling failed logins per account
+// and per DAV credential. After FreeAttempts consecutive failures each further
+// failure blocks the subject for BaseDelay, doubling every time; after
+// MaxAttempts failures the subject is locked for Duration.
+type LockoutConfig struct {
+	Enabled      bool          `yaml:"enabled" env:"CALDAV_LOCKOUT_ENABLED"`
+	FreeAttempts int           `yaml:"free_attempts" env:"CALDAV_LOCKOUT_FREE_ATTEMPTS"`
+	BaseDelay    time.Duration `yaml:"base_delay" env:"CALDAV_LOCKOUT_BASE_DELAY"`
+	MaxAttempts  int           `yaml:"max_attempts" env:"CALDAV_LOCKOUT_MAX_ATTEMPTS"`
+	Duration     time.Duration `yaml:"duration" env:"CALDAV_LOCKOUT_DURATION"`
+	ResetAfter   time.Duration `yaml:"reset_after" env:"CALDAV_LOCKOUT_RESET_AFTER"` // Forget failures older than this
+}
+
+// OAuthConfig contains OAuth2/OIDC settings. Google, Microsoft and Custom
+// are shorthands configurable through the environment; any number of
+// further OIDC providers can be listed under Providers in config.yaml.
 type OAuthConfig struct {
-	Google    OAuthProviderConfig `yaml:"google" envPrefix:"CALDAV_OAUTH_GOOGLE_"`
-	Microsoft OAuthProviderConfig `yaml:"microsoft" envPrefix:"CALDAV_OAUTH_MICROSOFT_"`
-	Custom    OAuthProviderConfig `yaml:"custom" envPrefix:"CALDAV_OAUTH_CUSTOM_"`
+	Google    OAuthProviderConfig  `yaml:"google" envPrefix:"CALDAV_OAUTH_GOOGLE_"`
+	Microsoft OAuthProviderConfig  `yaml:"microsoft" envPrefix:"CALDAV_OAUTH_MICROSOFT_"`
+	Custom    OAuthProviderConfig  `yaml:"custom" envPrefix:"CALDAV_OAUTH_CUSTOM_"`
+	Providers []OIDCProviderConfig `yaml:"providers"`
+}
+
+// OIDCProviderConfig describes a named generic OpenID Connect provider
+type OIDCProviderConfig struct {
+	Name           string          `yaml:"name"`         // Used in /api/v1/auth/oauth/{name}
+	DisplayName    string          `yaml:"display_name"` // Shown on the login page
+	Icon           string          `yaml:"icon"`         // PrimeIcons class, e.g. "pi pi-building"
+	Issuer         string          `yaml:"issuer"`
+	ClientID       string          `yaml:"client_id"`
+	ClientSecret   string          `yaml:"client_secret"`
+	Scopes         []string        `yaml:"scopes"` // Defaults to openid, profile, email
+	Claims         OIDCClaimConfig `yaml:"claims"`
+	AllowedDomains []string        `yaml:"allowed_domains"` // Empty allows every email domain
+	AutoRegister   *bool           `yaml:"auto_register"`   // Create unknown users on first sign-in (default true)
+	TrustEmail     bool            `yaml:"trust_email"`     // Link to existing accounts by email even without email_verified
+}
+
+// OIDCClaimConfig maps provider claims to user attributes
+type OIDCClaimConfig struct {
+	Username    string `yaml:"username"`     // Default preferred_username
+	DisplayName string `yaml:"display_name"` // Default name
+	Email       string `yaml:"email"`        // Default email
+}
+
+// AutoRegisterEnabled reports whether unknown users are created on their
+// first sign-in
+func (p OIDCProviderConfig) AutoRegisterEnabled() bool {
+	return p.AutoRegister == nil || *p.AutoRegister
+}
+
+// isAESKey reports whether s is a base64-encoded AES-256 key
+func isAESKey(s string) bool {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	return err == nil && len(key) == 32
+}
+
+var providerNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,49}$`)
+
+// reservedProviderNames cannot be used for providers because they clash
+// with the shorthands or with /api/v1/auth/oauth routes
+var reservedProviderNames = map[string]bool{"google": true, "microsoft": true, "custom": true, "providers": true}
+
+// OIDCProviders returns every configured provider: the Google, Microsoft and
+// Custom shorthands that have credentials, followed by Providers
+func (c OAuthConfig) OIDCProviders() []OIDCProviderConfig {
+	var providers []OIDCProviderConfig
+	shorthands := []struct {
+		name, displayName string
+		conf              OAuthProviderConfig
+	}{
+		{"google", "Google", c.Google},
+		{"microsoft", "Microsoft", c.Microsoft},
+		{"custom", "SSO (custom)", c.Custom},
+	}
+	for _, s := range shorthands {
+		if s.conf.ClientID == "" || s.conf.ClientSecret == "" {
+			continue
+		}
+		providers = append(providers, OIDCProviderConfig{
+			Name:         s.name,
+			DisplayName:  s.displayName,
+			Issuer:       s.conf.Issuer,
+			ClientID:     s.conf.ClientID,
+			ClientSecret: s.conf.ClientSecret,
+			TrustEmail:   s.conf.TrustEmail,
+		})
+	}
+	return append(providers, c.Providers...)
+}
+
+// OAuthServerConfig contains settings for the built-in OAuth2/OIDC
+// authorization server that lets third-party apps access user data
+type OAuthServerConfig struct {
+	Enabled            bool          `yaml:"enabled" env:"CALDAV_OAUTH_SERVER_ENABLED"`
+	CodeExpiry         time.Duration `yaml:"code_expiry" env:"CALDAV_OAUTH_SERVER_CODE_EXPIRY"`
+	AccessTokenExpiry  time.Duration `yaml:"access_token_expiry" env:"CALDAV_OAUTH_SERVER_ACCESS_TOKEN_EXPIRY"`
+	RefreshTokenExpiry time.Duration `yaml:"refresh_token_expiry" env:"CALDAV_OAUTH_SERVER_REFRESH_TOKEN_EXPIRY"`
 }
 
 // OAuthProviderConfig contains settings for an OAuth/OIDC provider
@@ -84,6 +185,7 @@ type OAuthProviderConfig struct {
 	ClientID     string `yaml:"client_id" env:"CLIENT_ID"`
 	ClientSecret string `yaml:"client_secret" env:"CLIENT_SECRET"`
 	Issuer       string `yaml:"issuer" env:"ISSUER"`
+	TrustEmail   bool   `yaml:"trust_email" env:"TRUST_EMAIL"` // See OIDCProviderConfig.TrustEmail
 }
 
 // TLSConfig contains TLS/SSL settings
@@ -111,6 +213,71 @@ type SecurityConfig struct {
 	RequestTimeout time.Duration `yaml:"request_timeout" env:"CALDAV_REQUEST_TIMEOUT"`
 }
 
+// EncryptionConfig contains settings for encrypting sensitive columns at rest.
+// Keys are base64-encoded 32-byte AES keys. Encryption is enabled when a
+// master key is configured; PreviousKeys keep data written under retired
+// master keys readable until `rotate-keys` has re-wrapped it.
+type EncryptionConfig struct {
+	MasterKey       string   `yaml:"master_key" env:"CALDAV_ENCRYPTION_MASTER_KEY"`
+	MasterKeyFile   string   `yaml:"master_key_file" env:"CALDAV_ENCRYPTION_MASTER_KEY_FILE"`
+	PreviousKeys    []string `yaml:"previous_keys" env:"CALDAV_ENCRYPTION_PREVIOUS_KEYS" envSeparator:","`
+	EncryptPayloads bool     `yaml:"encrypt_payloads" env:"CALDAV_ENCRYPTION_PAYLOADS"` // Also encrypt iCalendar/vCard data
+}
+
+// Enabled reports whether a master key is configured
+func (c EncryptionConfig) Enabled() bool {
+	return c.MasterKey != "" || c.MasterKeyFile != ""
+}
+
+// PhotoConfig controls how contact photos are normalized. Uploaded and
+// synced photos are re-encoded as JPEG (PNG when transparent) with the longest
+// edge capped at MaxDimension; a thumbnail is kept for every ThumbnailSizes
+// entry. Zero values fall back to the defaults.
+type PhotoConfig struct {
+	MaxDimension   int   `yaml:"max_dimension" env:"CALDAV_PHOTO_MAX_DIMENSION"`
+	ThumbnailSizes []int `yaml:"thumbnail_sizes" env:"CALDAV_PHOTO_THUMBNAIL_SIZES" envSeparator:","`
+	JPEGQuality    int   `yaml:"jpeg_quality" env:"CALDAV_PHOTO_JPEG_QUALITY"`
+	MaxInputBytes  int64 `yaml:"max_input_bytes" env:"CALDAV_PHOTO_MAX_INPUT_BYTES"`   // Larger photos are rejected
+	MaxInputPixels int   `yaml:"max_input_pixels" env:"CALDAV_PHOTO_MAX_INPUT_PIXELS"` // Guards against decompression bombs
+}
+
+// DirectoryConfig controls the global address list: a read-only address
+// book, generated from the user accounts, that every user sees next to
+// their own address books
+type DirectoryConfig struct {
+	Enabled           bool   `yaml:"enabled" env:"CALDAV_DIRECTORY_ENABLED"`
+	Name              string `yaml:"name" env:"CALDAV_DIRECTORY_NAME"`
+	Organization      string `yaml:"organization" env:"CALDAV_DIRECTORY_ORGANIZATION"` // ORG of every card
+	IncludeUnverified bool   `yaml:"include_unverified" env:"CALDAV_DIRECTORY_INCLUDE_UNVERIFIED"`
+}
+
+// phoneRegionPattern matches ISO 3166-1 alpha-2 region codes
+var phoneRegionPattern = regexp.MustCompile(`^[A-Za-z]{2}$`)
+
+// ContactsConfig contains contact search settings. Phone numbers are
+// indexed in E.164; DefaultPhoneRegion (ISO 3166-1 alpha-2, e.g. "DE")
+// supplies the country code of numbers written without one.
+type ContactsConfig struct {
+	DefaultPhoneRegion string `yaml:"default_phone_region" env:"CALDAV_CONTACTS_DEFAULT_PHONE_REGION"`
+}
+
+// SubscriptionsConfig controls subscribed external iCalendar feeds. A
+// background fetcher checks every PollInterval for feeds whose refresh
+// interval elapsed; without Enabled feeds are only fetched when created or
+// refreshed through the API. Feeds on loopback, private and link-local addresses are
+// refused unless AllowPrivateNetworks is set.
+// MaxPerUser caps how many feeds one user may subscribe to.
+type SubscriptionsConfig struct {
+	Enabled              bool          `yaml:"enabled" env:"CALDAV_SUBSCRIPTIONS_ENABLED"`
+	PollInterval         time.Duration `yaml:"poll_interval" env:"CALDAV_SUBSCRIPTIONS_POLL_INTERVAL"`
+	DefaultRefresh       time.Duration `yaml:"default_refresh" env:"CALDAV_SUBSCRIPTIONS_DEFAULT_REFRESH"`
+	MinRefresh           time.Duration `yaml:"min_refresh" env:"CALDAV_SUBSCRIPTIONS_MIN_REFRESH"`
+	Timeout              time.Duration `yaml:"timeout" env:"CALDAV_SUBSCRIPTIONS_TIMEOUT"`
+	MaxFeedBytes         int64         `yaml:"max_feed_bytes" env:"CALDAV_SUBSCRIPTIONS_MAX_FEED_BYTES"`
+	MaxPerUser           int           `yaml:"max_per_user" env:"CALDAV_SUBSCRIPTIONS_MAX_PER_USER"` // 0 for no limit
+	AllowPrivateNetworks bool          `yaml:"allow_private_networks" env:"CALDAV_SUBSCRIPTIONS_ALLOW_PRIVATE_NETWORKS"`
+}
+
 // DSN returns the database connection string based on the driver
 func (c *DatabaseConfig) DSN(dataDir string) string {
 	if c.IsSQLite() {
@@ -176,6 +343,14 @@ func Load(configPath string) (*Config, error) {
 			Requests: 100,
 			Window:   time.Minute,
 		},
+		Lockout: LockoutConfig{
+			Enabled:      true,
+			FreeAttempts: 3,
+			BaseDelay:    time.Second,
+			MaxAttempts:  10,
+			Duration:     15 * time.Minute,
+			ResetAfter:   time.Hour,
+		},
 		OAuth: OAuthConfig{
 			Google: OAuthProviderConfig{
 				Issuer: "https://accounts.google.com",
@@ -184,6 +359,12 @@ func Load(configPath string) (*Config, error) {
 				Issuer: "https://login.microsoftonline.com/common/v2.0",
 			},
 		},
+		OAuthServer: OAuthServerConfig{
+			Enabled:            true,
+			CodeExpiry:         10 * time.Minute,
+			AccessTokenExpiry:  time.Hour,
+			RefreshTokenExpiry: 30 * 24 * time.Hour,
+		},
 		TLS: TLSConfig{
 			Enabled: false,
 		},
@@ -201,6 +382,26 @@ func Load(configPath string) (*Config, error) {
 			MaxRequestSize: 10 * 1024 * 1024, // 10MB
 			RequestTimeout: 30 * time.Second,
 		},
+		Photos: PhotoConfig{
+			MaxDimension:   512,
+			ThumbnailSizes: []int{48, 128, 256},
+			JPEGQuality:    85,
+			MaxInputBytes:  10 * 1024 * 1024, // 10MB
+			MaxInputPixels: 40_000_000,
+		},
+		Directory: DirectoryConfig{
+			Enabled: false,
+			Name:    "Directory",
+		},
+		Subscriptions: SubscriptionsConfig{
+			Enabled:        true,
+			PollInterval:   time.Minute,
+			DefaultRefresh: 6 * time.Hour,
+			MinRefresh:     15 * time.Minute,
+			Timeout:        30 * time.Second,
+			MaxFeedBytes:   10 * 1024 * 1024, // 10MB
+			MaxPerUser:     50,
+		},
 	}
 
 	// 1. Load from YAML file if it exists
@@ -280,6 +481,78 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	if c.Lockout.Enabled {
+		if c.Lockout.MaxAttempts <= c.Lockout.FreeAttempts {
+			errs = append(errs, "CALDAV_LOCKOUT_MAX_ATTEMPTS must be greater than CALDAV_LOCKOUT_FREE_ATTEMPTS")
+		}
+		if c.Lockout.BaseDelay <= 0 || c.Lockout.Duration <= 0 {
+			errs = append(errs, "CALDAV_LOCKOUT_BASE_DELAY and CALDAV_LOCKOUT_DURATION must be positive")
+		}
+	}
+
+	seen := map[string]bool{}
+	for i, p := range c.OAuth.Providers {
+		switch {
+		case !providerNamePattern.MatchString(p.Name):
+			errs = append(errs, fmt.Sprintf("oauth.providers[%d]: name %q must be lowercase letters, digits and dashes", i, p.Name))
+		case reservedProviderNames[p.Name]:
+			errs = append(errs, fmt.Sprintf("oauth.providers[%d]: name %q is reserved", i, p.Name))
+		case seen[p.Name]:
+			errs = append(errs, fmt.Sprintf("oauth.providers[%d]: duplicate name %q", i, p.Name))
+		}
+		seen[p.Name] = true
+		if p.Issuer == "" || p.ClientID == "" {
+			errs = append(errs, fmt.Sprintf("oauth.providers[%d]: issuer and client_id are required", i))
+		}
+	}
+
+	if c.Encryption.MasterKey != "" && c.Encryption.MasterKeyFile != "" {
+		errs = append(errs, "CALDAV_ENCRYPTION_MASTER_KEY and CALDAV_ENCRYPTION_MASTER_KEY_FILE are mutually exclusive")
+	}
+	if c.Encryption.MasterKey != "" && !isAESKey(c.Encryption.MasterKey) {
+		errs = append(errs, "CALDAV_ENCRYPTION_MASTER_KEY must be a base64-encoded 32-byte key")
+	}
+	for i, k := range c.Encryption.PreviousKeys {
+		if !isAESKey(k) {
+			errs = append(errs, fmt.Sprintf("CALDAV_ENCRYPTION_PREVIOUS_KEYS[%d] must be a base64-encoded 32-byte key", i))
+		}
+	}
+	if !c.Encryption.Enabled() && (len(c.Encryption.PreviousKeys) > 0 || c.Encryption.EncryptPayloads) {
+		errs = append(errs, "encryption settings require CALDAV_ENCRYPTION_MASTER_KEY or CALDAV_ENCRYPTION_MASTER_KEY_FILE")
+	}
+
+	if c.Photos.MaxDimension < 0 || c.Photos.JPEGQuality < 0 || c.Photos.JPEGQuality > 100 {
+		errs = append(errs, "CALDAV_PHOTO_MAX_DIMENSION must be positive and CALDAV_PHOTO_JPEG_QUALITY between 1 and 100")
+	}
+	for i, size := range c.Photos.ThumbnailSizes {
+		if size <= 0 || (c.Photos.MaxDimension > 0 && size > c.Photos.MaxDimension) {
+			errs = append(errs, fmt.Sprintf("CALDAV_PHOTO_THUMBNAIL_SIZES[%d] must be between 1 and the maximum dimension", i))
+		}
+	}
+
+	if r := c.Contacts.DefaultPhoneRegion; r != "" && !phoneRegionPattern.MatchString(r) {
+		errs = append(errs, "CALDAV_CONTACTS_DEFAULT_PHONE_REGION must be a two-letter region code such as DE or US")
+	}
+
+	if c.Subscriptions.Enabled {
+		s := c.Subscriptions
+		if s.PollInterval <= 0 || s.MinRefresh <= 0 || s.Timeout <= 0 || s.MaxFeedBytes <= 0 {
+			errs = append(errs, "CALDAV_SUBSCRIPTIONS_POLL_INTERVAL, _MIN_REFRESH, _TIMEOUT and _MAX_FEED_BYTES must be positive")
+		}
+		if s.DefaultRefresh < s.MinRefresh {
+			errs = append(errs, "CALDAV_SUBSCRIPTIONS_DEFAULT_REFRESH must not be shorter than CALDAV_SUBSCRIPTIONS_MIN_REFRESH")
+		}
+	}
+	if c.Subscriptions.MaxPerUser < 0 {
+		errs = append(errs, "CALDAV_SUBSCRIPTIONS_MAX_PER_USER must not be negative")
+	}
+
+	if c.OAuthServer.Enabled {
+		if c.OAuthServer.CodeExpiry <= 0 || c.OAuthServer.AccessTokenExpiry <= 0 || c.OAuthServer.RefreshTokenExpiry <= 0 {
+			errs = append(errs, "CALDAV_OAUTH_SERVER_*_EXPIRY settings must be positive")
+		}
+	}
+
 	if len(errs) > 0 {
 		return fmt.Errorf("configuration errors:\n  - %s", strings.Join(errs, "\n  - "))
 	}
@@ -38,6 +38,22 @@ func TestLoadEnvOverrides(t *testing.T) {
 	assert.Equal(t, "testdb", cfg.Database.Name)
 }
 
+func TestOIDCProviders_Shorthands(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("CALDAV_OAUTH_MICROSOFT_CLIENT_ID", "id")
+	os.Setenv("CALDAV_OAUTH_MICROSOFT_CLIENT_SECRET", "secret")
+	os.Setenv("CALDAV_OAUTH_MICROSOFT_TRUST_EMAIL", "true")
+
+	cfg, err := Load("")
+	assert.NoError(t, err)
+
+	providers := cfg.OAuth.OIDCProviders()
+	if assert.Len(t, providers, 1) {
+		assert.Equal(t, "microsoft", providers[0].Name)
+		assert.True(t, providers[0].TrustEmail)
+	}
+}
+
 func TestLoadYAML(t *testing.T) {
 	os.Clearenv()
 	yamlContent := `
@@ -2,6 +2,7 @@ package config
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -72,6 +73,110 @@ func TestConfig_Validate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "Lockout threshold not above free attempts",
+			config: Config{
+				BaseURL: "http://localhost:8080",
+				JWT: JWTConfig{
+					Secret: "secure-secret-16",
+				},
+				Lockout: LockoutConfig{
+					Enabled:      true,
+					FreeAttempts: 5,
+					MaxAttempts:  5,
+					BaseDelay:    time.Second,
+					Duration:     time.Minute,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "OAuth server without token lifetime",
+			config: Config{
+				BaseURL: "http://localhost:8080",
+				JWT: JWTConfig{
+					Secret: "secure-secret-16",
+				},
+				OAuthServer: OAuthServerConfig{
+					Enabled:    true,
+					CodeExpiry: time.Minute,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Encryption master key too short",
+			config: Config{
+				BaseURL: "http://localhost:8080",
+				JWT: JWTConfig{
+					Secret: "secure-secret-16",
+				},
+				Encryption: EncryptionConfig{
+					MasterKey: "c2hvcnQ=",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Payload encryption without master key",
+			config: Config{
+				BaseURL: "http://localhost:8080",
+				JWT: JWTConfig{
+					Secret: "secure-secret-16",
+				},
+				Encryption: EncryptionConfig{
+					EncryptPayloads: true,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Named OIDC providers",
+			config: Config{
+				BaseURL: "http://localhost:8080",
+				JWT: JWTConfig{
+					Secret: "secure-secret-16",
+				},
+				OAuth: OAuthConfig{
+					Providers: []OIDCProviderConfig{
+						{Name: "keycloak", Issuer: "https://sso.example.com/realms/corp", ClientID: "caldav"},
+						{Name: "partner-entra", Issuer: "https://login.microsoftonline.com/tenant/v2.0", ClientID: "app"},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Duplicate OIDC provider name",
+			config: Config{
+				BaseURL: "http://localhost:8080",
+				JWT: JWTConfig{
+					Secret: "secure-secret-16",
+				},
+				OAuth: OAuthConfig{
+					Providers: []OIDCProviderConfig{
+						{Name: "keycloak", Issuer: "https://a.example.com", ClientID: "a"},
+						{Name: "keycloak", Issuer: "https://b.example.com", ClientID: "b"},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Reserved OIDC provider name",
+			config: Config{
+				BaseURL: "http://localhost:8080",
+				JWT: JWTConfig{
+					Secret: "secure-secret-16",
+				},
+				OAuth: OAuthConfig{
+					Providers: []OIDCProviderConfig{
+						{Name: "providers", Issuer: "https://a.example.com", ClientID: "a"},
+					},
+				},
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
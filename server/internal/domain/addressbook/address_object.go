@@ -16,7 +16,7 @@ type AddressObject struct {
 	Path          string `gorm:"size:255;not null"`
 	UID           string `gorm:"index;size:255;not null"` // vCard UID
 	ETag          string `gorm:"size:64;not null"`
-	VCardData     string `gorm:"type:text;not null"`
+	VCardData     string `gorm:"type:text;not null;serializer:encrypted_payload"`
 	VCardVersion  string `gorm:"size:5;not null"` // "3.0" or "4.0"
 	ContentLength int    `gorm:"not null"`
 	// Denormalized fields for search
@@ -26,14 +26,22 @@ type AddressObject struct {
 	Email         string `gorm:"size:255;index"` // Primary email
 	Phone         string `gorm:"size:50"`        // Primary phone
 	Organization  string `gorm:"size:255"`
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	DeletedAt     gorm.DeletedAt `gorm:"index"`
-	AddressBook   AddressBook    `gorm:"foreignKey:AddressBookID"`
+	Kind          string `gorm:"size:20;index"` // KindIndividual or KindGroup; empty for rows written before groups
+	// Search columns filled by IndexForSearch: every value of a property,
+	// folded (phones in E.164), delimited by SearchValueSeparator
+	SearchNames        string `gorm:"type:text"`
+	SearchEmails       string `gorm:"type:text"`
+	SearchPhones       string `gorm:"type:text"`
+	SearchOrganization string `gorm:"type:text"`
+	SearchKey          string `gorm:"size:20;index"` // SearchIndexKey the columns were built with
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+	DeletedAt          gorm.DeletedAt `gorm:"index"`
+	AddressBook        AddressBook    `gorm:"foreignKey:AddressBookID"`
 }
 
 // PopulateDenormFieldsFromVCard parses VCardData and mirrors a small set of
-// properties (FN, N, EMAIL, TEL, ORG) into the denormalized columns used for
+// properties (FN, N, EMAIL, TEL, ORG, KIND) into the denormalized columns used for
 // list views and search indexes. Every write path that mutates VCardData
 // should call this (or ExtractDenormFieldsFromCard, if a parsed Card is
 // already on hand) so the columns never drift from the canonical vCard blob.
@@ -73,4 +81,5 @@ func ExtractDenormFieldsFromCard(card vcard.Card, o *AddressObject) {
 	o.Email = card.PreferredValue(vcard.FieldEmail)
 	o.Phone = card.PreferredValue(vcard.FieldTelephone)
 	o.Organization = card.PreferredValue(vcard.FieldOrganization)
+	o.Kind = CardKind(card)
 }
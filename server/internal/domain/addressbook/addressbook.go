@@ -9,19 +9,23 @@ import (
 )
 
 type AddressBook struct {
-	ID          uint   `gorm:"primaryKey"`
-	UUID        string `gorm:"uniqueIndex;size:36;not null"`
-	UserID      uint   `gorm:"index;not null"`
-	Path        string `gorm:"size:255;not null"`
-	Name        string `gorm:"size:255;not null"`
-	Description string `gorm:"size:1000"`
-	SyncToken   string `gorm:"size:64;not null"`
-	CTag        string `gorm:"size:64;not null"`
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	DeletedAt   gorm.DeletedAt  `gorm:"index"`
-	User        user.User       `gorm:"foreignKey:UserID"`
-	Contacts    []AddressObject `gorm:"foreignKey:AddressBookID"`
+	ID              uint    `gorm:"primaryKey"`
+	UUID            string  `gorm:"uniqueIndex;size:36;not null"`
+	UserID          uint    `gorm:"index;not null"`
+	Path            string  `gorm:"size:255;not null"`
+	Name            string  `gorm:"size:255;not null"`
+	Description     string  `gorm:"size:1000"`
+	SyncToken       string  `gorm:"size:64;not null"`
+	CTag            string  `gorm:"size:64;not null"`
+	PublicToken     *string `gorm:"uniqueIndex;size:64" json:"-"`
+	PublicEnabled   bool    `gorm:"default:false"`
+	PublicEnabledAt *time.Time
+	PublicOptions   PublicOptions `gorm:"embedded;embeddedPrefix:public_" json:"-"`
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	DeletedAt       gorm.DeletedAt  `gorm:"index"`
+	User            user.User       `gorm:"foreignKey:UserID"`
+	Contacts        []AddressObject `gorm:"foreignKey:AddressBookID"`
 }
 
 // GenerateSyncToken generates a new sync token
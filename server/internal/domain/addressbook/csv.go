@@ -0,0 +1,969 @@
+package addressbook
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-vcard"
+)
+
+// ErrInvalidCSVMapping is returned for user-defined mappings that name
+// unknown fields
+var ErrInvalidCSVMapping = errors.New("invalid CSV mapping")
+
+// CSV column targets. Email, phone, URL and address columns may carry vCard
+// TYPE values; address components with the same type form one address.
+const (
+	CSVFullName     = "full_name"
+	CSVGivenName    = "given_name"
+	CSVMiddleName   = "middle_name"
+	CSVFamilyName   = "family_name"
+	CSVPrefix       = "prefix"
+	CSVSuffix       = "suffix"
+	CSVNickname     = "nickname"
+	CSVOrganization = "organization"
+	CSVDepartment   = "department"
+	CSVTitle        = "title"
+	CSVBirthday     = "birthday"
+	CSVAnniversary  = "anniversary"
+	CSVNote         = "note"
+	CSVCategories   = "categories"
+	CSVEmail        = "email"
+	CSVPhone        = "phone"
+	CSVURL          = "url"
+	CSVStreet       = "address.street"
+	CSVExtended     = "address.extended"
+	CSVPOBox        = "address.po_box"
+	CSVCity         = "address.city"
+	CSVRegion       = "address.region"
+	CSVPostalCode   = "address.postal_code"
+	CSVCountry      = "address.country"
+)
+
+var csvFields = []string{
+	CSVFullName, CSVGivenName, CSVMiddleName, CSVFamilyName, CSVPrefix, CSVSuffix, CSVNickname,
+	CSVOrganization, CSVDepartment, CSVTitle, CSVBirthday, CSVAnniversary, CSVNote, CSVCategories,
+	CSVEmail, CSVPhone, CSVURL,
+	CSVStreet, CSVExtended, CSVPOBox, CSVCity, CSVRegion, CSVPostalCode, CSVCountry,
+}
+
+// CSVColumn maps one CSV column to a contact field. "{n}" in a header
+// matches a number, so "E-mail {n} - Value" covers every numbered e-mail
+// column of a Google export.
+type CSVColumn struct {
+	Header  string
+	Aliases []string // Other headers accepted on import, e.g. of older export formats
+	Field   string
+	Type    string // Comma-separated vCard TYPE values
+	// TypeHeaders name the column holding a type label for this value,
+	// with "{n}" replaced by the same number. The first one is written on
+	// export.
+	TypeHeaders []string
+}
+
+// CSVMapping describes the column layout of a CSV contact file
+type CSVMapping struct {
+	Name               string
+	Columns            []CSVColumn
+	CategorySeparator  string
+	BirthdayLayout     string // Go time layout used on export
+	MultiValueSplitter string // Separator of several values in one cell, if any
+}
+
+// Names of the built-in CSV mappings
+const (
+	CSVMappingGoogle  = "google"
+	CSVMappingOutlook = "outlook"
+	CSVMappingCustom  = "custom"
+)
+
+// GoogleCSVMapping is the Google Contacts export format. Older exports
+// ("Given Name", "E-mail 1 - Type", ...) are accepted on import as well.
+var GoogleCSVMapping = CSVMapping{
+	Name: CSVMappingGoogle,
+	Columns: []CSVColumn{
+		{Header: "First Name", Aliases: []string{"Given Name"}, Field: CSVGivenName},
+		{Header: "Middle Name", Aliases: []string{"Additional Name"}, Field: CSVMiddleName},
+		{Header: "Last Name", Aliases: []string{"Family Name"}, Field: CSVFamilyName},
+		{Header: "Name Prefix", Field: CSVPrefix},
+		{Header: "Name Suffix", Field: CSVSuffix},
+		{Header: "Nickname", Field: CSVNickname},
+		{Header: "File As", Aliases: []string{"Name"}, Field: CSVFullName},
+		{Header: "Organization Name", Aliases: []string{"Organization 1 - Name"}, Field: CSVOrganization},
+		{Header: "Organization Title", Aliases: []string{"Organization 1 - Title"}, Field: CSVTitle},
+		{Header: "Organization Department", Aliases: []string{"Organization 1 - Department"}, Field: CSVDepartment},
+		{Header: "Birthday", Field: CSVBirthday},
+		{Header: "Notes", Field: CSVNote},
+		{Header: "Labels", Aliases: []string{"Group Membership"}, Field: CSVCategories},
+		{Header: "E-mail {n} - Value", Field: CSVEmail, TypeHeaders: []string{"E-mail {n} - Label", "E-mail {n} - Type"}},
+		{Header: "Phone {n} - Value", Field: CSVPhone, TypeHeaders: []string{"Phone {n} - Label", "Phone {n} - Type"}},
+		{Header: "Address {n} - Street", Field: CSVStreet, TypeHeaders: []string{"Address {n} - Label", "Address {n} - Type"}},
+		{Header: "Address {n} - City", Field: CSVCity},
+		{Header: "Address {n} - PO Box", Field: CSVPOBox},
+		{Header: "Address {n} - Region", Field: CSVRegion},
+		{Header: "Address {n} - Postal Code", Field: CSVPostalCode},
+		{Header: "Address {n} - Country", Field: CSVCountry},
+		{Header: "Address {n} - Extended Address", Field: CSVExtended},
+		{Header: "Website {n} - Value", Field: CSVURL, TypeHeaders: []string{"Website {n} - Label", "Website {n} - Type"}},
+	},
+	CategorySeparator:  " ::: ",
+	BirthdayLayout:     "2006-01-02",
+	MultiValueSplitter: " ::: ",
+}
+
+// OutlookCSVMapping is the Outlook "Comma Separated Values" export format
+var OutlookCSVMapping = CSVMapping{
+	Name: CSVMappingOutlook,
+	Columns: []CSVColumn{
+		{Header: "Title", Field: CSVPrefix},
+		{Header: "First Name", Field: CSVGivenName},
+		{Header: "Middle Name", Field: CSVMiddleName},
+		{Header: "Last Name", Field: CSVFamilyName},
+		{Header: "Suffix", Field: CSVSuffix},
+		{Header: "Nickname", Field: CSVNickname},
+		{Header: "Company", Field: CSVOrganization},
+		{Header: "Department", Field: CSVDepartment},
+		{Header: "Job Title", Field: CSVTitle},
+		{Header: "Business Street", Field: CSVStreet, Type: "work"},
+		{Header: "Business City", Field: CSVCity, Type: "work"},
+		{Header: "Business State", Field: CSVRegion, Type: "work"},
+		{Header: "Business Postal Code", Field: CSVPostalCode, Type: "work"},
+		{Header: "Business Country/Region", Field: CSVCountry, Type: "work"},
+		{Header: "Home Street", Field: CSVStreet, Type: "home"},
+		{Header: "Home City", Field: CSVCity, Type: "home"},
+		{Header: "Home State", Field: CSVRegion, Type: "home"},
+		{Header: "Home Postal Code", Field: CSVPostalCode, Type: "home"},
+		{Header: "Home Country/Region", Field: CSVCountry, Type: "home"},
+		{Header: "Other Street", Field: CSVStreet},
+		{Header: "Other City", Field: CSVCity},
+		{Header: "Other State", Field: CSVRegion},
+		{Header: "Other Postal Code", Field: CSVPostalCode},
+		{Header: "Other Country/Region", Field: CSVCountry},
+		{Header: "Business Fax", Field: CSVPhone, Type: "work,fax"},
+		{Header: "Business Phone", Field: CSVPhone, Type: "work"},
+		{Header: "Business Phone 2", Field: CSVPhone, Type: "work"},
+		{Header: "Home Fax", Field: CSVPhone, Type: "home,fax"},
+		{Header: "Home Phone", Field: CSVPhone, Type: "home"},
+		{Header: "Home Phone 2", Field: CSVPhone, Type: "home"},
+		{Header: "Mobile Phone", Field: CSVPhone, Type: "cell"},
+		{Header: "Pager", Field: CSVPhone, Type: "pager"},
+		{Header: "Other Phone", Field: CSVPhone},
+		{Header: "Anniversary", Field: CSVAnniversary},
+		{Header: "Birthday", Field: CSVBirthday},
+		{Header: "Categories", Field: CSVCategories},
+		{Header: "E-mail Address", Field: CSVEmail},
+		{Header: "E-mail 2 Address", Field: CSVEmail},
+		{Header: "E-mail 3 Address", Field: CSVEmail},
+		{Header: "Notes", Field: CSVNote},
+		{Header: "Web Page", Field: CSVURL},
+		{Header: "Personal Web Page", Field: CSVURL, Type: "home"},
+	},
+	CategorySeparator: ";",
+	BirthdayLayout:    "1/2/2006",
+}
+
+// CSVMappingByName returns a built-in mapping
+func CSVMappingByName(name string) (CSVMapping, bool) {
+	switch strings.ToLower(name) {
+	case CSVMappingGoogle:
+		return GoogleCSVMapping, true
+	case CSVMappingOutlook:
+		return OutlookCSVMapping, true
+	}
+	return CSVMapping{}, false
+}
+
+// NewCSVMapping builds a user-defined mapping from CSV headers to fields.
+// A field may be followed by TYPE values, e.g. "phone:cell" or
+// "address.city:work,pref".
+func NewCSVMapping(columns map[string]string) (CSVMapping, error) {
+	if len(columns) == 0 {
+		return CSVMapping{}, fmt.Errorf("%w: no columns", ErrInvalidCSVMapping)
+	}
+
+	headers := make([]string, 0, len(columns))
+	for header := range columns {
+		headers = append(headers, header)
+	}
+	sort.Strings(headers)
+
+	mapping := CSVMapping{
+		Name:              CSVMappingCustom,
+		CategorySeparator: ",",
+		BirthdayLayout:    "2006-01-02",
+	}
+	for _, header := range headers {
+		field, types, _ := strings.Cut(strings.ToLower(strings.TrimSpace(columns[header])), ":")
+		if !isCSVField(field) {
+			return CSVMapping{}, fmt.Errorf("%w: unknown field %q for column %q", ErrInvalidCSVMapping, field, header)
+		}
+		if types != "" && !isTypedCSVField(field) {
+			return CSVMapping{}, fmt.Errorf("%w: field %q does not take a type", ErrInvalidCSVMapping, field)
+		}
+		mapping.Columns = append(mapping.Columns, CSVColumn{Header: header, Field: field, Type: types})
+	}
+	return mapping, nil
+}
+
+func isCSVField(field string) bool {
+	for _, f := range csvFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+func isTypedCSVField(field string) bool {
+	return field == CSVEmail || field == CSVPhone || field == CSVURL || isAddressField(field)
+}
+
+func isAddressField(field string) bool {
+	return strings.HasPrefix(field, "address.")
+}
+
+// CSVRow is one data row of a CSV file, converted to a vCard 3.0 card
+type CSVRow struct {
+	Index int // 0-based, not counting the header
+	Card  vcard.Card
+	Err   error
+}
+
+// boundColumn is a mapping column resolved against the header of a file
+type boundColumn struct {
+	index     int
+	column    CSVColumn
+	typeIndex int    // Column holding the type label, -1 if none
+	group     string // Address grouping key
+}
+
+// ParseCSV reads a CSV contact file with a header row. Semicolon and tab
+// separated files, as spreadsheets write them in many locales, are detected
+// from the header. Rows that cannot be converted carry an error; blank rows
+// are left out.
+func ParseCSV(r io.Reader, mapping CSVMapping) ([]CSVRow, error) {
+	buffered := bufio.NewReader(r)
+	firstLine, _ := buffered.Peek(4096)
+	if i := bytes.IndexByte(firstLine, '\n'); i >= 0 {
+		firstLine = firstLine[:i]
+	}
+
+	reader := csv.NewReader(buffered)
+	reader.Comma = csvDelimiter(firstLine)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("CSV file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff") // UTF-8 byte order mark
+	}
+
+	columns := bindColumns(header, mapping)
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("no column of the CSV header matches the %s mapping", mapping.Name)
+	}
+
+	var rows []CSVRow
+	for index := 0; ; index++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				rows = append(rows, CSVRow{Index: index, Err: err})
+				continue
+			}
+			return nil, fmt.Errorf("failed to read CSV: %w", err)
+		}
+		if isBlankRecord(record) {
+			index--
+			continue
+		}
+
+		card, err := csvRecordToCard(record, columns, mapping)
+		rows = append(rows, CSVRow{Index: index, Card: card, Err: err})
+	}
+	return rows, nil
+}
+
+// csvDelimiter picks the most frequent separator of a header line
+func csvDelimiter(header []byte) rune {
+	delimiter, most := ',', bytes.Count(header, []byte{','})
+	for _, candidate := range []rune{';', '\t'} {
+		if n := bytes.Count(header, []byte(string(candidate))); n > most {
+			delimiter, most = candidate, n
+		}
+	}
+	return delimiter
+}
+
+func bindColumns(header []string, mapping CSVMapping) []boundColumn {
+	byHeader := make(map[string]int, len(header))
+	for i, h := range header {
+		byHeader[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+
+	var bound []boundColumn
+	for i, h := range header {
+		h = strings.TrimSpace(h)
+		for _, col := range mapping.Columns {
+			n, ok := matchCSVHeader(col, h)
+			if !ok {
+				continue
+			}
+			b := boundColumn{index: i, column: col, typeIndex: -1, group: strings.ToLower(col.Type)}
+			if n != "" {
+				b.group = n
+			}
+			for _, th := range col.TypeHeaders {
+				if idx, ok := byHeader[strings.ToLower(strings.ReplaceAll(th, "{n}", n))]; ok {
+					b.typeIndex = idx
+					break
+				}
+			}
+			bound = append(bound, b)
+			break
+		}
+	}
+	return bound
+}
+
+// matchCSVHeader reports whether a file header belongs to a column and
+// returns the number matched by "{n}"
+func matchCSVHeader(col CSVColumn, header string) (string, bool) {
+	for _, pattern := range append([]string{col.Header}, col.Aliases...) {
+		prefix, suffix, numbered := strings.Cut(pattern, "{n}")
+		if !numbered {
+			if strings.EqualFold(pattern, header) {
+				return "", true
+			}
+			continue
+		}
+		if len(header) <= len(prefix)+len(suffix) ||
+			!strings.EqualFold(header[:len(prefix)], prefix) ||
+			!strings.EqualFold(header[len(header)-len(suffix):], suffix) {
+			continue
+		}
+		n := header[len(prefix) : len(header)-len(suffix)]
+		if _, err := strconv.Atoi(n); err == nil {
+			return n, true
+		}
+	}
+	return "", false
+}
+
+func isBlankRecord(record []string) bool {
+	for _, v := range record {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func csvRecordToCard(record []string, columns []boundColumn, mapping CSVMapping) (vcard.Card, error) {
+	cell := func(i int) string {
+		if i < 0 || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(unescapeCSVFormula(record[i]))
+	}
+
+	single := make(map[string]string)
+	var categories []string
+	card := make(vcard.Card)
+	var addrOrder []string
+	addresses := make(map[string]*vcard.Address)
+
+	for _, col := range columns {
+		value := cell(col.index)
+		if value == "" {
+			continue
+		}
+		field := col.column.Field
+		types, pref := csvTypes(col.column.Type)
+		if label := cell(col.typeIndex); label != "" {
+			types, pref = csvLabelTypes(label)
+		}
+
+		switch {
+		case field == CSVCategories:
+			categories = append(categories, splitCategories(value, mapping.CategorySeparator)...)
+		case field == CSVEmail || field == CSVPhone || field == CSVURL:
+			name := map[string]string{CSVEmail: vcard.FieldEmail, CSVPhone: vcard.FieldTelephone, CSVURL: vcard.FieldURL}[field]
+			for _, v := range splitMultiValue(value, mapping.MultiValueSplitter) {
+				card.Add(name, &vcard.Field{Value: v, Params: csvParams(types, pref)})
+			}
+		case isAddressField(field):
+			addr, ok := addresses[col.group]
+			if !ok {
+				addr = &vcard.Address{Field: &vcard.Field{Params: csvParams(types, pref)}}
+				addresses[col.group] = addr
+				addrOrder = append(addrOrder, col.group)
+			} else if col.typeIndex >= 0 && len(addr.Field.Params.Types()) == 0 {
+				addr.Field.Params = csvParams(types, pref)
+			}
+			setAddressPart(addr, field, structuredPart(value))
+		default:
+			if _, ok := single[field]; !ok {
+				single[field] = value
+			}
+		}
+	}
+
+	card.SetValue(vcard.FieldVersion, VCardVersion3)
+	card.SetName(&vcard.Name{
+		FamilyName:      structuredPart(single[CSVFamilyName]),
+		GivenName:       structuredPart(single[CSVGivenName]),
+		AdditionalName:  structuredPart(single[CSVMiddleName]),
+		HonorificPrefix: structuredPart(single[CSVPrefix]),
+		HonorificSuffix: structuredPart(single[CSVSuffix]),
+	})
+	if v := single[CSVNickname]; v != "" {
+		card.SetValue(vcard.FieldNickname, v)
+	}
+	if org := single[CSVOrganization]; org != "" || single[CSVDepartment] != "" {
+		value := structuredPart(org)
+		if dep := single[CSVDepartment]; dep != "" {
+			value += ";" + structuredPart(dep)
+		}
+		card.SetValue(vcard.FieldOrganization, value)
+	}
+	if v := single[CSVTitle]; v != "" {
+		card.SetValue(vcard.FieldTitle, v)
+	}
+	if v := single[CSVNote]; v != "" {
+		card.SetValue(vcard.FieldNote, v)
+	}
+	for _, pair := range []struct{ field, property string }{
+		{CSVBirthday, vcard.FieldBirthday},
+		{CSVAnniversary, v4OnlyFields[vcard.FieldAnniversary]},
+	} {
+		date, err := parseCSVDate(single[pair.field])
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q", pair.field, single[pair.field])
+		}
+		if date != "" {
+			card.SetValue(pair.property, date)
+		}
+	}
+	// One property per category: go-vcard escapes commas within a value
+	for _, category := range categories {
+		card.Add(vcard.FieldCategories, &vcard.Field{Value: category})
+	}
+	for _, key := range addrOrder {
+		card.AddAddress(addresses[key])
+	}
+
+	fn := single[CSVFullName]
+	if fn == "" {
+		fn = joinNonEmpty(" ", single[CSVPrefix], single[CSVGivenName], single[CSVMiddleName], single[CSVFamilyName], single[CSVSuffix])
+	}
+	for _, fallback := range []string{single[CSVNickname], single[CSVOrganization], card.Value(vcard.FieldEmail)} {
+		if fn == "" {
+			fn = fallback
+		}
+	}
+	if fn == "" {
+		return nil, fmt.Errorf("row has no name, organization or e-mail address")
+	}
+	card.SetValue(vcard.FieldFormattedName, fn)
+	return card, nil
+}
+
+// structuredPart keeps a cell from splitting a structured value such as N
+// or ADR, whose components are separated by semicolons
+func structuredPart(value string) string {
+	return strings.ReplaceAll(value, ";", ",")
+}
+
+func setAddressPart(addr *vcard.Address, field, value string) {
+	switch field {
+	case CSVStreet:
+		addr.StreetAddress = value
+	case CSVExtended:
+		addr.ExtendedAddress = value
+	case CSVPOBox:
+		addr.PostOfficeBox = value
+	case CSVCity:
+		addr.Locality = value
+	case CSVRegion:
+		addr.Region = value
+	case CSVPostalCode:
+		addr.PostalCode = value
+	case CSVCountry:
+		addr.Country = value
+	}
+}
+
+func addressPart(addr *vcard.Address, field string) string {
+	switch field {
+	case CSVStreet:
+		return addr.StreetAddress
+	case CSVExtended:
+		return addr.ExtendedAddress
+	case CSVPOBox:
+		return addr.PostOfficeBox
+	case CSVCity:
+		return addr.Locality
+	case CSVRegion:
+		return addr.Region
+	case CSVPostalCode:
+		return addr.PostalCode
+	case CSVCountry:
+		return addr.Country
+	}
+	return ""
+}
+
+func splitMultiValue(value, separator string) []string {
+	if separator == "" {
+		return []string{value}
+	}
+	var values []string
+	for _, v := range strings.Split(value, separator) {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// splitCategories splits a category cell, dropping Google's system labels
+// such as "* myContacts"
+func splitCategories(value, separator string) []string {
+	var categories []string
+	for _, c := range splitMultiValue(value, separator) {
+		if !strings.HasPrefix(c, "* ") {
+			categories = append(categories, strings.ReplaceAll(c, ",", " "))
+		}
+	}
+	return categories
+}
+
+func csvTypes(value string) ([]string, bool) {
+	var types []string
+	pref := false
+	for _, t := range strings.Split(strings.ToLower(value), ",") {
+		switch t = strings.TrimSpace(t); t {
+		case "":
+		case "pref":
+			pref = true
+		default:
+			types = append(types, t)
+		}
+	}
+	return types, pref
+}
+
+// csvLabelTypes maps a type label such as Google's "* Mobile" or "Work Fax"
+// to vCard TYPE values. A leading "*" marks the preferred value.
+func csvLabelTypes(label string) ([]string, bool) {
+	label = strings.ToLower(strings.TrimSpace(label))
+	pref := strings.HasPrefix(label, "*")
+	label = strings.TrimSpace(strings.TrimPrefix(label, "*"))
+
+	var types []string
+	for _, word := range strings.Fields(label) {
+		switch word {
+		case "home", "personal":
+			types = append(types, "home")
+		case "work", "business":
+			types = append(types, "work")
+		case "mobile", "cell":
+			types = append(types, "cell")
+		case "fax":
+			types = append(types, "fax")
+		case "pager":
+			types = append(types, "pager")
+		case "main":
+			types = append(types, "voice")
+		}
+	}
+	return types, pref
+}
+
+func csvParams(types []string, pref bool) vcard.Params {
+	params := make(vcard.Params)
+	if pref {
+		types = append(types, "pref")
+	}
+	for _, t := range types {
+		params.Add(vcard.ParamType, t)
+	}
+	return params
+}
+
+// csvDateLayouts are tried in order when reading birthdays and anniversaries
+var csvDateLayouts = []string{
+	"2006-01-02", "20060102", "2006/01/02", "1/2/2006", "1/2/06", "2.1.2006", "January 2, 2006", "Jan 2, 2006",
+}
+
+// parseCSVDate normalizes a date cell to the vCard date form. Dates without
+// a year ("--05-17", as written by Google) are kept. Outlook writes "0/0/00"
+// for no date.
+func parseCSVDate(value string) (string, error) {
+	if value == "" || value == "0/0/00" || value == "0/0/0000" {
+		return "", nil
+	}
+	if strings.HasPrefix(value, "--") {
+		if t, err := time.Parse("--01-02", value); err == nil {
+			return t.Format("--01-02"), nil
+		}
+		if t, err := time.Parse("--0102", value); err == nil {
+			return t.Format("--01-02"), nil
+		}
+	}
+	for _, layout := range csvDateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.Format("2006-01-02"), nil
+		}
+	}
+	return "", fmt.Errorf("unrecognized date %q", value)
+}
+
+func joinNonEmpty(sep string, values ...string) string {
+	var parts []string
+	for _, v := range values {
+		if v != "" {
+			parts = append(parts, v)
+		}
+	}
+	return strings.Join(parts, sep)
+}
+
+// WriteCSV writes cards as CSV with the mapping's header. Numbered columns
+// are repeated as often as the card with the most values needs. Cards are
+// read as vCard 3.0; ConvertVCard them first if needed.
+func WriteCSV(w io.Writer, cards []vcard.Card, mapping CSVMapping) error {
+	header, cells := csvLayout(cards, mapping)
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for _, card := range cards {
+		record := make([]string, len(header))
+		for _, c := range cells {
+			c.fill(record, card, mapping)
+		}
+		for i, value := range record {
+			record[i] = escapeCSVFormula(value)
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// csvFormulaPrefixes start the cells spreadsheets evaluate as a formula
+const csvFormulaPrefixes = "=+-@\t\r"
+
+// escapeCSVFormula prefixes cells that spreadsheets would evaluate as a
+// formula with an apostrophe, which they show as text instead (CSV
+// injection). Phone numbers such as "+49 30 1234567" are left alone: they
+// can't call a function, and Google Contacts and Outlook would import the
+// apostrophe literally. ParseCSV removes the apostrophe again.
+func escapeCSVFormula(value string) string {
+	if value == "" || !strings.ContainsRune(csvFormulaPrefixes, rune(value[0])) {
+		return value
+	}
+	if (value[0] == '+' || value[0] == '-') && isPhoneLike(value) {
+		return value
+	}
+	return "'" + value
+}
+
+// unescapeCSVFormula reverses escapeCSVFormula
+func unescapeCSVFormula(value string) string {
+	if len(value) > 1 && value[0] == '\'' && strings.ContainsRune(csvFormulaPrefixes, rune(value[1])) {
+		return value[1:]
+	}
+	return value
+}
+
+// csvCell writes one field of a card into its column(s) of a record
+type csvCell struct {
+	index     int
+	typeIndex int // -1 without a type column
+	column    CSVColumn
+	n         int // 1-based position for numbered columns, 0 otherwise
+	slot      int // Position among fixed columns of the same field family
+}
+
+func csvLayout(cards []vcard.Card, mapping CSVMapping) ([]string, []csvCell) {
+	var header []string
+	var cells []csvCell
+	done := make(map[string]bool)
+	slots := make(map[string]int)
+
+	for _, col := range mapping.Columns {
+		family := csvFamily(col.Field)
+		if !strings.Contains(col.Header, "{n}") {
+			cells = append(cells, csvCell{index: len(header), typeIndex: -1, column: col, slot: slots[family+"|"+col.Field]})
+			slots[family+"|"+col.Field]++
+			header = append(header, col.Header)
+			continue
+		}
+		if done[family] {
+			continue
+		}
+		done[family] = true
+
+		// Emit the whole numbered block of this family at once
+		count := 0
+		for _, card := range cards {
+			count = max(count, csvFamilyCount(card, family))
+		}
+		for n := 1; n <= count; n++ {
+			typeIndex := -1
+			for _, other := range mapping.Columns {
+				if csvFamily(other.Field) != family || !strings.Contains(other.Header, "{n}") {
+					continue
+				}
+				if len(other.TypeHeaders) > 0 && typeIndex < 0 {
+					typeIndex = len(header)
+					header = append(header, strings.ReplaceAll(other.TypeHeaders[0], "{n}", strconv.Itoa(n)))
+				}
+				cells = append(cells, csvCell{index: len(header), typeIndex: typeIndex, column: other, n: n})
+				header = append(header, strings.ReplaceAll(other.Header, "{n}", strconv.Itoa(n)))
+			}
+		}
+	}
+	return header, cells
+}
+
+// csvFamily groups fields that are filled from the same vCard property
+func csvFamily(field string) string {
+	if isAddressField(field) {
+		return "address"
+	}
+	return field
+}
+
+func csvFamilyCount(card vcard.Card, family string) int {
+	switch family {
+	case "address":
+		return len(card[vcard.FieldAddress])
+	case CSVEmail:
+		return len(card[vcard.FieldEmail])
+	case CSVPhone:
+		return len(card[vcard.FieldTelephone])
+	case CSVURL:
+		return len(card[vcard.FieldURL])
+	}
+	return 0
+}
+
+func (c csvCell) fill(record []string, card vcard.Card, mapping CSVMapping) {
+	field := c.column.Field
+	if family := csvFamily(field); family == CSVEmail || family == CSVPhone || family == CSVURL || family == "address" {
+		f := c.pick(card, mapping)
+		if f == nil {
+			return
+		}
+		if family == "address" {
+			for _, addr := range card.Addresses() {
+				if addr.Field == f {
+					record[c.index] = addressPart(addr, field)
+				}
+			}
+		} else {
+			record[c.index] = f.Value
+		}
+		if c.typeIndex >= 0 {
+			record[c.typeIndex] = csvLabel(f.Params)
+		}
+		return
+	}
+
+	name := card.Name()
+	if name == nil {
+		name = &vcard.Name{}
+	}
+	org := strings.SplitN(card.PreferredValue(vcard.FieldOrganization), ";", 2)
+	switch field {
+	case CSVFullName:
+		record[c.index] = card.PreferredValue(vcard.FieldFormattedName)
+	case CSVGivenName:
+		record[c.index] = name.GivenName
+	case CSVMiddleName:
+		record[c.index] = name.AdditionalName
+	case CSVFamilyName:
+		record[c.index] = name.FamilyName
+	case CSVPrefix:
+		record[c.index] = name.HonorificPrefix
+	case CSVSuffix:
+		record[c.index] = name.HonorificSuffix
+	case CSVNickname:
+		record[c.index] = card.PreferredValue(vcard.FieldNickname)
+	case CSVOrganization:
+		record[c.index] = org[0]
+	case CSVDepartment:
+		if len(org) > 1 {
+			record[c.index] = strings.ReplaceAll(org[1], ";", " ")
+		}
+	case CSVTitle:
+		record[c.index] = card.PreferredValue(vcard.FieldTitle)
+	case CSVBirthday:
+		record[c.index] = formatCSVDate(card.Value(vcard.FieldBirthday), mapping.BirthdayLayout)
+	case CSVAnniversary:
+		value := card.Value(v4OnlyFields[vcard.FieldAnniversary])
+		if value == "" {
+			value = card.Value(vcard.FieldAnniversary)
+		}
+		record[c.index] = formatCSVDate(value, mapping.BirthdayLayout)
+	case CSVNote:
+		record[c.index] = card.PreferredValue(vcard.FieldNote)
+	case CSVCategories:
+		var categories []string
+		for _, f := range card[vcard.FieldCategories] {
+			categories = append(categories, splitMultiValue(f.Value, ",")...)
+		}
+		record[c.index] = strings.Join(categories, mapping.CategorySeparator)
+	}
+}
+
+// pick chooses the property value written into a cell. Numbered columns
+// take the n-th value; fixed columns take values by best matching TYPE, in
+// the order the mapping lists them.
+func (c csvCell) pick(card vcard.Card, mapping CSVMapping) *vcard.Field {
+	var fields []*vcard.Field
+	switch csvFamily(c.column.Field) {
+	case "address":
+		fields = card[vcard.FieldAddress]
+	case CSVEmail:
+		fields = card[vcard.FieldEmail]
+	case CSVPhone:
+		fields = card[vcard.FieldTelephone]
+	case CSVURL:
+		fields = card[vcard.FieldURL]
+	}
+	if c.n > 0 {
+		if c.n <= len(fields) {
+			return fields[c.n-1]
+		}
+		return nil
+	}
+
+	// Replay the assignment for the columns of this family so every value
+	// lands in exactly one column
+	family := csvFamily(c.column.Field)
+	var slots []CSVColumn
+	for _, col := range mapping.Columns {
+		if csvFamily(col.Field) != family || strings.Contains(col.Header, "{n}") {
+			continue
+		}
+		if family == "address" && col.Field != c.column.Field {
+			continue // One address component stands for the whole slot
+		}
+		slots = append(slots, col)
+	}
+
+	assigned := make([]*vcard.Field, len(slots))
+	for _, f := range fields {
+		types, _ := csvTypes(strings.Join(f.Params.Types(), ","))
+		best, bestScore := -1, -1
+		for i, slot := range slots {
+			if assigned[i] != nil {
+				continue
+			}
+			slotTypes, _ := csvTypes(slot.Type)
+			if score, ok := typeMatch(slotTypes, types); ok && score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+		if best >= 0 {
+			assigned[best] = f
+		}
+	}
+	if c.slot < len(assigned) {
+		return assigned[c.slot]
+	}
+	return nil
+}
+
+// typeMatch reports whether a value with the given types fits a column
+// and how many of the column's types it matched
+func typeMatch(columnTypes, valueTypes []string) (int, bool) {
+	for _, ct := range columnTypes {
+		found := false
+		for _, vt := range valueTypes {
+			if strings.EqualFold(ct, vt) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, false
+		}
+	}
+	return len(columnTypes), true
+}
+
+// csvLabel turns TYPE values into a Google style label
+func csvLabel(params vcard.Params) string {
+	types, pref := csvTypes(strings.Join(params.Types(), ","))
+	has := func(t string) bool {
+		for _, v := range types {
+			if v == t {
+				return true
+			}
+		}
+		return false
+	}
+
+	var label string
+	switch {
+	case has("fax") && has("work"):
+		label = "Work Fax"
+	case has("fax"):
+		label = "Home Fax"
+	case has("cell"):
+		label = "Mobile"
+	case has("pager"):
+		label = "Pager"
+	case has("work"):
+		label = "Work"
+	case has("home"):
+		label = "Home"
+	case has("voice"):
+		label = "Main"
+	default:
+		label = "Other"
+	}
+	if pref {
+		label = "* " + label
+	}
+	return label
+}
+
+func formatCSVDate(value, layout string) string {
+	if value == "" || layout == "" {
+		return value
+	}
+	for _, l := range []string{"2006-01-02", "20060102"} {
+		if t, err := time.Parse(l, value); err == nil {
+			return t.Format(layout)
+		}
+	}
+	return value
+}
@@ -0,0 +1,225 @@
+package addressbook
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+
+	"github.com/emersion/go-vcard"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const googleCSV = "\ufeffFirst Name,Middle Name,Last Name,Nickname,Organization Name,Organization Title,Birthday,Notes,Labels," +
+	"E-mail 1 - Label,E-mail 1 - Value,E-mail 2 - Label,E-mail 2 - Value,Phone 1 - Label,Phone 1 - Value," +
+	"Address 1 - Label,Address 1 - Formatted,Address 1 - Street,Address 1 - City,Address 1 - Postal Code,Address 1 - Country\n" +
+	"Jane,Q,Doe,JD,Acme,Engineer,1990-05-17,Likes tea,Friends ::: * myContacts," +
+	"* Work,jane@acme.test,Home,jane@home.test ::: jd@home.test,Mobile,+1 555 0100," +
+	"Home,\"1 Main St, Springfield\",1 Main St,Springfield,12345,USA\n" +
+	",,,,,,,,,,,,,,,,,,,,\n" +
+	"Bob,,,,,,not a date,,,,,,,,,,,,,,\n" +
+	",,,,,,,,,,nobody@example.test,,,,,,,,,,\n"
+
+func TestParseCSV_Google(t *testing.T) {
+	rows, err := ParseCSV(strings.NewReader(googleCSV), GoogleCSVMapping)
+	require.NoError(t, err)
+	require.Len(t, rows, 3) // The blank row is skipped
+
+	require.NoError(t, rows[0].Err)
+	card := rows[0].Card
+	assert.Equal(t, "3.0", card.Value(vcard.FieldVersion))
+	assert.Equal(t, "Jane Q Doe", card.Value(vcard.FieldFormattedName))
+	assert.Equal(t, "Doe", card.Name().FamilyName)
+	assert.Equal(t, "JD", card.Value(vcard.FieldNickname))
+	assert.Equal(t, "Acme", card.Value(vcard.FieldOrganization))
+	assert.Equal(t, "Engineer", card.Value(vcard.FieldTitle))
+	assert.Equal(t, "1990-05-17", card.Value(vcard.FieldBirthday))
+	assert.Equal(t, "Likes tea", card.Value(vcard.FieldNote))
+	require.Len(t, card[vcard.FieldCategories], 1)
+	assert.Equal(t, "Friends", card.Value(vcard.FieldCategories))
+
+	emails := card[vcard.FieldEmail]
+	require.Len(t, emails, 3)
+	assert.Equal(t, "jane@acme.test", emails[0].Value)
+	assert.True(t, emails[0].Params.HasType("work"))
+	assert.True(t, emails[0].Params.HasType("pref"))
+	assert.Equal(t, "jd@home.test", emails[2].Value)
+	assert.True(t, emails[2].Params.HasType("home"))
+	assert.True(t, card.Get(vcard.FieldTelephone).Params.HasType("cell"))
+
+	addresses := card.Addresses()
+	require.Len(t, addresses, 1)
+	assert.Equal(t, "1 Main St", addresses[0].StreetAddress)
+	assert.Equal(t, "Springfield", addresses[0].Locality)
+	assert.True(t, addresses[0].Params.HasType("home"))
+
+	assert.Equal(t, 1, rows[1].Index)
+	assert.ErrorContains(t, rows[1].Err, "birthday")
+
+	require.NoError(t, rows[2].Err)
+	assert.Equal(t, "nobody@example.test", rows[2].Card.Value(vcard.FieldFormattedName))
+}
+
+func TestParseCSV_Outlook(t *testing.T) {
+	data := "Title,First Name,Last Name,Company,Department,Business Street,Business City,Home City," +
+		"Business Fax,Mobile Phone,E-mail Address,E-mail 2 Address,Birthday,Categories\n" +
+		"Dr.,Ann,Lee,Initech,R&D,2 Park Ave,Metropolis,Gotham,+1 555 0199,+1 555 0111,ann@initech.test,ann@home.test,3/4/1985,Work;VIP\n"
+
+	rows, err := ParseCSV(strings.NewReader(data), OutlookCSVMapping)
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	require.NoError(t, rows[0].Err)
+	card := rows[0].Card
+
+	assert.Equal(t, "Dr. Ann Lee", card.Value(vcard.FieldFormattedName))
+	assert.Equal(t, "Initech;R&D", card.Value(vcard.FieldOrganization))
+	assert.Equal(t, "1985-03-04", card.Value(vcard.FieldBirthday))
+	assert.Len(t, card[vcard.FieldEmail], 2)
+	assert.Len(t, card[vcard.FieldCategories], 2)
+
+	// Components of the same type form one address
+	addresses := card.Addresses()
+	require.Len(t, addresses, 2)
+	assert.Equal(t, "2 Park Ave", addresses[0].StreetAddress)
+	assert.Equal(t, "Metropolis", addresses[0].Locality)
+	assert.True(t, addresses[0].Params.HasType("work"))
+	assert.Equal(t, "Gotham", addresses[1].Locality)
+
+	fax := card.Get(vcard.FieldTelephone)
+	assert.True(t, fax.Params.HasType("fax"))
+	assert.True(t, fax.Params.HasType("work"))
+}
+
+func TestParseCSV_UnknownHeader(t *testing.T) {
+	_, err := ParseCSV(strings.NewReader("Foo,Bar\n1,2\n"), GoogleCSVMapping)
+	assert.Error(t, err)
+}
+
+func TestNewCSVMapping(t *testing.T) {
+	mapping, err := NewCSVMapping(map[string]string{
+		"Name":   "full_name",
+		"Mail":   "email:work",
+		"Handy":  "phone:cell",
+		"Ort":    "address.city:home",
+		"Straße": "address.street:home",
+	})
+	require.NoError(t, err)
+
+	rows, err := ParseCSV(strings.NewReader("Name,Mail,Handy,Ort,Straße,Ignored\nMax Muster,max@test.de,0170 123,Berlin,Hauptstr. 1,x\n"), mapping)
+	require.NoError(t, err)
+	require.NoError(t, rows[0].Err)
+	card := rows[0].Card
+	assert.Equal(t, "Max Muster", card.Value(vcard.FieldFormattedName))
+	assert.True(t, card.Get(vcard.FieldEmail).Params.HasType("work"))
+	require.Len(t, card.Addresses(), 1)
+	assert.Equal(t, "Hauptstr. 1", card.Addresses()[0].StreetAddress)
+
+	_, err = NewCSVMapping(map[string]string{"Name": "shoe_size"})
+	assert.ErrorIs(t, err, ErrInvalidCSVMapping)
+	_, err = NewCSVMapping(map[string]string{"Name": "full_name:work"})
+	assert.ErrorIs(t, err, ErrInvalidCSVMapping)
+}
+
+func TestWriteCSV_RoundTrip(t *testing.T) {
+	for _, mapping := range []CSVMapping{GoogleCSVMapping, OutlookCSVMapping} {
+		t.Run(mapping.Name, func(t *testing.T) {
+			rows, err := ParseCSV(strings.NewReader(googleCSV), GoogleCSVMapping)
+			require.NoError(t, err)
+			cards := []vcard.Card{rows[0].Card, rows[2].Card}
+
+			var buf bytes.Buffer
+			require.NoError(t, WriteCSV(&buf, cards, mapping))
+
+			records, err := csv.NewReader(bytes.NewReader(buf.Bytes())).ReadAll()
+			require.NoError(t, err)
+			require.Len(t, records, 3)
+
+			again, err := ParseCSV(bytes.NewReader(buf.Bytes()), mapping)
+			require.NoError(t, err)
+			require.Len(t, again, 2)
+			require.NoError(t, again[0].Err)
+			card := again[0].Card
+			assert.Equal(t, "Doe", card.Name().FamilyName)
+			assert.Equal(t, "1990-05-17", card.Value(vcard.FieldBirthday))
+			assert.Equal(t, "Friends", card.Value(vcard.FieldCategories))
+			assert.Equal(t, "Springfield", card.Addresses()[0].Locality)
+			assert.True(t, card.Get(vcard.FieldTelephone).Params.HasType("cell"))
+			assert.Equal(t, "jane@acme.test", card.Value(vcard.FieldEmail))
+		})
+	}
+
+	// Outlook has three e-mail columns and no numbered blocks
+	var buf bytes.Buffer
+	require.NoError(t, WriteCSV(&buf, nil, OutlookCSVMapping))
+	header, err := csv.NewReader(&buf).Read()
+	require.NoError(t, err)
+	assert.Len(t, header, len(OutlookCSVMapping.Columns))
+}
+
+func TestWriteCSV_FormulaInjection(t *testing.T) {
+	card, err := vcard.NewDecoder(strings.NewReader("BEGIN:VCARD\r\nVERSION:3.0\r\nUID:f1\r\nFN:=HYPERLINK(\"http://evil.test\")\r\n" +
+		"N:@SUM(A1);-Jane;;;\r\nTEL;TYPE=CELL:+1 555 0100\r\nORG:Acme\r\nNOTE:\\tindented\r\nEND:VCARD\r\n")).Decode()
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteCSV(&buf, []vcard.Card{card}, GoogleCSVMapping))
+	records, err := csv.NewReader(bytes.NewReader(buf.Bytes())).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	for i, value := range records[1] {
+		if value != "" && !isPhoneLike(value) {
+			assert.NotContains(t, "=+-@\t\r", value[:1], "column %s", records[0][i])
+		}
+	}
+	// Phone numbers can't call a function and stay importable
+	assert.Contains(t, records[1], "+1 555 0100")
+	assert.Contains(t, records[1], "Acme")
+
+	// Importing the export restores the values
+	rows, err := ParseCSV(bytes.NewReader(buf.Bytes()), GoogleCSVMapping)
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	require.NoError(t, rows[0].Err)
+	again := rows[0].Card
+	assert.Equal(t, "@SUM(A1)", again.Name().FamilyName)
+	assert.Equal(t, "-Jane", again.Name().GivenName)
+	assert.Equal(t, "+1 555 0100", again.Value(vcard.FieldTelephone))
+}
+
+func TestEscapeCSVFormula(t *testing.T) {
+	for value, want := range map[string]string{
+		"+49 30 1234567":       "+49 30 1234567",
+		"+1 (555) 010-0100":    "+1 (555) 010-0100",
+		"-42":                  "-42",
+		"+1+cmd|' /C calc'!A0": "'+1+cmd|' /C calc'!A0",
+		"-2+3":                 "-2+3",
+		"=SUM(A1:A2)":          "'=SUM(A1:A2)",
+		"@SUM(A1)":             "'@SUM(A1)",
+		"+":                    "'+",
+		"Jane":                 "Jane",
+		"\t=1":                 "'\t=1",
+	} {
+		assert.Equal(t, want, escapeCSVFormula(value), value)
+		assert.Equal(t, value, unescapeCSVFormula(escapeCSVFormula(value)), value)
+	}
+}
+
+func TestParseCSV_Delimiter(t *testing.T) {
+	rows, err := ParseCSV(strings.NewReader("Vorname;Nachname;E-Mail-Adresse\nMax;Muster;max@test.de\n"), mustCSVMapping(t, map[string]string{
+		"Vorname":        "given_name",
+		"Nachname":       "family_name",
+		"E-Mail-Adresse": "email",
+	}))
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, "Max Muster", rows[0].Card.Value(vcard.FieldFormattedName))
+	assert.Equal(t, "max@test.de", rows[0].Card.Value(vcard.FieldEmail))
+}
+
+func mustCSVMapping(t *testing.T, columns map[string]string) CSVMapping {
+	t.Helper()
+	mapping, err := NewCSVMapping(columns)
+	require.NoError(t, err)
+	return mapping
+}
@@ -0,0 +1,129 @@
+package addressbook
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-vcard"
+	"github.com/jherrma/caldav-server/internal/domain/user"
+)
+
+// DirectoryPath is the path of the global address list in every user's
+// address book home
+const DirectoryPath = "directory"
+
+// DirectoryAddressBookID is the AddressBookID under which directory changes
+// are written to the sync change log. The directory has no AddressBook row,
+// so the ID never collides with a real address book.
+const DirectoryAddressBookID uint = 0
+
+// DirectoryEntry is a published card of the global address list. The card
+// and its ETag are kept so that regenerating the directory only reports the
+// users whose card actually changed.
+type DirectoryEntry struct {
+	ID            uint   `gorm:"primaryKey"`
+	UserID        uint   `gorm:"uniqueIndex;not null"`
+	UID           string `gorm:"uniqueIndex;size:255;not null"`
+	ETag          string `gorm:"size:64;not null"`
+	FormattedName string `gorm:"size:500;index"`
+	VCardData     string `gorm:"type:text;not null"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// TableName specifies the table name for DirectoryEntry
+func (DirectoryEntry) TableName() string {
+	return "directory_entries"
+}
+
+// Path returns the resource name of the entry within the directory
+func (e *DirectoryEntry) Path() string {
+	return e.UID + ".vcf"
+}
+
+// AddressObject presents the entry as a read-only address object
+func (e *DirectoryEntry) AddressObject() *AddressObject {
+	obj := &AddressObject{
+		UUID:          e.UID,
+		AddressBookID: DirectoryAddressBookID,
+		Path:          e.Path(),
+		UID:           e.UID,
+		ETag:          e.ETag,
+		VCardData:     e.VCardData,
+		VCardVersion:  VCardVersion3,
+		ContentLength: len(e.VCardData),
+		CreatedAt:     e.CreatedAt,
+		UpdatedAt:     e.UpdatedAt,
+	}
+	_ = obj.PopulateDenormFieldsFromVCard()
+	return obj
+}
+
+// NewDirectoryEntry builds the directory entry of a user. org, when set,
+// becomes the organization of the card with the user's department as unit.
+func NewDirectoryEntry(u *user.User, org string) (*DirectoryEntry, error) {
+	card := DirectoryCard(u, org)
+
+	var buf bytes.Buffer
+	if err := vcard.NewEncoder(&buf).Encode(card); err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(buf.Bytes())
+
+	return &DirectoryEntry{
+		UserID:        u.ID,
+		UID:           u.UUID,
+		ETag:          fmt.Sprintf("\"%s\"", hex.EncodeToString(sum[:16])),
+		FormattedName: card.Value(vcard.FieldFormattedName),
+		VCardData:     buf.String(),
+	}, nil
+}
+
+// DirectoryCard returns the vCard 3.0 published for a user in the directory
+func DirectoryCard(u *user.User, org string) vcard.Card {
+	card := make(vcard.Card)
+	card.SetValue(vcard.FieldVersion, VCardVersion3)
+	card.SetValue(vcard.FieldUID, u.UUID)
+
+	fn := strings.TrimSpace(u.DisplayName)
+	if fn == "" {
+		fn = u.Username
+	}
+	card.SetValue(vcard.FieldFormattedName, fn)
+
+	name := &vcard.Name{GivenName: fn}
+	if i := strings.LastIndex(fn, " "); i > 0 && strings.TrimSpace(u.DisplayName) != "" {
+		name.GivenName, name.FamilyName = fn[:i], fn[i+1:]
+	}
+	card.SetName(name)
+
+	if u.Username != "" {
+		card.SetValue(vcard.FieldNickname, u.Username)
+	}
+	card.Add(vcard.FieldEmail, &vcard.Field{
+		Value:  u.Email,
+		Params: vcard.Params{vcard.ParamType: {vcard.TypeWork}},
+	})
+	if u.Phone != "" {
+		card.Add(vcard.FieldTelephone, &vcard.Field{
+			Value:  u.Phone,
+			Params: vcard.Params{vcard.ParamType: {vcard.TypeWork}},
+		})
+	}
+	if u.JobTitle != "" {
+		card.SetValue(vcard.FieldTitle, u.JobTitle)
+	}
+	if org != "" || u.Department != "" {
+		value := structuredPart(org)
+		if u.Department != "" {
+			value += ";" + structuredPart(u.Department)
+		}
+		card.SetValue(vcard.FieldOrganization, value)
+	}
+
+	return card
+}
@@ -0,0 +1,146 @@
+package addressbook
+
+import (
+	"strings"
+
+	"github.com/emersion/go-vcard"
+)
+
+// Contact groups come in two flavours:
+//
+//   - vCard 4 (RFC 6350): KIND:group with one MEMBER:urn:uuid:<uid> per member
+//   - Apple / vCard 3: X-ADDRESSBOOKSERVER-KIND:group with
+//     X-ADDRESSBOOKSERVER-MEMBER:urn:uuid:<uid>
+//
+// Both reference members by their vCard UID.
+
+// Object kinds stored in AddressObject.Kind
+const (
+	KindIndividual = "individual"
+	KindGroup      = "group"
+)
+
+// Group styles
+const (
+	GroupStyleVCard4 = "vcard4"
+	GroupStyleApple  = "apple"
+)
+
+const (
+	fieldAppleKind   = "X-ADDRESSBOOKSERVER-KIND"
+	fieldAppleMember = "X-ADDRESSBOOKSERVER-MEMBER"
+	memberURNPrefix  = "urn:uuid:"
+)
+
+// IsValidGroupStyle reports whether style names a supported group style
+func IsValidGroupStyle(style string) bool {
+	return style == GroupStyleVCard4 || style == GroupStyleApple
+}
+
+// CardKind returns KindGroup for group vCards of either style and
+// KindIndividual otherwise
+func CardKind(card vcard.Card) string {
+	if GroupStyle(card) != "" {
+		return KindGroup
+	}
+	return KindIndividual
+}
+
+// GroupStyle returns the style of a group vCard, or "" if the card is not a
+// group
+func GroupStyle(card vcard.Card) string {
+	if strings.EqualFold(card.Value(vcard.FieldKind), string(vcard.KindGroup)) {
+		return GroupStyleVCard4
+	}
+	if strings.EqualFold(card.Value(fieldAppleKind), "group") {
+		return GroupStyleApple
+	}
+	return ""
+}
+
+// GroupMembers returns the UIDs of the members of a group vCard, reading both
+// styles. Member references that are not urn:uuid URIs are returned as-is.
+func GroupMembers(card vcard.Card) []string {
+	var members []string
+	seen := map[string]bool{}
+	for _, name := range []string{vcard.FieldMember, fieldAppleMember} {
+		for _, f := range card[name] {
+			uid := MemberUID(f.Value)
+			if uid != "" && !seen[uid] {
+				seen[uid] = true
+				members = append(members, uid)
+			}
+		}
+	}
+	return members
+}
+
+// SetGroup marks the card as a group of the given style with the given
+// members, removing any properties of the other style
+func SetGroup(card vcard.Card, style string, memberUIDs []string) {
+	delete(card, vcard.FieldKind)
+	delete(card, vcard.FieldMember)
+	delete(card, fieldAppleKind)
+	delete(card, fieldAppleMember)
+
+	kindField, memberField := vcard.FieldKind, vcard.FieldMember
+	version := "4.0"
+	if style == GroupStyleApple {
+		kindField, memberField = fieldAppleKind, fieldAppleMember
+		version = "3.0"
+	}
+
+	card.SetValue(vcard.FieldVersion, version)
+	card.SetValue(kindField, string(vcard.KindGroup))
+	for _, uid := range memberUIDs {
+		card.Add(memberField, &vcard.Field{Value: MemberURI(uid)})
+	}
+}
+
+// ConvertGroupStyle rewrites a group vCard into the other style, keeping
+// its members. It reports false when the card is not a group.
+func ConvertGroupStyle(card vcard.Card, style string) bool {
+	if GroupStyle(card) == "" {
+		return false
+	}
+	SetGroup(card, style, GroupMembers(card))
+	return true
+}
+
+// RemoveGroupMember drops a member from a group vCard. It reports whether
+// the member was present.
+func RemoveGroupMember(card vcard.Card, uid string) bool {
+	style := GroupStyle(card)
+	if style == "" {
+		return false
+	}
+	members := GroupMembers(card)
+	kept := members[:0]
+	for _, m := range members {
+		if m != uid {
+			kept = append(kept, m)
+		}
+	}
+	if len(kept) == len(members) {
+		return false
+	}
+	SetGroup(card, style, kept)
+	return true
+}
+
+// MemberURI formats a member UID as the URI stored in MEMBER properties
+func MemberURI(uid string) string {
+	if strings.Contains(uid, ":") {
+		return uid
+	}
+	return memberURNPrefix + uid
+}
+
+// MemberUID extracts the UID from a MEMBER property value
+func MemberUID(value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) > len(memberURNPrefix) && strings.EqualFold(value[:len(memberURNPrefix)], memberURNPrefix) {
+		return value[len(memberURNPrefix):]
+	}
+	return value
+}
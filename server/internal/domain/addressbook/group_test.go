@@ -0,0 +1,39 @@
+package addressbook
+
+import (
+	"testing"
+
+	"github.com/emersion/go-vcard"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroupStyles(t *testing.T) {
+	card := make(vcard.Card)
+	card.SetValue(vcard.FieldFormattedName, "Team")
+	assert.Equal(t, KindIndividual, CardKind(card))
+	assert.Empty(t, GroupMembers(card))
+
+	SetGroup(card, GroupStyleApple, []string{"a", "b"})
+	assert.Equal(t, KindGroup, CardKind(card))
+	assert.Equal(t, GroupStyleApple, GroupStyle(card))
+	assert.Equal(t, "urn:uuid:a", card.Value("X-ADDRESSBOOKSERVER-MEMBER"))
+	assert.Equal(t, "3.0", card.Value(vcard.FieldVersion))
+
+	assert.True(t, ConvertGroupStyle(card, GroupStyleVCard4))
+	assert.Equal(t, GroupStyleVCard4, GroupStyle(card))
+	assert.Equal(t, []string{"a", "b"}, GroupMembers(card))
+	assert.Nil(t, card["X-ADDRESSBOOKSERVER-MEMBER"])
+	assert.Equal(t, "4.0", card.Value(vcard.FieldVersion))
+
+	assert.True(t, RemoveGroupMember(card, "a"))
+	assert.False(t, RemoveGroupMember(card, "a"))
+	assert.Equal(t, []string{"b"}, GroupMembers(card))
+}
+
+func TestMemberUID(t *testing.T) {
+	assert.Equal(t, "1234", MemberUID("urn:uuid:1234"))
+	assert.Equal(t, "1234", MemberUID("URN:UUID:1234"))
+	assert.Equal(t, "mailto:a@example.com", MemberUID("mailto:a@example.com"))
+	assert.Equal(t, "mailto:a@example.com", MemberURI("mailto:a@example.com"))
+	assert.Equal(t, "urn:uuid:1234", MemberURI("1234"))
+}
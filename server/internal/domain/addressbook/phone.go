@@ -0,0 +1,113 @@
+package addressbook
+
+import "strings"
+
+// phoneRegion describes how national numbers are written in a region
+type phoneRegion struct {
+	callingCode string
+	trunkPrefix string // Dialled before the area code within the country; dropped in E.164
+}
+
+// phoneRegions maps ISO 3166-1 alpha-2 region codes to their calling codes.
+// Italy and San Marino keep the leading zero in international format, so
+// they have no trunk prefix.
+var phoneRegions = map[string]phoneRegion{
+	"AT": {"43", "0"}, "AU": {"61", "0"}, "BE": {"32", "0"}, "BG": {"359", "0"},
+	"BR": {"55", "0"}, "CA": {"1", "1"}, "CH": {"41", "0"}, "CN": {"86", "0"},
+	"CZ": {"420", ""}, "DE": {"49", "0"}, "DK": {"45", ""}, "EE": {"372", ""},
+	"ES": {"34", ""}, "FI": {"358", "0"}, "FR": {"33", "0"}, "GB": {"44", "0"},
+	"GR": {"30", ""}, "HR": {"385", "0"}, "HU": {"36", "06"}, "IE": {"353", "0"},
+	"IL": {"972", "0"}, "IN": {"91", "0"}, "IT": {"39", ""}, "JP": {"81", "0"},
+	"KR": {"82", "0"}, "LI": {"423", ""}, "LT": {"370", "8"}, "LU": {"352", ""},
+	"LV": {"371", ""}, "MX": {"52", ""}, "NL": {"31", "0"}, "NO": {"47", ""},
+	"NZ": {"64", "0"}, "PL": {"48", ""}, "PT": {"351", ""}, "RO": {"40", "0"},
+	"RU": {"7", "8"}, "SE": {"46", "0"}, "SG": {"65", ""}, "SI": {"386", "0"},
+	"SK": {"421", "0"}, "SM": {"378", ""}, "TR": {"90", "0"}, "UA": {"380", "0"},
+	"US": {"1", "1"}, "ZA": {"27", "0"},
+}
+
+// ValidPhoneRegion reports whether region is a supported region code. The
+// empty region is valid and disables national number handling.
+func ValidPhoneRegion(region string) bool {
+	if region == "" {
+		return true
+	}
+	_, ok := phoneRegions[strings.ToUpper(region)]
+	return ok
+}
+
+// NormalizePhone converts a phone number to E.164 ("+4930123456"). Numbers
+// with an international prefix ("+", "00") keep their country code; national
+// numbers get the calling code of region with the trunk prefix removed, so
+// "030/123456" and "+49 30 123456" agree for region DE. Extensions, tel: URI
+// parameters and formatting are dropped. Without a known region a national
+// number is reduced to its digits. Values without digits yield "".
+func NormalizePhone(raw, region string) string {
+	international, digits := splitPhone(raw)
+	if digits == "" {
+		return ""
+	}
+	if international {
+		return "+" + digits
+	}
+	r, ok := phoneRegions[strings.ToUpper(region)]
+	if !ok {
+		return digits
+	}
+	if r.trunkPrefix != "" && strings.HasPrefix(digits, r.trunkPrefix) {
+		digits = digits[len(r.trunkPrefix):]
+	}
+	return "+" + r.callingCode + digits
+}
+
+// PhoneSearchText converts a phone number typed as a search query into the
+// form stored in the search index. A query written like a full number (with
+// an international or trunk prefix) is normalized to E.164; anything else is
+// reduced to its digits so it still matches part of a stored number.
+func PhoneSearchText(query, region string) string {
+	international, digits := splitPhone(query)
+	if international {
+		return NormalizePhone(query, region)
+	}
+	if r, ok := phoneRegions[strings.ToUpper(region)]; ok && r.trunkPrefix != "" && strings.HasPrefix(digits, r.trunkPrefix) {
+		// In the NANP the trunk prefix "1" is also a common leading digit,
+		// so only a complete eleven-digit number counts as prefixed
+		if r.callingCode != "1" || len(digits) == 11 {
+			return NormalizePhone(query, region)
+		}
+	}
+	return digits
+}
+
+// splitPhone strips formatting from a phone number and reports whether it
+// was written with an international prefix
+func splitPhone(raw string) (international bool, digits string) {
+	s := strings.TrimSpace(raw)
+	if len(s) >= 4 && strings.EqualFold(s[:4], "tel:") {
+		s = s[4:]
+	}
+	// tel: URI parameters (";ext=12") follow the number
+	if i := strings.IndexByte(s, ';'); i >= 0 {
+		s = s[:i]
+	}
+
+	var b strings.Builder
+scan:
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == '+' && b.Len() == 0:
+			international = true
+		case (r == 'x' || r == 'X' || r == 'e' || r == 'E') && b.Len() > 0:
+			// "x12" or "ext. 12": the extension is not part of the number
+			break scan
+		}
+	}
+	digits = b.String()
+	if !international && strings.HasPrefix(digits, "00") {
+		international = true
+		digits = digits[2:]
+	}
+	return international, digits
+}
@@ -1,7 +1,55 @@
 package addressbook
 
+import "errors"
+
+// ErrInvalidPhoto is returned when photo data is not a decodable image or
+// exceeds the configured input limits
+var ErrInvalidPhoto = errors.New("invalid photo")
+
 type ContactPhoto struct {
 	AddressObjectID uint   `gorm:"primaryKey"`
 	PhotoData       string `gorm:"type:text"` // Base64 encoded string
 	PhotoType       string `gorm:"size:20"`   // e.g. "JPEG", "PNG"
+	Width           int
+	Height          int
+	ETag            string `gorm:"size:64"` // Content hash, shared by the photo and its thumbnails
+}
+
+// ContactPhotoThumbnail is a downscaled copy of a ContactPhoto whose longest
+// edge is Size pixels
+type ContactPhotoThumbnail struct {
+	ID              uint   `gorm:"primaryKey"`
+	AddressObjectID uint   `gorm:"uniqueIndex:idx_photo_thumbnail_size;not null"`
+	Size            int    `gorm:"uniqueIndex:idx_photo_thumbnail_size;not null"`
+	PhotoData       string `gorm:"type:text"` // Base64 encoded string
+	PhotoType       string `gorm:"size:20"`
+	Width           int
+	Height          int
+}
+
+// PhotoProcessor validates and normalizes contact photos before they are
+// stored. Implementations decode the image, cap its resolution, re-encode it
+// and render the thumbnails.
+type PhotoProcessor interface {
+	// Process returns the normalized photo, or an error wrapping
+	// ErrInvalidPhoto when data is not an acceptable image
+	Process(data []byte) (*ProcessedPhoto, error)
+}
+
+// ProcessedPhoto is the result of a PhotoProcessor
+type ProcessedPhoto struct {
+	Data       []byte
+	Type       string // "JPEG" or "PNG"
+	Width      int
+	Height     int
+	Thumbnails []PhotoThumbnail
+}
+
+// PhotoThumbnail is one rendered thumbnail of a ProcessedPhoto
+type PhotoThumbnail struct {
+	Size   int // Requested longest edge
+	Data   []byte
+	Type   string
+	Width  int
+	Height int
 }
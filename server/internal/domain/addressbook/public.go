@@ -0,0 +1,90 @@
+package addressbook
+
+import (
+	"strings"
+
+	"github.com/emersion/go-vcard"
+)
+
+// PublicOptions are the publication settings of an address book's public
+// link. The zero value publishes every contact with all fields.
+type PublicOptions struct {
+	HidePhones    bool `gorm:"not null;default:false"`
+	HideAddresses bool `gorm:"not null;default:false"`
+	HideEmails    bool `gorm:"not null;default:false"`
+	HideBirthdays bool `gorm:"not null;default:false"`
+	HideNotes     bool `gorm:"not null;default:false"`
+	HidePhotos    bool `gorm:"not null;default:false"`
+}
+
+// clientDateFields are the extensions clients store dates in besides BDAY
+// and ANNIVERSARY. Apple's X-ABDATE carries its label in a grouped X-ABLabel.
+var clientDateFields = []string{"X-ABDATE", "X-EVOLUTION-ANNIVERSARY", "X-KADDRESSBOOK-X-ANNIVERSARY", "X-MS-ANNIVERSARY"}
+
+// hiddenFields returns the vCard properties the options leave out
+func (o *PublicOptions) hiddenFields() []string {
+	var fields []string
+	if o.HidePhones {
+		fields = append(fields, vcard.FieldTelephone)
+	}
+	if o.HideAddresses {
+		fields = append(fields, vcard.FieldAddress, "LABEL", vcard.FieldGeolocation)
+	}
+	if o.HideEmails {
+		fields = append(fields, vcard.FieldEmail)
+	}
+	if o.HideBirthdays {
+		fields = append(fields, vcard.FieldBirthday, vcard.FieldAnniversary, v4OnlyFields[vcard.FieldAnniversary])
+		fields = append(fields, clientDateFields...)
+	}
+	if o.HideNotes {
+		fields = append(fields, vcard.FieldNote)
+	}
+	if o.HidePhotos {
+		fields = append(fields, vcard.FieldPhoto)
+	}
+	return fields
+}
+
+// PublishCard returns the card as the public link shows it, or nil if the
+// link leaves it out. Cards of CLASS PRIVATE or CONFIDENTIAL (RFC 2426
+// §3.7.1) are left out. Hidden fields are removed together with the
+// properties grouped with them, such as the X-ABLabel of an Apple
+// "item1.TEL".
+func PublishCard(card vcard.Card, o *PublicOptions) vcard.Card {
+	switch strings.ToUpper(card.Value("CLASS")) {
+	case "PRIVATE", "CONFIDENTIAL":
+		return nil
+	}
+
+	published := make(vcard.Card, len(card))
+	for name, fields := range card {
+		published[name] = fields
+	}
+	groups := make(map[string]bool)
+	for _, name := range o.hiddenFields() {
+		for _, f := range published[name] {
+			if f.Group != "" {
+				groups[f.Group] = true
+			}
+		}
+		delete(published, name)
+	}
+	if len(groups) == 0 {
+		return published
+	}
+	for name, fields := range published {
+		var kept []*vcard.Field
+		for _, f := range fields {
+			if !groups[f.Group] {
+				kept = append(kept, f)
+			}
+		}
+		if len(kept) == 0 {
+			delete(published, name)
+		} else {
+			published[name] = kept
+		}
+	}
+	return published
+}
@@ -0,0 +1,60 @@
+package addressbook
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/emersion/go-vcard"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublishCard(t *testing.T) {
+	decode := func(extra string) vcard.Card {
+		card, err := vcard.NewDecoder(strings.NewReader("BEGIN:VCARD\r\nVERSION:3.0\r\nUID:c1\r\nFN:Jane Doe\r\n" +
+			"N:Doe;Jane;;;\r\nEMAIL:jane@example.com\r\nitem1.TEL:+49 30 1234567\r\nitem1.X-ABLabel:Desk\r\n" +
+			"ADR;TYPE=WORK:;;Main St 1;Berlin;;10115;Germany\r\nBDAY:1990-05-01\r\nNOTE:Prefers email\r\n" + extra +
+			"END:VCARD\r\n")).Decode()
+		require.NoError(t, err)
+		return card
+	}
+
+	t.Run("All Fields", func(t *testing.T) {
+		card := decode("")
+		pub := PublishCard(card, &PublicOptions{})
+		require.NotNil(t, pub)
+		assert.Equal(t, len(card), len(pub))
+		assert.NotNil(t, pub.Get("X-ABLABEL"))
+	})
+
+	t.Run("Hidden Fields", func(t *testing.T) {
+		card := decode("")
+		pub := PublishCard(card, &PublicOptions{HidePhones: true, HideAddresses: true, HideBirthdays: true, HideNotes: true})
+		require.NotNil(t, pub)
+		assert.Equal(t, "Jane Doe", pub.Value(vcard.FieldFormattedName))
+		assert.Equal(t, "jane@example.com", pub.Value(vcard.FieldEmail))
+		assert.Nil(t, pub.Get(vcard.FieldTelephone))
+		assert.Nil(t, pub.Get("X-ABLABEL"))
+		assert.Nil(t, pub.Get(vcard.FieldAddress))
+		assert.Nil(t, pub.Get(vcard.FieldBirthday))
+		assert.Nil(t, pub.Get(vcard.FieldNote))
+		// The original card is left untouched
+		assert.NotNil(t, card.Get(vcard.FieldTelephone))
+	})
+
+	t.Run("Hidden Dates", func(t *testing.T) {
+		pub := PublishCard(decode("X-ANNIVERSARY:2015-06-20\r\nitem2.X-ABDATE:2018-03-01\r\nitem2.X-ABLabel:_$!<Anniversary>!$_\r\n"),
+			&PublicOptions{HideBirthdays: true})
+		require.NotNil(t, pub)
+		assert.Nil(t, pub.Get("X-ANNIVERSARY"))
+		assert.Nil(t, pub.Get("X-ABDATE"))
+		// Only the label of the date goes, not the one of the phone
+		assert.Equal(t, "Desk", pub.Value("X-ABLABEL"))
+	})
+
+	t.Run("Private Contacts", func(t *testing.T) {
+		assert.Nil(t, PublishCard(decode("CLASS:PRIVATE\r\n"), &PublicOptions{}))
+		assert.Nil(t, PublishCard(decode("CLASS:CONFIDENTIAL\r\n"), &PublicOptions{}))
+		assert.NotNil(t, PublishCard(decode("CLASS:PUBLIC\r\n"), &PublicOptions{}))
+	})
+}
@@ -25,6 +25,9 @@ type Repository interface {
 	ListByUserID(ctx context.Context, userID uint) ([]AddressBook, error)
 	Update(ctx context.Context, addressBook *AddressBook) error
 	Delete(ctx context.Context, id uint) error
+	// FindByPublicToken retrieves an address book with an enabled public
+	// link by its token; nil if there is none
+	FindByPublicToken(ctx context.Context, token string) (*AddressBook, error)
 	CreateObject(ctx context.Context, object *AddressObject) error
 	GetObjectByID(ctx context.Context, id uint) (*AddressObject, error)
 	GetObjectByPath(ctx context.Context, addressBookID uint, path string) (*AddressObject, error)
@@ -33,7 +36,22 @@ type Repository interface {
 	GetObjectByUUID(ctx context.Context, uuid string) (*AddressObject, error)
 	UpdateObject(ctx context.Context, object *AddressObject) error
 	DeleteObjectByUUID(ctx context.Context, uuid string) error
+	// SaveObjectChanges updates the modified objects and deletes the objects
+	// with the given UUIDs in one transaction
+	SaveObjectChanges(ctx context.Context, modified []*AddressObject, deletedUUIDs []string) error
+	// SearchObjects returns the objects of a user's address books matching a
+	// search query; see ParseSearchQuery for the syntax
 	SearchObjects(ctx context.Context, userID uint, query string, addressBookID *uint, limit int) ([]AddressObject, error)
+	// ReindexSearch rebuilds the search columns and Kind of objects indexed by
+	// an older release or for another phone region and returns how many changed
+	ReindexSearch(ctx context.Context) (int, error)
+	// ListGroups returns the group vCards (Kind == KindGroup) of an address book
+	ListGroups(ctx context.Context, addressBookID uint) ([]AddressObject, error)
+	// GetPhoto returns the photo of an address object, or for size > 0 its
+	// smallest thumbnail at least that large; nil when there is no photo
+	GetPhoto(ctx context.Context, objectID uint, size int) (*ContactPhoto, error)
+	// GetObjectByUID finds an object by its vCard UID within an address book
+	GetObjectByUID(ctx context.Context, addressBookID uint, uid string) (*AddressObject, error)
 
 	// CountContactsByUserID counts all contacts across all address books for a user
 	CountContactsByUserID(ctx context.Context, userID uint) (int64, error)
@@ -42,3 +60,19 @@ type Repository interface {
 	GetChangesSinceToken(ctx context.Context, addressBookID uint, token string) ([]*SyncChangeLog, error)
 	RecordChange(ctx context.Context, addressBookID uint, path, uid, changeType, token string) error
 }
+
+// DirectoryRepository persists the published entries of the global address
+// list and their change log
+type DirectoryRepository interface {
+	// ListEntries returns all entries ordered by formatted name
+	ListEntries(ctx context.Context) ([]DirectoryEntry, error)
+	// SaveChanges stores created and modified entries, removes the given
+	// entries and logs every change under one sync token
+	SaveChanges(ctx context.Context, created, modified, removed []DirectoryEntry, token string) error
+	// SyncToken returns the token of the latest directory change, or an
+	// empty string if the directory never changed
+	SyncToken(ctx context.Context) (string, error)
+	// GetChangesSinceToken returns the directory changes logged after the
+	// given token, or gorm.ErrRecordNotFound for an unknown token
+	GetChangesSinceToken(ctx context.Context, token string) ([]*SyncChangeLog, error)
+}
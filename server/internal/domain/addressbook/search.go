@@ -0,0 +1,262 @@
+package addressbook
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/emersion/go-vcard"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// SearchIndexVersion is bumped whenever the content of the search columns
+// changes, so rows indexed by an older release are indexed again. Version 2
+// also backfills Kind, which rows written before groups lack.
+const SearchIndexVersion = 2
+
+// SearchValueSeparator delimits the values of a multi-valued search column.
+// Columns start and end with it, so a value can be matched as a whole
+// ("|alice@example.com|") or by prefix and suffix.
+const SearchValueSeparator = "|"
+
+// SearchField restricts a search term to one kind of property
+type SearchField string
+
+const (
+	SearchFieldAny          SearchField = ""
+	SearchFieldName         SearchField = "name"
+	SearchFieldEmail        SearchField = "email"
+	SearchFieldPhone        SearchField = "tel"
+	SearchFieldOrganization SearchField = "org"
+)
+
+// searchFieldPrefixes maps the field prefixes accepted in search queries
+// ("email:alice") to the field they select
+var searchFieldPrefixes = map[string]SearchField{
+	"name":  SearchFieldName,
+	"email": SearchFieldEmail,
+	"tel":   SearchFieldPhone,
+	"phone": SearchFieldPhone,
+	"org":   SearchFieldOrganization,
+}
+
+// SearchIndexKey identifies how the search columns of a row were built. A
+// row whose key differs from the current one was indexed by an older
+// release or for another default phone region.
+func SearchIndexKey(region string) string {
+	return fmt.Sprintf("v%d:%s", SearchIndexVersion, strings.ToUpper(region))
+}
+
+// IndexForSearch fills the search columns from a parsed vCard. Names,
+// email addresses and organizations are folded with FoldText; phone numbers
+// are normalized to E.164 with region as the default region. Every value
+// of a property is indexed, not only the preferred one.
+func (o *AddressObject) IndexForSearch(card vcard.Card, region string) {
+	var names []string
+	for _, f := range card[vcard.FieldFormattedName] {
+		names = append(names, f.Value)
+	}
+	for _, f := range card[vcard.FieldName] {
+		// Family;Given;Additional;Prefix;Suffix
+		parts := strings.Split(f.Value, ";")
+		names = append(names, parts[:min(len(parts), 3)]...)
+	}
+	for _, f := range card[vcard.FieldNickname] {
+		names = append(names, strings.Split(f.Value, ",")...)
+	}
+
+	var emails []string
+	for _, f := range card[vcard.FieldEmail] {
+		emails = append(emails, strings.TrimPrefix(strings.TrimSpace(f.Value), "mailto:"))
+	}
+
+	var phones []string
+	for _, f := range card[vcard.FieldTelephone] {
+		phones = append(phones, NormalizePhone(f.Value, region))
+	}
+
+	var orgs []string
+	for _, f := range card[vcard.FieldOrganization] {
+		// Organization name followed by its units
+		orgs = append(orgs, strings.Split(f.Value, ";")...)
+	}
+
+	o.SearchNames = joinSearchValues(names, FoldText)
+	o.SearchEmails = joinSearchValues(emails, FoldText)
+	o.SearchPhones = joinSearchValues(phones, strings.TrimSpace)
+	o.SearchOrganization = joinSearchValues(orgs, FoldText)
+	o.SearchKey = SearchIndexKey(region)
+}
+
+// PopulateSearchFieldsFromVCard parses VCardData and fills the search
+// columns; see IndexForSearch
+func (o *AddressObject) PopulateSearchFieldsFromVCard(region string) error {
+	card, err := vcard.NewDecoder(strings.NewReader(o.VCardData)).Decode()
+	if err != nil {
+		return fmt.Errorf("parse vCard: %w", err)
+	}
+	o.IndexForSearch(card, region)
+	return nil
+}
+
+// FoldText prepares text for accent- and case-insensitive matching: accents
+// are removed and the result is lowercased, so "Müller" becomes "muller"
+func FoldText(s string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	folded, _, err := transform.String(t, s)
+	if err != nil {
+		folded = s
+	}
+	return strings.ToLower(folded)
+}
+
+// joinSearchValues normalizes values and joins the distinct, non-empty ones
+// into a multi-valued search column
+func joinSearchValues(values []string, normalize func(string) string) string {
+	var kept []string
+	seen := make(map[string]bool)
+	for _, v := range values {
+		v = strings.TrimSpace(strings.ReplaceAll(normalize(v), SearchValueSeparator, " "))
+		if v == "" || seen[v] {
+			continue
+		}
+		seen[v] = true
+		kept = append(kept, v)
+	}
+	if len(kept) == 0 {
+		return ""
+	}
+	return SearchValueSeparator + strings.Join(kept, SearchValueSeparator) + SearchValueSeparator
+}
+
+// SearchTerm is one condition of a contact search. Text is folded for name,
+// email and organization matching; Phone is the form of the term matched
+// against phone numbers and empty when the term has no digits.
+type SearchTerm struct {
+	Field SearchField
+	Text  string
+	Phone string
+}
+
+// SearchQuery is a parsed contact search. A contact matches when it
+// matches every term.
+type SearchQuery struct {
+	Terms []SearchTerm
+}
+
+// ParseSearchQuery splits a search query into terms. Words are separate
+// terms unless quoted; a word prefixed with "name:", "email:", "org:" or
+// "tel:" only matches that kind of property. Digits following "tel:" belong
+// to the phone number, and a query that as a whole looks like a phone number
+// is a single term, so "+49 30 1234" matches the stored "+49301234…". region
+// is the default phone region used to normalize phone numbers.
+func ParseSearchQuery(query, region string) SearchQuery {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return SearchQuery{}
+	}
+	if isPhoneLike(query) {
+		return SearchQuery{Terms: []SearchTerm{newSearchTerm(SearchFieldAny, query, region)}}
+	}
+
+	var q SearchQuery
+	words := splitSearchWords(query)
+	for i := 0; i < len(words); i++ {
+		field, text := SearchFieldAny, words[i]
+		if prefix, rest, ok := strings.Cut(text, ":"); ok {
+			if f, known := searchFieldPrefixes[strings.ToLower(prefix)]; known {
+				field, text = f, rest
+			}
+		}
+		if field == SearchFieldPhone {
+			for i+1 < len(words) && isPhoneLike(words[i+1]) {
+				i++
+				text += " " + words[i]
+			}
+		}
+		text = strings.Trim(text, `"`)
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		q.Terms = append(q.Terms, newSearchTerm(field, text, region))
+	}
+	return q
+}
+
+func newSearchTerm(field SearchField, text, region string) SearchTerm {
+	return SearchTerm{
+		Field: field,
+		Text:  strings.ReplaceAll(FoldText(strings.TrimSpace(text)), SearchValueSeparator, " "),
+		Phone: PhoneSearchText(text, region),
+	}
+}
+
+// Matches reports whether an indexed object matches every term of the query
+func (q SearchQuery) Matches(o *AddressObject) bool {
+	for _, t := range q.Terms {
+		if !t.matches(o) {
+			return false
+		}
+	}
+	return true
+}
+
+func (t SearchTerm) matches(o *AddressObject) bool {
+	phone := t.Phone != "" && strings.Contains(o.SearchPhones, t.Phone)
+	switch t.Field {
+	case SearchFieldName:
+		return strings.Contains(o.SearchNames, t.Text)
+	case SearchFieldEmail:
+		return strings.Contains(o.SearchEmails, t.Text)
+	case SearchFieldOrganization:
+		return strings.Contains(o.SearchOrganization, t.Text)
+	case SearchFieldPhone:
+		return phone
+	default:
+		return phone || strings.Contains(o.SearchNames, t.Text) ||
+			strings.Contains(o.SearchEmails, t.Text) || strings.Contains(o.SearchOrganization, t.Text)
+	}
+}
+
+// splitSearchWords splits a query at whitespace outside double quotes
+func splitSearchWords(query string) []string {
+	var words []string
+	var b strings.Builder
+	quoted := false
+	for _, r := range query {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			b.WriteRune(r)
+		case unicode.IsSpace(r) && !quoted:
+			if b.Len() > 0 {
+				words = append(words, b.String())
+				b.Reset()
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+	if b.Len() > 0 {
+		words = append(words, b.String())
+	}
+	return words
+}
+
+// isPhoneLike reports whether s consists of phone number characters and
+// contains at least one digit
+func isPhoneLike(s string) bool {
+	digit := false
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			digit = true
+		case strings.ContainsRune(" +-/().", r):
+		default:
+			return false
+		}
+	}
+	return digit
+}
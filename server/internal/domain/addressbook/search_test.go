@@ -0,0 +1,117 @@
+package addressbook
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/emersion/go-vcard"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizePhone(t *testing.T) {
+	tests := []struct {
+		raw    string
+		region string
+		want   string
+	}{
+		{"+49 30 1234567", "DE", "+49301234567"},
+		{"030/1234567", "DE", "+49301234567"},
+		{"0049 (30) 123-4567", "US", "+49301234567"},
+		{"tel:+49-30-1234567;ext=12", "", "+49301234567"},
+		{"030 1234567 ext. 12", "DE", "+49301234567"},
+		{"(415) 555-0100", "us", "+14155550100"},
+		{"1 415 555 0100", "US", "+14155550100"},
+		{"06 1234 5678", "IT", "+390612345678"},
+		{"030 1234567", "", "0301234567"},
+		{"030 1234567", "XX", "0301234567"},
+		{"n/a", "DE", ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, NormalizePhone(tt.raw, tt.region), tt.raw)
+	}
+
+	assert.True(t, ValidPhoneRegion(""))
+	assert.True(t, ValidPhoneRegion("de"))
+	assert.False(t, ValidPhoneRegion("XX"))
+}
+
+func TestPhoneSearchText(t *testing.T) {
+	assert.Equal(t, "+49301234", PhoneSearchText("+49 30 1234", "DE"))
+	assert.Equal(t, "+49301234", PhoneSearchText("030/1234", "DE"))
+	assert.Equal(t, "1234", PhoneSearchText("12-34", "DE"))
+	// A leading 1 is only a NANP trunk prefix on a complete number
+	assert.Equal(t, "1555", PhoneSearchText("1555", "US"))
+	assert.Equal(t, "+14155550100", PhoneSearchText("14155550100", "US"))
+	assert.Empty(t, PhoneSearchText("alice", "DE"))
+}
+
+func TestIndexForSearch(t *testing.T) {
+	data := "BEGIN:VCARD\r\nVERSION:3.0\r\nFN:José Müller\r\nN:Müller;José;;;\r\nNICKNAME:Pepe\r\n" +
+		"EMAIL;TYPE=work:Jose@Example.com\r\nEMAIL:pepe|home@example.org\r\n" +
+		"TEL;TYPE=cell:0171 2345678\r\nTEL:+1 415 555 0100\r\nORG:Café Ltd;Sales\r\nEND:VCARD\r\n"
+	card, err := vcard.NewDecoder(strings.NewReader(data)).Decode()
+	require.NoError(t, err)
+
+	var o AddressObject
+	o.IndexForSearch(card, "DE")
+	assert.Equal(t, "|jose muller|muller|jose|pepe|", o.SearchNames)
+	assert.Equal(t, "|jose@example.com|pepe home@example.org|", o.SearchEmails)
+	assert.Equal(t, "|+491712345678|+14155550100|", o.SearchPhones)
+	assert.Equal(t, "|cafe ltd|sales|", o.SearchOrganization)
+	assert.Equal(t, "v2:DE", o.SearchKey)
+
+	var empty AddressObject
+	empty.IndexForSearch(vcard.Card{}, "")
+	assert.Empty(t, empty.SearchNames)
+	assert.Equal(t, "v2:", empty.SearchKey)
+}
+
+func TestParseSearchQuery(t *testing.T) {
+	q := ParseSearchQuery(`Müller email:jose@ org:"Café Ltd" tel:030 1234`, "DE")
+	assert.Equal(t, []SearchTerm{
+		{Field: SearchFieldAny, Text: "muller"},
+		{Field: SearchFieldEmail, Text: "jose@"},
+		{Field: SearchFieldOrganization, Text: "cafe ltd"},
+		{Field: SearchFieldPhone, Text: "030 1234", Phone: "+49301234"},
+	}, q.Terms)
+
+	// A query that looks like a phone number as a whole is one term
+	q = ParseSearchQuery("+49 30 1234", "DE")
+	require.Len(t, q.Terms, 1)
+	assert.Equal(t, "+49301234", q.Terms[0].Phone)
+
+	// Unknown prefixes are plain text, empty scoped terms are dropped
+	q = ParseSearchQuery("note:x email:", "")
+	assert.Equal(t, []SearchTerm{{Field: SearchFieldAny, Text: "note:x"}}, q.Terms)
+	assert.Empty(t, ParseSearchQuery("  ", "").Terms)
+}
+
+func TestSearchQueryMatches(t *testing.T) {
+	o := &AddressObject{
+		SearchNames:        "|jose muller|muller|jose|",
+		SearchEmails:       "|jose@example.com|pepe@example.org|",
+		SearchPhones:       "|+49301234567|",
+		SearchOrganization: "|acme|",
+	}
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"MÜLLER", true},
+		{"pepe@example", true},
+		{"030/1234567", true},
+		{"+49 30 1234", true},
+		{"4567", true},
+		{"jose acme", true},
+		{"jose initech", false},
+		{"email:muller", false},
+		{"name:acme", false},
+		{"org:acme", true},
+		{"tel:0301234567", true},
+		{"tel:jose", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, ParseSearchQuery(tt.query, "DE").Matches(o), tt.query)
+	}
+}
@@ -0,0 +1,266 @@
+package addressbook
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/emersion/go-vcard"
+)
+
+// Supported vCard versions
+const (
+	VCardVersion3 = "3.0"
+	VCardVersion4 = "4.0"
+)
+
+// Binary properties that are inlined as ENCODING=b in vCard 3.0 and as data
+// URIs in vCard 4.0
+var binaryFields = []string{vcard.FieldPhoto, vcard.FieldLogo, vcard.FieldSound, vcard.FieldKey}
+
+// Properties defined by vCard 3.0 only (RFC 6350 §6 drops them)
+var v3OnlyFields = []string{"AGENT", "CLASS", "LABEL", "MAILER", "NAME", "SORT-STRING"}
+
+// Properties defined by vCard 4.0 only, with the X- names vCard 3.0 clients
+// commonly use for them. An empty name means the property is dropped.
+var v4OnlyFields = map[string]string{
+	vcard.FieldAnniversary:  "X-ANNIVERSARY",
+	vcard.FieldGender:       "X-GENDER",
+	vcard.FieldLanguage:     "",
+	vcard.FieldXML:          "",
+	vcard.FieldClientPIDMap: "",
+}
+
+// IsSupportedVCardVersion reports whether version is 3.0 or 4.0
+func IsSupportedVCardVersion(version string) bool {
+	return version == VCardVersion3 || version == VCardVersion4
+}
+
+// ConvertVCard rewrites a card in place into the given version. It converts
+// PHOTO, LOGO, SOUND and KEY between inline and data URI form, TYPE=pref and
+// PREF=1, and group cards between the Apple and the vCard 4 style. Properties
+// that do not exist in the target version are renamed to their X- variant or
+// dropped. Cards already in the target version are left untouched.
+func ConvertVCard(card vcard.Card, version string) {
+	if card.Value(vcard.FieldVersion) == version {
+		return
+	}
+
+	switch version {
+	case VCardVersion4:
+		toV4(card)
+	case VCardVersion3:
+		toV3(card)
+	default:
+		return
+	}
+	card.SetValue(vcard.FieldVersion, version)
+}
+
+// ConvertVCardData converts every card in a vCard stream into the given
+// version and re-encodes them
+func ConvertVCardData(data, version string) (string, error) {
+	if !IsSupportedVCardVersion(version) {
+		return "", fmt.Errorf("unsupported vCard version %q", version)
+	}
+
+	var buf bytes.Buffer
+	dec := vcard.NewDecoder(strings.NewReader(data))
+	enc := vcard.NewEncoder(&buf)
+	for {
+		card, err := dec.Decode()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		ConvertVCard(card, version)
+		if err := enc.Encode(card); err != nil {
+			return "", err
+		}
+	}
+	return buf.String(), nil
+}
+
+func toV4(card vcard.Card) {
+	if GroupStyle(card) == GroupStyleApple {
+		ConvertGroupStyle(card, GroupStyleVCard4)
+	}
+	for _, name := range v3OnlyFields {
+		delete(card, name)
+	}
+	for v4Name, xName := range v4OnlyFields {
+		if xName != "" && card[xName] != nil && card[v4Name] == nil {
+			card[v4Name] = card[xName]
+			delete(card, xName)
+		}
+	}
+
+	for name, fields := range card {
+		for _, f := range fields {
+			if f.Params == nil {
+				continue
+			}
+			if removeType(f.Params, "pref") {
+				f.Params.Set(vcard.ParamPreferred, "1")
+			}
+			if isBinaryField(name) && strings.EqualFold(f.Params.Get("ENCODING"), "b") {
+				mediaType := binaryMediaType(name, f.Params.Types())
+				delete(f.Params, "ENCODING")
+				delete(f.Params, vcard.ParamType)
+				f.Value = "data:" + mediaType + ";base64," + f.Value
+			}
+		}
+	}
+}
+
+func toV3(card vcard.Card) {
+	switch {
+	case GroupStyle(card) == GroupStyleVCard4:
+		ConvertGroupStyle(card, GroupStyleApple)
+	case card[vcard.FieldKind] != nil:
+		// Apple only knows group cards; individual, org and location
+		// cards are plain vCard 3.0 contacts
+		delete(card, vcard.FieldKind)
+		delete(card, vcard.FieldMember)
+	}
+	for v4Name, xName := range v4OnlyFields {
+		if xName != "" && card[v4Name] != nil && card[xName] == nil {
+			card[xName] = card[v4Name]
+		}
+		delete(card, v4Name)
+	}
+
+	for name, fields := range card {
+		for _, f := range fields {
+			if f.Params != nil {
+				pref := f.Params.Get(vcard.ParamPreferred)
+				delete(f.Params, vcard.ParamPreferred)
+				delete(f.Params, vcard.ParamPID)
+				delete(f.Params, vcard.ParamAltID)
+				if pref == "1" && !f.Params.HasType("pref") {
+					f.Params.Add(vcard.ParamType, "pref")
+				}
+			}
+			if isBinaryField(name) {
+				if mediaType, data, ok := parseDataURI(f.Value); ok {
+					if f.Params == nil {
+						f.Params = make(vcard.Params)
+					}
+					f.Params.Set("ENCODING", "b")
+					if sub := mediaSubtype(mediaType); sub != "" {
+						f.Params.Set(vcard.ParamType, strings.ToUpper(sub))
+					}
+					delete(f.Params, vcard.ParamValue)
+					delete(f.Params, vcard.ParamMediaType)
+					f.Value = data
+				}
+			}
+			if name == vcard.FieldTelephone && f.Params != nil && strings.EqualFold(f.Params.Get(vcard.ParamValue), "uri") {
+				delete(f.Params, vcard.ParamValue)
+				f.Value = strings.TrimPrefix(f.Value, "tel:")
+			}
+		}
+	}
+}
+
+func isBinaryField(name string) bool {
+	for _, n := range binaryFields {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// binaryMediaType derives a MIME type from the vCard 3.0 TYPE parameter of a
+// binary property, e.g. TYPE=JPEG on PHOTO becomes image/jpeg
+func binaryMediaType(name string, types []string) string {
+	sub := "jpeg"
+	switch name {
+	case vcard.FieldSound:
+		sub = "ogg"
+	case vcard.FieldKey:
+		sub = "pgp-keys"
+	}
+	for _, t := range types {
+		if t != "" && !strings.EqualFold(t, "pref") {
+			sub = strings.ToLower(t)
+			break
+		}
+	}
+	if strings.Contains(sub, "/") {
+		return sub
+	}
+
+	switch name {
+	case vcard.FieldSound:
+		return "audio/" + sub
+	case vcard.FieldKey:
+		return "application/" + sub
+	default:
+		return "image/" + sub
+	}
+}
+
+// parseDataURI splits a base64 data URI into its media type and payload
+func parseDataURI(value string) (mediaType, data string, ok bool) {
+	if !strings.HasPrefix(strings.ToLower(value), "data:") {
+		return "", "", false
+	}
+	header, data, found := strings.Cut(value[len("data:"):], ",")
+	if !found || !strings.HasSuffix(strings.ToLower(header), ";base64") {
+		return "", "", false
+	}
+	return strings.TrimSuffix(header[:len(header)-len(";base64")], ";"), data, true
+}
+
+// ParsePhotoDataURI returns the base64 payload and vCard 3.0 TYPE of a PHOTO
+// given as a data URI
+func ParsePhotoDataURI(value string) (data, photoType string, ok bool) {
+	mediaType, data, ok := parseDataURI(value)
+	if !ok {
+		return "", "", false
+	}
+	return data, strings.ToUpper(mediaSubtype(mediaType)), true
+}
+
+// PhotoDataURI formats a base64 photo with a vCard 3.0 TYPE as a data URI
+func PhotoDataURI(data, photoType string) string {
+	return "data:" + binaryMediaType(vcard.FieldPhoto, []string{photoType}) + ";base64," + data
+}
+
+func mediaSubtype(mediaType string) string {
+	_, sub, _ := strings.Cut(mediaType, "/")
+	return sub
+}
+
+// removeType drops a value from the TYPE parameter and reports whether it
+// was present
+func removeType(params vcard.Params, value string) bool {
+	types := params[vcard.ParamType]
+	found := false
+	kept := types[:0]
+	for _, t := range types {
+		// TYPE may hold comma-separated values in a single entry
+		var parts []string
+		for _, p := range strings.Split(t, ",") {
+			if strings.EqualFold(p, value) {
+				found = true
+				continue
+			}
+			parts = append(parts, p)
+		}
+		if len(parts) > 0 {
+			kept = append(kept, strings.Join(parts, ","))
+		}
+	}
+	if len(kept) == 0 {
+		delete(params, vcard.ParamType)
+	} else {
+		params[vcard.ParamType] = kept
+	}
+	return found
+}
@@ -0,0 +1,80 @@
+package addressbook
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/emersion/go-vcard"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const v3Card = "BEGIN:VCARD\r\n" +
+	"VERSION:3.0\r\n" +
+	"UID:abc\r\n" +
+	"FN:Jane Doe\r\n" +
+	"EMAIL;TYPE=work,pref:jane@example.com\r\n" +
+	"PHOTO;ENCODING=b;TYPE=PNG:iVBORw0KGgo=\r\n" +
+	"X-ANNIVERSARY:2010-06-01\r\n" +
+	"MAILER:PigeonMail\r\n" +
+	"END:VCARD\r\n"
+
+func decodeCard(t *testing.T, data string) vcard.Card {
+	t.Helper()
+	card, err := vcard.NewDecoder(strings.NewReader(data)).Decode()
+	require.NoError(t, err)
+	return card
+}
+
+func TestConvertVCard_RoundTrip(t *testing.T) {
+	card := decodeCard(t, v3Card)
+
+	ConvertVCard(card, VCardVersion4)
+	assert.Equal(t, "4.0", card.Value(vcard.FieldVersion))
+	assert.Equal(t, "data:image/png;base64,iVBORw0KGgo=", card.Value(vcard.FieldPhoto))
+	assert.Empty(t, card.Get(vcard.FieldPhoto).Params.Get("ENCODING"))
+	email := card.Get(vcard.FieldEmail)
+	assert.Equal(t, "1", email.Params.Get(vcard.ParamPreferred))
+	assert.False(t, email.Params.HasType("pref"))
+	assert.True(t, email.Params.HasType("work"))
+	assert.Equal(t, "2010-06-01", card.Value(vcard.FieldAnniversary))
+	assert.Nil(t, card["MAILER"])
+
+	ConvertVCard(card, VCardVersion3)
+	assert.Equal(t, "3.0", card.Value(vcard.FieldVersion))
+	photo := card.Get(vcard.FieldPhoto)
+	assert.Equal(t, "iVBORw0KGgo=", photo.Value)
+	assert.Equal(t, "b", photo.Params.Get("ENCODING"))
+	assert.Equal(t, "PNG", photo.Params.Get(vcard.ParamType))
+	email = card.Get(vcard.FieldEmail)
+	assert.True(t, email.Params.HasType("pref"))
+	assert.Empty(t, email.Params.Get(vcard.ParamPreferred))
+	assert.Equal(t, "2010-06-01", card.Value("X-ANNIVERSARY"))
+	assert.Nil(t, card[vcard.FieldAnniversary])
+}
+
+func TestConvertVCard_Kind(t *testing.T) {
+	group := make(vcard.Card)
+	SetGroup(group, GroupStyleApple, []string{"m1"})
+	ConvertVCard(group, VCardVersion4)
+	assert.Equal(t, GroupStyleVCard4, GroupStyle(group))
+	assert.Equal(t, []string{"m1"}, GroupMembers(group))
+
+	ConvertVCard(group, VCardVersion3)
+	assert.Equal(t, GroupStyleApple, GroupStyle(group))
+	assert.Equal(t, "3.0", group.Value(vcard.FieldVersion))
+
+	org := decodeCard(t, "BEGIN:VCARD\r\nVERSION:4.0\r\nKIND:org\r\nFN:ACME\r\nTEL;VALUE=uri:tel:+15551234\r\nEND:VCARD\r\n")
+	ConvertVCard(org, VCardVersion3)
+	assert.Nil(t, org[vcard.FieldKind])
+	assert.Equal(t, "+15551234", org.Value(vcard.FieldTelephone))
+}
+
+func TestConvertVCardData(t *testing.T) {
+	out, err := ConvertVCardData(v3Card+v3Card, VCardVersion4)
+	require.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(out, "VERSION:4.0"))
+
+	_, err = ConvertVCardData(v3Card, "2.1")
+	assert.Error(t, err)
+}
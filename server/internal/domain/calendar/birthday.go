@@ -0,0 +1,308 @@
+package calendar
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-ical"
+)
+
+// BirthdayCalendarPath is the path of the birthday calendar in every user's
+// calendar home
+const BirthdayCalendarPath = "birthdays"
+
+// BirthdayCalendarID is the CalendarID the birthday events are presented
+// under. The birthday calendar has no Calendar row, so the ID never collides
+// with a real calendar.
+const BirthdayCalendarID uint = 0
+
+// OmittedYear is the placeholder year Apple clients store in contact dates
+// without a year
+const OmittedYear = 1604
+
+// YearlessEventYear is the year in which the events of year-less contact
+// dates start. It is a leap year, so February 29 stays valid, and recent
+// enough for recurrence expansion in clients and in this server.
+const YearlessEventYear = 1972
+
+// Kinds of contact dates in the birthday calendar
+const (
+	BirthdayKindBirthday    = "birthday"
+	BirthdayKindAnniversary = "anniversary"
+)
+
+// DefaultBirthdayCalendarName is the name of the birthday calendar until the
+// user renames it
+const DefaultBirthdayCalendarName = "Birthdays"
+
+// BirthdaySettings are a user's settings of the birthday calendar. Users
+// without a row get the defaults of NewBirthdaySettings.
+type BirthdaySettings struct {
+	ID       uint   `gorm:"primaryKey"`
+	UserID   uint   `gorm:"uniqueIndex;not null"`
+	Disabled bool   `gorm:"not null;default:false"`
+	Name     string `gorm:"size:255;not null"`
+	Color    string `gorm:"size:7;not null"`
+	// ReminderEnabled adds an alarm to every event, ReminderDaysBefore days
+	// before the date at ReminderTime (HH:MM)
+	ReminderEnabled    bool   `gorm:"not null;default:false"`
+	ReminderDaysBefore int    `gorm:"not null;default:0"`
+	ReminderTime       string `gorm:"size:5;not null"`
+	// SourceTag fingerprints the address books the events were generated
+	// from, so unchanged contacts are not parsed again
+	SourceTag string `gorm:"size:64;not null;default:''"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// TableName specifies the table name for BirthdaySettings
+func (BirthdaySettings) TableName() string {
+	return "birthday_calendar_settings"
+}
+
+// NewBirthdaySettings returns the default settings of a user
+func NewBirthdaySettings(userID uint) *BirthdaySettings {
+	return &BirthdaySettings{
+		UserID:       userID,
+		Name:         DefaultBirthdayCalendarName,
+		Color:        "#e83e8c",
+		ReminderTime: "09:00",
+	}
+}
+
+// Enabled reports whether the birthday calendar is shown
+func (s *BirthdaySettings) Enabled() bool {
+	return !s.Disabled
+}
+
+// ReminderTrigger returns the alarm trigger relative to the start of the
+// all-day event
+func (s *BirthdaySettings) ReminderTrigger() time.Duration {
+	hour, _ := strconv.Atoi(s.ReminderTime[:2])
+	minute, _ := strconv.Atoi(s.ReminderTime[3:])
+	return time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute -
+		time.Duration(s.ReminderDaysBefore)*24*time.Hour
+}
+
+// BirthdayEvent is a generated event of the birthday calendar. The iCalendar
+// data and its ETag are kept so that regenerating the calendar only reports
+// the events that actually changed.
+type BirthdayEvent struct {
+	ID              uint   `gorm:"primaryKey"`
+	UserID          uint   `gorm:"uniqueIndex:idx_birthday_events_user_path;not null"`
+	Path            string `gorm:"uniqueIndex:idx_birthday_events_user_path;size:255;not null"`
+	UID             string `gorm:"size:255;not null"`
+	AddressObjectID uint   `gorm:"index;not null"`
+	Kind            string `gorm:"size:20;not null"`
+	ETag            string `gorm:"size:64;not null"`
+	Summary         string `gorm:"size:500"`
+	ICalData        string `gorm:"type:text;not null"`
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+// TableName specifies the table name for BirthdayEvent
+func (BirthdayEvent) TableName() string {
+	return "birthday_events"
+}
+
+// CalendarObject presents the event as a read-only calendar object
+func (e *BirthdayEvent) CalendarObject() *CalendarObject {
+	obj := &CalendarObject{
+		UUID:          e.UID,
+		CalendarID:    BirthdayCalendarID,
+		Path:          e.Path,
+		UID:           e.UID,
+		ETag:          e.ETag,
+		ComponentType: "VEVENT",
+		ICalData:      e.ICalData,
+		ContentLength: len(e.ICalData),
+		Summary:       e.Summary,
+		IsAllDay:      true,
+		CreatedAt:     e.CreatedAt,
+		UpdatedAt:     e.UpdatedAt,
+	}
+	if cal, err := ical.NewDecoder(strings.NewReader(e.ICalData)).Decode(); err == nil {
+		if events := cal.Events(); len(events) > 0 {
+			if start, err := events[0].DateTimeStart(time.UTC); err == nil {
+				end := start.AddDate(0, 0, 1)
+				obj.StartTime, obj.EndTime = &start, &end
+			}
+		}
+	}
+	return obj
+}
+
+// BirthdayChangeLog tracks changes to a user's birthday calendar for
+// WebDAV-Sync (RFC 6578)
+type BirthdayChangeLog struct {
+	ID           uint      `gorm:"primaryKey"`
+	UserID       uint      `gorm:"index;not null"`
+	ResourcePath string    `gorm:"size:255;not null"`
+	ResourceUID  string    `gorm:"size:255"`
+	ChangeType   string    `gorm:"size:20;not null"` // created, modified, deleted
+	SyncToken    string    `gorm:"index;size:64;not null"`
+	CreatedAt    time.Time `gorm:"index"`
+}
+
+// TableName specifies the table name for BirthdayChangeLog
+func (BirthdayChangeLog) TableName() string {
+	return "birthday_change_logs"
+}
+
+// SyncChangeLog presents the entry as a change of the birthday calendar
+func (l *BirthdayChangeLog) SyncChangeLog() *SyncChangeLog {
+	return &SyncChangeLog{
+		ID:           l.ID,
+		CalendarID:   BirthdayCalendarID,
+		ResourcePath: l.ResourcePath,
+		ResourceUID:  l.ResourceUID,
+		ChangeType:   l.ChangeType,
+		SyncToken:    l.SyncToken,
+		CreatedAt:    l.CreatedAt,
+	}
+}
+
+// ContactDate is a birthday or anniversary of a contact. Year is zero when
+// the contact only records the month and day.
+type ContactDate struct {
+	Year  int
+	Month time.Month
+	Day   int
+}
+
+// ParseContactDate parses a vCard BDAY or ANNIVERSARY value: a full date
+// (1990-05-01, 19900501, optionally followed by a time) or a date without
+// year (--05-01, --0501). Dates in OmittedYear count as year-less.
+func ParseContactDate(value string) (ContactDate, bool) {
+	value = strings.TrimSpace(value)
+	if i := strings.IndexByte(value, 'T'); i >= 0 {
+		value = value[:i]
+	}
+
+	var year, month, day string
+	switch {
+	case strings.HasPrefix(value, "--"):
+		rest := strings.ReplaceAll(value[2:], "-", "")
+		if len(rest) != 4 {
+			return ContactDate{}, false
+		}
+		month, day = rest[:2], rest[2:]
+	default:
+		digits := strings.ReplaceAll(value, "-", "")
+		if len(digits) != 8 {
+			return ContactDate{}, false
+		}
+		year, month, day = digits[:4], digits[4:6], digits[6:]
+	}
+
+	d := ContactDate{}
+	m, err := strconv.Atoi(month)
+	if err != nil || m < 1 || m > 12 {
+		return ContactDate{}, false
+	}
+	d.Month = time.Month(m)
+	if d.Day, err = strconv.Atoi(day); err != nil {
+		return ContactDate{}, false
+	}
+	if year != "" {
+		if d.Year, err = strconv.Atoi(year); err != nil {
+			return ContactDate{}, false
+		}
+		if d.Year == OmittedYear {
+			d.Year = 0
+		}
+	}
+
+	// Reject days the month does not have, February 29 only in leap years
+	check := d.Year
+	if check == 0 {
+		check = YearlessEventYear
+	}
+	if t := time.Date(check, d.Month, d.Day, 0, 0, 0, 0, time.UTC); d.Day < 1 || t.Day() != d.Day {
+		return ContactDate{}, false
+	}
+	return d, true
+}
+
+// BirthdaySource is a birthday or anniversary found on a contact
+type BirthdaySource struct {
+	AddressObjectID   uint
+	AddressObjectUUID string
+	Name              string
+	Kind              string
+	Date              ContactDate
+	// Stamp is used as DTSTAMP, so it has to stay the same while the
+	// contact's date does not change
+	Stamp time.Time
+}
+
+// NewBirthdayEvent builds the yearly all-day event of a contact date
+func NewBirthdayEvent(userID uint, src BirthdaySource, settings *BirthdaySettings) (*BirthdayEvent, error) {
+	uid := src.AddressObjectUUID + "-" + src.Kind
+	summary := fmt.Sprintf("%s's %s", src.Name, src.Kind)
+
+	year := src.Date.Year
+	if year == 0 {
+		year = YearlessEventYear
+	}
+	start := time.Date(year, src.Date.Month, src.Date.Day, 0, 0, 0, 0, time.UTC)
+
+	event := ical.NewEvent()
+	event.Props.SetText(ical.PropUID, uid)
+	event.Props.SetDateTime(ical.PropDateTimeStamp, src.Stamp.UTC())
+	event.Props.SetDate(ical.PropDateTimeStart, start)
+	event.Props.SetDate(ical.PropDateTimeEnd, start.AddDate(0, 0, 1))
+	rule := "FREQ=YEARLY"
+	if src.Date.Month == time.February && src.Date.Day == 29 {
+		// Celebrated on February 28 in common years
+		rule = "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=-1"
+	}
+	event.Props.Set(&ical.Prop{Name: ical.PropRecurrenceRule, Params: ical.Params{}, Value: rule})
+	event.Props.SetText(ical.PropSummary, summary)
+	if src.Date.Year != 0 {
+		verb := "Born"
+		if src.Kind == BirthdayKindAnniversary {
+			verb = "Since"
+		}
+		event.Props.SetText(ical.PropDescription, fmt.Sprintf("%s %d", verb, src.Date.Year))
+	}
+	event.Props.SetText(ical.PropTransparency, "TRANSPARENT")
+	event.Props.SetText(ical.PropCategories, strings.ToUpper(src.Kind[:1])+src.Kind[1:])
+
+	if settings.ReminderEnabled {
+		alarm := ical.NewComponent(ical.CompAlarm)
+		alarm.Props.SetText(ical.PropAction, "DISPLAY")
+		alarm.Props.SetText(ical.PropDescription, summary)
+		trigger := ical.NewProp(ical.PropTrigger)
+		trigger.SetDuration(settings.ReminderTrigger())
+		alarm.Props.Set(trigger)
+		event.Children = append(event.Children, alarm)
+	}
+
+	cal := ical.NewCalendar()
+	cal.Props.SetText(ical.PropVersion, "2.0")
+	cal.Props.SetText(ical.PropProductID, "-//CalCard//Birthdays//EN")
+	cal.Children = append(cal.Children, event.Component)
+
+	var buf strings.Builder
+	if err := ical.NewEncoder(&buf).Encode(cal); err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256([]byte(buf.String()))
+
+	return &BirthdayEvent{
+		UserID:          userID,
+		Path:            uid + ".ics",
+		UID:             uid,
+		AddressObjectID: src.AddressObjectID,
+		Kind:            src.Kind,
+		ETag:            fmt.Sprintf("\"%s\"", hex.EncodeToString(sum[:16])),
+		Summary:         summary,
+		ICalData:        buf.String(),
+	}, nil
+}
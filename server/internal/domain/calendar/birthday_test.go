@@ -0,0 +1,70 @@
+package calendar
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseContactDate(t *testing.T) {
+	tests := []struct {
+		value string
+		want  ContactDate
+		ok    bool
+	}{
+		{"1990-05-01", ContactDate{1990, time.May, 1}, true},
+		{"19900501", ContactDate{1990, time.May, 1}, true},
+		{"1990-05-01T00:00:00Z", ContactDate{1990, time.May, 1}, true},
+		{"--05-01", ContactDate{0, time.May, 1}, true},
+		{"--0229", ContactDate{0, time.February, 29}, true},
+		{"1604-12-24", ContactDate{0, time.December, 24}, true},
+		{"1991-02-29", ContactDate{}, false},
+		{"1990-13-01", ContactDate{}, false},
+		{"circa 1800", ContactDate{}, false},
+		{"", ContactDate{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, ok := ParseContactDate(tt.value)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNewBirthdayEvent(t *testing.T) {
+	settings := NewBirthdaySettings(1)
+	src := BirthdaySource{
+		AddressObjectID:   7,
+		AddressObjectUUID: "obj-uuid",
+		Name:              "Alice Liddell",
+		Kind:              BirthdayKindBirthday,
+		Date:              ContactDate{Month: time.February, Day: 29},
+		Stamp:             time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	event, err := NewBirthdayEvent(1, src, settings)
+	require.NoError(t, err)
+	assert.Equal(t, "obj-uuid-birthday.ics", event.Path)
+	assert.Equal(t, "Alice Liddell's birthday", event.Summary)
+	assert.Contains(t, event.ICalData, "DTSTART;VALUE=DATE:19720229")
+	assert.NotContains(t, event.ICalData, "VALARM")
+
+	// Year-less leap day birthdays fall on February 28 in common years
+	instances, err := ExpandRecurringEvent(event.CalendarObject(),
+		time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.Len(t, instances, 1)
+	assert.Equal(t, time.Date(2025, 2, 28, 0, 0, 0, 0, time.UTC), instances[0].Start)
+	assert.True(t, instances[0].IsAllDay)
+
+	// The reminder changes the data and therefore the ETag
+	settings.ReminderEnabled = true
+	settings.ReminderDaysBefore = 1
+	reminded, err := NewBirthdayEvent(1, src, settings)
+	require.NoError(t, err)
+	assert.Contains(t, reminded.ICalData, "TRIGGER:-PT54000S")
+	assert.NotEqual(t, event.ETag, reminded.ETag)
+}
@@ -21,24 +21,26 @@ const (
 
 // Calendar represents a calendar collection
 type Calendar struct {
-	ID                  uint           `gorm:"primaryKey" json:"id"`
-	UUID                string         `gorm:"uniqueIndex;size:36;not null" json:"uuid"`
-	UserID              uint           `gorm:"index;not null" json:"user_id"`
-	Owner               user.User      `gorm:"foreignKey:UserID" json:"-"`
-	Path                string         `gorm:"size:255;not null" json:"path"` // URL path component
-	Name                string         `gorm:"size:255;not null" json:"name"`
-	Description         string         `gorm:"size:1000" json:"description"`
-	Color               string         `gorm:"size:7;not null" json:"color"` // #RRGGBB
-	Timezone            string         `gorm:"size:50;not null" json:"timezone"`
-	SupportedComponents string         `gorm:"size:100;not null" json:"supported_components"` // "VEVENT,VTODO"
-	SyncToken           string         `gorm:"size:64;not null;default:''" json:"sync_token"`
-	CTag                string         `gorm:"column:ctag;size:64;not null;default:''" json:"ctag"`
-	PublicToken         *string        `gorm:"uniqueIndex;size:64" json:"-"`
-	PublicEnabled       bool           `gorm:"default:false" json:"public_enabled"`
-	PublicEnabledAt     *time.Time     `json:"public_enabled_at,omitempty"`
-	CreatedAt           time.Time      `json:"created_at"`
-	UpdatedAt           time.Time      `json:"updated_at"`
-	DeletedAt           gorm.DeletedAt `gorm:"index" json:"-"`
+	ID                  uint              `gorm:"primaryKey" json:"id"`
+	UUID                string            `gorm:"uniqueIndex;size:36;not null" json:"uuid"`
+	UserID              uint              `gorm:"index;not null" json:"user_id"`
+	Owner               user.User         `gorm:"foreignKey:UserID" json:"-"`
+	Path                string            `gorm:"size:255;not null" json:"path"` // URL path component
+	Name                string            `gorm:"size:255;not null" json:"name"`
+	Description         string            `gorm:"size:1000" json:"description"`
+	Color               string            `gorm:"size:7;not null" json:"color"` // #RRGGBB
+	Timezone            string            `gorm:"size:50;not null" json:"timezone"`
+	SupportedComponents string            `gorm:"size:100;not null" json:"supported_components"` // "VEVENT,VTODO"
+	SyncToken           string            `gorm:"size:64;not null;default:''" json:"sync_token"`
+	CTag                string            `gorm:"column:ctag;size:64;not null;default:''" json:"ctag"`
+	ReadOnly            bool              `gorm:"not null;default:false" json:"read_only"` // Subscribed feeds; objects change only through the fetcher
+	PublicToken         *string           `gorm:"uniqueIndex;size:64" json:"-"`
+	PublicEnabled       bool              `gorm:"default:false" json:"public_enabled"`
+	PublicEnabledAt     *time.Time        `json:"public_enabled_at,omitempty"`
+	PublicFeed          PublicFeedOptions `gorm:"embedded;embeddedPrefix:public_feed_" json:"-"`
+	CreatedAt           time.Time         `json:"created_at"`
+	UpdatedAt           time.Time         `json:"updated_at"`
+	DeletedAt           gorm.DeletedAt    `gorm:"index" json:"-"`
 }
 
 // TableName specifies the table name for Calendar
@@ -15,7 +15,7 @@ type CalendarObject struct {
 	UID           string         `gorm:"index;size:255;not null" json:"uid"` // iCalendar UID
 	ETag          string         `gorm:"size:64;not null" json:"etag"`
 	ComponentType string         `gorm:"size:20;not null" json:"component_type"` // VEVENT, VTODO
-	ICalData      string         `gorm:"type:text;not null" json:"ical_data"`
+	ICalData      string         `gorm:"type:text;not null;serializer:encrypted_payload" json:"ical_data"`
 	ContentLength int            `gorm:"not null" json:"content_length"`
 	Summary       string         `gorm:"size:500" json:"summary"`      // Denormalized for search
 	Description   string         `gorm:"type:text" json:"description"` // Denormalized
@@ -0,0 +1,140 @@
+package calendar
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-ical"
+)
+
+// PublicBusySummary is the summary of the busy blocks that stand in for
+// events published without details
+const PublicBusySummary = "Busy"
+
+// MaxPublicFeedDays bounds the past and future window of a public feed
+const MaxPublicFeedDays = 3650
+
+// PublicFeedOptions are the publication settings of a calendar's public
+// feed. The zero value publishes every event with all details.
+type PublicFeedOptions struct {
+	// PastDays and FutureDays limit the feed to events that overlap the
+	// window from PastDays before today to FutureDays after it; nil leaves
+	// that side open
+	PastDays   *int
+	FutureDays *int
+	// FreeBusyOnly publishes busy blocks without any details
+	FreeBusyOnly     bool `gorm:"not null;default:false"`
+	HideAttendees    bool `gorm:"not null;default:false"`
+	HideDescriptions bool `gorm:"not null;default:false"`
+	HideAlarms       bool `gorm:"not null;default:false"`
+	// PasswordHash is the bcrypt hash of the password the feed asks for
+	// with HTTP Basic auth; empty for feeds without a password
+	PasswordHash string `gorm:"size:255;not null;default:''"`
+	// ExpiresAt ends the publication; the token stops working afterwards
+	ExpiresAt *time.Time
+}
+
+// Protected reports whether the feed asks for a password
+func (o *PublicFeedOptions) Protected() bool {
+	return o.PasswordHash != ""
+}
+
+// Expired reports whether the publication ended
+func (o *PublicFeedOptions) Expired(now time.Time) bool {
+	return o.ExpiresAt != nil && !now.Before(*o.ExpiresAt)
+}
+
+// Window returns the time range the feed covers; a zero start or end
+// leaves that side open. The window follows whole days in loc.
+func (o *PublicFeedOptions) Window(now time.Time, loc *time.Location) (start, end time.Time) {
+	today := now.In(loc)
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, loc)
+	if o.PastDays != nil {
+		start = today.AddDate(0, 0, -*o.PastDays)
+	}
+	if o.FutureDays != nil {
+		end = today.AddDate(0, 0, *o.FutureDays+1)
+	}
+	return start, end
+}
+
+// ValidatePublicFeedDays validates the past or future window of a public feed
+func ValidatePublicFeedDays(days *int) error {
+	if days != nil && (*days < 0 || *days > MaxPublicFeedDays) {
+		return fmt.Errorf("window days must be between 0 and %d", MaxPublicFeedDays)
+	}
+	return nil
+}
+
+// busyProperties are the properties a busy block keeps: its identity, its
+// time and its recurrence
+var busyProperties = []string{
+	ical.PropUID, ical.PropDateTimeStamp, ical.PropDateTimeStart, ical.PropDateTimeEnd,
+	ical.PropDuration, ical.PropRecurrenceRule, ical.PropRecurrenceDates,
+	ical.PropExceptionDates, ical.PropRecurrenceID, ical.PropSequence,
+}
+
+// PublishedAsBusy reports whether PublishComponent turns an event into a
+// busy block
+func PublishedAsBusy(comp *ical.Component, o *PublicFeedOptions) bool {
+	return o.FreeBusyOnly || componentClass(comp) == "CONFIDENTIAL"
+}
+
+func componentClass(comp *ical.Component) string {
+	if p := comp.Props.Get(ical.PropClass); p != nil {
+		return strings.ToUpper(p.Value)
+	}
+	return ""
+}
+
+// PublishComponent returns the event or task as the public feed shows it,
+// or nil if the feed leaves it out. CLASS:PRIVATE events are left out and
+// CLASS:CONFIDENTIAL ones become busy blocks (RFC 5545 §3.8.1.3). In
+// free/busy mode every event becomes a busy block; transparent and
+// cancelled events and tasks are left out.
+func PublishComponent(comp *ical.Component, o *PublicFeedOptions) *ical.Component {
+	if componentClass(comp) == "PRIVATE" {
+		return nil
+	}
+
+	if PublishedAsBusy(comp, o) {
+		if comp.Name != ical.CompEvent {
+			return nil
+		}
+		if p := comp.Props.Get(ical.PropTransparency); p != nil && p.Value == "TRANSPARENT" {
+			return nil
+		}
+		if p := comp.Props.Get(ical.PropStatus); p != nil && p.Value == "CANCELLED" {
+			return nil
+		}
+		busy := ical.NewComponent(comp.Name)
+		for _, name := range busyProperties {
+			if props, ok := comp.Props[name]; ok {
+				busy.Props[name] = props
+			}
+		}
+		busy.Props.SetText(ical.PropSummary, PublicBusySummary)
+		return busy
+	}
+
+	published := ical.NewComponent(comp.Name)
+	for name, props := range comp.Props {
+		published.Props[name] = props
+	}
+	if o.HideAttendees {
+		delete(published.Props, ical.PropAttendee)
+		delete(published.Props, ical.PropOrganizer)
+	}
+	if o.HideDescriptions {
+		delete(published.Props, ical.PropDescription)
+		delete(published.Props, "X-ALT-DESC")
+	}
+	for _, child := range comp.Children {
+		if child.Name == ical.CompAlarm && o.HideAlarms {
+			continue
+		}
+		published.Children = append(published.Children, child)
+	}
+	return published
+}
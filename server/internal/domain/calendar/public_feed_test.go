@@ -0,0 +1,78 @@
+package calendar
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-ical"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublishComponent(t *testing.T) {
+	event := func(extra string) *ical.Component {
+		cal, err := ical.NewDecoder(strings.NewReader("BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//Test//EN\r\n" +
+			"BEGIN:VEVENT\r\nUID:e1\r\nDTSTAMP:20240101T000000Z\r\nDTSTART:20240122T090000Z\r\nDTEND:20240122T100000Z\r\n" +
+			"RRULE:FREQ=WEEKLY\r\nSUMMARY:Review\r\nDESCRIPTION:Salaries\r\nLOCATION:Room 1\r\n" +
+			"ORGANIZER:mailto:boss@example.com\r\nATTENDEE:mailto:staff@example.com\r\n" + extra +
+			"BEGIN:VALARM\r\nACTION:DISPLAY\r\nTRIGGER:-PT15M\r\nDESCRIPTION:Reminder\r\nEND:VALARM\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n")).Decode()
+		require.NoError(t, err)
+		return cal.Children[0]
+	}
+
+	t.Run("All Details", func(t *testing.T) {
+		pub := PublishComponent(event(""), &PublicFeedOptions{})
+		require.NotNil(t, pub)
+		assert.NotNil(t, pub.Props.Get(ical.PropDescription))
+		assert.NotNil(t, pub.Props.Get(ical.PropAttendee))
+		assert.Len(t, pub.Children, 1)
+	})
+
+	t.Run("Stripped Details", func(t *testing.T) {
+		pub := PublishComponent(event(""), &PublicFeedOptions{HideAttendees: true, HideDescriptions: true, HideAlarms: true})
+		require.NotNil(t, pub)
+		assert.Equal(t, "Review", pub.Props.Get(ical.PropSummary).Value)
+		assert.Nil(t, pub.Props.Get(ical.PropDescription))
+		assert.Nil(t, pub.Props.Get(ical.PropAttendee))
+		assert.Nil(t, pub.Props.Get(ical.PropOrganizer))
+		assert.Empty(t, pub.Children)
+	})
+
+	t.Run("Busy Blocks", func(t *testing.T) {
+		for _, pub := range []*ical.Component{
+			PublishComponent(event(""), &PublicFeedOptions{FreeBusyOnly: true}),
+			PublishComponent(event("CLASS:CONFIDENTIAL\r\n"), &PublicFeedOptions{}),
+		} {
+			require.NotNil(t, pub)
+			assert.Equal(t, PublicBusySummary, pub.Props.Get(ical.PropSummary).Value)
+			assert.NotNil(t, pub.Props.Get(ical.PropRecurrenceRule))
+			assert.NotNil(t, pub.Props.Get(ical.PropDateTimeStart))
+			assert.Nil(t, pub.Props.Get(ical.PropLocation))
+			assert.Nil(t, pub.Props.Get(ical.PropAttendee))
+			assert.Empty(t, pub.Children)
+		}
+		assert.Nil(t, PublishComponent(event("TRANSP:TRANSPARENT\r\n"), &PublicFeedOptions{FreeBusyOnly: true}))
+	})
+
+	t.Run("Private Events", func(t *testing.T) {
+		assert.Nil(t, PublishComponent(event("CLASS:PRIVATE\r\n"), &PublicFeedOptions{}))
+	})
+}
+
+func TestPublicFeedWindow(t *testing.T) {
+	past, future := 7, 30
+	now := time.Date(2024, 3, 10, 15, 30, 0, 0, time.UTC)
+
+	start, end := (&PublicFeedOptions{}).Window(now, time.UTC)
+	assert.True(t, start.IsZero())
+	assert.True(t, end.IsZero())
+
+	start, end = (&PublicFeedOptions{PastDays: &past, FutureDays: &future}).Window(now, time.UTC)
+	assert.Equal(t, time.Date(2024, 3, 3, 0, 0, 0, 0, time.UTC), start)
+	assert.Equal(t, time.Date(2024, 4, 10, 0, 0, 0, 0, time.UTC), end)
+
+	expiry := now.Add(-time.Minute)
+	assert.True(t, (&PublicFeedOptions{ExpiresAt: &expiry}).Expired(now))
+	assert.False(t, (&PublicFeedOptions{}).Expired(now))
+}
@@ -58,9 +58,50 @@ type CalendarRepository interface {
 	// GetCalendarObjectByUUID retrieves a calendar object by UUID
 	GetCalendarObjectByUUID(ctx context.Context, uuid string) (*CalendarObject, error)
 
+	// GetCalendarObjectByUID retrieves the object of a calendar with an
+	// iCalendar UID, or nil if there is none
+	GetCalendarObjectByUID(ctx context.Context, calendarID uint, uid string) (*CalendarObject, error)
+
 	// GetUserPermission determines a user's permission for a calendar
 	GetUserPermission(ctx context.Context, calendarID, userID uint) (CalendarPermission, error)
 
 	// FindByPublicToken retrieves a calendar by its public token
 	FindByPublicToken(ctx context.Context, token string) (*Calendar, error)
 }
+
+// BirthdayRepository persists the birthday calendar settings, the generated
+// events and their change log
+type BirthdayRepository interface {
+	// GetSettings returns the settings of a user, or nil if the user never
+	// changed them
+	GetSettings(ctx context.Context, userID uint) (*BirthdaySettings, error)
+	// SaveSettings creates or updates the settings of a user
+	SaveSettings(ctx context.Context, settings *BirthdaySettings) error
+	// ListEvents returns the events of a user ordered by summary
+	ListEvents(ctx context.Context, userID uint) ([]BirthdayEvent, error)
+	// SaveChanges stores created and modified events, removes the given
+	// events and logs every change under one sync token
+	SaveChanges(ctx context.Context, userID uint, created, modified, removed []BirthdayEvent, token string) error
+	// SyncToken returns the token of the latest change of a user's birthday
+	// calendar, or an empty string if it never changed
+	SyncToken(ctx context.Context, userID uint) (string, error)
+	// GetChangesSinceToken returns the changes logged after the given token,
+	// or gorm.ErrRecordNotFound for an unknown token
+	GetChangesSinceToken(ctx context.Context, userID uint, token string) ([]*SyncChangeLog, error)
+}
+
+// SubscriptionRepository persists feed subscriptions
+type SubscriptionRepository interface {
+	Create(ctx context.Context, s *Subscription) error
+	// GetByID returns a subscription with its calendar, or nil
+	GetByID(ctx context.Context, id uint) (*Subscription, error)
+	// GetByCalendarID returns the subscription of a calendar, or nil
+	GetByCalendarID(ctx context.Context, calendarID uint) (*Subscription, error)
+	// ListByUserID returns the subscriptions of a user with their calendars
+	ListByUserID(ctx context.Context, userID uint) ([]*Subscription, error)
+	// ListDue returns up to limit subscriptions whose next fetch is due,
+	// the longest overdue first
+	ListDue(ctx context.Context, now time.Time, limit int) ([]*Subscription, error)
+	Update(ctx context.Context, s *Subscription) error
+	Delete(ctx context.Context, id uint) error
+}
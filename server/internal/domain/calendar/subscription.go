@@ -0,0 +1,236 @@
+package calendar
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-ical"
+)
+
+// ErrInvalidSubscriptionURL is returned for feed URLs that are not absolute
+// http, https, webcal or webcals URLs
+var ErrInvalidSubscriptionURL = errors.New("subscription URL must be an http, https or webcal URL")
+
+// MaxSubscriptionBackoff bounds how long a failing feed waits before the
+// next attempt
+const MaxSubscriptionBackoff = 24 * time.Hour
+
+// Subscription is an external iCalendar feed a user subscribed to. The
+// fetcher mirrors the feed into a read-only calendar, asking the origin
+// only for changes with the validators of the previous response.
+type Subscription struct {
+	ID         uint     `gorm:"primaryKey"`
+	UserID     uint     `gorm:"index;not null"`
+	CalendarID uint     `gorm:"uniqueIndex;not null"`
+	Calendar   Calendar `gorm:"foreignKey:CalendarID"`
+	URL        string   `gorm:"size:2048;not null"`
+	// RefreshInterval is the time between two fetches of the feed
+	RefreshInterval time.Duration `gorm:"not null"`
+	// ETag and LastModified are the validators of the last feed response,
+	// sent as If-None-Match and If-Modified-Since
+	ETag          string    `gorm:"size:255;not null;default:''"`
+	LastModified  string    `gorm:"size:64;not null;default:''"`
+	NextFetchAt   time.Time `gorm:"index;not null"`
+	LastFetchedAt *time.Time
+	LastSuccessAt *time.Time
+	// LastError describes why the last fetch failed; empty after a success
+	LastError    string `gorm:"size:1000;not null;default:''"`
+	FailureCount int    `gorm:"not null;default:0"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// NormalizeSubscriptionURL validates a feed URL and rewrites the webcal and
+// webcals schemes to http and https
+func NormalizeSubscriptionURL(raw string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" {
+		return "", ErrInvalidSubscriptionURL
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "webcal":
+		u.Scheme = "http"
+	case "https", "webcals":
+		u.Scheme = "https"
+	default:
+		return "", ErrInvalidSubscriptionURL
+	}
+	u.Fragment = ""
+	return u.String(), nil
+}
+
+// Succeeded records a fetch that returned the feed or confirmed it unchanged
+func (s *Subscription) Succeeded(now time.Time) {
+	s.LastFetchedAt = &now
+	s.LastSuccessAt = &now
+	s.LastError = ""
+	s.FailureCount = 0
+	s.NextFetchAt = now.Add(s.RefreshInterval)
+}
+
+// Failed records a failed fetch. Consecutive failures double the time to
+// the next attempt, up to MaxSubscriptionBackoff.
+func (s *Subscription) Failed(now time.Time, err error) {
+	s.LastFetchedAt = &now
+	s.LastError = err.Error()
+	if len(s.LastError) > 1000 {
+		s.LastError = s.LastError[:1000]
+	}
+	s.FailureCount++
+	wait := s.RefreshInterval
+	for i := 1; i < s.FailureCount && wait < MaxSubscriptionBackoff; i++ {
+		wait *= 2
+	}
+	if limit := max(MaxSubscriptionBackoff, s.RefreshInterval); wait > limit {
+		wait = limit
+	}
+	s.NextFetchAt = now.Add(wait)
+}
+
+// FeedRequest asks for a feed, conditionally on the validators of the
+// previous response
+type FeedRequest struct {
+	URL          string
+	ETag         string
+	LastModified string
+}
+
+// FeedResponse is the answer of a feed's origin
+type FeedResponse struct {
+	// NotModified is set when the origin confirmed the previous response;
+	// Data is empty then
+	NotModified  bool
+	Data         []byte
+	ETag         string
+	LastModified string
+}
+
+// FeedFetcher downloads external iCalendar feeds
+type FeedFetcher interface {
+	Fetch(ctx context.Context, req FeedRequest) (*FeedResponse, error)
+}
+
+// FeedObject is one calendar object of a feed: the components sharing a
+// UID, with the time zones they refer to
+type FeedObject struct {
+	UID           string
+	ComponentType string
+	Data          *ical.Calendar
+}
+
+// SplitFeed splits a feed into one object per UID, as CalDAV stores them
+// (RFC 4791 §4.1). Components without a UID are identified by a hash of
+// their content, so they keep their identity while they do not change;
+// components without a DTSTAMP get their LAST-MODIFIED or CREATED time, or
+// the Unix epoch, so that the objects stay the same from fetch to fetch.
+func SplitFeed(feed *ical.Calendar) []FeedObject {
+	timezones := make(map[string]*ical.Component)
+	for _, comp := range feed.Children {
+		if comp.Name == ical.CompTimezone {
+			if p := comp.Props.Get(ical.PropTimezoneID); p != nil {
+				timezones[p.Value] = comp
+			}
+		}
+	}
+
+	var order []string
+	objects := make(map[string]*FeedObject)
+	for _, comp := range feed.Children {
+		switch comp.Name {
+		case ical.CompEvent, ical.CompToDo, ical.CompJournal:
+		default:
+			continue
+		}
+		uid := ""
+		if p := comp.Props.Get(ical.PropUID); p != nil {
+			uid = strings.TrimSpace(p.Value)
+		}
+		if uid == "" {
+			uid = componentHash(comp)
+			comp.Props.SetText(ical.PropUID, uid)
+		}
+		if comp.Props.Get(ical.PropDateTimeStamp) == nil {
+			stamp := time.Unix(0, 0)
+			for _, name := range []string{ical.PropLastModified, ical.PropCreated} {
+				if p := comp.Props.Get(name); p != nil {
+					if t, err := p.DateTime(time.UTC); err == nil {
+						stamp = t
+						break
+					}
+				}
+			}
+			comp.Props.SetDateTime(ical.PropDateTimeStamp, stamp.UTC())
+		}
+		obj, ok := objects[uid]
+		if !ok {
+			obj = &FeedObject{UID: uid, ComponentType: comp.Name, Data: ical.NewCalendar()}
+			obj.Data.Props.SetText(ical.PropVersion, "2.0")
+			if p := feed.Props.Get(ical.PropProductID); p != nil {
+				obj.Data.Props.SetText(ical.PropProductID, p.Value)
+			} else {
+				obj.Data.Props.SetText(ical.PropProductID, "-//CalDAV Server//EN")
+			}
+			objects[uid] = obj
+			order = append(order, uid)
+		}
+		obj.Data.Children = append(obj.Data.Children, comp)
+	}
+
+	result := make([]FeedObject, 0, len(order))
+	for _, uid := range order {
+		obj := objects[uid]
+		var tzids []string
+		for _, comp := range obj.Data.Children {
+			tzids = append(tzids, referencedTimezones(comp)...)
+		}
+		sort.Strings(tzids)
+		var zones []*ical.Component
+		for i, id := range tzids {
+			if tz, ok := timezones[id]; ok && (i == 0 || tzids[i-1] != id) {
+				zones = append(zones, tz)
+			}
+		}
+		obj.Data.Children = append(zones, obj.Data.Children...)
+		result = append(result, *obj)
+	}
+	return result
+}
+
+// referencedTimezones returns the TZID parameters of a component and its
+// subcomponents
+func referencedTimezones(comp *ical.Component) []string {
+	var ids []string
+	for _, props := range comp.Props {
+		for _, p := range props {
+			if id := p.Params.Get(ical.ParamTimezoneID); id != "" {
+				ids = append(ids, id)
+			}
+		}
+	}
+	for _, child := range comp.Children {
+		ids = append(ids, referencedTimezones(child)...)
+	}
+	return ids
+}
+
+// componentHash identifies a component by its properties
+func componentHash(comp *ical.Component) string {
+	names := make([]string, 0, len(comp.Props))
+	for name := range comp.Props {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	h := sha256.New()
+	for _, name := range names {
+		for _, p := range comp.Props[name] {
+			h.Write([]byte(name + ":" + p.Value + "\n"))
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))[:32] + "@subscription"
+}
@@ -0,0 +1,98 @@
+package calendar
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-ical"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeSubscriptionURL(t *testing.T) {
+	for raw, want := range map[string]string{
+		"webcal://example.com/cal.ics":       "http://example.com/cal.ics",
+		"webcals://example.com/cal.ics":      "https://example.com/cal.ics",
+		" https://example.com/cal.ics#top ":  "https://example.com/cal.ics",
+		"HTTP://example.com/cal.ics?key=abc": "http://example.com/cal.ics?key=abc",
+	} {
+		got, err := NormalizeSubscriptionURL(raw)
+		require.NoError(t, err, raw)
+		assert.Equal(t, want, got)
+	}
+	for _, raw := range []string{"", "example.com/cal.ics", "ftp://example.com/cal.ics", "file:///etc/passwd"} {
+		_, err := NormalizeSubscriptionURL(raw)
+		assert.ErrorIs(t, err, ErrInvalidSubscriptionURL, raw)
+	}
+}
+
+func TestSubscriptionBackoff(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := &Subscription{RefreshInterval: time.Hour}
+
+	s.Failed(now, errors.New("feed returned HTTP 500"))
+	assert.Equal(t, now.Add(time.Hour), s.NextFetchAt)
+	s.Failed(now, errors.New("feed returned HTTP 500"))
+	assert.Equal(t, now.Add(2*time.Hour), s.NextFetchAt)
+	for i := 0; i < 10; i++ {
+		s.Failed(now, errors.New("feed returned HTTP 500"))
+	}
+	assert.Equal(t, now.Add(MaxSubscriptionBackoff), s.NextFetchAt)
+	assert.Equal(t, 12, s.FailureCount)
+	assert.Equal(t, "feed returned HTTP 500", s.LastError)
+	assert.Nil(t, s.LastSuccessAt)
+
+	s.Succeeded(now)
+	assert.Equal(t, now.Add(time.Hour), s.NextFetchAt)
+	assert.Zero(t, s.FailureCount)
+	assert.Empty(t, s.LastError)
+	require.NotNil(t, s.LastSuccessAt)
+}
+
+func TestSplitFeed(t *testing.T) {
+	feed, err := ical.NewDecoder(strings.NewReader("BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//Feed//EN\r\n" +
+		"BEGIN:VTIMEZONE\r\nTZID:Europe/Berlin\r\nBEGIN:STANDARD\r\nDTSTART:19701025T030000\r\n" +
+		"TZOFFSETFROM:+0200\r\nTZOFFSETTO:+0100\r\nEND:STANDARD\r\nEND:VTIMEZONE\r\n" +
+		"BEGIN:VEVENT\r\nUID:series\r\nDTSTAMP:20240101T000000Z\r\nDTSTART;TZID=Europe/Berlin:20240101T090000\r\n" +
+		"RRULE:FREQ=DAILY\r\nSUMMARY:Standup\r\nEND:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\nUID:series\r\nDTSTAMP:20240101T000000Z\r\nRECURRENCE-ID;TZID=Europe/Berlin:20240102T090000\r\n" +
+		"DTSTART;TZID=Europe/Berlin:20240102T100000\r\nSUMMARY:Standup\r\nEND:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\nDTSTART:20240105T120000Z\r\nLAST-MODIFIED:20231201T080000Z\r\nSUMMARY:Lunch\r\nEND:VEVENT\r\n" +
+		"BEGIN:VTODO\r\nUID:todo\r\nDTSTAMP:20240101T000000Z\r\nSUMMARY:Prepare\r\nEND:VTODO\r\n" +
+		"END:VCALENDAR\r\n")).Decode()
+	require.NoError(t, err)
+
+	objects := SplitFeed(feed)
+	require.Len(t, objects, 3)
+
+	series := objects[0]
+	assert.Equal(t, "series", series.UID)
+	assert.Equal(t, ical.CompEvent, series.ComponentType)
+	require.Len(t, series.Data.Children, 3)
+	assert.Equal(t, ical.CompTimezone, series.Data.Children[0].Name)
+	assert.Equal(t, "-//Feed//EN", series.Data.Props.Get(ical.PropProductID).Value)
+
+	lunch := objects[1]
+	assert.True(t, strings.HasSuffix(lunch.UID, "@subscription"))
+	require.Len(t, lunch.Data.Children, 1)
+	stamp, err := lunch.Data.Children[0].Props.DateTime(ical.PropDateTimeStamp, time.UTC)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2023, 12, 1, 8, 0, 0, 0, time.UTC), stamp)
+
+	assert.Equal(t, "todo", objects[2].UID)
+	assert.Equal(t, ical.CompToDo, objects[2].ComponentType)
+
+	for _, obj := range objects {
+		var b strings.Builder
+		assert.NoError(t, ical.NewEncoder(&b).Encode(obj.Data), obj.UID)
+	}
+
+	// Components without a UID keep their identity from fetch to fetch
+	again, err := ical.NewDecoder(strings.NewReader("BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//Feed//EN\r\n" +
+		"BEGIN:VEVENT\r\nDTSTART:20240105T120000Z\r\nLAST-MODIFIED:20231201T080000Z\r\nSUMMARY:Lunch\r\nEND:VEVENT\r\n" +
+		"END:VCALENDAR\r\n")).Decode()
+	require.NoError(t, err)
+	assert.Equal(t, lunch.UID, SplitFeed(again)[0].UID)
+}
@@ -8,6 +8,8 @@ import (
 
 var hexColorRegex = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
 
+var reminderTimeRegex = regexp.MustCompile(`^([01][0-9]|2[0-3]):[0-5][0-9]$`)
+
 // ValidateHexColor validates a hex color string
 func ValidateHexColor(color string) error {
 	if color == "" {
@@ -49,3 +51,15 @@ func ValidateDescription(description string) error {
 	}
 	return nil
 }
+
+// ValidateReminder validates the reminder of the birthday calendar: the days
+// before the date and the time of day (HH:MM)
+func ValidateReminder(daysBefore int, timeOfDay string) error {
+	if daysBefore < 0 || daysBefore > 30 {
+		return fmt.Errorf("reminder days must be between 0 and 30")
+	}
+	if !reminderTimeRegex.MatchString(timeOfDay) {
+		return fmt.Errorf("invalid reminder time format, expected HH:MM")
+	}
+	return nil
+}
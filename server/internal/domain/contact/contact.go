@@ -11,6 +11,7 @@ type Contact struct {
 	AddressBookID string `json:"addressbook_id"` // UUID of the AddressBook
 	UID           string `json:"uid"`            // vCard UID
 	Etag          string `json:"etag,omitempty"`
+	Version       string `json:"version,omitempty"` // vCard version, "3.0" or "4.0"
 
 	// Name components
 	Prefix        string `json:"prefix,omitempty"`
@@ -38,6 +39,11 @@ type Contact struct {
 	PhotoType string `json:"-"`                   // e.g. "JPEG", "PNG"
 	PhotoURL  string `json:"photo_url,omitempty"` // Constructed URL for the photo
 
+	// Groups
+	Kind       string   `json:"kind,omitempty"`        // "group" for contact groups, empty for individuals
+	GroupStyle string   `json:"group_style,omitempty"` // "vcard4" or "apple", groups only
+	Members    []string `json:"members,omitempty"`     // vCard UIDs of the group members
+
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
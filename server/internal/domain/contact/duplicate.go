@@ -0,0 +1,25 @@
+package contact
+
+// Reasons a pair of contacts was considered a duplicate
+const (
+	MatchEmail        = "email"
+	MatchPhone        = "phone"
+	MatchName         = "name"
+	MatchOrganization = "organization"
+)
+
+// DuplicateCluster is a set of contacts that probably describe the same
+// person. Contacts may come from different address books of the user.
+type DuplicateCluster struct {
+	Score    float64          `json:"score"` // Highest pair score in the cluster, 0..1
+	Contacts []*Contact       `json:"contacts"`
+	Matches  []DuplicateMatch `json:"matches"`
+}
+
+// DuplicateMatch is a scored pair of contacts within a cluster
+type DuplicateMatch struct {
+	ContactA string   `json:"contact_a"` // UUID of the first AddressObject
+	ContactB string   `json:"contact_b"` // UUID of the second AddressObject
+	Score    float64  `json:"score"`
+	Reasons  []string `json:"reasons"` // MatchEmail, MatchPhone, MatchName, MatchOrganization
+}
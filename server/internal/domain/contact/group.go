@@ -0,0 +1,24 @@
+package contact
+
+import "time"
+
+// Group represents a contact group (distribution list) stored as a group
+// vCard in an address book
+type Group struct {
+	ID            string        `json:"id"`             // UUID of the AddressObject
+	AddressBookID string        `json:"addressbook_id"` // ID of the AddressBook
+	UID           string        `json:"uid"`            // vCard UID
+	Etag          string        `json:"etag,omitempty"`
+	Name          string        `json:"name"`
+	Style         string        `json:"style"` // "vcard4" (KIND:group) or "apple" (X-ADDRESSBOOKSERVER-KIND)
+	Members       []GroupMember `json:"members"`
+	CreatedAt     time.Time     `json:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at"`
+}
+
+// GroupMember is a member reference of a group
+type GroupMember struct {
+	ID            string `json:"id,omitempty"` // UUID of the member's AddressObject; empty if it is not in the address book
+	UID           string `json:"uid"`          // vCard UID of the member
+	FormattedName string `json:"formatted_name,omitempty"`
+}
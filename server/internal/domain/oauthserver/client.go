@@ -0,0 +1,50 @@
+package oauthserver
+
+import (
+	"strings"
+	"time"
+)
+
+// Client is a third-party application registered to access user data
+// through the built-in authorization server
+type Client struct {
+	ID           uint   `gorm:"primaryKey"`
+	ClientID     string `gorm:"uniqueIndex;size:64;not null"`
+	UserID       uint   `gorm:"index;not null"` // User who registered the client
+	Name         string `gorm:"size:100;not null"`
+	SecretHash   string `gorm:"size:64"`            // Empty for public clients (native apps, SPAs)
+	RedirectURIs string `gorm:"size:2000;not null"` // Space-separated, matched exactly
+	Scopes       string `gorm:"size:500;not null"`  // Scopes the client may request
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// TableName returns the table name for the Client model
+func (Client) TableName() string {
+	return "oauth_clients"
+}
+
+// IsConfidential reports whether the client authenticates with a secret
+func (c *Client) IsConfidential() bool {
+	return c.SecretHash != ""
+}
+
+// RedirectURIList returns the registered redirect URIs
+func (c *Client) RedirectURIList() []string {
+	return strings.Fields(c.RedirectURIs)
+}
+
+// AllowsRedirectURI reports whether uri exactly matches a registered redirect URI
+func (c *Client) AllowsRedirectURI(uri string) bool {
+	for _, registered := range c.RedirectURIList() {
+		if registered == uri {
+			return true
+		}
+	}
+	return false
+}
+
+// ScopeList returns the scopes the client may request
+func (c *Client) ScopeList() []string {
+	return ParseScope(c.Scopes)
+}
@@ -0,0 +1,96 @@
+package oauthserver
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// Prefixes of issued token strings. They let the authentication middleware
+// tell OAuth access tokens apart from the web UI's JWTs without a lookup.
+const (
+	AccessTokenPrefix  = "cco_at_"
+	RefreshTokenPrefix = "cco_rt_"
+)
+
+// HashToken returns the SHA-256 hex digest under which codes, tokens and
+// client secrets are stored
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// AuthorizationCode is a short-lived, single-use code issued after the user
+// approves a client. Only its hash is stored.
+type AuthorizationCode struct {
+	ID                  uint   `gorm:"primaryKey"`
+	CodeHash            string `gorm:"uniqueIndex;size:64;not null"`
+	ClientID            string `gorm:"index;size:64;not null"`
+	UserID              uint   `gorm:"index;not null"`
+	RedirectURI         string `gorm:"size:500;not null"`
+	Scope               string `gorm:"size:500;not null"`
+	CodeChallenge       string `gorm:"size:128"`
+	CodeChallengeMethod string `gorm:"size:10"`
+	Nonce               string `gorm:"size:255"`
+	ExpiresAt           time.Time
+	UsedAt              *time.Time
+	CreatedAt           time.Time
+}
+
+// TableName returns the table name for the AuthorizationCode model
+func (AuthorizationCode) TableName() string {
+	return "oauth_authorization_codes"
+}
+
+// Token is an access token, optionally paired with a refresh token, issued
+// to a client. Only hashes of the token strings are stored.
+type Token struct {
+	ID                  uint    `gorm:"primaryKey"`
+	ClientID            string  `gorm:"index;size:64;not null"`
+	UserID              uint    `gorm:"index;not null"`
+	Scope               string  `gorm:"size:500;not null"`
+	AccessTokenHash     string  `gorm:"uniqueIndex;size:64;not null"`
+	RefreshTokenHash    *string `gorm:"uniqueIndex;size:64"`
+	AuthorizationCodeID uint    `gorm:"index"`
+	AccessExpiresAt     time.Time
+	RefreshExpiresAt    *time.Time
+	RevokedAt           *time.Time `gorm:"index"`
+	CreatedAt           time.Time
+}
+
+// TableName returns the table name for the Token model
+func (Token) TableName() string {
+	return "oauth_tokens"
+}
+
+// ScopeList returns the granted scopes
+func (t *Token) ScopeList() []string {
+	return ParseScope(t.Scope)
+}
+
+// AccessActive reports whether the access token can be used
+func (t *Token) AccessActive(now time.Time) bool {
+	return t.RevokedAt == nil && t.AccessExpiresAt.After(now)
+}
+
+// RefreshActive reports whether the refresh token can be used
+func (t *Token) RefreshActive(now time.Time) bool {
+	return t.RevokedAt == nil && t.RefreshTokenHash != nil &&
+		t.RefreshExpiresAt != nil && t.RefreshExpiresAt.After(now)
+}
+
+// Consent records the scopes a user has approved for a client, so the
+// consent screen can be skipped on later authorizations
+type Consent struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"uniqueIndex:idx_oauth_consent;not null"`
+	ClientID  string `gorm:"uniqueIndex:idx_oauth_consent;size:64;not null"`
+	Scope     string `gorm:"size:500;not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// TableName returns the table name for the Consent model
+func (Consent) TableName() string {
+	return "oauth_consents"
+}
@@ -0,0 +1,44 @@
+package oauthserver
+
+import "context"
+
+// ClientRepository defines the interface for OAuth client persistence
+type ClientRepository interface {
+	Create(ctx context.Context, client *Client) error
+	GetByClientID(ctx context.Context, clientID string) (*Client, error)
+	ListByUserID(ctx context.Context, userID uint) ([]Client, error)
+	Delete(ctx context.Context, id uint) error
+}
+
+// AuthorizationCodeRepository defines the interface for authorization code persistence
+type AuthorizationCodeRepository interface {
+	Create(ctx context.Context, code *AuthorizationCode) error
+	GetByHash(ctx context.Context, hash string) (*AuthorizationCode, error)
+	// MarkUsed redeems the code unless it was already redeemed. It returns
+	// false when the code had been used before.
+	MarkUsed(ctx context.Context, id uint) (bool, error)
+}
+
+// TokenRepository defines the interface for issued token persistence
+type TokenRepository interface {
+	Create(ctx context.Context, token *Token) error
+	GetByAccessHash(ctx context.Context, hash string) (*Token, error)
+	GetByRefreshHash(ctx context.Context, hash string) (*Token, error)
+	Revoke(ctx context.Context, id uint) error
+	RevokeByAuthorizationCode(ctx context.Context, codeID uint) error
+	RevokeByClient(ctx context.Context, clientID string) error
+}
+
+// ConsentRepository defines the interface for user consent persistence
+type ConsentRepository interface {
+	Get(ctx context.Context, userID uint, clientID string) (*Consent, error)
+	Save(ctx context.Context, consent *Consent) error
+	DeleteByClient(ctx context.Context, clientID string) error
+}
+
+// IDTokenSigner signs OpenID Connect ID tokens and publishes the public keys
+type IDTokenSigner interface {
+	SignIDToken(claims map[string]interface{}) (string, error)
+	// JWKS returns the JSON Web Key Set used to verify ID tokens
+	JWKS() map[string]interface{}
+}
@@ -0,0 +1,95 @@
+package oauthserver
+
+import (
+	"sort"
+	"strings"
+)
+
+// Scopes that can be granted to third-party clients
+const (
+	ScopeOpenID         = "openid"
+	ScopeProfile        = "profile"
+	ScopeEmail          = "email"
+	ScopeCalendarsRead  = "calendars:read"
+	ScopeCalendarsWrite = "calendars:write"
+	ScopeContactsRead   = "contacts:read"
+	ScopeContactsWrite  = "contacts:write"
+)
+
+// scopeDescriptions are shown on the consent screen
+var scopeDescriptions = map[string]string{
+	ScopeOpenID:         "Verify your identity",
+	ScopeProfile:        "View your name",
+	ScopeEmail:          "View your email address",
+	ScopeCalendarsRead:  "View your calendars and events",
+	ScopeCalendarsWrite: "Create, change and delete your calendars and events",
+	ScopeContactsRead:   "View your address books and contacts",
+	ScopeContactsWrite:  "Create, change and delete your address books and contacts",
+}
+
+// writeImplies maps a write scope to the read scope it includes
+var writeImplies = map[string]string{
+	ScopeCalendarsWrite: ScopeCalendarsRead,
+	ScopeContactsWrite:  ScopeContactsRead,
+}
+
+// SupportedScopes returns all scopes in a stable order
+func SupportedScopes() []string {
+	scopes := make([]string, 0, len(scopeDescriptions))
+	for s := range scopeDescriptions {
+		scopes = append(scopes, s)
+	}
+	sort.Strings(scopes)
+	return scopes
+}
+
+// IsSupported reports whether the scope is known
+func IsSupported(scope string) bool {
+	_, ok := scopeDescriptions[scope]
+	return ok
+}
+
+// Describe returns the human readable description of a scope
+func Describe(scope string) string {
+	return scopeDescriptions[scope]
+}
+
+// ParseScope splits a space-delimited scope parameter (RFC 6749 §3.3),
+// dropping duplicates
+func ParseScope(s string) []string {
+	seen := make(map[string]bool)
+	var scopes []string
+	for _, scope := range strings.Fields(s) {
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
+
+// FormatScope joins scopes into a space-delimited scope parameter
+func FormatScope(scopes []string) string {
+	return strings.Join(scopes, " ")
+}
+
+// Allows reports whether the granted scopes cover the required scope. A
+// write scope includes the matching read scope.
+func Allows(granted []string, required string) bool {
+	for _, g := range granted {
+		if g == required || writeImplies[g] == required {
+			return true
+		}
+	}
+	return false
+}
+
+// Covers reports whether the granted scopes cover every requested scope
+func Covers(granted, requested []string) bool {
+	for _, r := range requested {
+		if !Allows(granted, r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,32 @@
+package oauthserver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllows(t *testing.T) {
+	granted := ParseScope("openid calendars:write contacts:read")
+
+	assert.True(t, Allows(granted, ScopeOpenID))
+	assert.True(t, Allows(granted, ScopeCalendarsWrite))
+	assert.True(t, Allows(granted, ScopeCalendarsRead), "write implies read")
+	assert.True(t, Allows(granted, ScopeContactsRead))
+	assert.False(t, Allows(granted, ScopeContactsWrite), "read does not imply write")
+	assert.False(t, Allows(granted, ScopeEmail))
+}
+
+func TestCovers(t *testing.T) {
+	granted := ParseScope("profile contacts:write")
+
+	assert.True(t, Covers(granted, ParseScope("contacts:read profile")))
+	assert.False(t, Covers(granted, ParseScope("contacts:read email")))
+	assert.True(t, Covers(granted, nil))
+}
+
+func TestParseScope(t *testing.T) {
+	assert.Equal(t, []string{"openid", "profile"}, ParseScope("  openid profile openid "))
+	assert.Empty(t, ParseScope(""))
+	assert.Equal(t, "openid profile", FormatScope(ParseScope("openid  profile")))
+}
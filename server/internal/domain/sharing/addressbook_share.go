@@ -8,18 +8,24 @@ import (
 	"gorm.io/gorm"
 )
 
-// AddressBookShare represents an address book shared with another user
+// AddressBookShare represents an address book shared with another user or
+// with a group; exactly one of SharedWithID and SharedWithGroupID is set.
+// Shares with a user are invitations that grant access once accepted.
 type AddressBookShare struct {
-	ID            uint                    `gorm:"primaryKey" json:"id"`
-	UUID          string                  `gorm:"uniqueIndex;size:36;not null" json:"uuid"`
-	AddressBookID uint                    `gorm:"index;not null;uniqueIndex:idx_addressbook_share_user" json:"addressbook_id"`
-	SharedWithID  uint                    `gorm:"index;not null;uniqueIndex:idx_addressbook_share_user" json:"shared_with_id"`
-	Permission    string                  `gorm:"size:20;not null" json:"permission"` // "read" or "read-write"
-	CreatedAt     time.Time               `json:"created_at"`
-	UpdatedAt     time.Time               `json:"updated_at"`
-	DeletedAt     gorm.DeletedAt          `gorm:"index" json:"-"`
-	AddressBook   addressbook.AddressBook `gorm:"foreignKey:AddressBookID" json:"-"`
-	SharedWith    user.User               `gorm:"foreignKey:SharedWithID" json:"shared_with"`
+	ID                uint                    `gorm:"primaryKey" json:"id"`
+	UUID              string                  `gorm:"uniqueIndex;size:36;not null" json:"uuid"`
+	AddressBookID     uint                    `gorm:"index;not null;uniqueIndex:idx_addressbook_share_user;uniqueIndex:idx_addressbook_share_group" json:"addressbook_id"`
+	SharedWithID      *uint                   `gorm:"index;uniqueIndex:idx_addressbook_share_user" json:"shared_with_id,omitempty"`
+	SharedWithGroupID *uint                   `gorm:"index;uniqueIndex:idx_addressbook_share_group" json:"shared_with_group_id,omitempty"`
+	Permission        string                  `gorm:"size:20;not null" json:"permission"`                    // "read" or "read-write"
+	Status            string                  `gorm:"size:20;not null;default:accepted;index" json:"status"` // ShareStatusPending, ShareStatusAccepted or ShareStatusDeclined
+	CreatedAt         time.Time               `json:"created_at"`
+	UpdatedAt         time.Time               `json:"updated_at"`
+	DeletedAt         gorm.DeletedAt          `gorm:"index" json:"-"`
+	AddressBook       addressbook.AddressBook `gorm:"foreignKey:AddressBookID" json:"-"`
+	SharedWith        *user.User              `gorm:"foreignKey:SharedWithID" json:"shared_with,omitempty"`
+	SharedWithGroup   *user.Group             `gorm:"foreignKey:SharedWithGroupID" json:"shared_with_group,omitempty"`
+	Mount             *SharedAddressBook      `gorm:"-" json:"-"` // set by FindAddressBooksSharedWithUser
 }
 
 // TableName overrides the table name
@@ -0,0 +1,45 @@
+package sharing
+
+import (
+	"time"
+
+	"github.com/jherrma/caldav-server/internal/domain/user"
+)
+
+// Calendar proxy levels, named after the CalendarServer proxy groups
+// calendar-proxy-read and calendar-proxy-write
+const (
+	ProxyRead  = "read"
+	ProxyWrite = "write"
+)
+
+// ValidProxyLevel reports whether level is a calendar proxy level
+func ValidProxyLevel(level string) bool {
+	return level == ProxyRead || level == ProxyWrite
+}
+
+// CalendarProxy lets a user act for another user in their calendar home.
+// Read proxies see all of the user's calendars; write proxies may also
+// change them and schedule on the user's behalf.
+type CalendarProxy struct {
+	ID        uint   `gorm:"primaryKey"`
+	UUID      string `gorm:"uniqueIndex;size:36;not null"`
+	UserID    uint   `gorm:"not null;uniqueIndex:idx_calendar_proxy"`       // the user who grants the rights
+	ProxyID   uint   `gorm:"not null;index;uniqueIndex:idx_calendar_proxy"` // the user who acts for them
+	Level     string `gorm:"size:20;not null"`                              // ProxyRead or ProxyWrite
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	User  user.User `gorm:"foreignKey:UserID"`
+	Proxy user.User `gorm:"foreignKey:ProxyID"`
+}
+
+// TableName specifies the table name for CalendarProxy
+func (CalendarProxy) TableName() string {
+	return "calendar_proxies"
+}
+
+// CanWrite reports whether the proxy may change the user's calendars
+func (p *CalendarProxy) CanWrite() bool {
+	return p.Level == ProxyWrite
+}
@@ -8,18 +8,24 @@ import (
 	"gorm.io/gorm"
 )
 
-// CalendarShare represents a calendar shared with another user
+// CalendarShare represents a calendar shared with another user or with a
+// group; exactly one of SharedWithID and SharedWithGroupID is set. Shares
+// with a user are invitations that grant access once accepted.
 type CalendarShare struct {
-	ID           uint              `gorm:"primaryKey" json:"id"`
-	UUID         string            `gorm:"uniqueIndex;size:36;not null" json:"uuid"`
-	CalendarID   uint              `gorm:"index;not null;uniqueIndex:idx_calendar_share_user" json:"calendar_id"`
-	SharedWithID uint              `gorm:"index;not null;uniqueIndex:idx_calendar_share_user" json:"shared_with_id"`
-	Permission   string            `gorm:"size:20;not null" json:"permission"` // "read" or "read-write"
-	CreatedAt    time.Time         `json:"created_at"`
-	UpdatedAt    time.Time         `json:"updated_at"`
-	DeletedAt    gorm.DeletedAt    `gorm:"index" json:"-"`
-	Calendar     calendar.Calendar `gorm:"foreignKey:CalendarID" json:"-"`
-	SharedWith   user.User         `gorm:"foreignKey:SharedWithID" json:"shared_with"`
+	ID                uint              `gorm:"primaryKey" json:"id"`
+	UUID              string            `gorm:"uniqueIndex;size:36;not null" json:"uuid"`
+	CalendarID        uint              `gorm:"index;not null;uniqueIndex:idx_calendar_share_user;uniqueIndex:idx_calendar_share_group" json:"calendar_id"`
+	SharedWithID      *uint             `gorm:"index;uniqueIndex:idx_calendar_share_user" json:"shared_with_id,omitempty"`
+	SharedWithGroupID *uint             `gorm:"index;uniqueIndex:idx_calendar_share_group" json:"shared_with_group_id,omitempty"`
+	Permission        string            `gorm:"size:20;not null" json:"permission"`                    // "read" or "read-write"
+	Status            string            `gorm:"size:20;not null;default:accepted;index" json:"status"` // ShareStatusPending, ShareStatusAccepted or ShareStatusDeclined
+	CreatedAt         time.Time         `json:"created_at"`
+	UpdatedAt         time.Time         `json:"updated_at"`
+	DeletedAt         gorm.DeletedAt    `gorm:"index" json:"-"`
+	Calendar          calendar.Calendar `gorm:"foreignKey:CalendarID" json:"-"`
+	SharedWith        *user.User        `gorm:"foreignKey:SharedWithID" json:"shared_with,omitempty"`
+	SharedWithGroup   *user.Group       `gorm:"foreignKey:SharedWithGroupID" json:"shared_with_group,omitempty"`
+	Mount             *SharedCalendar   `gorm:"-" json:"-"` // set by FindCalendarsSharedWithUser
 }
 
 // TableName overrides the table name used by User to `calendar_shares`
@@ -0,0 +1,51 @@
+package sharing
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jherrma/caldav-server/internal/domain/user"
+)
+
+// Notification types, named after the calendarserver.org notification
+// elements they are served as
+const (
+	// NotificationInvite tells a sharee about an invitation or its revocation
+	NotificationInvite = "invite-notification"
+	// NotificationInviteReply tells a sharer that an invitation was answered
+	NotificationInviteReply = "invite-reply"
+)
+
+// NotificationStatusRevoked is the status of an invite notification whose
+// share was revoked; the other statuses are the ShareStatus* values
+const NotificationStatusRevoked = "revoked"
+
+// Notification is an entry of a user's calendar sharing notification
+// collection (calendarserver.org sharing). The calendar's name and path are
+// copied so notifications about revoked shares stay readable.
+type Notification struct {
+	ID           uint   `gorm:"primaryKey"`
+	UUID         string `gorm:"uniqueIndex;size:36;not null"`
+	UserID       uint   `gorm:"index;not null"` // Recipient
+	Type         string `gorm:"size:30;not null"`
+	ShareUUID    string `gorm:"index;size:36;not null"`
+	FromUserID   uint   `gorm:"not null"` // Sharer of invites, sharee of replies
+	CalendarName string `gorm:"size:255"`
+	CalendarPath string `gorm:"size:255;not null"` // DAV path of the calendar in its owner's calendar home
+	Permission   string `gorm:"size:20"`
+	Status       string `gorm:"size:20;not null"`
+	Summary      string `gorm:"size:500"` // Message of the sharer
+	CreatedAt    time.Time
+	FromUser     user.User `gorm:"foreignKey:FromUserID"`
+}
+
+// TableName returns the table name for the Notification model
+func (Notification) TableName() string {
+	return "share_notifications"
+}
+
+// ETag returns the entity tag of the notification resource; notifications
+// are never modified
+func (n *Notification) ETag() string {
+	return fmt.Sprintf("%d-%d", n.ID, n.CreatedAt.Unix())
+}
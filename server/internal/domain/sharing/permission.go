@@ -0,0 +1,71 @@
+package sharing
+
+// Share permissions
+const (
+	PermissionRead      = "read"
+	PermissionReadWrite = "read-write"
+)
+
+// ValidPermission reports whether p is a share permission
+func ValidPermission(p string) bool {
+	return p == PermissionRead || p == PermissionReadWrite
+}
+
+// HigherPermission returns the stronger of two share permissions
+func HigherPermission(a, b string) string {
+	if a == PermissionReadWrite || b == PermissionReadWrite {
+		return PermissionReadWrite
+	}
+	return PermissionRead
+}
+
+// EffectiveCalendarShares reduces the direct and group shares that reach
+// one user to a single share per calendar carrying the highest permission.
+// A direct share represents the calendar when there is one; the order of
+// first appearance is kept.
+func EffectiveCalendarShares(shares []CalendarShare) []CalendarShare {
+	index := make(map[uint]int)
+	var res []CalendarShare
+	for _, s := range shares {
+		i, seen := index[s.CalendarID]
+		if !seen {
+			index[s.CalendarID] = len(res)
+			res = append(res, s)
+			continue
+		}
+		perm := HigherPermission(res[i].Permission, s.Permission)
+		if res[i].SharedWithID == nil && s.SharedWithID != nil {
+			res[i] = s
+		}
+		res[i].Permission = perm
+	}
+	return res
+}
+
+// EffectiveAddressBookShares is EffectiveCalendarShares for address books
+func EffectiveAddressBookShares(shares []AddressBookShare) []AddressBookShare {
+	index := make(map[uint]int)
+	var res []AddressBookShare
+	for _, s := range shares {
+		i, seen := index[s.AddressBookID]
+		if !seen {
+			index[s.AddressBookID] = len(res)
+			res = append(res, s)
+			continue
+		}
+		perm := HigherPermission(res[i].Permission, s.Permission)
+		if res[i].SharedWithID == nil && s.SharedWithID != nil {
+			res[i] = s
+		}
+		res[i].Permission = perm
+	}
+	return res
+}
+
+// Share statuses. Shares with a user start as pending invitations; shares
+// with a group are accepted when created.
+const (
+	ShareStatusPending  = "pending"
+	ShareStatusAccepted = "accepted"
+	ShareStatusDeclined = "declined"
+)
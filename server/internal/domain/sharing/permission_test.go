@@ -0,0 +1,34 @@
+package sharing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEffectiveCalendarShares(t *testing.T) {
+	userID, groupID, otherGroupID := uint(7), uint(1), uint(2)
+	shares := []CalendarShare{
+		{ID: 1, CalendarID: 10, SharedWithGroupID: &groupID, Permission: PermissionReadWrite},
+		{ID: 2, CalendarID: 20, SharedWithGroupID: &groupID, Permission: PermissionRead},
+		{ID: 3, CalendarID: 10, SharedWithID: &userID, Permission: PermissionRead},
+		{ID: 4, CalendarID: 20, SharedWithGroupID: &otherGroupID, Permission: PermissionRead},
+	}
+
+	effective := EffectiveCalendarShares(shares)
+	if assert.Len(t, effective, 2) {
+		// The direct share represents the calendar with the group's permission
+		assert.Equal(t, uint(3), effective[0].ID)
+		assert.Equal(t, PermissionReadWrite, effective[0].Permission)
+		assert.Equal(t, uint(2), effective[1].ID)
+		assert.Equal(t, PermissionRead, effective[1].Permission)
+	}
+	assert.Empty(t, EffectiveCalendarShares(nil))
+}
+
+func TestPermission(t *testing.T) {
+	assert.True(t, ValidPermission(PermissionRead))
+	assert.False(t, ValidPermission("write"))
+	assert.Equal(t, PermissionReadWrite, HigherPermission(PermissionRead, PermissionReadWrite))
+	assert.Equal(t, PermissionRead, HigherPermission(PermissionRead, PermissionRead))
+}
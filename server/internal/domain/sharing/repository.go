@@ -9,10 +9,23 @@ type CalendarShareRepository interface {
 	Create(ctx context.Context, share *CalendarShare) error
 	GetByUUID(ctx context.Context, uuid string) (*CalendarShare, error)
 	ListByCalendarID(ctx context.Context, calendarID uint) ([]CalendarShare, error)
+	// FindCalendarsSharedWithUser returns one share per calendar shared with
+	// the user directly or through a group, with the highest permission
+	// among them (see EffectiveCalendarShares). Direct shares count once
+	// accepted. The user's own calendars are left out. Mount holds where
+	// each calendar appears for the user; new ones are mounted on the way.
 	FindCalendarsSharedWithUser(ctx context.Context, userID uint) ([]CalendarShare, error)
+	// UpdateSharedCalendar saves the display properties a user chose for a
+	// calendar shared with them
+	UpdateSharedCalendar(ctx context.Context, sc *SharedCalendar) error
+	// ListPendingForUser returns the invitations the user has not answered,
+	// with the calendar and its owner loaded
+	ListPendingForUser(ctx context.Context, userID uint) ([]CalendarShare, error)
 	Update(ctx context.Context, share *CalendarShare) error
 	Revoke(ctx context.Context, id uint) error
+	// GetByCalendarAndUser returns the direct share of a calendar with a user
 	GetByCalendarAndUser(ctx context.Context, calendarID, userID uint) (*CalendarShare, error)
+	GetByCalendarAndGroup(ctx context.Context, calendarID, groupID uint) (*CalendarShare, error)
 }
 
 // AddressBookShareRepository defines the interface for address book share persistence
@@ -20,8 +33,44 @@ type AddressBookShareRepository interface {
 	Create(ctx context.Context, share *AddressBookShare) error
 	GetByUUID(ctx context.Context, uuid string) (*AddressBookShare, error)
 	ListByAddressBookID(ctx context.Context, addressBookID uint) ([]AddressBookShare, error)
+	// FindAddressBooksSharedWithUser returns one share per address book
+	// shared with the user directly or through a group, with the highest
+	// permission among them. Direct shares count once accepted. The user's
+	// own address books are left out.
 	FindAddressBooksSharedWithUser(ctx context.Context, userID uint) ([]AddressBookShare, error)
+	// ListPendingForUser returns the invitations the user has not answered,
+	// with the address book and its owner loaded
+	ListPendingForUser(ctx context.Context, userID uint) ([]AddressBookShare, error)
 	Update(ctx context.Context, share *AddressBookShare) error
 	Revoke(ctx context.Context, id uint) error
+	// GetByAddressBookAndUser returns the direct share of an address book
+	// with a user
 	GetByAddressBookAndUser(ctx context.Context, addressBookID, userID uint) (*AddressBookShare, error)
+	GetByAddressBookAndGroup(ctx context.Context, addressBookID, groupID uint) (*AddressBookShare, error)
+}
+
+// NotificationRepository defines the interface for sharing notification persistence
+type NotificationRepository interface {
+	Create(ctx context.Context, n *Notification) error
+	// ListByUser returns the notifications of a user, oldest first
+	ListByUser(ctx context.Context, userID uint) ([]Notification, error)
+	// GetByUUID returns a notification of the user, or nil if there is none
+	GetByUUID(ctx context.Context, userID uint, uuid string) (*Notification, error)
+	Delete(ctx context.Context, id uint) error
+	// DeleteByShare removes the notifications of a user about a share
+	DeleteByShare(ctx context.Context, userID uint, shareUUID string) error
+}
+
+// CalendarProxyRepository stores who may act for whom in calendars
+type CalendarProxyRepository interface {
+	// ListByUser returns the proxies of a user with the proxy loaded
+	ListByUser(ctx context.Context, userID uint) ([]CalendarProxy, error)
+	// ListForProxy returns the grants that let a user act for others, with
+	// the granting user loaded
+	ListForProxy(ctx context.Context, proxyID uint) ([]CalendarProxy, error)
+	// Get returns the grant of a user to a proxy, or nil if there is none
+	Get(ctx context.Context, userID, proxyID uint) (*CalendarProxy, error)
+	// Set creates the grant or changes its level
+	Set(ctx context.Context, proxy *CalendarProxy) error
+	Delete(ctx context.Context, id uint) error
 }
@@ -0,0 +1,20 @@
+package sharing
+
+import "time"
+
+// SharedAddressBook is where an address book shared with a user is mounted
+// in their address book home. Like SharedCalendar it outlives the share, so
+// the address book keeps its path while shares come and go.
+type SharedAddressBook struct {
+	ID            uint      `gorm:"primaryKey" json:"-"`
+	UserID        uint      `gorm:"not null;uniqueIndex:idx_shared_addressbook_addressbook;uniqueIndex:idx_shared_addressbook_path" json:"-"`
+	AddressBookID uint      `gorm:"not null;uniqueIndex:idx_shared_addressbook_addressbook" json:"-"`
+	Path          string    `gorm:"size:255;not null;uniqueIndex:idx_shared_addressbook_path" json:"path"` // URL path component in the user's address book home
+	CreatedAt     time.Time `json:"-"`
+	UpdatedAt     time.Time `json:"-"`
+}
+
+// TableName specifies the table name for SharedAddressBook
+func (SharedAddressBook) TableName() string {
+	return "shared_addressbooks"
+}
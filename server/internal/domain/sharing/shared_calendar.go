@@ -0,0 +1,52 @@
+package sharing
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jherrma/caldav-server/internal/domain/calendar"
+)
+
+// SharedCalendar is how a calendar shared with a user appears to them:
+// where it is mounted in their calendar home and the display properties
+// they chose. It outlives the share, so a calendar shared again returns to
+// the same place.
+type SharedCalendar struct {
+	ID          uint      `gorm:"primaryKey" json:"-"`
+	UserID      uint      `gorm:"not null;uniqueIndex:idx_shared_calendar_calendar;uniqueIndex:idx_shared_calendar_path" json:"-"`
+	CalendarID  uint      `gorm:"not null;uniqueIndex:idx_shared_calendar_calendar" json:"-"`
+	Path        string    `gorm:"size:255;not null;uniqueIndex:idx_shared_calendar_path" json:"path"` // URL path component in the user's calendar home
+	DisplayName string    `gorm:"size:255" json:"display_name"`                                       // empty keeps the owner's name
+	Color       string    `gorm:"size:7" json:"color"`                                                // empty keeps the owner's color
+	Hidden      bool      `gorm:"not null;default:false" json:"hidden"`
+	CreatedAt   time.Time `json:"-"`
+	UpdatedAt   time.Time `json:"-"`
+}
+
+// TableName specifies the table name for SharedCalendar
+func (SharedCalendar) TableName() string {
+	return "shared_calendars"
+}
+
+// Apply returns cal as the user sees it; the owner's calendar is not changed
+func (s *SharedCalendar) Apply(cal calendar.Calendar) calendar.Calendar {
+	cal.Path = s.Path
+	if s.DisplayName != "" {
+		cal.Name = s.DisplayName
+	}
+	if s.Color != "" {
+		cal.Color = s.Color
+	}
+	return cal
+}
+
+// MountPath picks the path of a calendar shared with a user: the owner's
+// username and the calendar path, numbered when taken already
+func MountPath(ownerUsername, calendarPath string, taken map[string]bool) string {
+	base := fmt.Sprintf("%s-%s", ownerUsername, calendarPath)
+	p := base
+	for i := 2; taken[p]; i++ {
+		p = fmt.Sprintf("%s-%d", base, i)
+	}
+	return p
+}
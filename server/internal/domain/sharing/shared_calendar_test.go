@@ -0,0 +1,28 @@
+package sharing
+
+import (
+	"testing"
+
+	"github.com/jherrma/caldav-server/internal/domain/calendar"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMountPath(t *testing.T) {
+	assert.Equal(t, "alice-default", MountPath("alice", "default", map[string]bool{"default": true}))
+	assert.Equal(t, "alice-default-2", MountPath("alice", "default", map[string]bool{"alice-default": true}))
+	assert.Equal(t, "alice-default-3", MountPath("alice", "default", map[string]bool{"alice-default": true, "alice-default-2": true}))
+}
+
+func TestSharedCalendarApply(t *testing.T) {
+	cal := calendar.Calendar{Path: "default", Name: "Work", Color: "#112233"}
+
+	view := (&SharedCalendar{Path: "alice-default"}).Apply(cal)
+	assert.Equal(t, "alice-default", view.Path)
+	assert.Equal(t, "Work", view.Name)
+	assert.Equal(t, "#112233", view.Color)
+
+	view = (&SharedCalendar{Path: "alice-default", DisplayName: "Alice's work", Color: "#ff0000"}).Apply(cal)
+	assert.Equal(t, "Alice's work", view.Name)
+	assert.Equal(t, "#ff0000", view.Color)
+	assert.Equal(t, "default", cal.Path)
+}
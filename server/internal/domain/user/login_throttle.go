@@ -0,0 +1,36 @@
+package user
+
+import "time"
+
+// Subjects whose failed authentication attempts are tracked
+const (
+	ThrottleSubjectAccount           = "account"
+	ThrottleSubjectCalDAVCredential  = "caldav_credential"
+	ThrottleSubjectCardDAVCredential = "carddav_credential"
+	ThrottleSubjectPublicFeed        = "public_feed" // Password of a public calendar feed
+)
+
+// LoginThrottle counts consecutive failed authentication attempts for one
+// account or DAV credential. While LockedUntil is in the future, attempts for
+// the subject are rejected without checking the password.
+type LoginThrottle struct {
+	ID            uint   `gorm:"primaryKey"`
+	SubjectType   string `gorm:"uniqueIndex:idx_login_throttle_subject;size:32;not null"`
+	SubjectID     uint   `gorm:"uniqueIndex:idx_login_throttle_subject;not null"`
+	UserID        uint   `gorm:"index;not null"` // Owner of the account or credential
+	FailureCount  int    `gorm:"not null;default:0"`
+	LastFailureAt *time.Time
+	LockedUntil   *time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// TableName returns the table name for the LoginThrottle model
+func (LoginThrottle) TableName() string {
+	return "login_throttles"
+}
+
+// IsLocked reports whether attempts are currently rejected
+func (t *LoginThrottle) IsLocked(now time.Time) bool {
+	return t.LockedUntil != nil && t.LockedUntil.After(now)
+}
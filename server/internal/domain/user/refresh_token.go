@@ -1,23 +1,41 @@
 package user
 
 import (
+	"errors"
 	"time"
 )
 
-// RefreshToken represents a long-lived token used to obtain new access tokens
+// ErrRefreshTokenReused is returned when a refresh token that has already been
+// rotated is presented again, which indicates the token was leaked.
+var ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+
+// RefreshToken represents a long-lived token used to obtain new access tokens.
+//
+// Every login starts a new token family (a session). Each refresh rotates the
+// token: the presented token is revoked and a successor with the same FamilyID
+// is issued, so the family always has at most one usable token.
 type RefreshToken struct {
-	ID        uint      `gorm:"primaryKey"`
-	UserID    uint      `gorm:"index;not null"`
-	TokenHash string    `gorm:"uniqueIndex;size:64;not null"`
-	ExpiresAt time.Time `gorm:"index;not null"`
-	UserAgent string    `gorm:"size:500"`
-	IP        string    `gorm:"size:45"`
-	CreatedAt time.Time
-	RevokedAt *time.Time `gorm:"index"`
-	User      User       `gorm:"foreignKey:UserID"`
+	ID              uint      `gorm:"primaryKey"`
+	UserID          uint      `gorm:"index;not null"`
+	FamilyID        string    `gorm:"index;size:36"`
+	TokenHash       string    `gorm:"uniqueIndex;size:64;not null"`
+	ExpiresAt       time.Time `gorm:"index;not null"`
+	DeviceName      string    `gorm:"size:100"`
+	UserAgent       string    `gorm:"size:500"`
+	IP              string    `gorm:"size:45"`
+	AuthenticatedAt time.Time // Time of the login that started the family
+	CreatedAt       time.Time
+	LastUsedAt      *time.Time
+	RevokedAt       *time.Time `gorm:"index"`
+	User            User       `gorm:"foreignKey:UserID"`
 }
 
 // TableName returns the table name for the RefreshToken model
 func (RefreshToken) TableName() string {
 	return "refresh_tokens"
 }
+
+// IsActive checks if the token is neither revoked nor expired
+func (t *RefreshToken) IsActive() bool {
+	return t.RevokedAt == nil && t.ExpiresAt.After(time.Now())
+}
@@ -16,18 +16,60 @@ type UserRepository interface {
 	Delete(ctx context.Context, userID uint) error
 	GetByOAuth(ctx context.Context, provider, providerID string) (*User, error)
 	Count(ctx context.Context) (int64, error)
+	// ListActive returns all active users ordered by ID
+	ListActive(ctx context.Context) ([]User, error)
 
 	CreateVerification(ctx context.Context, v *EmailVerification) error
 	GetVerificationByToken(ctx context.Context, token string) (*EmailVerification, error)
 	DeleteVerification(ctx context.Context, token string) error
 }
 
+// GroupRepository defines the interface for user group persistence. Groups
+// are returned with their owner and members (and the members' users) loaded.
+type GroupRepository interface {
+	Create(ctx context.Context, group *Group) error
+	GetByUUID(ctx context.Context, uuid string) (*Group, error)
+	// ListAll returns every group ordered by name
+	ListAll(ctx context.Context) ([]Group, error)
+	// ListForUser returns the groups the user may use: groups managed by
+	// administrators and the groups the user owns, belongs to or is invited to
+	ListForUser(ctx context.Context, userID uint) ([]Group, error)
+	Update(ctx context.Context, group *Group) error
+	// Delete removes the group together with its memberships and the shares
+	// that target it
+	Delete(ctx context.Context, id uint) error
+	// AddMember adds the user to the group, as an invitation when pending;
+	// adding a member twice is a no-op
+	AddMember(ctx context.Context, groupID, userID uint, pending bool) error
+	// AcceptMember turns the user's invitation into a membership
+	AcceptMember(ctx context.Context, groupID, userID uint) error
+	RemoveMember(ctx context.Context, groupID, userID uint) error
+}
+
 // RefreshTokenRepository defines the interface for refresh token persistence
 type RefreshTokenRepository interface {
 	Create(ctx context.Context, token *RefreshToken) error
 	GetByHash(ctx context.Context, hash string) (*RefreshToken, error)
 	DeleteByHash(ctx context.Context, hash string) error
 	DeleteByUserID(ctx context.Context, userID uint) error
+	DeleteByFamily(ctx context.Context, userID uint, familyID string) error
+	ListActiveByUserID(ctx context.Context, userID uint) ([]RefreshToken, error)
+	// MarkRotated revokes the token unless it was already revoked. It returns
+	// false when another request rotated or revoked the token first.
+	MarkRotated(ctx context.Context, id uint) (bool, error)
+	RevokeFamily(ctx context.Context, userID uint, familyID string) (int64, error)
+	RevokeAllExcept(ctx context.Context, userID uint, familyID string) (int64, error)
+}
+
+// LoginThrottleRepository defines the interface for failed login tracking
+type LoginThrottleRepository interface {
+	Get(ctx context.Context, subjectType string, subjectID uint) (*LoginThrottle, error)
+	// IncrementFailures atomically adds one failure for the subject, creating
+	// the record if needed, and returns the updated record.
+	IncrementFailures(ctx context.Context, subjectType string, subjectID, userID uint, at time.Time) (*LoginThrottle, error)
+	SetLockedUntil(ctx context.Context, id uint, until *time.Time) error
+	Delete(ctx context.Context, subjectType string, subjectID uint) error
+	DeleteByUserID(ctx context.Context, userID uint) (int64, error)
 }
 
 // AppPasswordRepository defines the interface for app password persistence
@@ -16,9 +16,16 @@ type User struct {
 	DisplayName   string `gorm:"size:255"`
 	IsActive      bool   `gorm:"not null"`
 	EmailVerified bool   `gorm:"not null"`
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	DeletedAt     gorm.DeletedAt `gorm:"index"`
+	IsAdmin       bool   `gorm:"not null;default:false"`
+	// Optional profile fields, published in the directory address book
+	Phone      string `gorm:"size:50"`
+	JobTitle   string `gorm:"size:255"`
+	Department string `gorm:"size:255"`
+	// DirectoryHidden keeps the user out of the directory (set by admins)
+	DirectoryHidden bool `gorm:"not null;default:false"`
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	DeletedAt       gorm.DeletedAt `gorm:"index"`
 
 	OAuthConnections []OAuthConnection `gorm:"foreignKey:UserID"`
 }
@@ -30,8 +37,8 @@ type OAuthConnection struct {
 	Provider      string `gorm:"size:50;not null"`  // google, microsoft, custom
 	ProviderID    string `gorm:"size:255;not null"` // sub claim from OIDC
 	ProviderEmail string `gorm:"size:255"`
-	AccessToken   string `gorm:"size:2000"` // encrypted
-	RefreshToken  string `gorm:"size:2000"` // encrypted
+	AccessToken   string `gorm:"type:text;serializer:encrypted"`
+	RefreshToken  string `gorm:"type:text;serializer:encrypted"`
 	TokenExpiry   *time.Time
 	CreatedAt     time.Time
 	UpdatedAt     time.Time
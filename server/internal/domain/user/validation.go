@@ -3,6 +3,8 @@ package user
 import (
 	"errors"
 	"net/mail"
+	"regexp"
+	"strings"
 	"unicode"
 )
 
@@ -13,8 +15,23 @@ var (
 	ErrPasswordNoLower   = errors.New("password must contain at least one lowercase letter")
 	ErrPasswordNoDigit   = errors.New("password must contain at least one digit")
 	ErrPasswordNoSpecial = errors.New("password must contain at least one special character")
+	ErrInvalidUsername   = errors.New("username must be 3-64 characters of letters, digits, '.', '_' or '-'")
 )
 
+// usernamePattern keeps usernames safe for use in DAV principal paths
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{2,63}$`)
+
+// reservedUsernames are DAV paths next to the users' principals
+var reservedUsernames = map[string]bool{"principals": true}
+
+// ValidateUsername checks if the username is usable in URLs
+func ValidateUsername(username string) error {
+	if !usernamePattern.MatchString(username) || reservedUsernames[strings.ToLower(username)] {
+		return ErrInvalidUsername
+	}
+	return nil
+}
+
 // ValidateEmail checks if the email format is valid
 func ValidateEmail(email string) error {
 	_, err := mail.ParseAddress(email)
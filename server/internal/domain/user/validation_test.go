@@ -52,3 +52,27 @@ func TestValidatePassword(t *testing.T) {
 		}
 	}
 }
+
+func TestValidateUsername(t *testing.T) {
+	tests := []struct {
+		username string
+		valid    bool
+	}{
+		{"alice", true},
+		{"jane.doe-2", true},
+		{"ab", false},
+		{"-alice", false},
+		{"alice/bob", false},
+		{"principals", false},
+		{"Principals", false},
+	}
+
+	for _, tt := range tests {
+		err := ValidateUsername(tt.username)
+		if tt.valid {
+			assert.NoError(t, err, "Username: %s", tt.username)
+		} else {
+			assert.ErrorIs(t, err, ErrInvalidUsername, "Username: %s", tt.username)
+		}
+	}
+}
@@ -5,8 +5,10 @@ import (
 
 	"github.com/jherrma/caldav-server/internal/domain"
 	"github.com/jherrma/caldav-server/internal/domain/calendar"
+	"github.com/jherrma/caldav-server/internal/domain/oauthserver"
 	"github.com/jherrma/caldav-server/internal/domain/sharing"
 	"github.com/jherrma/caldav-server/internal/domain/user"
+	_ "github.com/jherrma/caldav-server/internal/infrastructure/encryption" // serializers used by model tags
 )
 
 // Models returns all domain models for migration
@@ -15,6 +17,7 @@ func Models() []interface{} {
 		&user.User{},
 		&user.EmailVerification{},
 		&user.RefreshToken{},
+		&user.LoginThrottle{},
 		&user.PasswordReset{},
 		&user.AppPassword{},
 		&user.OAuthConnection{},
@@ -25,11 +28,27 @@ func Models() []interface{} {
 		&addressbook.AddressBook{},
 		&addressbook.AddressObject{},
 		&addressbook.ContactPhoto{},
+		&addressbook.ContactPhotoThumbnail{},
 		&addressbook.SyncChangeLog{},
+		&addressbook.DirectoryEntry{},
+		&calendar.BirthdaySettings{},
+		&calendar.BirthdayEvent{},
+		&calendar.BirthdayChangeLog{},
+		&calendar.Subscription{},
 		&user.CalDAVCredential{},
 		&user.CalDAVCredential{},
 		&user.CardDAVCredential{},
+		&user.Group{},
+		&user.GroupMember{},
 		&sharing.CalendarShare{},
 		&sharing.AddressBookShare{},
+		&sharing.SharedCalendar{},
+		&sharing.SharedAddressBook{},
+		&sharing.CalendarProxy{},
+		&sharing.Notification{},
+		&oauthserver.Client{},
+		&oauthserver.AuthorizationCode{},
+		&oauthserver.Token{},
+		&oauthserver.Consent{},
 	}
 }
@@ -0,0 +1,149 @@
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"testing"
+
+	"github.com/jherrma/caldav-server/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type secretRecord struct {
+	ID      uint   `gorm:"primaryKey"`
+	Token   string `gorm:"type:text;serializer:encrypted"`
+	Payload string `gorm:"type:text;serializer:encrypted_payload"`
+}
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, 32)
+}
+
+func rawColumn(t *testing.T, db *gorm.DB, id uint, column string) string {
+	t.Helper()
+	var value string
+	require.NoError(t, db.Table("secret_records").Select(column).Where("id = ?", id).Row().Scan(&value))
+	return value
+}
+
+func TestKeyring_EncryptDecrypt(t *testing.T) {
+	k, err := NewKeyring(testKey(1))
+	require.NoError(t, err)
+
+	a, err := k.Encrypt("refresh-token")
+	require.NoError(t, err)
+	b, err := k.Encrypt("refresh-token")
+	require.NoError(t, err)
+	assert.True(t, IsEncrypted(a))
+	assert.NotEqual(t, a, b, "every record gets its own data key and nonce")
+
+	plain, err := k.Decrypt(a)
+	require.NoError(t, err)
+	assert.Equal(t, "refresh-token", plain)
+
+	other, err := NewKeyring(testKey(2))
+	require.NoError(t, err)
+	_, err = other.Decrypt(a)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+
+	_, err = k.Decrypt(Prefix + k.CurrentKeyID() + ":AAAA:AAAA")
+	assert.ErrorIs(t, err, ErrMalformed)
+}
+
+func TestKeyring_Rewrap(t *testing.T) {
+	oldRing, err := NewKeyring(testKey(1))
+	require.NoError(t, err)
+	sealed, err := oldRing.Encrypt("secret")
+	require.NoError(t, err)
+
+	newRing, err := NewKeyring(testKey(2), testKey(1))
+	require.NoError(t, err)
+
+	rewrapped, changed, err := newRing.Rewrap(sealed)
+	require.NoError(t, err)
+	assert.True(t, changed)
+
+	// Only the wrapped data key changes, the ciphertext stays the same
+	_, _, oldCiphertext, _ := parse(sealed)
+	keyID, _, newCiphertext, _ := parse(rewrapped)
+	assert.Equal(t, oldCiphertext, newCiphertext)
+	assert.Equal(t, newRing.CurrentKeyID(), keyID)
+
+	onlyNew, err := NewKeyring(testKey(2))
+	require.NoError(t, err)
+	plain, err := onlyNew.Decrypt(rewrapped)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", plain)
+
+	_, changed, err = newRing.Rewrap(rewrapped)
+	require.NoError(t, err)
+	assert.False(t, changed)
+}
+
+func TestLoadKeyring(t *testing.T) {
+	k, err := LoadKeyring(config.EncryptionConfig{})
+	assert.NoError(t, err)
+	assert.Nil(t, k)
+
+	_, err = LoadKeyring(config.EncryptionConfig{MasterKey: base64.StdEncoding.EncodeToString([]byte("short"))})
+	assert.ErrorIs(t, err, ErrInvalidKeySize)
+
+	k, err = LoadKeyring(config.EncryptionConfig{MasterKey: base64.StdEncoding.EncodeToString(testKey(3))})
+	require.NoError(t, err)
+	assert.Equal(t, keyID(testKey(3)), k.CurrentKeyID())
+}
+
+func TestSerializerAndRotate(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&secretRecord{}))
+	t.Cleanup(func() { Use(nil, false) })
+
+	// Rows written before encryption was configured stay readable
+	Use(nil, false)
+	legacy := secretRecord{Token: "legacy-token", Payload: "BEGIN:VCALENDAR"}
+	require.NoError(t, db.Create(&legacy).Error)
+	assert.Equal(t, "legacy-token", rawColumn(t, db, legacy.ID, "token"))
+
+	oldRing, err := NewKeyring(testKey(1))
+	require.NoError(t, err)
+	Use(oldRing, false)
+
+	rec := secretRecord{Token: "access-token", Payload: "BEGIN:VCARD"}
+	require.NoError(t, db.Create(&rec).Error)
+	assert.True(t, IsEncrypted(rawColumn(t, db, rec.ID, "token")))
+	assert.Equal(t, "BEGIN:VCARD", rawColumn(t, db, rec.ID, "payload"), "payloads are only encrypted when enabled")
+
+	var loaded []secretRecord
+	require.NoError(t, db.Order("id").Find(&loaded).Error)
+	assert.Equal(t, "legacy-token", loaded[0].Token)
+	assert.Equal(t, "access-token", loaded[1].Token)
+
+	// Rotate to a new master key, encrypting payloads as well
+	newRing, err := NewKeyring(testKey(2), testKey(1))
+	require.NoError(t, err)
+	Use(newRing, true)
+
+	res, err := Rotate(context.Background(), db, newRing, true, &secretRecord{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, res.Rewrapped)
+	assert.Equal(t, 3, res.Encrypted) // legacy token, both payloads
+	assert.True(t, IsEncrypted(rawColumn(t, db, legacy.ID, "payload")))
+
+	onlyNew, err := NewKeyring(testKey(2))
+	require.NoError(t, err)
+	Use(onlyNew, true)
+
+	var got secretRecord
+	require.NoError(t, db.First(&got, rec.ID).Error)
+	assert.Equal(t, "access-token", got.Token)
+	assert.Equal(t, "BEGIN:VCARD", got.Payload)
+
+	// Without any key the encrypted rows cannot be read
+	Use(nil, false)
+	assert.ErrorIs(t, db.First(&got, rec.ID).Error, ErrNoMasterKey)
+}
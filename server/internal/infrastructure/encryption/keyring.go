@@ -0,0 +1,250 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jherrma/caldav-server/internal/config"
+)
+
+// Prefix marks a column value as an envelope-encrypted record:
+//
+//	enc:v1:<key id>:<wrapped data key>:<ciphertext>
+//
+// The data key is a random AES-256 key generated for every record and
+// wrapped (AES-GCM) with the master key identified by <key id>. Rotating the
+// master key only re-wraps data keys; the ciphertext is left untouched.
+const Prefix = "enc:v1:"
+
+var (
+	ErrUnknownKey     = errors.New("record is encrypted with an unknown master key")
+	ErrMalformed      = errors.New("malformed encrypted record")
+	ErrNoMasterKey    = errors.New("record is encrypted but no master key is configured")
+	ErrInvalidKeySize = errors.New("master key must be 32 bytes")
+)
+
+// Keyring holds the current master key and any previous ones still needed to
+// unwrap data keys that have not been rotated yet
+type Keyring struct {
+	currentID string
+	keys      map[string]cipher.AEAD
+}
+
+// NewKeyring creates a keyring that encrypts with current and can decrypt
+// records wrapped with current or any of previous
+func NewKeyring(current []byte, previous ...[]byte) (*Keyring, error) {
+	k := &Keyring{keys: make(map[string]cipher.AEAD)}
+	id, err := k.add(current)
+	if err != nil {
+		return nil, err
+	}
+	k.currentID = id
+	for _, p := range previous {
+		if _, err := k.add(p); err != nil {
+			return nil, err
+		}
+	}
+	return k, nil
+}
+
+// LoadKeyring builds the keyring from configuration. It returns nil when no
+// master key is configured.
+func LoadKeyring(cfg config.EncryptionConfig) (*Keyring, error) {
+	if !cfg.Enabled() {
+		return nil, nil
+	}
+
+	encoded := cfg.MasterKey
+	if cfg.MasterKeyFile != "" {
+		data, err := os.ReadFile(cfg.MasterKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read master key file: %w", err)
+		}
+		encoded = string(data)
+	}
+	current, err := decodeKey(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("master key: %w", err)
+	}
+
+	var previous [][]byte
+	for i, p := range cfg.PreviousKeys {
+		key, err := decodeKey(p)
+		if err != nil {
+			return nil, fmt.Errorf("previous key %d: %w", i, err)
+		}
+		previous = append(previous, key)
+	}
+	return NewKeyring(current, previous...)
+}
+
+// CurrentKeyID returns the identifier of the master key used for new records
+func (k *Keyring) CurrentKeyID() string {
+	return k.currentID
+}
+
+// Encrypt seals plaintext under a fresh data key
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", fmt.Errorf("failed to generate data key: %w", err)
+	}
+	dataAEAD, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+	ciphertext, err := seal(dataAEAD, []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+	wrapped, err := seal(k.keys[k.currentID], dataKey)
+	if err != nil {
+		return "", err
+	}
+	return format(k.currentID, wrapped, ciphertext), nil
+}
+
+// Decrypt opens a record produced by Encrypt
+func (k *Keyring) Decrypt(value string) (string, error) {
+	keyID, wrapped, ciphertext, err := parse(value)
+	if err != nil {
+		return "", err
+	}
+	dataKey, err := k.unwrap(keyID, wrapped)
+	if err != nil {
+		return "", err
+	}
+	dataAEAD, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := open(dataAEAD, ciphertext)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	return string(plaintext), nil
+}
+
+// Rewrap re-encrypts the data key of a record under the current master key.
+// The second return value is false when the record already uses it.
+func (k *Keyring) Rewrap(value string) (string, bool, error) {
+	keyID, wrapped, ciphertext, err := parse(value)
+	if err != nil {
+		return "", false, err
+	}
+	if keyID == k.currentID {
+		return value, false, nil
+	}
+	dataKey, err := k.unwrap(keyID, wrapped)
+	if err != nil {
+		return "", false, err
+	}
+	rewrapped, err := seal(k.keys[k.currentID], dataKey)
+	if err != nil {
+		return "", false, err
+	}
+	return format(k.currentID, rewrapped, ciphertext), true, nil
+}
+
+// IsEncrypted reports whether a column value is an encrypted record
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, Prefix)
+}
+
+func (k *Keyring) add(key []byte) (string, error) {
+	if len(key) != 32 {
+		return "", ErrInvalidKeySize
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	id := keyID(key)
+	k.keys[id] = aead
+	return id, nil
+}
+
+func (k *Keyring) unwrap(keyID string, wrapped []byte) ([]byte, error) {
+	kek, ok := k.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w (%s)", ErrUnknownKey, keyID)
+	}
+	dataKey, err := open(kek, wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	return dataKey, nil
+}
+
+// keyID derives a short public identifier from a master key
+func keyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:4])
+}
+
+func decodeKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, ErrInvalidKeySize
+	}
+	return key, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts with a random nonce and prepends the nonce to the result
+func seal(aead cipher.AEAD, plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func open(aead cipher.AEAD, sealed []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, nil)
+}
+
+func format(keyID string, wrapped, ciphertext []byte) string {
+	return Prefix + keyID + ":" +
+		base64.RawStdEncoding.EncodeToString(wrapped) + ":" +
+		base64.RawStdEncoding.EncodeToString(ciphertext)
+}
+
+func parse(value string) (keyID string, wrapped, ciphertext []byte, err error) {
+	if !IsEncrypted(value) {
+		return "", nil, nil, ErrMalformed
+	}
+	parts := strings.Split(strings.TrimPrefix(value, Prefix), ":")
+	if len(parts) != 3 {
+		return "", nil, nil, ErrMalformed
+	}
+	if wrapped, err = base64.RawStdEncoding.DecodeString(parts[1]); err != nil {
+		return "", nil, nil, ErrMalformed
+	}
+	if ciphertext, err = base64.RawStdEncoding.DecodeString(parts[2]); err != nil {
+		return "", nil, nil, ErrMalformed
+	}
+	return parts[0], wrapped, ciphertext, nil
+}
@@ -0,0 +1,88 @@
+package encryption
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+
+	"gorm.io/gorm/schema"
+)
+
+// Serializer names for use in GORM struct tags. Columns tagged
+// `serializer:encrypted` are always encrypted once a master key is
+// configured; `serializer:encrypted_payload` columns only when payload
+// encryption is switched on as well. Both read plaintext and encrypted
+// values, so existing rows keep working until they are rewritten or
+// rotated.
+const (
+	SerializerName        = "encrypted"
+	PayloadSerializerName = "encrypted_payload"
+)
+
+type state struct {
+	keyring  *Keyring
+	payloads bool
+}
+
+var current atomic.Pointer[state]
+
+func init() {
+	current.Store(&state{})
+	// Registered unconditionally so the domain models parse even when
+	// encryption is not configured (tests, tooling)
+	schema.RegisterSerializer(SerializerName, serializer{})
+	schema.RegisterSerializer(PayloadSerializerName, serializer{payload: true})
+}
+
+// Use installs the keyring the serializers encrypt and decrypt with. A nil
+// keyring stores new values in plaintext.
+func Use(keyring *Keyring, encryptPayloads bool) {
+	current.Store(&state{keyring: keyring, payloads: encryptPayloads})
+}
+
+type serializer struct {
+	payload bool
+}
+
+// Scan decrypts the database value into the string field
+func (s serializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var value string
+	switch v := dbValue.(type) {
+	case nil:
+	case string:
+		value = v
+	case []byte:
+		value = string(v)
+	default:
+		return fmt.Errorf("unsupported type %T for encrypted column %s", dbValue, field.DBName)
+	}
+
+	if IsEncrypted(value) {
+		keyring := current.Load().keyring
+		if keyring == nil {
+			return fmt.Errorf("%s: %w", field.DBName, ErrNoMasterKey)
+		}
+		plaintext, err := keyring.Decrypt(value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", field.DBName, err)
+		}
+		value = plaintext
+	}
+
+	return field.Set(ctx, dst, value)
+}
+
+// Value encrypts the string field for storage
+func (s serializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	value, ok := fieldValue.(string)
+	if !ok {
+		return nil, fmt.Errorf("encrypted column %s must be a string, got %T", field.DBName, fieldValue)
+	}
+
+	st := current.Load()
+	if value == "" || st.keyring == nil || (s.payload && !st.payloads) {
+		return value, nil
+	}
+	return st.keyring.Encrypt(value)
+}
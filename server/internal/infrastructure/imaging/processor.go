@@ -0,0 +1,189 @@
+// Package imaging normalizes contact photos using only the standard library
+// decoders (JPEG, PNG and GIF).
+package imaging
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif" // Register the GIF decoder
+	"image/jpeg"
+	"image/png"
+	"sort"
+
+	"github.com/jherrma/caldav-server/internal/config"
+	"github.com/jherrma/caldav-server/internal/domain/addressbook"
+)
+
+// Defaults for zero PhotoConfig values
+const (
+	defaultMaxDimension   = 512
+	defaultJPEGQuality    = 85
+	defaultMaxInputBytes  = 10 * 1024 * 1024
+	defaultMaxInputPixels = 40_000_000
+)
+
+// PhotoProcessor implements addressbook.PhotoProcessor. Photos are decoded,
+// turned upright according to their EXIF orientation, downscaled to the
+// configured maximum and re-encoded as JPEG, or PNG when they have
+// transparency. Re-encoding also strips embedded metadata.
+type PhotoProcessor struct {
+	maxDimension   int
+	thumbnailSizes []int
+	jpegQuality    int
+	maxInputBytes  int64
+	maxInputPixels int
+}
+
+func NewPhotoProcessor(cfg config.PhotoConfig) *PhotoProcessor {
+	p := &PhotoProcessor{
+		maxDimension:   cfg.MaxDimension,
+		jpegQuality:    cfg.JPEGQuality,
+		maxInputBytes:  cfg.MaxInputBytes,
+		maxInputPixels: cfg.MaxInputPixels,
+	}
+	if p.maxDimension <= 0 {
+		p.maxDimension = defaultMaxDimension
+	}
+	if p.jpegQuality <= 0 || p.jpegQuality > 100 {
+		p.jpegQuality = defaultJPEGQuality
+	}
+	if p.maxInputBytes <= 0 {
+		p.maxInputBytes = defaultMaxInputBytes
+	}
+	if p.maxInputPixels <= 0 {
+		p.maxInputPixels = defaultMaxInputPixels
+	}
+
+	seen := map[int]bool{}
+	for _, size := range cfg.ThumbnailSizes {
+		if size > 0 && size <= p.maxDimension && !seen[size] {
+			seen[size] = true
+			p.thumbnailSizes = append(p.thumbnailSizes, size)
+		}
+	}
+	sort.Ints(p.thumbnailSizes)
+	return p
+}
+
+func (p *PhotoProcessor) Process(data []byte) (*addressbook.ProcessedPhoto, error) {
+	if int64(len(data)) > p.maxInputBytes {
+		return nil, fmt.Errorf("%w: larger than %d bytes", addressbook.ErrInvalidPhoto, p.maxInputBytes)
+	}
+
+	// Check the dimensions before decoding so a small file can't expand
+	// into gigabytes of pixels
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", addressbook.ErrInvalidPhoto, err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > p.maxInputPixels {
+		return nil, fmt.Errorf("%w: %dx%d pixels exceed the limit", addressbook.ErrInvalidPhoto, cfg.Width, cfg.Height)
+	}
+
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", addressbook.ErrInvalidPhoto, err)
+	}
+
+	rgba := image.NewRGBA(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, img.Bounds().Min, draw.Src)
+	if format == "jpeg" {
+		rgba = orient(rgba, exifOrientation(data))
+	}
+
+	encodeAs := "JPEG"
+	if !rgba.Opaque() {
+		encodeAs = "PNG"
+	}
+
+	photo := fit(rgba, p.maxDimension)
+	out := &addressbook.ProcessedPhoto{
+		Type:   encodeAs,
+		Width:  photo.Bounds().Dx(),
+		Height: photo.Bounds().Dy(),
+	}
+	if out.Data, err = p.encode(photo, encodeAs); err != nil {
+		return nil, err
+	}
+
+	for _, size := range p.thumbnailSizes {
+		thumb := fit(photo, size)
+		encoded, err := p.encode(thumb, encodeAs)
+		if err != nil {
+			return nil, err
+		}
+		out.Thumbnails = append(out.Thumbnails, addressbook.PhotoThumbnail{
+			Size:   size,
+			Data:   encoded,
+			Type:   encodeAs,
+			Width:  thumb.Bounds().Dx(),
+			Height: thumb.Bounds().Dy(),
+		})
+	}
+	return out, nil
+}
+
+func (p *PhotoProcessor) encode(img image.Image, photoType string) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	if photoType == "PNG" {
+		err = (&png.Encoder{CompressionLevel: png.BestCompression}).Encode(&buf, img)
+	} else {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: p.jpegQuality})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode photo: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// fit scales img down so its longest edge is at most maxEdge, keeping the
+// aspect ratio. Smaller images are returned unchanged.
+func fit(img *image.RGBA, maxEdge int) *image.RGBA {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	if w <= maxEdge && h <= maxEdge {
+		return img
+	}
+	if w >= h {
+		h = max(1, h*maxEdge/w)
+		w = maxEdge
+	} else {
+		w = max(1, w*maxEdge/h)
+		h = maxEdge
+	}
+	return downscale(img, w, h)
+}
+
+// downscale resizes img to w x h by averaging the source pixels covered by
+// each destination pixel (box filter). The pixels are premultiplied, so
+// transparent areas don't bleed their color.
+func downscale(src *image.RGBA, w, h int) *image.RGBA {
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		sy0, sy1 := y*sh/h, max((y+1)*sh/h, y*sh/h+1)
+		for x := 0; x < w; x++ {
+			sx0, sx1 := x*sw/w, max((x+1)*sw/w, x*sw/w+1)
+			var r, g, b, a, n uint64
+			for sy := sy0; sy < sy1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := sx0; sx < sx1; sx++ {
+					px := row[sx*4 : sx*4+4]
+					r += uint64(px[0])
+					g += uint64(px[1])
+					b += uint64(px[2])
+					a += uint64(px[3])
+					n++
+				}
+			}
+			i := y*dst.Stride + x*4
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(b / n)
+			dst.Pix[i+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
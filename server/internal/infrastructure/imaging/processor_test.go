@@ -0,0 +1,121 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/jherrma/caldav-server/internal/config"
+	"github.com/jherrma/caldav-server/internal/domain/addressbook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encodeJPEG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), 128, 255})
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, img, nil))
+	return buf.Bytes()
+}
+
+// withOrientation inserts an EXIF APP1 segment carrying the orientation tag
+// right after the JPEG SOI marker
+func withOrientation(data []byte, orientation uint16) []byte {
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08\x00\x01")
+	entry := make([]byte, 12)
+	binary.BigEndian.PutUint16(entry[0:], 0x0112)
+	binary.BigEndian.PutUint16(entry[2:], 3) // SHORT
+	binary.BigEndian.PutUint32(entry[4:], 1)
+	binary.BigEndian.PutUint16(entry[8:], orientation)
+	tiff = append(append(tiff, entry...), 0, 0, 0, 0)
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	segment = append(segment, payload...)
+
+	out := append([]byte{}, data[:2]...)
+	out = append(out, segment...)
+	return append(out, data[2:]...)
+}
+
+func TestPhotoProcessor_Downscale(t *testing.T) {
+	p := NewPhotoProcessor(config.PhotoConfig{MaxDimension: 100, ThumbnailSizes: []int{64, 16, 64, 500}})
+
+	out, err := p.Process(encodeJPEG(t, 400, 200))
+	require.NoError(t, err)
+	assert.Equal(t, "JPEG", out.Type)
+	assert.Equal(t, 100, out.Width)
+	assert.Equal(t, 50, out.Height)
+
+	decoded, format, err := image.Decode(bytes.NewReader(out.Data))
+	require.NoError(t, err)
+	assert.Equal(t, "jpeg", format)
+	assert.Equal(t, image.Rect(0, 0, 100, 50), decoded.Bounds())
+
+	// Sorted, deduplicated, and limited to the maximum dimension
+	require.Len(t, out.Thumbnails, 2)
+	assert.Equal(t, 16, out.Thumbnails[0].Size)
+	assert.Equal(t, 16, out.Thumbnails[0].Width)
+	assert.Equal(t, 8, out.Thumbnails[0].Height)
+	assert.Equal(t, 64, out.Thumbnails[1].Size)
+	assert.Equal(t, 32, out.Thumbnails[1].Height)
+}
+
+func TestPhotoProcessor_SmallPhotoKeepsSize(t *testing.T) {
+	p := NewPhotoProcessor(config.PhotoConfig{MaxDimension: 100, ThumbnailSizes: []int{48}})
+	out, err := p.Process(encodeJPEG(t, 20, 30))
+	require.NoError(t, err)
+	assert.Equal(t, 20, out.Width)
+	assert.Equal(t, 30, out.Height)
+	assert.Equal(t, 20, out.Thumbnails[0].Width)
+}
+
+func TestPhotoProcessor_TransparencyKeepsPNG(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 10, 10))
+	img.Set(1, 1, color.NRGBA{255, 0, 0, 128})
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+
+	out, err := NewPhotoProcessor(config.PhotoConfig{}).Process(buf.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, "PNG", out.Type)
+	_, format, err := image.Decode(bytes.NewReader(out.Data))
+	require.NoError(t, err)
+	assert.Equal(t, "png", format)
+}
+
+func TestPhotoProcessor_EXIFOrientation(t *testing.T) {
+	data := withOrientation(encodeJPEG(t, 40, 20), 6)
+	assert.Equal(t, 6, exifOrientation(data))
+
+	out, err := NewPhotoProcessor(config.PhotoConfig{}).Process(data)
+	require.NoError(t, err)
+	assert.Equal(t, 20, out.Width)
+	assert.Equal(t, 40, out.Height)
+}
+
+func TestPhotoProcessor_Rejects(t *testing.T) {
+	p := NewPhotoProcessor(config.PhotoConfig{MaxInputBytes: 1 << 20, MaxInputPixels: 10_000})
+
+	for name, data := range map[string][]byte{
+		"not an image":    []byte("definitely not an image"),
+		"too many pixels": encodeJPEG(t, 200, 100),
+	} {
+		_, err := p.Process(data)
+		assert.ErrorIs(t, err, addressbook.ErrInvalidPhoto, name)
+	}
+
+	_, err := NewPhotoProcessor(config.PhotoConfig{MaxInputBytes: 100}).Process(encodeJPEG(t, 20, 20))
+	assert.ErrorIs(t, err, addressbook.ErrInvalidPhoto)
+}
@@ -2,6 +2,7 @@ package logging
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"time"
 )
@@ -86,3 +87,59 @@ func (l *SecurityLogger) LogAppPasswordRevoked(ctx context.Context, userID uint,
 	}
 	l.logger.Info("security_event", slog.Any("event", event))
 }
+
+// LogRefreshTokenReuse logs the replay of an already-rotated refresh token
+func (l *SecurityLogger) LogRefreshTokenReuse(ctx context.Context, userID uint, familyID string, ip string, userAgent string) {
+	event := SecurityEvent{
+		Timestamp: time.Now(),
+		Event:     "refresh_token_reuse",
+		UserID:    &userID,
+		Details:   "Session revoked: " + familyID,
+		IP:        ip,
+		UserAgent: userAgent,
+		Success:   false,
+	}
+	l.logger.Warn("security_event", slog.Any("event", event))
+}
+
+// LogSessionRevoked logs the revocation of one or more sessions by the user
+func (l *SecurityLogger) LogSessionRevoked(ctx context.Context, userID uint, details string, ip string, userAgent string) {
+	event := SecurityEvent{
+		Timestamp: time.Now(),
+		Event:     "session_revoked",
+		UserID:    &userID,
+		Details:   details,
+		IP:        ip,
+		UserAgent: userAgent,
+		Success:   true,
+	}
+	l.logger.Info("security_event", slog.Any("event", event))
+}
+
+// LogAccountLocked logs an account or credential being locked after repeated failed logins
+func (l *SecurityLogger) LogAccountLocked(ctx context.Context, userID uint, subject string, failures int, until time.Time, ip string, userAgent string) {
+	event := SecurityEvent{
+		Timestamp: time.Now(),
+		Event:     "account_locked",
+		UserID:    &userID,
+		Details:   fmt.Sprintf("%s locked until %s after %d failed attempts", subject, until.UTC().Format(time.RFC3339), failures),
+		IP:        ip,
+		UserAgent: userAgent,
+		Success:   false,
+	}
+	l.logger.Warn("security_event", slog.Any("event", event))
+}
+
+// LogAccountUnlocked logs an administrator clearing the lockouts of a user
+func (l *SecurityLogger) LogAccountUnlocked(ctx context.Context, adminID uint, userID uint, ip string, userAgent string) {
+	event := SecurityEvent{
+		Timestamp: time.Now(),
+		Event:     "account_unlocked",
+		UserID:    &userID,
+		Details:   fmt.Sprintf("Unlocked by user %d", adminID),
+		IP:        ip,
+		UserAgent: userAgent,
+		Success:   true,
+	}
+	l.logger.Info("security_event", slog.Any("event", event))
+}
@@ -0,0 +1,122 @@
+// Package webcal downloads subscribed iCalendar feeds over HTTP.
+package webcal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/jherrma/caldav-server/internal/config"
+	"github.com/jherrma/caldav-server/internal/domain/calendar"
+)
+
+// ErrPrivateAddress is returned for feeds on loopback, private and
+// link-local addresses when private networks are not allowed
+var ErrPrivateAddress = errors.New("feed address is in a private network")
+
+// Fetcher implements calendar.FeedFetcher. Requests are conditional on the
+// validators of the previous response, bodies are capped at the configured
+// size, and every connection, including those of redirects, is checked
+// against the private network rule after DNS resolution. Proxies are
+// bypassed while that rule applies.
+type Fetcher struct {
+	client   *http.Client
+	maxBytes int64
+}
+
+func NewFetcher(cfg config.SubscriptionsConfig) *Fetcher {
+	dialer := &net.Dialer{Timeout: cfg.Timeout}
+	if !cfg.AllowPrivateNetworks {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || isPrivate(ip) {
+				return ErrPrivateAddress
+			}
+			return nil
+		}
+	}
+	transport := &http.Transport{
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: cfg.Timeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       90 * time.Second,
+	}
+	// Through a proxy only the proxy's address would be checked while the
+	// proxy reaches the feed host, private or not, so the proxy settings of
+	// the environment only apply when private networks are allowed anyway
+	if cfg.AllowPrivateNetworks {
+		transport.Proxy = http.ProxyFromEnvironment
+	}
+	return &Fetcher{
+		client:   &http.Client{Timeout: cfg.Timeout, Transport: transport},
+		maxBytes: cfg.MaxFeedBytes,
+	}
+}
+
+func isPrivate(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast()
+}
+
+func (f *Fetcher) Fetch(ctx context.Context, req calendar.FeedRequest) (*calendar.FeedResponse, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, req.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Accept", "text/calendar, */*;q=0.5")
+	httpReq.Header.Set("User-Agent", "CalDAV-Server (calendar subscription)")
+	if req.ETag != "" {
+		httpReq.Header.Set("If-None-Match", req.ETag)
+	}
+	if req.LastModified != "" {
+		httpReq.Header.Set("If-Modified-Since", req.LastModified)
+	}
+
+	resp, err := f.client.Do(httpReq)
+	if err != nil {
+		if errors.Is(err, ErrPrivateAddress) {
+			return nil, ErrPrivateAddress
+		}
+		return nil, fmt.Errorf("failed to fetch feed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	validators := func(out *calendar.FeedResponse) *calendar.FeedResponse {
+		out.ETag, out.LastModified = resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+		if resp.StatusCode == http.StatusNotModified {
+			// A 304 may leave out validators that did not change
+			if out.ETag == "" {
+				out.ETag = req.ETag
+			}
+			if out.LastModified == "" {
+				out.LastModified = req.LastModified
+			}
+		}
+		return out
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified:
+		return validators(&calendar.FeedResponse{NotModified: true}), nil
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return nil, fmt.Errorf("feed returned HTTP %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, f.maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read feed: %w", err)
+	}
+	if int64(len(data)) > f.maxBytes {
+		return nil, fmt.Errorf("feed is larger than %d bytes", f.maxBytes)
+	}
+	return validators(&calendar.FeedResponse{Data: data}), nil
+}
@@ -0,0 +1,74 @@
+package webcal
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jherrma/caldav-server/internal/config"
+	"github.com/jherrma/caldav-server/internal/domain/calendar"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetcher(t *testing.T) {
+	const feed = "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//Test//EN\r\nEND:VCALENDAR\r\n"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing.ics":
+			http.NotFound(w, r)
+			return
+		case "/large.ics":
+			w.Write([]byte(strings.Repeat("X", 2048)))
+			return
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Last-Modified", "Mon, 01 Jan 2024 00:00:00 GMT")
+		w.Write([]byte(feed))
+	}))
+	defer srv.Close()
+
+	cfg := config.SubscriptionsConfig{Timeout: 5 * time.Second, MaxFeedBytes: 1024, AllowPrivateNetworks: true}
+	ctx := context.Background()
+
+	t.Run("Conditional Requests", func(t *testing.T) {
+		f := NewFetcher(cfg)
+		resp, err := f.Fetch(ctx, calendar.FeedRequest{URL: srv.URL + "/feed.ics"})
+		require.NoError(t, err)
+		assert.False(t, resp.NotModified)
+		assert.Equal(t, feed, string(resp.Data))
+		assert.Equal(t, `"v1"`, resp.ETag)
+		assert.Equal(t, "Mon, 01 Jan 2024 00:00:00 GMT", resp.LastModified)
+
+		resp, err = f.Fetch(ctx, calendar.FeedRequest{URL: srv.URL + "/feed.ics", ETag: resp.ETag, LastModified: resp.LastModified})
+		require.NoError(t, err)
+		assert.True(t, resp.NotModified)
+		assert.Equal(t, `"v1"`, resp.ETag)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		f := NewFetcher(cfg)
+		_, err := f.Fetch(ctx, calendar.FeedRequest{URL: srv.URL + "/missing.ics"})
+		assert.ErrorContains(t, err, "HTTP 404")
+		_, err = f.Fetch(ctx, calendar.FeedRequest{URL: srv.URL + "/large.ics"})
+		assert.ErrorContains(t, err, "larger than 1024 bytes")
+	})
+
+	t.Run("Private Networks", func(t *testing.T) {
+		cfg := cfg
+		cfg.AllowPrivateNetworks = false
+		_, err := NewFetcher(cfg).Fetch(ctx, calendar.FeedRequest{URL: srv.URL + "/feed.ics"})
+		assert.ErrorIs(t, err, ErrPrivateAddress)
+
+		// A proxy would reach private hosts on the server's behalf
+		assert.Nil(t, NewFetcher(cfg).client.Transport.(*http.Transport).Proxy)
+		assert.NotNil(t, NewFetcher(config.SubscriptionsConfig{AllowPrivateNetworks: true}).client.Transport.(*http.Transport).Proxy)
+	})
+}
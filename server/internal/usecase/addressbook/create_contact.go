@@ -58,13 +58,18 @@ func (uc *CreateContactUseCase) Execute(ctx context.Context, input CreateContact
 		input.VCardData = buf.String()
 	}
 
+	version := card.Value(vcard.FieldVersion)
+	if version == "" {
+		version = "3.0"
+	}
+
 	obj := &addressbook.AddressObject{
 		UUID:          uuid.New().String(), // internal DB UUID, distinct from vCard UID
 		AddressBookID: input.AddressBookID,
 		Path:          uid + ".vcf",
 		UID:           uid,
 		VCardData:     input.VCardData,
-		VCardVersion:  "3.0",
+		VCardVersion:  version,
 		ETag:          fmt.Sprintf("%d", time.Now().UnixNano()),
 		ContentLength: len(input.VCardData),
 		CreatedAt:     time.Now(),
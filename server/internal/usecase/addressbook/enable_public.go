@@ -0,0 +1,73 @@
+package addressbook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/jherrma/caldav-server/internal/domain/addressbook"
+)
+
+type EnablePublicInput struct {
+	Enabled bool `json:"enabled"`
+}
+
+type EnablePublicOutput struct {
+	Enabled   bool                 `json:"enabled"`
+	PublicURL *string              `json:"public_url"`
+	Token     *string              `json:"token"`
+	EnabledAt *time.Time           `json:"enabled_at"`
+	Options   *PublicOptionsOutput `json:"options"`
+}
+
+type EnablePublicUseCase struct {
+	repo    addressbook.Repository
+	baseURL string
+}
+
+func NewEnablePublicUseCase(repo addressbook.Repository, baseURL string) *EnablePublicUseCase {
+	return &EnablePublicUseCase{
+		repo:    repo,
+		baseURL: baseURL,
+	}
+}
+
+func generatePublicToken() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base64.URLEncoding.EncodeToString(b)
+}
+
+func (uc *EnablePublicUseCase) Execute(ctx context.Context, userID, addressBookID uint, input EnablePublicInput) (*EnablePublicOutput, error) {
+	ab, err := uc.repo.GetByID(ctx, addressBookID)
+	if err != nil || ab == nil {
+		return nil, fmt.Errorf("address book not found")
+	}
+	if ab.UserID != userID {
+		return nil, fmt.Errorf("permission denied")
+	}
+
+	if input.Enabled {
+		// Enable public access
+		if ab.PublicToken == nil || *ab.PublicToken == "" {
+			token := generatePublicToken()
+			ab.PublicToken = &token
+		}
+		ab.PublicEnabled = true
+		now := time.Now()
+		ab.PublicEnabledAt = &now
+	} else {
+		// Disable public access
+		ab.PublicEnabled = false
+		ab.PublicToken = nil
+		ab.PublicEnabledAt = nil
+	}
+
+	if err := uc.repo.Update(ctx, ab); err != nil {
+		return nil, err
+	}
+
+	return publicStatus(ab, uc.baseURL), nil
+}
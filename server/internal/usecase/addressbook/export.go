@@ -1,10 +1,12 @@
 package addressbook
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"strings"
 
+	"github.com/emersion/go-vcard"
 	"github.com/jherrma/caldav-server/internal/domain/addressbook"
 )
 
@@ -16,7 +18,10 @@ func NewExportUseCase(repo addressbook.Repository) *ExportUseCase {
 	return &ExportUseCase{repo: repo}
 }
 
-func (uc *ExportUseCase) Execute(ctx context.Context, id uint, userID uint) ([]byte, string, error) {
+// Execute exports every contact of the address book as one vCard stream.
+// A non-empty version converts all cards to vCard 3.0 or 4.0; otherwise
+// each card is exported in the version it is stored in.
+func (uc *ExportUseCase) Execute(ctx context.Context, id uint, userID uint, version string) ([]byte, string, error) {
 	ab, err := uc.repo.GetByID(ctx, id)
 	if err != nil {
 		return nil, "", err
@@ -33,10 +38,56 @@ func (uc *ExportUseCase) Execute(ctx context.Context, id uint, userID uint) ([]b
 
 	var sb strings.Builder
 	for _, contact := range contacts {
-		sb.WriteString(contact.VCardData)
+		data := contact.VCardData
+		if version != "" {
+			if data, err = addressbook.ConvertVCardData(data, version); err != nil {
+				return nil, "", fmt.Errorf("failed to convert contact %s: %w", contact.UID, err)
+			}
+		}
+		sb.WriteString(data)
 		sb.WriteString("\n")
 	}
 
 	filename := fmt.Sprintf("%s.vcf", ab.Name)
 	return []byte(sb.String()), filename, nil
 }
+
+// ExecuteCSV exports the individual contacts of the address book as CSV in
+// the given column layout. Contact groups have no CSV representation and are
+// left out.
+func (uc *ExportUseCase) ExecuteCSV(ctx context.Context, id uint, userID uint, mapping addressbook.CSVMapping) ([]byte, string, error) {
+	ab, err := uc.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+	if ab == nil || ab.UserID != userID {
+		return nil, "", fmt.Errorf("address book not found")
+	}
+
+	contacts, _, err := uc.repo.ListObjects(ctx, ab.ID, -1, 0, "name", "asc")
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to fetch contacts: %w", err)
+	}
+
+	cards := make([]vcard.Card, 0, len(contacts))
+	for _, contact := range contacts {
+		card, err := vcard.NewDecoder(strings.NewReader(contact.VCardData)).Decode()
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to parse contact %s: %w", contact.UID, err)
+		}
+		if addressbook.CardKind(card) == addressbook.KindGroup {
+			continue
+		}
+		// The CSV layouts read TYPE=pref and X-ANNIVERSARY
+		addressbook.ConvertVCard(card, addressbook.VCardVersion3)
+		cards = append(cards, card)
+	}
+
+	var buf bytes.Buffer
+	if err := addressbook.WriteCSV(&buf, cards, mapping); err != nil {
+		return nil, "", fmt.Errorf("failed to write CSV: %w", err)
+	}
+
+	filename := fmt.Sprintf("%s.csv", ab.Name)
+	return buf.Bytes(), filename, nil
+}
@@ -1,7 +1,9 @@
 package addressbook_test
 
 import (
+	"bytes"
 	"context"
+	"encoding/csv"
 	"strings"
 	"testing"
 
@@ -43,6 +45,9 @@ func (m *mockRepo) CreateObject(ctx context.Context, object *addressbook.Address
 func (m *mockRepo) GetObjectByID(ctx context.Context, id uint) (*addressbook.AddressObject, error) {
 	return nil, nil
 }
+func (m *mockRepo) FindByPublicToken(ctx context.Context, token string) (*addressbook.AddressBook, error) {
+	return nil, nil
+}
 func (m *mockRepo) GetByUUID(ctx context.Context, uuid string) (*addressbook.AddressBook, error) {
 	return nil, nil
 }
@@ -53,9 +58,22 @@ func (m *mockRepo) UpdateObject(ctx context.Context, object *addressbook.Address
 	return nil
 }
 func (m *mockRepo) DeleteObjectByUUID(ctx context.Context, uuid string) error { return nil }
+func (m *mockRepo) SaveObjectChanges(ctx context.Context, modified []*addressbook.AddressObject, deletedUUIDs []string) error {
+	return nil
+}
 func (m *mockRepo) SearchObjects(ctx context.Context, userID uint, query string, addressBookID *uint, limit int) ([]addressbook.AddressObject, error) {
 	return nil, nil
 }
+func (m *mockRepo) ReindexSearch(ctx context.Context) (int, error) { return 0, nil }
+func (m *mockRepo) ListGroups(ctx context.Context, addressBookID uint) ([]addressbook.AddressObject, error) {
+	return nil, nil
+}
+func (m *mockRepo) GetPhoto(ctx context.Context, objectID uint, size int) (*addressbook.ContactPhoto, error) {
+	return nil, nil
+}
+func (m *mockRepo) GetObjectByUID(ctx context.Context, addressBookID uint, uid string) (*addressbook.AddressObject, error) {
+	return nil, nil
+}
 func (m *mockRepo) GetByUserAndPath(ctx context.Context, userID uint, path string) (*addressbook.AddressBook, error) {
 	return nil, nil
 }
@@ -100,7 +118,7 @@ func TestExportUseCase_Execute(t *testing.T) {
 	repo.On("GetByID", ctx, abID).Return(ab, nil)
 	repo.On("ListObjects", ctx, abID).Return(contacts, nil)
 
-	data, filename, err := uc.Execute(ctx, abID, userID)
+	data, filename, err := uc.Execute(ctx, abID, userID, "")
 
 	assert.NoError(t, err)
 	assert.Equal(t, "MyContacts.vcf", filename)
@@ -116,3 +134,66 @@ func TestExportUseCase_Execute(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "Jane Smith", card2.PreferredValue(vcard.FieldFormattedName))
 }
+
+func TestExportUseCase_ExecuteVersion(t *testing.T) {
+	repo := new(mockRepo)
+	uc := addressbookuc.NewExportUseCase(repo)
+	ctx := context.Background()
+
+	ab := &addressbook.AddressBook{ID: 10, UserID: 1, Name: "MyContacts"}
+	contacts := []addressbook.AddressObject{
+		{VCardData: "BEGIN:VCARD\r\nVERSION:3.0\r\nFN:John Doe\r\nEMAIL;TYPE=pref:john@example.com\r\nEND:VCARD\r\n"},
+		{VCardData: "BEGIN:VCARD\r\nVERSION:4.0\r\nFN:Jane Smith\r\nEND:VCARD\r\n"},
+	}
+
+	repo.On("GetByID", ctx, uint(10)).Return(ab, nil)
+	repo.On("ListObjects", ctx, uint(10)).Return(contacts, nil)
+
+	data, _, err := uc.Execute(ctx, 10, 1, addressbook.VCardVersion4)
+	assert.NoError(t, err)
+
+	dec := vcard.NewDecoder(strings.NewReader(string(data)))
+	card1, err := dec.Decode()
+	assert.NoError(t, err)
+	assert.Equal(t, "4.0", card1.Value(vcard.FieldVersion))
+	assert.Equal(t, "1", card1.Get(vcard.FieldEmail).Params.Get(vcard.ParamPreferred))
+
+	card2, err := dec.Decode()
+	assert.NoError(t, err)
+	assert.Equal(t, "4.0", card2.Value(vcard.FieldVersion))
+}
+
+func TestExportUseCase_ExecuteCSV(t *testing.T) {
+	repo := new(mockRepo)
+	uc := addressbookuc.NewExportUseCase(repo)
+	ctx := context.Background()
+
+	ab := &addressbook.AddressBook{ID: 10, UserID: 1, Name: "MyContacts"}
+	contacts := []addressbook.AddressObject{
+		{VCardData: "BEGIN:VCARD\r\nVERSION:4.0\r\nFN:Jane Smith\r\nN:Smith;Jane;;;\r\nEMAIL;PREF=1;TYPE=work:jane@example.com\r\nTEL;TYPE=cell:+1 555 0100\r\nEND:VCARD\r\n"},
+		{VCardData: "BEGIN:VCARD\r\nVERSION:4.0\r\nKIND:group\r\nFN:Friends\r\nMEMBER:urn:uuid:abc\r\nEND:VCARD\r\n"},
+	}
+
+	repo.On("GetByID", ctx, uint(10)).Return(ab, nil)
+	repo.On("ListObjects", ctx, uint(10)).Return(contacts, nil)
+
+	data, filename, err := uc.ExecuteCSV(ctx, 10, 1, addressbook.OutlookCSVMapping)
+	assert.NoError(t, err)
+	assert.Equal(t, "MyContacts.csv", filename)
+
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	assert.NoError(t, err)
+	assert.Len(t, records, 2) // Header and Jane; the group is left out
+
+	row := make(map[string]string)
+	for i, header := range records[0] {
+		row[header] = records[1][i]
+	}
+	assert.Equal(t, "Jane", row["First Name"])
+	assert.Equal(t, "Smith", row["Last Name"])
+	assert.Equal(t, "jane@example.com", row["E-mail Address"])
+	assert.Equal(t, "+1 555 0100", row["Mobile Phone"])
+
+	_, _, err = uc.ExecuteCSV(ctx, 10, 2, addressbook.GoogleCSVMapping)
+	assert.Error(t, err)
+}
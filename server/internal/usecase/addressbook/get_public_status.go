@@ -0,0 +1,32 @@
+package addressbook
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jherrma/caldav-server/internal/domain/addressbook"
+)
+
+type GetPublicStatusUseCase struct {
+	repo    addressbook.Repository
+	baseURL string
+}
+
+func NewGetPublicStatusUseCase(repo addressbook.Repository, baseURL string) *GetPublicStatusUseCase {
+	return &GetPublicStatusUseCase{
+		repo:    repo,
+		baseURL: baseURL,
+	}
+}
+
+func (uc *GetPublicStatusUseCase) Execute(ctx context.Context, userID, addressBookID uint) (*EnablePublicOutput, error) {
+	ab, err := uc.repo.GetByID(ctx, addressBookID)
+	if err != nil || ab == nil {
+		return nil, fmt.Errorf("address book not found")
+	}
+	if ab.UserID != userID {
+		return nil, fmt.Errorf("permission denied")
+	}
+
+	return publicStatus(ab, uc.baseURL), nil
+}
@@ -0,0 +1,148 @@
+package addressbook
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/emersion/go-vcard"
+	"github.com/jherrma/caldav-server/internal/domain/addressbook"
+)
+
+// ErrPublicFeedNotFound is returned for unknown and disabled public tokens
+var ErrPublicFeedNotFound = errors.New("address book not found")
+
+// PublicOptionsInput replaces the publication settings of an address book
+type PublicOptionsInput struct {
+	HidePhones    bool `json:"hide_phones"`
+	HideAddresses bool `json:"hide_addresses"`
+	HideEmails    bool `json:"hide_emails"`
+	HideBirthdays bool `json:"hide_birthdays"`
+	HideNotes     bool `json:"hide_notes"`
+	HidePhotos    bool `json:"hide_photos"`
+}
+
+// PublicOptionsOutput describes the publication settings of an address book
+type PublicOptionsOutput PublicOptionsInput
+
+// publicStatus describes the public access of an address book
+func publicStatus(ab *addressbook.AddressBook, baseURL string) *EnablePublicOutput {
+	o := ab.PublicOptions
+	output := &EnablePublicOutput{
+		Enabled:   ab.PublicEnabled,
+		EnabledAt: ab.PublicEnabledAt,
+		Options: &PublicOptionsOutput{
+			HidePhones:    o.HidePhones,
+			HideAddresses: o.HideAddresses,
+			HideEmails:    o.HideEmails,
+			HideBirthdays: o.HideBirthdays,
+			HideNotes:     o.HideNotes,
+			HidePhotos:    o.HidePhotos,
+		},
+	}
+	if ab.PublicEnabled && ab.PublicToken != nil && *ab.PublicToken != "" {
+		url := fmt.Sprintf("%s/public/addressbook/%s.vcf", baseURL, *ab.PublicToken)
+		output.PublicURL = &url
+		output.Token = ab.PublicToken
+	}
+	return output
+}
+
+type UpdatePublicOptionsUseCase struct {
+	repo    addressbook.Repository
+	baseURL string
+}
+
+func NewUpdatePublicOptionsUseCase(repo addressbook.Repository, baseURL string) *UpdatePublicOptionsUseCase {
+	return &UpdatePublicOptionsUseCase{
+		repo:    repo,
+		baseURL: baseURL,
+	}
+}
+
+func (uc *UpdatePublicOptionsUseCase) Execute(ctx context.Context, userID, addressBookID uint, input PublicOptionsInput) (*EnablePublicOutput, error) {
+	ab, err := uc.repo.GetByID(ctx, addressBookID)
+	if err != nil || ab == nil {
+		return nil, fmt.Errorf("address book not found")
+	}
+	if ab.UserID != userID {
+		return nil, fmt.Errorf("permission denied")
+	}
+
+	ab.PublicOptions = addressbook.PublicOptions{
+		HidePhones:    input.HidePhones,
+		HideAddresses: input.HideAddresses,
+		HideEmails:    input.HideEmails,
+		HideBirthdays: input.HideBirthdays,
+		HideNotes:     input.HideNotes,
+		HidePhotos:    input.HidePhotos,
+	}
+	if err := uc.repo.Update(ctx, ab); err != nil {
+		return nil, err
+	}
+	return publicStatus(ab, uc.baseURL), nil
+}
+
+// PublicFeed is a published address book as its public token shows it
+type PublicFeed struct {
+	AddressBook *addressbook.AddressBook
+	// Cards are the published contacts and groups, ordered by name
+	Cards []vcard.Card
+	// ETag changes with the contacts and the publication settings
+	ETag string
+}
+
+type PublicFeedUseCase struct {
+	repo addressbook.Repository
+}
+
+func NewPublicFeedUseCase(repo addressbook.Repository) *PublicFeedUseCase {
+	return &PublicFeedUseCase{repo: repo}
+}
+
+// Execute opens the public feed of a token
+func (uc *PublicFeedUseCase) Execute(ctx context.Context, token string) (*PublicFeed, error) {
+	ab, err := uc.repo.FindByPublicToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if ab == nil || !ab.PublicEnabled {
+		return nil, ErrPublicFeedNotFound
+	}
+
+	contacts, _, err := uc.repo.ListObjects(ctx, ab.ID, -1, 0, "name", "asc")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch contacts: %w", err)
+	}
+
+	feed := &PublicFeed{AddressBook: ab}
+	for _, contact := range contacts {
+		// ListObjects leaves out the stored photos, so published ones are
+		// reloaded with the card
+		if !ab.PublicOptions.HidePhotos {
+			full, err := uc.repo.GetObjectByUUID(ctx, contact.UUID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to fetch contact %s: %w", contact.UID, err)
+			}
+			if full != nil {
+				contact = *full
+			}
+		}
+		card, err := vcard.NewDecoder(strings.NewReader(contact.VCardData)).Decode()
+		if err != nil {
+			continue
+		}
+		if published := addressbook.PublishCard(card, &ab.PublicOptions); published != nil {
+			feed.Cards = append(feed.Cards, published)
+		}
+	}
+
+	// Changing the options saves the address book, so UpdatedAt covers them
+	h := sha256.New()
+	fmt.Fprintf(h, "%s|%d", ab.CTag, ab.UpdatedAt.UnixNano())
+	feed.ETag = `"` + hex.EncodeToString(h.Sum(nil))[:32] + `"`
+	return feed, nil
+}
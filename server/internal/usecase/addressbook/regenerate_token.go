@@ -0,0 +1,62 @@
+package addressbook
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jherrma/caldav-server/internal/domain/addressbook"
+)
+
+type RegenerateTokenOutput struct {
+	Enabled   bool       `json:"enabled"`
+	PublicURL *string    `json:"public_url"`
+	Token     *string    `json:"token"`
+	EnabledAt *time.Time `json:"enabled_at"`
+	Message   string     `json:"message"`
+}
+
+type RegenerateTokenUseCase struct {
+	repo    addressbook.Repository
+	baseURL string
+}
+
+func NewRegenerateTokenUseCase(repo addressbook.Repository, baseURL string) *RegenerateTokenUseCase {
+	return &RegenerateTokenUseCase{
+		repo:    repo,
+		baseURL: baseURL,
+	}
+}
+
+func (uc *RegenerateTokenUseCase) Execute(ctx context.Context, userID, addressBookID uint) (*RegenerateTokenOutput, error) {
+	ab, err := uc.repo.GetByID(ctx, addressBookID)
+	if err != nil || ab == nil {
+		return nil, fmt.Errorf("address book not found")
+	}
+	if ab.UserID != userID {
+		return nil, fmt.Errorf("permission denied")
+	}
+
+	if !ab.PublicEnabled {
+		return nil, fmt.Errorf("public access is not enabled")
+	}
+
+	// Generate new token
+	token := generatePublicToken()
+	ab.PublicToken = &token
+	now := time.Now()
+	ab.PublicEnabledAt = &now
+
+	if err := uc.repo.Update(ctx, ab); err != nil {
+		return nil, err
+	}
+
+	status := publicStatus(ab, uc.baseURL)
+	return &RegenerateTokenOutput{
+		Enabled:   true,
+		PublicURL: status.PublicURL,
+		Token:     ab.PublicToken,
+		EnabledAt: ab.PublicEnabledAt,
+		Message:   "Previous public URL is no longer valid",
+	}, nil
+}
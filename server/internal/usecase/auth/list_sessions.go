@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/jherrma/caldav-server/internal/domain/user"
+)
+
+// Session describes one logged-in device, i.e. one refresh token family
+type Session struct {
+	ID         string
+	DeviceName string
+	UserAgent  string
+	IP         string
+	CreatedAt  time.Time
+	LastUsedAt time.Time
+	ExpiresAt  time.Time
+}
+
+// ListSessionsUseCase lists the active sessions of a user
+type ListSessionsUseCase struct {
+	tokenRepo user.RefreshTokenRepository
+}
+
+// NewListSessionsUseCase creates a new ListSessionsUseCase
+func NewListSessionsUseCase(tokenRepo user.RefreshTokenRepository) *ListSessionsUseCase {
+	return &ListSessionsUseCase{tokenRepo: tokenRepo}
+}
+
+// Execute returns the user's active sessions, most recently refreshed first
+func (uc *ListSessionsUseCase) Execute(ctx context.Context, userID uint) ([]Session, error) {
+	tokens, err := uc.tokenRepo.ListActiveByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]Session, 0, len(tokens))
+	for _, t := range tokens {
+		s := Session{
+			ID:         t.FamilyID,
+			DeviceName: t.DeviceName,
+			UserAgent:  t.UserAgent,
+			IP:         t.IP,
+			CreatedAt:  t.AuthenticatedAt,
+			LastUsedAt: t.CreatedAt,
+			ExpiresAt:  t.ExpiresAt,
+		}
+		if s.CreatedAt.IsZero() {
+			s.CreatedAt = t.CreatedAt
+		}
+		if t.LastUsedAt != nil {
+			s.LastUsedAt = *t.LastUsedAt
+		}
+		if s.DeviceName == "" {
+			s.DeviceName = DeviceName(t.UserAgent)
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, nil
+}
@@ -0,0 +1,234 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/jherrma/caldav-server/internal/config"
+	"github.com/jherrma/caldav-server/internal/domain/user"
+	"github.com/jherrma/caldav-server/internal/infrastructure/logging"
+)
+
+// ErrAccountLocked is returned while an account or credential is blocked
+// after repeated failed logins
+var ErrAccountLocked = errors.New("too many failed login attempts, try again later")
+
+// LockedError carries how long the caller has to wait before trying again
+type LockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockedError) Error() string { return ErrAccountLocked.Error() }
+
+func (e *LockedError) Unwrap() error { return ErrAccountLocked }
+
+// RetryAfterSeconds returns the wait time rounded up to whole seconds, as
+// used by the Retry-After header
+func (e *LockedError) RetryAfterSeconds() int {
+	return int(math.Ceil(e.RetryAfter.Seconds()))
+}
+
+// LoginSubject identifies what a failed attempt is counted against: a user
+// account (web login and DAV Basic auth with the account email) or a
+// dedicated CalDAV/CardDAV credential.
+type LoginSubject struct {
+	Type   string
+	ID     uint
+	UserID uint
+	Label  string // Shown to the owner in the lockout notification
+}
+
+// AccountSubject returns the subject for logins with the account email
+func AccountSubject(u *user.User) LoginSubject {
+	return LoginSubject{Type: user.ThrottleSubjectAccount, ID: u.ID, UserID: u.ID, Label: "Your account"}
+}
+
+// CalDAVCredentialSubject returns the subject for a CalDAV credential
+func CalDAVCredentialSubject(cred *user.CalDAVCredential) LoginSubject {
+	return LoginSubject{
+		Type:   user.ThrottleSubjectCalDAVCredential,
+		ID:     cred.ID,
+		UserID: cred.UserID,
+		Label:  fmt.Sprintf("Your CalDAV credential %q (%s)", cred.Name, cred.Username),
+	}
+}
+
+// CardDAVCredentialSubject returns the subject for a CardDAV credential
+func CardDAVCredentialSubject(cred *user.CardDAVCredential) LoginSubject {
+	return LoginSubject{
+		Type:   user.ThrottleSubjectCardDAVCredential,
+		ID:     cred.ID,
+		UserID: cred.UserID,
+		Label:  fmt.Sprintf("Your CardDAV credential %q (%s)", cred.Name, cred.Username),
+	}
+}
+
+// PublicFeedSubject returns the subject for the password of the public feed
+// of a calendar
+func PublicFeedSubject(calendarID, ownerID uint, name string) LoginSubject {
+	return LoginSubject{
+		Type:   user.ThrottleSubjectPublicFeed,
+		ID:     calendarID,
+		UserID: ownerID,
+		Label:  fmt.Sprintf("The public feed of your calendar %q", name),
+	}
+}
+
+// LockoutService throttles failed logins with exponential backoff and locks
+// the subject after too many consecutive failures. Counters are persisted so
+// they survive restarts and apply across server instances.
+//
+// A nil *LockoutService, or one with lockout disabled, allows every attempt.
+type LockoutService struct {
+	repo         user.LoginThrottleRepository
+	userRepo     user.UserRepository
+	emailService EmailService
+	cfg          config.LockoutConfig
+	logger       *logging.SecurityLogger
+	now          func() time.Time
+}
+
+// NewLockoutService creates a new lockout service
+func NewLockoutService(
+	repo user.LoginThrottleRepository,
+	userRepo user.UserRepository,
+	emailService EmailService,
+	cfg *config.Config,
+	logger *logging.SecurityLogger,
+) *LockoutService {
+	return &LockoutService{
+		repo:         repo,
+		userRepo:     userRepo,
+		emailService: emailService,
+		cfg:          cfg.Lockout,
+		logger:       logger,
+		now:          time.Now,
+	}
+}
+
+func (s *LockoutService) enabled() bool {
+	return s != nil && s.cfg.Enabled
+}
+
+// Check returns a *LockedError if the subject may not attempt to log in yet.
+// It must be called before the password is verified.
+func (s *LockoutService) Check(ctx context.Context, subject LoginSubject) error {
+	if !s.enabled() {
+		return nil
+	}
+
+	t, err := s.repo.Get(ctx, subject.Type, subject.ID)
+	if err != nil {
+		// Fail open: the IP rate limiter still applies
+		fmt.Printf("failed to load login throttle: %v\n", err)
+		return nil
+	}
+	if t == nil {
+		return nil
+	}
+
+	now := s.now()
+	if t.IsLocked(now) {
+		return &LockedError{RetryAfter: t.LockedUntil.Sub(now)}
+	}
+	return nil
+}
+
+// RecordFailure counts a failed attempt. Once the subject is past the free
+// attempts it is blocked for an exponentially growing delay; reaching the
+// maximum locks it and notifies the owner by email.
+func (s *LockoutService) RecordFailure(ctx context.Context, subject LoginSubject, ip, userAgent string) {
+	if !s.enabled() {
+		return
+	}
+
+	now := s.now()
+	existing, err := s.repo.Get(ctx, subject.Type, subject.ID)
+	if err != nil {
+		fmt.Printf("failed to load login throttle: %v\n", err)
+		return
+	}
+	if existing != nil && existing.LastFailureAt != nil && now.Sub(*existing.LastFailureAt) > s.cfg.ResetAfter {
+		if err := s.repo.Delete(ctx, subject.Type, subject.ID); err != nil {
+			fmt.Printf("failed to reset login throttle: %v\n", err)
+		}
+	}
+
+	t, err := s.repo.IncrementFailures(ctx, subject.Type, subject.ID, subject.UserID, now)
+	if err != nil {
+		fmt.Printf("failed to record failed login: %v\n", err)
+		return
+	}
+
+	delay := s.delayFor(t.FailureCount)
+	if delay <= 0 {
+		return
+	}
+	until := now.Add(delay)
+	if err := s.repo.SetLockedUntil(ctx, t.ID, &until); err != nil {
+		fmt.Printf("failed to lock login subject: %v\n", err)
+		return
+	}
+
+	if t.FailureCount == s.cfg.MaxAttempts {
+		s.logger.LogAccountLocked(ctx, subject.UserID, subject.Type, t.FailureCount, until, ip, userAgent)
+		s.notifyOwner(ctx, subject, t.FailureCount, until, ip)
+	}
+}
+
+// RecordSuccess clears the failure counter after a successful login
+func (s *LockoutService) RecordSuccess(ctx context.Context, subject LoginSubject) {
+	if !s.enabled() {
+		return
+	}
+
+	// Read first so the common case (no failures) does not write
+	t, err := s.repo.Get(ctx, subject.Type, subject.ID)
+	if err != nil || t == nil {
+		return
+	}
+	if err := s.repo.Delete(ctx, subject.Type, subject.ID); err != nil {
+		fmt.Printf("failed to reset login throttle: %v\n", err)
+	}
+}
+
+// delayFor returns how long the subject is blocked after its n-th
+// consecutive failure
+func (s *LockoutService) delayFor(failures int) time.Duration {
+	if failures >= s.cfg.MaxAttempts {
+		return s.cfg.Duration
+	}
+	if failures <= s.cfg.FreeAttempts {
+		return 0
+	}
+
+	shift := failures - s.cfg.FreeAttempts - 1
+	if shift > 30 {
+		return s.cfg.Duration
+	}
+	delay := s.cfg.BaseDelay << shift
+	if delay > s.cfg.Duration {
+		return s.cfg.Duration
+	}
+	return delay
+}
+
+func (s *LockoutService) notifyOwner(ctx context.Context, subject LoginSubject, failures int, until time.Time, ip string) {
+	owner, err := s.userRepo.GetByID(ctx, subject.UserID)
+	if err != nil || owner == nil {
+		return
+	}
+
+	body := fmt.Sprintf(
+		"%s was locked after %d failed sign-in attempts (last attempt from %s).\n\n"+
+			"Sign-in is blocked until %s. If these attempts were not you, change your password "+
+			"and review your app passwords and DAV credentials once the lock expires.\n",
+		subject.Label, failures, ip, until.UTC().Format(time.RFC1123),
+	)
+	if err := s.emailService.SendEmail(ctx, owner.Email, "Sign-in temporarily locked", body); err != nil {
+		fmt.Printf("failed to send lockout notification: %v\n", err)
+	}
+}
@@ -0,0 +1,213 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/jherrma/caldav-server/internal/config"
+	"github.com/jherrma/caldav-server/internal/domain/user"
+	"github.com/jherrma/caldav-server/internal/infrastructure/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryThrottleRepo is an in-memory user.LoginThrottleRepository
+type memoryThrottleRepo struct {
+	records map[string]*user.LoginThrottle
+	nextID  uint
+}
+
+func newMemoryThrottleRepo() *memoryThrottleRepo {
+	return &memoryThrottleRepo{records: map[string]*user.LoginThrottle{}}
+}
+
+func throttleKey(subjectType string, subjectID uint) string {
+	return fmt.Sprintf("%s:%d", subjectType, subjectID)
+}
+
+func (r *memoryThrottleRepo) Get(ctx context.Context, subjectType string, subjectID uint) (*user.LoginThrottle, error) {
+	t, ok := r.records[throttleKey(subjectType, subjectID)]
+	if !ok {
+		return nil, nil
+	}
+	c := *t
+	return &c, nil
+}
+
+func (r *memoryThrottleRepo) IncrementFailures(ctx context.Context, subjectType string, subjectID, userID uint, at time.Time) (*user.LoginThrottle, error) {
+	key := throttleKey(subjectType, subjectID)
+	t, ok := r.records[key]
+	if !ok {
+		r.nextID++
+		t = &user.LoginThrottle{ID: r.nextID, SubjectType: subjectType, SubjectID: subjectID, UserID: userID}
+		r.records[key] = t
+	}
+	t.FailureCount++
+	t.LastFailureAt = &at
+	c := *t
+	return &c, nil
+}
+
+func (r *memoryThrottleRepo) SetLockedUntil(ctx context.Context, id uint, until *time.Time) error {
+	for _, t := range r.records {
+		if t.ID == id {
+			t.LockedUntil = until
+		}
+	}
+	return nil
+}
+
+func (r *memoryThrottleRepo) Delete(ctx context.Context, subjectType string, subjectID uint) error {
+	delete(r.records, throttleKey(subjectType, subjectID))
+	return nil
+}
+
+func (r *memoryThrottleRepo) DeleteByUserID(ctx context.Context, userID uint) (int64, error) {
+	var n int64
+	for key, t := range r.records {
+		if t.UserID == userID {
+			delete(r.records, key)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func newTestLockoutService(repo user.LoginThrottleRepository, userRepo *mockUserRepo, emailSvc *mockEmailService, clock *time.Time) *LockoutService {
+	cfg := &config.Config{Lockout: config.LockoutConfig{
+		Enabled:      true,
+		FreeAttempts: 3,
+		BaseDelay:    time.Second,
+		MaxAttempts:  8,
+		Duration:     15 * time.Minute,
+		ResetAfter:   time.Hour,
+	}}
+	logger := logging.NewSecurityLogger(slog.New(slog.NewJSONHandler(io.Discard, nil)))
+	s := NewLockoutService(repo, userRepo, emailSvc, cfg, logger)
+	s.now = func() time.Time { return *clock }
+	return s
+}
+
+func TestLockoutService_DelayFor(t *testing.T) {
+	now := time.Now()
+	s := newTestLockoutService(newMemoryThrottleRepo(), nil, nil, &now)
+
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{1, 0},
+		{3, 0},
+		{4, time.Second},
+		{5, 2 * time.Second},
+		{7, 8 * time.Second},
+		{8, 15 * time.Minute},
+		{50, 15 * time.Minute},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, s.delayFor(tt.failures), "failures=%d", tt.failures)
+	}
+}
+
+func TestLockoutService_BackoffAndLockout(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	userRepo := new(mockUserRepo)
+	emailSvc := new(mockEmailService)
+	s := newTestLockoutService(newMemoryThrottleRepo(), userRepo, emailSvc, &now)
+
+	owner := &user.User{ID: 1, Email: "owner@example.com"}
+	subject := AccountSubject(owner)
+
+	for i := 0; i < 3; i++ {
+		s.RecordFailure(ctx, subject, "10.0.0.1", "curl")
+	}
+	require.NoError(t, s.Check(ctx, subject), "free attempts must not block")
+
+	s.RecordFailure(ctx, subject, "10.0.0.1", "curl")
+	err := s.Check(ctx, subject)
+	var locked *LockedError
+	require.True(t, errors.As(err, &locked))
+	assert.True(t, errors.Is(err, ErrAccountLocked))
+	assert.Equal(t, 1, locked.RetryAfterSeconds())
+
+	now = now.Add(time.Second)
+	require.NoError(t, s.Check(ctx, subject), "delay expired")
+
+	// Reaching the maximum locks the account and notifies the owner once
+	userRepo.On("GetByID", ctx, uint(1)).Return(owner, nil).Once()
+	emailSvc.On("SendEmail", ctx, "owner@example.com", "Sign-in temporarily locked", mock.MatchedBy(func(body string) bool {
+		return assert.Contains(t, body, "8 failed sign-in attempts") && assert.Contains(t, body, "10.0.0.1")
+	})).Return(nil).Once()
+
+	for i := 0; i < 4; i++ {
+		s.RecordFailure(ctx, subject, "10.0.0.1", "curl")
+	}
+	err = s.Check(ctx, subject)
+	require.True(t, errors.As(err, &locked))
+	assert.Equal(t, 15*time.Minute, locked.RetryAfter)
+
+	userRepo.AssertExpectations(t)
+	emailSvc.AssertExpectations(t)
+}
+
+func TestLockoutService_SuccessResetsCounter(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	repo := newMemoryThrottleRepo()
+	s := newTestLockoutService(repo, nil, nil, &now)
+	subject := LoginSubject{Type: user.ThrottleSubjectCalDAVCredential, ID: 2, UserID: 1}
+
+	for i := 0; i < 3; i++ {
+		s.RecordFailure(ctx, subject, "10.0.0.1", "")
+	}
+	s.RecordSuccess(ctx, subject)
+
+	s.RecordFailure(ctx, subject, "10.0.0.1", "")
+	t1, _ := repo.Get(ctx, subject.Type, subject.ID)
+	require.NotNil(t, t1)
+	assert.Equal(t, 1, t1.FailureCount)
+	assert.NoError(t, s.Check(ctx, subject))
+}
+
+func TestLockoutService_OldFailuresAreForgotten(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	repo := newMemoryThrottleRepo()
+	s := newTestLockoutService(repo, nil, nil, &now)
+	subject := LoginSubject{Type: user.ThrottleSubjectAccount, ID: 1, UserID: 1}
+
+	for i := 0; i < 3; i++ {
+		s.RecordFailure(ctx, subject, "10.0.0.1", "")
+	}
+	now = now.Add(2 * time.Hour)
+	s.RecordFailure(ctx, subject, "10.0.0.1", "")
+
+	assert.NoError(t, s.Check(ctx, subject))
+	t1, _ := repo.Get(ctx, subject.Type, subject.ID)
+	assert.Equal(t, 1, t1.FailureCount)
+}
+
+func TestLockoutService_Disabled(t *testing.T) {
+	ctx := context.Background()
+	subject := LoginSubject{Type: user.ThrottleSubjectAccount, ID: 1, UserID: 1}
+
+	var nilService *LockoutService
+	nilService.RecordFailure(ctx, subject, "", "")
+	assert.NoError(t, nilService.Check(ctx, subject))
+
+	repo := newMemoryThrottleRepo()
+	cfg := &config.Config{Lockout: config.LockoutConfig{Enabled: false}}
+	s := NewLockoutService(repo, nil, nil, cfg, nil)
+	for i := 0; i < 20; i++ {
+		s.RecordFailure(ctx, subject, "", "")
+	}
+	assert.NoError(t, s.Check(ctx, subject))
+	assert.Empty(t, repo.records)
+}
@@ -23,6 +23,7 @@ type LoginUseCase struct {
 	userRepo   user.UserRepository
 	tokenRepo  user.RefreshTokenRepository
 	jwtManager user.TokenProvider
+	lockout    *LockoutService
 	cfg        *config.Config
 	logger     *logging.SecurityLogger
 }
@@ -40,6 +41,7 @@ func NewLoginUseCase(
 	userRepo user.UserRepository,
 	tokenRepo user.RefreshTokenRepository,
 	jwtManager user.TokenProvider,
+	lockout *LockoutService,
 	cfg *config.Config,
 	logger *logging.SecurityLogger,
 ) *LoginUseCase {
@@ -47,6 +49,7 @@ func NewLoginUseCase(
 		userRepo:   userRepo,
 		tokenRepo:  tokenRepo,
 		jwtManager: jwtManager,
+		lockout:    lockout,
 		cfg:        cfg,
 		logger:     logger,
 	}
@@ -66,10 +69,20 @@ func (uc *LoginUseCase) Execute(ctx context.Context, email, password string, use
 		return nil, ErrInvalidCredentials
 	}
 
+	// Locked accounts are rejected before the password is checked, so a
+	// correct guess during the lockout does not reveal itself
+	subject := AccountSubject(u)
+	if err := uc.lockout.Check(ctx, subject); err != nil {
+		uc.logger.LogLoginAttempt(ctx, email, ip, userAgent, false, "account_locked")
+		return nil, err
+	}
+
 	if err := bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)); err != nil {
+		uc.lockout.RecordFailure(ctx, subject, ip, userAgent)
 		uc.logger.LogLoginAttempt(ctx, email, ip, userAgent, false, "invalid_password")
 		return nil, ErrInvalidCredentials
 	}
+	uc.lockout.RecordSuccess(ctx, subject)
 
 	if !u.IsActive {
 		uc.logger.LogLoginAttempt(ctx, email, ip, userAgent, false, "account_inactive")
@@ -83,23 +96,9 @@ func (uc *LoginUseCase) Execute(ctx context.Context, email, password string, use
 		return nil, fmt.Errorf("failed to generate access token: %w", err)
 	}
 
-	refreshToken, err := uc.jwtManager.GenerateRefreshToken()
+	refreshToken, err := startSession(ctx, uc.tokenRepo, uc.jwtManager, u.ID, uc.cfg.JWT.RefreshExpiry, userAgent, ip)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
-	}
-
-	hash := uc.jwtManager.HashToken(refreshToken)
-
-	rt := &user.RefreshToken{
-		UserID:    u.ID,
-		TokenHash: hash,
-		ExpiresAt: time.Now().Add(uc.cfg.JWT.RefreshExpiry),
-		UserAgent: userAgent,
-		IP:        ip,
-	}
-
-	if err := uc.tokenRepo.Create(ctx, rt); err != nil {
-		return nil, fmt.Errorf("failed to store refresh token: %w", err)
+		return nil, err
 	}
 
 	return &LoginResult{
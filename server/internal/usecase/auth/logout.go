@@ -17,8 +17,17 @@ func NewLogoutUseCase(tokenRepo user.RefreshTokenRepository, jwtManager user.Tok
 	return &LogoutUseCase{tokenRepo: tokenRepo, jwtManager: jwtManager}
 }
 
-// Execute performs the logout logic by revoking the refresh token
+// Execute performs the logout logic by deleting every token of the session
+// the refresh token belongs to
 func (uc *LogoutUseCase) Execute(ctx context.Context, refreshToken string) error {
 	hash := uc.jwtManager.HashToken(refreshToken)
-	return uc.tokenRepo.DeleteByHash(ctx, hash)
+
+	t, err := uc.tokenRepo.GetByHash(ctx, hash)
+	if err != nil {
+		return err
+	}
+	if t == nil {
+		return nil
+	}
+	return uc.tokenRepo.DeleteByFamily(ctx, t.UserID, t.FamilyID)
 }
@@ -2,6 +2,7 @@ package auth
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -12,6 +13,12 @@ import (
 	"golang.org/x/oauth2"
 )
 
+var (
+	ErrOAuthDomainNotAllowed     = errors.New("email domain is not allowed for this provider")
+	ErrOAuthRegistrationDisabled = errors.New("no account exists for this identity and automatic registration is disabled")
+	ErrOAuthEmailNotVerified     = errors.New("an account with this email already exists, but the provider has not verified the email")
+)
+
 // OAuthCallbackUseCase handles the OAuth callback and user login/creation
 type OAuthCallbackUseCase struct {
 	providerManager  authadapter.OAuthProviderManager
@@ -61,6 +68,11 @@ func (uc *OAuthCallbackUseCase) Execute(ctx context.Context, providerName, code,
 		return nil, fmt.Errorf("failed to get user info: %w", err)
 	}
 
+	policy := provider.Policy()
+	if !policy.AllowsEmail(userInfo.Email) {
+		return nil, ErrOAuthDomainNotAllowed
+	}
+
 	existingUser, err := uc.userRepo.GetByOAuth(ctx, providerName, userInfo.Subject)
 	if err != nil {
 		return nil, err
@@ -109,10 +121,18 @@ func (uc *OAuthCallbackUseCase) Execute(ctx context.Context, providerName, code,
 		}
 
 		if u != nil {
+			// Only an email the provider vouches for may take over an
+			// existing account; anyone can claim an address at some IdPs
+			if !policy.EmailVerified(userInfo) {
+				return nil, ErrOAuthEmailNotVerified
+			}
 			if err := uc.linkProvider(ctx, u.ID, providerName, userInfo, token.AccessToken, token.RefreshToken, token.Expiry); err != nil {
 				return nil, err
 			}
 		} else {
+			if !policy.AutoRegister {
+				return nil, ErrOAuthRegistrationDisabled
+			}
 			u, err = uc.createUser(ctx, userInfo)
 			if err != nil {
 				return nil, err
@@ -129,22 +149,9 @@ func (uc *OAuthCallbackUseCase) Execute(ctx context.Context, providerName, code,
 		return nil, fmt.Errorf("failed to generate access token: %w", err)
 	}
 
-	refreshToken, err := uc.tokenProvider.GenerateRefreshToken()
+	refreshToken, err := startSession(ctx, uc.refreshTokenRepo, uc.tokenProvider, u.ID, uc.config.JWT.RefreshExpiry, userAgent, ip)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
-	}
-
-	hash := uc.tokenProvider.HashToken(refreshToken)
-	rt := &user.RefreshToken{
-		UserID:    u.ID,
-		TokenHash: hash,
-		ExpiresAt: time.Now().Add(uc.config.JWT.RefreshExpiry),
-		UserAgent: userAgent,
-		IP:        ip,
-	}
-
-	if err := uc.refreshTokenRepo.Create(ctx, rt); err != nil {
-		return nil, fmt.Errorf("failed to store refresh token: %w", err)
+		return nil, err
 	}
 
 	return &LoginResult{
@@ -161,15 +168,15 @@ func (uc *OAuthCallbackUseCase) linkProvider(ctx context.Context, userID uint, p
 		Provider:      providerName,
 		ProviderID:    userInfo.Subject,
 		ProviderEmail: userInfo.Email,
-		AccessToken:   accessToken,  // Should be encrypted
-		RefreshToken:  refreshToken, // Should be encrypted
+		AccessToken:   accessToken,
+		RefreshToken:  refreshToken,
 		TokenExpiry:   &expiry,
 	}
 	return uc.oauthRepo.Create(ctx, conn)
 }
 
 func (uc *OAuthCallbackUseCase) createUser(ctx context.Context, userInfo *authadapter.UserInfo) (*user.User, error) {
-	username, err := GenerateUniqueUsername(ctx, uc.userRepo)
+	username, err := uc.pickUsername(ctx, userInfo.Username)
 	if err != nil {
 		return nil, err
 	}
@@ -194,3 +201,18 @@ func (uc *OAuthCallbackUseCase) createUser(ctx context.Context, userInfo *authad
 	}
 	return u, nil
 }
+
+// pickUsername uses the username claimed by the provider when it is valid and
+// still free, and a generated one otherwise
+func (uc *OAuthCallbackUseCase) pickUsername(ctx context.Context, claimed string) (string, error) {
+	if user.ValidateUsername(claimed) == nil {
+		existing, err := uc.userRepo.GetByUsername(ctx, claimed)
+		if err != nil {
+			return "", err
+		}
+		if existing == nil {
+			return claimed, nil
+		}
+	}
+	return GenerateUniqueUsername(ctx, uc.userRepo)
+}
@@ -34,6 +34,7 @@ func (m *mockOAuthProviderManager) ListProviders() []string {
 
 type mockOAuthProvider struct {
 	mock.Mock
+	policy authadapter.ProviderPolicy
 }
 
 func (m *mockOAuthProvider) Name() string {
@@ -41,6 +42,12 @@ func (m *mockOAuthProvider) Name() string {
 	return args.String(0)
 }
 
+func (m *mockOAuthProvider) DisplayName() string { return "Mock" }
+
+func (m *mockOAuthProvider) Icon() string { return "" }
+
+func (m *mockOAuthProvider) Policy() authadapter.ProviderPolicy { return m.policy }
+
 func (m *mockOAuthProvider) AuthCodeURL(state string, opts ...oauth2.AuthCodeOption) string {
 	args := m.Called(state, opts)
 	return args.String(0)
@@ -121,6 +128,31 @@ func (m *mockRefreshTokenRepo) DeleteByUserID(ctx context.Context, userID uint)
 	return args.Error(0)
 }
 
+func (m *mockRefreshTokenRepo) DeleteByFamily(ctx context.Context, userID uint, familyID string) error {
+	args := m.Called(ctx, userID, familyID)
+	return args.Error(0)
+}
+
+func (m *mockRefreshTokenRepo) ListActiveByUserID(ctx context.Context, userID uint) ([]user.RefreshToken, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]user.RefreshToken), args.Error(1)
+}
+
+func (m *mockRefreshTokenRepo) MarkRotated(ctx context.Context, id uint) (bool, error) {
+	args := m.Called(ctx, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *mockRefreshTokenRepo) RevokeFamily(ctx context.Context, userID uint, familyID string) (int64, error) {
+	args := m.Called(ctx, userID, familyID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *mockRefreshTokenRepo) RevokeAllExcept(ctx context.Context, userID uint, familyID string) (int64, error) {
+	args := m.Called(ctx, userID, familyID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
 type mockTokenProvider struct {
 	mock.Mock
 }
@@ -200,7 +232,7 @@ func TestOAuthCallbackUseCase_Execute_LinkNewUser(t *testing.T) {
 	userAgent := "test-agent"
 	ip := "127.0.0.1"
 
-	provider := new(mockOAuthProvider)
+	provider := &mockOAuthProvider{policy: authadapter.ProviderPolicy{AutoRegister: true}}
 	token := &oauth2.Token{AccessToken: "access_token"}
 	userInfo := &authadapter.UserInfo{Subject: "sub123", Email: "new@example.com", Name: "New User"}
 
@@ -333,3 +365,137 @@ func TestOAuthCallbackUseCase_Execute_LinkAlreadyLinkedSuccess(t *testing.T) {
 	assert.Nil(t, result) // Success returns nil result for linking
 	oauthRepo.AssertExpectations(t)
 }
+
+func TestOAuthCallbackUseCase_Execute_ClaimedUsername(t *testing.T) {
+	providerManager := new(mockOAuthProviderManager)
+	userRepo := new(mockUserRepo)
+	oauthRepo := new(mockOAuthRepo)
+	refreshTokenRepo := new(mockRefreshTokenRepo)
+	tokenProvider := new(mockTokenProvider)
+	cfg := &config.Config{JWT: config.JWTConfig{RefreshExpiry: time.Hour}}
+
+	uc := NewOAuthCallbackUseCase(providerManager, userRepo, oauthRepo, refreshTokenRepo, tokenProvider, cfg)
+
+	ctx := context.Background()
+	providerName := "keycloak"
+	code := "auth_code"
+
+	provider := &mockOAuthProvider{policy: authadapter.ProviderPolicy{AutoRegister: true}}
+	token := &oauth2.Token{AccessToken: "access_token"}
+	userInfo := &authadapter.UserInfo{Subject: "sub123", Email: "jdoe@corp.example", Name: "Jane Doe", Username: "jdoe"}
+
+	providerManager.On("GetProvider", providerName).Return(provider, nil)
+	provider.On("Exchange", ctx, code).Return(token, nil)
+	provider.On("UserInfo", ctx, mock.Anything).Return(userInfo, nil)
+	userRepo.On("GetByOAuth", ctx, providerName, userInfo.Subject).Return(nil, nil)
+	userRepo.On("GetByEmail", ctx, userInfo.Email).Return(nil, nil)
+	userRepo.On("GetByUsername", ctx, "jdoe").Return(nil, nil)
+	userRepo.On("Create", ctx, mock.MatchedBy(func(u *user.User) bool {
+		return u.Username == "jdoe"
+	})).Return(nil)
+	oauthRepo.On("Create", ctx, mock.Anything).Return(nil)
+	tokenProvider.On("GenerateAccessToken", mock.Anything, userInfo.Email).Return("jwt_access", time.Now().Add(time.Hour), nil)
+	tokenProvider.On("GenerateRefreshToken").Return("jwt_refresh", nil)
+	tokenProvider.On("HashToken", "jwt_refresh").Return("hashed_refresh")
+	refreshTokenRepo.On("Create", ctx, mock.Anything).Return(nil)
+
+	result, err := uc.Execute(ctx, providerName, code, "", "", nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "jdoe", result.User.Username)
+}
+
+func TestOAuthCallbackUseCase_Execute_DomainNotAllowed(t *testing.T) {
+	providerManager := new(mockOAuthProviderManager)
+	userRepo := new(mockUserRepo)
+	cfg := &config.Config{JWT: config.JWTConfig{RefreshExpiry: time.Hour}}
+
+	uc := NewOAuthCallbackUseCase(providerManager, userRepo, new(mockOAuthRepo), new(mockRefreshTokenRepo), new(mockTokenProvider), cfg)
+
+	ctx := context.Background()
+	provider := &mockOAuthProvider{policy: authadapter.ProviderPolicy{AllowedDomains: []string{"corp.example"}, AutoRegister: true}}
+	userInfo := &authadapter.UserInfo{Subject: "sub123", Email: "guest@partner.example"}
+
+	providerManager.On("GetProvider", "keycloak").Return(provider, nil)
+	provider.On("Exchange", ctx, "auth_code").Return(&oauth2.Token{AccessToken: "access_token"}, nil)
+	provider.On("UserInfo", ctx, mock.Anything).Return(userInfo, nil)
+
+	_, err := uc.Execute(ctx, "keycloak", "auth_code", "", "", nil)
+
+	assert.ErrorIs(t, err, ErrOAuthDomainNotAllowed)
+	userRepo.AssertNotCalled(t, "GetByOAuth", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestOAuthCallbackUseCase_Execute_RegistrationDisabled(t *testing.T) {
+	providerManager := new(mockOAuthProviderManager)
+	userRepo := new(mockUserRepo)
+	cfg := &config.Config{JWT: config.JWTConfig{RefreshExpiry: time.Hour}}
+
+	uc := NewOAuthCallbackUseCase(providerManager, userRepo, new(mockOAuthRepo), new(mockRefreshTokenRepo), new(mockTokenProvider), cfg)
+
+	ctx := context.Background()
+	provider := &mockOAuthProvider{policy: authadapter.ProviderPolicy{AllowedDomains: []string{"CORP.example"}}}
+	userInfo := &authadapter.UserInfo{Subject: "sub123", Email: "new@corp.example"}
+
+	providerManager.On("GetProvider", "keycloak").Return(provider, nil)
+	provider.On("Exchange", ctx, "auth_code").Return(&oauth2.Token{AccessToken: "access_token"}, nil)
+	provider.On("UserInfo", ctx, mock.Anything).Return(userInfo, nil)
+	userRepo.On("GetByOAuth", ctx, "keycloak", userInfo.Subject).Return(nil, nil)
+	userRepo.On("GetByEmail", ctx, userInfo.Email).Return(nil, nil)
+
+	_, err := uc.Execute(ctx, "keycloak", "auth_code", "", "", nil)
+
+	assert.ErrorIs(t, err, ErrOAuthRegistrationDisabled)
+	userRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestOAuthCallbackUseCase_Execute_UnverifiedEmail(t *testing.T) {
+	ctx := context.Background()
+	existingUser := &user.User{ID: 1, UUID: "uuid1", Email: "victim@example.com"}
+
+	for _, tc := range []struct {
+		name     string
+		verified bool
+		trust    bool
+		linked   bool
+	}{
+		{"Unverified", false, false, false},
+		{"Verified", true, false, true},
+		{"Trusted Provider", false, true, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			providerManager := new(mockOAuthProviderManager)
+			userRepo := new(mockUserRepo)
+			oauthRepo := new(mockOAuthRepo)
+			refreshTokenRepo := new(mockRefreshTokenRepo)
+			tokenProvider := new(mockTokenProvider)
+			cfg := &config.Config{JWT: config.JWTConfig{RefreshExpiry: time.Hour}}
+			uc := NewOAuthCallbackUseCase(providerManager, userRepo, oauthRepo, refreshTokenRepo, tokenProvider, cfg)
+
+			provider := &mockOAuthProvider{policy: authadapter.ProviderPolicy{AutoRegister: true, TrustEmail: tc.trust}}
+			userInfo := &authadapter.UserInfo{Subject: "attacker", Email: existingUser.Email, EmailVerified: tc.verified}
+
+			providerManager.On("GetProvider", "partner").Return(provider, nil)
+			provider.On("Exchange", ctx, "auth_code").Return(&oauth2.Token{AccessToken: "access_token"}, nil)
+			provider.On("UserInfo", ctx, mock.Anything).Return(userInfo, nil)
+			userRepo.On("GetByOAuth", ctx, "partner", userInfo.Subject).Return(nil, nil)
+			userRepo.On("GetByEmail", ctx, userInfo.Email).Return(existingUser, nil)
+			oauthRepo.On("Create", ctx, mock.Anything).Return(nil)
+			tokenProvider.On("GenerateAccessToken", existingUser.UUID, existingUser.Email).Return("jwt_access", time.Now().Add(time.Hour), nil)
+			tokenProvider.On("GenerateRefreshToken").Return("jwt_refresh", nil)
+			tokenProvider.On("HashToken", "jwt_refresh").Return("hashed_refresh")
+			refreshTokenRepo.On("Create", ctx, mock.Anything).Return(nil)
+
+			result, err := uc.Execute(ctx, "partner", "auth_code", "", "", nil)
+
+			if !tc.linked {
+				assert.ErrorIs(t, err, ErrOAuthEmailNotVerified)
+				oauthRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, existingUser, result.User)
+			oauthRepo.AssertCalled(t, "Create", ctx, mock.Anything)
+		})
+	}
+}
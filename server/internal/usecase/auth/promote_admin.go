@@ -0,0 +1,44 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/jherrma/caldav-server/internal/domain/user"
+)
+
+// PromoteAdminUseCase grants administrator rights to an existing account.
+// Registration only makes the very first account an administrator, so
+// instances created before administrators existed use this to get one.
+type PromoteAdminUseCase struct {
+	userRepo user.UserRepository
+}
+
+// NewPromoteAdminUseCase creates a new promote admin use case
+func NewPromoteAdminUseCase(userRepo user.UserRepository) *PromoteAdminUseCase {
+	return &PromoteAdminUseCase{userRepo: userRepo}
+}
+
+// Execute promotes the user with the given email address or username
+func (uc *PromoteAdminUseCase) Execute(ctx context.Context, emailOrUsername string) (*user.User, error) {
+	u, err := uc.userRepo.GetByEmail(ctx, emailOrUsername)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		if u, err = uc.userRepo.GetByUsername(ctx, emailOrUsername); err != nil {
+			return nil, err
+		}
+	}
+	if u == nil {
+		return nil, ErrUserNotFound
+	}
+	if u.IsAdmin {
+		return u, nil
+	}
+
+	u.IsAdmin = true
+	if err := uc.userRepo.Update(ctx, u); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
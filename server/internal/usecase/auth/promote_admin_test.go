@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jherrma/caldav-server/internal/domain/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPromoteAdminUseCase(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("By Username", func(t *testing.T) {
+		repo := new(mockUserRepo)
+		uc := NewPromoteAdminUseCase(repo)
+		u := &user.User{ID: 1, Email: "alice@example.com", Username: "alice"}
+
+		repo.On("GetByEmail", ctx, "alice").Return(nil, nil)
+		repo.On("GetByUsername", ctx, "alice").Return(u, nil)
+		repo.On("Update", ctx, mock.MatchedBy(func(u *user.User) bool { return u.IsAdmin })).Return(nil)
+
+		promoted, err := uc.Execute(ctx, "alice")
+		require.NoError(t, err)
+		assert.True(t, promoted.IsAdmin)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("Already Admin", func(t *testing.T) {
+		repo := new(mockUserRepo)
+		uc := NewPromoteAdminUseCase(repo)
+		u := &user.User{ID: 1, Email: "alice@example.com", IsAdmin: true}
+
+		repo.On("GetByEmail", ctx, u.Email).Return(u, nil)
+
+		_, err := uc.Execute(ctx, u.Email)
+		require.NoError(t, err)
+		repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Unknown User", func(t *testing.T) {
+		repo := new(mockUserRepo)
+		uc := NewPromoteAdminUseCase(repo)
+
+		repo.On("GetByEmail", ctx, "nobody").Return(nil, nil)
+		repo.On("GetByUsername", ctx, "nobody").Return(nil, nil)
+
+		_, err := uc.Execute(ctx, "nobody")
+		assert.ErrorIs(t, err, ErrUserNotFound)
+	})
+}
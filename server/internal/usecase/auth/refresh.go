@@ -6,32 +6,50 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/jherrma/caldav-server/internal/config"
 	"github.com/jherrma/caldav-server/internal/domain/user"
+	"github.com/jherrma/caldav-server/internal/infrastructure/logging"
 )
 
 var (
 	ErrInvalidRefreshToken = errors.New("invalid refresh token")
 )
 
-// RefreshUseCase handles token refresh
+// RefreshUseCase handles token refresh with refresh token rotation
 type RefreshUseCase struct {
 	tokenRepo  user.RefreshTokenRepository
 	jwtManager user.TokenProvider
+	cfg        *config.Config
+	logger     *logging.SecurityLogger
 }
 
-// RefreshResult contains the new access token
+// RefreshResult contains the new access token and the rotated refresh token
 type RefreshResult struct {
-	AccessToken string
-	ExpiresAt   time.Time
+	AccessToken  string
+	RefreshToken string
+	ExpiresAt    time.Time
 }
 
 // NewRefreshUseCase creates a new refresh use case
-func NewRefreshUseCase(tokenRepo user.RefreshTokenRepository, jwtManager user.TokenProvider) *RefreshUseCase {
-	return &RefreshUseCase{tokenRepo: tokenRepo, jwtManager: jwtManager}
+func NewRefreshUseCase(
+	tokenRepo user.RefreshTokenRepository,
+	jwtManager user.TokenProvider,
+	cfg *config.Config,
+	logger *logging.SecurityLogger,
+) *RefreshUseCase {
+	return &RefreshUseCase{
+		tokenRepo:  tokenRepo,
+		jwtManager: jwtManager,
+		cfg:        cfg,
+		logger:     logger,
+	}
 }
 
-// Execute performs the refresh logic
-func (uc *RefreshUseCase) Execute(ctx context.Context, refreshToken string) (*RefreshResult, error) {
+// Execute exchanges a refresh token for a new access token and a new refresh
+// token. The presented token is revoked. Presenting a token that was already
+// rotated revokes its whole family, logging out both the legitimate client
+// and whoever replayed the token.
+func (uc *RefreshUseCase) Execute(ctx context.Context, refreshToken, userAgent, ip string) (*RefreshResult, error) {
 	hash := uc.jwtManager.HashToken(refreshToken)
 
 	t, err := uc.tokenRepo.GetByHash(ctx, hash)
@@ -42,17 +60,67 @@ func (uc *RefreshUseCase) Execute(ctx context.Context, refreshToken string) (*Re
 		return nil, ErrInvalidRefreshToken
 	}
 
-	if t.RevokedAt != nil || t.ExpiresAt.Before(time.Now()) {
+	if t.RevokedAt != nil {
+		return nil, uc.revokeReusedFamily(ctx, t, userAgent, ip)
+	}
+	if t.ExpiresAt.Before(time.Now()) {
 		return nil, ErrInvalidRefreshToken
 	}
 
+	// Conditional revoke: if a concurrent request rotated this token first,
+	// this is a replay as well.
+	rotated, err := uc.tokenRepo.MarkRotated(ctx, t.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+	if !rotated {
+		return nil, uc.revokeReusedFamily(ctx, t, userAgent, ip)
+	}
+
+	next, err := uc.jwtManager.GenerateRefreshToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+
+	now := time.Now()
+	successor := &user.RefreshToken{
+		UserID:          t.UserID,
+		FamilyID:        t.FamilyID,
+		TokenHash:       uc.jwtManager.HashToken(next),
+		ExpiresAt:       now.Add(uc.cfg.JWT.RefreshExpiry),
+		DeviceName:      t.DeviceName,
+		UserAgent:       truncate(userAgent, 500),
+		IP:              ip,
+		AuthenticatedAt: t.AuthenticatedAt,
+		LastUsedAt:      &now,
+	}
+	if successor.AuthenticatedAt.IsZero() {
+		successor.AuthenticatedAt = t.CreatedAt
+	}
+	if successor.DeviceName == "" {
+		successor.DeviceName = DeviceName(userAgent)
+	}
+
+	if err := uc.tokenRepo.Create(ctx, successor); err != nil {
+		return nil, fmt.Errorf("failed to store refresh token: %w", err)
+	}
+
 	accessToken, expiresAt, err := uc.jwtManager.GenerateAccessToken(t.User.UUID, t.User.Email)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate access token: %w", err)
 	}
 
 	return &RefreshResult{
-		AccessToken: accessToken,
-		ExpiresAt:   expiresAt,
+		AccessToken:  accessToken,
+		RefreshToken: next,
+		ExpiresAt:    expiresAt,
 	}, nil
 }
+
+func (uc *RefreshUseCase) revokeReusedFamily(ctx context.Context, t *user.RefreshToken, userAgent, ip string) error {
+	if _, err := uc.tokenRepo.RevokeFamily(ctx, t.UserID, t.FamilyID); err != nil {
+		return fmt.Errorf("failed to revoke token family: %w", err)
+	}
+	uc.logger.LogRefreshTokenReuse(ctx, t.UserID, t.FamilyID, ip, userAgent)
+	return user.ErrRefreshTokenReused
+}
@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jherrma/caldav-server/internal/config"
+	"github.com/jherrma/caldav-server/internal/domain/user"
+	"github.com/jherrma/caldav-server/internal/infrastructure/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRefreshUseCase(tokenRepo *mockRefreshTokenRepo, tokenProvider *mockTokenProvider) *RefreshUseCase {
+	cfg := &config.Config{JWT: config.JWTConfig{RefreshExpiry: time.Hour}}
+	logger := logging.NewSecurityLogger(slog.New(slog.NewJSONHandler(io.Discard, nil)))
+	return NewRefreshUseCase(tokenRepo, tokenProvider, cfg, logger)
+}
+
+func TestRefreshUseCase_Execute_RotatesToken(t *testing.T) {
+	tokenRepo := new(mockRefreshTokenRepo)
+	tokenProvider := new(mockTokenProvider)
+	uc := newTestRefreshUseCase(tokenRepo, tokenProvider)
+	ctx := context.Background()
+
+	loginTime := time.Now().Add(-24 * time.Hour)
+	current := &user.RefreshToken{
+		ID:              7,
+		UserID:          1,
+		FamilyID:        "family-1",
+		ExpiresAt:       time.Now().Add(time.Hour),
+		DeviceName:      "Firefox on Linux",
+		AuthenticatedAt: loginTime,
+		User:            user.User{ID: 1, UUID: "user-uuid", Email: "user@example.com"},
+	}
+
+	tokenProvider.On("HashToken", "old-token").Return("old-hash")
+	tokenProvider.On("HashToken", "new-token").Return("new-hash")
+	tokenProvider.On("GenerateRefreshToken").Return("new-token", nil)
+	tokenProvider.On("GenerateAccessToken", "user-uuid", "user@example.com").Return("access", time.Now().Add(time.Hour), nil)
+	tokenRepo.On("GetByHash", ctx, "old-hash").Return(current, nil)
+	tokenRepo.On("MarkRotated", ctx, uint(7)).Return(true, nil)
+	tokenRepo.On("Create", ctx, mock.MatchedBy(func(rt *user.RefreshToken) bool {
+		return rt.FamilyID == "family-1" &&
+			rt.TokenHash == "new-hash" &&
+			rt.DeviceName == "Firefox on Linux" &&
+			rt.AuthenticatedAt.Equal(loginTime) &&
+			rt.IP == "10.0.0.1"
+	})).Return(nil)
+
+	res, err := uc.Execute(ctx, "old-token", "Mozilla/5.0 (X11; Linux x86_64) Firefox/128.0", "10.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, "access", res.AccessToken)
+	assert.Equal(t, "new-token", res.RefreshToken)
+
+	tokenRepo.AssertExpectations(t)
+	tokenRepo.AssertNotCalled(t, "RevokeFamily", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRefreshUseCase_Execute_ReuseRevokesFamily(t *testing.T) {
+	tokenRepo := new(mockRefreshTokenRepo)
+	tokenProvider := new(mockTokenProvider)
+	uc := newTestRefreshUseCase(tokenRepo, tokenProvider)
+	ctx := context.Background()
+
+	rotatedAt := time.Now().Add(-time.Minute)
+	rotated := &user.RefreshToken{
+		ID:        7,
+		UserID:    1,
+		FamilyID:  "family-1",
+		ExpiresAt: time.Now().Add(time.Hour),
+		RevokedAt: &rotatedAt,
+	}
+
+	tokenProvider.On("HashToken", "old-token").Return("old-hash")
+	tokenRepo.On("GetByHash", ctx, "old-hash").Return(rotated, nil)
+	tokenRepo.On("RevokeFamily", ctx, uint(1), "family-1").Return(int64(1), nil)
+
+	res, err := uc.Execute(ctx, "old-token", "", "10.0.0.2")
+	assert.Nil(t, res)
+	assert.ErrorIs(t, err, user.ErrRefreshTokenReused)
+
+	tokenRepo.AssertExpectations(t)
+	tokenRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestRefreshUseCase_Execute_ConcurrentRotationRevokesFamily(t *testing.T) {
+	tokenRepo := new(mockRefreshTokenRepo)
+	tokenProvider := new(mockTokenProvider)
+	uc := newTestRefreshUseCase(tokenRepo, tokenProvider)
+	ctx := context.Background()
+
+	current := &user.RefreshToken{
+		ID:        7,
+		UserID:    1,
+		FamilyID:  "family-1",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	tokenProvider.On("HashToken", "old-token").Return("old-hash")
+	tokenRepo.On("GetByHash", ctx, "old-hash").Return(current, nil)
+	tokenRepo.On("MarkRotated", ctx, uint(7)).Return(false, nil)
+	tokenRepo.On("RevokeFamily", ctx, uint(1), "family-1").Return(int64(1), nil)
+
+	_, err := uc.Execute(ctx, "old-token", "", "10.0.0.2")
+	assert.ErrorIs(t, err, user.ErrRefreshTokenReused)
+	tokenRepo.AssertExpectations(t)
+}
+
+func TestRefreshUseCase_Execute_Expired(t *testing.T) {
+	tokenRepo := new(mockRefreshTokenRepo)
+	tokenProvider := new(mockTokenProvider)
+	uc := newTestRefreshUseCase(tokenRepo, tokenProvider)
+	ctx := context.Background()
+
+	expired := &user.RefreshToken{ID: 7, UserID: 1, FamilyID: "family-1", ExpiresAt: time.Now().Add(-time.Minute)}
+	tokenProvider.On("HashToken", "old-token").Return("old-hash")
+	tokenRepo.On("GetByHash", ctx, "old-hash").Return(expired, nil)
+
+	_, err := uc.Execute(ctx, "old-token", "", "")
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	tokenRepo.AssertNotCalled(t, "MarkRotated", mock.Anything, mock.Anything)
+}
+
+func TestDeviceName(t *testing.T) {
+	tests := []struct {
+		userAgent string
+		want      string
+	}{
+		{"", "Unknown device"},
+		{"Mozilla/5.0 (X11; Linux x86_64; rv:128.0) Gecko/20100101 Firefox/128.0", "Firefox on Linux"},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36 Edg/126.0.0.0", "Edge on Windows"},
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1", "Safari on iOS"},
+		{"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36", "Chrome on macOS"},
+		{"DAVx5/4.4.1-ose (2024/06/01; dav4jvm; okhttp/4.12.0)", "DAVx5"},
+		// Cut at 100 bytes without splitting the two-byte "é"
+		{strings.Repeat("a", 99) + "é", strings.Repeat("a", 99)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			assert.Equal(t, tt.want, DeviceName(tt.userAgent))
+			assert.True(t, utf8.ValidString(DeviceName(tt.userAgent)))
+		})
+	}
+}
@@ -68,6 +68,12 @@ func (uc *RegisterUseCase) Execute(ctx context.Context, email, password, display
 		return nil, "", fmt.Errorf("failed to hash password: %w", err)
 	}
 
+	// The first account of an instance administers it
+	userCount, err := uc.repo.Count(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
 	// 4. Create user
 	u := &user.User{
 		UUID:         uuid.New().String(),
@@ -75,6 +81,7 @@ func (uc *RegisterUseCase) Execute(ctx context.Context, email, password, display
 		Username:     username,
 		PasswordHash: string(hash),
 		DisplayName:  displayName,
+		IsAdmin:      userCount == 0,
 	}
 
 	// 5. Conditional activation based on SMTP host
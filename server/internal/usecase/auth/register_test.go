@@ -114,6 +114,11 @@ func (m *mockUserRepo) Count(ctx context.Context) (int64, error) {
 	return args.Get(0).(int64), args.Error(1)
 }
 
+func (m *mockUserRepo) ListActive(ctx context.Context) ([]user.User, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]user.User), args.Error(1)
+}
+
 type mockEmailService struct {
 	mock.Mock
 }
@@ -145,6 +150,7 @@ func TestRegisterUseCase_Execute_NoSMTP(t *testing.T) {
 	repo.On("GetByEmail", ctx, email).Return(nil, nil)
 	// We can't predict the username, so we mock GetByUsername to return nil (available)
 	repo.On("GetByUsername", ctx, mock.Anything).Return(nil, nil)
+	repo.On("Count", ctx).Return(int64(0), nil)
 	repo.On("Create", ctx, mock.MatchedBy(func(u *user.User) bool {
 		return u.Email == email && len(u.Username) == 16 && u.IsActive == true && u.EmailVerified == true
 	})).Return(nil)
@@ -156,6 +162,7 @@ func TestRegisterUseCase_Execute_NoSMTP(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotNil(t, u)
 	assert.Len(t, u.Username, 16)
+	assert.True(t, u.IsAdmin, "first user should be admin")
 	assert.Empty(t, token)
 	repo.AssertExpectations(t)
 }
@@ -177,6 +184,7 @@ func TestRegisterUseCase_Execute_WithSMTP(t *testing.T) {
 
 	repo.On("GetByEmail", ctx, email).Return(nil, nil)
 	repo.On("GetByUsername", ctx, mock.Anything).Return(nil, nil)
+	repo.On("Count", ctx).Return(int64(3), nil)
 	repo.On("Create", ctx, mock.MatchedBy(func(u *user.User) bool {
 		return u.Email == email && len(u.Username) == 16 && u.IsActive == false && u.EmailVerified == false
 	})).Return(nil)
@@ -190,6 +198,7 @@ func TestRegisterUseCase_Execute_WithSMTP(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotNil(t, u)
 	assert.Len(t, u.Username, 16)
+	assert.False(t, u.IsAdmin)
 	assert.NotEmpty(t, token)
 	repo.AssertExpectations(t)
 	emailSvc.AssertExpectations(t)
@@ -213,6 +222,7 @@ func TestRegisterUseCase_Execute_CaseInsensitive(t *testing.T) {
 	repo.On("GetByEmail", ctx, expectedEmail).Return(nil, nil)
 	// Duplicate removed
 	repo.On("GetByUsername", ctx, mock.Anything).Return(nil, nil)
+	repo.On("Count", ctx).Return(int64(1), nil)
 	repo.On("Create", ctx, mock.MatchedBy(func(u *user.User) bool {
 		return u.Email == expectedEmail
 	})).Return(nil)
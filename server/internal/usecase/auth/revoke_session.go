@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jherrma/caldav-server/internal/domain/user"
+	"github.com/jherrma/caldav-server/internal/infrastructure/logging"
+)
+
+var (
+	ErrSessionNotFound = errors.New("session not found")
+)
+
+// RevokeSessionUseCase ends a single session of a user
+type RevokeSessionUseCase struct {
+	tokenRepo user.RefreshTokenRepository
+	logger    *logging.SecurityLogger
+}
+
+// NewRevokeSessionUseCase creates a new RevokeSessionUseCase
+func NewRevokeSessionUseCase(tokenRepo user.RefreshTokenRepository, logger *logging.SecurityLogger) *RevokeSessionUseCase {
+	return &RevokeSessionUseCase{tokenRepo: tokenRepo, logger: logger}
+}
+
+// Execute revokes every token of the session. Access tokens already issued to
+// the session stay valid until they expire.
+func (uc *RevokeSessionUseCase) Execute(ctx context.Context, userID uint, sessionID, ip, userAgent string) error {
+	n, err := uc.tokenRepo.RevokeFamily(ctx, userID, sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	if n == 0 {
+		return ErrSessionNotFound
+	}
+
+	uc.logger.LogSessionRevoked(ctx, userID, "Session: "+sessionID, ip, userAgent)
+	return nil
+}
+
+// RevokeAllSessionsUseCase ends all sessions of a user, optionally keeping the
+// caller's own
+type RevokeAllSessionsUseCase struct {
+	tokenRepo  user.RefreshTokenRepository
+	jwtManager user.TokenProvider
+	logger     *logging.SecurityLogger
+}
+
+// NewRevokeAllSessionsUseCase creates a new RevokeAllSessionsUseCase
+func NewRevokeAllSessionsUseCase(
+	tokenRepo user.RefreshTokenRepository,
+	jwtManager user.TokenProvider,
+	logger *logging.SecurityLogger,
+) *RevokeAllSessionsUseCase {
+	return &RevokeAllSessionsUseCase{tokenRepo: tokenRepo, jwtManager: jwtManager, logger: logger}
+}
+
+// Execute revokes all sessions. If currentRefreshToken belongs to the user,
+// its session is kept. It returns the number of revoked tokens.
+func (uc *RevokeAllSessionsUseCase) Execute(ctx context.Context, userID uint, currentRefreshToken, ip, userAgent string) (int64, error) {
+	keep := ""
+	if currentRefreshToken != "" {
+		t, err := uc.tokenRepo.GetByHash(ctx, uc.jwtManager.HashToken(currentRefreshToken))
+		if err != nil {
+			return 0, err
+		}
+		if t != nil && t.UserID == userID {
+			keep = t.FamilyID
+		}
+	}
+
+	n, err := uc.tokenRepo.RevokeAllExcept(ctx, userID, keep)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	details := "All sessions"
+	if keep != "" {
+		details = "All sessions except " + keep
+	}
+	uc.logger.LogSessionRevoked(ctx, userID, details, ip, userAgent)
+	return n, nil
+}
@@ -0,0 +1,115 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/jherrma/caldav-server/internal/domain/user"
+)
+
+// startSession issues the first refresh token of a new token family and
+// returns the plaintext token.
+func startSession(
+	ctx context.Context,
+	tokenRepo user.RefreshTokenRepository,
+	tokenProvider user.TokenProvider,
+	userID uint,
+	expiry time.Duration,
+	userAgent, ip string,
+) (string, error) {
+	refreshToken, err := tokenProvider.GenerateRefreshToken()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+
+	now := time.Now()
+	rt := &user.RefreshToken{
+		UserID:          userID,
+		FamilyID:        uuid.New().String(),
+		TokenHash:       tokenProvider.HashToken(refreshToken),
+		ExpiresAt:       now.Add(expiry),
+		DeviceName:      DeviceName(userAgent),
+		UserAgent:       truncate(userAgent, 500),
+		IP:              ip,
+		AuthenticatedAt: now,
+		LastUsedAt:      &now,
+	}
+
+	if err := tokenRepo.Create(ctx, rt); err != nil {
+		return "", fmt.Errorf("failed to store refresh token: %w", err)
+	}
+	return refreshToken, nil
+}
+
+// DeviceName derives a short human-readable label such as "Firefox on Linux"
+// from a User-Agent header. It returns "Unknown device" if nothing matches.
+func DeviceName(userAgent string) string {
+	if userAgent == "" {
+		return "Unknown device"
+	}
+
+	// Order matters: Edge and Opera also claim to be Chrome, and Chrome
+	// claims to be Safari.
+	browsers := []struct{ token, name string }{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+		{"curl/", "curl"},
+	}
+	systems := []struct{ token, name string }{
+		{"iPhone", "iOS"},
+		{"iPad", "iPadOS"},
+		{"Android", "Android"},
+		{"Windows", "Windows"},
+		{"Mac OS X", "macOS"},
+		{"Macintosh", "macOS"},
+		{"CrOS", "ChromeOS"},
+		{"Linux", "Linux"},
+	}
+
+	browser := ""
+	for _, b := range browsers {
+		if strings.Contains(userAgent, b.token) {
+			browser = b.name
+			break
+		}
+	}
+	system := ""
+	for _, s := range systems {
+		if strings.Contains(userAgent, s.token) {
+			system = s.name
+			break
+		}
+	}
+
+	switch {
+	case browser != "" && system != "":
+		return browser + " on " + system
+	case browser != "":
+		return browser
+	case system != "":
+		return system
+	}
+
+	// Fall back to the product token, e.g. "DAVx5/4.3" → "DAVx5".
+	product := strings.SplitN(userAgent, " ", 2)[0]
+	product = strings.SplitN(product, "/", 2)[0]
+	return truncate(product, 100)
+}
+
+// truncate cuts s to at most n bytes without splitting a UTF-8 sequence
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,44 @@
+package auth
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jherrma/caldav-server/internal/domain/user"
+	"github.com/jherrma/caldav-server/internal/infrastructure/logging"
+)
+
+var ErrUserNotFound = errors.New("user not found")
+
+// UnlockAccountUseCase lets an administrator clear the failed login counters
+// and lockouts of a user's account and DAV credentials
+type UnlockAccountUseCase struct {
+	userRepo     user.UserRepository
+	throttleRepo user.LoginThrottleRepository
+	logger       *logging.SecurityLogger
+}
+
+// NewUnlockAccountUseCase creates a new unlock account use case
+func NewUnlockAccountUseCase(userRepo user.UserRepository, throttleRepo user.LoginThrottleRepository, logger *logging.SecurityLogger) *UnlockAccountUseCase {
+	return &UnlockAccountUseCase{userRepo: userRepo, throttleRepo: throttleRepo, logger: logger}
+}
+
+// Execute unlocks the user identified by UUID and returns the number of
+// cleared throttle records
+func (uc *UnlockAccountUseCase) Execute(ctx context.Context, adminID uint, userUUID, ip, userAgent string) (int64, error) {
+	u, err := uc.userRepo.GetByUUID(ctx, userUUID)
+	if err != nil {
+		return 0, err
+	}
+	if u == nil {
+		return 0, ErrUserNotFound
+	}
+
+	cleared, err := uc.throttleRepo.DeleteByUserID(ctx, u.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	uc.logger.LogAccountUnlocked(ctx, adminID, u.ID, ip, userAgent)
+	return cleared, nil
+}
@@ -0,0 +1,389 @@
+package birthday
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/emersion/go-vcard"
+	"github.com/jherrma/caldav-server/internal/domain/addressbook"
+	"github.com/jherrma/caldav-server/internal/domain/calendar"
+)
+
+var (
+	// ErrDisabled is returned when the user switched the birthday calendar off
+	ErrDisabled = errors.New("birthday calendar is disabled")
+	// ErrNotFound is returned for unknown birthday events
+	ErrNotFound = errors.New("birthday event not found")
+	// ErrInvalidSettings wraps validation failures of UpdateSettings
+	ErrInvalidSettings = errors.New("invalid birthday calendar settings")
+)
+
+// UpdateSettingsInput changes the birthday calendar settings; nil fields are
+// left unchanged
+type UpdateSettingsInput struct {
+	Enabled            *bool   `json:"enabled"`
+	Name               *string `json:"name"`
+	Color              *string `json:"color"`
+	ReminderEnabled    *bool   `json:"reminder_enabled"`
+	ReminderDaysBefore *int    `json:"reminder_days_before"`
+	ReminderTime       *string `json:"reminder_time"`
+}
+
+// SettingsOutput describes a user's birthday calendar
+type SettingsOutput struct {
+	Enabled            bool   `json:"enabled"`
+	Name               string `json:"name"`
+	Color              string `json:"color"`
+	Path               string `json:"path"`
+	ReminderEnabled    bool   `json:"reminder_enabled"`
+	ReminderDaysBefore int    `json:"reminder_days_before"`
+	ReminderTime       string `json:"reminder_time"`
+	EventCount         int    `json:"event_count"`
+	SyncToken          string `json:"sync_token,omitempty"`
+}
+
+// Service generates the read-only birthday calendar of a user from the
+// birthdays and anniversaries of the contacts in all of the user's address
+// books. The events are reconciled with the contacts whenever the calendar
+// is read, so its sync token changes exactly when a date, a name or the
+// reminder changes.
+type Service struct {
+	repo   calendar.BirthdayRepository
+	abRepo addressbook.Repository
+	mu     sync.Mutex
+}
+
+func NewService(repo calendar.BirthdayRepository, abRepo addressbook.Repository) *Service {
+	return &Service{repo: repo, abRepo: abRepo}
+}
+
+// Settings returns the settings of a user, or the defaults if the user never
+// changed them
+func (s *Service) Settings(ctx context.Context, userID uint) (*calendar.BirthdaySettings, error) {
+	settings, err := s.repo.GetSettings(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if settings == nil {
+		settings = calendar.NewBirthdaySettings(userID)
+	}
+	return settings, nil
+}
+
+// Describe returns the settings of a user together with the number of
+// events and the current sync token
+func (s *Service) Describe(ctx context.Context, userID uint) (*SettingsOutput, error) {
+	settings, err := s.Settings(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &SettingsOutput{
+		Enabled:            settings.Enabled(),
+		Name:               settings.Name,
+		Color:              settings.Color,
+		Path:               calendar.BirthdayCalendarPath,
+		ReminderEnabled:    settings.ReminderEnabled,
+		ReminderDaysBefore: settings.ReminderDaysBefore,
+		ReminderTime:       settings.ReminderTime,
+	}
+	if settings.Enabled() {
+		events, token, err := s.Events(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		out.EventCount, out.SyncToken = len(events), token
+	}
+	return out, nil
+}
+
+// UpdateSettings changes the settings of a user. Changing the reminder
+// regenerates every event on the next read.
+func (s *Service) UpdateSettings(ctx context.Context, userID uint, input UpdateSettingsInput) (*SettingsOutput, error) {
+	if err := s.updateSettings(ctx, userID, input); err != nil {
+		return nil, err
+	}
+	return s.Describe(ctx, userID)
+}
+
+func (s *Service) updateSettings(ctx context.Context, userID uint, input UpdateSettingsInput) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	settings, err := s.Settings(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if input.Enabled != nil {
+		settings.Disabled = !*input.Enabled
+	}
+	if input.Name != nil {
+		if err := calendar.ValidateName(*input.Name); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidSettings, err)
+		}
+		settings.Name = *input.Name
+	}
+	if input.Color != nil {
+		if err := calendar.ValidateHexColor(*input.Color); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidSettings, err)
+		}
+		settings.Color = *input.Color
+	}
+	if input.ReminderEnabled != nil {
+		settings.ReminderEnabled = *input.ReminderEnabled
+	}
+	if input.ReminderDaysBefore != nil {
+		settings.ReminderDaysBefore = *input.ReminderDaysBefore
+	}
+	if input.ReminderTime != nil {
+		settings.ReminderTime = *input.ReminderTime
+	}
+	if err := calendar.ValidateReminder(settings.ReminderDaysBefore, settings.ReminderTime); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSettings, err)
+	}
+
+	return s.repo.SaveSettings(ctx, settings)
+}
+
+// Events returns the events of a user's birthday calendar ordered by
+// summary together with the current sync token
+func (s *Service) Events(ctx context.Context, userID uint) ([]calendar.BirthdayEvent, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	settings, err := s.Settings(ctx, userID)
+	if err != nil {
+		return nil, "", err
+	}
+	if !settings.Enabled() {
+		return nil, "", ErrDisabled
+	}
+	return s.refresh(ctx, settings)
+}
+
+// Get returns the event with the given resource name
+func (s *Service) Get(ctx context.Context, userID uint, path string) (*calendar.BirthdayEvent, error) {
+	events, _, err := s.Events(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range events {
+		if events[i].Path == path {
+			return &events[i], nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// Instances returns the occurrences of a user's birthdays and anniversaries
+// between start and end, ordered by date
+func (s *Service) Instances(ctx context.Context, userID uint, start, end time.Time) ([]calendar.EventInstance, error) {
+	events, _, err := s.Events(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []calendar.EventInstance
+	for i := range events {
+		instances, err := calendar.ExpandRecurringEvent(events[i].CalendarObject(), start, end)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, instances...)
+	}
+	sort.SliceStable(res, func(i, j int) bool { return res[i].Start.Before(res[j].Start) })
+	return res, nil
+}
+
+// Changes returns the changes of a user's birthday calendar since the given
+// sync token and the new token. An empty token lists every event as created.
+func (s *Service) Changes(ctx context.Context, userID uint, token string) ([]*calendar.SyncChangeLog, string, error) {
+	events, current, err := s.Events(ctx, userID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if token == "" {
+		changes := make([]*calendar.SyncChangeLog, 0, len(events))
+		for i := range events {
+			changes = append(changes, &calendar.SyncChangeLog{
+				CalendarID:   calendar.BirthdayCalendarID,
+				ResourcePath: events[i].Path,
+				ResourceUID:  events[i].UID,
+				ChangeType:   "created",
+				SyncToken:    current,
+			})
+		}
+		return changes, current, nil
+	}
+	if token == current {
+		return nil, current, nil
+	}
+
+	changes, err := s.repo.GetChangesSinceToken(ctx, userID, token)
+	if err != nil {
+		return nil, "", err
+	}
+	return changes, current, nil
+}
+
+// refresh brings the stored events in line with the user's contacts. The
+// contacts are only parsed again when an address book or the reminder
+// changed since the last refresh. The caller holds the lock.
+func (s *Service) refresh(ctx context.Context, settings *calendar.BirthdaySettings) ([]calendar.BirthdayEvent, string, error) {
+	books, err := s.abRepo.ListByUserID(ctx, settings.UserID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	tag := sourceTag(books, settings)
+	if tag != settings.SourceTag {
+		if err := s.regenerate(ctx, books, settings); err != nil {
+			return nil, "", err
+		}
+		settings.SourceTag = tag
+		if err := s.repo.SaveSettings(ctx, settings); err != nil {
+			return nil, "", err
+		}
+	}
+
+	events, err := s.repo.ListEvents(ctx, settings.UserID)
+	if err != nil {
+		return nil, "", err
+	}
+	token, err := s.repo.SyncToken(ctx, settings.UserID)
+	if err != nil {
+		return nil, "", err
+	}
+	return events, token, nil
+}
+
+// regenerate builds the events of all contact dates and stores the
+// difference to the stored events
+func (s *Service) regenerate(ctx context.Context, books []addressbook.AddressBook, settings *calendar.BirthdaySettings) error {
+	stored, err := s.repo.ListEvents(ctx, settings.UserID)
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]calendar.BirthdayEvent, len(stored))
+	for _, e := range stored {
+		existing[e.Path] = e
+	}
+
+	var created, modified []calendar.BirthdayEvent
+	for _, book := range books {
+		objects, err := s.abRepo.QueryObjects(ctx, book.ID, &addressbook.ObjectQuery{})
+		if err != nil {
+			return err
+		}
+		for i := range objects {
+			for _, src := range contactDates(&objects[i]) {
+				event, err := calendar.NewBirthdayEvent(settings.UserID, src, settings)
+				if err != nil {
+					return err
+				}
+				old, ok := existing[event.Path]
+				delete(existing, event.Path)
+				switch {
+				case !ok:
+					created = append(created, *event)
+				case old.ETag != event.ETag:
+					event.ID = old.ID
+					event.CreatedAt = old.CreatedAt
+					modified = append(modified, *event)
+				}
+			}
+		}
+	}
+
+	removed := make([]calendar.BirthdayEvent, 0, len(existing))
+	for _, e := range existing {
+		removed = append(removed, e)
+	}
+
+	if len(created) == 0 && len(modified) == 0 && len(removed) == 0 {
+		return nil
+	}
+	return s.repo.SaveChanges(ctx, settings.UserID, created, modified, removed, calendar.GenerateSyncToken())
+}
+
+// sourceTag fingerprints the address books and the reminder. Every change
+// of an address object advances the sync token of its address book.
+func sourceTag(books []addressbook.AddressBook, settings *calendar.BirthdaySettings) string {
+	h := sha256.New()
+	for _, book := range books {
+		fmt.Fprintf(h, "%d:%s;", book.ID, book.SyncToken)
+	}
+	fmt.Fprintf(h, "%t:%d:%s", settings.ReminderEnabled, settings.ReminderDaysBefore, settings.ReminderTime)
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// contactDates returns the birthday and anniversary of a contact. Groups
+// and cards without a usable date yield nothing.
+func contactDates(obj *addressbook.AddressObject) []calendar.BirthdaySource {
+	if obj.Kind == addressbook.KindGroup {
+		return nil
+	}
+	card, err := vcard.NewDecoder(strings.NewReader(obj.VCardData)).Decode()
+	if err != nil {
+		return nil
+	}
+
+	name := contactName(obj, card)
+	var res []calendar.BirthdaySource
+	add := func(kind string, field *vcard.Field) {
+		if field == nil {
+			return
+		}
+		date, ok := calendar.ParseContactDate(field.Value)
+		if !ok {
+			return
+		}
+		// Apple clients store year-less dates in a placeholder year
+		if omit, err := strconv.Atoi(field.Params.Get("X-APPLE-OMIT-YEAR")); err == nil && omit == date.Year {
+			date.Year = 0
+		}
+		res = append(res, calendar.BirthdaySource{
+			AddressObjectID:   obj.ID,
+			AddressObjectUUID: obj.UUID,
+			Name:              name,
+			Kind:              kind,
+			Date:              date,
+			Stamp:             obj.CreatedAt,
+		})
+	}
+
+	add(calendar.BirthdayKindBirthday, card.Get(vcard.FieldBirthday))
+	anniversary := card.Get(vcard.FieldAnniversary)
+	if anniversary == nil {
+		anniversary = card.Get("X-ANNIVERSARY")
+	}
+	add(calendar.BirthdayKindAnniversary, anniversary)
+	return res
+}
+
+// contactName returns the name shown in the event summary
+func contactName(obj *addressbook.AddressObject, card vcard.Card) string {
+	if obj.FormattedName != "" {
+		return obj.FormattedName
+	}
+	if name := strings.TrimSpace(obj.GivenName + " " + obj.FamilyName); name != "" {
+		return name
+	}
+	if fn := card.PreferredValue(vcard.FieldFormattedName); fn != "" {
+		return fn
+	}
+	if obj.Organization != "" {
+		return obj.Organization
+	}
+	return obj.Email
+}
@@ -0,0 +1,168 @@
+package birthday_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jherrma/caldav-server/internal/adapter/repository"
+	"github.com/jherrma/caldav-server/internal/domain/addressbook"
+	"github.com/jherrma/caldav-server/internal/domain/calendar"
+	"github.com/jherrma/caldav-server/internal/domain/user"
+	"github.com/jherrma/caldav-server/internal/usecase/birthday"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type fixture struct {
+	svc    *birthday.Service
+	abRepo addressbook.Repository
+	userID uint
+	book   *addressbook.AddressBook
+}
+
+func setupService(t *testing.T) *fixture {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&user.User{}, &addressbook.AddressBook{}, &addressbook.AddressObject{},
+		&addressbook.ContactPhoto{}, &addressbook.SyncChangeLog{},
+		&calendar.BirthdaySettings{}, &calendar.BirthdayEvent{}, &calendar.BirthdayChangeLog{}))
+
+	u := &user.User{UUID: "owner-uuid", Email: "owner@example.com", Username: "owner", PasswordHash: "hash", IsActive: true}
+	require.NoError(t, repository.NewUserRepository(db).Create(context.Background(), u))
+
+	abRepo := repository.NewAddressBookRepository(db)
+	book := &addressbook.AddressBook{UUID: "ab-uuid", UserID: u.ID, Path: "contacts", Name: "Contacts", SyncToken: "1", CTag: "1"}
+	require.NoError(t, abRepo.Create(context.Background(), book))
+
+	return &fixture{
+		svc:    birthday.NewService(repository.NewBirthdayRepository(db), abRepo),
+		abRepo: abRepo,
+		userID: u.ID,
+		book:   book,
+	}
+}
+
+func (f *fixture) putContact(t *testing.T, uid, card string) *addressbook.AddressObject {
+	t.Helper()
+	ctx := context.Background()
+	data := "BEGIN:VCARD\r\nVERSION:3.0\r\nUID:" + uid + "\r\n" + card + "END:VCARD\r\n"
+
+	obj, err := f.abRepo.GetObjectByUID(ctx, f.book.ID, uid)
+	require.NoError(t, err)
+	if obj == nil {
+		obj = &addressbook.AddressObject{UUID: uid + "-uuid", AddressBookID: f.book.ID, Path: uid + ".vcf", UID: uid, ETag: "1"}
+	}
+	obj.VCardData = data
+	obj.VCardVersion = addressbook.VCardVersion3
+	require.NoError(t, obj.PopulateDenormFieldsFromVCard())
+	if obj.ID == 0 {
+		require.NoError(t, f.abRepo.CreateObject(ctx, obj))
+	} else {
+		require.NoError(t, f.abRepo.UpdateObject(ctx, obj))
+	}
+	return obj
+}
+
+func TestService_Events(t *testing.T) {
+	ctx := context.Background()
+	f := setupService(t)
+
+	f.putContact(t, "alice", "FN:Alice Liddell\r\nBDAY:1990-05-01\r\nX-ANNIVERSARY:2015-06-20\r\n")
+	f.putContact(t, "bob", "FN:Bob\r\nBDAY:--12-24\r\n")
+	f.putContact(t, "carl", "FN:Carl\r\n")
+
+	events, token, err := f.svc.Events(ctx, f.userID)
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	assert.NotEmpty(t, token)
+	assert.Equal(t, "Alice Liddell's anniversary", events[0].Summary)
+	assert.Equal(t, "Alice Liddell's birthday", events[1].Summary)
+	assert.Contains(t, events[1].ICalData, "DESCRIPTION:Born 1990")
+	assert.Equal(t, "Bob's birthday", events[2].Summary)
+	assert.NotContains(t, events[2].ICalData, "DESCRIPTION")
+
+	// Reading again without contact changes keeps the token
+	_, again, err := f.svc.Events(ctx, f.userID)
+	require.NoError(t, err)
+	assert.Equal(t, token, again)
+
+	instances, err := f.svc.Instances(ctx, f.userID,
+		time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.Len(t, instances, 3)
+	assert.Equal(t, time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC), instances[0].Start)
+	assert.Equal(t, time.Date(2026, 12, 24, 0, 0, 0, 0, time.UTC), instances[2].Start)
+}
+
+func TestService_ChangesFollowContacts(t *testing.T) {
+	ctx := context.Background()
+	f := setupService(t)
+
+	f.putContact(t, "alice", "FN:Alice\r\nBDAY:1990-05-01\r\n")
+	initial, token, err := f.svc.Changes(ctx, f.userID, "")
+	require.NoError(t, err)
+	require.Len(t, initial, 1)
+	assert.Equal(t, "alice-uuid-birthday.ics", initial[0].ResourcePath)
+
+	// An edit that keeps the date and name does not change the event
+	f.putContact(t, "alice", "FN:Alice\r\nBDAY:1990-05-01\r\nEMAIL:alice@example.com\r\n")
+	changes, same, err := f.svc.Changes(ctx, f.userID, token)
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+	assert.Equal(t, token, same)
+
+	// A new birthday date is a modification
+	f.putContact(t, "alice", "FN:Alice\r\nBDAY:1990-05-02\r\n")
+	changes, token, err = f.svc.Changes(ctx, f.userID, token)
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Equal(t, "modified", changes[0].ChangeType)
+
+	// Removing the birthday deletes the event
+	f.putContact(t, "alice", "FN:Alice\r\n")
+	changes, token, err = f.svc.Changes(ctx, f.userID, token)
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Equal(t, "deleted", changes[0].ChangeType)
+
+	_, _, err = f.svc.Changes(ctx, f.userID, "unknown")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	_, err = f.svc.Get(ctx, f.userID, "alice-uuid-birthday.ics")
+	assert.ErrorIs(t, err, birthday.ErrNotFound)
+}
+
+func TestService_Settings(t *testing.T) {
+	ctx := context.Background()
+	f := setupService(t)
+	f.putContact(t, "alice", "FN:Alice\r\nBDAY:1990-05-01\r\n")
+
+	out, err := f.svc.Describe(ctx, f.userID)
+	require.NoError(t, err)
+	assert.True(t, out.Enabled)
+	assert.Equal(t, calendar.DefaultBirthdayCalendarName, out.Name)
+	assert.Equal(t, 1, out.EventCount)
+
+	// Turning the reminder on regenerates the events
+	enabled, days := true, 2
+	out, err = f.svc.UpdateSettings(ctx, f.userID, birthday.UpdateSettingsInput{ReminderEnabled: &enabled, ReminderDaysBefore: &days})
+	require.NoError(t, err)
+	assert.True(t, out.ReminderEnabled)
+	event, err := f.svc.Get(ctx, f.userID, "alice-uuid-birthday.ics")
+	require.NoError(t, err)
+	assert.Contains(t, event.ICalData, "TRIGGER:-PT140400S")
+
+	badTime := "9am"
+	_, err = f.svc.UpdateSettings(ctx, f.userID, birthday.UpdateSettingsInput{ReminderTime: &badTime})
+	assert.ErrorIs(t, err, birthday.ErrInvalidSettings)
+
+	disabled := false
+	out, err = f.svc.UpdateSettings(ctx, f.userID, birthday.UpdateSettingsInput{Enabled: &disabled})
+	require.NoError(t, err)
+	assert.False(t, out.Enabled)
+	_, _, err = f.svc.Events(ctx, f.userID)
+	assert.ErrorIs(t, err, birthday.ErrDisabled)
+}
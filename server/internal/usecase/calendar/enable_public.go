@@ -15,10 +15,12 @@ type EnablePublicInput struct {
 }
 
 type EnablePublicOutput struct {
-	Enabled   bool       `json:"enabled"`
-	PublicURL *string    `json:"public_url"`
-	Token     *string    `json:"token"`
-	EnabledAt *time.Time `json:"enabled_at"`
+	Enabled   bool                     `json:"enabled"`
+	PublicURL *string                  `json:"public_url"`
+	EmbedURL  *string                  `json:"embed_url"` // HTML view for embedding in other sites
+	Token     *string                  `json:"token"`
+	EnabledAt *time.Time               `json:"enabled_at"`
+	Options   *PublicFeedOptionsOutput `json:"options"`
 }
 
 type EnablePublicUseCase struct {
@@ -68,15 +70,5 @@ func (uc *EnablePublicUseCase) Execute(ctx context.Context, userID, calendarID u
 		return nil, err
 	}
 
-	output := &EnablePublicOutput{
-		Enabled:   cal.PublicEnabled,
-		EnabledAt: cal.PublicEnabledAt,
-	}
-	if cal.PublicEnabled && cal.PublicToken != nil && *cal.PublicToken != "" {
-		url := fmt.Sprintf("%s/public/calendar/%s.ics", uc.baseURL, *cal.PublicToken)
-		output.PublicURL = &url
-		output.Token = cal.PublicToken
-	}
-
-	return output, nil
+	return publicStatus(cal, uc.baseURL), nil
 }
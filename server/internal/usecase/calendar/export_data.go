@@ -0,0 +1,68 @@
+package calendar
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/emersion/go-ical"
+	"github.com/jherrma/caldav-server/internal/domain/calendar"
+	eventuc "github.com/jherrma/caldav-server/internal/usecase/event"
+)
+
+// ExportCalendarDataUseCase exports a calendar as one parsed iCalendar, for
+// the jCal and JSCalendar exports
+type ExportCalendarDataUseCase struct {
+	repo calendar.CalendarRepository
+}
+
+// NewExportCalendarDataUseCase creates a new use case
+func NewExportCalendarDataUseCase(repo calendar.CalendarRepository) *ExportCalendarDataUseCase {
+	return &ExportCalendarDataUseCase{repo: repo}
+}
+
+// ExportCalendarDataOutput is a calendar with its objects merged into one
+// iCalendar carrying the calendar's name and description
+type ExportCalendarDataOutput struct {
+	Calendar *calendar.Calendar
+	Data     *ical.Calendar
+	Filename string // without extension
+}
+
+// Execute exports a calendar the way the .ics export does, parsed
+func (uc *ExportCalendarDataUseCase) Execute(ctx context.Context, userID uint, calendarUUID string) (*ExportCalendarDataOutput, error) {
+	cal, err := uc.repo.GetByUUID(ctx, calendarUUID)
+	if err != nil {
+		return nil, fmt.Errorf("calendar not found")
+	}
+	if cal.UserID != userID {
+		return nil, fmt.Errorf("access denied")
+	}
+
+	objects, err := uc.repo.GetCalendarObjects(ctx, cal.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch calendar objects: %w", err)
+	}
+	cals := make([]*ical.Calendar, 0, len(objects))
+	for _, obj := range objects {
+		data, err := eventuc.ParseICalData(obj.ICalData)
+		if err != nil {
+			return nil, err
+		}
+		cals = append(cals, data)
+	}
+
+	merged := eventuc.MergeCalendars(cals)
+	merged.Props.SetText(ical.PropCalendarScale, "GREGORIAN")
+	merged.Props.SetText(ical.PropName, cal.Name)
+	merged.Props.SetText("X-WR-CALNAME", cal.Name)
+	merged.Props.SetText("X-WR-TIMEZONE", cal.Timezone)
+	if cal.Description != "" {
+		merged.Props.SetText(ical.PropDescription, cal.Description)
+		merged.Props.SetText("X-WR-CALDESC", cal.Description)
+	}
+	return &ExportCalendarDataOutput{
+		Calendar: cal,
+		Data:     merged,
+		Filename: sanitizeFilename(cal.Name),
+	}, nil
+}
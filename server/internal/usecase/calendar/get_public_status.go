@@ -28,15 +28,5 @@ func (uc *GetPublicStatusUseCase) Execute(ctx context.Context, userID, calendarI
 		return nil, fmt.Errorf("permission denied")
 	}
 
-	output := &EnablePublicOutput{
-		Enabled:   cal.PublicEnabled,
-		EnabledAt: cal.PublicEnabledAt,
-	}
-	if cal.PublicEnabled && cal.PublicToken != nil && *cal.PublicToken != "" {
-		url := fmt.Sprintf("%s/public/calendar/%s.ics", uc.baseURL, *cal.PublicToken)
-		output.PublicURL = &url
-		output.Token = cal.PublicToken
-	}
-
-	return output, nil
+	return publicStatus(cal, uc.baseURL), nil
 }
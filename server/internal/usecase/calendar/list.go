@@ -18,6 +18,7 @@ type CalendarWithEventCount struct {
 	*calendar.Calendar
 	EventCount int64          `json:"event_count"`
 	Shared     bool           `json:"shared"`
+	Hidden     bool           `json:"hidden,omitempty"` // shared calendars the user hid from their CalDAV clients
 	Owner      *CalendarOwner `json:"owner,omitempty"`
 }
 
@@ -62,7 +63,8 @@ func (uc *ListCalendarsUseCase) Execute(ctx context.Context, userID uint) ([]*Ca
 		}
 
 		for _, share := range shares {
-			cal := share.Calendar
+			// Shared calendars carry the user's own path, name and color
+			cal := share.Mount.Apply(share.Calendar)
 			eventCount, err := uc.repo.GetEventCount(ctx, cal.ID)
 			if err != nil {
 				eventCount = 0
@@ -72,6 +74,7 @@ func (uc *ListCalendarsUseCase) Execute(ctx context.Context, userID uint) ([]*Ca
 				Calendar:   &cal,
 				EventCount: eventCount,
 				Shared:     true,
+				Hidden:     share.Mount.Hidden,
 				Owner: &CalendarOwner{
 					ID:          cal.Owner.UUID,
 					DisplayName: cal.Owner.DisplayName,
@@ -0,0 +1,388 @@
+package calendar
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-ical"
+	"github.com/jherrma/caldav-server/internal/domain/calendar"
+	"github.com/jherrma/caldav-server/internal/usecase/auth"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	// ErrPublicFeedNotFound is returned for unknown, disabled and expired
+	// public tokens
+	ErrPublicFeedNotFound = errors.New("calendar not found")
+	// ErrPublicFeedUnauthorized is returned when a password protected feed
+	// is requested without the right password
+	ErrPublicFeedUnauthorized = errors.New("password required")
+)
+
+// publicListingDays is how far ahead the event listing of a feed without a
+// future window goes
+const publicListingDays = 365
+
+// PublicFeedOptionsInput replaces the publication settings of a calendar.
+// A nil window side leaves it open; a nil password keeps the current one
+// and an empty one removes it.
+type PublicFeedOptionsInput struct {
+	PastDays         *int       `json:"past_days"`
+	FutureDays       *int       `json:"future_days"`
+	FreeBusyOnly     bool       `json:"free_busy_only"`
+	HideAttendees    bool       `json:"hide_attendees"`
+	HideDescriptions bool       `json:"hide_descriptions"`
+	HideAlarms       bool       `json:"hide_alarms"`
+	Password         *string    `json:"password"`
+	ExpiresAt        *time.Time `json:"expires_at"`
+}
+
+// PublicFeedOptionsOutput describes the publication settings of a calendar
+type PublicFeedOptionsOutput struct {
+	PastDays          *int       `json:"past_days"`
+	FutureDays        *int       `json:"future_days"`
+	FreeBusyOnly      bool       `json:"free_busy_only"`
+	HideAttendees     bool       `json:"hide_attendees"`
+	HideDescriptions  bool       `json:"hide_descriptions"`
+	HideAlarms        bool       `json:"hide_alarms"`
+	PasswordProtected bool       `json:"password_protected"`
+	ExpiresAt         *time.Time `json:"expires_at"`
+}
+
+func toPublicFeedOptionsOutput(o *calendar.PublicFeedOptions) *PublicFeedOptionsOutput {
+	return &PublicFeedOptionsOutput{
+		PastDays:          o.PastDays,
+		FutureDays:        o.FutureDays,
+		FreeBusyOnly:      o.FreeBusyOnly,
+		HideAttendees:     o.HideAttendees,
+		HideDescriptions:  o.HideDescriptions,
+		HideAlarms:        o.HideAlarms,
+		PasswordProtected: o.Protected(),
+		ExpiresAt:         o.ExpiresAt,
+	}
+}
+
+// publicStatus describes the public access of a calendar
+func publicStatus(cal *calendar.Calendar, baseURL string) *EnablePublicOutput {
+	output := &EnablePublicOutput{
+		Enabled:   cal.PublicEnabled,
+		EnabledAt: cal.PublicEnabledAt,
+		Options:   toPublicFeedOptionsOutput(&cal.PublicFeed),
+	}
+	if cal.PublicEnabled && cal.PublicToken != nil && *cal.PublicToken != "" {
+		url := fmt.Sprintf("%s/public/calendar/%s.ics", baseURL, *cal.PublicToken)
+		embedURL := fmt.Sprintf("%s/public/calendar/%s/view", baseURL, *cal.PublicToken)
+		output.PublicURL = &url
+		output.EmbedURL = &embedURL
+		output.Token = cal.PublicToken
+	}
+	return output
+}
+
+type UpdatePublicFeedOptionsUseCase struct {
+	calendarRepo calendar.CalendarRepository
+	baseURL      string
+}
+
+func NewUpdatePublicFeedOptionsUseCase(calendarRepo calendar.CalendarRepository, baseURL string) *UpdatePublicFeedOptionsUseCase {
+	return &UpdatePublicFeedOptionsUseCase{
+		calendarRepo: calendarRepo,
+		baseURL:      baseURL,
+	}
+}
+
+func (uc *UpdatePublicFeedOptionsUseCase) Execute(ctx context.Context, userID, calendarID uint, input PublicFeedOptionsInput) (*EnablePublicOutput, error) {
+	cal, err := uc.calendarRepo.GetByID(ctx, calendarID)
+	if err != nil || cal == nil {
+		return nil, fmt.Errorf("calendar not found")
+	}
+	if cal.UserID != userID {
+		return nil, fmt.Errorf("permission denied")
+	}
+	if err := calendar.ValidatePublicFeedDays(input.PastDays); err != nil {
+		return nil, err
+	}
+	if err := calendar.ValidatePublicFeedDays(input.FutureDays); err != nil {
+		return nil, err
+	}
+
+	options := calendar.PublicFeedOptions{
+		PastDays:         input.PastDays,
+		FutureDays:       input.FutureDays,
+		FreeBusyOnly:     input.FreeBusyOnly,
+		HideAttendees:    input.HideAttendees,
+		HideDescriptions: input.HideDescriptions,
+		HideAlarms:       input.HideAlarms,
+		PasswordHash:     cal.PublicFeed.PasswordHash,
+		ExpiresAt:        input.ExpiresAt,
+	}
+	if input.Password != nil {
+		options.PasswordHash = ""
+		if *input.Password != "" {
+			hash, err := bcrypt.GenerateFromPassword([]byte(*input.Password), bcrypt.DefaultCost)
+			if err != nil {
+				return nil, err
+			}
+			options.PasswordHash = string(hash)
+		}
+	}
+	cal.PublicFeed = options
+
+	if err := uc.calendarRepo.Update(ctx, cal); err != nil {
+		return nil, err
+	}
+	return publicStatus(cal, uc.baseURL), nil
+}
+
+// PublicFeed is a published calendar as its public token shows it
+type PublicFeed struct {
+	Calendar *calendar.Calendar
+	// Data is the feed in iCalendar format
+	Data *ical.Calendar
+	// ETag changes with the calendar, its publication settings and, for
+	// feeds with a window, the day
+	ETag    string
+	objects []*calendar.CalendarObject
+	busy    map[string]bool // objects published as busy blocks
+}
+
+// PublicEvent is an event occurrence in the listing of a public feed
+type PublicEvent struct {
+	UID         string    `json:"uid"`
+	Summary     string    `json:"summary"`
+	Description string    `json:"description,omitempty"`
+	Location    string    `json:"location,omitempty"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	AllDay      bool      `json:"all_day"`
+	Busy        bool      `json:"busy"` // A busy block without details
+}
+
+// Events returns the event occurrences of the feed between start and end,
+// ordered by start
+func (f *PublicFeed) Events(start, end time.Time) []PublicEvent {
+	var events []PublicEvent
+	for _, obj := range f.objects {
+		instances, err := calendar.ExpandRecurringEvent(obj, start, end)
+		if err != nil {
+			continue
+		}
+		for _, inst := range instances {
+			events = append(events, PublicEvent{
+				UID:         inst.UID,
+				Summary:     inst.Summary,
+				Description: inst.Description,
+				Location:    inst.Location,
+				Start:       inst.Start,
+				End:         inst.End,
+				AllDay:      inst.IsAllDay,
+				Busy:        f.busy[obj.UUID],
+			})
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Start.Before(events[j].Start) })
+	return events
+}
+
+// ListingWindow returns the range the event listing of the feed covers: the
+// publication window, from today when it has no past limit and for a year
+// when it has no future limit
+func (f *PublicFeed) ListingWindow(now time.Time) (start, end time.Time) {
+	loc := calendarLocation(f.Calendar)
+	start, end = f.Calendar.PublicFeed.Window(now, loc)
+	today := now.In(loc)
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, loc)
+	if start.IsZero() {
+		start = today
+	}
+	if end.IsZero() {
+		end = today.AddDate(0, 0, publicListingDays)
+	}
+	return start, end
+}
+
+// ClampToWindow limits a range to the publication window of the feed, so
+// that views of any range show no more occurrences than the feed
+func (f *PublicFeed) ClampToWindow(start, end, now time.Time) (time.Time, time.Time) {
+	windowStart, windowEnd := f.Calendar.PublicFeed.Window(now, calendarLocation(f.Calendar))
+	if !windowStart.IsZero() && start.Before(windowStart) {
+		start = windowStart
+	}
+	if !windowEnd.IsZero() && end.After(windowEnd) {
+		end = windowEnd
+	}
+	return start, end
+}
+
+type PublicFeedUseCase struct {
+	calendarRepo calendar.CalendarRepository
+	lockout      *auth.LockoutService
+}
+
+// NewPublicFeedUseCase creates the public feed use case. Wrong feed
+// passwords are throttled by lockout like failed logins.
+func NewPublicFeedUseCase(calendarRepo calendar.CalendarRepository, lockout *auth.LockoutService) *PublicFeedUseCase {
+	return &PublicFeedUseCase{calendarRepo: calendarRepo, lockout: lockout}
+}
+
+// Execute opens the public feed of a token. password is the password sent
+// with the request, if any.
+func (uc *PublicFeedUseCase) Execute(ctx context.Context, token, password, userAgent, ip string) (*PublicFeed, error) {
+	cal, err := uc.calendarRepo.FindByPublicToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if cal == nil || !cal.PublicEnabled || cal.PublicFeed.Expired(now) {
+		return nil, ErrPublicFeedNotFound
+	}
+	if cal.PublicFeed.Protected() {
+		if err := uc.checkPassword(ctx, cal, password, userAgent, ip); err != nil {
+			return nil, err
+		}
+	}
+
+	objects, err := uc.calendarRepo.GetCalendarObjects(ctx, cal.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	loc := calendarLocation(cal)
+	start, end := cal.PublicFeed.Window(now, loc)
+	feed := &PublicFeed{Calendar: cal, Data: ical.NewCalendar(), busy: make(map[string]bool)}
+	feed.Data.Props.SetText(ical.PropVersion, "2.0")
+	feed.Data.Props.SetText(ical.PropProductID, "-//CalDAV Server//EN")
+	feed.Data.Props.SetText(ical.PropCalendarScale, "GREGORIAN")
+	feed.Data.Props.SetText(ical.PropMethod, "PUBLISH")
+	setExtendedText(feed.Data, "X-WR-CALNAME", cal.Name)
+	if cal.Timezone != "" {
+		setExtendedText(feed.Data, "X-WR-TIMEZONE", cal.Timezone)
+	}
+	timezones := make(map[string]*ical.Component)
+	for _, obj := range objects {
+		if !inWindow(obj, start, end) {
+			continue
+		}
+		data, err := ical.NewDecoder(strings.NewReader(obj.ICalData)).Decode()
+		if err != nil {
+			continue
+		}
+		published := ical.NewCalendar()
+		for _, comp := range data.Children {
+			switch comp.Name {
+			case ical.CompTimezone:
+				if p := comp.Props.Get(ical.PropTimezoneID); p != nil {
+					timezones[p.Value] = comp
+				}
+			case ical.CompEvent, ical.CompToDo:
+				if pub := calendar.PublishComponent(comp, &cal.PublicFeed); pub != nil {
+					published.Children = append(published.Children, pub)
+					if calendar.PublishedAsBusy(comp, &cal.PublicFeed) {
+						feed.busy[obj.UUID] = true
+					}
+				}
+			}
+		}
+		if len(published.Children) == 0 {
+			continue
+		}
+		feed.Data.Children = append(feed.Data.Children, published.Children...)
+
+		// The listing expands the published components, so it shows no
+		// more than the feed
+		var sb strings.Builder
+		published.Props.SetText(ical.PropVersion, "2.0")
+		published.Props.SetText(ical.PropProductID, "-//CalDAV Server//EN")
+		if err := ical.NewEncoder(&sb).Encode(published); err != nil {
+			continue
+		}
+		feed.objects = append(feed.objects, &calendar.CalendarObject{
+			UUID: obj.UUID, CalendarID: obj.CalendarID, UID: obj.UID, ICalData: sb.String(), IsAllDay: obj.IsAllDay,
+		})
+	}
+	ids := make([]string, 0, len(timezones))
+	for id := range timezones {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for i := len(ids) - 1; i >= 0; i-- {
+		feed.Data.Children = append([]*ical.Component{timezones[ids[i]]}, feed.Data.Children...)
+	}
+
+	feed.ETag = publicFeedETag(cal, start, end)
+	return feed, nil
+}
+
+// setExtendedText sets a text property of a non-standard name, which clients
+// expect without a VALUE parameter
+func setExtendedText(cal *ical.Calendar, name, value string) {
+	prop := ical.NewProp(name)
+	prop.SetText(value)
+	prop.Params.Del(ical.ParamValue)
+	cal.Props.Set(prop)
+}
+
+// inWindow reports whether an object may have occurrences between start
+// and end; zero bounds are open
+func inWindow(obj *calendar.CalendarObject, start, end time.Time) bool {
+	if start.IsZero() && end.IsZero() {
+		return true
+	}
+	if !strings.Contains(obj.ICalData, "RRULE") && !strings.Contains(obj.ICalData, "RDATE") {
+		if obj.StartTime == nil {
+			return true
+		}
+		objEnd := obj.StartTime
+		if obj.EndTime != nil {
+			objEnd = obj.EndTime
+		}
+		return (start.IsZero() || !objEnd.Before(start)) && (end.IsZero() || obj.StartTime.Before(end))
+	}
+	if end.IsZero() || obj.ComponentType != ical.CompEvent {
+		// Open-ended series cannot be ruled out without expanding them
+		return true
+	}
+	instances, err := calendar.ExpandRecurringEvent(obj, start, end)
+	return err != nil || len(instances) > 0
+}
+
+func publicFeedETag(cal *calendar.Calendar, start, end time.Time) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s|%d|%d|%d", cal.CTag, cal.UpdatedAt.UnixNano(), start.Unix(), end.Unix())
+	return `"` + hex.EncodeToString(h.Sum(nil))[:32] + `"`
+}
+
+// calendarLocation returns the timezone of a calendar, UTC if it has none
+func calendarLocation(cal *calendar.Calendar) *time.Location {
+	if cal.Timezone != "" {
+		if loc, err := time.LoadLocation(cal.Timezone); err == nil {
+			return loc
+		}
+	}
+	return time.UTC
+}
+
+// checkPassword verifies the password of a protected feed. Requests without
+// a password only get the challenge; wrong passwords count towards the
+// lockout of the feed, which is checked before bcrypt runs.
+func (uc *PublicFeedUseCase) checkPassword(ctx context.Context, cal *calendar.Calendar, password, userAgent, ip string) error {
+	subject := auth.PublicFeedSubject(cal.ID, cal.UserID, cal.Name)
+	if err := uc.lockout.Check(ctx, subject); err != nil {
+		return err
+	}
+	if password == "" {
+		return ErrPublicFeedUnauthorized
+	}
+	if bcrypt.CompareHashAndPassword([]byte(cal.PublicFeed.PasswordHash), []byte(password)) != nil {
+		uc.lockout.RecordFailure(ctx, subject, ip, userAgent)
+		return ErrPublicFeedUnauthorized
+	}
+	uc.lockout.RecordSuccess(ctx, subject)
+	return nil
+}
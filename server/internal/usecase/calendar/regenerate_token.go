@@ -11,6 +11,7 @@ import (
 type RegenerateTokenOutput struct {
 	Enabled   bool       `json:"enabled"`
 	PublicURL *string    `json:"public_url"`
+	EmbedURL  *string    `json:"embed_url"`
 	Token     *string    `json:"token"`
 	EnabledAt *time.Time `json:"enabled_at"`
 	Message   string     `json:"message"`
@@ -52,9 +53,11 @@ func (uc *RegenerateTokenUseCase) Execute(ctx context.Context, userID, calendarI
 	}
 
 	url := fmt.Sprintf("%s/public/calendar/%s.ics", uc.baseURL, *cal.PublicToken)
+	embedURL := fmt.Sprintf("%s/public/calendar/%s/view", uc.baseURL, *cal.PublicToken)
 	return &RegenerateTokenOutput{
 		Enabled:   true,
 		PublicURL: &url,
+		EmbedURL:  &embedURL,
 		Token:     cal.PublicToken,
 		EnabledAt: cal.PublicEnabledAt,
 		Message:   "Previous public URL is no longer valid",
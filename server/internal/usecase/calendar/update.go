@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/jherrma/caldav-server/internal/domain/calendar"
+	"github.com/jherrma/caldav-server/internal/domain/sharing"
 )
 
 // UpdateCalendarRequest represents the request to update a calendar
@@ -13,16 +14,18 @@ type UpdateCalendarRequest struct {
 	Description *string `json:"description"`
 	Color       *string `json:"color"`
 	Timezone    *string `json:"timezone"`
+	Hidden      *bool   `json:"hidden"` // shared calendars only
 }
 
 // UpdateCalendarUseCase handles calendar updates
 type UpdateCalendarUseCase struct {
-	repo calendar.CalendarRepository
+	repo      calendar.CalendarRepository
+	shareRepo sharing.CalendarShareRepository
 }
 
 // NewUpdateCalendarUseCase creates a new use case
-func NewUpdateCalendarUseCase(repo calendar.CalendarRepository) *UpdateCalendarUseCase {
-	return &UpdateCalendarUseCase{repo: repo}
+func NewUpdateCalendarUseCase(repo calendar.CalendarRepository, shareRepo sharing.CalendarShareRepository) *UpdateCalendarUseCase {
+	return &UpdateCalendarUseCase{repo: repo, shareRepo: shareRepo}
 }
 
 // Execute updates a calendar
@@ -33,9 +36,12 @@ func (uc *UpdateCalendarUseCase) Execute(ctx context.Context, userID uint, calen
 		return nil, fmt.Errorf("calendar not found")
 	}
 
-	// Verify ownership
+	// Sharees change how the calendar appears to them, not the calendar
 	if cal.UserID != userID {
-		return nil, fmt.Errorf("access denied")
+		return uc.updateShared(ctx, userID, cal, req)
+	}
+	if req.Hidden != nil {
+		return nil, fmt.Errorf("only shared calendars can be hidden")
 	}
 
 	// Update fields if provided
@@ -76,3 +82,55 @@ func (uc *UpdateCalendarUseCase) Execute(ctx context.Context, userID uint, calen
 
 	return cal, nil
 }
+
+// updateShared saves the display name, color and visibility a sharee chose
+// for a calendar shared with them; empty values restore the owner's
+func (uc *UpdateCalendarUseCase) updateShared(ctx context.Context, userID uint, cal *calendar.Calendar, req UpdateCalendarRequest) (*calendar.Calendar, error) {
+	if uc.shareRepo == nil {
+		return nil, fmt.Errorf("access denied")
+	}
+	shares, err := uc.shareRepo.FindCalendarsSharedWithUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load shared calendars: %w", err)
+	}
+	var mount *sharing.SharedCalendar
+	for i := range shares {
+		if shares[i].CalendarID == cal.ID {
+			mount = shares[i].Mount
+			break
+		}
+	}
+	if mount == nil {
+		return nil, fmt.Errorf("access denied")
+	}
+
+	if req.Description != nil || req.Timezone != nil {
+		return nil, fmt.Errorf("only the owner can change the description or timezone")
+	}
+	if req.Name != nil {
+		if *req.Name != "" {
+			if err := calendar.ValidateName(*req.Name); err != nil {
+				return nil, err
+			}
+		}
+		mount.DisplayName = *req.Name
+	}
+	if req.Color != nil {
+		if *req.Color != "" {
+			if err := calendar.ValidateHexColor(*req.Color); err != nil {
+				return nil, err
+			}
+		}
+		mount.Color = *req.Color
+	}
+	if req.Hidden != nil {
+		mount.Hidden = *req.Hidden
+	}
+
+	if err := uc.shareRepo.UpdateSharedCalendar(ctx, mount); err != nil {
+		return nil, fmt.Errorf("failed to update calendar: %w", err)
+	}
+
+	view := mount.Apply(*cal)
+	return &view, nil
+}
@@ -0,0 +1,46 @@
+package contact
+
+import (
+	"context"
+
+	"github.com/jherrma/caldav-server/internal/domain/addressbook"
+	"github.com/jherrma/caldav-server/internal/domain/contact"
+)
+
+type AddGroupMemberUseCase struct {
+	repo addressbook.Repository
+}
+
+func NewAddGroupMemberUseCase(repo addressbook.Repository) *AddGroupMemberUseCase {
+	return &AddGroupMemberUseCase{repo: repo}
+}
+
+// Execute adds the contact to the group. Adding an existing member is a
+// no-op.
+func (uc *AddGroupMemberUseCase) Execute(ctx context.Context, addressBookID uint, groupUUID, contactUUID string) (*contact.Group, error) {
+	obj, card, err := loadGroup(ctx, uc.repo, addressBookID, groupUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	member, err := uc.repo.GetObjectByUUID(ctx, contactUUID)
+	if err != nil {
+		return nil, err
+	}
+	if member == nil || member.AddressBookID != addressBookID || member.UUID == obj.UUID {
+		return nil, ErrMemberNotFound
+	}
+
+	members := addressbook.GroupMembers(card)
+	for _, uid := range members {
+		if uid == member.UID {
+			return toGroup(ctx, uc.repo, obj, card)
+		}
+	}
+
+	addressbook.SetGroup(card, addressbook.GroupStyle(card), append(members, member.UID))
+	if err := saveCard(ctx, uc.repo, obj, card); err != nil {
+		return nil, err
+	}
+	return toGroup(ctx, uc.repo, obj, card)
+}
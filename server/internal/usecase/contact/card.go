@@ -0,0 +1,58 @@
+package contact
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-vcard"
+	"github.com/jherrma/caldav-server/internal/domain/addressbook"
+)
+
+// loadCard fetches a contact object of the address book together with its
+// parsed vCard. GetObjectByUUID injects the stored photo, so the card is
+// complete. A nil object means the contact does not exist in this address
+// book.
+func loadCard(ctx context.Context, repo addressbook.Repository, addressBookID uint, contactUUID string) (*addressbook.AddressObject, vcard.Card, error) {
+	obj, err := repo.GetObjectByUUID(ctx, contactUUID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if obj == nil || obj.AddressBookID != addressBookID {
+		return nil, nil, nil
+	}
+	card, err := vcard.NewDecoder(strings.NewReader(obj.VCardData)).Decode()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse vcard for contact %s: %w", contactUUID, err)
+	}
+	return obj, card, nil
+}
+
+// saveCard writes a modified vCard back to its object. UpdateObject bumps
+// the sync token so CardDAV clients pick up the change.
+func saveCard(ctx context.Context, repo addressbook.Repository, obj *addressbook.AddressObject, card vcard.Card) error {
+	if err := encodeCard(obj, card); err != nil {
+		return err
+	}
+	return repo.UpdateObject(ctx, obj)
+}
+
+// encodeCard stores a modified vCard in its object without saving it
+func encodeCard(obj *addressbook.AddressObject, card vcard.Card) error {
+	card.SetValue(vcard.FieldRevision, time.Now().UTC().Format("20060102T150405Z"))
+
+	var buf bytes.Buffer
+	if err := vcard.NewEncoder(&buf).Encode(card); err != nil {
+		return fmt.Errorf("failed to encode vcard: %w", err)
+	}
+
+	obj.VCardData = buf.String()
+	obj.VCardVersion = card.Value(vcard.FieldVersion)
+	obj.ContentLength = len(obj.VCardData)
+	obj.ETag = fmt.Sprintf("%d", time.Now().UnixNano())
+	obj.UpdatedAt = time.Now()
+	addressbook.ExtractDenormFieldsFromCard(card, obj)
+	return nil
+}
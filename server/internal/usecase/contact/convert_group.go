@@ -0,0 +1,38 @@
+package contact
+
+import (
+	"context"
+
+	"github.com/jherrma/caldav-server/internal/domain/addressbook"
+	"github.com/jherrma/caldav-server/internal/domain/contact"
+)
+
+type ConvertGroupUseCase struct {
+	repo addressbook.Repository
+}
+
+func NewConvertGroupUseCase(repo addressbook.Repository) *ConvertGroupUseCase {
+	return &ConvertGroupUseCase{repo: repo}
+}
+
+// Execute rewrites the group in the given style (vCard 4 KIND:group or Apple
+// X-ADDRESSBOOKSERVER-KIND), keeping its members
+func (uc *ConvertGroupUseCase) Execute(ctx context.Context, addressBookID uint, groupUUID, style string) (*contact.Group, error) {
+	if !addressbook.IsValidGroupStyle(style) {
+		return nil, ErrInvalidGroupStyle
+	}
+
+	obj, card, err := loadGroup(ctx, uc.repo, addressBookID, groupUUID)
+	if err != nil {
+		return nil, err
+	}
+	if addressbook.GroupStyle(card) == style {
+		return toGroup(ctx, uc.repo, obj, card)
+	}
+
+	addressbook.ConvertGroupStyle(card, style)
+	if err := saveCard(ctx, uc.repo, obj, card); err != nil {
+		return nil, err
+	}
+	return toGroup(ctx, uc.repo, obj, card)
+}
@@ -0,0 +1,41 @@
+package contact
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/emersion/go-vcard"
+	"github.com/jherrma/caldav-server/internal/domain/addressbook"
+	addressbookuc "github.com/jherrma/caldav-server/internal/usecase/addressbook"
+)
+
+// CreateCardUseCase stores a contact given as a complete vCard, as decoded
+// from a jCard or JSContact request body
+type CreateCardUseCase struct {
+	repo       addressbook.Repository
+	addrBookUC *addressbookuc.CreateContactUseCase
+}
+
+func NewCreateCardUseCase(repo addressbook.Repository, addrBookUC *addressbookuc.CreateContactUseCase) *CreateCardUseCase {
+	return &CreateCardUseCase{repo: repo, addrBookUC: addrBookUC}
+}
+
+// Execute returns the stored object and its vCard as read back from the
+// repository
+func (uc *CreateCardUseCase) Execute(ctx context.Context, userID, addressBookID uint, card vcard.Card) (*addressbook.AddressObject, vcard.Card, error) {
+	var buf bytes.Buffer
+	if err := vcard.NewEncoder(&buf).Encode(card); err != nil {
+		return nil, nil, fmt.Errorf("failed to encode vcard: %w", err)
+	}
+
+	obj, err := uc.addrBookUC.Execute(ctx, addressbookuc.CreateContactInput{
+		UserID:        userID,
+		AddressBookID: addressBookID,
+		VCardData:     buf.String(),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return loadCard(ctx, uc.repo, addressBookID, obj.UUID)
+}
@@ -0,0 +1,77 @@
+package contact
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/emersion/go-vcard"
+	"github.com/google/uuid"
+	"github.com/jherrma/caldav-server/internal/domain/addressbook"
+	"github.com/jherrma/caldav-server/internal/domain/contact"
+	addressbookuc "github.com/jherrma/caldav-server/internal/usecase/addressbook"
+)
+
+// CreateGroupInput holds the data for a new contact group
+type CreateGroupInput struct {
+	Name      string   `json:"name"`
+	Style     string   `json:"style"`      // "vcard4" or "apple" (default)
+	MemberIDs []string `json:"member_ids"` // Contact UUIDs in the same address book
+}
+
+type CreateGroupUseCase struct {
+	repo       addressbook.Repository
+	addrBookUC *addressbookuc.CreateContactUseCase
+}
+
+func NewCreateGroupUseCase(repo addressbook.Repository, addrBookUC *addressbookuc.CreateContactUseCase) *CreateGroupUseCase {
+	return &CreateGroupUseCase{repo: repo, addrBookUC: addrBookUC}
+}
+
+func (uc *CreateGroupUseCase) Execute(ctx context.Context, userID, addressBookID uint, input CreateGroupInput) (*contact.Group, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return nil, ErrGroupNameRequired
+	}
+	if input.Style == "" {
+		input.Style = addressbook.GroupStyleApple
+	}
+	if !addressbook.IsValidGroupStyle(input.Style) {
+		return nil, ErrInvalidGroupStyle
+	}
+
+	memberUIDs := make([]string, 0, len(input.MemberIDs))
+	for _, id := range input.MemberIDs {
+		m, err := uc.repo.GetObjectByUUID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if m == nil || m.AddressBookID != addressBookID {
+			return nil, ErrMemberNotFound
+		}
+		memberUIDs = append(memberUIDs, m.UID)
+	}
+
+	card := make(vcard.Card)
+	card.SetValue(vcard.FieldUID, uuid.New().String())
+	card.SetValue(vcard.FieldFormattedName, name)
+	// Apple clients display N rather than FN for groups
+	card.SetValue(vcard.FieldName, name)
+	addressbook.SetGroup(card, input.Style, memberUIDs)
+
+	var buf bytes.Buffer
+	if err := vcard.NewEncoder(&buf).Encode(card); err != nil {
+		return nil, fmt.Errorf("failed to encode group vcard: %w", err)
+	}
+
+	obj, err := uc.addrBookUC.Execute(ctx, addressbookuc.CreateContactInput{
+		UserID:        userID,
+		AddressBookID: addressBookID,
+		VCardData:     buf.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toGroup(ctx, uc.repo, obj, card)
+}
@@ -27,5 +27,13 @@ func (uc *DeleteUseCase) Execute(ctx context.Context, addressBookID uint, contac
 
 	// 2. Delete object. DeleteObjectByUUID atomically bumps the address
 	// book's sync_token / CTag and records a "deleted" SyncChangeLog entry.
-	return uc.repo.DeleteObjectByUUID(ctx, contactUUID)
+	if err := uc.repo.DeleteObjectByUUID(ctx, contactUUID); err != nil {
+		return err
+	}
+
+	// 3. Drop the contact from the groups that listed it
+	if err := RemoveFromGroups(ctx, uc.repo, addressBookID, obj.UID); err != nil {
+		return fmt.Errorf("contact deleted but group memberships could not be updated: %w", err)
+	}
+	return nil
 }
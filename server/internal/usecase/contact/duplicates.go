@@ -0,0 +1,189 @@
+package contact
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/emersion/go-vcard"
+	"github.com/jherrma/caldav-server/internal/domain/addressbook"
+	"github.com/jherrma/caldav-server/internal/domain/contact"
+)
+
+// DefaultDuplicateThreshold is the minimum pair score reported as a
+// duplicate: a shared email or phone number is enough on its own, a shared
+// name needs the organization to agree as well
+const DefaultDuplicateThreshold = 0.5
+
+// Weights of the individual signals; the pair score is their sum capped at 1
+const (
+	weightEmail        = 0.6
+	weightPhone        = 0.5
+	weightName         = 0.4
+	weightOrganization = 0.1
+)
+
+// phoneSuffixDigits is the number of trailing digits compared for phone
+// numbers. It covers the subscriber number while ignoring how the country or
+// trunk prefix was written ("+49 30 …" vs "030 …").
+const phoneSuffixDigits = 9
+
+// legalSuffixes are dropped from organization names before comparing
+var legalSuffixes = map[string]bool{
+	"inc": true, "incorporated": true, "llc": true, "ltd": true, "limited": true,
+	"corp": true, "corporation": true, "co": true, "company": true, "plc": true,
+	"gmbh": true, "ag": true, "kg": true, "ug": true, "sa": true, "sarl": true,
+	"bv": true, "nv": true, "srl": true, "spa": true, "ab": true, "oy": true,
+}
+
+// matchKeys holds the normalized values of a card used for duplicate scoring
+type matchKeys struct {
+	emails       []string
+	phones       []string
+	name         string
+	organization string
+}
+
+// cardMatchKeys extracts the normalized matching values of a vCard. Every
+// EMAIL and TEL counts, not only the preferred one.
+func cardMatchKeys(card vcard.Card) matchKeys {
+	var k matchKeys
+	for _, f := range card[vcard.FieldEmail] {
+		if v := normalizeEmail(f.Value); v != "" {
+			k.emails = appendUnique(k.emails, v)
+		}
+	}
+	for _, f := range card[vcard.FieldTelephone] {
+		if v := normalizePhone(f.Value); v != "" {
+			k.phones = appendUnique(k.phones, v)
+		}
+	}
+
+	if n := card.Name(); n != nil && (n.GivenName != "" || n.FamilyName != "") {
+		k.name = normalizeName(n.GivenName + " " + n.FamilyName)
+	} else {
+		k.name = normalizeName(card.PreferredValue(vcard.FieldFormattedName))
+	}
+
+	if org := card.PreferredValue(vcard.FieldOrganization); org != "" {
+		// Only the organization name, not its units
+		k.organization = normalizeOrganization(strings.SplitN(org, ";", 2)[0])
+	}
+	return k
+}
+
+// scoreMatch scores how likely two cards describe the same person and
+// names the signals that agreed
+func scoreMatch(a, b matchKeys) (float64, []string) {
+	var score float64
+	var reasons []string
+
+	if intersects(a.emails, b.emails) {
+		score += weightEmail
+		reasons = append(reasons, contact.MatchEmail)
+	}
+	if intersects(a.phones, b.phones) {
+		score += weightPhone
+		reasons = append(reasons, contact.MatchPhone)
+	}
+	if a.name != "" && a.name == b.name {
+		score += weightName
+		reasons = append(reasons, contact.MatchName)
+	}
+	// A shared employer says little on its own
+	if len(reasons) > 0 && a.organization != "" && a.organization == b.organization {
+		score += weightOrganization
+		reasons = append(reasons, contact.MatchOrganization)
+	}
+
+	if score > 1 {
+		score = 1
+	}
+	return score, reasons
+}
+
+// blockingKeys returns the keys a card is bucketed under, so only cards
+// sharing at least one key are compared
+func (k matchKeys) blockingKeys() []string {
+	var keys []string
+	for _, e := range k.emails {
+		keys = append(keys, "e:"+e)
+	}
+	for _, p := range k.phones {
+		keys = append(keys, "p:"+p)
+	}
+	if k.name != "" {
+		keys = append(keys, "n:"+k.name)
+	}
+	return keys
+}
+
+// normalizeEmail lowercases an address and strips a mailto: prefix
+func normalizeEmail(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	return strings.TrimPrefix(s, "mailto:")
+}
+
+// normalizePhone reduces a phone number to its digits and keeps the last
+// phoneSuffixDigits of them. Numbers too short to be meaningful yield "".
+func normalizePhone(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	digits := strings.TrimLeft(b.String(), "0")
+	if len(digits) < 6 {
+		return ""
+	}
+	if len(digits) > phoneSuffixDigits {
+		digits = digits[len(digits)-phoneSuffixDigits:]
+	}
+	return digits
+}
+
+// normalizeName folds case and accents, drops punctuation and sorts the name
+// tokens so "Müller, Hans" and "hans muller" line up
+func normalizeName(s string) string {
+	tokens := strings.FieldsFunc(addressbook.FoldText(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	sort.Strings(tokens)
+	return strings.Join(tokens, " ")
+}
+
+// normalizeOrganization folds an organization name and drops legal form
+// suffixes such as "Inc." or "GmbH"
+func normalizeOrganization(s string) string {
+	tokens := strings.FieldsFunc(addressbook.FoldText(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	kept := tokens[:0]
+	for _, t := range tokens {
+		if !legalSuffixes[t] {
+			kept = append(kept, t)
+		}
+	}
+	return strings.Join(kept, " ")
+}
+
+func intersects(a, b []string) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func appendUnique(list []string, v string) []string {
+	for _, x := range list {
+		if x == v {
+			return list
+		}
+	}
+	return append(list, v)
+}
@@ -0,0 +1,83 @@
+package contact
+
+import (
+	"testing"
+
+	"github.com/emersion/go-vcard"
+	"github.com/jherrma/caldav-server/internal/domain/contact"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeMatchValues(t *testing.T) {
+	assert.Equal(t, "jane@acme.example", normalizeEmail(" MAILTO:Jane@ACME.example "))
+	assert.Equal(t, normalizePhone("+49 30 1234567"), normalizePhone("030/1234567"))
+	assert.Equal(t, normalizePhone("0049 (30) 123-4567"), normalizePhone("+49301234567"))
+	assert.Empty(t, normalizePhone("112"))
+	assert.Equal(t, "hans muller", normalizeName("Müller, Hans"))
+	assert.Equal(t, normalizeName("hans MULLER"), normalizeName("Müller, Hans"))
+	assert.Equal(t, "acme", normalizeOrganization("ACME, Inc."))
+	assert.Equal(t, normalizeOrganization("Beispiel GmbH"), normalizeOrganization("beispiel"))
+}
+
+func TestScoreMatch(t *testing.T) {
+	card := func(data string) matchKeys {
+		return cardMatchKeys(parseCard(t, "BEGIN:VCARD\r\nVERSION:3.0\r\n"+data+"END:VCARD\r\n"))
+	}
+
+	jane := card("FN:Jane Public\r\nN:Public;Jane;;;\r\nEMAIL:jane@acme.example\r\nEMAIL:jane@home.example\r\nTEL:+1 555 010 0100\r\nORG:ACME Inc.\r\n")
+
+	tests := []struct {
+		name    string
+		other   matchKeys
+		score   float64
+		reasons []string
+	}{
+		{"secondary email", card("FN:J. P.\r\nEMAIL:JANE@home.example\r\n"), weightEmail, []string{contact.MatchEmail}},
+		{"phone formatting", card("FN:Someone\r\nTEL:(555) 010-0100\r\n"), weightPhone, []string{contact.MatchPhone}},
+		{"name only", card("FN:Public Jane\r\n"), weightName, []string{contact.MatchName}},
+		{"name and organization", card("FN:Jane Public\r\nORG:Acme;Sales\r\n"), weightName + weightOrganization, []string{contact.MatchName, contact.MatchOrganization}},
+		{"organization alone", card("FN:John Doe\r\nORG:ACME\r\n"), 0, nil},
+		{"everything", card("N:Public;Jane;;;\r\nEMAIL:jane@acme.example\r\nTEL:15550100100\r\nORG:Acme\r\n"), 1, []string{contact.MatchEmail, contact.MatchPhone, contact.MatchName, contact.MatchOrganization}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score, reasons := scoreMatch(jane, tt.other)
+			assert.InDelta(t, tt.score, score, 1e-9)
+			assert.Equal(t, tt.reasons, reasons)
+		})
+	}
+}
+
+func TestMergeCard(t *testing.T) {
+	dst := parseCard(t, "BEGIN:VCARD\r\nVERSION:3.0\r\nUID:a\r\nFN:Jane Public\r\n"+
+		"EMAIL;TYPE=work:jane@acme.example\r\nTEL:+1 555 0100 100\r\n"+
+		"item1.URL:https://jane.example\r\nitem1.X-ABLABEL:Blog\r\nEND:VCARD\r\n")
+	src := parseCard(t, "BEGIN:VCARD\r\nVERSION:3.0\r\nUID:b\r\nFN:Jane Q. Public\r\nBDAY:1985-04-12\r\n"+
+		"EMAIL:JANE@acme.example\r\nEMAIL;TYPE=home:jane@home.example\r\nTEL:15550100100\r\n"+
+		"item1.URL:https://jane.example\r\nitem1.X-ABLABEL:Blog\r\n"+
+		"item2.EMAIL:jp@example.org\r\nitem2.X-ABLABEL:Old\r\nCATEGORIES:friends\r\nEND:VCARD\r\n")
+
+	mergeCard(dst, src)
+
+	assert.Equal(t, "a", dst.Value(vcard.FieldUID))
+	assert.Equal(t, "Jane Public", dst.Value(vcard.FieldFormattedName))
+	assert.Equal(t, "1985-04-12", dst.Value(vcard.FieldBirthday))
+	assert.Equal(t, []string{"jane@acme.example", "jane@home.example", "jp@example.org"}, fieldValues(dst[vcard.FieldEmail]))
+	assert.Len(t, dst[vcard.FieldTelephone], 1)
+	assert.Len(t, dst[vcard.FieldURL], 1)
+	assert.Equal(t, []string{"friends"}, dst.Categories())
+
+	// The new labelled email moved to a group name unused in the target
+	assert.Equal(t, "item2", dst[vcard.FieldEmail][2].Group)
+	assert.Len(t, dst["X-ABLABEL"], 2)
+	assert.Equal(t, "item2", dst["X-ABLABEL"][1].Group)
+	assert.Equal(t, "Old", dst["X-ABLABEL"][1].Value)
+}
+
+func fieldValues(fields []*vcard.Field) []string {
+	var values []string
+	for _, f := range fields {
+		values = append(values, f.Value)
+	}
+	return values
+}
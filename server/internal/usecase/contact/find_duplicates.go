@@ -0,0 +1,163 @@
+package contact
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/emersion/go-vcard"
+	"github.com/jherrma/caldav-server/internal/domain/addressbook"
+	"github.com/jherrma/caldav-server/internal/domain/contact"
+)
+
+// FindDuplicatesUseCase finds contacts that probably describe the same
+// person, within one address book or across all address books of a user
+type FindDuplicatesUseCase struct {
+	repo addressbook.Repository
+}
+
+func NewFindDuplicatesUseCase(repo addressbook.Repository) *FindDuplicatesUseCase {
+	return &FindDuplicatesUseCase{repo: repo}
+}
+
+type FindDuplicatesInput struct {
+	UserID        uint
+	AddressBookID *uint   // Restrict to one address book; nil searches all of the user's
+	MinScore      float64 // Minimum pair score; DefaultDuplicateThreshold when zero
+}
+
+type FindDuplicatesOutput struct {
+	Clusters []*contact.DuplicateCluster `json:"clusters"`
+	Count    int                         `json:"count"`
+}
+
+// candidate is a contact considered for duplicate detection
+type candidate struct {
+	obj  *addressbook.AddressObject
+	keys matchKeys
+}
+
+// scoredPair is a pair of candidate indexes that scored above the threshold
+type scoredPair struct {
+	a, b    int
+	score   float64
+	reasons []string
+}
+
+func (uc *FindDuplicatesUseCase) Execute(ctx context.Context, input FindDuplicatesInput) (*FindDuplicatesOutput, error) {
+	if input.MinScore <= 0 {
+		input.MinScore = DefaultDuplicateThreshold
+	}
+
+	// 1. Collect the individual contacts of the user's address books
+	books, err := uc.repo.ListByUserID(ctx, input.UserID)
+	if err != nil {
+		return nil, err
+	}
+	var candidates []candidate
+	for _, ab := range books {
+		if input.AddressBookID != nil && ab.ID != *input.AddressBookID {
+			continue
+		}
+		objs, _, err := uc.repo.ListObjects(ctx, ab.ID, -1, 0, "name", "asc")
+		if err != nil {
+			return nil, err
+		}
+		for i := range objs {
+			card, err := vcard.NewDecoder(strings.NewReader(objs[i].VCardData)).Decode()
+			if err != nil || addressbook.CardKind(card) == addressbook.KindGroup {
+				continue // Groups are not people; unparsable cards can't be compared
+			}
+			candidates = append(candidates, candidate{obj: &objs[i], keys: cardMatchKeys(card)})
+		}
+	}
+
+	// 2. Bucket by blocking keys and score only the pairs sharing a bucket
+	buckets := map[string][]int{}
+	for i, c := range candidates {
+		for _, key := range c.keys.blockingKeys() {
+			buckets[key] = append(buckets[key], i)
+		}
+	}
+
+	seen := map[[2]int]bool{}
+	var matches []scoredPair
+	parent := make([]int, len(candidates))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	for _, members := range buckets {
+		for x := 0; x < len(members); x++ {
+			for y := x + 1; y < len(members); y++ {
+				a, b := members[x], members[y]
+				if seen[[2]int{a, b}] {
+					continue
+				}
+				seen[[2]int{a, b}] = true
+
+				score, reasons := scoreMatch(candidates[a].keys, candidates[b].keys)
+				if score < input.MinScore {
+					continue
+				}
+				matches = append(matches, scoredPair{a: a, b: b, score: score, reasons: reasons})
+				parent[find(a)] = find(b)
+			}
+		}
+	}
+
+	// 3. Connected contacts form a cluster
+	clusters := map[int]*contact.DuplicateCluster{}
+	indexes := map[int][]int{}
+	for _, m := range matches {
+		root := find(m.a)
+		cl := clusters[root]
+		if cl == nil {
+			cl = &contact.DuplicateCluster{}
+			clusters[root] = cl
+		}
+		if m.score > cl.Score {
+			cl.Score = m.score
+		}
+		cl.Matches = append(cl.Matches, contact.DuplicateMatch{
+			ContactA: candidates[m.a].obj.UUID,
+			ContactB: candidates[m.b].obj.UUID,
+			Score:    m.score,
+			Reasons:  m.reasons,
+		})
+	}
+	for i := range candidates {
+		if _, ok := clusters[find(i)]; ok {
+			indexes[find(i)] = append(indexes[find(i)], i)
+		}
+	}
+
+	out := &FindDuplicatesOutput{Clusters: []*contact.DuplicateCluster{}}
+	for root, cl := range clusters {
+		for _, i := range indexes[root] {
+			cl.Contacts = append(cl.Contacts, FromAddressObject(candidates[i].obj))
+		}
+		sort.Slice(cl.Matches, func(i, j int) bool { return cl.Matches[i].Score > cl.Matches[j].Score })
+		out.Clusters = append(out.Clusters, cl)
+	}
+	// Most certain clusters first, then by the name of their first contact
+	sort.Slice(out.Clusters, func(i, j int) bool {
+		a, b := out.Clusters[i], out.Clusters[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Contacts[0].FormattedName != b.Contacts[0].FormattedName {
+			return a.Contacts[0].FormattedName < b.Contacts[0].FormattedName
+		}
+		return a.Contacts[0].ID < b.Contacts[0].ID
+	})
+	out.Count = len(out.Clusters)
+	return out, nil
+}
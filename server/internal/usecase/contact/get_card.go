@@ -0,0 +1,24 @@
+package contact
+
+import (
+	"context"
+
+	"github.com/emersion/go-vcard"
+	"github.com/jherrma/caldav-server/internal/domain/addressbook"
+)
+
+// GetCardUseCase returns the full stored vCard of a contact, for the jCard
+// and JSContact representations
+type GetCardUseCase struct {
+	repo addressbook.Repository
+}
+
+func NewGetCardUseCase(repo addressbook.Repository) *GetCardUseCase {
+	return &GetCardUseCase{repo: repo}
+}
+
+// Execute returns nil when the contact does not exist in the address book
+func (uc *GetCardUseCase) Execute(ctx context.Context, addressBookID uint, contactUUID string) (vcard.Card, error) {
+	_, card, err := loadCard(ctx, uc.repo, addressBookID, contactUUID)
+	return card, err
+}
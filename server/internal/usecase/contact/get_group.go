@@ -0,0 +1,24 @@
+package contact
+
+import (
+	"context"
+
+	"github.com/jherrma/caldav-server/internal/domain/addressbook"
+	"github.com/jherrma/caldav-server/internal/domain/contact"
+)
+
+type GetGroupUseCase struct {
+	repo addressbook.Repository
+}
+
+func NewGetGroupUseCase(repo addressbook.Repository) *GetGroupUseCase {
+	return &GetGroupUseCase{repo: repo}
+}
+
+func (uc *GetGroupUseCase) Execute(ctx context.Context, addressBookID uint, groupUUID string) (*contact.Group, error) {
+	obj, card, err := loadGroup(ctx, uc.repo, addressBookID, groupUUID)
+	if err != nil {
+		return nil, err
+	}
+	return toGroup(ctx, uc.repo, obj, card)
+}
@@ -0,0 +1,94 @@
+package contact
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/emersion/go-vcard"
+	"github.com/jherrma/caldav-server/internal/domain/addressbook"
+	"github.com/jherrma/caldav-server/internal/domain/contact"
+)
+
+var (
+	ErrGroupNotFound     = errors.New("group not found")
+	ErrMemberNotFound    = errors.New("member contact not found in this address book")
+	ErrInvalidGroupStyle = errors.New("group style must be \"vcard4\" or \"apple\"")
+	ErrGroupNameRequired = errors.New("group name is required")
+)
+
+// loadGroup fetches a group object of the address book and parses its vCard
+func loadGroup(ctx context.Context, repo addressbook.Repository, addressBookID uint, groupUUID string) (*addressbook.AddressObject, vcard.Card, error) {
+	obj, err := repo.GetObjectByUUID(ctx, groupUUID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if obj == nil || obj.AddressBookID != addressBookID {
+		return nil, nil, ErrGroupNotFound
+	}
+	card, err := vcard.NewDecoder(strings.NewReader(obj.VCardData)).Decode()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse group vcard: %w", err)
+	}
+	if addressbook.GroupStyle(card) == "" {
+		return nil, nil, ErrGroupNotFound
+	}
+	return obj, card, nil
+}
+
+// toGroup maps a group object to the REST representation, resolving member
+// UIDs to the contacts of the same address book
+func toGroup(ctx context.Context, repo addressbook.Repository, obj *addressbook.AddressObject, card vcard.Card) (*contact.Group, error) {
+	g := &contact.Group{
+		ID:            obj.UUID,
+		AddressBookID: fmt.Sprintf("%d", obj.AddressBookID),
+		UID:           obj.UID,
+		Etag:          obj.ETag,
+		Name:          card.PreferredValue(vcard.FieldFormattedName),
+		Style:         addressbook.GroupStyle(card),
+		Members:       []contact.GroupMember{},
+		CreatedAt:     obj.CreatedAt,
+		UpdatedAt:     obj.UpdatedAt,
+	}
+
+	for _, uid := range addressbook.GroupMembers(card) {
+		member := contact.GroupMember{UID: uid}
+		m, err := repo.GetObjectByUID(ctx, obj.AddressBookID, uid)
+		if err != nil {
+			return nil, err
+		}
+		if m != nil {
+			member.ID = m.UUID
+			member.FormattedName = m.FormattedName
+		}
+		g.Members = append(g.Members, member)
+	}
+	return g, nil
+}
+
+// RemoveFromGroups drops a member from every group of the address book that
+// references it
+func RemoveFromGroups(ctx context.Context, repo addressbook.Repository, addressBookID uint, memberUID string) error {
+	groups, err := repo.ListGroups(ctx, addressBookID)
+	if err != nil {
+		return err
+	}
+	for _, g := range groups {
+		card, err := vcard.NewDecoder(strings.NewReader(g.VCardData)).Decode()
+		if err != nil || !addressbook.RemoveGroupMember(card, memberUID) {
+			continue // Not a member, or an unparsable group we leave alone
+		}
+		// Reload through GetObjectByUUID so the stored photo is part of the
+		// vCard; UpdateObject would otherwise drop it
+		obj, card, err := loadGroup(ctx, repo, addressBookID, g.UUID)
+		if err != nil {
+			return err
+		}
+		addressbook.RemoveGroupMember(card, memberUID)
+		if err := saveCard(ctx, repo, obj, card); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,380 @@
+package contact
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/emersion/go-vcard"
+	"github.com/jherrma/caldav-server/internal/domain/addressbook"
+)
+
+// Media types of the JSON contact representations
+const (
+	MediaTypeJCard     = "application/vcard+json"     // RFC 7095
+	MediaTypeJSContact = "application/jscontact+json" // RFC 9553
+)
+
+var ErrInvalidJCard = errors.New("invalid jCard")
+
+// Properties whose value is a list of ';'-separated components, encoded as a
+// JSON array in jCard
+var structuredFields = map[string]bool{
+	vcard.FieldName:         true,
+	vcard.FieldAddress:      true,
+	vcard.FieldGender:       true,
+	vcard.FieldOrganization: true,
+	vcard.FieldClientPIDMap: true,
+}
+
+// Properties whose value is a ','-separated list, encoded as several values
+var multiValueFields = map[string]bool{
+	vcard.FieldCategories: true,
+	vcard.FieldNickname:   true,
+}
+
+// ToJCard converts a card to its jCard form. The card keeps its VERSION, so
+// a vCard 3.0 contact round-trips unchanged; date values are written in the
+// extended ISO 8601 format jCard requires.
+func ToJCard(card vcard.Card) []interface{} {
+	version := card.Value(vcard.FieldVersion)
+
+	props := []interface{}{jcardProperty(vcard.FieldVersion, &vcard.Field{Value: version}, version)}
+	for _, name := range sortedFields(card) {
+		if name == vcard.FieldVersion {
+			continue
+		}
+		for _, f := range card[name] {
+			props = append(props, jcardProperty(name, f, version))
+		}
+	}
+	return []interface{}{"vcard", props}
+}
+
+// FromJCard parses a jCard document into a card
+func FromJCard(data []byte) (vcard.Card, error) {
+	var doc []json.RawMessage
+	if err := json.Unmarshal(data, &doc); err != nil || len(doc) != 2 {
+		return nil, fmt.Errorf("%w: expected [\"vcard\", [properties]]", ErrInvalidJCard)
+	}
+	var tag string
+	if err := json.Unmarshal(doc[0], &tag); err != nil || tag != "vcard" {
+		return nil, fmt.Errorf("%w: expected [\"vcard\", [properties]]", ErrInvalidJCard)
+	}
+	var props [][]interface{}
+	if err := json.Unmarshal(doc[1], &props); err != nil {
+		return nil, fmt.Errorf("%w: properties must be arrays", ErrInvalidJCard)
+	}
+
+	// VERSION decides the default value types, so read it first
+	version := ""
+	for _, p := range props {
+		if len(p) >= 4 && strings.EqualFold(fmt.Sprint(p[0]), vcard.FieldVersion) {
+			version = fmt.Sprint(p[3])
+		}
+	}
+
+	card := make(vcard.Card)
+	for _, p := range props {
+		name, field, err := parseJCardProperty(p, version)
+		if err != nil {
+			return nil, err
+		}
+		card.Add(name, field)
+	}
+	if card.Value(vcard.FieldVersion) == "" {
+		card.SetValue(vcard.FieldVersion, addressbook.VCardVersion4)
+	}
+	return card, nil
+}
+
+// jcardProperty encodes one vCard property as [name, params, type, value...]
+func jcardProperty(name string, f *vcard.Field, version string) []interface{} {
+	params := jcardParams(f.Params)
+	if f.Group != "" {
+		params["group"] = f.Group
+	}
+
+	valueType := defaultValueType(name, version)
+	if v := f.Params.Get(vcard.ParamValue); v != "" {
+		valueType = strings.ToLower(v)
+		delete(params, "value")
+	}
+
+	prop := []interface{}{strings.ToLower(name), params, valueType}
+	switch {
+	case structuredFields[name] && (name != vcard.FieldOrganization || strings.Contains(f.Value, ";")):
+		components := splitComponents(f.Value)
+		value := make([]interface{}, len(components))
+		for i, c := range components {
+			if (name == vcard.FieldName || name == vcard.FieldAddress) && strings.Contains(c, ",") {
+				parts := strings.Split(c, ",")
+				list := make([]interface{}, len(parts))
+				for j, p := range parts {
+					list[j] = p
+				}
+				value[i] = list
+			} else {
+				value[i] = c
+			}
+		}
+		prop = append(prop, value)
+	case multiValueFields[name]:
+		for _, v := range strings.Split(f.Value, ",") {
+			prop = append(prop, v)
+		}
+	case isDateType(valueType):
+		prop = append(prop, extendedDateTime(f.Value))
+	case valueType == "text":
+		prop = append(prop, strings.ReplaceAll(f.Value, `\;`, ";"))
+	default:
+		prop = append(prop, f.Value)
+	}
+	return prop
+}
+
+func parseJCardProperty(p []interface{}, version string) (string, *vcard.Field, error) {
+	if len(p) < 4 {
+		return "", nil, fmt.Errorf("%w: property needs name, parameters, type and value", ErrInvalidJCard)
+	}
+	rawName, ok := p[0].(string)
+	if !ok || rawName == "" {
+		return "", nil, fmt.Errorf("%w: property name must be a string", ErrInvalidJCard)
+	}
+	rawParams, ok := p[1].(map[string]interface{})
+	if !ok {
+		return "", nil, fmt.Errorf("%w: parameters of %s must be an object", ErrInvalidJCard, rawName)
+	}
+	valueType, ok := p[2].(string)
+	if !ok {
+		return "", nil, fmt.Errorf("%w: type of %s must be a string", ErrInvalidJCard, rawName)
+	}
+
+	name := strings.ToUpper(rawName)
+	field := &vcard.Field{}
+	if g, ok := rawParams["group"].(string); ok {
+		field.Group = g
+		delete(rawParams, "group")
+	}
+	params, err := vcardParams(rawParams)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: %s: %v", ErrInvalidJCard, rawName, err)
+	}
+	if len(params) > 0 {
+		field.Params = params
+	}
+	valueType = strings.ToLower(valueType)
+	if valueType != "unknown" && valueType != defaultValueType(name, version) {
+		if field.Params == nil {
+			field.Params = make(vcard.Params)
+		}
+		field.Params.Set(vcard.ParamValue, valueType)
+	}
+
+	values := make([]string, 0, len(p)-3)
+	for _, v := range p[3:] {
+		if list, ok := v.([]interface{}); ok {
+			components := make([]string, len(list))
+			for i, c := range list {
+				if sub, ok := c.([]interface{}); ok {
+					parts := make([]string, len(sub))
+					for j, s := range sub {
+						parts[j] = escapeComponent(jsonScalar(s))
+					}
+					components[i] = strings.Join(parts, ",")
+				} else {
+					components[i] = escapeComponent(jsonScalar(c))
+				}
+			}
+			values = append(values, strings.Join(components, ";"))
+			continue
+		}
+		value := jsonScalar(v)
+		if valueType == "text" && !structuredFields[name] {
+			value = strings.ReplaceAll(value, ";", `\;`)
+		}
+		values = append(values, value)
+	}
+	field.Value = strings.Join(values, ",")
+	return name, field, nil
+}
+
+// defaultValueType returns the value type a property has without a VALUE
+// parameter (RFC 6350, RFC 2426 for the binary properties of vCard 3.0)
+func defaultValueType(name, version string) string {
+	switch name {
+	case vcard.FieldPhoto, vcard.FieldLogo, vcard.FieldSound, vcard.FieldKey:
+		if version == addressbook.VCardVersion3 {
+			return "binary"
+		}
+		return "uri"
+	case vcard.FieldSource, vcard.FieldIMPP, vcard.FieldGeolocation, vcard.FieldMember, vcard.FieldURL,
+		vcard.FieldFreeOrBusyURL, vcard.FieldCalendarAddressURI, vcard.FieldCalendarURI, vcard.FieldRelated:
+		return "uri"
+	case vcard.FieldBirthday, vcard.FieldAnniversary, "DEATHDATE":
+		return "date-and-or-time"
+	case vcard.FieldRevision:
+		return "timestamp"
+	case vcard.FieldLanguage:
+		return "language-tag"
+	}
+	if strings.HasPrefix(name, "X-") || !knownFields[name] {
+		return "unknown"
+	}
+	return "text"
+}
+
+var knownFields = map[string]bool{
+	vcard.FieldSource: true, vcard.FieldKind: true, vcard.FieldXML: true,
+	vcard.FieldFormattedName: true, vcard.FieldName: true, vcard.FieldNickname: true, vcard.FieldPhoto: true,
+	vcard.FieldBirthday: true, vcard.FieldAnniversary: true, vcard.FieldGender: true, vcard.FieldAddress: true,
+	vcard.FieldTelephone: true, vcard.FieldEmail: true, vcard.FieldIMPP: true, vcard.FieldLanguage: true,
+	vcard.FieldTimezone: true, vcard.FieldGeolocation: true, vcard.FieldTitle: true, vcard.FieldRole: true,
+	vcard.FieldLogo: true, vcard.FieldOrganization: true, vcard.FieldMember: true, vcard.FieldRelated: true,
+	vcard.FieldCategories: true, vcard.FieldNote: true, vcard.FieldProductID: true, vcard.FieldRevision: true,
+	vcard.FieldSound: true, vcard.FieldUID: true, vcard.FieldClientPIDMap: true, vcard.FieldURL: true,
+	vcard.FieldVersion: true, vcard.FieldKey: true, vcard.FieldFreeOrBusyURL: true,
+	vcard.FieldCalendarAddressURI: true, vcard.FieldCalendarURI: true,
+	"LABEL": true, "MAILER": true, "CLASS": true, "SORT-STRING": true, "AGENT": true, "NAME": true,
+	"DEATHDATE": true, "DEATHPLACE": true, "BIRTHPLACE": true, "EXPERTISE": true, "HOBBY": true, "INTEREST": true,
+}
+
+func isDateType(valueType string) bool {
+	switch valueType {
+	case "date", "time", "date-time", "date-and-or-time", "timestamp":
+		return true
+	}
+	return false
+}
+
+// extendedDateTime rewrites a basic ISO 8601 date, time or timestamp
+// (19850412, --0412, T102200Z, 19961022T140000+0200) into the extended form
+// jCard uses. Values it does not recognise are returned unchanged.
+func extendedDateTime(value string) string {
+	date, timePart, hasTime := strings.Cut(value, "T")
+
+	switch {
+	case len(date) == 8 && isDigits(date):
+		date = date[:4] + "-" + date[4:6] + "-" + date[6:]
+	case len(date) == 6 && strings.HasPrefix(date, "--") && isDigits(date[2:]):
+		date = "--" + date[2:4] + "-" + date[4:]
+	}
+	if !hasTime {
+		return date
+	}
+
+	zone := ""
+	if i := strings.IndexAny(timePart, "Z+-"); i >= 0 {
+		timePart, zone = timePart[:i], timePart[i:]
+	}
+	if isDigits(timePart) && len(timePart)%2 == 0 && len(timePart) <= 6 {
+		var parts []string
+		for i := 0; i < len(timePart); i += 2 {
+			parts = append(parts, timePart[i:i+2])
+		}
+		timePart = strings.Join(parts, ":")
+	}
+	if len(zone) == 5 && isDigits(zone[1:]) {
+		zone = zone[:3] + ":" + zone[3:]
+	}
+	return date + "T" + timePart + zone
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// jcardParams converts vCard parameters into a jCard parameter object with
+// lowercase names; multi-valued parameters become arrays
+func jcardParams(params vcard.Params) map[string]interface{} {
+	out := make(map[string]interface{}, len(params))
+	for k, values := range params {
+		if len(values) == 1 {
+			out[strings.ToLower(k)] = values[0]
+			continue
+		}
+		list := make([]interface{}, len(values))
+		for i, v := range values {
+			list[i] = v
+		}
+		out[strings.ToLower(k)] = list
+	}
+	return out
+}
+
+func vcardParams(raw map[string]interface{}) (vcard.Params, error) {
+	params := make(vcard.Params, len(raw))
+	for k, v := range raw {
+		key := strings.ToUpper(k)
+		switch val := v.(type) {
+		case []interface{}:
+			for _, item := range val {
+				params.Add(key, jsonScalar(item))
+			}
+		case map[string]interface{}:
+			return nil, fmt.Errorf("parameter %s must be a string or an array", k)
+		default:
+			params.Add(key, jsonScalar(val))
+		}
+	}
+	return params, nil
+}
+
+func jsonScalar(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case bool:
+		return strings.ToUpper(strconv.FormatBool(val))
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+// splitComponents splits a structured value on unescaped semicolons
+func splitComponents(value string) []string {
+	var components []string
+	var cur strings.Builder
+	for i := 0; i < len(value); i++ {
+		switch {
+		case value[i] == '\\' && i+1 < len(value) && value[i+1] == ';':
+			cur.WriteByte(';')
+			i++
+		case value[i] == ';':
+			components = append(components, cur.String())
+			cur.Reset()
+		default:
+			cur.WriteByte(value[i])
+		}
+	}
+	return append(components, cur.String())
+}
+
+func escapeComponent(s string) string {
+	return strings.ReplaceAll(s, ";", `\;`)
+}
+
+// sortedFields returns the property names of a card in a stable order
+func sortedFields(card vcard.Card) []string {
+	names := make([]string, 0, len(card))
+	for name := range card {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,97 @@
+package contact
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/emersion/go-vcard"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// richVCard carries properties the contact DTO does not model
+const richVCard = "BEGIN:VCARD\r\n" +
+	"VERSION:4.0\r\n" +
+	"UID:urn:uuid:rich-1\r\n" +
+	"FN:Dr. Jane Q. Public\r\n" +
+	"N;SORT-AS=Public:Public;Jane;Q.,Quinn;Dr.;PhD\r\n" +
+	"NICKNAME:JQ,Janie\r\n" +
+	"ORG:ACME\\, Inc.;Research;Lab 3\r\n" +
+	"TITLE:Scientist\r\n" +
+	"EMAIL;TYPE=work;PREF=1:jane@acme.example\r\n" +
+	"TEL;TYPE=cell,voice,x-custom:+1-555-0100\r\n" +
+	"ADR;TYPE=home;LABEL=\"Main St 1\":;Apt 4;Main St 1;Springfield;IL;62701;USA\r\n" +
+	"IMPP;PREF=1:xmpp:jane@acme.example\r\n" +
+	"RELATED;TYPE=friend:urn:uuid:friend-1\r\n" +
+	"BDAY:19850412\r\n" +
+	"ANNIVERSARY:--0601\r\n" +
+	"GEO:geo:39.78,-89.65\r\n" +
+	"CATEGORIES:science,friends\r\n" +
+	"NOTE:Line one\\nLine two\r\n" +
+	"REV:20240102T030405Z\r\n" +
+	"item1.X-ABLABEL:Lab\r\n" +
+	"X-CUSTOM;X-PARAM=a,b:custom value\r\n" +
+	"END:VCARD\r\n"
+
+func parseCard(t *testing.T, data string) vcard.Card {
+	t.Helper()
+	card, err := vcard.NewDecoder(strings.NewReader(data)).Decode()
+	require.NoError(t, err)
+	return card
+}
+
+func TestJCard_RoundTrip(t *testing.T) {
+	for _, data := range []string{richVCard, appleGroupVCard} {
+		card := parseCard(t, data)
+
+		out, err := json.Marshal(ToJCard(card))
+		require.NoError(t, err)
+
+		back, err := FromJCard(out)
+		require.NoError(t, err)
+		assert.Equal(t, normalizeDates(card), back)
+	}
+}
+
+func TestJCard_Format(t *testing.T) {
+	out, err := json.Marshal(ToJCard(parseCard(t, richVCard)))
+	require.NoError(t, err)
+
+	var doc []interface{}
+	require.NoError(t, json.Unmarshal(out, &doc))
+	assert.Equal(t, "vcard", doc[0])
+	props := map[string][]interface{}{}
+	for _, p := range doc[1].([]interface{}) {
+		prop := p.([]interface{})
+		props[prop[0].(string)] = prop
+	}
+
+	assert.Equal(t, []interface{}{"version", map[string]interface{}{}, "text", "4.0"}, props["version"])
+	assert.Equal(t, []interface{}{"n", map[string]interface{}{"sort-as": "Public"}, "text",
+		[]interface{}{"Public", "Jane", []interface{}{"Q.", "Quinn"}, "Dr.", "PhD"}}, props["n"])
+	assert.Equal(t, []interface{}{"nickname", map[string]interface{}{}, "text", "JQ", "Janie"}, props["nickname"])
+	assert.Equal(t, []interface{}{"bday", map[string]interface{}{}, "date-and-or-time", "1985-04-12"}, props["bday"])
+	assert.Equal(t, []interface{}{"anniversary", map[string]interface{}{}, "date-and-or-time", "--06-01"}, props["anniversary"])
+	assert.Equal(t, []interface{}{"rev", map[string]interface{}{}, "timestamp", "2024-01-02T03:04:05Z"}, props["rev"])
+	assert.Equal(t, []interface{}{"x-ablabel", map[string]interface{}{"group": "item1"}, "unknown", "Lab"}, props["x-ablabel"])
+	assert.Equal(t, []interface{}{"x-custom", map[string]interface{}{"x-param": []interface{}{"a", "b"}}, "unknown", "custom value"}, props["x-custom"])
+}
+
+func TestFromJCard_Invalid(t *testing.T) {
+	for _, data := range []string{`{}`, `["vcard"]`, `["vcalendar", []]`, `["vcard", [["fn", {}, "text"]]]`, `["vcard", [["fn", [], "text", "x"]]]`} {
+		_, err := FromJCard([]byte(data))
+		assert.ErrorIs(t, err, ErrInvalidJCard, data)
+	}
+}
+
+// normalizeDates rewrites the basic-format dates of a card the way jCard
+// does, since those come back in extended format
+func normalizeDates(card vcard.Card) vcard.Card {
+	for _, name := range []string{vcard.FieldBirthday, vcard.FieldAnniversary, vcard.FieldRevision} {
+		for _, f := range card[name] {
+			f.Value = extendedDateTime(f.Value)
+		}
+	}
+	return card
+}
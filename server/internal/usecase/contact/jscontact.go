@@ -0,0 +1,695 @@
+package contact
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-vcard"
+	"github.com/jherrma/caldav-server/internal/domain/addressbook"
+)
+
+var ErrInvalidJSContact = errors.New("invalid JSContact card")
+
+// JSContactCard is a contact in the JSContact format of RFC 9553. vCard
+// properties without a JSContact equivalent travel in VCardProps as jCard
+// properties, and parameters that have no JSContact equivalent in the
+// VCardParams of the entry they belong to (RFC 9555), so a card converts
+// back without loss.
+type JSContactCard struct {
+	Type           string                     `json:"@type"`
+	Version        string                     `json:"version"`
+	UID            string                     `json:"uid,omitempty"`
+	Kind           string                     `json:"kind,omitempty"`
+	Name           *JSContactName             `json:"name,omitempty"`
+	Nicknames      map[string]*JSContactEntry `json:"nicknames,omitempty"`
+	Organizations  map[string]*JSContactEntry `json:"organizations,omitempty"`
+	Titles         map[string]*JSContactEntry `json:"titles,omitempty"`
+	Emails         map[string]*JSContactEntry `json:"emails,omitempty"`
+	Phones         map[string]*JSContactEntry `json:"phones,omitempty"`
+	Addresses      map[string]*JSContactEntry `json:"addresses,omitempty"`
+	OnlineServices map[string]*JSContactEntry `json:"onlineServices,omitempty"`
+	Links          map[string]*JSContactEntry `json:"links,omitempty"`
+	Media          map[string]*JSContactEntry `json:"media,omitempty"`
+	Anniversaries  map[string]*JSContactEntry `json:"anniversaries,omitempty"`
+	Notes          map[string]*JSContactEntry `json:"notes,omitempty"`
+	Keywords       map[string]bool            `json:"keywords,omitempty"`
+	RelatedTo      map[string]*JSContactEntry `json:"relatedTo,omitempty"`
+	Members        map[string]bool            `json:"members,omitempty"`
+	Updated        string                     `json:"updated,omitempty"`
+	ProdID         string                     `json:"prodId,omitempty"`
+	VCardProps     [][]interface{}            `json:"vCardProps,omitempty"`
+}
+
+// JSContactName is the name of a JSContact card
+type JSContactName struct {
+	Full        string                 `json:"full,omitempty"`
+	Components  []JSContactComponent   `json:"components,omitempty"`
+	VCardParams map[string]interface{} `json:"vCardParams,omitempty"`
+}
+
+// JSContactComponent is one part of a name or an address
+type JSContactComponent struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+// JSContactDate is a JSContact PartialDate
+type JSContactDate struct {
+	Type  string `json:"@type"`
+	Year  int    `json:"year,omitempty"`
+	Month int    `json:"month,omitempty"`
+	Day   int    `json:"day,omitempty"`
+}
+
+// JSContactEntry is an entry of one of the JSContact card collections. Only
+// the fields defined for its collection are set, e.g. address and contexts
+// for emails or number and features for phones.
+type JSContactEntry struct {
+	Kind        string                 `json:"kind,omitempty"`
+	Name        string                 `json:"name,omitempty"`
+	Units       []JSContactEntry       `json:"units,omitempty"`
+	Address     string                 `json:"address,omitempty"`
+	Number      string                 `json:"number,omitempty"`
+	URI         string                 `json:"uri,omitempty"`
+	Components  []JSContactComponent   `json:"components,omitempty"`
+	Date        *JSContactDate         `json:"date,omitempty"`
+	Note        string                 `json:"note,omitempty"`
+	Relation    map[string]bool        `json:"relation,omitempty"`
+	Features    map[string]bool        `json:"features,omitempty"`
+	Contexts    map[string]bool        `json:"contexts,omitempty"`
+	Pref        int                    `json:"pref,omitempty"`
+	VCardParams map[string]interface{} `json:"vCardParams,omitempty"`
+}
+
+// N and ADR component kinds in vCard order
+var (
+	nameComponentKinds    = []string{"surname", "given", "given2", "title", "credential"}
+	addressComponentKinds = []string{"postOfficeBox", "apartment", "name", "locality", "region", "postcode", "country"}
+)
+
+// vCard TEL types and their JSContact phone features
+var phoneFeatures = map[string]string{
+	"voice": "voice", "fax": "fax", "cell": "mobile", "text": "text",
+	"video": "video", "pager": "pager", "textphone": "textphone",
+}
+
+// ToJSContact converts a card to a JSContact card
+func ToJSContact(card vcard.Card) *JSContactCard {
+	version := card.Value(vcard.FieldVersion)
+	js := &JSContactCard{
+		Type:    "Card",
+		Version: "1.0",
+		// VERSION has no JSContact equivalent but decides how the card is
+		// written back
+		VCardProps: [][]interface{}{jcardProperty(vcard.FieldVersion, &vcard.Field{Value: version}, version)},
+	}
+	keep := func(name string, f *vcard.Field) {
+		js.VCardProps = append(js.VCardProps, jcardProperty(name, f, version))
+	}
+
+	groupStyle := addressbook.GroupStyle(card)
+	if groupStyle != "" {
+		js.Kind = addressbook.KindGroup
+		js.Members = make(map[string]bool)
+		for _, uid := range addressbook.GroupMembers(card) {
+			js.Members[uid] = true
+		}
+	}
+
+	for _, name := range sortedFields(card) {
+		fields := card[name]
+		switch name {
+		case vcard.FieldVersion:
+			continue
+		case vcard.FieldKind, vcard.FieldMember, "X-ADDRESSBOOKSERVER-KIND", "X-ADDRESSBOOKSERVER-MEMBER":
+			if groupStyle != "" {
+				continue
+			}
+			if f := plainField(fields); name == vcard.FieldKind && f != nil {
+				js.Kind = strings.ToLower(f.Value)
+				continue
+			}
+		case vcard.FieldUID:
+			if f := plainField(fields); f != nil {
+				js.UID = f.Value
+				continue
+			}
+		case vcard.FieldProductID:
+			if f := plainField(fields); f != nil {
+				js.ProdID = f.Value
+				continue
+			}
+		case vcard.FieldRevision:
+			if f := plainField(fields); f != nil {
+				if t, err := parseRevision(f.Value); err == nil {
+					js.Updated = t.UTC().Format(time.RFC3339)
+					continue
+				}
+			}
+		case vcard.FieldFormattedName:
+			if f := fields[0]; len(f.Params) == 0 && f.Group == "" {
+				js.name().Full = f.Value
+				for _, extra := range fields[1:] {
+					keep(name, extra)
+				}
+				continue
+			}
+		case vcard.FieldName:
+			if components := splitComponents(fields[0].Value); len(fields) == 1 && len(components) <= len(nameComponentKinds) {
+				n := js.name()
+				n.Components = toComponents(components, nameComponentKinds)
+				n.VCardParams = residualParams(fields[0])
+				continue
+			}
+		case vcard.FieldCategories:
+			if f := plainField(fields); f != nil {
+				js.Keywords = make(map[string]bool)
+				for _, k := range strings.Split(f.Value, ",") {
+					if k != "" {
+						js.Keywords[k] = true
+					}
+				}
+				continue
+			}
+		case vcard.FieldRelated:
+			js.RelatedTo = make(map[string]*JSContactEntry)
+			for _, f := range fields {
+				if _, dup := js.RelatedTo[f.Value]; dup {
+					keep(name, f)
+					continue
+				}
+				e := &JSContactEntry{Relation: make(map[string]bool)}
+				for _, t := range f.Params.Types() {
+					e.Relation[t] = true
+				}
+				e.VCardParams = residualParams(f, vcard.ParamType)
+				js.RelatedTo[f.Value] = e
+			}
+			continue
+		case vcard.FieldBirthday, vcard.FieldAnniversary, "DEATHDATE":
+			kind := map[string]string{vcard.FieldBirthday: "birth", vcard.FieldAnniversary: "wedding", "DEATHDATE": "death"}[name]
+			for _, f := range fields {
+				date, ok := parsePartialDate(f.Value)
+				if !ok || f.Params.Get(vcard.ParamValue) != "" {
+					keep(name, f)
+					continue
+				}
+				js.Anniversaries = addEntry(js.Anniversaries, "k", &JSContactEntry{Kind: kind, Date: date, VCardParams: residualParams(f)})
+			}
+			continue
+		case vcard.FieldNickname, vcard.FieldOrganization, vcard.FieldTitle, vcard.FieldRole,
+			vcard.FieldEmail, vcard.FieldTelephone, vcard.FieldAddress, vcard.FieldIMPP,
+			vcard.FieldURL, vcard.FieldPhoto, vcard.FieldLogo, vcard.FieldNote:
+			for _, f := range fields {
+				js.addEntry(name, f, version)
+			}
+			continue
+		}
+
+		for _, f := range fields {
+			keep(name, f)
+		}
+	}
+	return js
+}
+
+func (js *JSContactCard) name() *JSContactName {
+	if js.Name == nil {
+		js.Name = &JSContactName{}
+	}
+	return js.Name
+}
+
+// addEntry maps a property that becomes an entry of one of the card's
+// collections
+func (js *JSContactCard) addEntry(name string, f *vcard.Field, version string) {
+	e := &JSContactEntry{}
+	consumed := []string{vcard.ParamType, vcard.ParamPreferred}
+	var residualTypes []string
+	for _, t := range f.Params.Types() {
+		switch {
+		case t == "pref":
+			e.Pref = 1
+		case t == "work":
+			e.context("work")
+		case t == "home":
+			e.context("private")
+		case name == vcard.FieldTelephone && phoneFeatures[t] != "":
+			if e.Features == nil {
+				e.Features = make(map[string]bool)
+			}
+			e.Features[phoneFeatures[t]] = true
+		default:
+			residualTypes = append(residualTypes, t)
+		}
+	}
+	if p, err := strconv.Atoi(f.Params.Get(vcard.ParamPreferred)); err == nil {
+		e.Pref = p
+	}
+
+	switch name {
+	case vcard.FieldNickname:
+		e.Name = f.Value
+		js.Nicknames = addEntry(js.Nicknames, "n", e)
+	case vcard.FieldOrganization:
+		components := splitComponents(f.Value)
+		e.Name = components[0]
+		for _, unit := range components[1:] {
+			e.Units = append(e.Units, JSContactEntry{Name: unit})
+		}
+		js.Organizations = addEntry(js.Organizations, "o", e)
+	case vcard.FieldTitle, vcard.FieldRole:
+		e.Name = f.Value
+		e.Kind = strings.ToLower(name)
+		js.Titles = addEntry(js.Titles, "t", e)
+	case vcard.FieldEmail:
+		e.Address = f.Value
+		js.Emails = addEntry(js.Emails, "e", e)
+	case vcard.FieldTelephone:
+		e.Number = f.Value
+		js.Phones = addEntry(js.Phones, "p", e)
+	case vcard.FieldAddress:
+		e.Components = toComponents(splitComponents(f.Value), addressComponentKinds)
+		js.Addresses = addEntry(js.Addresses, "a", e)
+	case vcard.FieldIMPP:
+		e.URI = f.Value
+		js.OnlineServices = addEntry(js.OnlineServices, "s", e)
+	case vcard.FieldURL:
+		e.URI = f.Value
+		js.Links = addEntry(js.Links, "l", e)
+	case vcard.FieldPhoto, vcard.FieldLogo:
+		e.Kind = strings.ToLower(name)
+		e.URI = f.Value
+		if strings.EqualFold(f.Params.Get("ENCODING"), "b") {
+			// vCard 3.0 inline data; the TYPE names the image format
+			photoType := ""
+			if len(residualTypes) > 0 {
+				photoType, residualTypes = residualTypes[0], residualTypes[1:]
+			}
+			e.URI = addressbook.PhotoDataURI(f.Value, photoType)
+			consumed = append(consumed, "ENCODING")
+		}
+		js.Media = addEntry(js.Media, "m", e)
+	case vcard.FieldNote:
+		e.Note = strings.ReplaceAll(f.Value, `\;`, ";")
+		js.Notes = addEntry(js.Notes, "n", e)
+	}
+
+	e.VCardParams = residualParams(f, consumed...)
+	if len(residualTypes) > 0 {
+		if e.VCardParams == nil {
+			e.VCardParams = make(map[string]interface{})
+		}
+		e.VCardParams["type"] = stringList(residualTypes)
+	}
+}
+
+func (e *JSContactEntry) context(c string) {
+	if e.Contexts == nil {
+		e.Contexts = make(map[string]bool)
+	}
+	e.Contexts[c] = true
+}
+
+// FromJSContact parses a JSContact card into a vCard
+func FromJSContact(data []byte) (vcard.Card, error) {
+	var js JSContactCard
+	if err := json.Unmarshal(data, &js); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidJSContact, err)
+	}
+	if js.Type != "Card" {
+		return nil, fmt.Errorf("%w: @type must be \"Card\"", ErrInvalidJSContact)
+	}
+
+	version := addressbook.VCardVersion4
+	for _, p := range js.VCardProps {
+		if len(p) >= 4 && strings.EqualFold(fmt.Sprint(p[0]), vcard.FieldVersion) {
+			version = fmt.Sprint(p[3])
+		}
+	}
+
+	card := make(vcard.Card)
+	for _, p := range js.VCardProps {
+		name, field, err := parseJCardProperty(p, version)
+		if err != nil {
+			return nil, fmt.Errorf("%w: vCardProps: %v", ErrInvalidJSContact, err)
+		}
+		if name != vcard.FieldVersion {
+			card.Add(name, field)
+		}
+	}
+	card.SetValue(vcard.FieldVersion, version)
+
+	set := func(name, value string) {
+		if value != "" {
+			card.SetValue(name, value)
+		}
+	}
+	set(vcard.FieldUID, js.UID)
+	set(vcard.FieldProductID, js.ProdID)
+	if js.Updated != "" {
+		t, err := time.Parse(time.RFC3339, js.Updated)
+		if err != nil {
+			return nil, fmt.Errorf("%w: updated: %v", ErrInvalidJSContact, err)
+		}
+		card.SetValue(vcard.FieldRevision, t.UTC().Format("20060102T150405Z"))
+	}
+
+	switch js.Kind {
+	case "":
+	case addressbook.KindGroup:
+		style := addressbook.GroupStyleVCard4
+		if version == addressbook.VCardVersion3 {
+			style = addressbook.GroupStyleApple
+		}
+		addressbook.SetGroup(card, style, sortedKeys(js.Members))
+	default:
+		card.SetValue(vcard.FieldKind, js.Kind)
+	}
+
+	if js.Name != nil {
+		if js.Name.Full != "" {
+			card.Add(vcard.FieldFormattedName, &vcard.Field{Value: js.Name.Full})
+		}
+		if len(js.Name.Components) > 0 {
+			f := &vcard.Field{Value: fromComponents(js.Name.Components, nameComponentKinds)}
+			applyParams(f, js.Name.VCardParams)
+			card.Add(vcard.FieldName, f)
+		}
+	}
+	if card.Value(vcard.FieldFormattedName) == "" {
+		card.SetValue(vcard.FieldFormattedName, fallbackFormattedName(&js))
+	}
+
+	if len(js.Keywords) > 0 {
+		card.SetValue(vcard.FieldCategories, strings.Join(sortedKeys(js.Keywords), ","))
+	}
+	for _, uri := range sortedKeys(js.RelatedTo) {
+		e := js.RelatedTo[uri]
+		f := &vcard.Field{Value: uri}
+		applyParams(f, e.VCardParams)
+		for _, r := range sortedKeys(e.Relation) {
+			f.Params.Add(vcard.ParamType, r)
+		}
+		card.Add(vcard.FieldRelated, f)
+	}
+	for _, key := range sortedIDs(js.Anniversaries) {
+		e := js.Anniversaries[key]
+		name := map[string]string{"birth": vcard.FieldBirthday, "wedding": vcard.FieldAnniversary, "death": "DEATHDATE"}[e.Kind]
+		if name == "" || e.Date == nil {
+			return nil, fmt.Errorf("%w: anniversaries/%s needs a kind of birth, wedding or death and a date", ErrInvalidJSContact, key)
+		}
+		f := &vcard.Field{Value: formatPartialDate(e.Date, version)}
+		applyParams(f, e.VCardParams)
+		card.Add(name, f)
+	}
+
+	collections := []struct {
+		name    string
+		entries map[string]*JSContactEntry
+	}{
+		{vcard.FieldNickname, js.Nicknames},
+		{vcard.FieldOrganization, js.Organizations},
+		{vcard.FieldTitle, js.Titles},
+		{vcard.FieldEmail, js.Emails},
+		{vcard.FieldTelephone, js.Phones},
+		{vcard.FieldAddress, js.Addresses},
+		{vcard.FieldIMPP, js.OnlineServices},
+		{vcard.FieldURL, js.Links},
+		{vcard.FieldPhoto, js.Media},
+		{vcard.FieldNote, js.Notes},
+	}
+	for _, c := range collections {
+		for _, key := range sortedIDs(c.entries) {
+			name, f := entryField(c.name, c.entries[key], version)
+			card.Add(name, f)
+		}
+	}
+	return card, nil
+}
+
+// entryField builds the vCard property of a collection entry
+func entryField(name string, e *JSContactEntry, version string) (string, *vcard.Field) {
+	f := &vcard.Field{}
+	applyParams(f, e.VCardParams)
+
+	switch name {
+	case vcard.FieldNickname:
+		f.Value = e.Name
+	case vcard.FieldOrganization:
+		components := []string{escapeComponent(e.Name)}
+		for _, u := range e.Units {
+			components = append(components, escapeComponent(u.Name))
+		}
+		f.Value = strings.Join(components, ";")
+	case vcard.FieldTitle:
+		if e.Kind == "role" {
+			name = vcard.FieldRole
+		}
+		f.Value = e.Name
+	case vcard.FieldEmail:
+		f.Value = e.Address
+	case vcard.FieldTelephone:
+		f.Value = e.Number
+		for _, feature := range sortedKeys(e.Features) {
+			t := feature
+			if feature == "mobile" {
+				t = "cell"
+			}
+			f.Params.Add(vcard.ParamType, t)
+		}
+	case vcard.FieldAddress:
+		f.Value = fromComponents(e.Components, addressComponentKinds)
+	case vcard.FieldIMPP, vcard.FieldURL:
+		f.Value = e.URI
+	case vcard.FieldPhoto:
+		if e.Kind == "logo" {
+			name = vcard.FieldLogo
+		}
+		f.Value = e.URI
+		if version == addressbook.VCardVersion3 {
+			if data, photoType, ok := addressbook.ParsePhotoDataURI(e.URI); ok {
+				f.Value = data
+				f.Params.Set("ENCODING", "b")
+				f.Params.Add(vcard.ParamType, photoType)
+			}
+		}
+	case vcard.FieldNote:
+		f.Value = strings.ReplaceAll(e.Note, ";", `\;`)
+	}
+
+	for _, c := range sortedKeys(e.Contexts) {
+		switch c {
+		case "work":
+			f.Params.Add(vcard.ParamType, "work")
+		case "private":
+			f.Params.Add(vcard.ParamType, "home")
+		}
+	}
+	if e.Pref > 0 {
+		if version == addressbook.VCardVersion3 {
+			if e.Pref == 1 {
+				f.Params.Add(vcard.ParamType, "pref")
+			}
+		} else {
+			f.Params.Set(vcard.ParamPreferred, strconv.Itoa(e.Pref))
+		}
+	}
+	if len(f.Params) == 0 {
+		f.Params = nil
+	}
+	return name, f
+}
+
+// plainField returns the only field of a property if it carries no
+// parameters or group, i.e. maps to a plain JSContact value
+func plainField(fields []*vcard.Field) *vcard.Field {
+	if len(fields) != 1 || len(fields[0].Params) > 0 || fields[0].Group != "" {
+		return nil
+	}
+	return fields[0]
+}
+
+// residualParams returns the parameters of a field that have no JSContact
+// equivalent, in jCard form
+func residualParams(f *vcard.Field, consumed ...string) map[string]interface{} {
+	params := make(vcard.Params)
+	for k, v := range f.Params {
+		params[k] = v
+	}
+	for _, k := range consumed {
+		delete(params, k)
+	}
+	out := jcardParams(params)
+	if f.Group != "" {
+		out["group"] = f.Group
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+// applyParams restores the residual parameters of an entry
+func applyParams(f *vcard.Field, raw map[string]interface{}) {
+	f.Params = make(vcard.Params)
+	for k, v := range raw {
+		if strings.EqualFold(k, "group") {
+			f.Group = fmt.Sprint(v)
+			continue
+		}
+		if list, ok := v.([]interface{}); ok {
+			for _, item := range list {
+				f.Params.Add(strings.ToUpper(k), jsonScalar(item))
+			}
+			continue
+		}
+		f.Params.Add(strings.ToUpper(k), jsonScalar(v))
+	}
+}
+
+func toComponents(values []string, kinds []string) []JSContactComponent {
+	var components []JSContactComponent
+	for i, v := range values {
+		for _, part := range strings.Split(v, ",") {
+			if part != "" {
+				components = append(components, JSContactComponent{Kind: kinds[i], Value: part})
+			}
+		}
+	}
+	return components
+}
+
+func fromComponents(components []JSContactComponent, kinds []string) string {
+	slots := make([][]string, len(kinds))
+	for _, c := range components {
+		kind := c.Kind
+		switch kind {
+		case "surname2":
+			kind = "surname"
+		case "generation":
+			kind = "credential"
+		}
+		for i, k := range kinds {
+			if k == kind {
+				slots[i] = append(slots[i], escapeComponent(c.Value))
+				break
+			}
+		}
+	}
+	values := make([]string, len(slots))
+	for i, s := range slots {
+		values[i] = strings.Join(s, ",")
+	}
+	return strings.Join(values, ";")
+}
+
+func fallbackFormattedName(js *JSContactCard) string {
+	if js.Name != nil {
+		var given, surname []string
+		for _, c := range js.Name.Components {
+			switch c.Kind {
+			case "given":
+				given = append(given, c.Value)
+			case "surname":
+				surname = append(surname, c.Value)
+			}
+		}
+		if full := strings.TrimSpace(strings.Join(append(given, surname...), " ")); full != "" {
+			return full
+		}
+	}
+	for _, key := range sortedIDs(js.Organizations) {
+		return js.Organizations[key].Name
+	}
+	return ""
+}
+
+// parsePartialDate reads vCard dates without a time: 1985-04-12, 19850412,
+// --04-12 and --0412
+func parsePartialDate(value string) (*JSContactDate, bool) {
+	d := &JSContactDate{Type: "PartialDate"}
+	digits := strings.ReplaceAll(value, "-", "")
+	if !isDigits(digits) {
+		return nil, false
+	}
+	switch {
+	case strings.HasPrefix(value, "--") && len(digits) == 4:
+		d.Month, _ = strconv.Atoi(digits[:2])
+		d.Day, _ = strconv.Atoi(digits[2:])
+	case len(digits) == 8 && (len(value) == 8 || len(value) == 10):
+		d.Year, _ = strconv.Atoi(digits[:4])
+		d.Month, _ = strconv.Atoi(digits[4:6])
+		d.Day, _ = strconv.Atoi(digits[6:])
+	default:
+		return nil, false
+	}
+	if d.Month < 1 || d.Month > 12 || d.Day < 1 || d.Day > 31 {
+		return nil, false
+	}
+	return d, true
+}
+
+// formatPartialDate writes a date the way cards of the given version
+// usually carry it: extended format for 3.0, basic format for 4.0
+func formatPartialDate(d *JSContactDate, version string) string {
+	sep := ""
+	if version == addressbook.VCardVersion3 {
+		sep = "-"
+	}
+	if d.Year == 0 {
+		return fmt.Sprintf("--%02d%s%02d", d.Month, sep, d.Day)
+	}
+	return fmt.Sprintf("%04d%s%02d%s%02d", d.Year, sep, d.Month, sep, d.Day)
+}
+
+func parseRevision(value string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", time.RFC3339, "2006-01-02T15:04:05Z"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unsupported REV format %q", value)
+}
+
+// addEntry stores an entry under the next free id of a collection
+func addEntry(m map[string]*JSContactEntry, prefix string, e *JSContactEntry) map[string]*JSContactEntry {
+	if m == nil {
+		m = make(map[string]*JSContactEntry)
+	}
+	m[prefix+strconv.Itoa(len(m)+1)] = e
+	return m
+}
+
+func stringList(values []string) interface{} {
+	if len(values) == 1 {
+		return values[0]
+	}
+	list := make([]interface{}, len(values))
+	for i, v := range values {
+		list[i] = v
+	}
+	return list
+}
+
+// sortedKeys returns the keys of a map in alphabetical order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sortedIDs returns entry ids in natural order, so that "e10" follows "e9"
+func sortedIDs(m map[string]*JSContactEntry) []string {
+	keys := sortedKeys(m)
+	sort.SliceStable(keys, func(i, j int) bool { return len(keys[i]) < len(keys[j]) })
+	return keys
+}
@@ -0,0 +1,130 @@
+package contact
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/emersion/go-vcard"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const appleGroupVCard = "BEGIN:VCARD\r\n" +
+	"VERSION:3.0\r\n" +
+	"UID:group-1\r\n" +
+	"FN:Team\r\n" +
+	"N:Team;;;;\r\n" +
+	"X-ADDRESSBOOKSERVER-KIND:group\r\n" +
+	"X-ADDRESSBOOKSERVER-MEMBER:urn:uuid:a\r\n" +
+	"X-ADDRESSBOOKSERVER-MEMBER:urn:uuid:b\r\n" +
+	"PHOTO;ENCODING=b;TYPE=PNG:iVBORw0KGgo=\r\n" +
+	"EMAIL;TYPE=INTERNET;TYPE=WORK;TYPE=pref:team@acme.example\r\n" +
+	"END:VCARD\r\n"
+
+func TestToJSContact(t *testing.T) {
+	js := ToJSContact(parseCard(t, richVCard))
+
+	assert.Equal(t, "Card", js.Type)
+	assert.Equal(t, "urn:uuid:rich-1", js.UID)
+	assert.Equal(t, "Dr. Jane Q. Public", js.Name.Full)
+	assert.Contains(t, js.Name.Components, JSContactComponent{Kind: "given2", Value: "Quinn"})
+	assert.Equal(t, map[string]interface{}{"sort-as": "Public"}, js.Name.VCardParams)
+
+	assert.Equal(t, "ACME, Inc.", js.Organizations["o1"].Name)
+	assert.Equal(t, []JSContactEntry{{Name: "Research"}, {Name: "Lab 3"}}, js.Organizations["o1"].Units)
+
+	assert.Equal(t, &JSContactEntry{Address: "jane@acme.example", Contexts: map[string]bool{"work": true}, Pref: 1}, js.Emails["e1"])
+	phone := js.Phones["p1"]
+	assert.Equal(t, map[string]bool{"mobile": true, "voice": true}, phone.Features)
+	assert.Equal(t, map[string]interface{}{"type": "x-custom"}, phone.VCardParams)
+
+	assert.Equal(t, "xmpp:jane@acme.example", js.OnlineServices["s1"].URI)
+	assert.Equal(t, map[string]bool{"friend": true}, js.RelatedTo["urn:uuid:friend-1"].Relation)
+	assert.Equal(t, map[string]bool{"science": true, "friends": true}, js.Keywords)
+	assert.Equal(t, "2024-01-02T03:04:05Z", js.Updated)
+
+	var kinds []string
+	for _, a := range js.Anniversaries {
+		kinds = append(kinds, a.Kind)
+		if a.Kind == "birth" {
+			assert.Equal(t, &JSContactDate{Type: "PartialDate", Year: 1985, Month: 4, Day: 12}, a.Date)
+		}
+	}
+	assert.ElementsMatch(t, []string{"birth", "wedding"}, kinds)
+
+	// GEO and the X- properties have no JSContact equivalent
+	var unmapped []string
+	for _, p := range js.VCardProps {
+		unmapped = append(unmapped, p[0].(string))
+	}
+	assert.ElementsMatch(t, []string{"version", "geo", "x-ablabel", "x-custom"}, unmapped)
+}
+
+func TestJSContact_RoundTrip(t *testing.T) {
+	card := parseCard(t, richVCard)
+	out, err := json.Marshal(ToJSContact(card))
+	require.NoError(t, err)
+
+	back, err := FromJSContact(out)
+	require.NoError(t, err)
+
+	// Every property comes back; only the order of TYPE values and of the
+	// keywords set may differ
+	assert.Equal(t, sortedFields(card), sortedFields(back))
+	for _, name := range sortedFields(card) {
+		require.Len(t, back[name], len(card[name]), name)
+		for i, f := range card[name] {
+			if name == vcard.FieldCategories {
+				assert.ElementsMatch(t, strings.Split(f.Value, ","), strings.Split(back[name][i].Value, ","))
+			} else {
+				assert.Equal(t, f.Value, back[name][i].Value, name)
+			}
+			assert.Equal(t, f.Group, back[name][i].Group, name)
+			assert.ElementsMatch(t, f.Params.Types(), back[name][i].Params.Types(), name)
+		}
+	}
+	assert.Equal(t, "Main St 1", back.Get(vcard.FieldAddress).Params.Get("LABEL"))
+	assert.Equal(t, "1", back.Get(vcard.FieldEmail).Params.Get(vcard.ParamPreferred))
+}
+
+func TestJSContact_AppleGroup(t *testing.T) {
+	js := ToJSContact(parseCard(t, appleGroupVCard))
+	assert.Equal(t, "group", js.Kind)
+	assert.Equal(t, map[string]bool{"a": true, "b": true}, js.Members)
+	assert.Equal(t, "data:image/png;base64,iVBORw0KGgo=", js.Media["m1"].URI)
+	assert.Equal(t, 1, js.Emails["e1"].Pref)
+
+	out, err := json.Marshal(js)
+	require.NoError(t, err)
+	back, err := FromJSContact(out)
+	require.NoError(t, err)
+
+	assert.Equal(t, "3.0", back.Value(vcard.FieldVersion))
+	assert.Equal(t, "group", back.Value("X-ADDRESSBOOKSERVER-KIND"))
+	assert.Len(t, back["X-ADDRESSBOOKSERVER-MEMBER"], 2)
+	assert.Nil(t, back[vcard.FieldKind])
+	photo := back.Get(vcard.FieldPhoto)
+	assert.Equal(t, "iVBORw0KGgo=", photo.Value)
+	assert.Equal(t, "b", photo.Params.Get("ENCODING"))
+	assert.True(t, back.Get(vcard.FieldEmail).Params.HasType("pref"))
+	assert.True(t, back.Get(vcard.FieldEmail).Params.HasType("internet"))
+}
+
+func TestFromJSContact_Minimal(t *testing.T) {
+	card, err := FromJSContact([]byte(`{"@type":"Card","version":"1.0","uid":"x",
+		"name":{"components":[{"kind":"given","value":"Ada"},{"kind":"surname","value":"Lovelace"}]},
+		"emails":{"e1":{"address":"ada@example.com","contexts":{"private":true},"pref":1}},
+		"anniversaries":{"k1":{"kind":"birth","date":{"@type":"PartialDate","month":12,"day":10}}}}`))
+	require.NoError(t, err)
+	assert.Equal(t, "4.0", card.Value(vcard.FieldVersion))
+	assert.Equal(t, "Ada Lovelace", card.Value(vcard.FieldFormattedName))
+	assert.Equal(t, "Lovelace;Ada;;;", card.Value(vcard.FieldName))
+	assert.Equal(t, "--1210", card.Value(vcard.FieldBirthday))
+	email := card.Get(vcard.FieldEmail)
+	assert.True(t, email.Params.HasType("home"))
+	assert.Equal(t, "1", email.Params.Get(vcard.ParamPreferred))
+
+	_, err = FromJSContact([]byte(`{"@type":"Group"}`))
+	assert.ErrorIs(t, err, ErrInvalidJSContact)
+}
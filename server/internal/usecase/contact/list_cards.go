@@ -0,0 +1,61 @@
+package contact
+
+import (
+	"context"
+
+	"github.com/emersion/go-vcard"
+	"github.com/jherrma/caldav-server/internal/domain/addressbook"
+)
+
+// ListCardsUseCase pages through the full stored vCards of an address book
+type ListCardsUseCase struct {
+	repo addressbook.Repository
+}
+
+func NewListCardsUseCase(repo addressbook.Repository) *ListCardsUseCase {
+	return &ListCardsUseCase{repo: repo}
+}
+
+type ListCardsOutput struct {
+	Cards  []vcard.Card
+	Total  int
+	Limit  int
+	Offset int
+}
+
+func (uc *ListCardsUseCase) Execute(ctx context.Context, input ListInput) (*ListCardsOutput, error) {
+	if input.Sort == "" {
+		input.Sort = "name"
+	}
+	if input.Order == "" {
+		input.Order = "asc"
+	}
+	if input.Limit <= 0 {
+		input.Limit = 50
+	}
+
+	objs, total, err := uc.repo.ListObjects(ctx, input.AddressBookID, input.Limit, input.Offset, input.Sort, input.Order)
+	if err != nil {
+		return nil, err
+	}
+
+	// ListObjects leaves out the stored photos, so every card is reloaded to
+	// keep the representation lossless
+	cards := make([]vcard.Card, 0, len(objs))
+	for _, obj := range objs {
+		_, card, err := loadCard(ctx, uc.repo, input.AddressBookID, obj.UUID)
+		if err != nil {
+			return nil, err
+		}
+		if card != nil {
+			cards = append(cards, card)
+		}
+	}
+
+	return &ListCardsOutput{
+		Cards:  cards,
+		Total:  int(total),
+		Limit:  input.Limit,
+		Offset: input.Offset,
+	}, nil
+}
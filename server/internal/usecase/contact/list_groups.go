@@ -0,0 +1,39 @@
+package contact
+
+import (
+	"context"
+	"strings"
+
+	"github.com/emersion/go-vcard"
+	"github.com/jherrma/caldav-server/internal/domain/addressbook"
+	"github.com/jherrma/caldav-server/internal/domain/contact"
+)
+
+type ListGroupsUseCase struct {
+	repo addressbook.Repository
+}
+
+func NewListGroupsUseCase(repo addressbook.Repository) *ListGroupsUseCase {
+	return &ListGroupsUseCase{repo: repo}
+}
+
+func (uc *ListGroupsUseCase) Execute(ctx context.Context, addressBookID uint) ([]*contact.Group, error) {
+	objs, err := uc.repo.ListGroups(ctx, addressBookID)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make([]*contact.Group, 0, len(objs))
+	for i := range objs {
+		card, err := vcard.NewDecoder(strings.NewReader(objs[i].VCardData)).Decode()
+		if err != nil {
+			continue
+		}
+		g, err := toGroup(ctx, uc.repo, &objs[i], card)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, g)
+	}
+	return groups, nil
+}
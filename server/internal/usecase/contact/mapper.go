@@ -21,6 +21,7 @@ func ToContact(vcardData string) (*contact.Contact, error) {
 
 	c := &contact.Contact{
 		UID:           card.Value(vcard.FieldUID),
+		Version:       card.Value(vcard.FieldVersion),
 		FormattedName: card.PreferredValue(vcard.FieldFormattedName),
 		Organization:  card.PreferredValue(vcard.FieldOrganization),
 		Title:         card.PreferredValue(vcard.FieldTitle),
@@ -87,12 +88,23 @@ func ToContact(vcardData string) (*contact.Contact, error) {
 	if field := card.Get(vcard.FieldPhoto); field != nil {
 		c.Photo = field.Value
 		c.PhotoType = extractType(field.Params)
+		if data, t, ok := addressbook.ParsePhotoDataURI(field.Value); ok {
+			c.Photo, c.PhotoType = data, t
+		}
+	}
+
+	// Group
+	if style := addressbook.GroupStyle(card); style != "" {
+		c.Kind = addressbook.KindGroup
+		c.GroupStyle = style
+		c.Members = addressbook.GroupMembers(card)
 	}
 
 	return c, nil
 }
 
-// ToVCard converts a Contact struct to vCard string
+// ToVCard converts a Contact struct to vCard string. The card is built as
+// vCard 3.0 and converted when the contact asks for 4.0.
 func ToVCard(c *contact.Contact) (string, error) {
 	card := make(vcard.Card)
 	card.SetValue(vcard.FieldVersion, "3.0")
@@ -196,9 +208,24 @@ func ToVCard(c *contact.Contact) (string, error) {
 		card.Add(vcard.FieldPhoto, &vcard.Field{Value: c.Photo, Params: params})
 	}
 
+	// Group. Defaults to the Apple style, which matches the vCard 3.0 we
+	// write and is understood by iOS, macOS and most CardDAV clients.
+	// Converting the card to 4.0 below switches it to the vCard 4 style.
+	if c.Kind == addressbook.KindGroup {
+		style := c.GroupStyle
+		if !addressbook.IsValidGroupStyle(style) {
+			style = addressbook.GroupStyleApple
+		}
+		addressbook.SetGroup(card, style, c.Members)
+	}
+
 	// Revision
 	card.SetValue(vcard.FieldRevision, time.Now().Format("20060102T150405Z"))
 
+	if c.Version == addressbook.VCardVersion4 {
+		addressbook.ConvertVCard(card, addressbook.VCardVersion4)
+	}
+
 	var buf bytes.Buffer
 	enc := vcard.NewEncoder(&buf)
 	if err := enc.Encode(card); err != nil {
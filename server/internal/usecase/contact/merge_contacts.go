@@ -0,0 +1,375 @@
+package contact
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/emersion/go-vcard"
+	"github.com/jherrma/caldav-server/internal/domain/addressbook"
+	"github.com/jherrma/caldav-server/internal/domain/contact"
+)
+
+var (
+	ErrMergeContactNotFound = errors.New("contact to merge not found")
+	ErrMergeTooFewContacts  = errors.New("at least two distinct contacts are required to merge")
+	ErrMergeGroup           = errors.New("contact groups cannot be merged")
+	ErrMergePhotoSource     = errors.New("photo_from must be one of the merged contacts")
+)
+
+// singleValueFields keep the target's value and are only taken from the
+// other contacts when the target has none. Everything else is unioned.
+var singleValueFields = map[string]bool{
+	vcard.FieldVersion:       true,
+	vcard.FieldUID:           true,
+	vcard.FieldFormattedName: true,
+	vcard.FieldName:          true,
+	vcard.FieldKind:          true,
+	vcard.FieldBirthday:      true,
+	vcard.FieldAnniversary:   true,
+	vcard.FieldGender:        true,
+	vcard.FieldProductID:     true,
+	vcard.FieldRevision:      true,
+	vcard.FieldTimezone:      true,
+	vcard.FieldGeolocation:   true,
+	"SORT-STRING":            true,
+}
+
+// MergeContactsUseCase combines duplicate contacts into one. The target
+// contact keeps its UID and location; the others are folded into it and
+// deleted.
+type MergeContactsUseCase struct {
+	repo addressbook.Repository
+}
+
+func NewMergeContactsUseCase(repo addressbook.Repository) *MergeContactsUseCase {
+	return &MergeContactsUseCase{repo: repo}
+}
+
+type MergeContactsInput struct {
+	UserID     uint
+	TargetID   string   // UUID of the contact that survives
+	ContactIDs []string // UUIDs of the contacts merged into the target and deleted
+	PhotoFrom  string   // UUID of the contact whose photo is kept; the target's, else the first one found, when empty
+}
+
+// mergeSource is a contact taking part in a merge
+type mergeSource struct {
+	obj  *addressbook.AddressObject
+	card vcard.Card
+}
+
+func (uc *MergeContactsUseCase) Execute(ctx context.Context, input MergeContactsInput) (*contact.Contact, error) {
+	// 1. Load every contact, verifying it belongs to the user
+	ids := []string{input.TargetID}
+	for _, id := range input.ContactIDs {
+		if !containsString(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	if input.TargetID == "" || len(ids) < 2 {
+		return nil, ErrMergeTooFewContacts
+	}
+	if input.PhotoFrom != "" && !containsString(ids, input.PhotoFrom) {
+		return nil, ErrMergePhotoSource
+	}
+
+	owned := map[uint]bool{}
+	sources := make([]mergeSource, 0, len(ids))
+	for _, id := range ids {
+		obj, err := uc.repo.GetObjectByUUID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if obj == nil {
+			return nil, ErrMergeContactNotFound
+		}
+		if _, ok := owned[obj.AddressBookID]; !ok {
+			ab, err := uc.repo.GetByID(ctx, obj.AddressBookID)
+			if err != nil {
+				return nil, err
+			}
+			owned[obj.AddressBookID] = ab != nil && ab.UserID == input.UserID
+		}
+		if !owned[obj.AddressBookID] {
+			return nil, ErrMergeContactNotFound
+		}
+
+		obj, card, err := loadCard(ctx, uc.repo, obj.AddressBookID, id)
+		if err != nil {
+			return nil, err
+		}
+		if obj == nil {
+			return nil, ErrMergeContactNotFound
+		}
+		if addressbook.CardKind(card) == addressbook.KindGroup {
+			return nil, ErrMergeGroup
+		}
+		sources = append(sources, mergeSource{obj: obj, card: card})
+	}
+
+	// 2. Fold the others into the target card
+	target := sources[0]
+	photo := target.card[vcard.FieldPhoto]
+	if input.PhotoFrom != "" {
+		photo = nil
+	}
+	notes := collectNotes(nil, target.card)
+	for _, src := range sources[1:] {
+		addressbook.ConvertVCard(src.card, target.card.Value(vcard.FieldVersion))
+		mergeCard(target.card, src.card)
+		notes = collectNotes(notes, src.card)
+		if photo == nil && input.PhotoFrom == "" {
+			photo = src.card[vcard.FieldPhoto]
+		}
+	}
+	for _, src := range sources {
+		if src.obj.UUID == input.PhotoFrom {
+			photo = src.card[vcard.FieldPhoto]
+		}
+	}
+
+	delete(target.card, vcard.FieldPhoto)
+	if len(photo) > 0 {
+		target.card[vcard.FieldPhoto] = photo[:1]
+	}
+	delete(target.card, vcard.FieldNote)
+	if len(notes) > 0 {
+		target.card.SetValue(vcard.FieldNote, strings.Join(notes, "\n\n"))
+	}
+
+	if err := encodeCard(target.obj, target.card); err != nil {
+		return nil, err
+	}
+
+	// 3. Store the target, the groups whose memberships move to it and the
+	// deletion of the others in one transaction. The deletions record the
+	// "deleted" sync entries.
+	groups, err := mergedGroups(ctx, uc.repo, target, sources[1:])
+	if err != nil {
+		return nil, err
+	}
+	deleted := make([]string, 0, len(sources)-1)
+	for _, src := range sources[1:] {
+		deleted = append(deleted, src.obj.UUID)
+	}
+	if err := uc.repo.SaveObjectChanges(ctx, append([]*addressbook.AddressObject{target.obj}, groups...), deleted); err != nil {
+		return nil, fmt.Errorf("failed to save merged contact: %w", err)
+	}
+
+	obj, err := uc.repo.GetObjectByUUID(ctx, target.obj.UUID)
+	if err != nil {
+		return nil, err
+	}
+	if obj == nil {
+		return nil, ErrMergeContactNotFound
+	}
+	return FromAddressObject(obj), nil
+}
+
+// mergeCard adds the properties of src to dst. Single-value properties only
+// fill gaps; other properties are added unless an equivalent value exists.
+// Property groups (item1.EMAIL with item1.X-ABLABEL) are carried over as a
+// unit under a fresh group name. PHOTO and NOTE are left to the caller.
+func mergeCard(dst, src vcard.Card) {
+	groups := map[string][]string{} // group -> field names of src using it
+	for name, fields := range src {
+		if name == vcard.FieldPhoto || name == vcard.FieldNote {
+			continue
+		}
+		for _, f := range fields {
+			if f.Group != "" {
+				if !containsString(groups[f.Group], name) {
+					groups[f.Group] = append(groups[f.Group], name)
+				}
+				continue
+			}
+			if singleValueFields[name] {
+				if isBlank(dst.Value(name)) && !isBlank(f.Value) {
+					dst.Set(name, copyField(f, ""))
+				}
+				continue
+			}
+			if !hasEquivalent(dst, name, f) {
+				dst.Add(name, copyField(f, ""))
+			}
+		}
+	}
+
+	groupNames := make([]string, 0, len(groups))
+	for group := range groups {
+		groupNames = append(groupNames, group)
+	}
+	sort.Strings(groupNames)
+	for _, group := range groupNames {
+		names := groups[group]
+		// Skip the group when all its real values already exist; a lone
+		// label would be meaningless
+		fresh := false
+		for _, name := range names {
+			if strings.HasPrefix(name, "X-AB") {
+				continue
+			}
+			for _, f := range src[name] {
+				if f.Group == group && !hasEquivalent(dst, name, f) {
+					fresh = true
+				}
+			}
+		}
+		if !fresh {
+			continue
+		}
+		newGroup := freeGroupName(dst)
+		for _, name := range names {
+			for _, f := range src[name] {
+				if f.Group == group {
+					dst.Add(name, copyField(f, newGroup))
+				}
+			}
+		}
+	}
+}
+
+// hasEquivalent reports whether dst already holds a value of the property
+// equal to f's after normalization
+func hasEquivalent(dst vcard.Card, name string, f *vcard.Field) bool {
+	key := mergeKey(name, f.Value)
+	for _, existing := range dst[name] {
+		if mergeKey(name, existing.Value) == key {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeKey normalizes a property value for deduplication
+func mergeKey(name, value string) string {
+	switch name {
+	case vcard.FieldEmail:
+		return normalizeEmail(value)
+	case vcard.FieldTelephone:
+		if p := normalizePhone(value); p != "" {
+			return p
+		}
+	}
+	return strings.ToLower(strings.TrimSpace(value))
+}
+
+// isBlank reports whether a value is empty, including structured values
+// with only empty components such as an N of ";;;;"
+func isBlank(value string) bool {
+	return strings.Trim(value, "; ") == ""
+}
+
+// collectNotes appends the distinct NOTE values of a card
+func collectNotes(notes []string, card vcard.Card) []string {
+	for _, f := range card[vcard.FieldNote] {
+		note := strings.TrimSpace(f.Value)
+		if note != "" && !containsString(notes, note) {
+			notes = append(notes, note)
+		}
+	}
+	return notes
+}
+
+// freeGroupName returns an "itemN" property group name unused in the card
+func freeGroupName(card vcard.Card) string {
+	used := map[string]bool{}
+	for _, fields := range card {
+		for _, f := range fields {
+			used[strings.ToLower(f.Group)] = true
+		}
+	}
+	for i := 1; ; i++ {
+		name := fmt.Sprintf("item%d", i)
+		if !used[name] {
+			return name
+		}
+	}
+}
+
+func copyField(f *vcard.Field, group string) *vcard.Field {
+	params := vcard.Params{}
+	for k, v := range f.Params {
+		params[k] = append([]string(nil), v...)
+	}
+	return &vcard.Field{Value: f.Value, Params: params, Group: group}
+}
+
+// mergedGroups returns the groups whose members change when the sources
+// are merged into the target, with their cards already updated. Members in
+// the target's address book now point at the target; in other address
+// books the merged-away contacts are removed.
+func mergedGroups(ctx context.Context, repo addressbook.Repository, target mergeSource, sources []mergeSource) ([]*addressbook.AddressObject, error) {
+	replaced := map[uint]map[string]string{} // address book -> old UID -> new UID, "" to remove
+	var addressBookIDs []uint
+	for _, src := range sources {
+		abID := src.obj.AddressBookID
+		if replaced[abID] == nil {
+			replaced[abID] = map[string]string{}
+			addressBookIDs = append(addressBookIDs, abID)
+		}
+		if abID == target.obj.AddressBookID {
+			replaced[abID][src.obj.UID] = target.obj.UID
+		} else {
+			replaced[abID][src.obj.UID] = ""
+		}
+	}
+
+	var updated []*addressbook.AddressObject
+	for _, abID := range addressBookIDs {
+		groups, err := repo.ListGroups(ctx, abID)
+		if err != nil {
+			return nil, err
+		}
+		for _, g := range groups {
+			card, err := vcard.NewDecoder(strings.NewReader(g.VCardData)).Decode()
+			if err != nil || !listsAny(addressbook.GroupMembers(card), replaced[abID]) {
+				continue // Not a member, or an unparsable group we leave alone
+			}
+			// Reload through GetObjectByUUID so the stored photo is part of
+			// the vCard; UpdateObject would otherwise drop it
+			obj, card, err := loadGroup(ctx, repo, abID, g.UUID)
+			if err != nil {
+				return nil, err
+			}
+			members := addressbook.GroupMembers(card)
+			kept := make([]string, 0, len(members))
+			for _, m := range members {
+				if uid, ok := replaced[abID][m]; ok {
+					m = uid
+				}
+				if m != "" && !containsString(kept, m) {
+					kept = append(kept, m)
+				}
+			}
+			addressbook.SetGroup(card, addressbook.GroupStyle(card), kept)
+			if err := encodeCard(obj, card); err != nil {
+				return nil, err
+			}
+			updated = append(updated, obj)
+		}
+	}
+	return updated, nil
+}
+
+// listsAny reports whether any of the members is a key of uids
+func listsAny(members []string, uids map[string]string) bool {
+	for _, m := range members {
+		if _, ok := uids[m]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(list []string, v string) bool {
+	for _, x := range list {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
@@ -1,10 +1,13 @@
 package contact
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/emersion/go-vcard"
 	"github.com/jherrma/caldav-server/internal/domain/addressbook"
 	"github.com/jherrma/caldav-server/internal/domain/contact"
 )
@@ -50,7 +53,28 @@ func (uc *MoveUseCase) Execute(ctx context.Context, userID uint, contactUUID str
 		return nil, fmt.Errorf("target address book not found or access denied")
 	}
 
-	// 3. Move object
+	// 3. Move object. A moved group keeps only the members that exist in
+	// the target address book.
+	if card, err := vcard.NewDecoder(strings.NewReader(obj.VCardData)).Decode(); err == nil && addressbook.GroupStyle(card) != "" {
+		var kept []string
+		for _, uid := range addressbook.GroupMembers(card) {
+			m, err := uc.repo.GetObjectByUID(ctx, targetAddressBookID, uid)
+			if err != nil {
+				return nil, err
+			}
+			if m != nil {
+				kept = append(kept, uid)
+			}
+		}
+		addressbook.SetGroup(card, addressbook.GroupStyle(card), kept)
+		var buf bytes.Buffer
+		if err := vcard.NewEncoder(&buf).Encode(card); err != nil {
+			return nil, fmt.Errorf("failed to encode group vcard: %w", err)
+		}
+		obj.VCardData = buf.String()
+		obj.ContentLength = len(obj.VCardData)
+	}
+
 	obj.AddressBookID = targetAddressBookID
 	obj.UpdatedAt = time.Now()
 	obj.ETag = fmt.Sprintf("%d", time.Now().UnixNano())
@@ -59,7 +83,12 @@ func (uc *MoveUseCase) Execute(ctx context.Context, userID uint, contactUUID str
 		return nil, fmt.Errorf("failed to move contact: %w", err)
 	}
 
-	// 4. Update Sync Tokens for both
+	// 4. Group memberships do not cross address books
+	if err := RemoveFromGroups(ctx, uc.repo, sourceID, obj.UID); err != nil {
+		fmt.Printf("failed to update groups of source address book: %v\n", err)
+	}
+
+	// 5. Update Sync Tokens for both
 	sourceAB.UpdateSyncTokens()
 	if err := uc.repo.Update(ctx, sourceAB); err != nil {
 		fmt.Printf("failed to update source address book ctag: %v\n", err)
@@ -3,99 +3,107 @@ package contact
 import (
 	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
-	"time"
 
+	"github.com/emersion/go-vcard"
 	"github.com/jherrma/caldav-server/internal/domain/addressbook"
 )
 
+var (
+	ErrContactNotFound = errors.New("contact not found")
+	ErrPhotoNotFound   = errors.New("photo not found")
+)
+
+// PhotoUseCase uploads, removes and serves contact photos. Photos are
+// normalized by the repository's photo processor on write, so the vCard
+// carries the downscaled version and thumbnails are kept alongside. The
+// repository stores photos it can't process as sent, so uploads are checked
+// against photos first and rejected instead.
 type PhotoUseCase struct {
-	repo addressbook.Repository
+	repo   addressbook.Repository
+	photos addressbook.PhotoProcessor
 }
 
-func NewPhotoUseCase(repo addressbook.Repository) *PhotoUseCase {
-	return &PhotoUseCase{repo: repo}
+// NewPhotoUseCase creates a new photo use case. photos may be nil, in which
+// case uploads aren't validated.
+func NewPhotoUseCase(repo addressbook.Repository, photos addressbook.PhotoProcessor) *PhotoUseCase {
+	return &PhotoUseCase{repo: repo, photos: photos}
 }
 
 func (uc *PhotoUseCase) Upload(ctx context.Context, addressBookID uint, contactUUID string, data []byte) error {
-	// 1. Get object
-	obj, err := uc.repo.GetObjectByUUID(ctx, contactUUID)
+	// 1. Load the complete card so no property is lost on the way back
+	obj, card, err := loadCard(ctx, uc.repo, addressBookID, contactUUID)
 	if err != nil {
 		return err
 	}
-	if obj == nil || obj.AddressBookID != addressBookID {
-		return fmt.Errorf("contact not found")
+	if obj == nil {
+		return ErrContactNotFound
 	}
-
-	// 2. Parse
-	currentContact, err := ToContact(obj.VCardData)
-	if err != nil {
-		return fmt.Errorf("failed to parse existing vcard: %w", err)
+	if uc.photos != nil {
+		if _, err := uc.photos.Process(data); err != nil {
+			return err
+		}
 	}
 
-	// 3. Update Photo (Encode to Base64)
-	encoded := base64.StdEncoding.EncodeToString(data)
-
-	// Detect Type
-	mimeType := http.DetectContentType(data)
-	// Map mime to vCard TYPE
+	// 2. Detect Type
 	// image/jpeg -> JPEG
 	// image/png -> PNG
 	// image/gif -> GIF
-	parts := strings.Split(mimeType, "/")
 	photoType := "JPEG" // Default
-	if len(parts) == 2 && parts[0] == "image" {
+	if parts := strings.Split(http.DetectContentType(data), "/"); len(parts) == 2 && parts[0] == "image" {
 		photoType = strings.ToUpper(parts[1])
 	}
 
-	currentContact.Photo = encoded
-	currentContact.PhotoType = photoType
-
-	// 4. To VCard
-	newVCardData, err := ToVCard(currentContact)
-	if err != nil {
-		return fmt.Errorf("failed to encode updated vcard: %w", err)
+	// 3. Replace the photo in the form the card's version expects
+	encoded := base64.StdEncoding.EncodeToString(data)
+	delete(card, vcard.FieldPhoto)
+	if card.Value(vcard.FieldVersion) == addressbook.VCardVersion4 {
+		card.Add(vcard.FieldPhoto, &vcard.Field{Value: addressbook.PhotoDataURI(encoded, photoType)})
+	} else {
+		params := make(vcard.Params)
+		params.Set("ENCODING", "b")
+		params.Set("TYPE", photoType)
+		card.Add(vcard.FieldPhoto, &vcard.Field{Value: encoded, Params: params})
 	}
 
-	// 5. Update Object
-	obj.VCardData = newVCardData
-	obj.UpdatedAt = time.Now()
-	obj.ETag = fmt.Sprintf("%d", time.Now().UnixNano())
-
-	// UpdateObject bumps the address book's sync_token / CTag and logs the
-	// change atomically; no second CTag update needed.
-	_ = addressBookID // kept in signature for future caching hooks
-	return uc.repo.UpdateObject(ctx, obj)
+	// UpdateObject processes the photo, bumps the address book's sync_token /
+	// CTag and logs the change atomically
+	return saveCard(ctx, uc.repo, obj, card)
 }
 
 func (uc *PhotoUseCase) Delete(ctx context.Context, addressBookID uint, contactUUID string) error {
-	obj, err := uc.repo.GetObjectByUUID(ctx, contactUUID)
+	obj, card, err := loadCard(ctx, uc.repo, addressBookID, contactUUID)
 	if err != nil {
 		return err
 	}
-	if obj == nil || obj.AddressBookID != addressBookID {
-		return fmt.Errorf("contact not found")
+	if obj == nil {
+		return ErrContactNotFound
 	}
 
-	currentContact, err := ToContact(obj.VCardData)
+	delete(card, vcard.FieldPhoto)
+	return saveCard(ctx, uc.repo, obj, card)
+}
+
+// Get returns the photo of a contact, or for size > 0 the smallest stored
+// thumbnail covering that size
+func (uc *PhotoUseCase) Get(ctx context.Context, addressBookID uint, contactUUID string, size int) (*addressbook.ContactPhoto, error) {
+	obj, err := uc.repo.GetObjectByUUID(ctx, contactUUID)
 	if err != nil {
-		return fmt.Errorf("failed to parse existing vcard: %w", err)
+		return nil, err
+	}
+	if obj == nil || obj.AddressBookID != addressBookID {
+		return nil, ErrContactNotFound
 	}
 
-	currentContact.Photo = ""
-
-	newVCardData, err := ToVCard(currentContact)
+	photo, err := uc.repo.GetPhoto(ctx, obj.ID, size)
 	if err != nil {
-		return fmt.Errorf("failed to encode updated vcard: %w", err)
+		return nil, fmt.Errorf("failed to load photo: %w", err)
 	}
-
-	obj.VCardData = newVCardData
-	obj.UpdatedAt = time.Now()
-	obj.ETag = fmt.Sprintf("%d", time.Now().UnixNano())
-
-	// UpdateObject bumps the address book's sync_token / CTag and logs the
-	// change atomically; no second CTag update needed.
-	return uc.repo.UpdateObject(ctx, obj)
+	if photo == nil || photo.PhotoData == "" {
+		return nil, ErrPhotoNotFound
+	}
+	return photo, nil
 }
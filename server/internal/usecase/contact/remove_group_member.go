@@ -0,0 +1,42 @@
+package contact
+
+import (
+	"context"
+
+	"github.com/jherrma/caldav-server/internal/domain/addressbook"
+	"github.com/jherrma/caldav-server/internal/domain/contact"
+)
+
+type RemoveGroupMemberUseCase struct {
+	repo addressbook.Repository
+}
+
+func NewRemoveGroupMemberUseCase(repo addressbook.Repository) *RemoveGroupMemberUseCase {
+	return &RemoveGroupMemberUseCase{repo: repo}
+}
+
+// Execute removes the contact from the group. memberID is the contact UUID
+// or, for members that no longer exist in the address book, the vCard UID.
+func (uc *RemoveGroupMemberUseCase) Execute(ctx context.Context, addressBookID uint, groupUUID, memberID string) (*contact.Group, error) {
+	obj, card, err := loadGroup(ctx, uc.repo, addressBookID, groupUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	uid := memberID
+	member, err := uc.repo.GetObjectByUUID(ctx, memberID)
+	if err != nil {
+		return nil, err
+	}
+	if member != nil && member.AddressBookID == addressBookID {
+		uid = member.UID
+	}
+
+	if !addressbook.RemoveGroupMember(card, uid) {
+		return nil, ErrMemberNotFound
+	}
+	if err := saveCard(ctx, uc.repo, obj, card); err != nil {
+		return nil, err
+	}
+	return toGroup(ctx, uc.repo, obj, card)
+}
@@ -0,0 +1,34 @@
+package contact
+
+import (
+	"context"
+
+	"github.com/emersion/go-vcard"
+	"github.com/jherrma/caldav-server/internal/domain/addressbook"
+)
+
+// ReplaceCardUseCase overwrites a contact with a complete vCard, as decoded
+// from a jCard or JSContact request body
+type ReplaceCardUseCase struct {
+	repo addressbook.Repository
+}
+
+func NewReplaceCardUseCase(repo addressbook.Repository) *ReplaceCardUseCase {
+	return &ReplaceCardUseCase{repo: repo}
+}
+
+// Execute replaces every property of the stored vCard. The UID is kept so
+// the object path and group memberships stay valid. A nil object means the
+// contact does not exist in the address book.
+func (uc *ReplaceCardUseCase) Execute(ctx context.Context, addressBookID uint, contactUUID string, card vcard.Card) (*addressbook.AddressObject, vcard.Card, error) {
+	obj, _, err := loadCard(ctx, uc.repo, addressBookID, contactUUID)
+	if err != nil || obj == nil {
+		return nil, nil, err
+	}
+
+	card.SetValue(vcard.FieldUID, obj.UID)
+	if err := saveCard(ctx, uc.repo, obj, card); err != nil {
+		return nil, nil, err
+	}
+	return loadCard(ctx, uc.repo, addressBookID, contactUUID)
+}
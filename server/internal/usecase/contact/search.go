@@ -2,17 +2,29 @@ package contact
 
 import (
 	"context"
+	"strings"
 
 	"github.com/jherrma/caldav-server/internal/domain/addressbook"
 	"github.com/jherrma/caldav-server/internal/domain/contact"
 )
 
+// DirectorySource supplies entries of the global address list to contact
+// search and autocomplete
+type DirectorySource interface {
+	Enabled() bool
+	Search(ctx context.Context, query string, limit int) ([]*addressbook.AddressObject, error)
+}
+
 type SearchUseCase struct {
-	repo addressbook.Repository
+	repo      addressbook.Repository
+	directory DirectorySource
 }
 
-func NewSearchUseCase(repo addressbook.Repository) *SearchUseCase {
-	return &SearchUseCase{repo: repo}
+// NewSearchUseCase creates the contact search. directory may be nil; when
+// set and enabled, searches across all address books also return matching
+// directory entries.
+func NewSearchUseCase(repo addressbook.Repository, directory DirectorySource) *SearchUseCase {
+	return &SearchUseCase{repo: repo, directory: directory}
 }
 
 type SearchInput struct {
@@ -40,8 +52,32 @@ func (uc *SearchUseCase) Execute(ctx context.Context, input SearchInput) (*Searc
 	}
 
 	var contacts []*contact.Contact
+	emails := make(map[string]bool)
 	for _, obj := range objs {
 		contacts = append(contacts, FromAddressObject(&obj))
+		if obj.Email != "" {
+			emails[strings.ToLower(obj.Email)] = true
+		}
+	}
+
+	// Fill the remaining slots from the directory, skipping people the user
+	// already has as a contact
+	if input.AddressBookID == nil && uc.directory != nil && uc.directory.Enabled() && len(contacts) < input.Limit {
+		entries, err := uc.directory.Search(ctx, input.Query, 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range entries {
+			if len(contacts) >= input.Limit {
+				break
+			}
+			if obj.Email != "" && emails[strings.ToLower(obj.Email)] {
+				continue
+			}
+			c := FromAddressObject(obj)
+			c.AddressBookID = addressbook.DirectoryPath
+			contacts = append(contacts, c)
+		}
 	}
 
 	return &SearchOutput{
@@ -0,0 +1,276 @@
+package directory
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/jherrma/caldav-server/internal/config"
+	"github.com/jherrma/caldav-server/internal/domain/addressbook"
+	"github.com/jherrma/caldav-server/internal/domain/contact"
+	"github.com/jherrma/caldav-server/internal/domain/user"
+	contactuc "github.com/jherrma/caldav-server/internal/usecase/contact"
+)
+
+var (
+	// ErrDisabled is returned when the directory is switched off
+	ErrDisabled = errors.New("directory is disabled")
+	// ErrNotFound is returned for unknown or hidden directory entries
+	ErrNotFound = errors.New("directory entry not found")
+	// ErrUserNotFound is returned when changing the visibility of an unknown user
+	ErrUserNotFound = errors.New("user not found")
+)
+
+// ListOutput is a page of directory contacts
+type ListOutput struct {
+	Contacts  []*contact.Contact `json:"contacts"`
+	Total     int                `json:"total"`
+	Limit     int                `json:"limit"`
+	Offset    int                `json:"offset"`
+	SyncToken string             `json:"sync_token,omitempty"`
+}
+
+// Service publishes the user accounts as the global address list. The
+// entries are reconciled with the users table whenever the directory is read,
+// so its sync token changes exactly when a user is added, removed, hidden or
+// changes a published field.
+type Service struct {
+	repo     addressbook.DirectoryRepository
+	userRepo user.UserRepository
+	cfg      config.DirectoryConfig
+	region   string // Default phone region for search
+	mu       sync.Mutex
+}
+
+func NewService(repo addressbook.DirectoryRepository, userRepo user.UserRepository, cfg *config.Config) *Service {
+	return &Service{repo: repo, userRepo: userRepo, cfg: cfg.Directory, region: cfg.Contacts.DefaultPhoneRegion}
+}
+
+// Enabled reports whether the directory is published. A nil service is
+// treated as disabled so callers can leave it out.
+func (s *Service) Enabled() bool {
+	return s != nil && s.cfg.Enabled
+}
+
+// Name returns the display name of the directory address book
+func (s *Service) Name() string {
+	if s.cfg.Name == "" {
+		return "Directory"
+	}
+	return s.cfg.Name
+}
+
+// Entries returns all directory entries ordered by name together with the
+// current sync token
+func (s *Service) Entries(ctx context.Context) ([]addressbook.DirectoryEntry, string, error) {
+	if !s.Enabled() {
+		return nil, "", ErrDisabled
+	}
+	return s.refresh(ctx)
+}
+
+// Get returns the entry with the given UID
+func (s *Service) Get(ctx context.Context, uid string) (*addressbook.DirectoryEntry, error) {
+	entries, _, err := s.Entries(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		if entries[i].UID == uid {
+			return &entries[i], nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// Search returns up to limit entries matching the query. Names, the
+// username, email addresses, phone numbers and the organization are
+// searched, ignoring accents and case.
+func (s *Service) Search(ctx context.Context, query string, limit int) ([]*addressbook.AddressObject, error) {
+	entries, _, err := s.Entries(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return filter(entries, query, s.region, limit), nil
+}
+
+// List returns a page of the directory as contacts, optionally filtered by
+// a search query
+func (s *Service) List(ctx context.Context, query string, limit, offset int) (*ListOutput, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	offset = max(offset, 0)
+
+	entries, token, err := s.Entries(ctx)
+	if err != nil {
+		return nil, err
+	}
+	objs := filter(entries, query, s.region, 0)
+
+	out := &ListOutput{Contacts: []*contact.Contact{}, Total: len(objs), Limit: limit, Offset: offset, SyncToken: token}
+	for i := offset; i < len(objs) && i < offset+limit; i++ {
+		out.Contacts = append(out.Contacts, ToContact(objs[i]))
+	}
+	return out, nil
+}
+
+// Contact returns the directory entry with the given UID as a contact
+func (s *Service) Contact(ctx context.Context, uid string) (*contact.Contact, error) {
+	entry, err := s.Get(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	return ToContact(entry.AddressObject()), nil
+}
+
+// SetHidden hides a user from the directory or lists them again. It works
+// while the directory is disabled, so admins can prepare it before enabling.
+func (s *Service) SetHidden(ctx context.Context, userUUID string, hidden bool) (*user.User, error) {
+	u, err := s.userRepo.GetByUUID(ctx, userUUID)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, ErrUserNotFound
+	}
+
+	u.DirectoryHidden = hidden
+	if err := s.userRepo.Update(ctx, u); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// ToContact converts a directory card to a contact of the directory
+// address book
+func ToContact(obj *addressbook.AddressObject) *contact.Contact {
+	c := contactuc.FromAddressObject(obj)
+	c.AddressBookID = addressbook.DirectoryPath
+	return c
+}
+
+// Changes returns the directory changes since the given sync token and the
+// new token. An empty token lists every entry as created.
+func (s *Service) Changes(ctx context.Context, token string) ([]*addressbook.SyncChangeLog, string, error) {
+	entries, current, err := s.Entries(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if token == "" {
+		changes := make([]*addressbook.SyncChangeLog, 0, len(entries))
+		for i := range entries {
+			changes = append(changes, &addressbook.SyncChangeLog{
+				AddressBookID: addressbook.DirectoryAddressBookID,
+				ResourcePath:  entries[i].Path(),
+				ResourceUID:   entries[i].UID,
+				ChangeType:    "created",
+				SyncToken:     current,
+			})
+		}
+		return changes, current, nil
+	}
+	if token == current {
+		return nil, current, nil
+	}
+
+	changes, err := s.repo.GetChangesSinceToken(ctx, token)
+	if err != nil {
+		return nil, "", err
+	}
+	return changes, current, nil
+}
+
+// refresh brings the stored entries in line with the users that are
+// currently visible in the directory
+func (s *Service) refresh(ctx context.Context) ([]addressbook.DirectoryEntry, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	users, err := s.userRepo.ListActive(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	stored, err := s.repo.ListEntries(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	existing := make(map[uint]addressbook.DirectoryEntry, len(stored))
+	for _, e := range stored {
+		existing[e.UserID] = e
+	}
+
+	var created, modified []addressbook.DirectoryEntry
+	for i := range users {
+		u := &users[i]
+		if !s.visible(u) {
+			continue
+		}
+		entry, err := addressbook.NewDirectoryEntry(u, s.cfg.Organization)
+		if err != nil {
+			return nil, "", err
+		}
+		old, ok := existing[u.ID]
+		delete(existing, u.ID)
+		switch {
+		case !ok:
+			created = append(created, *entry)
+		case old.ETag != entry.ETag || old.UID != entry.UID:
+			entry.ID = old.ID
+			entry.CreatedAt = old.CreatedAt
+			modified = append(modified, *entry)
+		}
+	}
+
+	removed := make([]addressbook.DirectoryEntry, 0, len(existing))
+	for _, e := range existing {
+		removed = append(removed, e)
+	}
+
+	if len(created) > 0 || len(modified) > 0 || len(removed) > 0 {
+		if err := s.repo.SaveChanges(ctx, created, modified, removed, addressbook.GenerateSyncToken()); err != nil {
+			return nil, "", err
+		}
+		if stored, err = s.repo.ListEntries(ctx); err != nil {
+			return nil, "", err
+		}
+	}
+
+	token, err := s.repo.SyncToken(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	return stored, token, nil
+}
+
+// visible applies the admin visibility rules
+func (s *Service) visible(u *user.User) bool {
+	if !u.IsActive || u.DirectoryHidden {
+		return false
+	}
+	return u.EmailVerified || s.cfg.IncludeUnverified
+}
+
+// filter returns the entries matching the query as address objects; a limit
+// of zero returns all of them. The query is matched like a contact search
+// (see addressbook.ParseSearchQuery); the username is published as NICKNAME
+// and therefore matches as a name.
+func filter(entries []addressbook.DirectoryEntry, query, region string, limit int) []*addressbook.AddressObject {
+	q := addressbook.ParseSearchQuery(query, region)
+	var res []*addressbook.AddressObject
+	for i := range entries {
+		if limit > 0 && len(res) >= limit {
+			break
+		}
+		obj := entries[i].AddressObject()
+		if len(q.Terms) > 0 {
+			if err := obj.PopulateSearchFieldsFromVCard(region); err != nil || !q.Matches(obj) {
+				continue
+			}
+		}
+		res = append(res, obj)
+	}
+	return res
+}
@@ -0,0 +1,143 @@
+package directory_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jherrma/caldav-server/internal/adapter/repository"
+	"github.com/jherrma/caldav-server/internal/config"
+	"github.com/jherrma/caldav-server/internal/domain/addressbook"
+	"github.com/jherrma/caldav-server/internal/domain/user"
+	contactuc "github.com/jherrma/caldav-server/internal/usecase/contact"
+	"github.com/jherrma/caldav-server/internal/usecase/directory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupService(t *testing.T, cfg config.DirectoryConfig) (*directory.Service, user.UserRepository, *gorm.DB) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&user.User{}, &addressbook.AddressBook{}, &addressbook.AddressObject{},
+		&addressbook.ContactPhoto{}, &addressbook.SyncChangeLog{}, &addressbook.DirectoryEntry{}))
+
+	userRepo := repository.NewUserRepository(db)
+	svc := directory.NewService(repository.NewDirectoryRepository(db), userRepo, &config.Config{Directory: cfg})
+	return svc, userRepo, db
+}
+
+func createUser(t *testing.T, repo user.UserRepository, name, displayName string, verified bool) *user.User {
+	t.Helper()
+	u := &user.User{
+		UUID:          name + "-uuid",
+		Email:         name + "@example.com",
+		Username:      name,
+		DisplayName:   displayName,
+		PasswordHash:  "hash",
+		IsActive:      true,
+		EmailVerified: verified,
+	}
+	require.NoError(t, repo.Create(context.Background(), u))
+	return u
+}
+
+func TestService_Refresh(t *testing.T) {
+	ctx := context.Background()
+	svc, userRepo, _ := setupService(t, config.DirectoryConfig{Enabled: true, Organization: "Acme"})
+
+	alice := createUser(t, userRepo, "alice", "Alice Liddell", true)
+	createUser(t, userRepo, "carl", "", false)
+
+	entries, token, err := svc.Entries(ctx)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "alice-uuid", entries[0].UID)
+	assert.NotEmpty(t, token)
+
+	// Reading again without user changes keeps the token
+	_, again, err := svc.Entries(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, token, again)
+
+	// A changed profile field is a modification
+	alice.Department = "R&D"
+	require.NoError(t, userRepo.Update(ctx, alice))
+	changes, newToken, err := svc.Changes(ctx, token)
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Equal(t, "modified", changes[0].ChangeType)
+	assert.NotEqual(t, token, newToken)
+
+	entry, err := svc.Get(ctx, "alice-uuid")
+	require.NoError(t, err)
+	obj := entry.AddressObject()
+	assert.Equal(t, "Acme;R&D", obj.Organization)
+	assert.Equal(t, "Liddell", obj.FamilyName)
+
+	// Deactivated users disappear
+	alice.IsActive = false
+	require.NoError(t, userRepo.Update(ctx, alice))
+	changes, _, err = svc.Changes(ctx, newToken)
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Equal(t, "deleted", changes[0].ChangeType)
+	_, err = svc.Get(ctx, "alice-uuid")
+	assert.ErrorIs(t, err, directory.ErrNotFound)
+
+	_, _, err = svc.Changes(ctx, "data:,unknown")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestService_IncludeUnverifiedAndDisabled(t *testing.T) {
+	ctx := context.Background()
+	svc, userRepo, _ := setupService(t, config.DirectoryConfig{Enabled: true, IncludeUnverified: true})
+	createUser(t, userRepo, "carl", "", false)
+
+	entries, _, err := svc.Entries(ctx)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	// Without a display name the username is the formatted name
+	assert.Equal(t, "carl", entries[0].FormattedName)
+
+	disabled, _, _ := setupService(t, config.DirectoryConfig{})
+	_, _, err = disabled.Entries(ctx)
+	assert.ErrorIs(t, err, directory.ErrDisabled)
+}
+
+func TestSearchUseCase_IncludesDirectory(t *testing.T) {
+	ctx := context.Background()
+	svc, userRepo, db := setupService(t, config.DirectoryConfig{Enabled: true})
+	abRepo := repository.NewAddressBookRepository(db)
+
+	owner := createUser(t, userRepo, "owner", "Owner", true)
+	createUser(t, userRepo, "dana", "Dana Scully", true)
+	createUser(t, userRepo, "danny", "Danny Ocean", true)
+
+	ab := &addressbook.AddressBook{UUID: "ab-uuid", UserID: owner.ID, Path: "contacts", Name: "Contacts", SyncToken: "1", CTag: "1"}
+	require.NoError(t, abRepo.Create(ctx, ab))
+	obj := &addressbook.AddressObject{
+		UUID:          "obj-uuid",
+		AddressBookID: ab.ID,
+		Path:          "dana.vcf",
+		UID:           "dana-contact",
+		ETag:          "1",
+		VCardData:     "BEGIN:VCARD\r\nVERSION:3.0\r\nUID:dana-contact\r\nFN:Dana (private)\r\nEMAIL:dana@example.com\r\nEND:VCARD\r\n",
+		VCardVersion:  "3.0",
+	}
+	require.NoError(t, obj.PopulateDenormFieldsFromVCard())
+	require.NoError(t, abRepo.CreateObject(ctx, obj))
+
+	out, err := contactuc.NewSearchUseCase(abRepo, svc).Execute(ctx, contactuc.SearchInput{UserID: owner.ID, Query: "dan"})
+	require.NoError(t, err)
+	require.Len(t, out.Contacts, 2)
+	// The user's own contact comes first and hides the directory entry with the same email
+	assert.Equal(t, "Dana (private)", out.Contacts[0].FormattedName)
+	assert.Equal(t, "Danny Ocean", out.Contacts[1].FormattedName)
+	assert.Equal(t, addressbook.DirectoryPath, out.Contacts[1].AddressBookID)
+
+	out, err = contactuc.NewSearchUseCase(abRepo, svc).Execute(ctx, contactuc.SearchInput{UserID: owner.ID, Query: "dan", AddressBookID: &ab.ID})
+	require.NoError(t, err)
+	assert.Len(t, out.Contacts, 1)
+}
@@ -0,0 +1,48 @@
+package event
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/emersion/go-ical"
+	"github.com/google/uuid"
+	"github.com/jherrma/caldav-server/internal/domain/calendar"
+)
+
+// CreateEventDataUseCase stores an event given as a complete iCalendar, as
+// decoded from a jCal or JSCalendar request body
+type CreateEventDataUseCase struct {
+	calendarRepo calendar.CalendarRepository
+}
+
+func NewCreateEventDataUseCase(calendarRepo calendar.CalendarRepository) *CreateEventDataUseCase {
+	return &CreateEventDataUseCase{calendarRepo: calendarRepo}
+}
+
+// Execute returns ErrInvalidEventData when the calendar holds no event or
+// task, or components with different UIDs, and ErrEventUIDConflict when the
+// calendar already has an object with the UID
+func (uc *CreateEventDataUseCase) Execute(ctx context.Context, calendarID uint, cal *ical.Calendar) (*calendar.CalendarObject, error) {
+	eventUUID := uuid.New().String()
+	obj := &calendar.CalendarObject{
+		UUID:       eventUUID,
+		CalendarID: calendarID,
+		Path:       fmt.Sprintf("%s.ics", eventUUID),
+	}
+	if err := storeCalendar(obj, cal); err != nil {
+		return nil, err
+	}
+
+	existing, err := uc.calendarRepo.GetCalendarObjectByUID(ctx, calendarID, obj.UID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrEventUIDConflict
+	}
+
+	if err := uc.calendarRepo.CreateCalendarObject(ctx, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
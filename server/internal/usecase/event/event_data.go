@@ -0,0 +1,140 @@
+package event
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-ical"
+	"github.com/jherrma/caldav-server/internal/domain/calendar"
+)
+
+var (
+	ErrInvalidEventData = errors.New("invalid event data")
+	// ErrEventUIDConflict is returned when the calendar already holds an
+	// object with the UID of a new event (CalDAV no-uid-conflict)
+	ErrEventUIDConflict = errors.New("an event with this UID already exists in the calendar")
+)
+
+// ParseICalData decodes the stored iCalendar data of a calendar object.
+// Imported objects hold only their VEVENT or VTODO block, which is wrapped
+// into a calendar here.
+func ParseICalData(data string) (*ical.Calendar, error) {
+	if !strings.Contains(data, "BEGIN:VCALENDAR") {
+		data = "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//CalCard//EN\r\n" +
+			strings.TrimRight(data, "\r\n") + "\r\nEND:VCALENDAR\r\n"
+	}
+	cal, err := ical.NewDecoder(strings.NewReader(data)).Decode()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse iCalendar data: %w", err)
+	}
+	return cal, nil
+}
+
+// storeCalendar validates a calendar written through the API and copies it,
+// together with the denormalized fields, into obj. All events or tasks of
+// the calendar must share the UID.
+func storeCalendar(obj *calendar.CalendarObject, cal *ical.Calendar) error {
+	var master *ical.Component
+	compType := ""
+	uid := ""
+	for _, comp := range cal.Children {
+		if comp.Name != ical.CompEvent && comp.Name != ical.CompToDo {
+			continue
+		}
+		if compType != "" && comp.Name != compType {
+			return fmt.Errorf("%w: events and tasks cannot be mixed", ErrInvalidEventData)
+		}
+		compType = comp.Name
+		p := comp.Props.Get(ical.PropUID)
+		if p == nil || p.Value == "" {
+			return fmt.Errorf("%w: every %s needs a UID", ErrInvalidEventData, comp.Name)
+		}
+		if uid != "" && p.Value != uid {
+			return fmt.Errorf("%w: all components must share one UID", ErrInvalidEventData)
+		}
+		uid = p.Value
+		if master == nil || (comp.Props.Get(ical.PropRecurrenceID) == nil && master.Props.Get(ical.PropRecurrenceID) != nil) {
+			master = comp
+		}
+	}
+	if master == nil {
+		return fmt.Errorf("%w: no VEVENT or VTODO", ErrInvalidEventData)
+	}
+
+	// Fix up missing required properties
+	if cal.Props.Get(ical.PropProductID) == nil {
+		cal.Props.SetText(ical.PropProductID, "-//CalCard//EN")
+	}
+	if cal.Props.Get(ical.PropVersion) == nil {
+		cal.Props.SetText(ical.PropVersion, "2.0")
+	}
+	for _, comp := range cal.Children {
+		if (comp.Name == ical.CompEvent || comp.Name == ical.CompToDo) && comp.Props.Get(ical.PropDateTimeStamp) == nil {
+			comp.Props.SetDateTime(ical.PropDateTimeStamp, time.Now())
+		}
+	}
+
+	var sb strings.Builder
+	if err := ical.NewEncoder(&sb).Encode(cal); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidEventData, err)
+	}
+
+	obj.UID = uid
+	obj.ComponentType = compType
+	obj.ICalData = sb.String()
+	obj.ContentLength = len(obj.ICalData)
+	obj.ETag = fmt.Sprintf("\"%s\"", calendar.GenerateSyncToken())
+	obj.Summary = textProp(master, ical.PropSummary)
+	obj.Description = textProp(master, ical.PropDescription)
+	obj.Location = textProp(master, ical.PropLocation)
+	obj.StartTime, obj.EndTime, obj.IsAllDay = componentTimes(master)
+	return nil
+}
+
+func textProp(comp *ical.Component, name string) string {
+	if p := comp.Props.Get(name); p != nil {
+		if text, err := p.Text(); err == nil {
+			return text
+		}
+	}
+	return ""
+}
+
+// componentTimes returns the start and end of a component. Without DTEND or
+// DUE the end follows from DURATION, or from the RFC 5545 defaults of one day
+// for dates and no time for date-times.
+func componentTimes(comp *ical.Component) (*time.Time, *time.Time, bool) {
+	var start, end *time.Time
+	allDay := false
+
+	p := comp.Props.Get(ical.PropDateTimeStart)
+	if p != nil {
+		if t, err := p.DateTime(time.UTC); err == nil {
+			start = &t
+			allDay = strings.EqualFold(p.Params.Get(ical.ParamValue), "DATE")
+		}
+	}
+	for _, name := range []string{ical.PropDateTimeEnd, ical.PropDue} {
+		if p := comp.Props.Get(name); p != nil {
+			if t, err := p.DateTime(time.UTC); err == nil {
+				end = &t
+				break
+			}
+		}
+	}
+	if end == nil && start != nil {
+		t := *start
+		if allDay {
+			t = start.AddDate(0, 0, 1)
+		}
+		if p := comp.Props.Get(ical.PropDuration); p != nil {
+			if days, exact, err := parseDuration(p.Value); err == nil {
+				t = start.AddDate(0, 0, days).Add(exact)
+			}
+		}
+		end = &t
+	}
+	return start, end, allDay
+}
@@ -0,0 +1,30 @@
+package event
+
+import (
+	"context"
+
+	"github.com/emersion/go-ical"
+	"github.com/jherrma/caldav-server/internal/domain/calendar"
+)
+
+// GetEventDataUseCase returns the full stored iCalendar of an event, for the
+// jCal and JSCalendar representations
+type GetEventDataUseCase struct {
+	calendarRepo calendar.CalendarRepository
+}
+
+func NewGetEventDataUseCase(calendarRepo calendar.CalendarRepository) *GetEventDataUseCase {
+	return &GetEventDataUseCase{calendarRepo: calendarRepo}
+}
+
+func (uc *GetEventDataUseCase) Execute(ctx context.Context, uuid string) (*calendar.CalendarObject, *ical.Calendar, error) {
+	obj, err := uc.calendarRepo.GetCalendarObjectByUUID(ctx, uuid)
+	if err != nil {
+		return nil, nil, err
+	}
+	cal, err := ParseICalData(obj.ICalData)
+	if err != nil {
+		return nil, nil, err
+	}
+	return obj, cal, nil
+}
@@ -0,0 +1,516 @@
+package event
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/emersion/go-ical"
+)
+
+// Media types of the JSON event representations
+const (
+	MediaTypeJCal       = "application/calendar+json"   // RFC 7265
+	MediaTypeJSCalendar = "application/jscalendar+json" // RFC 8984
+)
+
+var ErrInvalidJCal = errors.New("invalid jCal")
+
+// Default value types of the iCalendar properties (RFC 5545, RFC 7986,
+// RFC 9074). Properties not listed here are written with type "unknown".
+var defaultValueTypes = map[string]string{
+	ical.PropCalendarScale: "text", ical.PropMethod: "text", ical.PropProductID: "text", ical.PropVersion: "text",
+	ical.PropAttach: "uri", ical.PropCategories: "text", ical.PropClass: "text", ical.PropComment: "text",
+	ical.PropDescription: "text", ical.PropGeo: "float", ical.PropLocation: "text",
+	ical.PropPercentComplete: "integer", ical.PropPriority: "integer", ical.PropResources: "text",
+	ical.PropStatus: "text", ical.PropSummary: "text", ical.PropCompleted: "date-time",
+	ical.PropDateTimeEnd: "date-time", ical.PropDue: "date-time", ical.PropDateTimeStart: "date-time",
+	ical.PropDuration: "duration", ical.PropFreeBusy: "period", ical.PropTransparency: "text",
+	ical.PropTimezoneID: "text", ical.PropTimezoneName: "text", ical.PropTimezoneOffsetFrom: "utc-offset",
+	ical.PropTimezoneOffsetTo: "utc-offset", ical.PropTimezoneURL: "uri", ical.PropAttendee: "cal-address",
+	ical.PropContact: "text", ical.PropOrganizer: "cal-address", ical.PropRecurrenceID: "date-time",
+	ical.PropRelatedTo: "text", ical.PropURL: "uri", ical.PropUID: "text", ical.PropExceptionDates: "date-time",
+	ical.PropRecurrenceDates: "date-time", ical.PropRecurrenceRule: "recur", "EXRULE": "recur",
+	ical.PropAction: "text", ical.PropRepeat: "integer", ical.PropTrigger: "duration",
+	ical.PropCreated: "date-time", ical.PropDateTimeStamp: "date-time", ical.PropLastModified: "date-time",
+	ical.PropSequence: "integer", ical.PropRequestStatus: "text", ical.PropName: "text",
+	ical.PropRefreshInterval: "duration", ical.PropSource: "uri", ical.PropColor: "text",
+	ical.PropImage: "uri", ical.PropConference: "uri", "ACKNOWLEDGED": "date-time", "TZUNTIL": "date-time",
+}
+
+// Properties whose value is a ','-separated list, encoded as several values
+var multiValueProps = map[string]bool{
+	ical.PropCategories:      true,
+	ical.PropResources:       true,
+	ical.PropExceptionDates:  true,
+	ical.PropRecurrenceDates: true,
+	ical.PropFreeBusy:        true,
+}
+
+// Integer parts of a recurrence rule (RFC 7265 §3.6.10)
+var integerRecurParts = map[string]bool{
+	"COUNT": true, "INTERVAL": true, "BYSECOND": true, "BYMINUTE": true, "BYHOUR": true,
+	"BYMONTHDAY": true, "BYYEARDAY": true, "BYWEEKNO": true, "BYMONTH": true, "BYSETPOS": true,
+}
+
+// Order in which recurrence rule parts are written back
+var recurPartOrder = []string{
+	"FREQ", "UNTIL", "COUNT", "INTERVAL", "BYSECOND", "BYMINUTE", "BYHOUR", "BYDAY",
+	"BYMONTHDAY", "BYYEARDAY", "BYWEEKNO", "BYMONTH", "BYSETPOS", "WKST",
+}
+
+// ToJCal converts a component and its children to the jCal form
+// [name, [properties], [components]]. Text values are unescaped and dates
+// written in the extended ISO 8601 format jCal requires.
+func ToJCal(comp *ical.Component) []interface{} {
+	props := []interface{}{}
+	for _, name := range sortedProps(comp.Props) {
+		for i := range comp.Props[name] {
+			props = append(props, jcalProperty(&comp.Props[name][i]))
+		}
+	}
+	children := []interface{}{}
+	for _, child := range comp.Children {
+		children = append(children, ToJCal(child))
+	}
+	return []interface{}{strings.ToLower(comp.Name), props, children}
+}
+
+// FromJCal parses a jCal document holding a single vcalendar component
+func FromJCal(data []byte) (*ical.Calendar, error) {
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidJCal, err)
+	}
+	comp, err := parseJCalComponent(doc)
+	if err != nil {
+		return nil, err
+	}
+	if comp.Name != ical.CompCalendar {
+		return nil, fmt.Errorf("%w: expected a vcalendar component", ErrInvalidJCal)
+	}
+	return &ical.Calendar{Component: comp}, nil
+}
+
+func parseJCalComponent(raw interface{}) (*ical.Component, error) {
+	arr, ok := raw.([]interface{})
+	if !ok || len(arr) != 3 {
+		return nil, fmt.Errorf("%w: expected [name, [properties], [components]]", ErrInvalidJCal)
+	}
+	name, ok := arr[0].(string)
+	if !ok || name == "" {
+		return nil, fmt.Errorf("%w: component name must be a string", ErrInvalidJCal)
+	}
+	props, ok := arr[1].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: properties of %s must be an array", ErrInvalidJCal, name)
+	}
+	children, ok := arr[2].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: components of %s must be an array", ErrInvalidJCal, name)
+	}
+
+	comp := ical.NewComponent(strings.ToUpper(name))
+	for _, p := range props {
+		list, ok := p.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%w: properties must be arrays", ErrInvalidJCal)
+		}
+		prop, err := parseJCalProperty(list)
+		if err != nil {
+			return nil, err
+		}
+		comp.Props.Add(prop)
+	}
+	for _, c := range children {
+		child, err := parseJCalComponent(c)
+		if err != nil {
+			return nil, err
+		}
+		comp.Children = append(comp.Children, child)
+	}
+	return comp, nil
+}
+
+// jcalProperty encodes one iCalendar property as [name, params, type, value...]
+func jcalProperty(prop *ical.Prop) []interface{} {
+	params := jcalParams(prop.Params)
+	valueType := defaultValueType(prop.Name)
+	if v := prop.Params.Get(ical.ParamValue); v != "" {
+		valueType = strings.ToLower(v)
+		delete(params, "value")
+	}
+
+	out := []interface{}{strings.ToLower(prop.Name), params, valueType}
+	var values []string
+	switch {
+	case multiValueProps[prop.Name] && valueType == "text":
+		values = splitUnescaped(prop.Value, ',')
+	case multiValueProps[prop.Name]:
+		values = strings.Split(prop.Value, ",")
+	default:
+		values = []string{prop.Value}
+	}
+	for _, v := range values {
+		out = append(out, jcalValue(prop.Name, valueType, v))
+	}
+	return out
+}
+
+func jcalValue(name, valueType, value string) interface{} {
+	switch valueType {
+	case "text":
+		if name == ical.PropRequestStatus {
+			parts := splitUnescaped(value, ';')
+			list := make([]interface{}, len(parts))
+			for i, p := range parts {
+				list[i] = unescapeText(p)
+			}
+			return list
+		}
+		return unescapeText(value)
+	case "date", "date-time", "time":
+		return extendedDateTime(value)
+	case "utc-offset":
+		return extendedOffset(value)
+	case "period":
+		start, end, _ := strings.Cut(value, "/")
+		if !isDuration(end) {
+			end = extendedDateTime(end)
+		}
+		return []interface{}{extendedDateTime(start), end}
+	case "integer":
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+	case "float":
+		if name == ical.PropGeo {
+			lat, lon, _ := strings.Cut(value, ";")
+			return []interface{}{jsonNumber(lat), jsonNumber(lon)}
+		}
+		return jsonNumber(value)
+	case "boolean":
+		return strings.EqualFold(value, "TRUE")
+	case "recur":
+		return recurObject(value)
+	}
+	return value
+}
+
+func parseJCalProperty(p []interface{}) (*ical.Prop, error) {
+	if len(p) < 4 {
+		return nil, fmt.Errorf("%w: property needs name, parameters, type and value", ErrInvalidJCal)
+	}
+	rawName, ok := p[0].(string)
+	if !ok || rawName == "" {
+		return nil, fmt.Errorf("%w: property name must be a string", ErrInvalidJCal)
+	}
+	rawParams, ok := p[1].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: parameters of %s must be an object", ErrInvalidJCal, rawName)
+	}
+	valueType, ok := p[2].(string)
+	if !ok {
+		return nil, fmt.Errorf("%w: type of %s must be a string", ErrInvalidJCal, rawName)
+	}
+
+	prop := ical.NewProp(strings.ToUpper(rawName))
+	for k, v := range rawParams {
+		key := strings.ToUpper(k)
+		switch val := v.(type) {
+		case []interface{}:
+			for _, item := range val {
+				prop.Params.Add(key, jsonScalar(item))
+			}
+		case map[string]interface{}:
+			return nil, fmt.Errorf("%w: parameter %s of %s must be a string or an array", ErrInvalidJCal, k, rawName)
+		default:
+			prop.Params.Add(key, jsonScalar(val))
+		}
+	}
+	valueType = strings.ToLower(valueType)
+	if valueType != "unknown" && valueType != defaultValueType(prop.Name) {
+		prop.Params.Set(ical.ParamValue, strings.ToUpper(valueType))
+	}
+
+	values := make([]string, 0, len(p)-3)
+	for _, v := range p[3:] {
+		value, err := icalValue(prop.Name, valueType, v)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidJCal, rawName, err)
+		}
+		values = append(values, value)
+	}
+	prop.Value = strings.Join(values, ",")
+	return prop, nil
+}
+
+func icalValue(name, valueType string, v interface{}) (string, error) {
+	switch valueType {
+	case "text":
+		if list, ok := v.([]interface{}); ok {
+			parts := make([]string, len(list))
+			for i, item := range list {
+				parts[i] = escapeText(jsonScalar(item))
+			}
+			return strings.Join(parts, ";"), nil
+		}
+		return escapeText(jsonScalar(v)), nil
+	case "date", "date-time", "time":
+		return basicDateTime(jsonScalar(v)), nil
+	case "utc-offset":
+		return basicOffset(jsonScalar(v)), nil
+	case "period":
+		list, ok := v.([]interface{})
+		if !ok || len(list) != 2 {
+			return "", errors.New("period must be [start, end]")
+		}
+		end := jsonScalar(list[1])
+		if !isDuration(end) {
+			end = basicDateTime(end)
+		}
+		return basicDateTime(jsonScalar(list[0])) + "/" + end, nil
+	case "float":
+		if list, ok := v.([]interface{}); ok && name == ical.PropGeo && len(list) == 2 {
+			return jsonScalar(list[0]) + ";" + jsonScalar(list[1]), nil
+		}
+	case "recur":
+		if rule, ok := v.(map[string]interface{}); ok {
+			return recurString(rule), nil
+		}
+	}
+	if _, ok := v.(map[string]interface{}); ok {
+		return "", errors.New("unexpected object value")
+	}
+	return jsonScalar(v), nil
+}
+
+// recurObject splits an RRULE value into the jCal recur object
+func recurObject(value string) map[string]interface{} {
+	rule := make(map[string]interface{})
+	for _, part := range strings.Split(value, ";") {
+		key, val, ok := strings.Cut(part, "=")
+		if !ok {
+			continue
+		}
+		key = strings.ToUpper(key)
+		items := strings.Split(val, ",")
+		list := make([]interface{}, len(items))
+		for i, item := range items {
+			list[i] = item
+			if integerRecurParts[key] {
+				if n, err := strconv.Atoi(item); err == nil {
+					list[i] = n
+				}
+			} else if key == "UNTIL" {
+				list[i] = extendedDateTime(item)
+			}
+		}
+		if len(list) == 1 {
+			rule[strings.ToLower(key)] = list[0]
+		} else {
+			rule[strings.ToLower(key)] = list
+		}
+	}
+	return rule
+}
+
+func recurString(rule map[string]interface{}) string {
+	keys := make([]string, 0, len(rule))
+	for k := range rule {
+		keys = append(keys, strings.ToUpper(k))
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return recurPartRank(keys[i]) < recurPartRank(keys[j]) ||
+			(recurPartRank(keys[i]) == recurPartRank(keys[j]) && keys[i] < keys[j])
+	})
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		var items []string
+		switch val := rule[strings.ToLower(key)].(type) {
+		case []interface{}:
+			for _, item := range val {
+				items = append(items, jsonScalar(item))
+			}
+		default:
+			items = []string{jsonScalar(val)}
+		}
+		if key == "UNTIL" {
+			for i := range items {
+				items[i] = basicDateTime(items[i])
+			}
+		}
+		parts = append(parts, key+"="+strings.Join(items, ","))
+	}
+	return strings.Join(parts, ";")
+}
+
+func recurPartRank(key string) int {
+	for i, k := range recurPartOrder {
+		if k == key {
+			return i
+		}
+	}
+	return len(recurPartOrder)
+}
+
+func defaultValueType(name string) string {
+	if t, ok := defaultValueTypes[name]; ok {
+		return t
+	}
+	return "unknown"
+}
+
+// extendedDateTime rewrites a basic iCalendar date, time or date-time
+// (20240102, 103000Z, 20240102T103000) into its extended form. Values it
+// does not recognise are returned unchanged.
+func extendedDateTime(value string) string {
+	date, timePart, hasTime := strings.Cut(value, "T")
+	if !hasTime && len(value) != 8 {
+		date, timePart, hasTime = "", value, true
+	}
+	if len(date) == 8 && isDigits(date) {
+		date = date[:4] + "-" + date[4:6] + "-" + date[6:]
+	}
+	if !hasTime {
+		return date
+	}
+
+	zone := ""
+	if strings.HasSuffix(timePart, "Z") {
+		timePart, zone = timePart[:len(timePart)-1], "Z"
+	}
+	if len(timePart) == 6 && isDigits(timePart) {
+		timePart = timePart[:2] + ":" + timePart[2:4] + ":" + timePart[4:]
+	}
+	if date == "" {
+		return timePart + zone
+	}
+	return date + "T" + timePart + zone
+}
+
+// basicDateTime is the inverse of extendedDateTime
+func basicDateTime(value string) string {
+	return strings.NewReplacer("-", "", ":", "").Replace(value)
+}
+
+// extendedOffset rewrites +0100 or -013000 as +01:00 or -01:30:00
+func extendedOffset(value string) string {
+	if len(value) < 5 || !isDigits(value[1:]) {
+		return value
+	}
+	out := value[:3] + ":" + value[3:5]
+	if len(value) == 7 {
+		out += ":" + value[5:]
+	}
+	return out
+}
+
+func basicOffset(value string) string {
+	if value == "" {
+		return value
+	}
+	return value[:1] + strings.ReplaceAll(value[1:], ":", "")
+}
+
+func isDuration(value string) bool {
+	return strings.HasPrefix(value, "P") || strings.HasPrefix(value, "-P") || strings.HasPrefix(value, "+P")
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// jcalParams converts iCalendar parameters into a jCal parameter object with
+// lowercase names; multi-valued parameters become arrays
+func jcalParams(params ical.Params) map[string]interface{} {
+	out := make(map[string]interface{}, len(params))
+	for k, values := range params {
+		if len(values) == 1 {
+			out[strings.ToLower(k)] = values[0]
+			continue
+		}
+		list := make([]interface{}, len(values))
+		for i, v := range values {
+			list[i] = v
+		}
+		out[strings.ToLower(k)] = list
+	}
+	return out
+}
+
+func jsonScalar(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case bool:
+		return strings.ToUpper(strconv.FormatBool(val))
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+// jsonNumber returns a numeric value as a number, keeping it as a string if
+// it does not parse
+func jsonNumber(value string) interface{} {
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		return f
+	}
+	return value
+}
+
+var (
+	textUnescaper = strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n")
+	textEscaper   = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`)
+)
+
+func unescapeText(value string) string {
+	return textUnescaper.Replace(value)
+}
+
+func escapeText(value string) string {
+	return textEscaper.Replace(value)
+}
+
+// splitUnescaped splits an escaped text value on a separator that is not
+// preceded by a backslash, keeping the escapes of each part
+func splitUnescaped(value string, sep byte) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case sep:
+			parts = append(parts, value[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, value[start:])
+}
+
+// sortedProps returns the property names of a component in a stable order
+func sortedProps(props ical.Props) []string {
+	names := make([]string, 0, len(props))
+	for name := range props {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,121 @@
+package event
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/emersion/go-ical"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// richCalendar carries what the event DTO does not model: attendees, alarms,
+// categories, attachments and a recurrence with an exception
+const richCalendar = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"PRODID:-//Example//Test//EN\r\n" +
+	"BEGIN:VTIMEZONE\r\n" +
+	"TZID:Europe/Berlin\r\n" +
+	"BEGIN:STANDARD\r\n" +
+	"DTSTART:19701025T030000\r\n" +
+	"TZOFFSETFROM:+0200\r\n" +
+	"TZOFFSETTO:+0100\r\n" +
+	"RRULE:FREQ=YEARLY;BYDAY=-1SU;BYMONTH=10\r\n" +
+	"END:STANDARD\r\n" +
+	"END:VTIMEZONE\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:rich-1\r\n" +
+	"DTSTAMP:20240101T090000Z\r\n" +
+	"CREATED:20231231T120000Z\r\n" +
+	"SEQUENCE:2\r\n" +
+	"DTSTART;TZID=Europe/Berlin:20240108T100000\r\n" +
+	"DTEND;TZID=Europe/Berlin:20240108T113000\r\n" +
+	"SUMMARY:Team sync\\, weekly\r\n" +
+	"DESCRIPTION:Agenda:\\nStatus\r\n" +
+	"LOCATION;LANGUAGE=en:Room 1\r\n" +
+	"GEO:52.52;13.405\r\n" +
+	"CATEGORIES:work,meeting\r\n" +
+	"STATUS:CONFIRMED\r\n" +
+	"CLASS:CONFIDENTIAL\r\n" +
+	"TRANSP:OPAQUE\r\n" +
+	"PRIORITY:5\r\n" +
+	"RRULE:FREQ=WEEKLY;UNTIL=20240226T090000Z;BYDAY=MO\r\n" +
+	"EXDATE;TZID=Europe/Berlin:20240115T100000\r\n" +
+	"ORGANIZER;CN=Alice:mailto:alice@example.com\r\n" +
+	"ATTENDEE;CN=Bob;ROLE=OPT-PARTICIPANT;PARTSTAT=ACCEPTED;RSVP=TRUE;X-FOO=bar:mailto:bob@example.com\r\n" +
+	"ATTACH;FMTTYPE=text/plain:https://example.com/agenda.txt\r\n" +
+	"ATTACH;FMTTYPE=text/plain;VALUE=BINARY;ENCODING=BASE64:aGVsbG8=\r\n" +
+	"X-CUSTOM;X-PARAM=a:custom value\r\n" +
+	"BEGIN:VALARM\r\n" +
+	"ACTION:DISPLAY\r\n" +
+	"DESCRIPTION:Reminder\r\n" +
+	"TRIGGER:-PT15M\r\n" +
+	"END:VALARM\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:rich-1\r\n" +
+	"DTSTAMP:20240101T090000Z\r\n" +
+	"RECURRENCE-ID;TZID=Europe/Berlin:20240122T100000\r\n" +
+	"DTSTART;TZID=Europe/Berlin:20240122T140000\r\n" +
+	"DTEND;TZID=Europe/Berlin:20240122T150000\r\n" +
+	"SUMMARY:Team sync (moved)\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func parseCalendar(t *testing.T, data string) *ical.Calendar {
+	t.Helper()
+	cal, err := ical.NewDecoder(strings.NewReader(data)).Decode()
+	require.NoError(t, err)
+	return cal
+}
+
+func TestJCal_RoundTrip(t *testing.T) {
+	cal := parseCalendar(t, richCalendar)
+
+	out, err := json.Marshal(ToJCal(cal.Component))
+	require.NoError(t, err)
+
+	back, err := FromJCal(out)
+	require.NoError(t, err)
+	assert.Equal(t, cal.Component, back.Component)
+}
+
+func TestJCal_Format(t *testing.T) {
+	out, err := json.Marshal(ToJCal(parseCalendar(t, richCalendar).Component))
+	require.NoError(t, err)
+
+	var doc []interface{}
+	require.NoError(t, json.Unmarshal(out, &doc))
+	assert.Equal(t, "vcalendar", doc[0])
+	children := doc[2].([]interface{})
+	require.Len(t, children, 3)
+	assert.Equal(t, "vtimezone", children[0].([]interface{})[0])
+
+	event := children[1].([]interface{})
+	props := map[string][]interface{}{}
+	for _, p := range event[1].([]interface{}) {
+		prop := p.([]interface{})
+		props[prop[0].(string)] = prop
+	}
+	assert.Equal(t, []interface{}{"dtstart", map[string]interface{}{"tzid": "Europe/Berlin"}, "date-time", "2024-01-08T10:00:00"}, props["dtstart"])
+	assert.Equal(t, []interface{}{"summary", map[string]interface{}{}, "text", "Team sync, weekly"}, props["summary"])
+	assert.Equal(t, []interface{}{"categories", map[string]interface{}{}, "text", "work", "meeting"}, props["categories"])
+	assert.Equal(t, []interface{}{"geo", map[string]interface{}{}, "float", []interface{}{52.52, 13.405}}, props["geo"])
+	assert.Equal(t, []interface{}{"priority", map[string]interface{}{}, "integer", float64(5)}, props["priority"])
+	assert.Equal(t, []interface{}{"rrule", map[string]interface{}{}, "recur", map[string]interface{}{
+		"freq": "WEEKLY", "until": "2024-02-26T09:00:00Z", "byday": "MO",
+	}}, props["rrule"])
+	assert.Equal(t, []interface{}{"attach", map[string]interface{}{"fmttype": "text/plain", "encoding": "BASE64"}, "binary", "aGVsbG8="}, props["attach"])
+	assert.Equal(t, []interface{}{"x-custom", map[string]interface{}{"x-param": "a"}, "unknown", "custom value"}, props["x-custom"])
+
+	alarm := event[2].([]interface{})[0].([]interface{})
+	assert.Equal(t, "valarm", alarm[0])
+}
+
+func TestFromJCal_Invalid(t *testing.T) {
+	for _, data := range []string{`{}`, `["vcalendar"]`, `["vevent", [], []]`, `["vcalendar", [["summary", {}, "text"]], []]`, `["vcalendar", [["summary", [], "text", "x"]], []]`} {
+		_, err := FromJCal([]byte(data))
+		assert.ErrorIs(t, err, ErrInvalidJCal, data)
+	}
+}
@@ -0,0 +1,1361 @@
+package event
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-ical"
+)
+
+var ErrInvalidJSCalendar = errors.New("invalid JSCalendar object")
+
+// JSCalendarObject is an Event, Task or Group in the JSCalendar format of RFC
+// 8984. iCalendar properties without a JSCalendar equivalent travel in
+// ICalProps as jCal properties, and the components beside the object, such
+// as VTIMEZONE, in ICalComponents, so an object converts back without loss.
+// Of the calendar's own properties only PRODID is kept. Recurrence overrides
+// hold the complete exception instances, which are valid patches of the
+// master as well.
+type JSCalendarObject struct {
+	Type                string                       `json:"@type,omitempty"`
+	UID                 string                       `json:"uid,omitempty"`
+	ProdID              string                       `json:"prodId,omitempty"`
+	Created             string                       `json:"created,omitempty"`
+	Updated             string                       `json:"updated,omitempty"`
+	Sequence            int                          `json:"sequence,omitempty"`
+	Title               string                       `json:"title,omitempty"`
+	Description         string                       `json:"description,omitempty"`
+	Color               string                       `json:"color,omitempty"`
+	Keywords            map[string]bool              `json:"keywords,omitempty"`
+	Locations           map[string]*JSCalendarEntry  `json:"locations,omitempty"`
+	Links               map[string]*JSCalendarEntry  `json:"links,omitempty"`
+	RecurrenceID        string                       `json:"recurrenceId,omitempty"`
+	Start               string                       `json:"start,omitempty"`
+	Due                 string                       `json:"due,omitempty"`
+	TimeZone            string                       `json:"timeZone,omitempty"`
+	Duration            string                       `json:"duration,omitempty"`
+	ShowWithoutTime     bool                         `json:"showWithoutTime,omitempty"`
+	RecurrenceRules     []*JSCalendarRecurrenceRule  `json:"recurrenceRules,omitempty"`
+	RecurrenceOverrides map[string]*JSCalendarObject `json:"recurrenceOverrides,omitempty"`
+	Excluded            bool                         `json:"excluded,omitempty"`
+	Status              string                       `json:"status,omitempty"`
+	FreeBusyStatus      string                       `json:"freeBusyStatus,omitempty"`
+	Privacy             string                       `json:"privacy,omitempty"`
+	Priority            int                          `json:"priority,omitempty"`
+	Progress            string                       `json:"progress,omitempty"`
+	PercentComplete     int                          `json:"percentComplete,omitempty"`
+	ReplyTo             map[string]string            `json:"replyTo,omitempty"`
+	Participants        map[string]*JSCalendarEntry  `json:"participants,omitempty"`
+	Alerts              map[string]*JSCalendarEntry  `json:"alerts,omitempty"`
+	Entries             []*JSCalendarObject          `json:"entries,omitempty"`
+	ICalProps           [][]interface{}              `json:"iCalProps,omitempty"`
+	ICalComponents      [][]interface{}              `json:"iCalComponents,omitempty"`
+}
+
+// JSCalendarEntry is a Location, Link, Participant or Alert. Only the fields
+// defined for its type are set. ICalParams holds the parameters of the
+// underlying property that have no JSCalendar equivalent (for alerts those of
+// TRIGGER), ICalProps the other properties of an alarm.
+type JSCalendarEntry struct {
+	Type                string                 `json:"@type"`
+	Name                string                 `json:"name,omitempty"`
+	Coordinates         string                 `json:"coordinates,omitempty"`
+	Href                string                 `json:"href,omitempty"`
+	ContentType         string                 `json:"contentType,omitempty"`
+	Size                int                    `json:"size,omitempty"`
+	Rel                 string                 `json:"rel,omitempty"`
+	Email               string                 `json:"email,omitempty"`
+	SendTo              map[string]string      `json:"sendTo,omitempty"`
+	Kind                string                 `json:"kind,omitempty"`
+	Roles               map[string]bool        `json:"roles,omitempty"`
+	ParticipationStatus string                 `json:"participationStatus,omitempty"`
+	ExpectReply         bool                   `json:"expectReply,omitempty"`
+	Trigger             *JSCalendarTrigger     `json:"trigger,omitempty"`
+	Action              string                 `json:"action,omitempty"`
+	Acknowledged        string                 `json:"acknowledged,omitempty"`
+	ICalParams          map[string]interface{} `json:"iCalParams,omitempty"`
+	ICalProps           [][]interface{}        `json:"iCalProps,omitempty"`
+}
+
+// JSCalendarTrigger is an OffsetTrigger or AbsoluteTrigger of an alert
+type JSCalendarTrigger struct {
+	Type       string `json:"@type"`
+	Offset     string `json:"offset,omitempty"`
+	RelativeTo string `json:"relativeTo,omitempty"`
+	When       string `json:"when,omitempty"`
+}
+
+// JSCalendarRecurrenceRule is the JSCalendar form of an RRULE
+type JSCalendarRecurrenceRule struct {
+	Type           string           `json:"@type"`
+	Frequency      string           `json:"frequency"`
+	Interval       int              `json:"interval,omitempty"`
+	Rscale         string           `json:"rscale,omitempty"`
+	Skip           string           `json:"skip,omitempty"`
+	FirstDayOfWeek string           `json:"firstDayOfWeek,omitempty"`
+	ByDay          []JSCalendarNDay `json:"byDay,omitempty"`
+	ByMonthDay     []int            `json:"byMonthDay,omitempty"`
+	ByMonth        []string         `json:"byMonth,omitempty"`
+	ByYearDay      []int            `json:"byYearDay,omitempty"`
+	ByWeekNo       []int            `json:"byWeekNo,omitempty"`
+	ByHour         []int            `json:"byHour,omitempty"`
+	ByMinute       []int            `json:"byMinute,omitempty"`
+	BySecond       []int            `json:"bySecond,omitempty"`
+	BySetPosition  []int            `json:"bySetPosition,omitempty"`
+	Count          int              `json:"count,omitempty"`
+	Until          string           `json:"until,omitempty"`
+}
+
+// JSCalendarNDay is a weekday of a recurrence rule, optionally the nth of
+// its period
+type JSCalendarNDay struct {
+	Type        string `json:"@type"`
+	Day         string `json:"day"`
+	NthOfPeriod int    `json:"nthOfPeriod,omitempty"`
+}
+
+const utcTimeZone = "Etc/UTC"
+
+// iCalendar values and their JSCalendar equivalents
+var (
+	eventStatuses   = map[string]string{"TENTATIVE": "tentative", "CONFIRMED": "confirmed", "CANCELLED": "cancelled"}
+	taskProgress    = map[string]string{"NEEDS-ACTION": "needs-action", "COMPLETED": "completed", "IN-PROCESS": "in-process", "CANCELLED": "cancelled"}
+	privacyClasses  = map[string]string{"PUBLIC": "public", "PRIVATE": "private", "CONFIDENTIAL": "secret"}
+	freeBusyStatus  = map[string]string{"OPAQUE": "busy", "TRANSPARENT": "free"}
+	alertActions    = map[string]string{"DISPLAY": "display", "EMAIL": "email"}
+	participantRole = map[string][]string{
+		"REQ-PARTICIPANT": {"attendee"},
+		"OPT-PARTICIPANT": {"attendee", "optional"},
+		"NON-PARTICIPANT": {"informational"},
+		"CHAIR":           {"attendee", "chair"},
+	}
+)
+
+// ToJSCalendar converts a calendar to a JSCalendar object. A calendar
+// holding a single event or task (with its exceptions) becomes that Event or
+// Task, anything else a Group of them. Components other than VEVENT and
+// VTODO, such as VTIMEZONE, are kept in ICalComponents.
+func ToJSCalendar(cal *ical.Calendar) *JSCalendarObject {
+	entries, others := jsEntries(cal)
+	if len(entries) != 1 {
+		return jsGroup(cal, entries, others)
+	}
+	obj := entries[0]
+	obj.ProdID = calendarProdID(cal)
+	obj.ICalComponents = append(obj.ICalComponents, others...)
+	return obj
+}
+
+// ToJSCalendarGroup converts a calendar to a Group of its events and tasks,
+// however many there are
+func ToJSCalendarGroup(cal *ical.Calendar) *JSCalendarObject {
+	entries, others := jsEntries(cal)
+	return jsGroup(cal, entries, others)
+}
+
+func jsGroup(cal *ical.Calendar, entries []*JSCalendarObject, others [][]interface{}) *JSCalendarObject {
+	return &JSCalendarObject{
+		Type:           "Group",
+		ProdID:         calendarProdID(cal),
+		Entries:        entries,
+		ICalComponents: others,
+	}
+}
+
+func calendarProdID(cal *ical.Calendar) string {
+	if p := cal.Props.Get(ical.PropProductID); p != nil {
+		return unescapeText(p.Value)
+	}
+	return ""
+}
+
+// jsEntries maps the events and tasks of a calendar, and returns the other
+// components in jCal form
+func jsEntries(cal *ical.Calendar) ([]*JSCalendarObject, [][]interface{}) {
+	var entries []*JSCalendarObject
+	var others [][]interface{}
+	byUID := map[string]*JSCalendarObject{}
+
+	// Masters first, so exceptions can be attached to them
+	for _, comp := range cal.Children {
+		if !isSchedulable(comp) {
+			others = append(others, ToJCal(comp))
+			continue
+		}
+		if comp.Props.Get(ical.PropRecurrenceID) != nil {
+			continue
+		}
+		obj := componentToJS(comp)
+		entries = append(entries, obj)
+		if uid := comp.Props.Get(ical.PropUID); uid != nil && byUID[uid.Value] == nil {
+			byUID[uid.Value] = obj
+		}
+	}
+	for _, comp := range cal.Children {
+		if !isSchedulable(comp) || comp.Props.Get(ical.PropRecurrenceID) == nil {
+			continue
+		}
+		uid := comp.Props.Get(ical.PropUID)
+		if uid != nil && byUID[uid.Value] != nil && addOverride(byUID[uid.Value], comp) {
+			continue
+		}
+		// An exception without its master, e.g. a single invitation to one
+		// instance, stands on its own
+		entries = append(entries, componentToJS(comp))
+	}
+	return entries, others
+}
+
+// FromJSCalendar parses a JSCalendar Event, Task or Group into a calendar
+func FromJSCalendar(data []byte) (*ical.Calendar, error) {
+	var obj JSCalendarObject
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidJSCalendar, err)
+	}
+
+	cal := ical.NewCalendar()
+	prodID := obj.ProdID
+	if prodID == "" {
+		prodID = "-//CalCard//EN"
+	}
+	cal.Props.SetText(ical.PropProductID, prodID)
+	cal.Props.SetText(ical.PropVersion, "2.0")
+
+	objects := []*JSCalendarObject{&obj}
+	if obj.Type == "Group" {
+		objects = obj.Entries
+	}
+	others := obj.ICalComponents
+	for _, o := range objects {
+		comps, err := jsToComponents(o)
+		if err != nil {
+			return nil, err
+		}
+		cal.Children = append(cal.Children, comps...)
+		if o != &obj {
+			others = append(others, o.ICalComponents...)
+		}
+	}
+	for _, raw := range others {
+		comp, err := parseJCalComponent(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: iCalComponents: %v", ErrInvalidJSCalendar, err)
+		}
+		cal.Children = append(cal.Children, comp)
+	}
+	if len(cal.Children) == 0 {
+		return nil, fmt.Errorf("%w: no event or task", ErrInvalidJSCalendar)
+	}
+	return cal, nil
+}
+
+func isSchedulable(comp *ical.Component) bool {
+	return comp.Name == ical.CompEvent || comp.Name == ical.CompToDo
+}
+
+// componentToJS maps a VEVENT or VTODO to an Event or Task
+func componentToJS(comp *ical.Component) *JSCalendarObject {
+	obj := &JSCalendarObject{Type: "Event"}
+	if comp.Name == ical.CompToDo {
+		obj.Type = "Task"
+	}
+	keep := func(props ...ical.Prop) {
+		for i := range props {
+			obj.ICalProps = append(obj.ICalProps, jcalProperty(&props[i]))
+		}
+	}
+
+	// The start decides the time zone every other date is expressed in
+	startProp := comp.Props.Get(ical.PropDateTimeStart)
+	var start time.Time
+	startMapped, startKnown := false, false
+	if startProp != nil {
+		if local, tz, dateOnly, ok := jsDateTime(startProp); ok {
+			obj.Start, obj.TimeZone, obj.ShowWithoutTime = local, tz, dateOnly
+			startMapped = true
+			// A custom TZID has no known offset, so the end cannot be
+			// turned into a duration
+			if t, err := parseLocal(local, tz); err == nil {
+				start, startKnown = t, true
+			}
+		}
+	}
+
+	for _, name := range sortedProps(comp.Props) {
+		props := comp.Props[name]
+		p := &props[0]
+		single := len(props) == 1 && len(p.Params) == 0
+		switch name {
+		case ical.PropDateTimeStart:
+			if startMapped {
+				continue
+			}
+		case ical.PropUID:
+			if single {
+				obj.UID = unescapeText(p.Value)
+				continue
+			}
+		case ical.PropSummary:
+			if single {
+				obj.Title = unescapeText(p.Value)
+				continue
+			}
+		case ical.PropDescription:
+			if single {
+				obj.Description = unescapeText(p.Value)
+				continue
+			}
+		case ical.PropColor:
+			if single {
+				obj.Color = unescapeText(p.Value)
+				continue
+			}
+		case ical.PropCreated, ical.PropLastModified:
+			if single && strings.HasSuffix(p.Value, "Z") {
+				if name == ical.PropCreated {
+					obj.Created = extendedDateTime(p.Value)
+				} else {
+					obj.Updated = extendedDateTime(p.Value)
+				}
+				continue
+			}
+		case ical.PropSequence, ical.PropPriority, ical.PropPercentComplete:
+			// Zero is the default of all three, so an explicit zero is kept
+			if n, err := strconv.Atoi(p.Value); single && err == nil && n > 0 {
+				switch name {
+				case ical.PropSequence:
+					obj.Sequence = n
+				case ical.PropPriority:
+					obj.Priority = n
+				default:
+					obj.PercentComplete = n
+				}
+				continue
+			}
+		case ical.PropStatus:
+			statuses := eventStatuses
+			if comp.Name == ical.CompToDo {
+				statuses = taskProgress
+			}
+			if s, ok := statuses[strings.ToUpper(p.Value)]; single && ok {
+				if comp.Name == ical.CompToDo {
+					obj.Progress = s
+				} else {
+					obj.Status = s
+				}
+				continue
+			}
+		case ical.PropClass:
+			if s, ok := privacyClasses[strings.ToUpper(p.Value)]; single && ok {
+				obj.Privacy = s
+				continue
+			}
+		case ical.PropTransparency:
+			if s, ok := freeBusyStatus[strings.ToUpper(p.Value)]; single && ok {
+				obj.FreeBusyStatus = s
+				continue
+			}
+		case ical.PropCategories:
+			if plainProps(props) {
+				obj.Keywords = make(map[string]bool)
+				for _, c := range props {
+					for _, k := range splitUnescaped(c.Value, ',') {
+						obj.Keywords[unescapeText(k)] = true
+					}
+				}
+				continue
+			}
+		case ical.PropLocation:
+			if len(props) == 1 {
+				location(obj).Name = unescapeText(p.Value)
+				location(obj).ICalParams = residualParams(p.Params)
+				continue
+			}
+		case ical.PropGeo:
+			if single {
+				lat, lon, _ := strings.Cut(p.Value, ";")
+				location(obj).Coordinates = "geo:" + lat + "," + lon
+				continue
+			}
+		case ical.PropAttach:
+			for i := range props {
+				addLink(obj, &props[i])
+			}
+			continue
+		case ical.PropOrganizer:
+			if len(props) == 1 {
+				addParticipant(obj, p, true)
+				obj.ReplyTo = map[string]string{"imip": p.Value}
+				continue
+			}
+		case ical.PropAttendee:
+			for i := range props {
+				addParticipant(obj, &props[i], false)
+			}
+			continue
+		case ical.PropDateTimeEnd, ical.PropDuration:
+			if len(props) == 1 && startKnown && obj.Duration == "" {
+				if d, ok := eventDuration(p, start, obj.ShowWithoutTime, obj.TimeZone); ok {
+					obj.Duration = d
+					continue
+				}
+			}
+		case ical.PropDue:
+			if local, tz, _, ok := jsDateTime(p); len(props) == 1 && ok && (!startMapped || tz == obj.TimeZone) {
+				obj.Due = local
+				if !startMapped {
+					obj.TimeZone = tz
+				}
+				continue
+			}
+		case ical.PropRecurrenceRule:
+			if rule, ok := recurrenceRule(p, obj); len(props) == 1 && ok {
+				obj.RecurrenceRules = []*JSCalendarRecurrenceRule{rule}
+				continue
+			}
+		case ical.PropExceptionDates:
+			if excludeDates(obj, props) {
+				continue
+			}
+		case ical.PropRecurrenceID:
+			if local, tz, _, ok := jsDateTime(p); len(props) == 1 && ok {
+				obj.RecurrenceID = local
+				if !startMapped {
+					obj.TimeZone = tz
+				}
+				continue
+			}
+		}
+		keep(props...)
+	}
+
+	for _, child := range comp.Children {
+		if child.Name == ical.CompAlarm {
+			if obj.Alerts == nil {
+				obj.Alerts = make(map[string]*JSCalendarEntry)
+			}
+			obj.Alerts[fmt.Sprintf("a%d", len(obj.Alerts)+1)] = alarmToJS(child)
+			continue
+		}
+		// Only alarms may nest in events and tasks; anything else is
+		// written back beside the object
+		obj.ICalComponents = append(obj.ICalComponents, ToJCal(child))
+	}
+	return obj
+}
+
+// addOverride attaches an exception to its master. It reports false when the
+// exception's RECURRENCE-ID cannot be expressed in the master's time zone.
+func addOverride(master *JSCalendarObject, comp *ical.Component) bool {
+	key, ok := localIn(comp.Props.Get(ical.PropRecurrenceID), master.TimeZone, master.ShowWithoutTime)
+	if !ok || master.Start == "" {
+		return false
+	}
+	override := componentToJS(comp)
+	override.Type, override.UID, override.RecurrenceID = "", "", ""
+	if master.RecurrenceOverrides == nil {
+		master.RecurrenceOverrides = make(map[string]*JSCalendarObject)
+	}
+	master.RecurrenceOverrides[key] = override
+	return true
+}
+
+// excludeDates turns EXDATE values into excluded recurrence overrides
+func excludeDates(obj *JSCalendarObject, props []ical.Prop) bool {
+	if obj.Start == "" {
+		return false
+	}
+	var keys []string
+	for _, p := range props {
+		for _, v := range strings.Split(p.Value, ",") {
+			single := p
+			single.Value = v
+			key, ok := localIn(&single, obj.TimeZone, obj.ShowWithoutTime)
+			if !ok {
+				return false
+			}
+			keys = append(keys, key)
+		}
+	}
+	if obj.RecurrenceOverrides == nil {
+		obj.RecurrenceOverrides = make(map[string]*JSCalendarObject)
+	}
+	for _, key := range keys {
+		obj.RecurrenceOverrides[key] = &JSCalendarObject{Excluded: true}
+	}
+	return true
+}
+
+func location(obj *JSCalendarObject) *JSCalendarEntry {
+	if obj.Locations == nil {
+		obj.Locations = map[string]*JSCalendarEntry{"l1": {Type: "Location"}}
+	}
+	return obj.Locations["l1"]
+}
+
+func addLink(obj *JSCalendarObject, p *ical.Prop) {
+	link := &JSCalendarEntry{Type: "Link", Rel: "enclosure", Href: p.Value}
+	params := make(ical.Params)
+	for k, v := range p.Params {
+		params[k] = v
+	}
+	link.ContentType = params.Get(ical.ParamFormatType)
+	params.Del(ical.ParamFormatType)
+	if n, err := strconv.Atoi(params.Get("SIZE")); err == nil {
+		link.Size = n
+		params.Del("SIZE")
+	}
+	if strings.EqualFold(params.Get(ical.ParamValue), "BINARY") && strings.EqualFold(params.Get(ical.ParamEncoding), "BASE64") {
+		mediaType := link.ContentType
+		if mediaType == "" {
+			mediaType = "application/octet-stream"
+		}
+		link.Href = "data:" + mediaType + ";base64," + p.Value
+		params.Del(ical.ParamValue)
+		params.Del(ical.ParamEncoding)
+	}
+	link.ICalParams = residualParams(params)
+
+	if obj.Links == nil {
+		obj.Links = make(map[string]*JSCalendarEntry)
+	}
+	obj.Links[fmt.Sprintf("k%d", len(obj.Links)+1)] = link
+}
+
+func addParticipant(obj *JSCalendarObject, p *ical.Prop, organizer bool) {
+	part := &JSCalendarEntry{
+		Type:   "Participant",
+		SendTo: map[string]string{"imip": p.Value},
+		Roles:  make(map[string]bool),
+	}
+	params := make(ical.Params)
+	for k, v := range p.Params {
+		params[k] = v
+	}
+	part.Name = params.Get(ical.ParamCommonName)
+	params.Del(ical.ParamCommonName)
+	part.Email = params.Get(ical.ParamEmail)
+	params.Del(ical.ParamEmail)
+	if part.Email == "" && strings.HasPrefix(strings.ToLower(p.Value), "mailto:") {
+		part.Email = p.Value[len("mailto:"):]
+	}
+
+	if organizer {
+		part.Roles["owner"] = true
+	} else {
+		roles := participantRole["REQ-PARTICIPANT"]
+		if r := params.Get(ical.ParamRole); r != "" {
+			var ok bool
+			if roles, ok = participantRole[strings.ToUpper(r)]; ok {
+				params.Del(ical.ParamRole)
+			} else {
+				roles = participantRole["REQ-PARTICIPANT"]
+			}
+		}
+		for _, r := range roles {
+			part.Roles[r] = true
+		}
+		if v := params.Get(ical.ParamCalendarUserType); v != "" {
+			part.Kind = strings.ToLower(v)
+			params.Del(ical.ParamCalendarUserType)
+		}
+		if v := params.Get(ical.ParamParticipationStatus); v != "" {
+			part.ParticipationStatus = strings.ToLower(v)
+			params.Del(ical.ParamParticipationStatus)
+		}
+		if v := params.Get(ical.ParamRSVP); strings.EqualFold(v, "TRUE") {
+			part.ExpectReply = true
+			params.Del(ical.ParamRSVP)
+		}
+	}
+	part.ICalParams = residualParams(params)
+
+	if obj.Participants == nil {
+		obj.Participants = make(map[string]*JSCalendarEntry)
+	}
+	obj.Participants[fmt.Sprintf("p%d", len(obj.Participants)+1)] = part
+}
+
+func alarmToJS(comp *ical.Component) *JSCalendarEntry {
+	alert := &JSCalendarEntry{Type: "Alert"}
+	for _, name := range sortedProps(comp.Props) {
+		props := comp.Props[name]
+		p := &props[0]
+		switch name {
+		case ical.PropAction:
+			if a, ok := alertActions[strings.ToUpper(p.Value)]; len(props) == 1 && len(p.Params) == 0 && ok {
+				alert.Action = a
+				continue
+			}
+		case ical.PropTrigger:
+			if len(props) == 1 {
+				params := make(ical.Params)
+				for k, v := range p.Params {
+					params[k] = v
+				}
+				if strings.EqualFold(params.Get(ical.ParamValue), "DATE-TIME") && strings.HasSuffix(p.Value, "Z") {
+					params.Del(ical.ParamValue)
+					alert.Trigger = &JSCalendarTrigger{Type: "AbsoluteTrigger", When: extendedDateTime(p.Value)}
+				} else if isDuration(p.Value) {
+					relativeTo := "start"
+					if strings.EqualFold(params.Get(ical.ParamRelated), "END") {
+						relativeTo = "end"
+					}
+					params.Del(ical.ParamRelated)
+					alert.Trigger = &JSCalendarTrigger{Type: "OffsetTrigger", Offset: strings.TrimPrefix(p.Value, "+"), RelativeTo: relativeTo}
+				}
+				if alert.Trigger != nil {
+					alert.ICalParams = residualParams(params)
+					continue
+				}
+			}
+		case "ACKNOWLEDGED":
+			if len(props) == 1 && len(p.Params) == 0 && strings.HasSuffix(p.Value, "Z") {
+				alert.Acknowledged = extendedDateTime(p.Value)
+				continue
+			}
+		}
+		for i := range props {
+			alert.ICalProps = append(alert.ICalProps, jcalProperty(&props[i]))
+		}
+	}
+	return alert
+}
+
+// recurrenceRule maps an RRULE without parts JSCalendar cannot express
+func recurrenceRule(p *ical.Prop, obj *JSCalendarObject) (*JSCalendarRecurrenceRule, bool) {
+	if len(p.Params) != 0 {
+		return nil, false
+	}
+	rule := &JSCalendarRecurrenceRule{Type: "RecurrenceRule"}
+	for _, part := range strings.Split(p.Value, ";") {
+		key, val, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, false
+		}
+		var err error
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			rule.Frequency = strings.ToLower(val)
+		case "INTERVAL":
+			rule.Interval, err = strconv.Atoi(val)
+		case "COUNT":
+			rule.Count, err = strconv.Atoi(val)
+		case "RSCALE":
+			rule.Rscale = strings.ToLower(val)
+		case "SKIP":
+			rule.Skip = strings.ToLower(val)
+		case "WKST":
+			rule.FirstDayOfWeek = strings.ToLower(val)
+		case "UNTIL":
+			until := ical.NewProp(ical.PropRecurrenceID)
+			until.Value = val
+			if obj.TimeZone != "" && obj.TimeZone != utcTimeZone && strings.HasSuffix(val, "Z") {
+				// UNTIL is in UTC while the start has a time zone
+				t, perr := time.Parse("20060102T150405Z", val)
+				loc, lerr := time.LoadLocation(obj.TimeZone)
+				if perr != nil || lerr != nil {
+					return nil, false
+				}
+				rule.Until = t.In(loc).Format("2006-01-02T15:04:05")
+				continue
+			}
+			if rule.Until, ok = localIn(until, obj.TimeZone, obj.ShowWithoutTime); !ok {
+				return nil, false
+			}
+		case "BYDAY":
+			for _, d := range strings.Split(val, ",") {
+				if len(d) < 2 {
+					return nil, false
+				}
+				nday := JSCalendarNDay{Type: "NDay", Day: strings.ToLower(d[len(d)-2:])}
+				if n := d[:len(d)-2]; n != "" {
+					if nday.NthOfPeriod, err = strconv.Atoi(n); err != nil {
+						return nil, false
+					}
+				}
+				rule.ByDay = append(rule.ByDay, nday)
+			}
+		case "BYMONTH":
+			rule.ByMonth = strings.Split(val, ",")
+		case "BYMONTHDAY":
+			rule.ByMonthDay, err = intList(val)
+		case "BYYEARDAY":
+			rule.ByYearDay, err = intList(val)
+		case "BYWEEKNO":
+			rule.ByWeekNo, err = intList(val)
+		case "BYHOUR":
+			rule.ByHour, err = intList(val)
+		case "BYMINUTE":
+			rule.ByMinute, err = intList(val)
+		case "BYSECOND":
+			rule.BySecond, err = intList(val)
+		case "BYSETPOS":
+			rule.BySetPosition, err = intList(val)
+		default:
+			return nil, false
+		}
+		if err != nil {
+			return nil, false
+		}
+	}
+	return rule, rule.Frequency != ""
+}
+
+func intList(value string) ([]int, error) {
+	var out []int
+	for _, v := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
+
+// jsDateTime converts a DATE or DATE-TIME property into a JSCalendar
+// LocalDateTime and time zone. ok is false for parameters other than TZID
+// and VALUE.
+func jsDateTime(p *ical.Prop) (local, timeZone string, dateOnly, ok bool) {
+	for k := range p.Params {
+		if k != ical.ParamTimezoneID && k != ical.ParamValue {
+			return "", "", false, false
+		}
+	}
+	value := p.Value
+	switch {
+	case strings.EqualFold(p.Params.Get(ical.ParamValue), "DATE") || len(value) == 8:
+		if len(value) != 8 || !isDigits(value) {
+			return "", "", false, false
+		}
+		return extendedDateTime(value) + "T00:00:00", "", true, true
+	case strings.HasSuffix(value, "Z"):
+		timeZone = utcTimeZone
+		value = strings.TrimSuffix(value, "Z")
+	default:
+		timeZone = p.Params.Get(ical.ParamTimezoneID)
+	}
+	local = extendedDateTime(value)
+	if _, err := time.Parse("2006-01-02T15:04:05", local); err != nil {
+		return "", "", false, false
+	}
+	return local, timeZone, false, true
+}
+
+// icalDateTime is the inverse of jsDateTime
+func icalDateTime(name, local, timeZone string, dateOnly bool) *ical.Prop {
+	p := ical.NewProp(name)
+	switch {
+	case dateOnly:
+		p.Params.Set(ical.ParamValue, "DATE")
+		p.Value = basicDateTime(strings.SplitN(local, "T", 2)[0])
+	case timeZone == utcTimeZone:
+		p.Value = basicDateTime(local) + "Z"
+	default:
+		if timeZone != "" {
+			p.Params.Set(ical.ParamTimezoneID, timeZone)
+		}
+		p.Value = basicDateTime(local)
+	}
+	return p
+}
+
+// localIn expresses a date or date-time property as a LocalDateTime in the
+// given time zone, converting between zones when needed
+func localIn(p *ical.Prop, timeZone string, dateOnly bool) (string, bool) {
+	if p == nil {
+		return "", false
+	}
+	local, tz, isDate, ok := jsDateTime(p)
+	if !ok || isDate != dateOnly {
+		return "", false
+	}
+	if isDate || tz == timeZone {
+		return local, true
+	}
+	if tz == "" || timeZone == "" {
+		return "", false // floating times have no instant to convert
+	}
+	t, err := parseLocal(local, tz)
+	if err != nil {
+		return "", false
+	}
+	loc, err := loadZone(timeZone)
+	if err != nil {
+		return "", false
+	}
+	return t.In(loc).Format("2006-01-02T15:04:05"), true
+}
+
+func loadZone(timeZone string) (*time.Location, error) {
+	if timeZone == "" || timeZone == utcTimeZone {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(timeZone)
+}
+
+func parseLocal(local, timeZone string) (time.Time, error) {
+	loc, err := loadZone(timeZone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.ParseInLocation("2006-01-02T15:04:05", local, loc)
+}
+
+// eventDuration turns DTEND or DURATION into a JSCalendar duration
+func eventDuration(p *ical.Prop, start time.Time, dateOnly bool, timeZone string) (string, bool) {
+	if p.Name == ical.PropDuration {
+		if len(p.Params) != 0 {
+			return "", false
+		}
+		if _, _, err := parseDuration(p.Value); err != nil {
+			return "", false
+		}
+		return p.Value, true
+	}
+	local, tz, isDate, ok := jsDateTime(p)
+	if !ok || isDate != dateOnly || (tz == "") != (timeZone == "") {
+		return "", false
+	}
+	end, err := parseLocal(local, tz)
+	if err != nil || end.Before(start) {
+		return "", false
+	}
+	if loc, err := loadZone(timeZone); err == nil {
+		end = end.In(loc)
+	}
+	return formatDuration(start, end), true
+}
+
+// formatDuration expresses end - start as nominal days plus exact time, so
+// adding it back in the start's time zone gives the same end across DST
+// changes
+func formatDuration(start, end time.Time) string {
+	days := int(end.Sub(start).Hours() / 24)
+	for days > 0 && start.AddDate(0, 0, days).After(end) {
+		days--
+	}
+	for !start.AddDate(0, 0, days+1).After(end) {
+		days++
+	}
+	rest := end.Sub(start.AddDate(0, 0, days))
+
+	var sb strings.Builder
+	sb.WriteString("P")
+	if days > 0 {
+		fmt.Fprintf(&sb, "%dD", days)
+	}
+	if rest > 0 || days == 0 {
+		sb.WriteString("T")
+		h, m, s := int(rest.Hours()), int(rest.Minutes())%60, int(rest.Seconds())%60
+		if h > 0 {
+			fmt.Fprintf(&sb, "%dH", h)
+		}
+		if m > 0 {
+			fmt.Fprintf(&sb, "%dM", m)
+		}
+		if s > 0 || (h == 0 && m == 0) {
+			fmt.Fprintf(&sb, "%dS", s)
+		}
+	}
+	return sb.String()
+}
+
+// parseDuration splits a non-negative ISO 8601 duration into nominal days
+// and exact time
+func parseDuration(value string) (days int, exact time.Duration, err error) {
+	s := strings.TrimPrefix(value, "+")
+	if !strings.HasPrefix(s, "P") || len(s) < 3 {
+		return 0, 0, fmt.Errorf("invalid duration %q", value)
+	}
+	s = s[1:]
+	inTime := false
+	num := ""
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			num += string(r)
+		case r == 'T' && !inTime && num == "":
+			inTime = true
+		default:
+			n, convErr := strconv.Atoi(num)
+			if convErr != nil {
+				return 0, 0, fmt.Errorf("invalid duration %q", value)
+			}
+			num = ""
+			switch {
+			case !inTime && r == 'W':
+				days += 7 * n
+			case !inTime && r == 'D':
+				days += n
+			case inTime && r == 'H':
+				exact += time.Duration(n) * time.Hour
+			case inTime && r == 'M':
+				exact += time.Duration(n) * time.Minute
+			case inTime && r == 'S':
+				exact += time.Duration(n) * time.Second
+			default:
+				return 0, 0, fmt.Errorf("invalid duration %q", value)
+			}
+		}
+	}
+	if num != "" {
+		return 0, 0, fmt.Errorf("invalid duration %q", value)
+	}
+	return days, exact, nil
+}
+
+func plainProps(props []ical.Prop) bool {
+	for _, p := range props {
+		if len(p.Params) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// residualParams returns the parameters left after mapping in jCal form
+func residualParams(params ical.Params) map[string]interface{} {
+	if len(params) == 0 {
+		return nil
+	}
+	return jcalParams(params)
+}
+
+// jsToComponents converts an Event or Task, with its exceptions, back into
+// iCalendar components
+func jsToComponents(obj *JSCalendarObject) ([]*ical.Component, error) {
+	name := ical.CompEvent
+	switch obj.Type {
+	case "Event":
+	case "Task":
+		name = ical.CompToDo
+	default:
+		return nil, fmt.Errorf("%w: @type must be Event, Task or Group", ErrInvalidJSCalendar)
+	}
+	if obj.UID == "" {
+		return nil, fmt.Errorf("%w: uid is required", ErrInvalidJSCalendar)
+	}
+
+	master, err := jsToComponent(name, obj, obj)
+	if err != nil {
+		return nil, err
+	}
+	comps := []*ical.Component{master}
+
+	var excluded []string
+	for _, key := range sortedKeys(obj.RecurrenceOverrides) {
+		override := obj.RecurrenceOverrides[key]
+		if override == nil {
+			continue
+		}
+		if override.Excluded {
+			excluded = append(excluded, key)
+			continue
+		}
+		override.UID = obj.UID
+		override.RecurrenceID = key
+		comp, err := jsToComponent(name, override, obj)
+		if err != nil {
+			return nil, err
+		}
+		comps = append(comps, comp)
+	}
+	if len(excluded) > 0 {
+		exdate := icalDateTime(ical.PropExceptionDates, excluded[0], obj.TimeZone, obj.ShowWithoutTime)
+		for _, key := range excluded[1:] {
+			exdate.Value += "," + icalDateTime(ical.PropExceptionDates, key, obj.TimeZone, obj.ShowWithoutTime).Value
+		}
+		master.Props.Add(exdate)
+	}
+	return comps, nil
+}
+
+// jsToComponent builds one component. master is the object the component
+// belongs to; its time zone applies to the recurrence id of an exception.
+func jsToComponent(name string, obj, master *JSCalendarObject) (*ical.Component, error) {
+	comp := ical.NewComponent(name)
+	setText := func(prop, value string) {
+		if value != "" {
+			p := ical.NewProp(prop)
+			p.Value = escapeText(value)
+			comp.Props.Set(p)
+		}
+	}
+	setRaw := func(prop, value string) {
+		if value != "" {
+			p := ical.NewProp(prop)
+			p.Value = value
+			comp.Props.Set(p)
+		}
+	}
+	reverse := func(m map[string]string, v string) string {
+		for k, js := range m {
+			if js == v {
+				return k
+			}
+		}
+		return strings.ToUpper(v)
+	}
+
+	for _, raw := range obj.ICalProps {
+		p, err := parseJCalProperty(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: iCalProps: %v", ErrInvalidJSCalendar, err)
+		}
+		comp.Props.Add(p)
+	}
+
+	setText(ical.PropUID, obj.UID)
+	setText(ical.PropSummary, obj.Title)
+	setText(ical.PropDescription, obj.Description)
+	setText(ical.PropColor, obj.Color)
+	setRaw(ical.PropCreated, basicDateTime(obj.Created))
+	setRaw(ical.PropLastModified, basicDateTime(obj.Updated))
+	if obj.Sequence > 0 {
+		setRaw(ical.PropSequence, strconv.Itoa(obj.Sequence))
+	}
+	if obj.Priority > 0 {
+		setRaw(ical.PropPriority, strconv.Itoa(obj.Priority))
+	}
+	if obj.PercentComplete > 0 {
+		setRaw(ical.PropPercentComplete, strconv.Itoa(obj.PercentComplete))
+	}
+	if obj.Status != "" {
+		setRaw(ical.PropStatus, reverse(eventStatuses, obj.Status))
+	}
+	if obj.Progress != "" {
+		setRaw(ical.PropStatus, reverse(taskProgress, obj.Progress))
+	}
+	if obj.Privacy != "" {
+		setRaw(ical.PropClass, reverse(privacyClasses, obj.Privacy))
+	}
+	if obj.FreeBusyStatus != "" {
+		setRaw(ical.PropTransparency, reverse(freeBusyStatus, obj.FreeBusyStatus))
+	}
+	if len(obj.Keywords) > 0 {
+		var keywords []string
+		for _, k := range sortedKeys(obj.Keywords) {
+			if obj.Keywords[k] {
+				keywords = append(keywords, escapeText(k))
+			}
+		}
+		setRaw(ical.PropCategories, strings.Join(keywords, ","))
+	}
+
+	timeZone, dateOnly := obj.TimeZone, obj.ShowWithoutTime
+	if obj != master && obj.Start == "" {
+		timeZone, dateOnly = master.TimeZone, master.ShowWithoutTime
+	}
+	if obj.RecurrenceID != "" {
+		comp.Props.Set(icalDateTime(ical.PropRecurrenceID, obj.RecurrenceID, master.TimeZone, master.ShowWithoutTime))
+	}
+	if obj.Start != "" {
+		if _, err := time.Parse("2006-01-02T15:04:05", obj.Start); err != nil {
+			return nil, fmt.Errorf("%w: start must be a LocalDateTime", ErrInvalidJSCalendar)
+		}
+		comp.Props.Set(icalDateTime(ical.PropDateTimeStart, obj.Start, timeZone, dateOnly))
+	}
+	if obj.Due != "" {
+		comp.Props.Set(icalDateTime(ical.PropDue, obj.Due, timeZone, dateOnly))
+	}
+	if obj.Duration != "" {
+		days, exact, err := parseDuration(obj.Duration)
+		if err != nil || obj.Start == "" {
+			return nil, fmt.Errorf("%w: duration needs a start and an ISO 8601 duration", ErrInvalidJSCalendar)
+		}
+		start, err := parseLocal(obj.Start, timeZone)
+		if name == ical.CompEvent && err == nil {
+			end := start.AddDate(0, 0, days).Add(exact).Format("2006-01-02T15:04:05")
+			comp.Props.Set(icalDateTime(ical.PropDateTimeEnd, end, timeZone, dateOnly))
+		} else {
+			setRaw(ical.PropDuration, obj.Duration)
+		}
+	}
+
+	for _, rule := range obj.RecurrenceRules {
+		value, err := rruleValue(rule, timeZone, dateOnly)
+		if err != nil {
+			return nil, err
+		}
+		setRaw(ical.PropRecurrenceRule, value)
+	}
+
+	for _, id := range sortedIDs(obj.Locations) {
+		loc := obj.Locations[id]
+		if loc.Name != "" {
+			p := ical.NewProp(ical.PropLocation)
+			p.Value = escapeText(loc.Name)
+			if err := applyParams(p, loc.ICalParams); err != nil {
+				return nil, err
+			}
+			comp.Props.Set(p)
+		}
+		if geo := strings.TrimPrefix(loc.Coordinates, "geo:"); geo != loc.Coordinates {
+			lat, lon, _ := strings.Cut(strings.SplitN(geo, ";", 2)[0], ",")
+			setRaw(ical.PropGeo, lat+";"+lon)
+		}
+	}
+	for _, id := range sortedIDs(obj.Links) {
+		p, err := linkProp(obj.Links[id])
+		if err != nil {
+			return nil, err
+		}
+		comp.Props.Add(p)
+	}
+	for _, id := range sortedIDs(obj.Participants) {
+		props, err := participantProps(obj.Participants[id], obj.ReplyTo)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range props {
+			comp.Props.Add(p)
+		}
+	}
+	for _, id := range sortedIDs(obj.Alerts) {
+		alarm, err := alertComponent(obj.Alerts[id])
+		if err != nil {
+			return nil, err
+		}
+		comp.Children = append(comp.Children, alarm)
+	}
+
+	// DTSTAMP is required; objects created through JSCalendar get the
+	// current time
+	if comp.Props.Get(ical.PropDateTimeStamp) == nil {
+		comp.Props.SetDateTime(ical.PropDateTimeStamp, time.Now().UTC())
+	}
+	return comp, nil
+}
+
+func rruleValue(rule *JSCalendarRecurrenceRule, timeZone string, dateOnly bool) (string, error) {
+	if rule == nil || rule.Frequency == "" {
+		return "", fmt.Errorf("%w: recurrence rules need a frequency", ErrInvalidJSCalendar)
+	}
+	parts := []string{"FREQ=" + strings.ToUpper(rule.Frequency)}
+	add := func(key string, values []string) {
+		if len(values) > 0 {
+			parts = append(parts, key+"="+strings.Join(values, ","))
+		}
+	}
+	ints := func(values []int) []string {
+		out := make([]string, len(values))
+		for i, v := range values {
+			out[i] = strconv.Itoa(v)
+		}
+		return out
+	}
+
+	if rule.Until != "" {
+		until := icalDateTime("UNTIL", rule.Until, timeZone, dateOnly)
+		value := until.Value
+		if timeZone != "" && timeZone != utcTimeZone && !dateOnly {
+			// UNTIL must be in UTC when the start has a time zone
+			if t, err := parseLocal(rule.Until, timeZone); err == nil {
+				value = t.UTC().Format("20060102T150405Z")
+			}
+		}
+		add("UNTIL", []string{value})
+	}
+	if rule.Count > 0 {
+		add("COUNT", []string{strconv.Itoa(rule.Count)})
+	}
+	if rule.Interval > 0 {
+		add("INTERVAL", []string{strconv.Itoa(rule.Interval)})
+	}
+	add("BYSECOND", ints(rule.BySecond))
+	add("BYMINUTE", ints(rule.ByMinute))
+	add("BYHOUR", ints(rule.ByHour))
+	var days []string
+	for _, d := range rule.ByDay {
+		day := strings.ToUpper(d.Day)
+		if d.NthOfPeriod != 0 {
+			day = strconv.Itoa(d.NthOfPeriod) + day
+		}
+		days = append(days, day)
+	}
+	add("BYDAY", days)
+	add("BYMONTHDAY", ints(rule.ByMonthDay))
+	add("BYYEARDAY", ints(rule.ByYearDay))
+	add("BYWEEKNO", ints(rule.ByWeekNo))
+	add("BYMONTH", rule.ByMonth)
+	add("BYSETPOS", ints(rule.BySetPosition))
+	if rule.FirstDayOfWeek != "" {
+		add("WKST", []string{strings.ToUpper(rule.FirstDayOfWeek)})
+	}
+	if rule.Rscale != "" {
+		add("RSCALE", []string{strings.ToUpper(rule.Rscale)})
+	}
+	if rule.Skip != "" {
+		add("SKIP", []string{strings.ToUpper(rule.Skip)})
+	}
+	return strings.Join(parts, ";"), nil
+}
+
+func linkProp(link *JSCalendarEntry) (*ical.Prop, error) {
+	p := ical.NewProp(ical.PropAttach)
+	p.Value = link.Href
+	if err := applyParams(p, link.ICalParams); err != nil {
+		return nil, err
+	}
+	if link.ContentType != "" {
+		p.Params.Set(ical.ParamFormatType, link.ContentType)
+	}
+	if link.Size > 0 {
+		p.Params.Set("SIZE", strconv.Itoa(link.Size))
+	}
+	if strings.HasPrefix(link.Href, "data:") {
+		header, data, ok := strings.Cut(link.Href[len("data:"):], ",")
+		if ok && strings.HasSuffix(header, ";base64") {
+			p.Value = data
+			p.Params.Set(ical.ParamValue, "BINARY")
+			p.Params.Set(ical.ParamEncoding, "BASE64")
+		}
+	}
+	return p, nil
+}
+
+// participantProps returns the ORGANIZER and/or ATTENDEE of a participant
+func participantProps(part *JSCalendarEntry, replyTo map[string]string) ([]*ical.Prop, error) {
+	address := part.SendTo["imip"]
+	if address == "" && part.Email != "" {
+		address = "mailto:" + part.Email
+	}
+	if address == "" {
+		return nil, fmt.Errorf("%w: participants need sendTo.imip or an email", ErrInvalidJSCalendar)
+	}
+	newProp := func(name string) (*ical.Prop, error) {
+		p := ical.NewProp(name)
+		p.Value = address
+		if err := applyParams(p, part.ICalParams); err != nil {
+			return nil, err
+		}
+		if part.Name != "" {
+			p.Params.Set(ical.ParamCommonName, part.Name)
+		}
+		if part.Email != "" && !strings.EqualFold(address, "mailto:"+part.Email) {
+			p.Params.Set(ical.ParamEmail, part.Email)
+		}
+		return p, nil
+	}
+
+	var props []*ical.Prop
+	if part.Roles["owner"] || (len(part.Roles) == 0 && replyTo["imip"] == address) {
+		p, err := newProp(ical.PropOrganizer)
+		if err != nil {
+			return nil, err
+		}
+		props = append(props, p)
+	}
+	if part.Roles["attendee"] || part.Roles["informational"] || part.Roles["chair"] || part.Roles["optional"] ||
+		(len(part.Roles) == 0 && len(props) == 0) {
+		p, err := newProp(ical.PropAttendee)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case part.Roles["chair"]:
+			p.Params.Set(ical.ParamRole, "CHAIR")
+		case part.Roles["optional"]:
+			p.Params.Set(ical.ParamRole, "OPT-PARTICIPANT")
+		case part.Roles["informational"]:
+			p.Params.Set(ical.ParamRole, "NON-PARTICIPANT")
+		}
+		if part.Kind != "" {
+			p.Params.Set(ical.ParamCalendarUserType, strings.ToUpper(part.Kind))
+		}
+		if part.ParticipationStatus != "" {
+			p.Params.Set(ical.ParamParticipationStatus, strings.ToUpper(part.ParticipationStatus))
+		}
+		if part.ExpectReply {
+			p.Params.Set(ical.ParamRSVP, "TRUE")
+		}
+		props = append(props, p)
+	}
+	return props, nil
+}
+
+func alertComponent(alert *JSCalendarEntry) (*ical.Component, error) {
+	comp := ical.NewComponent(ical.CompAlarm)
+	for _, raw := range alert.ICalProps {
+		p, err := parseJCalProperty(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: iCalProps: %v", ErrInvalidJSCalendar, err)
+		}
+		comp.Props.Add(p)
+	}
+	if alert.Action != "" {
+		p := ical.NewProp(ical.PropAction)
+		p.Value = strings.ToUpper(alert.Action)
+		comp.Props.Set(p)
+	}
+	if alert.Acknowledged != "" {
+		p := ical.NewProp("ACKNOWLEDGED")
+		p.Value = basicDateTime(alert.Acknowledged)
+		comp.Props.Set(p)
+	}
+	if t := alert.Trigger; t != nil {
+		p := ical.NewProp(ical.PropTrigger)
+		if err := applyParams(p, alert.ICalParams); err != nil {
+			return nil, err
+		}
+		switch t.Type {
+		case "AbsoluteTrigger":
+			p.Params.Set(ical.ParamValue, "DATE-TIME")
+			p.Value = basicDateTime(t.When)
+		case "OffsetTrigger":
+			if t.RelativeTo == "end" {
+				p.Params.Set(ical.ParamRelated, "END")
+			}
+			p.Value = t.Offset
+		default:
+			return nil, fmt.Errorf("%w: unknown trigger type %q", ErrInvalidJSCalendar, t.Type)
+		}
+		comp.Props.Set(p)
+	}
+	if comp.Props.Get(ical.PropAction) == nil {
+		p := ical.NewProp(ical.PropAction)
+		p.Value = "DISPLAY"
+		comp.Props.Set(p)
+	}
+	return comp, nil
+}
+
+func applyParams(p *ical.Prop, raw map[string]interface{}) error {
+	for k, v := range raw {
+		key := strings.ToUpper(k)
+		switch val := v.(type) {
+		case []interface{}:
+			for _, item := range val {
+				p.Params.Add(key, jsonScalar(item))
+			}
+		case map[string]interface{}:
+			return fmt.Errorf("%w: parameter %s must be a string or an array", ErrInvalidJSCalendar, k)
+		default:
+			p.Params.Add(key, jsonScalar(val))
+		}
+	}
+	return nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sortedIDs orders entry ids naturally, so p2 comes before p10
+func sortedIDs(m map[string]*JSCalendarEntry) []string {
+	ids := sortedKeys(m)
+	sort.SliceStable(ids, func(i, j int) bool { return len(ids[i]) < len(ids[j]) })
+	return ids
+}
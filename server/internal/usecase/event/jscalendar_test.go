@@ -0,0 +1,165 @@
+package event
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/emersion/go-ical"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToJSCalendar(t *testing.T) {
+	obj := ToJSCalendar(parseCalendar(t, richCalendar))
+
+	assert.Equal(t, "Event", obj.Type)
+	assert.Equal(t, "rich-1", obj.UID)
+	assert.Equal(t, "-//Example//Test//EN", obj.ProdID)
+	assert.Equal(t, "Team sync, weekly", obj.Title)
+	assert.Equal(t, "Agenda:\nStatus", obj.Description)
+	assert.Equal(t, "2024-01-08T10:00:00", obj.Start)
+	assert.Equal(t, "Europe/Berlin", obj.TimeZone)
+	assert.Equal(t, "PT1H30M", obj.Duration)
+	assert.Equal(t, "2023-12-31T12:00:00Z", obj.Created)
+	assert.Equal(t, 2, obj.Sequence)
+	assert.Equal(t, 5, obj.Priority)
+	assert.Equal(t, "confirmed", obj.Status)
+	assert.Equal(t, "secret", obj.Privacy)
+	assert.Equal(t, "busy", obj.FreeBusyStatus)
+	assert.Equal(t, map[string]bool{"work": true, "meeting": true}, obj.Keywords)
+
+	require.Contains(t, obj.Locations, "l1")
+	assert.Equal(t, "Room 1", obj.Locations["l1"].Name)
+	assert.Equal(t, "geo:52.52,13.405", obj.Locations["l1"].Coordinates)
+	assert.Equal(t, map[string]interface{}{"language": "en"}, obj.Locations["l1"].ICalParams)
+
+	require.Len(t, obj.Links, 2)
+	hrefs := []string{obj.Links["k1"].Href, obj.Links["k2"].Href}
+	assert.ElementsMatch(t, []string{"https://example.com/agenda.txt", "data:text/plain;base64,aGVsbG8="}, hrefs)
+
+	require.Len(t, obj.RecurrenceRules, 1)
+	rule := obj.RecurrenceRules[0]
+	assert.Equal(t, "weekly", rule.Frequency)
+	assert.Equal(t, []JSCalendarNDay{{Type: "NDay", Day: "mo"}}, rule.ByDay)
+	assert.Equal(t, "2024-02-26T10:00:00", rule.Until)
+
+	require.Len(t, obj.RecurrenceOverrides, 2)
+	assert.True(t, obj.RecurrenceOverrides["2024-01-15T10:00:00"].Excluded)
+	moved := obj.RecurrenceOverrides["2024-01-22T10:00:00"]
+	require.NotNil(t, moved)
+	assert.Equal(t, "2024-01-22T14:00:00", moved.Start)
+	assert.Equal(t, "PT1H", moved.Duration)
+	assert.Equal(t, "Team sync (moved)", moved.Title)
+
+	require.Len(t, obj.Participants, 2)
+	var organizer, attendee *JSCalendarEntry
+	for _, p := range obj.Participants {
+		if p.Roles["owner"] {
+			organizer = p
+		} else {
+			attendee = p
+		}
+	}
+	require.NotNil(t, organizer)
+	require.NotNil(t, attendee)
+	assert.Equal(t, "Alice", organizer.Name)
+	assert.Equal(t, map[string]string{"imip": "mailto:alice@example.com"}, obj.ReplyTo)
+	assert.Equal(t, "bob@example.com", attendee.Email)
+	assert.Equal(t, map[string]bool{"attendee": true, "optional": true}, attendee.Roles)
+	assert.Equal(t, "accepted", attendee.ParticipationStatus)
+	assert.True(t, attendee.ExpectReply)
+	assert.Equal(t, map[string]interface{}{"x-foo": "bar"}, attendee.ICalParams)
+
+	require.Contains(t, obj.Alerts, "a1")
+	alert := obj.Alerts["a1"]
+	assert.Equal(t, "display", alert.Action)
+	assert.Equal(t, &JSCalendarTrigger{Type: "OffsetTrigger", Offset: "-PT15M", RelativeTo: "start"}, alert.Trigger)
+
+	require.Len(t, obj.ICalComponents, 1)
+	assert.Equal(t, "vtimezone", obj.ICalComponents[0][0])
+}
+
+func TestJSCalendar_RoundTrip(t *testing.T) {
+	cal := parseCalendar(t, richCalendar)
+
+	out, err := json.Marshal(ToJSCalendar(cal))
+	require.NoError(t, err)
+
+	back, err := FromJSCalendar(out)
+	require.NoError(t, err)
+
+	// Keywords are a set, so categories may come back in another order, and
+	// events are written before the kept components
+	require.Len(t, back.Children, 3)
+	assert.ElementsMatch(t, []string{"work", "meeting"}, splitUnescaped(back.Children[0].Props.Get(ical.PropCategories).Value, ','))
+	back.Children[0].Props.Get(ical.PropCategories).Value = "work,meeting"
+	assert.Equal(t, cal.Children[1].Props, back.Children[0].Props)
+	assert.Equal(t, cal.Children[1].Children, back.Children[0].Children)
+	assert.Equal(t, cal.Children[2].Props, back.Children[1].Props)
+	assert.Equal(t, cal.Children[0], back.Children[2])
+	assert.Equal(t, cal.Props.Get(ical.PropProductID), back.Props.Get(ical.PropProductID))
+}
+
+func TestJSCalendar_AllDayTask(t *testing.T) {
+	data := []byte(`{
+		"@type": "Task",
+		"uid": "task-1",
+		"title": "File taxes",
+		"start": "2024-04-01T00:00:00",
+		"due": "2024-04-15T00:00:00",
+		"showWithoutTime": true,
+		"progress": "in-process",
+		"percentComplete": 40
+	}`)
+
+	cal, err := FromJSCalendar(data)
+	require.NoError(t, err)
+	require.Len(t, cal.Children, 1)
+	todo := cal.Children[0]
+	assert.Equal(t, ical.CompToDo, todo.Name)
+	assert.Equal(t, "20240401", todo.Props.Get(ical.PropDateTimeStart).Value)
+	assert.Equal(t, "DATE", todo.Props.Get(ical.PropDue).Params.Get(ical.ParamValue))
+	assert.Equal(t, "IN-PROCESS", todo.Props.Get(ical.PropStatus).Value)
+	assert.NotNil(t, todo.Props.Get(ical.PropDateTimeStamp))
+
+	obj := ToJSCalendar(cal)
+	assert.Equal(t, "Task", obj.Type)
+	assert.True(t, obj.ShowWithoutTime)
+	assert.Equal(t, "2024-04-15T00:00:00", obj.Due)
+	assert.Equal(t, 40, obj.PercentComplete)
+}
+
+func TestFromJSCalendar_Group(t *testing.T) {
+	data := []byte(`{
+		"@type": "Group",
+		"entries": [
+			{"@type": "Event", "uid": "a", "title": "A", "start": "2024-01-01T09:00:00", "timeZone": "Etc/UTC", "duration": "P1DT2H"},
+			{"@type": "Event", "uid": "b", "title": "B", "start": "2024-01-02T09:00:00"}
+		]
+	}`)
+
+	cal, err := FromJSCalendar(data)
+	require.NoError(t, err)
+	require.Len(t, cal.Children, 2)
+	assert.Equal(t, "20240101T090000Z", cal.Children[0].Props.Get(ical.PropDateTimeStart).Value)
+	assert.Equal(t, "20240102T110000Z", cal.Children[0].Props.Get(ical.PropDateTimeEnd).Value)
+	assert.Equal(t, "20240102T090000", cal.Children[1].Props.Get(ical.PropDateTimeStart).Value)
+
+	obj := ToJSCalendar(cal)
+	assert.Equal(t, "Group", obj.Type)
+	assert.Len(t, obj.Entries, 2)
+}
+
+func TestFromJSCalendar_Invalid(t *testing.T) {
+	for _, data := range []string{
+		`[]`,
+		`{"@type": "Event"}`,
+		`{"@type": "Note", "uid": "x"}`,
+		`{"@type": "Event", "uid": "x", "start": "tomorrow"}`,
+		`{"@type": "Event", "uid": "x", "start": "2024-01-01T09:00:00", "duration": "1h"}`,
+		`{"@type": "Event", "uid": "x", "iCalProps": [["x-a", {}]]}`,
+	} {
+		_, err := FromJSCalendar([]byte(data))
+		assert.ErrorIs(t, err, ErrInvalidJSCalendar, data)
+	}
+}
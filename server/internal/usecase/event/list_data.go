@@ -0,0 +1,60 @@
+package event
+
+import (
+	"context"
+
+	"github.com/emersion/go-ical"
+	"github.com/jherrma/caldav-server/internal/domain/calendar"
+)
+
+// ListEventDataUseCase merges the events of a time range into one
+// iCalendar, for the jCal and JSCalendar representations of an event list
+type ListEventDataUseCase struct {
+	calendarRepo calendar.CalendarRepository
+}
+
+func NewListEventDataUseCase(calendarRepo calendar.CalendarRepository) *ListEventDataUseCase {
+	return &ListEventDataUseCase{calendarRepo: calendarRepo}
+}
+
+// Execute ignores input.Expand; recurring events are returned as their
+// master and exceptions
+func (uc *ListEventDataUseCase) Execute(ctx context.Context, input ListEventsInput) (*ical.Calendar, error) {
+	objects, err := uc.calendarRepo.ListEvents(ctx, input.CalendarID, input.Start, input.End)
+	if err != nil {
+		return nil, err
+	}
+
+	cals := make([]*ical.Calendar, 0, len(objects))
+	for _, obj := range objects {
+		cal, err := ParseICalData(obj.ICalData)
+		if err != nil {
+			return nil, err
+		}
+		cals = append(cals, cal)
+	}
+	return MergeCalendars(cals), nil
+}
+
+// MergeCalendars combines the components of several calendars into a new
+// one. Time zones shared by several objects are included once.
+func MergeCalendars(cals []*ical.Calendar) *ical.Calendar {
+	merged := ical.NewCalendar()
+	merged.Props.SetText(ical.PropProductID, "-//CalCard//EN")
+	merged.Props.SetText(ical.PropVersion, "2.0")
+
+	timezones := make(map[string]bool)
+	for _, cal := range cals {
+		for _, comp := range cal.Children {
+			if comp.Name == ical.CompTimezone {
+				tzid := comp.Props.Get(ical.PropTimezoneID)
+				if tzid == nil || timezones[tzid.Value] {
+					continue
+				}
+				timezones[tzid.Value] = true
+			}
+			merged.Children = append(merged.Children, comp)
+		}
+	}
+	return merged
+}
@@ -0,0 +1,42 @@
+package event
+
+import (
+	"context"
+
+	"github.com/emersion/go-ical"
+	"github.com/jherrma/caldav-server/internal/domain/calendar"
+)
+
+// ReplaceEventDataUseCase overwrites an event with a complete iCalendar, as
+// decoded from a jCal or JSCalendar request body
+type ReplaceEventDataUseCase struct {
+	calendarRepo calendar.CalendarRepository
+}
+
+func NewReplaceEventDataUseCase(calendarRepo calendar.CalendarRepository) *ReplaceEventDataUseCase {
+	return &ReplaceEventDataUseCase{calendarRepo: calendarRepo}
+}
+
+// Execute replaces every component of the stored event. The UID is kept so
+// CalDAV clients still see the same object.
+func (uc *ReplaceEventDataUseCase) Execute(ctx context.Context, uuid string, cal *ical.Calendar) (*calendar.CalendarObject, error) {
+	obj, err := uc.calendarRepo.GetCalendarObjectByUUID(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+	for _, comp := range cal.Children {
+		if comp.Name == ical.CompEvent || comp.Name == ical.CompToDo {
+			uid := ical.NewProp(ical.PropUID)
+			uid.Value = obj.UID
+			comp.Props.Set(uid)
+		}
+	}
+	if err := storeCalendar(obj, cal); err != nil {
+		return nil, err
+	}
+
+	if err := uc.calendarRepo.UpdateCalendarObject(ctx, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
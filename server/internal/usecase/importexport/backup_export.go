@@ -27,8 +27,9 @@ func NewBackupExportUseCase(calendarRepo calendar.CalendarRepository, addressBoo
 	}
 }
 
-// Execute generates a ZIP backup of all user data
-func (uc *BackupExportUseCase) Execute(ctx context.Context, userID uint) ([]byte, string, error) {
+// Execute generates a ZIP backup of all user data. A non-empty vcardVersion
+// converts every contact to vCard 3.0 or 4.0.
+func (uc *BackupExportUseCase) Execute(ctx context.Context, userID uint, vcardVersion string) ([]byte, string, error) {
 	buf := new(bytes.Buffer)
 	zipWriter := zip.NewWriter(buf)
 
@@ -82,8 +83,14 @@ func (uc *BackupExportUseCase) Execute(ctx context.Context, userID uint) ([]byte
 		// Build vCard content
 		var vcardContent strings.Builder
 		for _, obj := range objects {
-			vcardContent.WriteString(obj.VCardData)
-			if !strings.HasSuffix(obj.VCardData, "\n") {
+			data := obj.VCardData
+			if vcardVersion != "" {
+				if data, err = addressbook.ConvertVCardData(data, vcardVersion); err != nil {
+					return nil, "", fmt.Errorf("failed to convert contact %s: %w", obj.UID, err)
+				}
+			}
+			vcardContent.WriteString(data)
+			if !strings.HasSuffix(data, "\n") {
 				vcardContent.WriteString("\r\n")
 			}
 		}
@@ -32,6 +32,9 @@ func (uc *CalendarImportUseCase) Execute(ctx context.Context, userID uint, calen
 	if cal.UserID != userID {
 		return nil, fmt.Errorf("access denied")
 	}
+	if cal.ReadOnly {
+		return nil, fmt.Errorf("subscribed calendars are read-only")
+	}
 
 	// Default options
 	if opts.DuplicateHandling == "" {
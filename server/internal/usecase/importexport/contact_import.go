@@ -1,16 +1,19 @@
 package importexport
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"strings"
 	"time"
 
+	"github.com/emersion/go-vcard"
 	"github.com/google/uuid"
 	"github.com/jherrma/caldav-server/internal/domain/addressbook"
+	contactuc "github.com/jherrma/caldav-server/internal/usecase/contact"
 )
 
-// ContactImportUseCase handles contact import from vCard format
+// ContactImportUseCase handles contact import from vCard or CSV data
 type ContactImportUseCase struct {
 	addressBookRepo addressbook.Repository
 }
@@ -20,7 +23,9 @@ func NewContactImportUseCase(addressBookRepo addressbook.Repository) *ContactImp
 	return &ContactImportUseCase{addressBookRepo: addressBookRepo}
 }
 
-// Execute imports contacts from vCard data
+// Execute imports contacts from vCard or CSV data. CSV rows are converted to
+// vCard 3.0 using opts.CSVMapping; rows that cannot be converted are
+// reported as failed.
 func (uc *ContactImportUseCase) Execute(ctx context.Context, userID uint, addressBookID uint, data []byte, opts ImportOptions) (*ImportResult, error) {
 	// Get address book and verify ownership
 	ab, err := uc.addressBookRepo.GetByID(ctx, addressBookID)
@@ -36,14 +41,32 @@ func (uc *ContactImportUseCase) Execute(ctx context.Context, userID uint, addres
 		opts.DuplicateHandling = "skip"
 	}
 
-	// Split vCard data into individual cards
-	vcards := splitVCards(string(data))
+	// Split the data into individual cards
+	var vcards []string
+	var rowErrors map[int]error
+	switch opts.Format {
+	case FormatCSV:
+		if vcards, rowErrors, err = csvToVCards(data, opts.CSVMapping); err != nil {
+			return nil, err
+		}
+	case "", FormatVCard:
+		vcards = splitVCards(string(data))
+	default:
+		return nil, fmt.Errorf("unsupported import format %q", opts.Format)
+	}
 
 	result := &ImportResult{
-		Total: len(vcards),
+		Total:  len(vcards),
+		DryRun: opts.DryRun,
 	}
 
 	for i, vcardData := range vcards {
+		if err := rowErrors[i]; err != nil {
+			result.Failed++
+			result.Errors = append(result.Errors, ImportError{Index: i, Error: err.Error()})
+			continue
+		}
+
 		vcardData = strings.TrimSpace(vcardData)
 		if vcardData == "" {
 			continue
@@ -72,6 +95,9 @@ func (uc *ContactImportUseCase) Execute(ctx context.Context, userID uint, addres
 				result.Skipped++
 				continue
 			case "replace":
+				if opts.DryRun {
+					break
+				}
 				// Delete existing object
 				if err := uc.addressBookRepo.DeleteObjectByUUID(ctx, uid); err != nil {
 					result.Failed++
@@ -90,6 +116,23 @@ func (uc *ContactImportUseCase) Execute(ctx context.Context, userID uint, addres
 			}
 		}
 
+		if opts.DryRun {
+			preview, err := contactuc.ToContact(vcardData)
+			if err != nil {
+				result.Failed++
+				result.Errors = append(result.Errors, ImportError{
+					Index:   i,
+					UID:     uid,
+					Summary: fn,
+					Error:   err.Error(),
+				})
+				continue
+			}
+			result.Imported++
+			result.Contacts = append(result.Contacts, preview)
+			continue
+		}
+
 		// Create address object. The internal DB UUID must be unique and
 		// non-empty (the column has a unique index and NOT NULL) — otherwise
 		// the second row in a multi-contact import collides on uuid="".
@@ -122,6 +165,10 @@ func (uc *ContactImportUseCase) Execute(ctx context.Context, userID uint, addres
 		result.Imported++
 	}
 
+	if opts.DryRun {
+		return result, nil
+	}
+
 	// Update address book CTag
 	ab.CTag = fmt.Sprintf("ctag-%d", time.Now().UnixNano())
 	_ = uc.addressBookRepo.Update(ctx, ab)
@@ -129,6 +176,34 @@ func (uc *ContactImportUseCase) Execute(ctx context.Context, userID uint, addres
 	return result, nil
 }
 
+// csvToVCards converts CSV rows into vCard data. The returned errors are
+// keyed by row and leave an empty card in their place.
+func csvToVCards(data []byte, mapping *addressbook.CSVMapping) ([]string, map[int]error, error) {
+	if mapping == nil {
+		mapping = &addressbook.GoogleCSVMapping
+	}
+	rows, err := addressbook.ParseCSV(bytes.NewReader(data), *mapping)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	vcards := make([]string, len(rows))
+	rowErrors := make(map[int]error)
+	for i, row := range rows {
+		if row.Err != nil {
+			rowErrors[i] = row.Err
+			continue
+		}
+		var buf bytes.Buffer
+		if err := vcard.NewEncoder(&buf).Encode(row.Card); err != nil {
+			rowErrors[i] = err
+			continue
+		}
+		vcards[i] = buf.String()
+	}
+	return vcards, rowErrors, nil
+}
+
 // splitVCards splits a vCard file containing multiple cards into individual cards
 func splitVCards(data string) []string {
 	var cards []string
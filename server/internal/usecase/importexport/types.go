@@ -1,8 +1,24 @@
 package importexport
 
+import (
+	"github.com/jherrma/caldav-server/internal/domain/addressbook"
+	"github.com/jherrma/caldav-server/internal/domain/contact"
+)
+
+// Contact import formats
+const (
+	FormatVCard = "vcard"
+	FormatCSV   = "csv"
+)
+
 // ImportOptions defines options for import operations
 type ImportOptions struct {
 	DuplicateHandling string // "skip", "replace", "duplicate"
+
+	// Contact imports only
+	DryRun     bool                    // Parse and check the data without storing anything
+	Format     string                  // FormatVCard (default) or FormatCSV
+	CSVMapping *addressbook.CSVMapping // Column layout of CSV data, Google when nil
 }
 
 // ImportResult represents the result of an import operation
@@ -12,9 +28,15 @@ type ImportResult struct {
 	Skipped  int           `json:"skipped"`
 	Failed   int           `json:"failed"`
 	Errors   []ImportError `json:"errors,omitempty"`
+
+	// Set for dry runs: the counts above are what an import would do and
+	// Contacts holds the parsed contacts that would be imported
+	DryRun   bool               `json:"dry_run,omitempty"`
+	Contacts []*contact.Contact `json:"contacts,omitempty"`
 }
 
-// ImportError represents an error during import of a single item
+// ImportError represents an error during import of a single item. For CSV
+// data Index is the 0-based data row, not counting the header.
 type ImportError struct {
 	Index   int    `json:"index"`
 	UID     string `json:"uid,omitempty"`
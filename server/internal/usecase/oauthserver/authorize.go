@@ -0,0 +1,178 @@
+package oauthserver
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/jherrma/caldav-server/internal/config"
+	"github.com/jherrma/caldav-server/internal/domain/oauthserver"
+)
+
+// AuthorizationRequest holds the parameters of an authorization request
+// (RFC 6749 §4.1.1 with PKCE, RFC 7636)
+type AuthorizationRequest struct {
+	ResponseType        string
+	ClientID            string
+	RedirectURI         string
+	Scope               string
+	State               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	Nonce               string
+}
+
+// ConsentPrompt is what the consent screen shows the user
+type ConsentPrompt struct {
+	Client       *oauthserver.Client
+	RedirectURI  string
+	Scopes       []string
+	ConsentGiven bool // The user already approved all requested scopes
+}
+
+// AuthorizeUseCase validates authorization requests and issues authorization
+// codes once the signed-in user approves them
+type AuthorizeUseCase struct {
+	clients  oauthserver.ClientRepository
+	codes    oauthserver.AuthorizationCodeRepository
+	consents oauthserver.ConsentRepository
+	cfg      *config.Config
+}
+
+// NewAuthorizeUseCase creates a new authorize use case
+func NewAuthorizeUseCase(
+	clients oauthserver.ClientRepository,
+	codes oauthserver.AuthorizationCodeRepository,
+	consents oauthserver.ConsentRepository,
+	cfg *config.Config,
+) *AuthorizeUseCase {
+	return &AuthorizeUseCase{clients: clients, codes: codes, consents: consents, cfg: cfg}
+}
+
+// Prepare validates the request and returns what the consent screen needs
+func (uc *AuthorizeUseCase) Prepare(ctx context.Context, userID uint, req AuthorizationRequest) (*ConsentPrompt, error) {
+	client, err := uc.clients.GetByClientID(ctx, req.ClientID)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		return nil, newError(CodeInvalidClient, "unknown client")
+	}
+
+	// Until the redirect URI is verified, errors must not be redirected
+	redirectURI := req.RedirectURI
+	if redirectURI == "" {
+		if uris := client.RedirectURIList(); len(uris) == 1 {
+			redirectURI = uris[0]
+		}
+	}
+	if redirectURI == "" || !client.AllowsRedirectURI(redirectURI) {
+		return nil, newError(CodeInvalidRequest, "redirect_uri does not match a registered redirect URI")
+	}
+
+	redirectErr := func(code, description string) error {
+		return &Error{Code: code, Description: description, RedirectURI: redirectURI, State: req.State}
+	}
+
+	if req.ResponseType != "code" {
+		return nil, redirectErr(CodeUnsupportedResponseType, "only response_type=code is supported")
+	}
+
+	scopes := oauthserver.ParseScope(req.Scope)
+	if len(scopes) == 0 {
+		return nil, redirectErr(CodeInvalidScope, "scope is required")
+	}
+	allowed := client.ScopeList()
+	for _, s := range scopes {
+		if !oauthserver.IsSupported(s) || !slices.Contains(allowed, s) {
+			return nil, redirectErr(CodeInvalidScope, fmt.Sprintf("scope %q is not available to this client", s))
+		}
+	}
+
+	if req.CodeChallenge == "" {
+		if !client.IsConfidential() {
+			return nil, redirectErr(CodeInvalidRequest, "public clients must use PKCE (code_challenge)")
+		}
+	} else if req.CodeChallengeMethod != "S256" {
+		return nil, redirectErr(CodeInvalidRequest, "code_challenge_method must be S256")
+	}
+
+	consent, err := uc.consents.Get(ctx, userID, client.ClientID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ConsentPrompt{
+		Client:       client,
+		RedirectURI:  redirectURI,
+		Scopes:       scopes,
+		ConsentGiven: consent != nil && oauthserver.Covers(oauthserver.ParseScope(consent.Scope), scopes),
+	}, nil
+}
+
+// Decide records the user's decision and returns the URL the user agent is
+// sent back to: with an authorization code if approved, with
+// error=access_denied otherwise.
+func (uc *AuthorizeUseCase) Decide(ctx context.Context, userID uint, req AuthorizationRequest, approved bool) (string, error) {
+	prompt, err := uc.Prepare(ctx, userID, req)
+	if err != nil {
+		return "", err
+	}
+
+	if !approved {
+		denied := &Error{
+			Code:        CodeAccessDenied,
+			Description: "the user denied the request",
+			RedirectURI: prompt.RedirectURI,
+			State:       req.State,
+		}
+		return denied.RedirectTo(), nil
+	}
+
+	if err := uc.rememberConsent(ctx, userID, prompt); err != nil {
+		return "", err
+	}
+
+	code, err := randomToken("")
+	if err != nil {
+		return "", fmt.Errorf("failed to generate authorization code: %w", err)
+	}
+	if err := uc.codes.Create(ctx, &oauthserver.AuthorizationCode{
+		CodeHash:            oauthserver.HashToken(code),
+		ClientID:            prompt.Client.ClientID,
+		UserID:              userID,
+		RedirectURI:         prompt.RedirectURI,
+		Scope:               oauthserver.FormatScope(prompt.Scopes),
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+		Nonce:               req.Nonce,
+		ExpiresAt:           time.Now().Add(uc.cfg.OAuthServer.CodeExpiry),
+	}); err != nil {
+		return "", err
+	}
+
+	params := url.Values{"code": {code}, "iss": {uc.cfg.BaseURL}}
+	if req.State != "" {
+		params.Set("state", req.State)
+	}
+	return appendQuery(prompt.RedirectURI, params), nil
+}
+
+// rememberConsent adds the approved scopes to the user's stored consent
+func (uc *AuthorizeUseCase) rememberConsent(ctx context.Context, userID uint, prompt *ConsentPrompt) error {
+	scopes := prompt.Scopes
+	existing, err := uc.consents.Get(ctx, userID, prompt.Client.ClientID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		scopes = oauthserver.ParseScope(existing.Scope + " " + oauthserver.FormatScope(scopes))
+	}
+	return uc.consents.Save(ctx, &oauthserver.Consent{
+		UserID:   userID,
+		ClientID: prompt.Client.ClientID,
+		Scope:    oauthserver.FormatScope(scopes),
+	})
+}
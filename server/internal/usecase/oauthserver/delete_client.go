@@ -0,0 +1,39 @@
+package oauthserver
+
+import (
+	"context"
+
+	"github.com/jherrma/caldav-server/internal/domain/oauthserver"
+)
+
+// DeleteClientUseCase removes a client along with its tokens and consents
+type DeleteClientUseCase struct {
+	clients  oauthserver.ClientRepository
+	tokens   oauthserver.TokenRepository
+	consents oauthserver.ConsentRepository
+}
+
+// NewDeleteClientUseCase creates a new delete client use case
+func NewDeleteClientUseCase(clients oauthserver.ClientRepository, tokens oauthserver.TokenRepository, consents oauthserver.ConsentRepository) *DeleteClientUseCase {
+	return &DeleteClientUseCase{clients: clients, tokens: tokens, consents: consents}
+}
+
+// Execute deletes the client if it belongs to the user. All tokens issued to
+// it stop working immediately.
+func (uc *DeleteClientUseCase) Execute(ctx context.Context, userID uint, clientID string) error {
+	client, err := uc.clients.GetByClientID(ctx, clientID)
+	if err != nil {
+		return err
+	}
+	if client == nil || client.UserID != userID {
+		return ErrClientNotFound
+	}
+
+	if err := uc.tokens.RevokeByClient(ctx, client.ClientID); err != nil {
+		return err
+	}
+	if err := uc.consents.DeleteByClient(ctx, client.ClientID); err != nil {
+		return err
+	}
+	return uc.clients.Delete(ctx, client.ID)
+}
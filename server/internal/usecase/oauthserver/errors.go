@@ -0,0 +1,77 @@
+package oauthserver
+
+import (
+	"errors"
+	"net/url"
+)
+
+var (
+	ErrClientNotFound = errors.New("client not found")
+	ErrInvalidClient  = errors.New("invalid client registration")
+)
+
+// Error codes from RFC 6749 §4.1.2.1 and §5.2
+const (
+	CodeInvalidRequest          = "invalid_request"
+	CodeInvalidClient           = "invalid_client"
+	CodeInvalidGrant            = "invalid_grant"
+	CodeUnauthorizedClient      = "unauthorized_client"
+	CodeUnsupportedGrantType    = "unsupported_grant_type"
+	CodeUnsupportedResponseType = "unsupported_response_type"
+	CodeInvalidScope            = "invalid_scope"
+	CodeAccessDenied            = "access_denied"
+	CodeServerError             = "server_error"
+)
+
+// Error is an OAuth 2.0 protocol error. When RedirectURI is set the request
+// came with a verified redirect URI and the error is reported to the client
+// by redirecting there; otherwise it must be shown to the user.
+type Error struct {
+	Code        string
+	Description string
+	RedirectURI string
+	State       string
+}
+
+func (e *Error) Error() string {
+	if e.Description == "" {
+		return e.Code
+	}
+	return e.Code + ": " + e.Description
+}
+
+// RedirectTo returns the client redirect URL carrying the error, or an
+// empty string if the error must not be redirected
+func (e *Error) RedirectTo() string {
+	if e.RedirectURI == "" {
+		return ""
+	}
+	params := url.Values{"error": {e.Code}}
+	if e.Description != "" {
+		params.Set("error_description", e.Description)
+	}
+	if e.State != "" {
+		params.Set("state", e.State)
+	}
+	return appendQuery(e.RedirectURI, params)
+}
+
+func newError(code, description string) *Error {
+	return &Error{Code: code, Description: description}
+}
+
+// appendQuery adds params to the query of rawURL, keeping existing ones
+func appendQuery(rawURL string, params url.Values) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	q := u.Query()
+	for k, vs := range params {
+		for _, v := range vs {
+			q.Add(k, v)
+		}
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
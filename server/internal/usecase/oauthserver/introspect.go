@@ -0,0 +1,96 @@
+package oauthserver
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/jherrma/caldav-server/internal/domain/oauthserver"
+	"github.com/jherrma/caldav-server/internal/domain/user"
+)
+
+// IntrospectionResult is the token metadata of RFC 7662 §2.2. Inactive
+// tokens carry no other information.
+type IntrospectionResult struct {
+	Active    bool
+	Scope     string
+	ClientID  string
+	Username  string
+	Subject   string
+	TokenType string
+	ExpiresAt int64
+	IssuedAt  int64
+}
+
+// IntrospectUseCase reports whether a token is active (RFC 7662)
+type IntrospectUseCase struct {
+	clients  oauthserver.ClientRepository
+	tokens   oauthserver.TokenRepository
+	userRepo user.UserRepository
+}
+
+// NewIntrospectUseCase creates a new introspect use case
+func NewIntrospectUseCase(clients oauthserver.ClientRepository, tokens oauthserver.TokenRepository, userRepo user.UserRepository) *IntrospectUseCase {
+	return &IntrospectUseCase{clients: clients, tokens: tokens, userRepo: userRepo}
+}
+
+// Execute authenticates the calling client and looks up the token. Clients
+// can only introspect tokens issued to them; anything else is reported as
+// inactive so the endpoint cannot be used to probe other clients' tokens.
+func (uc *IntrospectUseCase) Execute(ctx context.Context, clientID, clientSecret, token string) (*IntrospectionResult, error) {
+	client, err := authenticateClient(ctx, uc.clients, clientID, clientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	inactive := &IntrospectionResult{Active: false}
+	t, tokenType, err := findToken(ctx, uc.tokens, token)
+	if err != nil {
+		return nil, newError(CodeServerError, "")
+	}
+	if t == nil || t.ClientID != client.ClientID {
+		return inactive, nil
+	}
+
+	now := time.Now()
+	expiresAt := t.AccessExpiresAt
+	if tokenType == "refresh_token" {
+		if !t.RefreshActive(now) {
+			return inactive, nil
+		}
+		expiresAt = *t.RefreshExpiresAt
+	} else if !t.AccessActive(now) {
+		return inactive, nil
+	}
+
+	u, err := uc.userRepo.GetByID(ctx, t.UserID)
+	if err != nil || u == nil {
+		return inactive, nil
+	}
+
+	return &IntrospectionResult{
+		Active:    true,
+		Scope:     t.Scope,
+		ClientID:  t.ClientID,
+		Username:  u.Email,
+		Subject:   u.UUID,
+		TokenType: tokenType,
+		ExpiresAt: expiresAt.Unix(),
+		IssuedAt:  t.CreatedAt.Unix(),
+	}, nil
+}
+
+// findToken looks up an access or refresh token by its prefix and reports
+// which kind it is
+func findToken(ctx context.Context, tokens oauthserver.TokenRepository, token string) (*oauthserver.Token, string, error) {
+	switch {
+	case strings.HasPrefix(token, oauthserver.AccessTokenPrefix):
+		t, err := tokens.GetByAccessHash(ctx, oauthserver.HashToken(token))
+		return t, "access_token", err
+	case strings.HasPrefix(token, oauthserver.RefreshTokenPrefix):
+		t, err := tokens.GetByRefreshHash(ctx, oauthserver.HashToken(token))
+		return t, "refresh_token", err
+	default:
+		return nil, "", nil
+	}
+}
@@ -0,0 +1,22 @@
+package oauthserver
+
+import (
+	"context"
+
+	"github.com/jherrma/caldav-server/internal/domain/oauthserver"
+)
+
+// ListClientsUseCase lists the clients a user has registered
+type ListClientsUseCase struct {
+	clients oauthserver.ClientRepository
+}
+
+// NewListClientsUseCase creates a new list clients use case
+func NewListClientsUseCase(clients oauthserver.ClientRepository) *ListClientsUseCase {
+	return &ListClientsUseCase{clients: clients}
+}
+
+// Execute returns the user's clients, newest first
+func (uc *ListClientsUseCase) Execute(ctx context.Context, userID uint) ([]oauthserver.Client, error) {
+	return uc.clients.ListByUserID(ctx, userID)
+}
@@ -0,0 +1,117 @@
+package oauthserver
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/jherrma/caldav-server/internal/domain/oauthserver"
+)
+
+// RegisterClientInput contains the details of a new client
+type RegisterClientInput struct {
+	Name         string
+	RedirectURIs []string
+	Scopes       []string // Defaults to all supported scopes
+	Confidential bool     // Issue a client secret (server-side apps)
+}
+
+// RegisterClientUseCase registers a third-party application owned by a user
+type RegisterClientUseCase struct {
+	clients oauthserver.ClientRepository
+}
+
+// NewRegisterClientUseCase creates a new register client use case
+func NewRegisterClientUseCase(clients oauthserver.ClientRepository) *RegisterClientUseCase {
+	return &RegisterClientUseCase{clients: clients}
+}
+
+// Execute registers the client and returns it with its secret. The secret is
+// only returned here; confidential clients that lose it must be re-registered.
+func (uc *RegisterClientUseCase) Execute(ctx context.Context, userID uint, in RegisterClientInput) (*oauthserver.Client, string, error) {
+	name := strings.TrimSpace(in.Name)
+	if name == "" || len(name) > 100 {
+		return nil, "", fmt.Errorf("%w: name must be 1-100 characters", ErrInvalidClient)
+	}
+
+	if len(in.RedirectURIs) == 0 {
+		return nil, "", fmt.Errorf("%w: at least one redirect URI is required", ErrInvalidClient)
+	}
+	for _, uri := range in.RedirectURIs {
+		if err := validateRedirectURI(uri); err != nil {
+			return nil, "", fmt.Errorf("%w: %v", ErrInvalidClient, err)
+		}
+	}
+
+	scopes := in.Scopes
+	if len(scopes) == 0 {
+		scopes = oauthserver.SupportedScopes()
+	}
+	for _, s := range scopes {
+		if !oauthserver.IsSupported(s) {
+			return nil, "", fmt.Errorf("%w: unsupported scope %q", ErrInvalidClient, s)
+		}
+	}
+
+	idBytes := make([]byte, 16)
+	if _, err := rand.Read(idBytes); err != nil {
+		return nil, "", fmt.Errorf("failed to generate client id: %w", err)
+	}
+
+	client := &oauthserver.Client{
+		ClientID:     hex.EncodeToString(idBytes),
+		UserID:       userID,
+		Name:         name,
+		RedirectURIs: strings.Join(in.RedirectURIs, " "),
+		Scopes:       oauthserver.FormatScope(scopes),
+	}
+
+	var secret string
+	if in.Confidential {
+		var err error
+		secret, err = randomToken("")
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to generate client secret: %w", err)
+		}
+		client.SecretHash = oauthserver.HashToken(secret)
+	}
+
+	if err := uc.clients.Create(ctx, client); err != nil {
+		return nil, "", err
+	}
+	return client, secret, nil
+}
+
+// validateRedirectURI accepts absolute URIs without fragment. Plain http is
+// only allowed for loopback addresses; custom schemes are allowed for native
+// apps (RFC 8252).
+func validateRedirectURI(raw string) error {
+	if strings.ContainsAny(raw, " \t\n") {
+		return fmt.Errorf("redirect URI %q must not contain whitespace", raw)
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" {
+		return fmt.Errorf("redirect URI %q must be an absolute URI", raw)
+	}
+	if u.Fragment != "" {
+		return fmt.Errorf("redirect URI %q must not contain a fragment", raw)
+	}
+
+	switch u.Scheme {
+	case "https":
+		if u.Host == "" {
+			return fmt.Errorf("redirect URI %q has no host", raw)
+		}
+	case "http":
+		host := u.Hostname()
+		ip := net.ParseIP(host)
+		if host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+			return fmt.Errorf("redirect URI %q must use https", raw)
+		}
+	}
+	return nil
+}
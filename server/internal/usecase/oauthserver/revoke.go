@@ -0,0 +1,40 @@
+package oauthserver
+
+import (
+	"context"
+
+	"github.com/jherrma/caldav-server/internal/domain/oauthserver"
+)
+
+// RevokeUseCase revokes access and refresh tokens (RFC 7009)
+type RevokeUseCase struct {
+	clients oauthserver.ClientRepository
+	tokens  oauthserver.TokenRepository
+}
+
+// NewRevokeUseCase creates a new revoke use case
+func NewRevokeUseCase(clients oauthserver.ClientRepository, tokens oauthserver.TokenRepository) *RevokeUseCase {
+	return &RevokeUseCase{clients: clients, tokens: tokens}
+}
+
+// Execute authenticates the client and revokes the token. Revoking either
+// token of a pair revokes both. Unknown tokens and tokens of other clients
+// are ignored, as RFC 7009 §2.2 requires the endpoint to succeed anyway.
+func (uc *RevokeUseCase) Execute(ctx context.Context, clientID, clientSecret, token string) error {
+	client, err := authenticateClient(ctx, uc.clients, clientID, clientSecret)
+	if err != nil {
+		return err
+	}
+
+	t, _, err := findToken(ctx, uc.tokens, token)
+	if err != nil {
+		return newError(CodeServerError, "")
+	}
+	if t == nil || t.ClientID != client.ClientID || t.RevokedAt != nil {
+		return nil
+	}
+	if err := uc.tokens.Revoke(ctx, t.ID); err != nil {
+		return newError(CodeServerError, "")
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package oauthserver
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+
+	"github.com/jherrma/caldav-server/internal/domain/oauthserver"
+)
+
+// randomToken returns prefix followed by 32 random bytes in hex
+func randomToken(prefix string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return prefix + hex.EncodeToString(b), nil
+}
+
+// authenticateClient verifies the client credentials presented at the token,
+// introspection and revocation endpoints. Public clients have no secret and
+// authenticate with their client ID alone.
+func authenticateClient(ctx context.Context, clients oauthserver.ClientRepository, clientID, clientSecret string) (*oauthserver.Client, error) {
+	if clientID == "" {
+		return nil, newError(CodeInvalidClient, "client authentication required")
+	}
+	client, err := clients.GetByClientID(ctx, clientID)
+	if err != nil {
+		return nil, newError(CodeServerError, "")
+	}
+	if client == nil {
+		return nil, newError(CodeInvalidClient, "unknown client")
+	}
+	if client.IsConfidential() {
+		presented := oauthserver.HashToken(clientSecret)
+		if clientSecret == "" || subtle.ConstantTimeCompare([]byte(presented), []byte(client.SecretHash)) != 1 {
+			return nil, newError(CodeInvalidClient, "client authentication failed")
+		}
+	}
+	return client, nil
+}
@@ -0,0 +1,257 @@
+package oauthserver
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/jherrma/caldav-server/internal/config"
+	"github.com/jherrma/caldav-server/internal/domain/oauthserver"
+	"github.com/jherrma/caldav-server/internal/domain/user"
+)
+
+// TokenRequest holds the parameters of a token request (RFC 6749 §4.1.3, §6)
+type TokenRequest struct {
+	GrantType    string
+	Code         string
+	RedirectURI  string
+	CodeVerifier string
+	RefreshToken string
+	Scope        string
+	ClientID     string
+	ClientSecret string
+}
+
+// TokenResult contains the issued tokens
+type TokenResult struct {
+	AccessToken  string
+	RefreshToken string
+	IDToken      string // Only when the openid scope was granted
+	ExpiresIn    int64
+	Scope        string
+}
+
+// TokenUseCase implements the token endpoint for the authorization_code and
+// refresh_token grants
+type TokenUseCase struct {
+	clients  oauthserver.ClientRepository
+	codes    oauthserver.AuthorizationCodeRepository
+	tokens   oauthserver.TokenRepository
+	userRepo user.UserRepository
+	signer   oauthserver.IDTokenSigner
+	cfg      *config.Config
+}
+
+// NewTokenUseCase creates a new token use case
+func NewTokenUseCase(
+	clients oauthserver.ClientRepository,
+	codes oauthserver.AuthorizationCodeRepository,
+	tokens oauthserver.TokenRepository,
+	userRepo user.UserRepository,
+	signer oauthserver.IDTokenSigner,
+	cfg *config.Config,
+) *TokenUseCase {
+	return &TokenUseCase{
+		clients:  clients,
+		codes:    codes,
+		tokens:   tokens,
+		userRepo: userRepo,
+		signer:   signer,
+		cfg:      cfg,
+	}
+}
+
+// Execute authenticates the client and performs the requested grant
+func (uc *TokenUseCase) Execute(ctx context.Context, req TokenRequest) (*TokenResult, error) {
+	client, err := authenticateClient(ctx, uc.clients, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	switch req.GrantType {
+	case "authorization_code":
+		return uc.exchangeCode(ctx, client, req)
+	case "refresh_token":
+		return uc.refresh(ctx, client, req)
+	case "":
+		return nil, newError(CodeInvalidRequest, "grant_type is required")
+	default:
+		return nil, newError(CodeUnsupportedGrantType, "")
+	}
+}
+
+func (uc *TokenUseCase) exchangeCode(ctx context.Context, client *oauthserver.Client, req TokenRequest) (*TokenResult, error) {
+	if req.Code == "" {
+		return nil, newError(CodeInvalidRequest, "code is required")
+	}
+
+	code, err := uc.codes.GetByHash(ctx, oauthserver.HashToken(req.Code))
+	if err != nil {
+		return nil, newError(CodeServerError, "")
+	}
+	if code == nil || code.ClientID != client.ClientID || code.ExpiresAt.Before(time.Now()) {
+		return nil, newError(CodeInvalidGrant, "authorization code is invalid or expired")
+	}
+	if req.RedirectURI != code.RedirectURI {
+		return nil, newError(CodeInvalidGrant, "redirect_uri does not match the authorization request")
+	}
+	if code.CodeChallenge != "" && !verifyPKCE(code.CodeChallenge, req.CodeVerifier) {
+		return nil, newError(CodeInvalidGrant, "code_verifier does not match the code challenge")
+	}
+
+	// A code redeemed twice was intercepted: revoke what the first
+	// redemption issued (RFC 6749 §4.1.2)
+	redeemed, err := uc.codes.MarkUsed(ctx, code.ID)
+	if err != nil {
+		return nil, newError(CodeServerError, "")
+	}
+	if !redeemed {
+		if err := uc.tokens.RevokeByAuthorizationCode(ctx, code.ID); err != nil {
+			return nil, newError(CodeServerError, "")
+		}
+		return nil, newError(CodeInvalidGrant, "authorization code was already used")
+	}
+
+	u, err := uc.userRepo.GetByID(ctx, code.UserID)
+	if err != nil || u == nil {
+		return nil, newError(CodeInvalidGrant, "user no longer exists")
+	}
+
+	res, err := uc.issue(ctx, client, u, code.Scope, code.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if uc.signer != nil && oauthserver.Allows(oauthserver.ParseScope(code.Scope), oauthserver.ScopeOpenID) {
+		res.IDToken, err = uc.idToken(client, u, code)
+		if err != nil {
+			return nil, newError(CodeServerError, "")
+		}
+	}
+	return res, nil
+}
+
+func (uc *TokenUseCase) refresh(ctx context.Context, client *oauthserver.Client, req TokenRequest) (*TokenResult, error) {
+	if req.RefreshToken == "" {
+		return nil, newError(CodeInvalidRequest, "refresh_token is required")
+	}
+
+	t, err := uc.tokens.GetByRefreshHash(ctx, oauthserver.HashToken(req.RefreshToken))
+	if err != nil {
+		return nil, newError(CodeServerError, "")
+	}
+	if t == nil || t.ClientID != client.ClientID {
+		return nil, newError(CodeInvalidGrant, "refresh token is invalid")
+	}
+	if t.RevokedAt != nil {
+		// Refresh tokens rotate, so a revoked one being replayed means it
+		// leaked: end the whole grant
+		if err := uc.tokens.RevokeByAuthorizationCode(ctx, t.AuthorizationCodeID); err != nil {
+			return nil, newError(CodeServerError, "")
+		}
+		return nil, newError(CodeInvalidGrant, "refresh token was already used")
+	}
+	if !t.RefreshActive(time.Now()) {
+		return nil, newError(CodeInvalidGrant, "refresh token expired")
+	}
+
+	scope := t.Scope
+	if req.Scope != "" {
+		requested := oauthserver.ParseScope(req.Scope)
+		if !oauthserver.Covers(t.ScopeList(), requested) {
+			return nil, newError(CodeInvalidScope, "requested scope exceeds the original grant")
+		}
+		scope = oauthserver.FormatScope(requested)
+	}
+
+	u, err := uc.userRepo.GetByID(ctx, t.UserID)
+	if err != nil || u == nil {
+		return nil, newError(CodeInvalidGrant, "user no longer exists")
+	}
+
+	if err := uc.tokens.Revoke(ctx, t.ID); err != nil {
+		return nil, newError(CodeServerError, "")
+	}
+	return uc.issue(ctx, client, u, scope, t.AuthorizationCodeID)
+}
+
+// issue creates and stores a new access/refresh token pair
+func (uc *TokenUseCase) issue(ctx context.Context, client *oauthserver.Client, u *user.User, scope string, codeID uint) (*TokenResult, error) {
+	accessToken, err := randomToken(oauthserver.AccessTokenPrefix)
+	if err != nil {
+		return nil, newError(CodeServerError, "")
+	}
+	refreshToken, err := randomToken(oauthserver.RefreshTokenPrefix)
+	if err != nil {
+		return nil, newError(CodeServerError, "")
+	}
+
+	now := time.Now()
+	refreshHash := oauthserver.HashToken(refreshToken)
+	refreshExpiry := now.Add(uc.cfg.OAuthServer.RefreshTokenExpiry)
+	if err := uc.tokens.Create(ctx, &oauthserver.Token{
+		ClientID:            client.ClientID,
+		UserID:              u.ID,
+		Scope:               scope,
+		AccessTokenHash:     oauthserver.HashToken(accessToken),
+		RefreshTokenHash:    &refreshHash,
+		AuthorizationCodeID: codeID,
+		AccessExpiresAt:     now.Add(uc.cfg.OAuthServer.AccessTokenExpiry),
+		RefreshExpiresAt:    &refreshExpiry,
+	}); err != nil {
+		return nil, newError(CodeServerError, "")
+	}
+
+	return &TokenResult{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(uc.cfg.OAuthServer.AccessTokenExpiry.Seconds()),
+		Scope:        scope,
+	}, nil
+}
+
+func (uc *TokenUseCase) idToken(client *oauthserver.Client, u *user.User, code *oauthserver.AuthorizationCode) (string, error) {
+	now := time.Now()
+	claims := IdentityClaims(u, oauthserver.ParseScope(code.Scope))
+	claims["iss"] = uc.cfg.BaseURL
+	claims["aud"] = client.ClientID
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(uc.cfg.OAuthServer.AccessTokenExpiry).Unix()
+	claims["auth_time"] = code.CreatedAt.Unix()
+	if code.Nonce != "" {
+		claims["nonce"] = code.Nonce
+	}
+	token, err := uc.signer.SignIDToken(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign id token: %w", err)
+	}
+	return token, nil
+}
+
+// IdentityClaims returns the OpenID Connect claims about the user that the
+// granted scopes release. Used for ID tokens and the userinfo endpoint.
+func IdentityClaims(u *user.User, scopes []string) map[string]interface{} {
+	claims := map[string]interface{}{"sub": u.UUID}
+	if oauthserver.Allows(scopes, oauthserver.ScopeProfile) {
+		claims["name"] = u.DisplayName
+		claims["preferred_username"] = u.Username
+	}
+	if oauthserver.Allows(scopes, oauthserver.ScopeEmail) {
+		claims["email"] = u.Email
+		claims["email_verified"] = u.EmailVerified
+	}
+	return claims
+}
+
+// verifyPKCE checks an S256 code verifier against the stored challenge
+func verifyPKCE(challenge, verifier string) bool {
+	if verifier == "" {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	computed := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(challenge)) == 1
+}
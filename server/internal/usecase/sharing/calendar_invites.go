@@ -0,0 +1,89 @@
+package sharing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jherrma/caldav-server/internal/domain/calendar"
+	"github.com/jherrma/caldav-server/internal/domain/sharing"
+	"github.com/jherrma/caldav-server/internal/domain/user"
+)
+
+// CalendarInviteChange adds, changes or removes the share of a calendar
+// with one user, as requested by a calendarserver.org share request
+type CalendarInviteChange struct {
+	UserIdentifier string // Email or Username
+	Remove         bool
+	Permission     string
+	Summary        string
+}
+
+// ApplyCalendarInvitesUseCase applies the changes of a DAV share request to
+// the direct shares of a calendar. New sharees are invited, existing shares
+// change their permission and removed sharees are notified.
+type ApplyCalendarInvitesUseCase struct {
+	shareRepo sharing.CalendarShareRepository
+	userRepo  user.UserRepository
+	create    *CreateCalendarShareUseCase
+	update    *UpdateCalendarShareUseCase
+	revoke    *RevokeCalendarShareUseCase
+}
+
+func NewApplyCalendarInvitesUseCase(
+	shareRepo sharing.CalendarShareRepository,
+	userRepo user.UserRepository,
+	create *CreateCalendarShareUseCase,
+	update *UpdateCalendarShareUseCase,
+	revoke *RevokeCalendarShareUseCase,
+) *ApplyCalendarInvitesUseCase {
+	return &ApplyCalendarInvitesUseCase{
+		shareRepo: shareRepo,
+		userRepo:  userRepo,
+		create:    create,
+		update:    update,
+		revoke:    revoke,
+	}
+}
+
+func (uc *ApplyCalendarInvitesUseCase) Execute(ctx context.Context, requestingUserID uint, cal *calendar.Calendar, changes []CalendarInviteChange) error {
+	if cal.UserID != requestingUserID {
+		return fmt.Errorf("permission denied")
+	}
+
+	for _, change := range changes {
+		sharee, err := uc.userRepo.GetByEmail(ctx, change.UserIdentifier)
+		if err != nil || sharee == nil {
+			sharee, err = uc.userRepo.GetByUsername(ctx, change.UserIdentifier)
+		}
+		if err != nil || sharee == nil {
+			return fmt.Errorf("user '%s' not found", change.UserIdentifier)
+		}
+
+		existing, err := uc.shareRepo.GetByCalendarAndUser(ctx, cal.ID, sharee.ID)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case change.Remove:
+			if existing != nil {
+				err = uc.revoke.Execute(ctx, requestingUserID, cal.ID, existing.UUID)
+			}
+		case existing != nil && existing.Status != sharing.ShareStatusDeclined:
+			if existing.Permission != change.Permission {
+				_, err = uc.update.Execute(ctx, requestingUserID, cal.ID, existing.UUID, UpdateCalendarShareInput{Permission: change.Permission})
+			}
+		default:
+			_, err = uc.create.Execute(ctx, requestingUserID, CreateCalendarShareInput{
+				CalendarID:     cal.ID,
+				UserIdentifier: sharee.Email,
+				Permission:     change.Permission,
+				Summary:        change.Summary,
+			})
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package sharing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jherrma/caldav-server/internal/domain/sharing"
+	"github.com/jherrma/caldav-server/internal/domain/user"
+)
+
+// ErrProxyNotFound is returned for proxies the requesting user did not grant
+var ErrProxyNotFound = errors.New("proxy not found")
+
+// ProxyOutput is a calendar proxy grant. User is the proxy in the grants a
+// user made and the granting user in their delegations.
+type ProxyOutput struct {
+	ID        string    `json:"id"` // Grant UUID
+	User      *UserInfo `json:"user"`
+	Level     string    `json:"level"` // "read" or "write"
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CalendarProxiesUseCase manages who may act for a user in their calendars
+// (calendar delegation)
+type CalendarProxiesUseCase struct {
+	proxyRepo sharing.CalendarProxyRepository
+	userRepo  user.UserRepository
+}
+
+func NewCalendarProxiesUseCase(proxyRepo sharing.CalendarProxyRepository, userRepo user.UserRepository) *CalendarProxiesUseCase {
+	return &CalendarProxiesUseCase{proxyRepo: proxyRepo, userRepo: userRepo}
+}
+
+// List returns the proxies of the user
+func (uc *CalendarProxiesUseCase) List(ctx context.Context, userID uint) ([]ProxyOutput, error) {
+	proxies, err := uc.proxyRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	output := make([]ProxyOutput, len(proxies))
+	for i, p := range proxies {
+		output[i] = ProxyOutput{ID: p.UUID, User: toUserInfo(&p.Proxy), Level: p.Level, CreatedAt: p.CreatedAt}
+	}
+	return output, nil
+}
+
+// ListDelegations returns the users the user may act for
+func (uc *CalendarProxiesUseCase) ListDelegations(ctx context.Context, userID uint) ([]ProxyOutput, error) {
+	proxies, err := uc.proxyRepo.ListForProxy(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	output := make([]ProxyOutput, len(proxies))
+	for i, p := range proxies {
+		output[i] = ProxyOutput{ID: p.UUID, User: toUserInfo(&p.User), Level: p.Level, CreatedAt: p.CreatedAt}
+	}
+	return output, nil
+}
+
+// Grant makes the user identified by email or username a proxy of the
+// requesting user, or changes the level of an existing proxy
+func (uc *CalendarProxiesUseCase) Grant(ctx context.Context, userID uint, identifier, level string) (*ProxyOutput, error) {
+	proxy, err := uc.grant(ctx, userID, identifier, level)
+	if err != nil {
+		return nil, err
+	}
+	return &ProxyOutput{ID: proxy.UUID, User: toUserInfo(&proxy.Proxy), Level: proxy.Level, CreatedAt: proxy.CreatedAt}, nil
+}
+
+func (uc *CalendarProxiesUseCase) grant(ctx context.Context, userID uint, identifier, level string) (*sharing.CalendarProxy, error) {
+	if !sharing.ValidProxyLevel(level) {
+		return nil, fmt.Errorf("level must be '%s' or '%s'", sharing.ProxyRead, sharing.ProxyWrite)
+	}
+	target, err := resolveShareTarget(ctx, uc.userRepo, nil, userID, identifier, "")
+	if err != nil {
+		return nil, err
+	}
+	if target.user.ID == userID {
+		return nil, fmt.Errorf("cannot make yourself a proxy")
+	}
+
+	proxy, err := uc.proxyRepo.Get(ctx, userID, target.user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if proxy == nil {
+		proxy = &sharing.CalendarProxy{UUID: uuid.New().String(), UserID: userID, ProxyID: target.user.ID}
+	}
+	proxy.Level = level
+	if err := uc.proxyRepo.Set(ctx, proxy); err != nil {
+		return nil, err
+	}
+	proxy.Proxy = *target.user
+	return proxy, nil
+}
+
+// Revoke removes a proxy of the requesting user
+func (uc *CalendarProxiesUseCase) Revoke(ctx context.Context, userID uint, proxyUUID string) error {
+	proxies, err := uc.proxyRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, p := range proxies {
+		if p.UUID == proxyUUID {
+			return uc.proxyRepo.Delete(ctx, p.ID)
+		}
+	}
+	return ErrProxyNotFound
+}
+
+// SetLevel makes exactly the identified users proxies of the requesting
+// user at level: they are granted the level, and the other proxies at that
+// level lose their rights. Clients edit the proxy groups this way.
+func (uc *CalendarProxiesUseCase) SetLevel(ctx context.Context, userID uint, level string, identifiers []string) error {
+	keep := make(map[uint]bool)
+	for _, identifier := range identifiers {
+		p, err := uc.grant(ctx, userID, identifier, level)
+		if err != nil {
+			return err
+		}
+		keep[p.ProxyID] = true
+	}
+
+	proxies, err := uc.proxyRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, p := range proxies {
+		if p.Level == level && !keep[p.ProxyID] {
+			if err := uc.proxyRepo.Delete(ctx, p.ID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
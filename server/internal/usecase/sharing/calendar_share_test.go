@@ -36,10 +36,18 @@ func (m *mockShareRepo) FindCalendarsSharedWithUser(ctx context.Context, userID
 	args := m.Called(ctx, userID)
 	return args.Get(0).([]sharing.CalendarShare), args.Error(1)
 }
+func (m *mockShareRepo) ListPendingForUser(ctx context.Context, userID uint) ([]sharing.CalendarShare, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]sharing.CalendarShare), args.Error(1)
+}
 func (m *mockShareRepo) Update(ctx context.Context, share *sharing.CalendarShare) error {
 	args := m.Called(ctx, share)
 	return args.Error(0)
 }
+func (m *mockShareRepo) UpdateSharedCalendar(ctx context.Context, sc *sharing.SharedCalendar) error {
+	args := m.Called(ctx, sc)
+	return args.Error(0)
+}
 func (m *mockShareRepo) Revoke(ctx context.Context, id uint) error {
 	args := m.Called(ctx, id)
 	return args.Error(0)
@@ -51,6 +59,13 @@ func (m *mockShareRepo) GetByCalendarAndUser(ctx context.Context, calendarID, us
 	}
 	return args.Get(0).(*sharing.CalendarShare), args.Error(1)
 }
+func (m *mockShareRepo) GetByCalendarAndGroup(ctx context.Context, calendarID, groupID uint) (*sharing.CalendarShare, error) {
+	args := m.Called(ctx, calendarID, groupID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*sharing.CalendarShare), args.Error(1)
+}
 
 type mockCalendarRepo struct {
 	mock.Mock
@@ -104,6 +119,9 @@ func (m *mockCalendarRepo) ListEvents(ctx context.Context, cid uint, s, e time.T
 func (m *mockCalendarRepo) GetCalendarObjectByUUID(ctx context.Context, u string) (*calendar.CalendarObject, error) {
 	return nil, nil
 }
+func (m *mockCalendarRepo) GetCalendarObjectByUID(ctx context.Context, cid uint, uid string) (*calendar.CalendarObject, error) {
+	return nil, nil
+}
 func (m *mockCalendarRepo) GetUserPermission(ctx context.Context, cid, uid uint) (calendar.CalendarPermission, error) {
 	return 0, nil
 }
@@ -154,12 +172,17 @@ func (m *mockUserRepo) Count(ctx context.Context) (int64, error) {
 	return args.Get(0).(int64), args.Error(1)
 }
 
+func (m *mockUserRepo) ListActive(ctx context.Context) ([]user.User, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]user.User), args.Error(1)
+}
+
 func TestCreateCalendarShare(t *testing.T) {
 	shareRepo := new(mockShareRepo)
 	calendarRepo := new(mockCalendarRepo)
 	userRepo := new(mockUserRepo)
 
-	uc := NewCreateCalendarShareUseCase(shareRepo, calendarRepo, userRepo)
+	uc := NewCreateCalendarShareUseCase(shareRepo, calendarRepo, userRepo, nil, nil)
 
 	ctx := context.Background()
 	ownerID := uint(1)
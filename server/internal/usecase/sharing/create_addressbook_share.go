@@ -14,32 +14,70 @@ import (
 type CreateAddressBookShareInput struct {
 	AddressBookID  uint   `json:"addressbook_id"`
 	UserIdentifier string `json:"user_identifier"` // Email or Username
+	GroupID        string `json:"group_id"`        // Share with a group instead of a user
 	Permission     string `json:"permission"`
+	Summary        string `json:"summary"` // Message for the invitation
 }
 
 type CreateAddressBookShareOutput struct {
-	ID            string    `json:"id"`
-	AddressBookID string    `json:"addressbook_id"`
-	SharedWith    UserInfo  `json:"shared_with"`
-	Permission    string    `json:"permission"`
-	CreatedAt     time.Time `json:"created_at"`
+	ID              string     `json:"id"`
+	AddressBookID   string     `json:"addressbook_id"`
+	SharedWith      *UserInfo  `json:"shared_with,omitempty"`
+	SharedWithGroup *GroupInfo `json:"shared_with_group,omitempty"`
+	Permission      string     `json:"permission"`
+	Status          string     `json:"status"`
+	CreatedAt       time.Time  `json:"created_at"`
 }
 
 type CreateAddressBookShareUseCase struct {
 	shareRepo       sharing.AddressBookShareRepository
 	addressBookRepo addressbook.Repository
 	userRepo        user.UserRepository
+	groupRepo       user.GroupRepository
+	notifier        *Notifier
 }
 
 func NewCreateAddressBookShareUseCase(
 	shareRepo sharing.AddressBookShareRepository,
 	addressBookRepo addressbook.Repository,
 	userRepo user.UserRepository,
+	groupRepo user.GroupRepository,
+	notifier *Notifier,
 ) *CreateAddressBookShareUseCase {
 	return &CreateAddressBookShareUseCase{
 		shareRepo:       shareRepo,
 		addressBookRepo: addressBookRepo,
 		userRepo:        userRepo,
+		groupRepo:       groupRepo,
+		notifier:        notifier,
+	}
+}
+
+// addressBookShareEvent describes a direct or group share for the notifier
+func addressBookShareEvent(share *sharing.AddressBookShare, ab *addressbook.AddressBook) shareEvent {
+	e := shareEvent{
+		shareUUID:  share.UUID,
+		collection: CollectionAddressBook,
+		name:       ab.Name,
+		ownerID:    ab.UserID,
+		permission: share.Permission,
+	}
+	if share.SharedWithID != nil {
+		e.shareeID = *share.SharedWithID
+	}
+	return e
+}
+
+// newAddressBookShareOutput maps a share with its recipient loaded
+func newAddressBookShareOutput(share *sharing.AddressBookShare, ab *addressbook.AddressBook) *CreateAddressBookShareOutput {
+	return &CreateAddressBookShareOutput{
+		ID:              share.UUID,
+		AddressBookID:   ab.UUID,
+		SharedWith:      toUserInfo(share.SharedWith),
+		SharedWithGroup: toGroupInfo(share.SharedWithGroup),
+		Permission:      share.Permission,
+		Status:          share.Status,
+		CreatedAt:       share.CreatedAt,
 	}
 }
 
@@ -53,53 +91,68 @@ func (uc *CreateAddressBookShareUseCase) Execute(ctx context.Context, requesting
 		return nil, fmt.Errorf("permission denied")
 	}
 
-	// 2. Find target user
-	targetUser, err := uc.userRepo.GetByEmail(ctx, input.UserIdentifier)
-	if err != nil || targetUser == nil {
-		// Try by username
-		targetUser, err = uc.userRepo.GetByUsername(ctx, input.UserIdentifier)
-	}
-	if err != nil || targetUser == nil {
-		return nil, fmt.Errorf("user '%s' not found", input.UserIdentifier)
+	// 2. Find target user or group
+	target, err := resolveShareTarget(ctx, uc.userRepo, uc.groupRepo, requestingUserID, input.UserIdentifier, input.GroupID)
+	if err != nil {
+		return nil, err
 	}
 
 	// 3. Validation
-	if targetUser.ID == requestingUserID {
+	if target.user != nil && target.user.ID == requestingUserID {
 		return nil, fmt.Errorf("cannot share address book with yourself")
 	}
-	if input.Permission != "read" && input.Permission != "read-write" {
+	if !sharing.ValidPermission(input.Permission) {
 		return nil, fmt.Errorf("invalid permission")
 	}
 
 	// 4. Check existing share
-	existing, _ := uc.shareRepo.GetByAddressBookAndUser(ctx, input.AddressBookID, targetUser.ID)
-	if existing != nil {
-		return nil, fmt.Errorf("address book is already shared with this user")
+	if target.group != nil {
+		if existing, _ := uc.shareRepo.GetByAddressBookAndGroup(ctx, input.AddressBookID, target.group.ID); existing != nil {
+			return nil, fmt.Errorf("address book is already shared with this group")
+		}
+	} else if existing, _ := uc.shareRepo.GetByAddressBookAndUser(ctx, input.AddressBookID, target.user.ID); existing != nil {
+		if existing.Status != sharing.ShareStatusDeclined {
+			return nil, fmt.Errorf("address book is already shared with this user")
+		}
+		// A declined invitation may be sent again
+		existing.Permission = input.Permission
+		existing.Status = sharing.ShareStatusPending
+		existing.UpdatedAt = time.Now()
+		if err := uc.shareRepo.Update(ctx, existing); err != nil {
+			return nil, err
+		}
+		uc.notifier.invited(ctx, addressBookShareEvent(existing, ab), input.Summary)
+		existing.SharedWith = target.user
+		return newAddressBookShareOutput(existing, ab), nil
 	}
 
-	// 5. Create share
+	// 5. Create share; users are invited, groups get access at once as
+	// their members agreed to join them
+	userID, groupID := target.ids()
 	share := &sharing.AddressBookShare{
-		UUID:          uuid.New().String(),
-		AddressBookID: input.AddressBookID,
-		SharedWithID:  targetUser.ID,
-		Permission:    input.Permission,
+		UUID:              uuid.New().String(),
+		AddressBookID:     input.AddressBookID,
+		SharedWithID:      userID,
+		SharedWithGroupID: groupID,
+		Permission:        input.Permission,
+		Status:            sharing.ShareStatusAccepted,
+	}
+	if target.user != nil {
+		share.Status = sharing.ShareStatusPending
 	}
 
 	if err := uc.shareRepo.Create(ctx, share); err != nil {
 		return nil, err
 	}
 
-	// 6. Return output
-	return &CreateAddressBookShareOutput{
-		ID:            share.UUID,
-		AddressBookID: ab.UUID,
-		SharedWith: UserInfo{
-			ID:          targetUser.UUID,
-			Username:    targetUser.Username,
-			DisplayName: targetUser.DisplayName,
-			Email:       targetUser.Email,
-		},
-		Permission: share.Permission,
-		CreatedAt:  share.CreatedAt,
-	}, nil
+	// 6. Notify
+	if target.group != nil {
+		uc.notifier.groupShared(ctx, addressBookShareEvent(share, ab), target.group)
+	} else {
+		uc.notifier.invited(ctx, addressBookShareEvent(share, ab), input.Summary)
+	}
+
+	// 7. Return output
+	share.SharedWith, share.SharedWithGroup = target.user, target.group
+	return newAddressBookShareOutput(share, ab), nil
 }
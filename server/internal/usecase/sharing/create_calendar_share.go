@@ -14,15 +14,19 @@ import (
 type CreateCalendarShareInput struct {
 	CalendarID     uint   `json:"calendar_id"`
 	UserIdentifier string `json:"user_identifier"` // Email or Username
+	GroupID        string `json:"group_id"`        // Share with a group instead of a user
 	Permission     string `json:"permission"`
+	Summary        string `json:"summary"` // Message for the invitation
 }
 
 type CreateCalendarShareOutput struct {
-	ID         string    `json:"id"`
-	CalendarID string    `json:"calendar_id"`
-	SharedWith UserInfo  `json:"shared_with"`
-	Permission string    `json:"permission"`
-	CreatedAt  time.Time `json:"created_at"`
+	ID              string     `json:"id"`
+	CalendarID      string     `json:"calendar_id"`
+	SharedWith      *UserInfo  `json:"shared_with,omitempty"`
+	SharedWithGroup *GroupInfo `json:"shared_with_group,omitempty"`
+	Permission      string     `json:"permission"`
+	Status          string     `json:"status"`
+	CreatedAt       time.Time  `json:"created_at"`
 }
 
 type UserInfo struct {
@@ -36,17 +40,52 @@ type CreateCalendarShareUseCase struct {
 	shareRepo    sharing.CalendarShareRepository
 	calendarRepo calendar.CalendarRepository
 	userRepo     user.UserRepository
+	groupRepo    user.GroupRepository
+	notifier     *Notifier
 }
 
 func NewCreateCalendarShareUseCase(
 	shareRepo sharing.CalendarShareRepository,
 	calendarRepo calendar.CalendarRepository,
 	userRepo user.UserRepository,
+	groupRepo user.GroupRepository,
+	notifier *Notifier,
 ) *CreateCalendarShareUseCase {
 	return &CreateCalendarShareUseCase{
 		shareRepo:    shareRepo,
 		calendarRepo: calendarRepo,
 		userRepo:     userRepo,
+		groupRepo:    groupRepo,
+		notifier:     notifier,
+	}
+}
+
+// calendarShareEvent describes a direct or group share for the notifier
+func calendarShareEvent(share *sharing.CalendarShare, cal *calendar.Calendar) shareEvent {
+	e := shareEvent{
+		shareUUID:  share.UUID,
+		collection: CollectionCalendar,
+		name:       cal.Name,
+		path:       cal.Path,
+		ownerID:    cal.UserID,
+		permission: share.Permission,
+	}
+	if share.SharedWithID != nil {
+		e.shareeID = *share.SharedWithID
+	}
+	return e
+}
+
+// newCalendarShareOutput maps a share with its recipient loaded
+func newCalendarShareOutput(share *sharing.CalendarShare, cal *calendar.Calendar) *CreateCalendarShareOutput {
+	return &CreateCalendarShareOutput{
+		ID:              share.UUID,
+		CalendarID:      cal.UUID,
+		SharedWith:      toUserInfo(share.SharedWith),
+		SharedWithGroup: toGroupInfo(share.SharedWithGroup),
+		Permission:      share.Permission,
+		Status:          share.Status,
+		CreatedAt:       share.CreatedAt,
 	}
 }
 
@@ -60,55 +99,68 @@ func (uc *CreateCalendarShareUseCase) Execute(ctx context.Context, requestingUse
 		return nil, fmt.Errorf("permission denied")
 	}
 
-	// 2. Find target user
-	targetUser, err := uc.userRepo.GetByEmail(ctx, input.UserIdentifier)
-	if err != nil || targetUser == nil {
-		// Try by username
-		targetUser, err = uc.userRepo.GetByUsername(ctx, input.UserIdentifier)
-	}
-	if err != nil || targetUser == nil {
-		return nil, fmt.Errorf("user '%s' not found", input.UserIdentifier)
+	// 2. Find target user or group
+	target, err := resolveShareTarget(ctx, uc.userRepo, uc.groupRepo, requestingUserID, input.UserIdentifier, input.GroupID)
+	if err != nil {
+		return nil, err
 	}
 
 	// 3. Validation
-	if targetUser.ID == requestingUserID {
+	if target.user != nil && target.user.ID == requestingUserID {
 		return nil, fmt.Errorf("cannot share calendar with yourself")
 	}
-	if input.Permission != "read" && input.Permission != "read-write" {
+	if !sharing.ValidPermission(input.Permission) {
 		return nil, fmt.Errorf("invalid permission")
 	}
 
 	// 4. Check existing share
-	existing, _ := uc.shareRepo.GetByCalendarAndUser(ctx, input.CalendarID, targetUser.ID)
-	if existing != nil {
-		// Update existing share if found? Requirement says "Cannot share same calendar to same user twice"
-		// implying conflict or update. Let's return error for now as per implied AC
-		return nil, fmt.Errorf("calendar is already shared with this user")
+	if target.group != nil {
+		if existing, _ := uc.shareRepo.GetByCalendarAndGroup(ctx, input.CalendarID, target.group.ID); existing != nil {
+			return nil, fmt.Errorf("calendar is already shared with this group")
+		}
+	} else if existing, _ := uc.shareRepo.GetByCalendarAndUser(ctx, input.CalendarID, target.user.ID); existing != nil {
+		if existing.Status != sharing.ShareStatusDeclined {
+			return nil, fmt.Errorf("calendar is already shared with this user")
+		}
+		// A declined invitation may be sent again
+		existing.Permission = input.Permission
+		existing.Status = sharing.ShareStatusPending
+		existing.UpdatedAt = time.Now()
+		if err := uc.shareRepo.Update(ctx, existing); err != nil {
+			return nil, err
+		}
+		uc.notifier.invited(ctx, calendarShareEvent(existing, cal), input.Summary)
+		existing.SharedWith = target.user
+		return newCalendarShareOutput(existing, cal), nil
 	}
 
-	// 5. Create share
+	// 5. Create share; users are invited, groups get access at once as
+	// their members agreed to join them
+	userID, groupID := target.ids()
 	share := &sharing.CalendarShare{
-		UUID:         uuid.New().String(),
-		CalendarID:   input.CalendarID,
-		SharedWithID: targetUser.ID,
-		Permission:   input.Permission,
+		UUID:              uuid.New().String(),
+		CalendarID:        input.CalendarID,
+		SharedWithID:      userID,
+		SharedWithGroupID: groupID,
+		Permission:        input.Permission,
+		Status:            sharing.ShareStatusAccepted,
+	}
+	if target.user != nil {
+		share.Status = sharing.ShareStatusPending
 	}
 
 	if err := uc.shareRepo.Create(ctx, share); err != nil {
 		return nil, err
 	}
 
-	// 6. Return output
-	return &CreateCalendarShareOutput{
-		ID:         share.UUID,
-		CalendarID: cal.UUID,
-		SharedWith: UserInfo{
-			ID:          targetUser.UUID,
-			Username:    targetUser.Username,
-			DisplayName: targetUser.DisplayName,
-			Email:       targetUser.Email,
-		},
-		Permission: share.Permission,
-		CreatedAt:  share.CreatedAt,
-	}, nil
+	// 6. Notify
+	if target.group != nil {
+		uc.notifier.groupShared(ctx, calendarShareEvent(share, cal), target.group)
+	} else {
+		uc.notifier.invited(ctx, calendarShareEvent(share, cal), input.Summary)
+	}
+
+	// 7. Return output
+	share.SharedWith, share.SharedWithGroup = target.user, target.group
+	return newCalendarShareOutput(share, cal), nil
 }
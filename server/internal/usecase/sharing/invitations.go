@@ -0,0 +1,164 @@
+package sharing
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jherrma/caldav-server/internal/domain/addressbook"
+	"github.com/jherrma/caldav-server/internal/domain/calendar"
+	"github.com/jherrma/caldav-server/internal/domain/sharing"
+	"github.com/jherrma/caldav-server/internal/domain/user"
+)
+
+// ErrInvitationNotFound is returned for shares that are not shared
+// directly with the requesting user
+var ErrInvitationNotFound = errors.New("invitation not found")
+
+// InvitationOutput is a share with the requesting user as seen by them
+type InvitationOutput struct {
+	ID           string    `json:"id"`   // Share UUID
+	Type         string    `json:"type"` // "calendar" or "addressbook"
+	CollectionID string    `json:"collection_id"`
+	Name         string    `json:"name"`
+	Owner        *UserInfo `json:"owner"`
+	Permission   string    `json:"permission"`
+	Status       string    `json:"status"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// InvitationsUseCase lists the invitations of a user and records their
+// answers. Accepting makes the calendar or address book available;
+// declining an accepted share removes it again.
+type InvitationsUseCase struct {
+	calendarShareRepo    sharing.CalendarShareRepository
+	addressBookShareRepo sharing.AddressBookShareRepository
+	calendarRepo         calendar.CalendarRepository
+	addressBookRepo      addressbook.Repository
+	userRepo             user.UserRepository
+	notifier             *Notifier
+}
+
+func NewInvitationsUseCase(
+	calendarShareRepo sharing.CalendarShareRepository,
+	addressBookShareRepo sharing.AddressBookShareRepository,
+	calendarRepo calendar.CalendarRepository,
+	addressBookRepo addressbook.Repository,
+	userRepo user.UserRepository,
+	notifier *Notifier,
+) *InvitationsUseCase {
+	return &InvitationsUseCase{
+		calendarShareRepo:    calendarShareRepo,
+		addressBookShareRepo: addressBookShareRepo,
+		calendarRepo:         calendarRepo,
+		addressBookRepo:      addressBookRepo,
+		userRepo:             userRepo,
+		notifier:             notifier,
+	}
+}
+
+// ListPending returns the unanswered invitations of the user, calendars first
+func (uc *InvitationsUseCase) ListPending(ctx context.Context, userID uint) ([]InvitationOutput, error) {
+	calendarShares, err := uc.calendarShareRepo.ListPendingForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	addressBookShares, err := uc.addressBookShareRepo.ListPendingForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	output := make([]InvitationOutput, 0, len(calendarShares)+len(addressBookShares))
+	for i := range calendarShares {
+		s := &calendarShares[i]
+		output = append(output, calendarInvitation(s, &s.Calendar, &s.Calendar.Owner))
+	}
+	for i := range addressBookShares {
+		s := &addressBookShares[i]
+		output = append(output, addressBookInvitation(s, &s.AddressBook, &s.AddressBook.User))
+	}
+	return output, nil
+}
+
+// Respond accepts or declines the share with the given UUID
+func (uc *InvitationsUseCase) Respond(ctx context.Context, userID uint, shareUUID string, accept bool) (*InvitationOutput, error) {
+	status := sharing.ShareStatusDeclined
+	if accept {
+		status = sharing.ShareStatusAccepted
+	}
+
+	if share, err := uc.calendarShareRepo.GetByUUID(ctx, shareUUID); err == nil && share != nil && sharedDirectlyWith(share.SharedWithID, userID) {
+		cal, err := uc.calendarRepo.GetByID(ctx, share.CalendarID)
+		if err != nil || cal == nil {
+			return nil, ErrInvitationNotFound
+		}
+		owner, err := uc.userRepo.GetByID(ctx, cal.UserID)
+		if err != nil || owner == nil {
+			return nil, ErrInvitationNotFound
+		}
+		if share.Status != status {
+			share.Status = status
+			share.UpdatedAt = time.Now()
+			if err := uc.calendarShareRepo.Update(ctx, share); err != nil {
+				return nil, err
+			}
+			uc.notifier.replied(ctx, calendarShareEvent(share, cal), status)
+		}
+		output := calendarInvitation(share, cal, owner)
+		return &output, nil
+	}
+
+	if share, err := uc.addressBookShareRepo.GetByUUID(ctx, shareUUID); err == nil && share != nil && sharedDirectlyWith(share.SharedWithID, userID) {
+		ab, err := uc.addressBookRepo.GetByID(ctx, share.AddressBookID)
+		if err != nil || ab == nil {
+			return nil, ErrInvitationNotFound
+		}
+		owner, err := uc.userRepo.GetByID(ctx, ab.UserID)
+		if err != nil || owner == nil {
+			return nil, ErrInvitationNotFound
+		}
+		if share.Status != status {
+			share.Status = status
+			share.UpdatedAt = time.Now()
+			if err := uc.addressBookShareRepo.Update(ctx, share); err != nil {
+				return nil, err
+			}
+			uc.notifier.replied(ctx, addressBookShareEvent(share, ab), status)
+		}
+		output := addressBookInvitation(share, ab, owner)
+		return &output, nil
+	}
+
+	return nil, ErrInvitationNotFound
+}
+
+// sharedDirectlyWith reports whether a share's SharedWithID is the user
+func sharedDirectlyWith(sharedWithID *uint, userID uint) bool {
+	return sharedWithID != nil && *sharedWithID == userID
+}
+
+func calendarInvitation(share *sharing.CalendarShare, cal *calendar.Calendar, owner *user.User) InvitationOutput {
+	return InvitationOutput{
+		ID:           share.UUID,
+		Type:         CollectionCalendar,
+		CollectionID: cal.UUID,
+		Name:         cal.Name,
+		Owner:        toUserInfo(owner),
+		Permission:   share.Permission,
+		Status:       share.Status,
+		CreatedAt:    share.CreatedAt,
+	}
+}
+
+func addressBookInvitation(share *sharing.AddressBookShare, ab *addressbook.AddressBook, owner *user.User) InvitationOutput {
+	return InvitationOutput{
+		ID:           share.UUID,
+		Type:         CollectionAddressBook,
+		CollectionID: ab.UUID,
+		Name:         ab.Name,
+		Owner:        toUserInfo(owner),
+		Permission:   share.Permission,
+		Status:       share.Status,
+		CreatedAt:    share.CreatedAt,
+	}
+}
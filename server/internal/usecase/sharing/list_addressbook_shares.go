@@ -13,10 +13,12 @@ type ListAddressBookSharesOutput struct {
 }
 
 type AddressBookShareInfo struct {
-	ID         string   `json:"id"`
-	SharedWith UserInfo `json:"shared_with"`
-	Permission string   `json:"permission"`
-	CreatedAt  string   `json:"created_at"`
+	ID              string     `json:"id"`
+	SharedWith      *UserInfo  `json:"shared_with,omitempty"`
+	SharedWithGroup *GroupInfo `json:"shared_with_group,omitempty"`
+	Permission      string     `json:"permission"`
+	Status          string     `json:"status"`
+	CreatedAt       string     `json:"created_at"`
 }
 
 type ListAddressBookSharesUseCase struct {
@@ -56,15 +58,12 @@ func (uc *ListAddressBookSharesUseCase) Execute(ctx context.Context, requestingU
 	}
 	for _, s := range shares {
 		output.Shares = append(output.Shares, AddressBookShareInfo{
-			ID: s.UUID,
-			SharedWith: UserInfo{
-				ID:          s.SharedWith.UUID,
-				Username:    s.SharedWith.Username,
-				DisplayName: s.SharedWith.DisplayName,
-				Email:       s.SharedWith.Email,
-			},
-			Permission: s.Permission,
-			CreatedAt:  s.CreatedAt.Format("2006-01-02T15:04:05Z"),
+			ID:              s.UUID,
+			SharedWith:      toUserInfo(s.SharedWith),
+			SharedWithGroup: toGroupInfo(s.SharedWithGroup),
+			Permission:      s.Permission,
+			Status:          s.Status,
+			CreatedAt:       s.CreatedAt.Format("2006-01-02T15:04:05Z"),
 		})
 	}
 
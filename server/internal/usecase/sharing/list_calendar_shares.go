@@ -41,19 +41,8 @@ func (uc *ListCalendarSharesUseCase) Execute(ctx context.Context, requestingUser
 
 	// 3. Map to output
 	output := make([]CreateCalendarShareOutput, len(shares))
-	for i, share := range shares {
-		output[i] = CreateCalendarShareOutput{
-			ID:         share.UUID,
-			CalendarID: cal.UUID,
-			SharedWith: UserInfo{
-				ID:          share.SharedWith.UUID,
-				Username:    share.SharedWith.Username,
-				DisplayName: share.SharedWith.DisplayName,
-				Email:       share.SharedWith.Email,
-			},
-			Permission: share.Permission,
-			CreatedAt:  share.CreatedAt,
-		}
+	for i := range shares {
+		output[i] = *newCalendarShareOutput(&shares[i], cal)
 	}
 
 	return output, nil
@@ -0,0 +1,218 @@
+package sharing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jherrma/caldav-server/internal/domain/sharing"
+	"github.com/jherrma/caldav-server/internal/domain/user"
+	"github.com/jherrma/caldav-server/internal/usecase/auth"
+)
+
+// Collection kinds in invitations
+const (
+	CollectionCalendar    = "calendar"
+	CollectionAddressBook = "addressbook"
+)
+
+// shareEvent describes the share a notification is about
+type shareEvent struct {
+	shareUUID  string
+	collection string // CollectionCalendar or CollectionAddressBook
+	name       string
+	path       string // Calendar path in the owner's calendar home, calendars only
+	ownerID    uint
+	shareeID   uint
+	permission string
+}
+
+func (e shareEvent) label() string {
+	if e.collection == CollectionCalendar {
+		return fmt.Sprintf("the calendar %q", e.name)
+	}
+	return fmt.Sprintf("the address book %q", e.name)
+}
+
+// davPath returns the path of a shared calendar in its owner's calendar home
+func davPath(owner *user.User, calendarPath string) string {
+	return fmt.Sprintf("/dav/%s/calendars/%s/", owner.Username, calendarPath)
+}
+
+// Notifier tells users about share invitations, their answers and
+// revocations. Every event is sent by email; calendar events are also added
+// to the DAV notification collection so clients can show them natively. A
+// nil Notifier sends nothing.
+type Notifier struct {
+	notifications sharing.NotificationRepository
+	userRepo      user.UserRepository
+	emailService  auth.EmailService
+	baseURL       string
+}
+
+func NewNotifier(
+	notifications sharing.NotificationRepository,
+	userRepo user.UserRepository,
+	emailService auth.EmailService,
+	baseURL string,
+) *Notifier {
+	return &Notifier{
+		notifications: notifications,
+		userRepo:      userRepo,
+		emailService:  emailService,
+		baseURL:       baseURL,
+	}
+}
+
+// invited announces a new invitation to the sharee
+func (n *Notifier) invited(ctx context.Context, e shareEvent, summary string) {
+	if n == nil {
+		return
+	}
+	owner, sharee, ok := n.users(ctx, e)
+	if !ok {
+		return
+	}
+
+	n.notify(ctx, e, owner, sharee.ID, owner, sharing.NotificationInvite, sharing.ShareStatusPending, summary)
+
+	body := fmt.Sprintf(`Hi %s,
+
+%s invited you to %s (%s access).
+
+Accept or decline the invitation at %s or in your calendar app.
+%s
+- CalDAV Server`, displayName(sharee), displayName(owner), e.label(), e.permission, n.baseURL, quoteSummary(summary))
+	n.send(ctx, sharee.Email, fmt.Sprintf("%s shared %s with you", displayName(owner), e.label()), body)
+}
+
+// groupShared tells the members of a group about a share with the group,
+// which needs no answer
+func (n *Notifier) groupShared(ctx context.Context, e shareEvent, group *user.Group) {
+	if n == nil {
+		return
+	}
+	owner, err := n.userRepo.GetByID(ctx, e.ownerID)
+	if err != nil || owner == nil {
+		return
+	}
+
+	for _, m := range group.Members {
+		if m.UserID == owner.ID {
+			continue
+		}
+		body := fmt.Sprintf(`Hi %s,
+
+%s shared %s with the group %q (%s access). It is now available in your account.
+
+- CalDAV Server`, displayName(&m.User), displayName(owner), e.label(), group.Name, e.permission)
+		n.send(ctx, m.User.Email, fmt.Sprintf("%s shared %s with %s", displayName(owner), e.label(), group.Name), body)
+	}
+}
+
+// replied tells the owner that the sharee accepted or declined
+func (n *Notifier) replied(ctx context.Context, e shareEvent, status string) {
+	if n == nil {
+		return
+	}
+	owner, sharee, ok := n.users(ctx, e)
+	if !ok {
+		return
+	}
+
+	if e.collection == CollectionCalendar {
+		if err := n.notifications.DeleteByShare(ctx, sharee.ID, e.shareUUID); err != nil {
+			fmt.Printf("failed to remove share notification: %v\n", err)
+		}
+	}
+	n.notify(ctx, e, owner, owner.ID, sharee, sharing.NotificationInviteReply, status, "")
+
+	body := fmt.Sprintf(`Hi %s,
+
+%s %s your invitation to %s.
+
+- CalDAV Server`, displayName(owner), displayName(sharee), status, e.label())
+	n.send(ctx, owner.Email, fmt.Sprintf("%s %s %s", displayName(sharee), status, e.label()), body)
+}
+
+// revoked tells the sharee that a share was removed
+func (n *Notifier) revoked(ctx context.Context, e shareEvent) {
+	if n == nil {
+		return
+	}
+	owner, sharee, ok := n.users(ctx, e)
+	if !ok {
+		return
+	}
+
+	if e.collection == CollectionCalendar {
+		if err := n.notifications.DeleteByShare(ctx, sharee.ID, e.shareUUID); err != nil {
+			fmt.Printf("failed to remove share notification: %v\n", err)
+		}
+	}
+	n.notify(ctx, e, owner, sharee.ID, owner, sharing.NotificationInvite, sharing.NotificationStatusRevoked, "")
+
+	body := fmt.Sprintf(`Hi %s,
+
+%s stopped sharing %s with you.
+
+- CalDAV Server`, displayName(sharee), displayName(owner), e.label())
+	n.send(ctx, sharee.Email, fmt.Sprintf("%s stopped sharing %s", displayName(owner), e.label()), body)
+}
+
+func (n *Notifier) users(ctx context.Context, e shareEvent) (owner, sharee *user.User, ok bool) {
+	owner, err := n.userRepo.GetByID(ctx, e.ownerID)
+	if err != nil || owner == nil {
+		return nil, nil, false
+	}
+	sharee, err = n.userRepo.GetByID(ctx, e.shareeID)
+	if err != nil || sharee == nil {
+		return nil, nil, false
+	}
+	return owner, sharee, true
+}
+
+// notify adds a calendar notification to the recipient's collection
+func (n *Notifier) notify(ctx context.Context, e shareEvent, owner *user.User, recipientID uint, from *user.User, notificationType, status, summary string) {
+	if e.collection != CollectionCalendar || n.notifications == nil {
+		return
+	}
+	err := n.notifications.Create(ctx, &sharing.Notification{
+		UUID:         uuid.New().String(),
+		UserID:       recipientID,
+		Type:         notificationType,
+		ShareUUID:    e.shareUUID,
+		FromUserID:   from.ID,
+		CalendarName: e.name,
+		CalendarPath: davPath(owner, e.path),
+		Permission:   e.permission,
+		Status:       status,
+		Summary:      summary,
+	})
+	if err != nil {
+		fmt.Printf("failed to store share notification: %v\n", err)
+	}
+}
+
+func (n *Notifier) send(ctx context.Context, to, subject, body string) {
+	if n.emailService == nil || to == "" {
+		return
+	}
+	if err := n.emailService.SendEmail(ctx, to, subject, body); err != nil {
+		fmt.Printf("failed to send sharing notification: %v\n", err)
+	}
+}
+
+func displayName(u *user.User) string {
+	if u.DisplayName != "" {
+		return u.DisplayName
+	}
+	return u.Username
+}
+
+func quoteSummary(summary string) string {
+	if summary == "" {
+		return ""
+	}
+	return fmt.Sprintf("\nMessage: %s\n", summary)
+}
@@ -11,15 +11,18 @@ import (
 type RevokeAddressBookShareUseCase struct {
 	shareRepo       sharing.AddressBookShareRepository
 	addressBookRepo addressbook.Repository
+	notifier        *Notifier
 }
 
 func NewRevokeAddressBookShareUseCase(
 	shareRepo sharing.AddressBookShareRepository,
 	addressBookRepo addressbook.Repository,
+	notifier *Notifier,
 ) *RevokeAddressBookShareUseCase {
 	return &RevokeAddressBookShareUseCase{
 		shareRepo:       shareRepo,
 		addressBookRepo: addressBookRepo,
+		notifier:        notifier,
 	}
 }
 
@@ -43,5 +46,13 @@ func (uc *RevokeAddressBookShareUseCase) Execute(ctx context.Context, requesting
 	}
 
 	// 3. Revoke
-	return uc.shareRepo.Revoke(ctx, share.ID)
+	if err := uc.shareRepo.Revoke(ctx, share.ID); err != nil {
+		return err
+	}
+
+	// 4. Notify the sharee unless the invitation was declined
+	if share.SharedWithID != nil && share.Status != sharing.ShareStatusDeclined {
+		uc.notifier.revoked(ctx, addressBookShareEvent(share, ab))
+	}
+	return nil
 }
@@ -11,15 +11,18 @@ import (
 type RevokeCalendarShareUseCase struct {
 	shareRepo    sharing.CalendarShareRepository
 	calendarRepo calendar.CalendarRepository
+	notifier     *Notifier
 }
 
 func NewRevokeCalendarShareUseCase(
 	shareRepo sharing.CalendarShareRepository,
 	calendarRepo calendar.CalendarRepository,
+	notifier *Notifier,
 ) *RevokeCalendarShareUseCase {
 	return &RevokeCalendarShareUseCase{
 		shareRepo:    shareRepo,
 		calendarRepo: calendarRepo,
+		notifier:     notifier,
 	}
 }
 
@@ -45,5 +48,13 @@ func (uc *RevokeCalendarShareUseCase) Execute(ctx context.Context, requestingUse
 	}
 
 	// 4. Revoke (Delete)
-	return uc.shareRepo.Revoke(ctx, share.ID)
+	if err := uc.shareRepo.Revoke(ctx, share.ID); err != nil {
+		return err
+	}
+
+	// 5. Notify the sharee unless the invitation was declined
+	if share.SharedWithID != nil && share.Status != sharing.ShareStatusDeclined {
+		uc.notifier.revoked(ctx, calendarShareEvent(share, cal))
+	}
+	return nil
 }
@@ -0,0 +1,78 @@
+package sharing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jherrma/caldav-server/internal/domain/user"
+)
+
+// GroupInfo identifies the group a share targets
+type GroupInfo struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// shareTarget is the user or the group a share is created for
+type shareTarget struct {
+	user  *user.User
+	group *user.Group
+}
+
+// resolveShareTarget finds the recipient of a new share: a user by email or
+// username, or a group by its ID. Groups must be usable by the requesting
+// user (see user.Group.CanUse).
+func resolveShareTarget(ctx context.Context, userRepo user.UserRepository, groupRepo user.GroupRepository, requestingUserID uint, identifier, groupID string) (*shareTarget, error) {
+	if (identifier == "") == (groupID == "") {
+		return nil, fmt.Errorf("either user_identifier or group_id is required")
+	}
+
+	if groupID != "" {
+		group, err := groupRepo.GetByUUID(ctx, groupID)
+		if err != nil || group == nil {
+			return nil, fmt.Errorf("group '%s' not found", groupID)
+		}
+		requester, err := userRepo.GetByID(ctx, requestingUserID)
+		if err != nil || requester == nil || !group.CanUse(requester) {
+			return nil, fmt.Errorf("group '%s' not found", groupID)
+		}
+		return &shareTarget{group: group}, nil
+	}
+
+	targetUser, err := userRepo.GetByEmail(ctx, identifier)
+	if err != nil || targetUser == nil {
+		// Try by username
+		targetUser, err = userRepo.GetByUsername(ctx, identifier)
+	}
+	if err != nil || targetUser == nil {
+		return nil, fmt.Errorf("user '%s' not found", identifier)
+	}
+	return &shareTarget{user: targetUser}, nil
+}
+
+// ids returns the share columns of the target
+func (t *shareTarget) ids() (userID, groupID *uint) {
+	if t.group != nil {
+		return nil, &t.group.ID
+	}
+	return &t.user.ID, nil
+}
+
+func toUserInfo(u *user.User) *UserInfo {
+	if u == nil {
+		return nil
+	}
+	return &UserInfo{
+		ID:          u.UUID,
+		Username:    u.Username,
+		DisplayName: u.DisplayName,
+		Email:       u.Email,
+	}
+}
+
+func toGroupInfo(g *user.Group) *GroupInfo {
+	if g == nil {
+		return nil
+	}
+	return &GroupInfo{ID: g.UUID, Name: g.Name}
+}
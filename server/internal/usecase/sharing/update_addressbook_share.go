@@ -47,7 +47,7 @@ func (uc *UpdateAddressBookShareUseCase) Execute(ctx context.Context, requesting
 	}
 
 	// 3. Validate permission
-	if input.Permission != "read" && input.Permission != "read-write" {
+	if !sharing.ValidPermission(input.Permission) {
 		return nil, fmt.Errorf("invalid permission")
 	}
 
@@ -57,16 +57,5 @@ func (uc *UpdateAddressBookShareUseCase) Execute(ctx context.Context, requesting
 		return nil, err
 	}
 
-	return &CreateAddressBookShareOutput{
-		ID:            share.UUID,
-		AddressBookID: ab.UUID,
-		SharedWith: UserInfo{
-			ID:          share.SharedWith.UUID,
-			Username:    share.SharedWith.Username,
-			DisplayName: share.SharedWith.DisplayName,
-			Email:       share.SharedWith.Email,
-		},
-		Permission: share.Permission,
-		CreatedAt:  share.CreatedAt,
-	}, nil
+	return newAddressBookShareOutput(share, ab), nil
 }
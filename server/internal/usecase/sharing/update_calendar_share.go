@@ -50,7 +50,7 @@ func (uc *UpdateCalendarShareUseCase) Execute(ctx context.Context, requestingUse
 	}
 
 	// 4. Validate permission
-	if input.Permission != "read" && input.Permission != "read-write" {
+	if !sharing.ValidPermission(input.Permission) {
 		return nil, fmt.Errorf("invalid permission")
 	}
 
@@ -63,16 +63,5 @@ func (uc *UpdateCalendarShareUseCase) Execute(ctx context.Context, requestingUse
 	}
 
 	// 6. Return output
-	return &CreateCalendarShareOutput{
-		ID:         share.UUID,
-		CalendarID: cal.UUID,
-		SharedWith: UserInfo{
-			ID:          share.SharedWith.UUID,
-			Username:    share.SharedWith.Username,
-			DisplayName: share.SharedWith.DisplayName,
-			Email:       share.SharedWith.Email,
-		},
-		Permission: share.Permission,
-		CreatedAt:  share.CreatedAt,
-	}, nil
+	return newCalendarShareOutput(share, cal), nil
 }
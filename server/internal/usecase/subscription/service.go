@@ -0,0 +1,538 @@
+package subscription
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/emersion/go-ical"
+	"github.com/google/uuid"
+	"github.com/jherrma/caldav-server/internal/config"
+	"github.com/jherrma/caldav-server/internal/domain/calendar"
+)
+
+var (
+	// ErrNotFound is returned for unknown subscriptions and those of other users
+	ErrNotFound = errors.New("subscription not found")
+	// ErrInvalidSubscription wraps validation failures of Create and Update
+	ErrInvalidSubscription = errors.New("invalid subscription")
+	// ErrLimitReached is returned by Create when the user has as many
+	// subscriptions as allowed
+	ErrLimitReached = errors.New("subscription limit reached")
+)
+
+// dueBatchSize is how many due feeds RefreshDue fetches per call
+const dueBatchSize = 50
+
+// CreateInput subscribes to a feed. Without a name the calendar is named
+// after the feed's X-WR-CALNAME, or its host; without a refresh interval the
+// configured default applies.
+type CreateInput struct {
+	URL            string `json:"url"`
+	Name           string `json:"name"`
+	Color          string `json:"color"`
+	RefreshMinutes int    `json:"refresh_minutes"`
+}
+
+// UpdateInput changes a subscription; nil fields are left unchanged. A new
+// URL is fetched right away.
+type UpdateInput struct {
+	URL            *string `json:"url"`
+	Name           *string `json:"name"`
+	Color          *string `json:"color"`
+	RefreshMinutes *int    `json:"refresh_minutes"`
+}
+
+// Output describes a subscription, its calendar and the state of its fetches
+type Output struct {
+	ID             uint       `json:"id"`
+	CalendarID     uint       `json:"calendar_id"`
+	CalendarUUID   string     `json:"calendar_uuid"`
+	Name           string     `json:"name"`
+	Color          string     `json:"color"`
+	URL            string     `json:"url"`
+	RefreshMinutes int        `json:"refresh_minutes"`
+	EventCount     int64      `json:"event_count"`
+	LastFetchedAt  *time.Time `json:"last_fetched_at"`
+	LastSuccessAt  *time.Time `json:"last_success_at"`
+	NextFetchAt    time.Time  `json:"next_fetch_at"`
+	LastError      string     `json:"last_error,omitempty"`
+	FailureCount   int        `json:"failure_count"`
+}
+
+// Service manages feed subscriptions and mirrors every feed into the
+// read-only calendar of its subscription. Feeds are fetched when they are
+// created or their URL changes, on request, and by Run whenever their
+// refresh interval elapsed.
+type Service struct {
+	subs           calendar.SubscriptionRepository
+	calendarRepo   calendar.CalendarRepository
+	fetcher        calendar.FeedFetcher
+	defaultRefresh time.Duration
+	minRefresh     time.Duration
+	maxPerUser     int      // 0 for no limit
+	locks          sync.Map // Subscription ID -> *sync.Mutex held while it is changed or its feed is refreshed
+}
+
+func NewService(subs calendar.SubscriptionRepository, calendarRepo calendar.CalendarRepository, fetcher calendar.FeedFetcher, cfg config.SubscriptionsConfig) *Service {
+	return &Service{
+		subs:           subs,
+		calendarRepo:   calendarRepo,
+		fetcher:        fetcher,
+		defaultRefresh: cfg.DefaultRefresh,
+		minRefresh:     cfg.MinRefresh,
+		maxPerUser:     cfg.MaxPerUser,
+	}
+}
+
+func (s *Service) refreshInterval(minutes int) (time.Duration, error) {
+	if minutes == 0 {
+		return s.defaultRefresh, nil
+	}
+	interval := time.Duration(minutes) * time.Minute
+	if interval < s.minRefresh || interval > calendar.MaxSubscriptionBackoff*30 {
+		return 0, fmt.Errorf("%w: refresh_minutes must be between %d and %d", ErrInvalidSubscription,
+			int(s.minRefresh/time.Minute), int(calendar.MaxSubscriptionBackoff*30/time.Minute))
+	}
+	return interval, nil
+}
+
+// Create subscribes a user to a feed and fetches it. A failing first fetch
+// does not undo the subscription; the error is reported in the output.
+func (s *Service) Create(ctx context.Context, userID uint, input CreateInput) (*Output, error) {
+	feedURL, err := calendar.NormalizeSubscriptionURL(input.URL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSubscription, err)
+	}
+	interval, err := s.refreshInterval(input.RefreshMinutes)
+	if err != nil {
+		return nil, err
+	}
+	name := strings.TrimSpace(input.Name)
+	if name != "" {
+		if err := calendar.ValidateName(name); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidSubscription, err)
+		}
+	}
+	color := input.Color
+	if color == "" {
+		color = calendar.GenerateRandomColor()
+	} else if err := calendar.ValidateHexColor(color); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSubscription, err)
+	}
+	if s.maxPerUser > 0 {
+		existing, err := s.subs.ListByUserID(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if len(existing) >= s.maxPerUser {
+			return nil, fmt.Errorf("%w: at most %d subscriptions per user", ErrLimitReached, s.maxPerUser)
+		}
+	}
+
+	calUUID := uuid.New().String()
+	cal := &calendar.Calendar{
+		UUID:                calUUID,
+		UserID:              userID,
+		Path:                fmt.Sprintf("%s.ics", calUUID),
+		Name:                name,
+		Color:               color,
+		Timezone:            "UTC",
+		SupportedComponents: "VEVENT,VTODO",
+		SyncToken:           calendar.GenerateSyncToken(),
+		CTag:                calendar.GenerateCTag(),
+		ReadOnly:            true,
+	}
+	if name == "" {
+		u, _ := url.Parse(feedURL)
+		cal.Name = u.Hostname()
+	}
+	if err := s.calendarRepo.Create(ctx, cal); err != nil {
+		return nil, fmt.Errorf("failed to create calendar: %w", err)
+	}
+
+	sub := &calendar.Subscription{
+		UserID:          userID,
+		CalendarID:      cal.ID,
+		Calendar:        *cal,
+		URL:             feedURL,
+		RefreshInterval: interval,
+		NextFetchAt:     time.Now(),
+	}
+	if err := s.subs.Create(ctx, sub); err != nil {
+		_ = s.calendarRepo.Delete(ctx, cal.ID)
+		return nil, fmt.Errorf("failed to create subscription: %w", err)
+	}
+
+	unlock := s.lock(sub.ID)
+	defer unlock()
+	if err := s.refresh(ctx, sub, name == ""); err != nil {
+		return nil, err
+	}
+	return s.output(ctx, sub), nil
+}
+
+// List returns the subscriptions of a user
+func (s *Service) List(ctx context.Context, userID uint) ([]*Output, error) {
+	subs, err := s.subs.ListByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*Output, 0, len(subs))
+	for _, sub := range subs {
+		if sub.Calendar.ID == 0 {
+			continue // The calendar was deleted; RefreshDue removes the subscription
+		}
+		result = append(result, s.output(ctx, sub))
+	}
+	return result, nil
+}
+
+// Get returns a subscription of a user
+func (s *Service) Get(ctx context.Context, userID, id uint) (*Output, error) {
+	sub, err := s.get(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.output(ctx, sub), nil
+}
+
+func (s *Service) get(ctx context.Context, userID, id uint) (*calendar.Subscription, error) {
+	sub, err := s.subs.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if sub == nil || sub.UserID != userID || sub.Calendar.ID == 0 {
+		return nil, ErrNotFound
+	}
+	return sub, nil
+}
+
+// Update changes the URL, refresh interval, name or color of a subscription
+func (s *Service) Update(ctx context.Context, userID, id uint, input UpdateInput) (*Output, error) {
+	unlock := s.lock(id)
+	defer unlock()
+	sub, err := s.get(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	cal := &sub.Calendar
+	if input.Name != nil {
+		if err := calendar.ValidateName(*input.Name); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidSubscription, err)
+		}
+		cal.Name = *input.Name
+	}
+	if input.Color != nil {
+		if err := calendar.ValidateHexColor(*input.Color); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidSubscription, err)
+		}
+		cal.Color = *input.Color
+	}
+	if input.Name != nil || input.Color != nil {
+		if err := s.calendarRepo.Update(ctx, cal); err != nil {
+			return nil, err
+		}
+	}
+
+	if input.RefreshMinutes != nil {
+		interval, err := s.refreshInterval(*input.RefreshMinutes)
+		if err != nil {
+			return nil, err
+		}
+		sub.NextFetchAt = sub.NextFetchAt.Add(interval - sub.RefreshInterval)
+		sub.RefreshInterval = interval
+	}
+	urlChanged := false
+	if input.URL != nil {
+		feedURL, err := calendar.NormalizeSubscriptionURL(*input.URL)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidSubscription, err)
+		}
+		if feedURL != sub.URL {
+			sub.URL, sub.ETag, sub.LastModified = feedURL, "", ""
+			urlChanged = true
+		}
+	}
+	if err := s.subs.Update(ctx, sub); err != nil {
+		return nil, err
+	}
+
+	if urlChanged {
+		if err := s.refresh(ctx, sub, false); err != nil {
+			return nil, err
+		}
+	}
+	return s.output(ctx, sub), nil
+}
+
+// Delete ends a subscription and deletes its calendar
+func (s *Service) Delete(ctx context.Context, userID, id uint) error {
+	unlock := s.lock(id)
+	defer unlock()
+	sub, err := s.get(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if err := s.subs.Delete(ctx, sub.ID); err != nil {
+		return err
+	}
+	s.locks.Delete(sub.ID)
+	return s.calendarRepo.Delete(ctx, sub.CalendarID)
+}
+
+// Refresh fetches a subscription's feed now
+func (s *Service) Refresh(ctx context.Context, userID, id uint) (*Output, error) {
+	unlock := s.lock(id)
+	defer unlock()
+	sub, err := s.get(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.refresh(ctx, sub, false); err != nil {
+		return nil, err
+	}
+	return s.output(ctx, sub), nil
+}
+
+// RefreshDue fetches the feeds whose refresh interval elapsed and returns
+// how many it fetched. Subscriptions whose calendar was deleted are removed.
+func (s *Service) RefreshDue(ctx context.Context) (int, error) {
+	subs, err := s.subs.ListDue(ctx, time.Now(), dueBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	fetched := 0
+	for _, sub := range subs {
+		ok, err := s.refreshDue(ctx, sub.ID)
+		if err != nil {
+			return fetched, err
+		}
+		if ok {
+			fetched++
+		}
+	}
+	return fetched, nil
+}
+
+// refreshDue refreshes a subscription listed by RefreshDue and reports
+// whether its feed was fetched. The subscription is loaded again once its
+// lock is held, as it may have been changed, refreshed or deleted since it
+// was listed.
+func (s *Service) refreshDue(ctx context.Context, id uint) (bool, error) {
+	unlock := s.lock(id)
+	defer unlock()
+	sub, err := s.subs.GetByID(ctx, id)
+	if err != nil || sub == nil || sub.NextFetchAt.After(time.Now()) {
+		return false, err
+	}
+	if sub.Calendar.ID == 0 {
+		if err := s.subs.Delete(ctx, sub.ID); err != nil {
+			return false, err
+		}
+		s.locks.Delete(sub.ID)
+		return false, nil
+	}
+	return true, s.refresh(ctx, sub, false)
+}
+
+// Run calls RefreshDue every interval until ctx is done
+func (s *Service) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if n, err := s.RefreshDue(ctx); err != nil {
+			slog.Error("failed to refresh calendar subscriptions", "error", err)
+		} else if n > 0 {
+			slog.Debug("refreshed calendar subscriptions", "feeds", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// lock takes the lock of a subscription and returns the function releasing
+// it. Changes, refreshes and the deletion of the same subscription take
+// turns, so none of them saves a stale copy over another's and its calendar
+// is never written twice at once; other feeds are fetched meanwhile.
+func (s *Service) lock(id uint) func() {
+	mu, _ := s.locks.LoadOrStore(id, &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+	return mu.(*sync.Mutex).Unlock
+}
+
+// refresh fetches the feed of a subscription and mirrors it into the
+// calendar. Fetch and feed errors are recorded on the subscription; only
+// storage errors are returned. With rename the calendar takes the feed's
+// X-WR-CALNAME. The caller holds the subscription's lock.
+func (s *Service) refresh(ctx context.Context, sub *calendar.Subscription, rename bool) error {
+	resp, err := s.fetcher.Fetch(ctx, calendar.FeedRequest{URL: sub.URL, ETag: sub.ETag, LastModified: sub.LastModified})
+	if err == nil && !resp.NotModified {
+		err = s.apply(ctx, sub, resp.Data, rename)
+	}
+	now := time.Now()
+	if err != nil {
+		sub.Failed(now, err)
+	} else {
+		sub.ETag, sub.LastModified = resp.ETag, resp.LastModified
+		sub.Succeeded(now)
+	}
+	return s.subs.Update(ctx, sub)
+}
+
+// apply replaces the objects of the subscription's calendar with those of
+// the feed, writing only the objects that changed so the calendar's sync
+// token moves exactly when the feed does
+func (s *Service) apply(ctx context.Context, sub *calendar.Subscription, data []byte, rename bool) error {
+	feed, err := ical.NewDecoder(bytes.NewReader(data)).Decode()
+	if err != nil {
+		return fmt.Errorf("invalid iCalendar feed: %w", err)
+	}
+
+	existing, err := s.calendarRepo.GetCalendarObjects(ctx, sub.CalendarID)
+	if err != nil {
+		return err
+	}
+	byUID := make(map[string]*calendar.CalendarObject, len(existing))
+	for _, obj := range existing {
+		byUID[obj.UID] = obj
+	}
+
+	objects := calendar.SplitFeed(feed)
+	seen := make(map[string]bool, len(objects))
+	skipped := 0
+	for _, fo := range objects {
+		var b strings.Builder
+		if err := ical.NewEncoder(&b).Encode(fo.Data); err != nil {
+			skipped++
+			continue
+		}
+		seen[fo.UID] = true
+		icalData := b.String()
+
+		obj := byUID[fo.UID]
+		if obj != nil && obj.ICalData == icalData {
+			continue
+		}
+		if obj == nil {
+			objUUID := uuid.New().String()
+			obj = &calendar.CalendarObject{
+				UUID:       objUUID,
+				CalendarID: sub.CalendarID,
+				Path:       fmt.Sprintf("%s.ics", objUUID),
+				UID:        fo.UID,
+			}
+		}
+		obj.ETag = fmt.Sprintf("\"%s\"", calendar.GenerateSyncToken())
+		obj.ComponentType = fo.ComponentType
+		obj.ICalData = icalData
+		obj.ContentLength = len(icalData)
+		setDenormalizedFields(obj, fo.Data)
+		if obj.ID == 0 {
+			err = s.calendarRepo.CreateCalendarObject(ctx, obj)
+		} else {
+			err = s.calendarRepo.UpdateCalendarObject(ctx, obj)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if skipped > 0 && skipped == len(objects) {
+		return fmt.Errorf("invalid iCalendar feed: none of its %d components could be stored", skipped)
+	}
+	for uid, obj := range byUID {
+		if !seen[uid] {
+			if err := s.calendarRepo.DeleteCalendarObject(ctx, obj); err != nil {
+				return err
+			}
+		}
+	}
+
+	if p := feed.Props.Get("X-WR-CALNAME"); rename && p != nil && strings.TrimSpace(p.Value) != "" {
+		cal, err := s.calendarRepo.GetByID(ctx, sub.CalendarID)
+		if err != nil || cal == nil {
+			return err
+		}
+		cal.Name = strings.TrimSpace(p.Value)
+		if err := s.calendarRepo.Update(ctx, cal); err != nil {
+			return err
+		}
+		sub.Calendar.Name = cal.Name
+	}
+	return nil
+}
+
+// setDenormalizedFields fills the summary, location and times of an object
+// from its master component, or its first one for a series of overrides
+func setDenormalizedFields(obj *calendar.CalendarObject, data *ical.Calendar) {
+	var comp *ical.Component
+	for _, c := range data.Children {
+		if c.Name == ical.CompTimezone {
+			continue
+		}
+		if comp == nil || (comp.Props.Get(ical.PropRecurrenceID) != nil && c.Props.Get(ical.PropRecurrenceID) == nil) {
+			comp = c
+		}
+	}
+	if comp == nil {
+		return
+	}
+
+	text := func(name string) string {
+		if p := comp.Props.Get(name); p != nil {
+			return p.Value
+		}
+		return ""
+	}
+	obj.Summary, obj.Description, obj.Location = text(ical.PropSummary), text(ical.PropDescription), text(ical.PropLocation)
+	obj.StartTime, obj.EndTime, obj.IsAllDay = nil, nil, false
+	if p := comp.Props.Get(ical.PropDateTimeStart); p != nil {
+		if t, err := p.DateTime(time.UTC); err == nil {
+			obj.StartTime = &t
+			obj.IsAllDay = p.ValueType() == ical.ValueDate
+		}
+	}
+	if p := comp.Props.Get(ical.PropDateTimeEnd); p != nil {
+		if t, err := p.DateTime(time.UTC); err == nil {
+			obj.EndTime = &t
+		}
+	} else if p := comp.Props.Get(ical.PropDue); p != nil {
+		if t, err := p.DateTime(time.UTC); err == nil {
+			obj.EndTime = &t
+		}
+	} else if p := comp.Props.Get(ical.PropDuration); p != nil && obj.StartTime != nil {
+		if d, err := p.Duration(); err == nil {
+			end := obj.StartTime.Add(d)
+			obj.EndTime = &end
+		}
+	}
+}
+
+func (s *Service) output(ctx context.Context, sub *calendar.Subscription) *Output {
+	count, _ := s.calendarRepo.GetEventCount(ctx, sub.CalendarID)
+	return &Output{
+		ID:             sub.ID,
+		CalendarID:     sub.CalendarID,
+		CalendarUUID:   sub.Calendar.UUID,
+		Name:           sub.Calendar.Name,
+		Color:          sub.Calendar.Color,
+		URL:            sub.URL,
+		RefreshMinutes: int(sub.RefreshInterval / time.Minute),
+		EventCount:     count,
+		LastFetchedAt:  sub.LastFetchedAt,
+		LastSuccessAt:  sub.LastSuccessAt,
+		NextFetchAt:    sub.NextFetchAt,
+		LastError:      sub.LastError,
+		FailureCount:   sub.FailureCount,
+	}
+}
@@ -0,0 +1,364 @@
+package subscription_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jherrma/caldav-server/internal/adapter/repository"
+	"github.com/jherrma/caldav-server/internal/config"
+	"github.com/jherrma/caldav-server/internal/domain/calendar"
+	"github.com/jherrma/caldav-server/internal/domain/user"
+	"github.com/jherrma/caldav-server/internal/usecase/subscription"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// fakeFetcher serves feeds from memory and answers conditional requests
+// with NotModified while a feed is unchanged
+type fakeFetcher struct {
+	mu       sync.Mutex
+	feeds    map[string]string        // URL -> feed; missing URLs fail
+	blocked  map[string]chan struct{} // URL -> closed to let its fetch finish
+	requests []calendar.FeedRequest
+}
+
+func (f *fakeFetcher) setFeed(url, events string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.feeds[url] = "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//Feed//EN\r\nX-WR-CALNAME:Holidays\r\n" + events + "END:VCALENDAR\r\n"
+}
+
+func (f *fakeFetcher) Fetch(ctx context.Context, req calendar.FeedRequest) (*calendar.FeedResponse, error) {
+	f.mu.Lock()
+	f.requests = append(f.requests, req)
+	data, ok := f.feeds[req.URL]
+	wait := f.blocked[req.URL]
+	f.mu.Unlock()
+
+	if wait != nil {
+		<-wait
+	}
+	if !ok {
+		return nil, errors.New("feed returned 404 Not Found")
+	}
+	etag := fmt.Sprintf(`"%x"`, sha256.Sum256([]byte(data)))
+	if req.ETag == etag {
+		return &calendar.FeedResponse{NotModified: true, ETag: etag}, nil
+	}
+	return &calendar.FeedResponse{Data: []byte(data), ETag: etag}, nil
+}
+
+func (f *fakeFetcher) lastRequest() calendar.FeedRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests[len(f.requests)-1]
+}
+
+type fixture struct {
+	svc          *subscription.Service
+	fetcher      *fakeFetcher
+	subs         calendar.SubscriptionRepository
+	calendarRepo *repository.CalendarRepository
+	userID       uint
+}
+
+func setupService(t *testing.T, maxPerUser int) *fixture {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&user.User{}, &calendar.Calendar{}, &calendar.CalendarObject{},
+		&calendar.SyncChangeLog{}, &calendar.Subscription{}))
+
+	u := &user.User{UUID: "owner-uuid", Email: "owner@example.com", Username: "owner", PasswordHash: "hash", IsActive: true}
+	require.NoError(t, repository.NewUserRepository(db).Create(context.Background(), u))
+
+	fetcher := &fakeFetcher{feeds: map[string]string{}, blocked: map[string]chan struct{}{}}
+	subs := repository.NewSubscriptionRepository(db)
+	calendarRepo := repository.NewCalendarRepository(db)
+	cfg := config.SubscriptionsConfig{DefaultRefresh: time.Hour, MinRefresh: 15 * time.Minute, MaxPerUser: maxPerUser}
+	return &fixture{
+		svc:          subscription.NewService(subs, calendarRepo, fetcher, cfg),
+		fetcher:      fetcher,
+		subs:         subs,
+		calendarRepo: calendarRepo,
+		userID:       u.ID,
+	}
+}
+
+func (f *fixture) objects(t *testing.T, calendarID uint) map[string]*calendar.CalendarObject {
+	t.Helper()
+	objs, err := f.calendarRepo.GetCalendarObjects(context.Background(), calendarID)
+	require.NoError(t, err)
+	byUID := make(map[string]*calendar.CalendarObject, len(objs))
+	for _, obj := range objs {
+		byUID[obj.UID] = obj
+	}
+	return byUID
+}
+
+func event(uid, summary string) string {
+	return "BEGIN:VEVENT\r\nUID:" + uid + "\r\nDTSTAMP:20260101T000000Z\r\nDTSTART;VALUE=DATE:20261225\r\nSUMMARY:" + summary + "\r\nEND:VEVENT\r\n"
+}
+
+func TestService_Create(t *testing.T) {
+	f := setupService(t, 0)
+	ctx := context.Background()
+	f.fetcher.setFeed("https://feeds.example.com/holidays.ics", event("xmas", "Christmas")+event("boxing", "Boxing Day"))
+
+	out, err := f.svc.Create(ctx, f.userID, subscription.CreateInput{URL: "webcals://feeds.example.com/holidays.ics"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://feeds.example.com/holidays.ics", out.URL)
+	assert.Equal(t, "Holidays", out.Name)
+	assert.Equal(t, 60, out.RefreshMinutes)
+	assert.Equal(t, int64(2), out.EventCount)
+	assert.Empty(t, out.LastError)
+	require.NotNil(t, out.LastSuccessAt)
+
+	// The feed is mirrored into a read-only calendar
+	cal, err := f.calendarRepo.GetByID(ctx, out.CalendarID)
+	require.NoError(t, err)
+	assert.True(t, cal.ReadOnly)
+	objs := f.objects(t, out.CalendarID)
+	require.Len(t, objs, 2)
+	assert.Equal(t, "Christmas", objs["xmas"].Summary)
+	assert.True(t, objs["xmas"].IsAllDay)
+
+	t.Run("Invalid Input", func(t *testing.T) {
+		for _, input := range []subscription.CreateInput{
+			{URL: "ftp://feeds.example.com/holidays.ics"},
+			{URL: "https://feeds.example.com/holidays.ics", RefreshMinutes: 5},
+			{URL: "https://feeds.example.com/holidays.ics", Color: "red"},
+		} {
+			_, err := f.svc.Create(ctx, f.userID, input)
+			assert.ErrorIs(t, err, subscription.ErrInvalidSubscription, input)
+		}
+	})
+
+	t.Run("Failing First Fetch", func(t *testing.T) {
+		out, err := f.svc.Create(ctx, f.userID, subscription.CreateInput{URL: "https://feeds.example.com/missing.ics", Name: "Missing"})
+		require.NoError(t, err)
+		assert.Equal(t, "Missing", out.Name)
+		assert.Contains(t, out.LastError, "404")
+		assert.Equal(t, 1, out.FailureCount)
+		assert.Nil(t, out.LastSuccessAt)
+	})
+}
+
+func TestService_Create_Limit(t *testing.T) {
+	f := setupService(t, 1)
+	ctx := context.Background()
+	f.fetcher.setFeed("https://feeds.example.com/holidays.ics", event("xmas", "Christmas"))
+
+	first, err := f.svc.Create(ctx, f.userID, subscription.CreateInput{URL: "https://feeds.example.com/holidays.ics"})
+	require.NoError(t, err)
+	_, err = f.svc.Create(ctx, f.userID, subscription.CreateInput{URL: "https://feeds.example.com/other.ics"})
+	assert.ErrorIs(t, err, subscription.ErrLimitReached)
+
+	// Deleting one makes room again
+	require.NoError(t, f.svc.Delete(ctx, f.userID, first.ID))
+	_, err = f.svc.Create(ctx, f.userID, subscription.CreateInput{URL: "https://feeds.example.com/other.ics"})
+	require.NoError(t, err)
+}
+
+func TestService_Refresh(t *testing.T) {
+	f := setupService(t, 0)
+	ctx := context.Background()
+	url := "https://feeds.example.com/holidays.ics"
+	f.fetcher.setFeed(url, event("xmas", "Christmas")+event("boxing", "Boxing Day"))
+
+	out, err := f.svc.Create(ctx, f.userID, subscription.CreateInput{URL: url})
+	require.NoError(t, err)
+	before := f.objects(t, out.CalendarID)
+	cal, err := f.calendarRepo.GetByID(ctx, out.CalendarID)
+	require.NoError(t, err)
+	token := cal.SyncToken
+
+	t.Run("Not Modified", func(t *testing.T) {
+		out, err := f.svc.Refresh(ctx, f.userID, out.ID)
+		require.NoError(t, err)
+		assert.Empty(t, out.LastError)
+
+		// The stored validator is sent and nothing is written
+		assert.NotEmpty(t, f.fetcher.lastRequest().ETag)
+		cal, err := f.calendarRepo.GetByID(ctx, out.CalendarID)
+		require.NoError(t, err)
+		assert.Equal(t, token, cal.SyncToken)
+	})
+
+	t.Run("Changed Feed", func(t *testing.T) {
+		f.fetcher.setFeed(url, event("xmas", "Christmas Day")+event("newyear", "New Year"))
+		out, err := f.svc.Refresh(ctx, f.userID, out.ID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), out.EventCount)
+
+		after := f.objects(t, out.CalendarID)
+		require.Len(t, after, 2)
+		assert.Equal(t, "Christmas Day", after["xmas"].Summary)
+		assert.Equal(t, before["xmas"].UUID, after["xmas"].UUID)
+		assert.NotEqual(t, before["xmas"].ETag, after["xmas"].ETag)
+		assert.Equal(t, "New Year", after["newyear"].Summary)
+		assert.Nil(t, after["boxing"])
+
+		cal, err := f.calendarRepo.GetByID(ctx, out.CalendarID)
+		require.NoError(t, err)
+		assert.NotEqual(t, token, cal.SyncToken)
+	})
+
+	t.Run("Failing Fetch", func(t *testing.T) {
+		f.fetcher.mu.Lock()
+		delete(f.fetcher.feeds, url)
+		f.fetcher.mu.Unlock()
+
+		out, err := f.svc.Refresh(ctx, f.userID, out.ID)
+		require.NoError(t, err)
+		assert.Contains(t, out.LastError, "404")
+		assert.Equal(t, 1, out.FailureCount)
+		// The last good copy stays
+		assert.Len(t, f.objects(t, out.CalendarID), 2)
+	})
+
+	t.Run("Other Users", func(t *testing.T) {
+		_, err := f.svc.Refresh(ctx, f.userID+1, out.ID)
+		assert.ErrorIs(t, err, subscription.ErrNotFound)
+	})
+}
+
+func TestService_RefreshDue(t *testing.T) {
+	f := setupService(t, 0)
+	ctx := context.Background()
+	f.fetcher.setFeed("https://feeds.example.com/a.ics", event("a", "A"))
+	f.fetcher.setFeed("https://feeds.example.com/b.ics", event("b", "B"))
+
+	a, err := f.svc.Create(ctx, f.userID, subscription.CreateInput{URL: "https://feeds.example.com/a.ics"})
+	require.NoError(t, err)
+	b, err := f.svc.Create(ctx, f.userID, subscription.CreateInput{URL: "https://feeds.example.com/b.ics"})
+	require.NoError(t, err)
+
+	// Both were just fetched, so nothing is due
+	n, err := f.svc.RefreshDue(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	for _, id := range []uint{a.ID, b.ID} {
+		sub, err := f.subs.GetByID(ctx, id)
+		require.NoError(t, err)
+		sub.NextFetchAt = time.Now().Add(-time.Minute)
+		require.NoError(t, f.subs.Update(ctx, sub))
+	}
+	// A deleted calendar ends its subscription on the next poll
+	require.NoError(t, f.calendarRepo.Delete(ctx, a.CalendarID))
+	f.fetcher.setFeed("https://feeds.example.com/b.ics", event("b", "B")+event("c", "C"))
+
+	n, err = f.svc.RefreshDue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	gone, err := f.subs.GetByID(ctx, a.ID)
+	require.NoError(t, err)
+	assert.Nil(t, gone)
+	assert.Len(t, f.objects(t, b.CalendarID), 2)
+
+	polled, err := f.svc.Get(ctx, f.userID, b.ID)
+	require.NoError(t, err)
+	assert.True(t, polled.NextFetchAt.After(time.Now()))
+}
+
+func TestService_RefreshLocksPerSubscription(t *testing.T) {
+	f := setupService(t, 0)
+	ctx := context.Background()
+	f.fetcher.setFeed("https://feeds.example.com/slow.ics", event("slow", "Slow"))
+	f.fetcher.setFeed("https://feeds.example.com/fast.ics", event("fast", "Fast"))
+
+	slow, err := f.svc.Create(ctx, f.userID, subscription.CreateInput{URL: "https://feeds.example.com/slow.ics"})
+	require.NoError(t, err)
+	fast, err := f.svc.Create(ctx, f.userID, subscription.CreateInput{URL: "https://feeds.example.com/fast.ics"})
+	require.NoError(t, err)
+
+	release := make(chan struct{})
+	f.fetcher.mu.Lock()
+	f.fetcher.blocked["https://feeds.example.com/slow.ics"] = release
+	f.fetcher.mu.Unlock()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := f.svc.Refresh(ctx, f.userID, slow.ID)
+		done <- err
+	}()
+	require.Eventually(t, func() bool {
+		return strings.HasSuffix(f.fetcher.lastRequest().URL, "slow.ics")
+	}, time.Second, time.Millisecond)
+
+	// Another feed is refreshed while the slow one is still being fetched
+	refreshed := make(chan error, 1)
+	go func() {
+		_, err := f.svc.Refresh(ctx, f.userID, fast.ID)
+		refreshed <- err
+	}()
+	select {
+	case err := <-refreshed:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("refresh of another subscription waited for the slow feed")
+	}
+
+	close(release)
+	require.NoError(t, <-done)
+}
+
+func TestService_UpdateWaitsForRefresh(t *testing.T) {
+	f := setupService(t, 0)
+	ctx := context.Background()
+	f.fetcher.setFeed("https://feeds.example.com/a.ics", event("a", "A"))
+
+	out, err := f.svc.Create(ctx, f.userID, subscription.CreateInput{URL: "https://feeds.example.com/a.ics"})
+	require.NoError(t, err)
+	sub, err := f.subs.GetByID(ctx, out.ID)
+	require.NoError(t, err)
+	sub.NextFetchAt = time.Now().Add(-time.Minute)
+	require.NoError(t, f.subs.Update(ctx, sub))
+
+	release := make(chan struct{})
+	f.fetcher.mu.Lock()
+	f.fetcher.blocked["https://feeds.example.com/a.ics"] = release
+	f.fetcher.mu.Unlock()
+
+	polled := make(chan error, 1)
+	go func() {
+		_, err := f.svc.RefreshDue(ctx)
+		polled <- err
+	}()
+	require.Eventually(t, func() bool {
+		f.fetcher.mu.Lock()
+		defer f.fetcher.mu.Unlock()
+		return len(f.fetcher.requests) == 2
+	}, time.Second, time.Millisecond)
+
+	// The interval changes while the background poll still fetches the feed
+	updated := make(chan error, 1)
+	go func() {
+		minutes := 120
+		_, err := f.svc.Update(ctx, f.userID, out.ID, subscription.UpdateInput{RefreshMinutes: &minutes})
+		updated <- err
+	}()
+	select {
+	case <-updated:
+		t.Fatal("update did not wait for the refresh of the same subscription")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	require.NoError(t, <-polled)
+	require.NoError(t, <-updated)
+
+	got, err := f.svc.Get(ctx, f.userID, out.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 120, got.RefreshMinutes)
+	assert.True(t, got.NextFetchAt.After(time.Now().Add(time.Hour)))
+}
@@ -3,6 +3,7 @@ package user
 import (
 	"context"
 	"errors"
+	"strings"
 
 	"github.com/jherrma/caldav-server/internal/domain/user"
 )
@@ -10,6 +11,9 @@ import (
 var (
 	ErrEmailAlreadyExists = errors.New("email address already registered")
 	ErrDisplayNameTooLong = errors.New("display name must be at most 255 characters")
+	// ErrProfileFieldTooLong is returned for a phone number over 50 or a job
+	// title or department over 255 characters
+	ErrProfileFieldTooLong = errors.New("profile field is too long")
 )
 
 type UpdateProfileUseCase struct {
@@ -22,6 +26,9 @@ func NewUpdateProfileUseCase(repo user.UserRepository) *UpdateProfileUseCase {
 
 type UpdateProfileRequest struct {
 	DisplayName *string
+	Phone       *string
+	JobTitle    *string
+	Department  *string
 }
 
 func (uc *UpdateProfileUseCase) Execute(ctx context.Context, userUUID string, req UpdateProfileRequest) (*user.User, error) {
@@ -39,6 +46,24 @@ func (uc *UpdateProfileUseCase) Execute(ctx context.Context, userUUID string, re
 		}
 		u.DisplayName = *req.DisplayName
 	}
+	for _, f := range []struct {
+		value *string
+		field *string
+		max   int
+	}{
+		{req.Phone, &u.Phone, 50},
+		{req.JobTitle, &u.JobTitle, 255},
+		{req.Department, &u.Department, 255},
+	} {
+		if f.value == nil {
+			continue
+		}
+		value := strings.TrimSpace(*f.value)
+		if len(value) > f.max {
+			return nil, ErrProfileFieldTooLong
+		}
+		*f.field = value
+	}
 
 	if err := uc.repo.Update(ctx, u); err != nil {
 		return nil, err
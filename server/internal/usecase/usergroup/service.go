@@ -0,0 +1,288 @@
+package usergroup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jherrma/caldav-server/internal/domain/user"
+)
+
+var (
+	// ErrNotFound is returned for unknown groups and for groups the user may not use
+	ErrNotFound = errors.New("group not found")
+	// ErrForbidden is returned when the user may see but not manage a group
+	ErrForbidden = errors.New("permission denied")
+	// ErrInvalidInput is returned for invalid names and unknown members
+	ErrInvalidInput = errors.New("invalid group")
+)
+
+// MemberOutput is a member of a group
+type MemberOutput struct {
+	ID          string    `json:"id"`
+	Username    string    `json:"username"`
+	DisplayName string    `json:"display_name"`
+	Email       string    `json:"email"`
+	AddedAt     time.Time `json:"added_at"`
+	Pending     bool      `json:"pending"` // invited, but not accepted yet
+}
+
+// OwnerOutput is the user managing a group
+type OwnerOutput struct {
+	ID          string `json:"id"`
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name"`
+}
+
+// GroupOutput is a group as seen by the requesting user
+type GroupOutput struct {
+	ID           string         `json:"id"`
+	Name         string         `json:"name"`
+	Description  string         `json:"description"`
+	AdminManaged bool           `json:"admin_managed"`
+	Owner        *OwnerOutput   `json:"owner,omitempty"`
+	Members      []MemberOutput `json:"members"`
+	CanManage    bool           `json:"can_manage"`
+	Invited      bool           `json:"invited"` // the user may accept joining the group
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+}
+
+// CreateInput describes a new group. Only administrators may create groups
+// managed by administrators.
+type CreateInput struct {
+	Name         string `json:"name"`
+	Description  string `json:"description"`
+	AdminManaged bool   `json:"admin_managed"`
+}
+
+// UpdateInput changes the fields that are set
+type UpdateInput struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+}
+
+// Service manages user groups. Calendars and address books shared with a
+// group are available to its members for as long as they belong to it.
+// Users join groups managed by administrators when they are added; other
+// groups they join by accepting the invitation that adding them creates.
+type Service struct {
+	groupRepo user.GroupRepository
+	userRepo  user.UserRepository
+}
+
+func NewService(groupRepo user.GroupRepository, userRepo user.UserRepository) *Service {
+	return &Service{groupRepo: groupRepo, userRepo: userRepo}
+}
+
+// List returns the groups the user may use; administrators see every group
+func (s *Service) List(ctx context.Context, userID uint) ([]GroupOutput, error) {
+	u, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []user.Group
+	if u.IsAdmin {
+		groups, err = s.groupRepo.ListAll(ctx)
+	} else {
+		groups, err = s.groupRepo.ListForUser(ctx, userID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	output := make([]GroupOutput, len(groups))
+	for i := range groups {
+		output[i] = toOutput(&groups[i], u)
+	}
+	return output, nil
+}
+
+// Create adds a group owned by the user, or managed by administrators
+func (s *Service) Create(ctx context.Context, userID uint, input CreateInput) (*GroupOutput, error) {
+	u, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := user.ValidateGroupName(input.Name); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	if input.AdminManaged && !u.IsAdmin {
+		return nil, ErrForbidden
+	}
+
+	group := &user.Group{
+		UUID:        uuid.New().String(),
+		Name:        strings.TrimSpace(input.Name),
+		Description: strings.TrimSpace(input.Description),
+	}
+	if !input.AdminManaged {
+		group.OwnerID = &u.ID
+	}
+	if err := s.groupRepo.Create(ctx, group); err != nil {
+		return nil, err
+	}
+	return s.Get(ctx, userID, group.UUID)
+}
+
+// Get returns a group the user may use
+func (s *Service) Get(ctx context.Context, userID uint, groupUUID string) (*GroupOutput, error) {
+	group, u, err := s.load(ctx, userID, groupUUID)
+	if err != nil {
+		return nil, err
+	}
+	output := toOutput(group, u)
+	return &output, nil
+}
+
+// Update renames a group or changes its description
+func (s *Service) Update(ctx context.Context, userID uint, groupUUID string, input UpdateInput) (*GroupOutput, error) {
+	group, u, err := s.loadForManagement(ctx, userID, groupUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Name != nil {
+		if err := user.ValidateGroupName(*input.Name); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+		}
+		group.Name = strings.TrimSpace(*input.Name)
+	}
+	if input.Description != nil {
+		group.Description = strings.TrimSpace(*input.Description)
+	}
+	if err := s.groupRepo.Update(ctx, group); err != nil {
+		return nil, err
+	}
+
+	output := toOutput(group, u)
+	return &output, nil
+}
+
+// Delete removes a group together with its memberships and shares
+func (s *Service) Delete(ctx context.Context, userID uint, groupUUID string) error {
+	group, _, err := s.loadForManagement(ctx, userID, groupUUID)
+	if err != nil {
+		return err
+	}
+	return s.groupRepo.Delete(ctx, group.ID)
+}
+
+// AddMember adds the user with the given email address or username. Users
+// other than the one adding them are invited to groups owned by a user.
+func (s *Service) AddMember(ctx context.Context, userID uint, groupUUID, identifier string) (*GroupOutput, error) {
+	group, _, err := s.loadForManagement(ctx, userID, groupUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	member, err := s.userRepo.GetByEmail(ctx, identifier)
+	if err != nil || member == nil {
+		member, err = s.userRepo.GetByUsername(ctx, identifier)
+	}
+	if err != nil || member == nil {
+		return nil, fmt.Errorf("%w: user '%s' not found", ErrInvalidInput, identifier)
+	}
+
+	pending := !group.AdminManaged() && member.ID != userID
+	if err := s.groupRepo.AddMember(ctx, group.ID, member.ID, pending); err != nil {
+		return nil, err
+	}
+	return s.Get(ctx, userID, groupUUID)
+}
+
+// AcceptInvitation makes the user a member of a group they were invited to.
+// Invitations are declined by leaving the group with RemoveMember.
+func (s *Service) AcceptInvitation(ctx context.Context, userID uint, groupUUID string) (*GroupOutput, error) {
+	group, _, err := s.load(ctx, userID, groupUUID)
+	if err != nil {
+		return nil, err
+	}
+	if !group.IsInvited(userID) {
+		return nil, ErrNotFound
+	}
+	if err := s.groupRepo.AcceptMember(ctx, group.ID, userID); err != nil {
+		return nil, err
+	}
+	return s.Get(ctx, userID, groupUUID)
+}
+
+// RemoveMember removes a member by user ID. Members may leave a group they
+// cannot manage.
+func (s *Service) RemoveMember(ctx context.Context, userID uint, groupUUID, memberUUID string) error {
+	group, u, err := s.load(ctx, userID, groupUUID)
+	if err != nil {
+		return err
+	}
+	if memberUUID != u.UUID && !group.CanManage(u) {
+		return ErrForbidden
+	}
+
+	for _, m := range group.Members {
+		if m.User.UUID == memberUUID {
+			return s.groupRepo.RemoveMember(ctx, group.ID, m.UserID)
+		}
+	}
+	return ErrNotFound
+}
+
+// load fetches a group the user may use or is invited to together with the
+// user
+func (s *Service) load(ctx context.Context, userID uint, groupUUID string) (*user.Group, *user.User, error) {
+	u, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	group, err := s.groupRepo.GetByUUID(ctx, groupUUID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if group == nil || !(group.CanUse(u) || group.IsInvited(u.ID)) {
+		return nil, nil, ErrNotFound
+	}
+	return group, u, nil
+}
+
+// loadForManagement is load for changes, which need CanManage
+func (s *Service) loadForManagement(ctx context.Context, userID uint, groupUUID string) (*user.Group, *user.User, error) {
+	group, u, err := s.load(ctx, userID, groupUUID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !group.CanManage(u) {
+		return nil, nil, ErrForbidden
+	}
+	return group, u, nil
+}
+
+func toOutput(g *user.Group, viewer *user.User) GroupOutput {
+	output := GroupOutput{
+		ID:           g.UUID,
+		Name:         g.Name,
+		Description:  g.Description,
+		AdminManaged: g.AdminManaged(),
+		Members:      make([]MemberOutput, len(g.Members)),
+		CanManage:    g.CanManage(viewer),
+		Invited:      g.IsInvited(viewer.ID),
+		CreatedAt:    g.CreatedAt,
+		UpdatedAt:    g.UpdatedAt,
+	}
+	if g.Owner != nil {
+		output.Owner = &OwnerOutput{ID: g.Owner.UUID, Username: g.Owner.Username, DisplayName: g.Owner.DisplayName}
+	}
+	for i, m := range g.Members {
+		output.Members[i] = MemberOutput{
+			ID:          m.User.UUID,
+			Username:    m.User.Username,
+			DisplayName: m.User.DisplayName,
+			Email:       m.User.Email,
+			AddedAt:     m.CreatedAt,
+			Pending:     m.Pending,
+		}
+	}
+	return output
+}
@@ -8,6 +8,7 @@ export default defineNuxtRouteMiddleware(async (to) => {
 
   // If not authenticated and trying to access a protected route
   if (!authStore.isAuthenticated && !to.path.startsWith("/auth")) {
-    return navigateTo("/auth/login");
+    // Remember where the user was going (e.g. an OAuth consent request)
+    return navigateTo({ path: "/auth/login", query: { redirect: to.fullPath } });
   }
 });
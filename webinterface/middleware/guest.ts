@@ -1,4 +1,4 @@
-export default defineNuxtRouteMiddleware(async () => {
+export default defineNuxtRouteMiddleware(async (to) => {
   const authStore = useAuthStore();
 
   // Initialize auth if not already done
@@ -6,8 +6,8 @@ export default defineNuxtRouteMiddleware(async () => {
     await authStore.initAuth();
   }
 
-  // If already authenticated, redirect to calendar (main app)
+  // If already authenticated, continue to the requested page or the calendar (main app)
   if (authStore.isAuthenticated) {
-    return navigateTo("/calendar");
+    return navigateTo(safeRedirect(to.query.redirect));
   }
 });
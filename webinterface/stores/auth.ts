@@ -34,6 +34,13 @@ export const useAuthStore = defineStore("auth", {
       this.isAuthenticated = true;
       this.isAdmin = response.user.is_admin || false;
 
+      this.storeRefreshToken(response.refresh_token);
+
+      // Schedule token refresh
+      this.scheduleTokenRefresh(response.expires_at);
+    },
+
+    storeRefreshToken(token: string) {
       // Store refresh token in cookie
       const refreshCookie = useCookie("refresh_token", {
         httpOnly: false, // Client needs to access it for refresh
@@ -41,10 +48,7 @@ export const useAuthStore = defineStore("auth", {
         sameSite: "strict",
         maxAge: 60 * 60 * 24 * 7, // 7 days
       });
-      refreshCookie.value = response.refresh_token;
-
-      // Schedule token refresh
-      this.scheduleTokenRefresh(response.expires_at);
+      refreshCookie.value = token;
     },
 
     async register(data: any) {
@@ -88,6 +92,8 @@ export const useAuthStore = defineStore("auth", {
         });
 
         this.accessToken = response.access_token;
+        // Refresh tokens are single-use; keep the rotated one
+        this.storeRefreshToken(response.refresh_token);
         this.scheduleTokenRefresh(response.expires_at);
       } catch {
         this.clearAuth();
@@ -18,6 +18,7 @@ export interface LoginResponse {
 
 export interface RefreshResponse {
   access_token: string;
+  refresh_token: string;
   token_type: string;
   expires_at: number;
 }
@@ -39,3 +40,20 @@ export interface AuthMethod {
 export interface AuthMethodsResponse {
   methods: AuthMethod[];
 }
+
+export interface OAuthScope {
+  name: string;
+  description: string;
+}
+
+export interface OAuthConsentPrompt {
+  client_id: string;
+  client_name: string;
+  redirect_uri: string;
+  scopes: OAuthScope[];
+  consent_given: boolean;
+}
+
+export interface OAuthDecisionResponse {
+  redirect_to: string;
+}
@@ -0,0 +1,8 @@
+// Returns the in-app path to continue to after sign-in. Only relative paths
+// are accepted so the login page cannot be used as an open redirect.
+export const safeRedirect = (redirect: unknown, fallback = "/calendar"): string => {
+  if (typeof redirect !== "string" || !redirect.startsWith("/") || redirect.startsWith("//") || redirect.startsWith("/\\")) {
+    return fallback;
+  }
+  return redirect;
+};